@@ -0,0 +1,68 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestExecBatch(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	// The server answers a batch once, after reading all of its statements
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "Welcome\n%s\n", prompt)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			n, ok, _ := protocol.ParseBatchHeader(scanner.Text())
+			if !ok {
+				return
+			}
+			var stmts []string
+			for len(stmts) < n && scanner.Scan() {
+				stmts = append(stmts, scanner.Text())
+			}
+			if strings.Contains(strings.Join(stmts, "\n"), "missing") {
+				fmt.Fprintf(conn, "%s\n%s\n", protocol.EncodeResult("", &protocol.Error{Code: protocol.CodeNotFound, Message: "Error: batch failed at statement 2, no changes applied: Table missing not found"}), prompt)
+				continue
+			}
+			fmt.Fprintf(conn, "Batch executed (%d statement(s)): %s\n%s\n", n, strings.Join(stmts, " | "), prompt)
+		}
+	}()
+
+	c, err := Dial(ln.Addr().String(), Options{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	resp, err := c.ExecBatch([]string{"INSERT INTO t VALUES (1)", "INSERT INTO t VALUES (2)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Batch executed (2 statement(s)): INSERT INTO t VALUES (1) | INSERT INTO t VALUES (2)"; resp != want {
+		t.Errorf("response %q, want %q", resp, want)
+	}
+
+	_, err = c.ExecBatch([]string{"INSERT INTO t VALUES (3)", "INSERT INTO missing VALUES (1)"})
+	if CodeOf(err) == "" {
+		t.Errorf("failed batch returned %v, want a coded error", err)
+	}
+	if _, err := c.ExecBatch(nil); err == nil {
+		t.Error("empty batch was sent")
+	}
+}
@@ -0,0 +1,256 @@
+// client/cluster.go
+package client
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClusterConfig describes a primary and its read replicas
+type ClusterConfig struct {
+	Primary  string
+	Replicas []string
+	Options  Options
+
+	// HealthCheckInterval is how often replicas are probed; zero means 5s
+	HealthCheckInterval time.Duration
+
+	// MaxReplicaLag is the staleness a read tolerates. Replicas whose reported
+	// lag exceeds it are skipped. Zero disables replica reads entirely.
+	MaxReplicaLag time.Duration
+
+	// ReplicaLag reports how far behind a replica is; use ReplicationLag to
+	// read it from SHOW REPLICATION STATUS. When nil, lag is treated as
+	// unknown and healthy replicas are always eligible.
+	ReplicaLag func(c *Conn) (time.Duration, error)
+
+	// ReadYourWrites sends reads to the primary for this long after a write
+	// made through the cluster, so callers see their own changes
+	ReadYourWrites time.Duration
+}
+
+// ErrNoPrimary is returned when the primary cannot be reached
+var ErrNoPrimary = errors.New("harudb: primary unavailable")
+
+// replica tracks one replica endpoint and its last health check
+type replica struct {
+	addr    string
+	conn    *Conn
+	healthy bool
+	lag     time.Duration
+}
+
+// Cluster routes writes to the primary and reads to healthy replicas
+type Cluster struct {
+	cfg       ClusterConfig
+	primary   *Conn
+	replicas  []*replica
+	next      int
+	inTx      bool
+	lastWrite time.Time
+	mu        sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// OpenCluster connects to the primary and starts checking replica health.
+// Replicas that are down are retried by the health checker.
+func OpenCluster(cfg ClusterConfig) (*Cluster, error) {
+	if cfg.HealthCheckInterval == 0 {
+		cfg.HealthCheckInterval = 5 * time.Second
+	}
+
+	primary, err := Dial(cfg.Primary, cfg.Options)
+	if err != nil {
+		return nil, errors.Join(ErrNoPrimary, err)
+	}
+
+	cl := &Cluster{
+		cfg:     cfg,
+		primary: primary,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, addr := range cfg.Replicas {
+		cl.replicas = append(cl.replicas, &replica{addr: addr})
+	}
+
+	cl.checkReplicas()
+	go cl.healthLoop()
+
+	return cl, nil
+}
+
+// Exec runs a statement on the primary or on a replica depending on whether
+// it reads or writes. Everything inside an explicit transaction goes to the
+// primary.
+func (cl *Cluster) Exec(statement string) (string, error) {
+	conn := cl.route(statement)
+	resp, err := conn.Exec(statement)
+	if err != nil && CodeOf(err) == "" && conn != cl.primary {
+		// The replica failed mid-request; take it out and retry on the primary
+		cl.markUnhealthy(conn)
+		return cl.primary.Exec(statement)
+	}
+	return resp, err
+}
+
+// Primary returns the primary connection
+func (cl *Cluster) Primary() *Conn {
+	return cl.primary
+}
+
+// HealthyReplicas returns the addresses of replicas currently eligible for reads
+func (cl *Cluster) HealthyReplicas() []string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	var addrs []string
+	for _, r := range cl.replicas {
+		if cl.eligible(r) {
+			addrs = append(addrs, r.addr)
+		}
+	}
+	return addrs
+}
+
+// Close stops health checks and closes every connection
+func (cl *Cluster) Close() error {
+	close(cl.stop)
+	<-cl.done
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for _, r := range cl.replicas {
+		if r.conn != nil {
+			r.conn.Close()
+		}
+	}
+	return cl.primary.Close()
+}
+
+// route picks the connection for a statement and tracks transaction state
+func (cl *Cluster) route(statement string) *Conn {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	upper := strings.ToUpper(strings.TrimSpace(statement))
+	switch {
+	case strings.HasPrefix(upper, "BEGIN"):
+		cl.inTx = true
+	case strings.HasPrefix(upper, "COMMIT"),
+		strings.HasPrefix(upper, "ROLLBACK") && !strings.HasPrefix(upper, "ROLLBACK TO"):
+		cl.inTx = false
+	}
+
+	readOnly := IsReadOnly(statement)
+	if !readOnly {
+		cl.lastWrite = time.Now()
+	}
+	if cl.inTx || !readOnly {
+		return cl.primary
+	}
+	if cl.cfg.ReadYourWrites > 0 && time.Since(cl.lastWrite) < cl.cfg.ReadYourWrites {
+		return cl.primary
+	}
+
+	// Round-robin across eligible replicas
+	for i := 0; i < len(cl.replicas); i++ {
+		r := cl.replicas[(cl.next+i)%len(cl.replicas)]
+		if cl.eligible(r) {
+			cl.next = (cl.next + i + 1) % len(cl.replicas)
+			return r.conn
+		}
+	}
+	return cl.primary
+}
+
+// eligible reports whether a replica may serve reads; callers hold cl.mu
+func (cl *Cluster) eligible(r *replica) bool {
+	return cl.cfg.MaxReplicaLag > 0 && r.healthy && r.conn != nil && r.lag <= cl.cfg.MaxReplicaLag
+}
+
+// markUnhealthy removes a replica connection from rotation until the next check
+func (cl *Cluster) markUnhealthy(conn *Conn) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for _, r := range cl.replicas {
+		if r.conn == conn {
+			r.healthy = false
+		}
+	}
+}
+
+// healthLoop periodically probes replicas until Close
+func (cl *Cluster) healthLoop() {
+	defer close(cl.done)
+	ticker := time.NewTicker(cl.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cl.stop:
+			return
+		case <-ticker.C:
+			cl.checkReplicas()
+		}
+	}
+}
+
+// checkReplicas reconnects to down replicas, pings the rest and refreshes lag
+func (cl *Cluster) checkReplicas() {
+	cl.mu.Lock()
+	replicas := append([]*replica(nil), cl.replicas...)
+	cl.mu.Unlock()
+
+	for _, r := range replicas {
+		conn, healthy, lag := cl.probe(r)
+
+		cl.mu.Lock()
+		r.conn, r.healthy, r.lag = conn, healthy, lag
+		cl.mu.Unlock()
+	}
+}
+
+// probe checks a single replica without holding the cluster lock
+func (cl *Cluster) probe(r *replica) (*Conn, bool, time.Duration) {
+	cl.mu.Lock()
+	conn := r.conn
+	cl.mu.Unlock()
+
+	if conn != nil {
+		if err := conn.Ping(); err != nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+	if conn == nil {
+		var err error
+		if conn, err = Dial(r.addr, cl.cfg.Options); err != nil {
+			return nil, false, 0
+		}
+	}
+
+	if cl.cfg.ReplicaLag == nil {
+		return conn, true, 0
+	}
+	lag, err := cl.cfg.ReplicaLag(conn)
+	if err != nil {
+		return conn, false, 0
+	}
+	return conn, true, lag
+}
+
+// IsReadOnly reports whether a statement only reads data and may be served
+// by a replica
+func IsReadOnly(statement string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(statement))
+	for _, prefix := range []string{"SELECT", "SHOW", "LIST", "DESCRIBE", "HELP"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
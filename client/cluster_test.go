@@ -0,0 +1,207 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer speaks the HaruDB line protocol and answers "<name>: <statement>"
+type fakeServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newFakeServer(t *testing.T, name string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	fs := &fakeServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			fs.mu.Lock()
+			fs.conns = append(fs.conns, conn)
+			fs.mu.Unlock()
+			go func(conn net.Conn) {
+				defer conn.Close()
+				fmt.Fprintf(conn, "\nWelcome to HaruDB\n%s\n", prompt)
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					stmt := strings.TrimSpace(scanner.Text())
+					if stmt == "exit" {
+						return
+					}
+					if strings.HasPrefix(stmt, "LOGIN") {
+						fmt.Fprintf(conn, "Login successful\n%s\n", prompt)
+						continue
+					}
+					fmt.Fprintf(conn, "%s: %s\n%s\n", name, stmt, prompt)
+				}
+			}(conn)
+		}
+	}()
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fakeServer) Addr() string {
+	return fs.ln.Addr().String()
+}
+
+// Close stops accepting and drops every open connection
+func (fs *fakeServer) Close() {
+	fs.ln.Close()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, conn := range fs.conns {
+		conn.Close()
+	}
+}
+
+func served(t *testing.T, cl *Cluster, stmt string) string {
+	t.Helper()
+	resp, err := cl.Exec(stmt)
+	if err != nil {
+		t.Fatalf("exec %q failed: %v", stmt, err)
+	}
+	return strings.SplitN(resp, ":", 2)[0]
+}
+
+func TestConnExec(t *testing.T) {
+	ln := newFakeServer(t, "primary")
+	conn, err := Dial(ln.Addr(), Options{Username: "admin", Password: "admin123"})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	resp, err := conn.Exec("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if resp != "primary: SELECT * FROM users" {
+		t.Errorf("unexpected response %q", resp)
+	}
+	if _, err := conn.Exec("SELECT 1\nDROP TABLE users"); err == nil {
+		t.Error("expected multi-line statement to be rejected")
+	}
+}
+
+func TestClusterReadWriteSplit(t *testing.T) {
+	primary := newFakeServer(t, "primary")
+	r1 := newFakeServer(t, "r1")
+	r2 := newFakeServer(t, "r2")
+
+	cl, err := OpenCluster(ClusterConfig{
+		Primary:       primary.Addr(),
+		Replicas:      []string{r1.Addr(), r2.Addr()},
+		MaxReplicaLag: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("open cluster failed: %v", err)
+	}
+	defer cl.Close()
+
+	if got := served(t, cl, "INSERT INTO users VALUES (1, 'a')"); got != "primary" {
+		t.Errorf("write served by %s", got)
+	}
+	first, second := served(t, cl, "SELECT * FROM users"), served(t, cl, "SELECT * FROM users")
+	if first == "primary" || second == "primary" || first == second {
+		t.Errorf("reads should round-robin across replicas, got %s then %s", first, second)
+	}
+
+	served(t, cl, "BEGIN TRANSACTION")
+	if got := served(t, cl, "SELECT * FROM users"); got != "primary" {
+		t.Errorf("read inside transaction served by %s", got)
+	}
+	served(t, cl, "COMMIT")
+	if got := served(t, cl, "SELECT * FROM users"); got == "primary" {
+		t.Error("read after commit should go back to a replica")
+	}
+
+	// A replica that goes away is skipped after the failed request
+	r1.Close()
+	r2.Close()
+	cl.checkReplicas()
+	if got := served(t, cl, "SELECT * FROM users"); got != "primary" {
+		t.Errorf("read with no healthy replicas served by %s", got)
+	}
+}
+
+func TestClusterStaleReadTolerance(t *testing.T) {
+	primary := newFakeServer(t, "primary")
+	r1 := newFakeServer(t, "r1")
+
+	lag := 5 * time.Second
+	cl, err := OpenCluster(ClusterConfig{
+		Primary:        primary.Addr(),
+		Replicas:       []string{r1.Addr()},
+		MaxReplicaLag:  time.Second,
+		ReplicaLag:     func(*Conn) (time.Duration, error) { return lag, nil },
+		ReadYourWrites: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("open cluster failed: %v", err)
+	}
+	defer cl.Close()
+
+	if got := served(t, cl, "SELECT * FROM users"); got != "primary" {
+		t.Errorf("lagging replica served a read")
+	}
+
+	lag = 0
+	cl.checkReplicas()
+	if got := served(t, cl, "SELECT * FROM users"); got != "r1" {
+		t.Errorf("caught-up replica should serve reads, got %s", got)
+	}
+
+	served(t, cl, "UPDATE users SET name = 'b' WHERE id = 1")
+	if got := served(t, cl, "SELECT * FROM users"); got != "primary" {
+		t.Errorf("read-your-writes should pin reads to the primary, got %s", got)
+	}
+}
+
+func TestReplicationLag(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "%s\n", prompt)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			fmt.Fprintf(conn, "Replication Status:\n  Role: replica\n  Connected: true\n  Time Lag: 1.5s\n%s\n", prompt)
+		}
+	}()
+
+	conn, err := Dial(ln.Addr().String(), Options{})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	lag, err := ReplicationLag(conn)
+	if err != nil {
+		t.Fatalf("failed to read lag: %v", err)
+	}
+	if lag != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s lag, got %s", lag)
+	}
+}
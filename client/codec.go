@@ -0,0 +1,51 @@
+// client/codec.go
+//
+// Value codecs and statement arguments. The codecs are shared with
+// programs that embed the engine (see protocol/values.go): a codec
+// registered here encodes and decodes values for both.
+package client
+
+import "github.com/Hareesh108/haruDB/internal/protocol"
+
+// RegisterCodec makes values of type T usable as statement arguments,
+// stored as tag:text where encode gives the text, and rebuilt by decode.
+// Tags are lowercase letters, digits and underscores, and each tag and type
+// is registered once. The time tag is registered for time.Time.
+func RegisterCodec[T any](tag string, encode func(T) (string, error), decode func(string) (T, error)) error {
+	return protocol.RegisterCodec(tag, encode, decode)
+}
+
+// EncodeValue returns v as a SQL literal: NULL for nil, a number or boolean
+// as written, a string quoted, and a value of a type with a codec as the
+// quoted tag:text. A string that would read as tag:text, or that starts
+// with ':', is stored with a ':' in front.
+func EncodeValue(v any) (string, error) {
+	return protocol.EncodeValue(v)
+}
+
+// DecodeValue rebuilds the value a codec stored as tag:text, and a string
+// EncodeValue stored with a ':' in front. Other values are returned
+// unchanged, as strings.
+func DecodeValue(s string) (any, error) {
+	return protocol.DecodeValue(s)
+}
+
+// Decode rebuilds a value of type T stored by its codec as tag:text
+func Decode[T any](s string) (T, error) {
+	return protocol.Decode[T](s)
+}
+
+// Bind replaces each ? placeholder of statement outside quotes with the
+// next of args, encoded with EncodeValue
+func Bind(statement string, args ...any) (string, error) {
+	return protocol.Bind(statement, args...)
+}
+
+// ExecArgs binds args to the ? placeholders of statement and runs it
+func (c *Conn) ExecArgs(statement string, args ...any) (string, error) {
+	bound, err := Bind(statement, args...)
+	if err != nil {
+		return "", err
+	}
+	return c.Exec(bound)
+}
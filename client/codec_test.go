@@ -0,0 +1,100 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/parser"
+)
+
+// money is an amount in cents, stored as a decimal
+type money int64
+
+func init() {
+	if err := RegisterCodec("money",
+		func(m money) (string, error) { return fmt.Sprintf("%d.%02d", m/100, m%100), nil },
+		func(s string) (money, error) {
+			var units, cents int64
+			_, err := fmt.Sscanf(s, "%d.%02d", &units, &cents)
+			return money(units*100 + cents), err
+		}); err != nil {
+		panic(err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	got, err := Bind("INSERT INTO t VALUES (?, ?, ?, ?, '?', ?)", 42, "it's", nil, true, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "INSERT INTO t VALUES (42, 'it''s', NULL, true, '?', 1.5)"; got != want {
+		t.Errorf("Bind: %s", got)
+	}
+	if _, err := Bind("SELECT * FROM t WHERE a = ? AND b = ?", 1); err == nil {
+		t.Error("Bind with too few arguments succeeded")
+	}
+	if _, err := Bind("SELECT * FROM t", 1); err == nil {
+		t.Error("Bind with too many arguments succeeded")
+	}
+	if _, err := Bind("SELECT * FROM t WHERE a = ?", struct{}{}); err == nil || !strings.Contains(err.Error(), "no codec registered for struct {}") {
+		t.Errorf("Bind of a type without codec: %v", err)
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	id := func(s string) (string, error) { return s, nil }
+	if err := RegisterCodec("time", id, id); err == nil {
+		t.Error("registered a tag twice")
+	}
+	if err := RegisterCodec("Bad-Tag", id, id); err == nil {
+		t.Error("registered an invalid tag")
+	}
+	if err := RegisterCodec("money2",
+		func(m money) (string, error) { return "", nil },
+		func(s string) (money, error) { return 0, nil }); err == nil {
+		t.Error("registered a type twice")
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := auth.CreateAdmin(dir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	engine := parser.NewEngine(dir)
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE payments (paid, amount, note)")
+
+	paid := time.Date(2024, 3, 9, 14, 5, 6, 789000000, time.FixedZone("IST", 5*3600+1800))
+	insert, err := Bind("INSERT INTO payments VALUES (?, ?, ?)", paid, money(1250), "rent: march")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.Execute(insert); !strings.HasPrefix(got, "1 row inserted") {
+		t.Fatalf("insert: %s", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(engine.Execute("SELECT * FROM payments")), "\n")
+	values := strings.Split(lines[1], " | ")
+	gotPaid, err := Decode[time.Time](values[0])
+	if err != nil || !gotPaid.Equal(paid) || gotPaid.Format(time.RFC3339) != paid.Format(time.RFC3339) {
+		t.Errorf("paid: %v, %v", gotPaid, err)
+	}
+	if got, err := DecodeValue(values[1]); err != nil || got != money(1250) {
+		t.Errorf("amount: %#v, %v", got, err)
+	}
+	// Text that does not start with a registered tag stays text
+	if got, err := DecodeValue(values[2]); err != nil || got != "rent: march" {
+		t.Errorf("note: %#v, %v", got, err)
+	}
+	if _, err := Decode[money](values[0]); err == nil {
+		t.Error("decoded a time as money")
+	}
+	if _, err := DecodeValue("time:yesterday"); err == nil {
+		t.Error("decoded an invalid time")
+	}
+}
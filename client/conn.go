@@ -0,0 +1,259 @@
+// client/conn.go
+package client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+// prompt is sent by the server after the banner and after every response
+const prompt = "haruDB> "
+
+// ErrClosed is returned when using a connection after Close
+var ErrClosed = errors.New("harudb: connection closed")
+
+// Options configures a single connection
+type Options struct {
+	Username string
+	Password string
+	// TLSConfig enables TLS when non-nil
+	TLSConfig *tls.Config
+	// Timeout bounds dialing and each statement round trip; zero means 10s
+	Timeout time.Duration
+	// Retry retries read-only statements, and those sent with
+	// ExecIdempotent, that fail with a retryable error
+	Retry RetryPolicy
+}
+
+// Conn is a connection to one HaruDB server. It is safe for concurrent use;
+// statements are sent one at a time.
+type Conn struct {
+	addr    string
+	opts    Options
+	netConn net.Conn
+	reader  *bufio.Reader
+	mu      sync.Mutex
+	closed  bool
+	// pending holds notifications read but not yet returned
+	pending []Notification
+	server  ServerInfo
+	// lastQueryID is the query ID of the last statement answered
+	lastQueryID uint64
+}
+
+// ServerInfo describes the server a connection is open to, as it announced
+// itself. Servers that predate the announcement leave it zero.
+type ServerInfo struct {
+	Version string
+	// Protocol is the version of the line protocol the server speaks
+	Protocol int
+	// TLS reports whether the connection is encrypted
+	TLS bool
+	// Features lists what the server supports: batch, notify,
+	// backup-stream, error-codes and query-ids, and possibly others
+	Features []string
+}
+
+// Supports reports whether the server announced feature
+func (s ServerInfo) Supports(feature string) bool {
+	return slices.Contains(s.Features, feature)
+}
+
+// Dial connects to the server at addr, reads the welcome banner and logs in
+// when credentials are provided
+func Dial(addr string, opts Options) (*Conn, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	var netConn net.Conn
+	var err error
+	if opts.TLSConfig != nil {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", addr, opts.TLSConfig)
+	} else {
+		netConn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("harudb: failed to connect to %s: %w", addr, err)
+	}
+
+	c := &Conn{addr: addr, opts: opts, netConn: netConn, reader: bufio.NewReader(netConn)}
+
+	// Read the server's HELLO up to the first prompt
+	netConn.SetReadDeadline(time.Now().Add(opts.Timeout))
+	banner, err := c.readResponse()
+	if err != nil {
+		netConn.Close()
+		// A server that is still recovering sends an error instead
+		if _, refused := parseResponse(banner); refused != nil {
+			return nil, fmt.Errorf("harudb: %s refused the connection: %w", addr, refused)
+		}
+		return nil, fmt.Errorf("harudb: failed to read banner from %s: %w", addr, err)
+	}
+	for _, line := range strings.Split(banner, "\n") {
+		if !protocol.IsHello(line) {
+			continue
+		}
+		hello, err := protocol.DecodeHello(line)
+		if err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("harudb: %s: %w", addr, err)
+		}
+		c.server = ServerInfo{Version: hello.Server, Protocol: hello.Protocol, TLS: hello.TLS, Features: hello.Features}
+	}
+
+	if opts.Username != "" {
+		resp, err := c.Exec(fmt.Sprintf("LOGIN %s %s", opts.Username, opts.Password))
+		if CodeOf(err) != "" {
+			netConn.Close()
+			return nil, fmt.Errorf("harudb: login to %s failed: %w", addr, err)
+		}
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		if !strings.Contains(resp, "successful") {
+			netConn.Close()
+			return nil, fmt.Errorf("harudb: login to %s failed: %s", addr, resp)
+		}
+	}
+
+	return c, nil
+}
+
+// Server describes the server the connection is open to
+func (c *Conn) Server() ServerInfo {
+	return c.server
+}
+
+// Addr returns the server address
+func (c *Conn) Addr() string {
+	return c.addr
+}
+
+// Exec sends one statement and returns the server's response text. A
+// statement the server rejects returns an *Error carrying its code.
+// Read-only statements are retried under Options.Retry.
+func (c *Conn) Exec(statement string) (string, error) {
+	if isIdempotent(statement) {
+		return c.retry(func() (string, error) { return c.exec(statement) })
+	}
+	return c.exec(statement)
+}
+
+// exec sends one statement once
+func (c *Conn) exec(statement string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return "", ErrClosed
+	}
+
+	statement = strings.TrimSpace(statement)
+	if strings.ContainsAny(statement, "\r\n") {
+		return "", fmt.Errorf("harudb: statements must be a single line")
+	}
+	return c.roundTrip(statement + "\n")
+}
+
+// ExecBatch sends statements as one batch, which the server runs in a
+// single transaction, and returns the server's response. If a statement
+// fails, no changes are applied and an *Error describing it is returned.
+func (c *Conn) ExecBatch(statements []string) (string, error) {
+	frame, err := protocol.EncodeBatch(statements)
+	if err != nil {
+		return "", fmt.Errorf("harudb: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return "", ErrClosed
+	}
+	return c.roundTrip(frame)
+}
+
+// roundTrip sends a framed request and parses the response. The caller
+// holds c.mu.
+func (c *Conn) roundTrip(frame string) (string, error) {
+	c.netConn.SetDeadline(time.Now().Add(c.opts.Timeout))
+	if _, err := c.netConn.Write([]byte(frame)); err != nil {
+		return "", fmt.Errorf("harudb: failed to send statement: %w", err)
+	}
+
+	c.lastQueryID = 0
+	resp, err := c.readResponse()
+	if err != nil {
+		return "", fmt.Errorf("harudb: failed to read response: %w", err)
+	}
+	result, err := parseResponse(resp)
+	if stmtErr, ok := err.(*Error); ok {
+		stmtErr.QueryID = c.lastQueryID
+	}
+	return result, err
+}
+
+// LastQueryID returns the query ID the server gave the last statement it
+// answered, which names it in the server's logs, SHOW PROCESSLIST and KILL.
+// It is 0 for servers that do not support query-ids.
+func (c *Conn) LastQueryID() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastQueryID
+}
+
+// Ping checks that the server is still answering
+func (c *Conn) Ping() error {
+	// The server may reject the empty statement; any answer will do
+	if _, err := c.Exec(""); CodeOf(err) == "" {
+		return err
+	}
+	return nil
+}
+
+// Close says goodbye to the server and closes the connection
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.netConn.SetWriteDeadline(time.Now().Add(time.Second))
+	fmt.Fprintf(c.netConn, "exit\n")
+	return c.netConn.Close()
+}
+
+// readResponse reads lines until the next prompt. Notifications arriving in
+// between are queued for WaitForNotification. On a read error it returns the
+// text read so far with the error.
+func (c *Conn) readResponse() (string, error) {
+	var sb strings.Builder
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return strings.TrimRight(sb.String()+line, "\n"), err
+		}
+		if c.queueNotification(line) {
+			continue
+		}
+		if strings.HasPrefix(line, prompt) {
+			c.lastQueryID = protocol.PromptQueryID(line)
+			return strings.TrimRight(sb.String(), "\n"), nil
+		}
+		sb.WriteString(line)
+	}
+}
@@ -0,0 +1,68 @@
+// client/errors.go
+package client
+
+import (
+	"errors"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+// ErrorCode classifies a statement the server rejected
+type ErrorCode string
+
+// Error codes reported by the server
+const (
+	CodeSyntax        ErrorCode = ErrorCode(protocol.CodeSyntax)
+	CodeNotFound      ErrorCode = ErrorCode(protocol.CodeNotFound)
+	CodeConstraint    ErrorCode = ErrorCode(protocol.CodeConstraint)
+	CodeSerialization ErrorCode = ErrorCode(protocol.CodeSerialization)
+	CodeAuth          ErrorCode = ErrorCode(protocol.CodeAuth)
+	CodeTimeout       ErrorCode = ErrorCode(protocol.CodeTimeout)
+	CodeBusy          ErrorCode = ErrorCode(protocol.CodeBusy)
+	CodeInternal      ErrorCode = ErrorCode(protocol.CodeInternal)
+)
+
+// Error is returned by Exec when the server rejects a statement. The
+// connection remains usable.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	// QueryID is the server's ID for the statement, 0 if it sent none
+	QueryID uint64
+}
+
+func (e *Error) Error() string {
+	return "harudb: " + string(e.Code) + ": " + e.Message
+}
+
+// CodeOf returns the code of a statement error, or "" if err is nil or a
+// connection error rather than a rejected statement. Statements an
+// in-process engine rejects fail with a *protocol.Error, whose code is
+// returned too.
+func CodeOf(err error) ErrorCode {
+	var stmtErr *Error
+	if errors.As(err, &stmtErr) {
+		return stmtErr.Code
+	}
+	var engineErr *protocol.Error
+	if errors.As(err, &engineErr) {
+		return ErrorCode(engineErr.Code)
+	}
+	return ""
+}
+
+// Retryable reports whether err is a statement error that may succeed if
+// the statement is sent again: the server was busy, the statement timed out
+// or its transaction lost a serialization conflict. A transaction that failed
+// must be retried from BEGIN.
+func Retryable(err error) bool {
+	return protocol.Retryable(protocol.ErrorCode(CodeOf(err)))
+}
+
+// parseResponse turns a coded error response into an *Error
+func parseResponse(resp string) (string, error) {
+	if code, message, ok := protocol.ParseError(resp); ok {
+		return "", &Error{Code: ErrorCode(code), Message: message}
+	}
+	return resp, nil
+}
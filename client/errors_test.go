@@ -0,0 +1,112 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatementErrors(t *testing.T) {
+	// The fake server answers "<name>: <statement>", so this name makes every
+	// answer a coded error
+	fs := newFakeServer(t, "ERROR NOT_FOUND")
+	conn, err := Dial(fs.Addr(), Options{})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Exec("SELECT * FROM missing")
+	var stmtErr *Error
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if stmtErr.Code != CodeNotFound || stmtErr.Message != "SELECT * FROM missing" {
+		t.Errorf("unexpected error %+v", stmtErr)
+	}
+	if CodeOf(err) != CodeNotFound || CodeOf(ErrClosed) != "" || CodeOf(nil) != "" {
+		t.Error("CodeOf should report only statement errors")
+	}
+	if err := conn.Ping(); err != nil {
+		t.Errorf("a rejected ping still means the server is up: %v", err)
+	}
+}
+
+func TestDialRefusedDuringRecovery(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("ERROR BUSY: Error: server is starting up (loaded 1/4 tables, replayed 0 WAL records); retry later\n"))
+		conn.Close()
+	}()
+
+	_, err = Dial(ln.Addr().String(), Options{})
+	if CodeOf(err) != CodeBusy {
+		t.Errorf("expected a BUSY error, got %v", err)
+	}
+}
+
+func TestRetryIdempotentStatements(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Every statement is rejected as busy twice before it succeeds
+	var mu sync.Mutex
+	tries := make(map[string]int)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "Welcome\n%s\n", prompt)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			stmt := scanner.Text()
+			mu.Lock()
+			tries[stmt]++
+			n := tries[stmt]
+			mu.Unlock()
+			if n <= 2 {
+				fmt.Fprintf(conn, "ERROR BUSY: Error: write queue is full (1 writes in flight); retry later\n%s\n", prompt)
+				continue
+			}
+			fmt.Fprintf(conn, "ok: %s\n%s\n", stmt, prompt)
+		}
+	}()
+
+	conn, err := Dial(ln.Addr().String(), Options{Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if resp, err := conn.Exec("SELECT * FROM t"); err != nil || resp != "ok: SELECT * FROM t" {
+		t.Errorf("select: %q %v", resp, err)
+	}
+	if _, err := conn.Exec("INSERT INTO t VALUES ('1')"); !Retryable(err) {
+		t.Errorf("insert should fail without retries, got %v", err)
+	}
+	if resp, err := conn.ExecIdempotent("UPDATE t SET a = '1'"); err != nil || resp != "ok: UPDATE t SET a = '1'" {
+		t.Errorf("idempotent update: %q %v", resp, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if tries["SELECT * FROM t"] != 3 || tries["INSERT INTO t VALUES ('1')"] != 1 {
+		t.Errorf("tries: %v", tries)
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestDialReadsHello(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		banner string
+		want   ServerInfo
+	}{
+		{"hello", protocol.EncodeHello(protocol.Hello{Server: "v0.0.5", Protocol: 1, Features: []string{"batch", "notify"}}),
+			ServerInfo{Version: "v0.0.5", Protocol: 1, Features: []string{"batch", "notify"}}},
+		// Servers from before HELLO send a welcome text
+		{"welcome text", "\nWelcome to HaruDB v0.0.4\n", ServerInfo{}},
+	} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			fmt.Fprintf(conn, "%s%s\n", tc.banner, prompt)
+			conn.Read(make([]byte, 1))
+		}()
+
+		c, err := Dial(ln.Addr().String(), Options{Timeout: 2 * time.Second})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := c.Server()
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: server %+v, want %+v", tc.name, got, tc.want)
+		}
+		if got.Supports("notify") != (tc.want.Protocol > 0) {
+			t.Errorf("%s: Supports(notify) = %v", tc.name, got.Supports("notify"))
+		}
+		c.Close()
+		ln.Close()
+	}
+}
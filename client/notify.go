@@ -0,0 +1,78 @@
+// client/notify.go
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+// ErrNoNotification is returned by WaitForNotification when nothing arrives
+// before the timeout
+var ErrNoNotification = errors.New("harudb: no notification received")
+
+// Notification is a payload sent with NOTIFY to a channel the connection
+// listens on
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Listen subscribes the connection to channel
+func (c *Conn) Listen(channel string) error {
+	_, err := c.Exec("LISTEN " + channel)
+	return err
+}
+
+// Unlisten unsubscribes the connection from channel, or from every channel
+// when channel is "*"
+func (c *Conn) Unlisten(channel string) error {
+	_, err := c.Exec("UNLISTEN " + channel)
+	return err
+}
+
+// WaitForNotification returns the next notification, waiting up to timeout
+// for one to arrive. Notifications received while running statements are
+// returned first, in order.
+func (c *Conn) WaitForNotification(timeout time.Duration) (Notification, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return Notification{}, ErrClosed
+	}
+	if len(c.pending) == 0 {
+		c.netConn.SetReadDeadline(time.Now().Add(timeout))
+		for len(c.pending) == 0 {
+			line, err := c.reader.ReadString('\n')
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					return Notification{}, ErrNoNotification
+				}
+				return Notification{}, fmt.Errorf("harudb: failed to read notification: %w", err)
+			}
+			c.queueNotification(line)
+		}
+	}
+
+	n := c.pending[0]
+	c.pending = c.pending[1:]
+	return n, nil
+}
+
+// queueNotification queues line if it is a notification and reports whether
+// it was one
+func (c *Conn) queueNotification(line string) bool {
+	if !protocol.IsNotification(line) {
+		return false
+	}
+	channel, payload, err := protocol.DecodeNotification(line)
+	if err == nil {
+		c.pending = append(c.pending, Notification{Channel: channel, Payload: payload})
+	}
+	return true
+}
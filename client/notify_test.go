@@ -0,0 +1,70 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestNotifications(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	// The server sends one notification inside a response and another
+	// while the client is idle
+	idle := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "Welcome\n%s\n", prompt)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			switch stmt := strings.TrimSpace(scanner.Text()); stmt {
+			case "LISTEN cache":
+				fmt.Fprintf(conn, "Listening on cache\n%s\n", prompt)
+			case "NOTIFY cache, 'a'":
+				fmt.Fprintf(conn, "NOTIFY cache delivered to 1 listener(s)\n%s%s\n",
+					protocol.EncodeNotification("cache", "a"), prompt)
+				<-idle
+				fmt.Fprint(conn, protocol.EncodeNotification("cache", "b"))
+			case "exit":
+				return
+			}
+		}
+	}()
+
+	c, err := Dial(ln.Addr().String(), Options{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Listen("cache"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Exec("NOTIFY cache, 'a'")
+	if err != nil || resp != "NOTIFY cache delivered to 1 listener(s)" {
+		t.Fatalf("NOTIFY: %q %v", resp, err)
+	}
+	if n, err := c.WaitForNotification(time.Second); err != nil || n.Payload != "a" {
+		t.Fatalf("first notification: %+v %v", n, err)
+	}
+	if _, err := c.WaitForNotification(50 * time.Millisecond); err != ErrNoNotification {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	close(idle)
+	if n, err := c.WaitForNotification(time.Second); err != nil || n != (Notification{Channel: "cache", Payload: "b"}) {
+		t.Fatalf("idle notification: %+v %v", n, err)
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestLastQueryID(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "%s%s", protocol.EncodeHello(protocol.Hello{Server: "v0.0.5", Protocol: 1}), protocol.EncodePrompt(0))
+		scanner := bufio.NewScanner(conn)
+		for id := uint64(7); scanner.Scan(); id++ {
+			switch stmt := scanner.Text(); {
+			case stmt == "exit":
+				return
+			case strings.HasPrefix(stmt, "SELECT"):
+				fmt.Fprintf(conn, "id\n1\n%s", protocol.EncodePrompt(id))
+			default:
+				fmt.Fprintf(conn, "%s\n%s", protocol.EncodeResult("", &protocol.Error{Code: protocol.CodeSyntax, Message: "Unknown command"}), protocol.EncodePrompt(id))
+			}
+		}
+	}()
+
+	c, err := Dial(ln.Addr().String(), Options{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := c.LastQueryID(); got != 0 {
+		t.Errorf("query ID before any statement: %d", got)
+	}
+	if _, err := c.Exec("SELECT id FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.LastQueryID(); got != 7 {
+		t.Errorf("query ID %d, want 7", got)
+	}
+	_, err = c.Exec("FROB")
+	stmtErr, ok := err.(*Error)
+	if !ok || stmtErr.QueryID != 8 || c.LastQueryID() != 8 {
+		t.Errorf("error %#v, last query ID %d", err, c.LastQueryID())
+	}
+}
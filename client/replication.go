@@ -0,0 +1,38 @@
+// client/replication.go
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReplicationLag is a ClusterConfig.ReplicaLag implementation that asks a
+// replica for SHOW REPLICATION STATUS. It needs an admin login and treats a
+// replica that is disconnected from its primary as unusable.
+func ReplicationLag(c *Conn) (time.Duration, error) {
+	resp, err := c.Exec("SHOW REPLICATION STATUS")
+	if err != nil {
+		return 0, err
+	}
+
+	fields := make(map[string]string)
+	for _, line := range strings.Split(resp, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+
+	if fields["Role"] != "replica" {
+		return 0, fmt.Errorf("harudb: %s is not a replica: %s", c.Addr(), resp)
+	}
+	if fields["Connected"] != "true" {
+		return 0, fmt.Errorf("harudb: replica %s is disconnected from its primary", c.Addr())
+	}
+	lag, err := time.ParseDuration(fields["Time Lag"])
+	if err != nil {
+		return 0, fmt.Errorf("harudb: unexpected replication status from %s: %w", c.Addr(), err)
+	}
+	return lag, nil
+}
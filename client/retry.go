@@ -0,0 +1,68 @@
+// client/retry.go
+package client
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// RetryPolicy retries idempotent statements that fail with a retryable
+// error (see Retryable), waiting an exponentially growing, jittered backoff
+// between attempts. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries including the first; below 2
+	// disables retries
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; zero means 50ms
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait, which doubles after each retry; zero means 2s
+	MaxBackoff time.Duration
+}
+
+// idempotentCommands start statements that only read, which Exec retries.
+// FETCH is not one: it moves its cursor, so a retry would skip rows.
+var idempotentCommands = []string{"SELECT", "SHOW", "DESCRIBE", "EXPLAIN"}
+
+// isIdempotent reports whether running statement twice has the same effect
+// as running it once
+func isIdempotent(statement string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(statement))
+	for _, cmd := range idempotentCommands {
+		if upper == cmd || strings.HasPrefix(upper, cmd+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecIdempotent is Exec for a statement the caller knows can safely run
+// more than once, such as an UPDATE setting fixed values, which is retried
+// under the connection's RetryPolicy even though it writes
+func (c *Conn) ExecIdempotent(statement string) (string, error) {
+	return c.retry(func() (string, error) { return c.exec(statement) })
+}
+
+// retry calls run until it succeeds, fails with an error that is not
+// retryable or has been tried MaxAttempts times
+func (c *Conn) retry(run func() (string, error)) (string, error) {
+	p := c.opts.Retry
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = 50 * time.Millisecond
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 2 * time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := run()
+		if err == nil || attempt >= p.MaxAttempts || !Retryable(err) {
+			return resp, err
+		}
+		// Sleep between half and all of the backoff so that clients failing
+		// together do not retry together
+		time.Sleep(backoff/2 + rand.N(backoff/2+1))
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
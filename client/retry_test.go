@@ -0,0 +1,21 @@
+package client
+
+import "testing"
+
+func TestIsIdempotent(t *testing.T) {
+	for stmt, want := range map[string]bool{
+		"SELECT * FROM users":      true,
+		"  show tables":            true,
+		"DESCRIBE users":           true,
+		"EXPLAIN SELECT * FROM t":  true,
+		"FETCH ALL FROM c":         false,
+		"FETCH NEXT FROM c":        false,
+		"INSERT INTO t VALUES (1)": false,
+		"SELECTED":                 false,
+		"UPDATE t SET a = 1 ROW 0": false,
+	} {
+		if got := isIdempotent(stmt); got != want {
+			t.Errorf("isIdempotent(%q) = %v, want %v", stmt, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// cmd/server/admin.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+)
+
+const resetAdminUsage = `Usage: harudb reset-admin-password [flags]
+
+Gives the admin user of a data directory a new random password, enabling
+the account again or recreating it if it was deleted. Stop the server
+first: a running server would overwrite the change.
+
+Flags:
+`
+
+// runResetAdminPassword implements "harudb reset-admin-password" on a
+// stopped server's data directory and returns the process exit code
+func runResetAdminPassword(args []string) int {
+	fs := flag.NewFlagSet("reset-admin-password", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), resetAdminUsage)
+		fs.PrintDefaults()
+	}
+	dataDir := fs.String("data-dir", "./data", "Data directory of the stopped server")
+	passwordFile := fs.String("password-file", "", "Write the new password to this file instead of printing it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	password, err := auth.ResetAdminPassword(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	if err := announceAdminPassword(password, *passwordFile); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
+
+// announceAdminPassword shows a generated admin password once: written to
+// passwordFile, readable only by its owner, or printed to stdout
+func announceAdminPassword(password, passwordFile string) error {
+	if passwordFile != "" {
+		if err := os.WriteFile(passwordFile, []byte(password+"\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write the admin password: %w", err)
+		}
+		fmt.Printf("🔑 The password of user %s was written to %s\n", auth.DefaultAdminUser, passwordFile)
+		return nil
+	}
+	fmt.Printf("🔑 The password of user %s is %s\n", auth.DefaultAdminUser, password)
+	fmt.Printf("   It is not shown again; change it with CHANGE PASSWORD after logging in\n")
+	return nil
+}
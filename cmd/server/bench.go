@@ -0,0 +1,127 @@
+// cmd/server/bench.go
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/client"
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/bench"
+	"github.com/Hareesh108/haruDB/internal/parser"
+)
+
+const benchUsage = `Usage: harudb bench [flags]
+
+Runs insert, select and update workloads and reports throughput and latency
+percentiles. By default the workloads run against a server; with --embedded
+they run in-process against an engine on --data-dir (a temporary directory
+if unset). The benchmark table is dropped afterwards unless --keep is set.
+
+Flags:
+`
+
+// engineExecutor runs statements on an in-process engine
+type engineExecutor struct {
+	engine *parser.Engine
+}
+
+func (e engineExecutor) Exec(statement string) (string, error) {
+	return e.engine.Exec(statement)
+}
+
+// runBench implements "harudb bench" and returns the process exit code
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), benchUsage)
+		fs.PrintDefaults()
+	}
+	host := fs.String("host", "localhost", "Server host")
+	port := fs.String("port", "54321", "Server port")
+	user := fs.String("user", "admin", "User to log in as")
+	password := fs.String("password", os.Getenv("HARUDB_PASSWORD"), "Password (defaults to $HARUDB_PASSWORD; with --embedded, a new data directory's admin gets it, or a random one)")
+	useTLS := fs.Bool("tls", false, "Connect with TLS")
+	embedded := fs.Bool("embedded", false, "Run against an in-process engine instead of a server")
+	dataDir := fs.String("data-dir", "", "--embedded: data directory (default: a temporary directory)")
+	workloads := fs.String("workloads", strings.Join(bench.Workloads, ","), "Comma-separated workloads to run")
+	threads := fs.Int("threads", 4, "Concurrent workers (one connection each against a server)")
+	rows := fs.Int("rows", 10000, "Operations per workload")
+	valueSize := fs.Int("value-size", 100, "Bytes per stored value")
+	table := fs.String("table", "bench", "Table to create for the benchmark")
+	keep := fs.Bool("keep", false, "Keep the benchmark table afterwards")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := bench.Config{
+		Workloads: strings.Split(*workloads, ","),
+		Threads:   *threads,
+		Rows:      *rows,
+		ValueSize: *valueSize,
+		Table:     *table,
+		Keep:      *keep,
+	}
+
+	var open func() (bench.Executor, error)
+	if *embedded {
+		dir := *dataDir
+		if dir == "" {
+			tmp, err := os.MkdirTemp("", "harudb-bench-")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "❌", err)
+				return 1
+			}
+			defer os.RemoveAll(tmp)
+			dir = tmp
+		}
+		// A new data directory gets its admin as the server would give it
+		// one, or with --password if set, so the benchmark can log in
+		var err error
+		if *password == "" {
+			*password, err = auth.BootstrapAdmin(dir)
+			if err == nil && *password == "" {
+				err = fmt.Errorf("%s already has users; pass --password", dir)
+			}
+		} else {
+			_, err = auth.CreateAdmin(dir, *password)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		engine := parser.NewEngine(dir)
+		if resp := engine.Execute(fmt.Sprintf("LOGIN %s %s", *user, *password)); !strings.Contains(resp, "successful") {
+			fmt.Fprintln(os.Stderr, "❌", strings.TrimSpace(resp))
+			return 1
+		}
+		// Workers share the engine, as connections share it in the server
+		open = func() (bench.Executor, error) { return engineExecutor{engine}, nil }
+		fmt.Printf("Benchmarking embedded engine in %s\n", dir)
+	} else {
+		opts := client.Options{Username: *user, Password: *password}
+		if *useTLS {
+			opts.TLSConfig = &tls.Config{ServerName: *host}
+		}
+		addr := net.JoinHostPort(*host, *port)
+		open = func() (bench.Executor, error) { return client.Dial(addr, opts) }
+		fmt.Printf("Benchmarking server at %s\n", addr)
+	}
+	fmt.Printf("threads=%d rows=%d value-size=%d\n\n", *threads, *rows, *valueSize)
+
+	results, err := bench.Run(cfg, open)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	bench.Report(os.Stdout, results)
+	return 0
+}
@@ -0,0 +1,163 @@
+// cmd/server/listen.go
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/parser"
+	"github.com/Hareesh108/haruDB/internal/protocol"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// listenAddr is an address given with --listen
+type listenAddr struct {
+	addr string
+	// scheme is "tls" or "tcp" when the address forces TLS on or off, ""
+	// when it follows --tls
+	scheme string
+}
+
+func (a listenAddr) String() string {
+	if a.scheme != "" {
+		return a.scheme + "://" + a.addr
+	}
+	return a.addr
+}
+
+// wantsTLS reports whether the address is served with TLS
+func (a listenAddr) wantsTLS(enableTLS bool) bool {
+	return a.scheme == "tls" || (a.scheme == "" && enableTLS)
+}
+
+// listenAddrs collects repeated --listen flags
+type listenAddrs []listenAddr
+
+func (l *listenAddrs) String() string {
+	names := make([]string, len(*l))
+	for i, a := range *l {
+		names[i] = a.String()
+	}
+	return strings.Join(names, ",")
+}
+
+// Set parses one --listen value, which may hold several comma-separated
+// addresses. A tls:// prefix serves the address with TLS and tcp:// in plain
+// text; without a prefix --tls decides. IPv6 hosts are written in brackets.
+func (l *listenAddrs) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		a := listenAddr{addr: v}
+		if scheme, addr, ok := strings.Cut(v, "://"); ok {
+			if scheme != "tls" && scheme != "tcp" {
+				return fmt.Errorf("invalid listen address %q: scheme must be tls:// or tcp://", v)
+			}
+			a = listenAddr{addr: addr, scheme: scheme}
+		}
+		if _, port, err := net.SplitHostPort(a.addr); err != nil || port == "" {
+			return fmt.Errorf("invalid listen address %q: expected host:port, e.g. 127.0.0.1:54321 or [::1]:54321", v)
+		}
+		*l = append(*l, a)
+	}
+	return nil
+}
+
+// openListeners binds every address, wrapping those served with TLS with
+// tlsConfig. Without a TLS configuration, addresses following --tls fall
+// back to plain text but tls:// addresses fail.
+func openListeners(addrs listenAddrs, enableTLS bool, tlsConfig *tls.Config) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, a := range addrs {
+		if a.scheme == "tls" && tlsConfig == nil {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("%s requires TLS, but TLS is not configured", a)
+		}
+		l, err := net.Listen("tcp", a.addr)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("failed to listen on %s: %w", a.addr, err)
+		}
+		if a.wantsTLS(enableTLS) && tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// closeListeners closes every listener
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// serve accepts connections on l until shutdown is closed. Until ready
+// holds the engine, connections are told the server is recovering and closed.
+func serve(l net.Listener, ready *atomic.Pointer[parser.Engine], progress *storage.RecoveryProgress, shutdown <-chan struct{}) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-shutdown:
+				return
+			default:
+			}
+			log.Printf("Error accepting connection on %s: %v", l.Addr(), err)
+			continue
+		}
+		engine := ready.Load()
+		if engine == nil {
+			go refuseDuringRecovery(conn, progress)
+			continue
+		}
+		go handleConnection(conn, engine)
+	}
+}
+
+// refuseDuringRecovery tells a client the server is still recovering and
+// closes the connection
+func refuseDuringRecovery(conn net.Conn, progress *storage.RecoveryProgress) {
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	msg := fmt.Sprintf("Error: server is starting up (%s); retry later", progress)
+	conn.Write([]byte(protocol.EncodeResult("", &protocol.Error{Code: protocol.CodeBusy, Message: msg}) + "\n"))
+}
+
+// reportRecovery logs progress every few seconds until done is closed
+func reportRecovery(progress *storage.RecoveryProgress, done <-chan struct{}) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			log.Printf("⏳ Recovering: %s\n", progress)
+		}
+	}
+}
+
+// tlsManagerFor returns the TLS manager when --tls is set or any listen
+// address uses TLS, or nil when TLS is not wanted or could not be configured
+func tlsManagerFor(dataDir string, enableTLS bool, addrs listenAddrs) *auth.TLSManager {
+	wanted := false
+	for _, a := range addrs {
+		wanted = wanted || a.wantsTLS(enableTLS)
+	}
+	if !wanted {
+		return nil
+	}
+	tlsManager := auth.NewTLSManager(dataDir)
+	if !tlsManager.IsTLSEnabled() {
+		log.Printf("Warning: TLS requested but not properly configured")
+		return nil
+	}
+	return tlsManager
+}
@@ -6,16 +6,30 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/cdc"
+	"github.com/Hareesh108/haruDB/internal/logging"
+	"github.com/Hareesh108/haruDB/internal/maintenance"
+	"github.com/Hareesh108/haruDB/internal/metrics"
 	"github.com/Hareesh108/haruDB/internal/parser"
+	"github.com/Hareesh108/haruDB/internal/protocol"
+	"github.com/Hareesh108/haruDB/internal/replication"
+	"github.com/Hareesh108/haruDB/internal/storage"
 )
 
 const DB_VERSION string = "v0.0.5"
@@ -37,15 +51,15 @@ func checkPortUsage(port string) {
 	case "windows":
 		cmd = exec.Command("netstat", "-ano", "-p", "TCP", "-f", "inet")
 	default:
-		fmt.Printf("⚠️  Port %s is already in use by another process\n", port)
-		fmt.Printf("   Please stop the other service or use a different port\n")
+		log.Printf("⚠️  Port %s is already in use by another process\n", port)
+		log.Printf("   Please stop the other service or use a different port\n")
 		return
 	}
 
 	output, err := cmd.Output()
 	if err != nil {
-		fmt.Printf("⚠️  Port %s is already in use by another process\n", port)
-		fmt.Printf("   Please stop the other service or use a different port\n")
+		log.Printf("⚠️  Port %s is already in use by another process\n", port)
+		log.Printf("   Please stop the other service or use a different port\n")
 		return
 	}
 
@@ -58,15 +72,15 @@ func checkPortUsage(port string) {
 				if len(parts) > 1 {
 					processName := parts[0]
 					if strings.Contains(strings.ToLower(processName), "harudb") {
-						fmt.Printf("ℹ️  Another HaruDB instance is already running on port %s\n", port)
-						fmt.Printf("   Please stop the existing instance or use a different port\n")
+						log.Printf("ℹ️  Another HaruDB instance is already running on port %s\n", port)
+						log.Printf("   Please stop the existing instance or use a different port\n")
 					} else {
-						fmt.Printf("⚠️  Port %s is already in use by: %s\n", port, processName)
-						fmt.Printf("   Please stop the other service or use a different port\n")
-						fmt.Printf("   Common solutions:\n")
-						fmt.Printf("   - Stop the other service: sudo systemctl stop <service>\n")
-						fmt.Printf("   - Kill the process: sudo kill -9 <PID>\n")
-						fmt.Printf("   - Use a different port: ./harudb --port 54322\n")
+						log.Printf("⚠️  Port %s is already in use by: %s\n", port, processName)
+						log.Printf("   Please stop the other service or use a different port\n")
+						log.Printf("   Common solutions:\n")
+						log.Printf("   - Stop the other service: sudo systemctl stop <service>\n")
+						log.Printf("   - Kill the process: sudo kill -9 <PID>\n")
+						log.Printf("   - Use a different port: ./harudb --port 54322\n")
 					}
 					return
 				}
@@ -74,80 +88,347 @@ func checkPortUsage(port string) {
 		}
 	}
 
-	fmt.Printf("⚠️  Port %s is already in use by another process\n", port)
-	fmt.Printf("   Please stop the other service or use a different port\n")
+	log.Printf("⚠️  Port %s is already in use by another process\n", port)
+	log.Printf("   Please stop the other service or use a different port\n")
+}
+
+// preloadTables loads the comma-separated tables of --preload into memory,
+// logging each one. A table that fails to load is logged and skipped.
+func preloadTables(db *storage.Database, list string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		started := time.Now()
+		result := db.Preload(name)
+		if parser.MessageError(result) != nil {
+			log.Printf("⚠️  Preloading %s failed: %s\n", name, result)
+			continue
+		}
+		log.Printf("🔥 %s in %s\n", result, time.Since(started).Round(time.Millisecond))
+	}
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reset-admin-password" {
+		os.Exit(runResetAdminPassword(os.Args[2:]))
+	}
+
 	dataDir := flag.String("data-dir", "./data", "Directory to store .harudb files")
 	enableTLS := flag.Bool("tls", false, "Enable TLS encryption")
-	port := flag.String("port", "54321", "Port to listen on")
+	adminPasswordFile := flag.String("admin-password-file", "", "Write the admin password generated for a new data directory to this file instead of printing it")
+	port := flag.String("port", "54321", "Port to listen on when no --listen address is given")
+	var listen listenAddrs
+	flag.Var(&listen, "listen", "Address to listen on, e.g. 127.0.0.1:54321, [::1]:54321 or tls://0.0.0.0:54322; repeat for several")
+	cdcWebhook := flag.String("cdc-webhook", "", "Forward committed changes to this HTTP webhook URL")
+	cdcKafkaREST := flag.String("cdc-kafka-rest", "", "Kafka REST Proxy URL to forward committed changes to")
+	cdcKafkaTopic := flag.String("cdc-kafka-topic", "harudb.changes", "Kafka topic for forwarded changes")
+	replicationListen := flag.String("replication-listen", "", "Address to serve replicas on, e.g. :54322 (makes this server a primary)")
+	replicaOf := flag.String("replica-of", "", "Primary replication address to follow (makes this server a read-only replica)")
+	replicationUser := flag.String("replication-user", "admin", "Admin user a replica logs in to the primary as")
+	replicationPassword := flag.String("replication-password", "", "Password for --replication-user")
+	maxScanParallelism := flag.Int("max-scan-parallelism", 0, "Workers per full-table scan (0 = number of CPUs, 1 = serial)")
+	sessionCleanupInterval := flag.Duration("session-cleanup-interval", 10*time.Minute, "How often expired sessions are removed (0 = never)")
+	txCleanupInterval := flag.Duration("tx-cleanup-interval", time.Minute, "How often finished transactions are garbage collected (0 = never)")
+	checkpointInterval := flag.Duration("checkpoint-interval", 5*time.Minute, "How often a WAL checkpoint is written (0 = never)")
+	retentionInterval := flag.Duration("retention-interval", 10*time.Minute, "How often rows beyond table retention policies are deleted (0 = never)")
+	maintenanceJitter := flag.Float64("maintenance-jitter", 0.1, "Fraction by which maintenance intervals are randomized")
+	metricsListen := flag.String("metrics-listen", "", "Address to serve Prometheus metrics on, e.g. :9187 (empty = disabled)")
+	queryMemoryMB := flag.Int64("query-memory-mb", 256, "Memory per query for sorting before spilling to temporary files (0 = unlimited)")
+	encryptTables := flag.Bool("encrypt-tables", false, "Encrypt new tables at rest unless CREATE TABLE says WITH (encrypted=false)")
+	logOutput := flag.String("log-output", "stdout", "Where to log: stdout, stderr, syslog or file")
+	logFile := flag.String("log-file", "", "Log file for --log-output file (default <data-dir>/harudb.log)")
+	logMaxSizeMB := flag.Int64("log-max-size-mb", 100, "Rotate the log file when it reaches this size (0 = never)")
+	logRotateInterval := flag.Duration("log-rotate-interval", 24*time.Hour, "Rotate the log file when it is this old (0 = never)")
+	logMaxFiles := flag.Int("log-max-files", 7, "Rotated log files to keep (0 = all)")
+	minFreeDiskMB := flag.Int64("min-free-disk-mb", 100, "Free space in the data directory below which new writes are refused (0 = never)")
+	walFailurePolicy := flag.String("wal-failure-policy", "warn", "What to do when the WAL cannot be opened: fail-fast (exit), read-only (reject writes) or warn (accept writes with a warning)")
+	writeQueueDepth := flag.Int("write-queue-depth", parser.DefaultWriteQueueDepth, "Writes that may be in flight at once before connections block or fail (0 = unlimited)")
+	querySlots := flag.Int("query-slots", parser.DefaultQuerySlots, "Statements that may run at once; others wait, admins' first (0 = unlimited)")
+	slowQueryThreshold := flag.Duration("slow-query-threshold", 0, "Log statements running at least this long with their query IDs (0 = never)")
+	repairBackupDir := flag.String("repair-backup-dir", "", "Repair pages that fail their checksum from the backups in this directory (empty = never)")
+	repairBackupPassphrase := flag.String("repair-backup-passphrase", "", "Passphrase of encrypted backups in --repair-backup-dir")
+	preload := flag.String("preload", "", "Comma-separated tables to load into memory before accepting statements, as PRELOAD TABLE does")
 	flag.Parse()
 
-	// Check if port is already in use
-	checkPortUsage(*port)
+	if *logFile == "" {
+		*logFile = filepath.Join(*dataDir, "harudb.log")
+	}
+	logWriter, err := logging.Open(logging.Config{
+		Output:      *logOutput,
+		File:        *logFile,
+		MaxSize:     *logMaxSizeMB << 20,
+		RotateEvery: *logRotateInterval,
+		MaxFiles:    *logMaxFiles,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open log: %v", err)
+	}
+	defer logWriter.Close()
+	log.SetOutput(logWriter)
+	if *logOutput == "syslog" {
+		// syslog stamps each message itself
+		log.SetFlags(0)
+	}
+
+	walPolicy, err := storage.ParseWALFailurePolicy(*walFailurePolicy)
+	if err != nil {
+		log.Fatalf("Invalid --wal-failure-policy: %v", err)
+	}
+
+	// Without --listen, serve every interface on --port
+	if len(listen) == 0 {
+		checkPortUsage(*port)
+		listen = listenAddrs{{addr: ":" + *port}}
+	}
 
 	// Make sure the data directory exists
 	if err := os.MkdirAll(*dataDir, 0755); err != nil {
 		log.Fatalf("Failed to create data dir %s: %v", *dataDir, err)
 	}
 
-	// Initialize TLS manager if enabled
-	var tlsManager *auth.TLSManager
-	if *enableTLS {
-		tlsManager = auth.NewTLSManager(*dataDir)
-		if !tlsManager.IsTLSEnabled() {
-			log.Printf("Warning: TLS requested but not properly configured")
+	// Initialize TLS manager if any address is served with TLS
+	var tlsConfig *tls.Config
+	if tlsManager := tlsManagerFor(*dataDir, *enableTLS, listen); tlsManager != nil {
+		tlsConfig = tlsManager.GetTLSConfig()
+		log.Printf("🔒 TLS encryption enabled\n")
+	}
+
+	listeners, err := openListeners(listen, *enableTLS, tlsConfig)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	defer closeListeners(listeners)
+	var addresses []string
+	serveTLS := false
+	for _, a := range listen {
+		withTLS := a.wantsTLS(*enableTLS) && tlsConfig != nil
+		serveTLS = serveTLS || withTLS
+		if withTLS {
+			addresses = append(addresses, "tls://"+a.addr)
 		} else {
-			fmt.Printf("🔒 TLS encryption enabled\n")
+			addresses = append(addresses, a.addr)
 		}
 	}
+	log.Printf("🚀 HaruDB server started on %s (data dir: %s)\n", strings.Join(addresses, ", "), *dataDir)
 
-	var listener net.Listener
-	var err error
+	// Connections are accepted at once but turned away until recovery has
+	// finished and the engine is ready
+	var ready atomic.Pointer[parser.Engine]
+	progress := &storage.RecoveryProgress{}
+	shutdown := make(chan struct{})
+	var serving sync.WaitGroup
+	for _, l := range listeners {
+		serving.Add(1)
+		go func(l net.Listener) {
+			defer serving.Done()
+			serve(l, &ready, progress, shutdown)
+		}(l)
+	}
 
-	if *enableTLS && tlsManager != nil && tlsManager.IsTLSEnabled() {
-		// Create TLS listener
-		tcpListener, err := net.Listen("tcp", ":"+*port)
+	// A new replica copies a base snapshot from its primary before the data
+	// directory is opened
+	if *replicationListen != "" && *replicaOf != "" {
+		log.Fatalf("--replication-listen and --replica-of cannot be combined")
+	}
+	var replica *replication.Replica
+	if *replicaOf != "" {
+		replica, err = replication.NewReplica(*replicaOf, *replicationUser, *replicationPassword, *dataDir)
 		if err != nil {
-			log.Fatalf("Failed to listen on port %s: %v", *port, err)
+			log.Fatalf("Failed to start replica: %v", err)
 		}
-		listener = tls.NewListener(tcpListener, tlsManager.GetTLSConfig())
-		fmt.Printf("🚀 HaruDB server started on port %s with TLS (data dir: %s)\n", *port, *dataDir)
-	} else {
-		// Create regular TCP listener
-		listener, err = net.Listen("tcp", ":"+*port)
-		if err != nil {
-			log.Fatalf("Failed to listen on port %s: %v", *port, err)
+		if replica.NeedsSnapshot() {
+			log.Printf("📥 Bootstrapping replica from %s\n", *replicaOf)
+		}
+		if err := replica.Bootstrap(); err != nil {
+			log.Fatalf("Failed to bootstrap replica: %v", err)
+		}
+	}
+
+	// A new data directory gets a random admin password instead of a
+	// well-known one
+	if password, err := auth.BootstrapAdmin(*dataDir); err != nil {
+		log.Fatalf("Failed to create the admin user: %v", err)
+	} else if password != "" {
+		if err := announceAdminPassword(password, *adminPasswordFile); err != nil {
+			log.Fatalf("%v", err)
 		}
-		fmt.Printf("🚀 HaruDB server started on port %s (data dir: %s)\n", *port, *dataDir)
 	}
-	defer listener.Close()
 
-	engine := parser.NewEngine(*dataDir)
+	recoveryStarted := time.Now()
+	recovered := make(chan struct{})
+	go reportRecovery(progress, recovered)
+	engine := parser.NewEngineWithProgress(*dataDir, progress)
+	close(recovered)
+	log.Printf("✅ Recovery finished in %s: %s\n", time.Since(recoveryStarted).Round(time.Millisecond), progress)
+	if walErr := engine.DB.WALError(); walErr != nil {
+		if walPolicy == storage.WALFailFast {
+			log.Fatalf("WAL is unavailable and --wal-failure-policy is fail-fast: %v", walErr)
+		}
+		log.Printf("⚠️  WAL is unavailable, running degraded (--wal-failure-policy %s)\n", walPolicy)
+	}
+	engine.DB.WALFailurePolicy = walPolicy
+	engine.DB.MinFreeBytes = *minFreeDiskMB << 20
+	engine.Info.Version = DB_VERSION
+	engine.Info.TLS = serveTLS
+	engine.Info.Listen = addresses
+	engine.DB.MaxScanParallelism = *maxScanParallelism
+	engine.DB.QueryMemoryBudget = *queryMemoryMB << 20
+	engine.Writes = parser.NewWriteQueue(*writeQueueDepth)
+	engine.Scheduler = parser.NewScheduler(*querySlots)
+	engine.SlowQueryThreshold = *slowQueryThreshold
+	engine.DB.EncryptTables = *encryptTables
+	engine.DB.SetRepairBackups(*repairBackupDir, *repairBackupPassphrase)
 
-	for {
-		conn, err := listener.Accept()
+	// Start replication
+	if *replicationListen != "" {
+		if err := engine.DB.EnableChangeLog(); err != nil {
+			log.Fatalf("Failed to enable change log for replication: %v", err)
+		}
+		replListener, err := net.Listen("tcp", *replicationListen)
 		if err != nil {
-			log.Printf("Error accepting connection: %v", err)
-			continue
+			log.Fatalf("Failed to listen for replicas on %s: %v", *replicationListen, err)
+		}
+		primary := replication.NewPrimary(engine.DB.Changes, engine.UserManager, engine.BackupManager)
+		engine.Replication = primary
+		go primary.Serve(replListener)
+		log.Printf("🔁 Serving replicas on %s\n", *replicationListen)
+	}
+	if replica != nil {
+		engine.Replication = replica
+		engine.ReadOnly = true
+		go replica.Run(engine.DB, nil)
+		log.Printf("🔁 Replicating from %s (read-only)\n", *replicaOf)
+	}
+
+	// Serve table statistics for Prometheus
+	if *metricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(engine.DB))
+		go func() {
+			if err := http.ListenAndServe(*metricsListen, mux); err != nil {
+				log.Printf("Metrics endpoint stopped: %v", err)
+			}
+		}()
+		log.Printf("📈 Serving metrics on %s/metrics\n", *metricsListen)
+	}
+
+	// Start change data capture sinks
+	var sinks []cdc.Sink
+	if *cdcWebhook != "" {
+		sinks = append(sinks, cdc.NewWebhookSink(*cdcWebhook))
+	}
+	if *cdcKafkaREST != "" {
+		sinks = append(sinks, cdc.NewKafkaSink(*cdcKafkaREST, *cdcKafkaTopic))
+	}
+	if len(sinks) > 0 {
+		if err := engine.DB.EnableChangeLog(); err != nil {
+			log.Fatalf("Failed to enable change capture: %v", err)
+		}
+		for _, sink := range sinks {
+			go cdc.NewForwarder(*dataDir, engine.DB.Changes, sink).Run(nil)
+			log.Printf("📤 Forwarding changes to CDC sink %s\n", sink.Name())
 		}
-		go handleConnection(conn, engine)
+	}
+
+	// Background maintenance
+	scheduler := maintenance.NewScheduler()
+	scheduler.Jitter = *maintenanceJitter
+	scheduler.Add("session cleanup", *sessionCleanupInterval, func() error {
+		engine.UserManager.CleanupExpiredSessions()
+		return nil
+	})
+	scheduler.Add("transaction cleanup", *txCleanupInterval, func() error {
+		engine.DB.TransactionManager.CleanupCompletedTransactions()
+		return nil
+	})
+	scheduler.Add("checkpoint", *checkpointInterval, engine.DB.Checkpoint)
+	scheduler.Add("retention", *retentionInterval, func() error {
+		_, err := engine.DB.EnforceRetention()
+		return err
+	})
+	scheduler.Start()
+
+	// Shut down gracefully on SIGINT/SIGTERM: stop accepting connections,
+	// let running maintenance finish and write a final checkpoint
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("🛑 Received %s, shutting down\n", sig)
+		close(shutdown)
+		closeListeners(listeners)
+	}()
+
+	// Warm the listed tables before the first statement runs
+	preloadTables(engine.DB, *preload)
+
+	ready.Store(engine)
+	serving.Wait()
+
+	scheduler.Stop()
+	if err := engine.DB.Checkpoint(); err != nil {
+		log.Printf("Final checkpoint failed: %v", err)
 	}
 }
 
 func handleConnection(conn net.Conn, engine *parser.Engine) {
 	defer conn.Close()
 
-	fmt.Fprintf(conn, "\nWelcome to HaruDB %s 🎉\n", DB_VERSION)
-	conn.Write([]byte("🔐 Authentication Required\n"))
-	conn.Write([]byte("Default admin: admin / admin123\n"))
-	conn.Write([]byte("Please change the default password after first login!\n\n"))
+	// The client logs in on a session of its own, with its own transaction,
+	// which is rolled back if it is still open when the client goes
+	client := engine.Connect(conn.RemoteAddr().String())
+	defer engine.Disconnect(client)
+	session := client.Engine()
+
+	// Notifications are written between responses, so every write to the
+	// connection holds writeMu
+	var writeMu sync.Mutex
+	write := func(s string) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.Write([]byte(s))
+	}
+	// Streamed output, such as BACKUP TO STDOUT, is written while the
+	// statement runs, with notifications held back until it is done
+	session.SetStream(func(send func(io.Writer) error) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return send(conn)
+	})
+	listener := engine.Notifications.NewListener()
+	defer listener.Close()
+	go func() {
+		for n := range listener.C() {
+			write(protocol.EncodeNotification(n.Channel, n.Payload))
+		}
+	}()
+
+	// Clients learn the server's version and features from the HELLO line
+	_, encrypted := conn.(*tls.Conn)
+	write(protocol.EncodeHello(protocol.Hello{
+		Server:   DB_VERSION,
+		Protocol: protocol.ProtocolVersion,
+		TLS:      encrypted,
+		Features: protocol.ServerFeatures,
+	}))
 
 	scanner := bufio.NewScanner(conn)
+	// queryID is the ID of the statement just answered, sent with the prompt
+	var queryID uint64
 	for {
 		// send prompt with newline
-		conn.Write([]byte("haruDB> \n"))
+		write(protocol.EncodePrompt(queryID))
+		queryID = 0
 
 		if !scanner.Scan() {
 			break
@@ -155,31 +436,108 @@ func handleConnection(conn net.Conn, engine *parser.Engine) {
 		input := strings.TrimSpace(scanner.Text())
 
 		if input == "exit" {
-			conn.Write([]byte("Goodbye 👋\n"))
+			write("Goodbye 👋\n")
 			break
 		}
 
-		// Execute with timeout to prevent hanging
-		resultChan := make(chan string, 1)
+		// LISTEN and UNLISTEN apply to this connection only
+		if result, ok := session.HandleListen(input, listener); ok {
+			write(protocol.EncodeResult(result, parser.MessageError(result)) + "\n")
+			continue
+		}
+
+		// A BATCH header is followed by its statements, one per line
+		var batch []string
+		if n, ok, err := protocol.ParseBatchHeader(input); ok {
+			if err != nil {
+				write(protocol.EncodeResult("", err) + "\n")
+				continue
+			}
+			for len(batch) < n && scanner.Scan() {
+				batch = append(batch, strings.TrimSpace(scanner.Text()))
+			}
+			if len(batch) < n {
+				break
+			}
+		}
+
+		// Statements wait here for an execution slot, in order of priority,
+		// and writes then for a write queue slot, so a connection flooding
+		// the server stops being read until earlier writes finish. The slots
+		// are held until the statement completes, even after a timeout.
+		releaseQuery, rejected := session.AdmitQuery()
+		if rejected != "" {
+			write(protocol.EncodeResult("", parser.MessageError(rejected)) + "\n")
+			continue
+		}
+		statements := batch
+		if statements == nil {
+			statements = []string{input}
+		}
+		releaseWrite, rejected := session.AdmitWrite(statements...)
+		if rejected != "" {
+			releaseQuery()
+			write(protocol.EncodeResult("", parser.MessageError(rejected)) + "\n")
+			continue
+		}
+		release := func() {
+			releaseWrite()
+			releaseQuery()
+		}
+
+		// Execute with the session's statement timeout to prevent hanging.
+		// KILL stops the wait early, as a timeout does.
+		timeout := session.StatementTimeout()
+		var query *parser.RunningQuery
+		if batch != nil {
+			query = session.StartQueryOn(client, fmt.Sprintf("%s %d", protocol.BatchCommand, len(batch)))
+		} else {
+			query = session.StartQueryOn(client, input)
+		}
+		queryID = query.ID
+		type response struct {
+			result string
+			err    error
+		}
+		resultChan := make(chan response, 1)
 		go func() {
-			result := engine.Execute(input)
-			resultChan <- result
+			defer release()
+			var resp response
+			if batch != nil {
+				resp.result, resp.err = session.ExecuteBatchQuery(query, batch)
+			} else {
+				resp.result, resp.err = session.ExecuteQuery(query, input)
+			}
+			resultChan <- resp
 		}()
 
-		var result string
+		var timer *time.Timer
+		var timeoutC <-chan time.Time
+		if timeout > 0 {
+			timer = time.NewTimer(timeout)
+			timeoutC = timer.C
+		}
+		var resp response
 		select {
-		case result = <-resultChan:
-			// Command completed successfully
-		case <-time.After(10 * time.Second):
+		case resp = <-resultChan:
+			// Command completed
+		case <-timeoutC:
 			// Command timed out
-			result = "Error: Command timed out after 10 seconds"
+			resp.err = &protocol.Error{Code: protocol.CodeTimeout, Message: fmt.Sprintf("Error: Command timed out after %s", timeout)}
+		case <-query.Killed():
+			resp.err = parser.MessageError(parser.KilledResult(query.ID))
+		}
+		if timer != nil {
+			timer.Stop()
 		}
 
+		// Failures carry an error code so clients can tell them from data
+		result := protocol.EncodeResult(resp.result, resp.err)
 		if !strings.HasSuffix(result, "\n") {
 			result += "\n"
 		}
 
 		// send result
-		conn.Write([]byte(result))
+		write(result)
 	}
 }
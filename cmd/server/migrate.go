@@ -0,0 +1,105 @@
+// cmd/server/migrate.go
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Hareesh108/haruDB/client"
+	"github.com/Hareesh108/haruDB/internal/migrate"
+)
+
+const migrateUsage = `Usage: harudb migrate [flags] [up|down|status]
+
+  up      Apply pending migrations (default)
+  down    Revert the most recently applied migrations
+  status  List migrations and whether they are applied
+
+Migrations are files in --dir named <version>_<name>.up.sql, with an
+optional <version>_<name>.down.sql; <version>_<name>.sql is an up migration.
+
+Flags:
+`
+
+// runMigrate implements "harudb migrate" against a running server and
+// returns the process exit code
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	host := fs.String("host", "localhost", "Server host")
+	port := fs.String("port", "54321", "Server port")
+	user := fs.String("user", "admin", "User to log in as")
+	password := fs.String("password", os.Getenv("HARUDB_PASSWORD"), "Password (defaults to $HARUDB_PASSWORD)")
+	useTLS := fs.Bool("tls", false, "Connect with TLS")
+	dir := fs.String("dir", "./migrations", "Directory containing migration files")
+	dryRun := fs.Bool("dry-run", false, "Print the statements that would run without executing them")
+	target := fs.Uint64("to", 0, "up: stop after this version (default: all)")
+	steps := fs.Int("steps", 1, "down: number of migrations to revert")
+
+	// Accept the command before or after the flags
+	command := "up"
+	if len(args) > 0 && (args[0] == "up" || args[0] == "down" || args[0] == "status") {
+		command, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		command = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	migrations, err := migrate.Load(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+
+	opts := client.Options{Username: *user, Password: *password}
+	if *useTLS {
+		opts.TLSConfig = &tls.Config{ServerName: *host}
+	}
+	conn, err := client.Dial(net.JoinHostPort(*host, *port), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	defer conn.Close()
+
+	runner := migrate.NewRunner(conn, os.Stdout)
+	runner.DryRun = *dryRun
+	if *dryRun {
+		fmt.Println("Dry run: no statements will be executed")
+	}
+
+	switch command {
+	case "up":
+		err = runner.Up(migrations, *target)
+	case "down":
+		if *steps < 1 {
+			fmt.Fprintf(os.Stderr, "❌ --steps must be at least 1, got %d\n", *steps)
+			return 2
+		}
+		err = runner.Down(migrations, *steps)
+	case "status":
+		err = runner.Status(migrations)
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
@@ -47,6 +47,11 @@ export default defineConfig({
 					items: [
 						{ label: 'Docker', slug: 'guides/docker' },
 						{ label: 'Connect', slug: 'guides/connect' },
+						{ label: 'Replication', slug: 'guides/replication' },
+						{ label: 'Change Data Capture', slug: 'guides/change-data-capture' },
+						{ label: 'Schema Migrations', slug: 'guides/migrations' },
+						{ label: 'Importing Data', slug: 'guides/importing' },
+						{ label: 'Benchmarking', slug: 'guides/benchmarking' },
 					],
 				},
 				{
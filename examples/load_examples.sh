@@ -17,6 +17,12 @@ fi
 echo "✅ HaruDB server is running"
 echo ""
 
+# The admin password is the one the server printed on its first start
+if [ -z "$HARUDB_PASSWORD" ]; then
+    echo "❌ Set HARUDB_PASSWORD to the admin password first"
+    exit 1
+fi
+
 # Function to load example
 load_example() {
     local example_name=$1
@@ -31,7 +37,7 @@ load_example() {
     
     # Create a temporary file with login commands
     temp_file=$(mktemp)
-    echo "LOGIN admin admin123" > "$temp_file"
+    echo "LOGIN admin $HARUDB_PASSWORD" > "$temp_file"
     cat "$sql_file" >> "$temp_file"
     echo "LOGOUT" >> "$temp_file"
     echo "exit" >> "$temp_file"
@@ -84,6 +90,6 @@ echo "🎉 Examples loaded successfully!"
 echo ""
 echo "You can now connect to the database and explore the examples:"
 echo "  ./haru-cli"
-echo "  LOGIN admin admin123"
+echo "  LOGIN admin <password>"
 echo "  SELECT * FROM customers;  # Banking example"
 echo "  SELECT * FROM restaurants;  # Food app example"
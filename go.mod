@@ -2,7 +2,10 @@ module github.com/Hareesh108/haruDB
 
 go 1.24.0
 
-require github.com/peterh/liner v1.2.2
+require (
+	github.com/peterh/liner v1.2.2
+	golang.org/x/text v0.26.0
+)
 
 require (
 	github.com/mattn/go-runewidth v0.0.3 // indirect
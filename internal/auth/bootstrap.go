@@ -0,0 +1,95 @@
+// internal/auth/bootstrap.go
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultAdminUser is the administrator a new data directory starts with
+const DefaultAdminUser = "admin"
+
+// GeneratePassword returns a random password for an administrator
+func GeneratePassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// BootstrapAdmin gives a data directory without a users file one holding
+// the administrator with a random password, and returns the password. A
+// data directory that already has users keeps them, and "" is returned.
+func BootstrapAdmin(dataDir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dataDir, "users.json")); !os.IsNotExist(err) {
+		return "", err
+	}
+	password, err := GeneratePassword()
+	if err != nil {
+		return "", err
+	}
+	if _, err := CreateAdmin(dataDir, password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// CreateAdmin gives a data directory without a users file one holding the
+// administrator with password, and reports whether it did. A data directory
+// that already has users keeps them.
+func CreateAdmin(dataDir, password string) (bool, error) {
+	usersFile := filepath.Join(dataDir, "users.json")
+	if _, err := os.Stat(usersFile); !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	um := &UserManager{users: make(map[string]*User), sessions: make(map[string]*Session), usersFile: usersFile}
+	um.users[DefaultAdminUser] = &User{
+		Username:     DefaultAdminUser,
+		PasswordHash: um.hashPassword(password),
+		Role:         RoleAdmin,
+		CreatedAt:    time.Now(),
+		IsActive:     true,
+	}
+	if err := um.saveUsers(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResetAdminPassword gives the administrator of a data directory a new
+// random password and returns it, enabling the account again, or creating
+// it if it was deleted. The server must be stopped: a running server keeps
+// its users in memory and would overwrite the change.
+func ResetAdminPassword(dataDir string) (string, error) {
+	if info, err := os.Stat(dataDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("no data directory at %s", dataDir)
+	}
+	um := &UserManager{users: make(map[string]*User), sessions: make(map[string]*Session), usersFile: filepath.Join(dataDir, "users.json")}
+	if err := um.loadUsers(); err != nil {
+		return "", err
+	}
+	password, err := GeneratePassword()
+	if err != nil {
+		return "", err
+	}
+	admin := um.users[DefaultAdminUser]
+	if admin == nil {
+		admin = &User{Username: DefaultAdminUser, CreatedAt: time.Now()}
+		um.users[DefaultAdminUser] = admin
+	}
+	admin.PasswordHash = um.hashPassword(password)
+	admin.Role = RoleAdmin
+	admin.IsActive = true
+	if err := um.saveUsers(); err != nil {
+		return "", err
+	}
+	return password, nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBootstrapAdmin(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	password, err := BootstrapAdmin(dir)
+	if err != nil || len(password) != 24 {
+		t.Fatalf("BootstrapAdmin = %q, %v", password, err)
+	}
+	um := NewUserManager(dir)
+	if _, err := um.AuthenticateUser(DefaultAdminUser, password); err != nil {
+		t.Errorf("generated password rejected: %v", err)
+	}
+	if _, err := um.AuthenticateUser(DefaultAdminUser, "admin123"); err == nil {
+		t.Error("the well-known default password was accepted")
+	}
+
+	// A data directory with users keeps them
+	if again, err := BootstrapAdmin(dir); again != "" || err != nil {
+		t.Errorf("second BootstrapAdmin = %q, %v", again, err)
+	}
+	if created, err := CreateAdmin(dir, "admin123"); created || err != nil {
+		t.Errorf("CreateAdmin over existing users = %v, %v", created, err)
+	}
+}
+
+func TestNoDefaultAdmin(t *testing.T) {
+	dir := t.TempDir()
+	um := NewUserManager(dir)
+	if users := um.ListUsers(); len(users) != 0 {
+		t.Errorf("a data directory without users got %v", users)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users.json")); !os.IsNotExist(err) {
+		t.Error("a users file was written for a data directory without users")
+	}
+
+	// A restore without users keeps the users the server has
+	if _, err := CreateAdmin(dir, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "users.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := um.AuthenticateUser(DefaultAdminUser, "secret"); err != nil {
+		t.Errorf("users lost by restoring a backup without users: %v", err)
+	}
+	if _, err := NewUserManager(dir).AuthenticateUser(DefaultAdminUser, "secret"); err != nil {
+		t.Errorf("kept users not written back: %v", err)
+	}
+}
+
+func TestResetAdminPassword(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := BootstrapAdmin(dir); err != nil {
+		t.Fatal(err)
+	}
+	um := NewUserManager(dir)
+	if err := um.CreateUser("ops", "secret", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.DeleteUser(DefaultAdminUser); err != nil {
+		t.Fatal(err)
+	}
+
+	password, err := ResetAdminPassword(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewUserManager(dir)
+	user, err := reloaded.AuthenticateUser(DefaultAdminUser, password)
+	if err != nil || user.Role != RoleAdmin {
+		t.Errorf("reset admin: %+v, %v", user, err)
+	}
+	if _, err := reloaded.AuthenticateUser("ops", "secret"); err != nil {
+		t.Errorf("other users must be kept: %v", err)
+	}
+
+	if _, err := ResetAdminPassword(filepath.Join(dir, "missing")); err == nil {
+		t.Error("reset in a missing data directory succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Error("reset created a data directory")
+	}
+}
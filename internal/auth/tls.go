@@ -9,6 +9,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"log"
 	"math/big"
 	"net"
 	"os"
@@ -36,7 +37,7 @@ func NewTLSManager(dataDir string) *TLSManager {
 	// Generate self-signed certificate if it doesn't exist
 	if !tm.certificateExists() {
 		if err := tm.generateSelfSignedCert(); err != nil {
-			fmt.Printf("Warning: Failed to generate self-signed certificate: %v\n", err)
+			log.Printf("Warning: Failed to generate self-signed certificate: %v\n", err)
 		}
 	}
 
@@ -125,7 +126,7 @@ func (tm *TLSManager) generateSelfSignedCert() error {
 func (tm *TLSManager) loadTLSConfig() {
 	cert, err := tls.LoadX509KeyPair(tm.certFile, tm.keyFile)
 	if err != nil {
-		fmt.Printf("Warning: Failed to load TLS certificate: %v\n", err)
+		log.Printf("Warning: Failed to load TLS certificate: %v\n", err)
 		tm.config = nil
 		return
 	}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -40,9 +41,40 @@ type Session struct {
 	CreatedAt  time.Time
 	LastAccess time.Time
 	IsActive   bool
+
+	// settings holds SET variables for this session
+	settings   map[string]string
+	settingsMu sync.Mutex
+}
+
+// Setting returns a session variable set with SetSetting
+func (s *Session) Setting(name string) (string, bool) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	value, ok := s.settings[name]
+	return value, ok
+}
+
+// SetSetting stores a session variable
+func (s *Session) SetSetting(name, value string) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	if s.settings == nil {
+		s.settings = make(map[string]string)
+	}
+	s.settings[name] = value
+}
+
+// ResetSetting removes a session variable so its default applies again
+func (s *Session) ResetSetting(name string) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	delete(s.settings, name)
 }
 
-// UserManager handles user authentication and management
+// UserManager handles user authentication and management. Every method
+// that changes users or sessions, including logging in, holds mu
+// exclusively, and users.json is only written under it.
 type UserManager struct {
 	users     map[string]*User
 	sessions  map[string]*Session
@@ -60,41 +92,27 @@ func NewUserManager(dataDir string) *UserManager {
 		usersFile: usersFile,
 	}
 
-	// Load existing users
-	um.loadUsers()
-
-	// Create default admin user if no users exist
-	if len(um.users) == 0 {
-		um.createDefaultAdmin()
+	// Load existing users. A users file that cannot be read is left alone
+	// rather than replaced. A data directory without users gets its admin
+	// from BootstrapAdmin.
+	if err := um.loadUsers(); err != nil {
+		log.Printf("Warning: %v; no user can log in until it is fixed\n", err)
 	}
 
 	return um
 }
 
-// createDefaultAdmin creates a default admin user
-func (um *UserManager) createDefaultAdmin() {
-	adminUser := &User{
-		Username:     "admin",
-		PasswordHash: um.hashPassword("admin123"),
-		Role:         RoleAdmin,
-		CreatedAt:    time.Now(),
-		IsActive:     true,
-	}
-
-	um.users["admin"] = adminUser
-	um.saveUsers()
-}
-
 // hashPassword hashes a password using SHA-256
 func (um *UserManager) hashPassword(password string) string {
 	hash := sha256.Sum256([]byte(password))
 	return hex.EncodeToString(hash[:])
 }
 
-// AuthenticateUser authenticates a user with username and password
+// AuthenticateUser authenticates a user with username and password and
+// records the login. It returns a copy of the user.
 func (um *UserManager) AuthenticateUser(username, password string) (*User, error) {
-	um.mu.RLock()
-	defer um.mu.RUnlock()
+	um.mu.Lock()
+	defer um.mu.Unlock()
 
 	user, exists := um.users[username]
 	if !exists {
@@ -109,11 +127,12 @@ func (um *UserManager) AuthenticateUser(username, password string) (*User, error
 		return nil, fmt.Errorf("invalid password")
 	}
 
-	// Update last login
+	// Update last login. Failing to save it does not fail the login.
 	user.LastLogin = time.Now()
 	um.saveUsers()
 
-	return user, nil
+	userCopy := *user
+	return &userCopy, nil
 }
 
 // CreateSession creates a new session for a user
@@ -139,8 +158,8 @@ func (um *UserManager) CreateSession(user *User) (*Session, error) {
 
 // ValidateSession validates a session ID
 func (um *UserManager) ValidateSession(sessionID string) (*Session, error) {
-	um.mu.RLock()
-	defer um.mu.RUnlock()
+	um.mu.Lock()
+	defer um.mu.Unlock()
 
 	session, exists := um.sessions[sessionID]
 	if !exists {
@@ -175,7 +194,11 @@ func (um *UserManager) CreateUser(username, password string, role UserRole) erro
 	}
 
 	um.users[username] = user
-	return um.saveUsers()
+	if err := um.saveUsers(); err != nil {
+		delete(um.users, username)
+		return err
+	}
+	return nil
 }
 
 // DeleteUser deletes a user
@@ -183,11 +206,16 @@ func (um *UserManager) DeleteUser(username string) error {
 	um.mu.Lock()
 	defer um.mu.Unlock()
 
-	if _, exists := um.users[username]; !exists {
+	user, exists := um.users[username]
+	if !exists {
 		return fmt.Errorf("user not found")
 	}
 
 	delete(um.users, username)
+	if err := um.saveUsers(); err != nil {
+		um.users[username] = user
+		return err
+	}
 
 	// Remove all sessions for this user
 	for sessionID, session := range um.sessions {
@@ -196,7 +224,7 @@ func (um *UserManager) DeleteUser(username string) error {
 		}
 	}
 
-	return um.saveUsers()
+	return nil
 }
 
 // ListUsers returns a list of all users
@@ -222,13 +250,14 @@ func (um *UserManager) generateSessionID() string {
 	return hex.EncodeToString(bytes)
 }
 
-// loadUsers loads users from file
+// loadUsers replaces the users with those in the users file. On error the
+// users are left as they were.
 func (um *UserManager) loadUsers() error {
-	if _, err := os.Stat(um.usersFile); os.IsNotExist(err) {
-		return nil // File doesn't exist, start with empty users
-	}
-
 	data, err := os.ReadFile(um.usersFile)
+	if os.IsNotExist(err) {
+		um.users = make(map[string]*User) // File doesn't exist, start with empty users
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read users file: %w", err)
 	}
@@ -237,22 +266,70 @@ func (um *UserManager) loadUsers() error {
 	if err := json.Unmarshal(data, &users); err != nil {
 		return fmt.Errorf("failed to unmarshal users: %w", err)
 	}
+	if users == nil {
+		users = make(map[string]*User)
+	}
 
 	um.users = users
 	return nil
 }
 
-// saveUsers saves users to file
+// Reload re-reads users from disk, e.g. after a restore replaced users.json.
+// Active sessions are kept, and so are the users when the file cannot be
+// read or holds none, as after restoring a backup without users.
+func (um *UserManager) Reload() error {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	previous := um.users
+	if err := um.loadUsers(); err != nil {
+		return err
+	}
+	if len(um.users) == 0 && len(previous) > 0 {
+		um.users = previous
+		return um.saveUsers()
+	}
+	return nil
+}
+
+// saveUsers replaces the users file atomically: the users are written to a
+// temporary file, synced and renamed over it, so a crash leaves either the
+// old or the new file. The caller holds um.mu exclusively.
 func (um *UserManager) saveUsers() error {
 	data, err := json.MarshalIndent(um.users, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal users: %w", err)
 	}
 
-	if err := os.WriteFile(um.usersFile, data, 0600); err != nil {
+	dir := filepath.Dir(um.usersFile)
+	f, err := os.CreateTemp(dir, filepath.Base(um.usersFile)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write users file: %w", err)
+	}
+	tmpPath := f.Name()
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, um.usersFile)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write users file: %w", err)
 	}
 
+	// Sync the directory so the rename survives a crash
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to sync users file: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync users file: %w", err)
+	}
 	return nil
 }
 
@@ -293,6 +370,11 @@ func (um *UserManager) UpdateUserPassword(username, newPassword string) error {
 		return fmt.Errorf("user not found")
 	}
 
+	oldHash := user.PasswordHash
 	user.PasswordHash = um.hashPassword(newPassword)
-	return um.saveUsers()
+	if err := um.saveUsers(); err != nil {
+		user.PasswordHash = oldHash
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentLogins(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := CreateAdmin(dir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	um := NewUserManager(dir)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				user, err := um.AuthenticateUser("admin", "admin123")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := um.CreateSession(user); err != nil {
+					t.Error(err)
+				}
+				if j == 0 {
+					if err := um.CreateUser(fmt.Sprintf("u%d", i), "pw", RoleUser); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Only users.json is left, and it holds every user
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "users.json" {
+		t.Errorf("data directory holds %v", entries)
+	}
+	if got := len(NewUserManager(dir).ListUsers()); got != 9 {
+		t.Errorf("reloaded %d users, want 9", got)
+	}
+}
+
+func TestUnreadableUsersFileKept(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(path, []byte(`{"admin": {`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	um := NewUserManager(dir)
+	if _, err := um.AuthenticateUser("admin", "admin123"); err == nil {
+		t.Error("default admin created over an unreadable users file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"admin": {` {
+		t.Errorf("users file rewritten: %s", data)
+	}
+
+	// Reload keeps the users it has when the file cannot be read
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateAdmin(dir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Reload(); err == nil {
+		t.Error("Reload accepted garbage")
+	}
+	if _, err := um.AuthenticateUser("admin", "admin123"); err != nil {
+		t.Errorf("users lost by a failed Reload: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// internal/bench/bench.go
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Workloads lists the supported workloads in the order they run
+var Workloads = []string{"insert", "select", "update"}
+
+// Executor runs one statement and returns the server's response, or an
+// error if the server rejects the statement
+type Executor interface {
+	Exec(statement string) (string, error)
+}
+
+// Config describes a benchmark run
+type Config struct {
+	// Workloads to measure, a subset of Workloads
+	Workloads []string
+	// Threads is the number of concurrent workers
+	Threads int
+	// Rows is the number of operations per workload; insert loads this many rows
+	Rows int
+	// ValueSize is the length in bytes of the stored value column
+	ValueSize int
+	// Table is created before the run and dropped afterwards unless Keep is set
+	Table string
+	Keep  bool
+}
+
+// Result is the measurement of one workload
+type Result struct {
+	Workload string
+	Ops      int
+	Errors   int
+	Elapsed  time.Duration
+	// Latency percentiles of successful operations
+	P50, P95, P99, Max time.Duration
+}
+
+// Throughput returns operations per second
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Ops) / r.Elapsed.Seconds()
+}
+
+// Run executes the configured workloads. open is called once per worker and
+// once more for setup and teardown. The table is always loaded before select
+// and update run, even when insert is not measured.
+func Run(cfg Config, open func() (Executor, error)) ([]Result, error) {
+	if cfg.Threads < 1 || cfg.Rows < 1 {
+		return nil, fmt.Errorf("threads and rows must be at least 1")
+	}
+	for _, w := range cfg.Workloads {
+		if !isWorkload(w) {
+			return nil, fmt.Errorf("unknown workload %q (want %s)", w, strings.Join(Workloads, ", "))
+		}
+	}
+
+	admin, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if !cfg.Keep {
+			admin.Exec("DROP TABLE " + cfg.Table)
+		}
+		closeExecutor(admin)
+	}()
+	admin.Exec("DROP TABLE " + cfg.Table)
+	for _, stmt := range []string{
+		fmt.Sprintf("CREATE TABLE %s (id, val)", cfg.Table),
+		fmt.Sprintf("CREATE INDEX ON %s (id)", cfg.Table),
+	} {
+		if err := execChecked(admin, stmt); err != nil {
+			return nil, fmt.Errorf("setup failed: %w", err)
+		}
+	}
+
+	workers := make([]Executor, cfg.Threads)
+	for i := range workers {
+		if workers[i], err = open(); err != nil {
+			return nil, err
+		}
+		defer closeExecutor(workers[i])
+	}
+
+	var results []Result
+	for _, name := range Workloads {
+		measured := contains(cfg.Workloads, name)
+		if !measured && name != "insert" {
+			continue
+		}
+		res := runWorkload(name, cfg, workers)
+		if measured {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// runWorkload runs cfg.Rows operations of one workload split across workers
+func runWorkload(name string, cfg Config, workers []Executor) Result {
+	value := strings.Repeat("x", cfg.ValueSize)
+	statement := func(i int, rng *rand.Rand) string {
+		switch name {
+		case "insert":
+			return fmt.Sprintf("INSERT INTO %s VALUES ('%d', '%s')", cfg.Table, i, value)
+		case "select":
+			return fmt.Sprintf("SELECT * FROM %s WHERE id = '%d'", cfg.Table, rng.IntN(cfg.Rows))
+		default:
+			return fmt.Sprintf("UPDATE %s SET val = '%s' ROW %d", cfg.Table, value, rng.IntN(cfg.Rows))
+		}
+	}
+
+	latencies := make([][]time.Duration, len(workers))
+	errors := make([]int, len(workers))
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w, exec := range workers {
+		wg.Add(1)
+		go func(w int, exec Executor) {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(w), uint64(start.UnixNano())))
+			// Worker w runs operations w, w+threads, w+2*threads, ...
+			for i := w; i < cfg.Rows; i += len(workers) {
+				opStart := time.Now()
+				if err := execChecked(exec, statement(i, rng)); err != nil {
+					errors[w]++
+					continue
+				}
+				latencies[w] = append(latencies[w], time.Since(opStart))
+			}
+		}(w, exec)
+	}
+	wg.Wait()
+
+	res := Result{Workload: name, Ops: cfg.Rows, Elapsed: time.Since(start)}
+	var all []time.Duration
+	for w := range workers {
+		all = append(all, latencies[w]...)
+		res.Errors += errors[w]
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	res.P50 = percentile(all, 50)
+	res.P95 = percentile(all, 95)
+	res.P99 = percentile(all, 99)
+	res.Max = percentile(all, 100)
+	return res
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	return sorted[max(i, 0)]
+}
+
+// Report writes results as a table
+func Report(out io.Writer, results []Result) {
+	fmt.Fprintf(out, "%-8s %8s %7s %10s %12s %10s %10s %10s %10s\n",
+		"workload", "ops", "errors", "elapsed", "ops/sec", "p50", "p95", "p99", "max")
+	for _, r := range results {
+		fmt.Fprintf(out, "%-8s %8d %7d %10s %12.0f %10s %10s %10s %10s\n",
+			r.Workload, r.Ops, r.Errors, r.Elapsed.Round(time.Millisecond), r.Throughput(),
+			round(r.P50), round(r.P95), round(r.P99), round(r.Max))
+	}
+}
+
+// round shortens a latency for display
+func round(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// execChecked runs a statement, discarding its response
+func execChecked(exec Executor, statement string) error {
+	_, err := exec.Exec(statement)
+	return err
+}
+
+func closeExecutor(exec Executor) {
+	if c, ok := exec.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+func isWorkload(name string) bool {
+	return contains(Workloads, name)
+}
+
+func contains(list []string, name string) bool {
+	for _, s := range list {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/parser"
+)
+
+// engineExec runs statements on an in-process engine
+type engineExec struct {
+	engine *parser.Engine
+}
+
+func (e engineExec) Exec(statement string) (string, error) {
+	return e.engine.Exec(statement)
+}
+
+// countingExec records statements and fails updates
+type countingExec struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (c *countingExec) Exec(statement string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, statement)
+	if strings.HasPrefix(statement, "UPDATE") {
+		return "", errors.New("row not found")
+	}
+	return "ok", nil
+}
+
+func TestRunEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := auth.CreateAdmin(dir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	engine := parser.NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+	open := func() (Executor, error) { return engineExec{engine}, nil }
+
+	results, err := Run(Config{Workloads: Workloads, Threads: 3, Rows: 20, ValueSize: 8, Table: "bench"}, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Ops != 20 || r.Errors != 0 {
+			t.Errorf("%s: ops=%d errors=%d", r.Workload, r.Ops, r.Errors)
+		}
+		if r.P50 <= 0 || r.P50 > r.P99 || r.P99 > r.Max {
+			t.Errorf("%s: bad percentiles p50=%v p99=%v max=%v", r.Workload, r.P50, r.P99, r.Max)
+		}
+	}
+	if out := engine.Execute("SELECT * FROM bench"); !strings.Contains(out, "not found") {
+		t.Errorf("benchmark table should be dropped, got %.60s", out)
+	}
+
+	var buf bytes.Buffer
+	Report(&buf, results)
+	if !strings.HasPrefix(buf.String(), "workload") || strings.Count(buf.String(), "\n") != 4 {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+}
+
+func TestRunLoadsBeforeSelectAndCountsErrors(t *testing.T) {
+	exec := &countingExec{}
+	open := func() (Executor, error) { return exec, nil }
+
+	results, err := Run(Config{Workloads: []string{"update"}, Threads: 2, Rows: 5, Table: "t", Keep: true}, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Workload != "update" || results[0].Errors != 5 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	inserts := 0
+	for _, s := range exec.statements {
+		if strings.HasPrefix(s, "INSERT") {
+			inserts++
+		}
+	}
+	if inserts != 5 {
+		t.Errorf("expected the table to be loaded with 5 rows, got %d inserts", inserts)
+	}
+	if last := exec.statements[len(exec.statements)-1]; strings.HasPrefix(last, "DROP") {
+		t.Error("Keep should leave the table in place")
+	}
+
+	if _, err := Run(Config{Workloads: []string{"delete"}, Threads: 1, Rows: 1}, open); err == nil {
+		t.Error("expected unknown workload error")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	for p, want := range map[int]time.Duration{50: 50, 95: 95, 99: 99, 100: 100} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("p%d = %v, want %v", p, got, want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("empty percentile = %v", got)
+	}
+}
@@ -0,0 +1,141 @@
+// internal/cdc/forwarder.go
+package cdc
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// Forwarder ships committed changes from the change log to a sink. Its
+// position is persisted in a cursor file and only advanced after the sink
+// acknowledges a batch, so delivery is at-least-once across restarts. The
+// position is acknowledged to the change log, which keeps the changes the
+// sink has not received yet.
+type Forwarder struct {
+	changes      *storage.ChangeLog
+	sink         Sink
+	cursorPath   string
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// NewForwarder creates a forwarder for sink whose cursor lives in dataDir
+func NewForwarder(dataDir string, changes *storage.ChangeLog, sink Sink) *Forwarder {
+	f := &Forwarder{
+		changes:      changes,
+		sink:         sink,
+		cursorPath:   filepath.Join(dataDir, "cdc_"+sink.Name()+".cursor"),
+		BatchSize:    defaultBatchSize,
+		PollInterval: defaultPollInterval,
+	}
+	cursor, err := f.Cursor()
+	if err != nil {
+		log.Printf("CDC sink %s: %v", sink.Name(), err)
+	}
+	f.acknowledge(cursor)
+	return f
+}
+
+// Run forwards changes until stop is closed, backing off on sink failures
+func (f *Forwarder) Run(stop <-chan struct{}) {
+	backoff := f.PollInterval
+	for {
+		n, err := f.ForwardOnce()
+		wait := f.PollInterval
+		switch {
+		case err != nil:
+			log.Printf("CDC sink %s: %v (retrying in %s)", f.sink.Name(), err, backoff)
+			wait = backoff
+			backoff = min(backoff*2, maxRetryBackoff)
+		case n > 0:
+			// More changes may be waiting, keep draining
+			wait = 0
+			backoff = f.PollInterval
+		default:
+			backoff = f.PollInterval
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ForwardOnce delivers the next batch of changes after the cursor and
+// returns how many were acknowledged
+func (f *Forwarder) ForwardOnce() (int, error) {
+	cursor, err := f.Cursor()
+	if err != nil {
+		return 0, err
+	}
+
+	events, err := f.changes.ReadAfter(cursor, f.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := f.sink.Send(events); err != nil {
+		return 0, err
+	}
+
+	if err := f.saveCursor(events[len(events)-1].Seq); err != nil {
+		return 0, err
+	}
+	f.acknowledge(events[len(events)-1].Seq)
+	return len(events), nil
+}
+
+// acknowledge tells the change log the sink has every change up to seq, so
+// they can be truncated. A failed truncation is retried on the next batch.
+func (f *Forwarder) acknowledge(seq uint64) {
+	if err := f.changes.Acknowledge("cdc:"+f.sink.Name(), seq); err != nil {
+		log.Printf("CDC sink %s: %v", f.sink.Name(), err)
+	}
+}
+
+// Cursor returns the sequence number of the last acknowledged change
+func (f *Forwarder) Cursor() (uint64, error) {
+	data, err := os.ReadFile(f.cursorPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read CDC cursor: %w", err)
+	}
+
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CDC cursor %s: %w", f.cursorPath, err)
+	}
+	return seq, nil
+}
+
+// saveCursor atomically persists the acknowledged sequence number
+func (f *Forwarder) saveCursor(seq uint64) error {
+	tmpPath := f.cursorPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.FormatUint(seq, 10)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write CDC cursor: %w", err)
+	}
+	if err := os.Rename(tmpPath, f.cursorPath); err != nil {
+		return fmt.Errorf("failed to save CDC cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package cdc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// recordingSink collects delivered events and can be made to fail
+type recordingSink struct {
+	events []storage.ChangeEvent
+	fail   bool
+}
+
+func (s *recordingSink) Name() string { return "test" }
+
+func (s *recordingSink) Send(events []storage.ChangeEvent) error {
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func TestForwarderDeliversCommittedChanges(t *testing.T) {
+	dataDir := t.TempDir()
+	db := storage.NewDatabase(dataDir)
+	defer db.Close()
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatalf("failed to enable change log: %v", err)
+	}
+
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.Insert("users", []string{"1", "Alice"})
+	_ = db.Update("users", 0, []string{"1", "Alicia"})
+
+	sink := &recordingSink{fail: true}
+	fwd := NewForwarder(dataDir, db.Changes, sink)
+
+	// A failed delivery must not advance the cursor
+	if _, err := fwd.ForwardOnce(); err == nil {
+		t.Fatal("expected delivery to fail")
+	}
+	if cursor, _ := fwd.Cursor(); cursor != 0 {
+		t.Fatalf("cursor advanced to %d after failed delivery", cursor)
+	}
+
+	sink.fail = false
+	n, err := fwd.ForwardOnce()
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 changes, got %d", n)
+	}
+	wantOps := []string{storage.ChangeCreateTable, storage.ChangeInsert, storage.ChangeUpdate}
+	for i, op := range wantOps {
+		if sink.events[i].Op != op || sink.events[i].Seq != uint64(i+1) {
+			t.Errorf("event %d = %s seq %d, want %s seq %d", i, sink.events[i].Op, sink.events[i].Seq, op, i+1)
+		}
+	}
+	if got := sink.events[2].OldValues; len(got) != 2 || got[1] != "Alice" {
+		t.Errorf("update should carry old values, got %v", got)
+	}
+
+	// The cursor survives a restart of the forwarder
+	_ = db.Delete("users", 0)
+	fwd = NewForwarder(dataDir, db.Changes, sink)
+	if n, err := fwd.ForwardOnce(); err != nil || n != 1 {
+		t.Fatalf("expected 1 new change after restart, got %d (%v)", n, err)
+	}
+	if sink.events[3].Op != storage.ChangeDelete {
+		t.Errorf("expected DELETE, got %s", sink.events[3].Op)
+	}
+}
+
+func TestForwarderAcknowledgementsTruncateChangeLog(t *testing.T) {
+	dataDir := t.TempDir()
+	db := storage.NewDatabase(dataDir)
+	defer db.Close()
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatalf("failed to enable change log: %v", err)
+	}
+	db.Changes.CompactAfter = 2
+
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.Insert("users", []string{"1", "Alice"})
+	_ = db.Insert("users", []string{"2", "Bob"})
+
+	sink := &recordingSink{}
+	fwd := NewForwarder(dataDir, db.Changes, sink)
+	if n, err := fwd.ForwardOnce(); err != nil || n != 3 {
+		t.Fatalf("expected 3 changes, got %d (%v)", n, err)
+	}
+	// The newest change is kept so sequence numbers continue
+	if first := db.Changes.FirstSeq(); first != 3 {
+		t.Errorf("expected delivered changes truncated up to 3, first is %d", first)
+	}
+
+	_ = db.Delete("users", 0)
+	if n, err := fwd.ForwardOnce(); err != nil || n != 1 {
+		t.Fatalf("expected 1 new change, got %d (%v)", n, err)
+	}
+	if got := sink.events[3]; got.Seq != 4 || got.Op != storage.ChangeDelete {
+		t.Errorf("expected DELETE seq 4, got %s seq %d", got.Op, got.Seq)
+	}
+}
+
+func TestKafkaSinkUsesRESTProxyFormat(t *testing.T) {
+	var gotPath, gotType string
+	var body struct {
+		Records []struct {
+			Key   string              `json:"key"`
+			Value storage.ChangeEvent `json:"value"`
+		} `json:"records"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotType = r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":41,"error_code":null,"error":null}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaSink(server.URL, "harudb.changes")
+	err := sink.Send([]storage.ChangeEvent{{Seq: 7, Op: storage.ChangeInsert, Table: "orders"}})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if gotPath != "/topics/harudb.changes" {
+		t.Errorf("unexpected path %s", gotPath)
+	}
+	if gotType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("unexpected content type %s", gotType)
+	}
+	if len(body.Records) != 1 || body.Records[0].Key != "orders" || body.Records[0].Value.Seq != 7 {
+		t.Errorf("unexpected records %+v", body.Records)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if err := NewWebhookSink(failing.URL).Send([]storage.ChangeEvent{{Seq: 1}}); err == nil {
+		t.Error("expected non-2xx response to fail delivery")
+	}
+}
+
+func TestKafkaSinkFailsOnRecordErrors(t *testing.T) {
+	batch := []storage.ChangeEvent{{Seq: 1, Table: "orders"}, {Seq: 2, Table: "orders"}}
+	for reply, ok := range map[string]bool{
+		`{"offsets":[{"partition":0,"offset":1},{"partition":0,"offset":2}]}`:                                                           true,
+		`{"offsets":[{"partition":0,"offset":1},{"partition":null,"offset":null,"error_code":50003,"error":"Kafka error: timed out"}]}`: false,
+		`{"offsets":[{"partition":0,"offset":1},{"error":"Kafka error: unknown topic"}]}`:                                               false,
+		`{"offsets":[{"partition":0,"offset":1}]}`:                                                                                      false,
+		`not json`: false,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(reply))
+		}))
+		err := NewKafkaSink(server.URL, "harudb.changes").Send(batch)
+		server.Close()
+		if (err == nil) != ok {
+			t.Errorf("%s: got %v, want delivered=%v", reply, err, ok)
+		}
+	}
+}
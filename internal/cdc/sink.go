@@ -0,0 +1,144 @@
+// internal/cdc/sink.go
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// Sink delivers batches of committed changes to an external system. Send must
+// only return nil once the whole batch has been accepted.
+type Sink interface {
+	// Name identifies the sink; it also names the sink's cursor file
+	Name() string
+	Send(events []storage.ChangeEvent) error
+}
+
+// defaultHTTPTimeout bounds a single delivery attempt
+const defaultHTTPTimeout = 10 * time.Second
+
+// WebhookSink POSTs each batch as {"events": [...]} to an HTTP endpoint.
+// Any 2xx response acknowledges the batch.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a sink that posts changes to url
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, Client: &http.Client{Timeout: defaultHTTPTimeout}}
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send posts a batch of events to the webhook
+func (s *WebhookSink) Send(events []storage.ChangeEvent) error {
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+	_, err = post(s.Client, s.URL, "application/json", body)
+	return err
+}
+
+// KafkaSink produces each change as a record on a Kafka topic through the
+// Kafka REST Proxy (v2 API), keyed by table name so that changes to the same
+// table stay ordered within a partition. The proxy answers 200 even when
+// records fail, so a batch is only delivered once every record has an
+// offset without an error.
+type KafkaSink struct {
+	RESTURL string
+	Topic   string
+	Client  *http.Client
+}
+
+// NewKafkaSink creates a sink producing to topic via the REST proxy at restURL
+func NewKafkaSink(restURL, topic string) *KafkaSink {
+	return &KafkaSink{RESTURL: restURL, Topic: topic, Client: &http.Client{Timeout: defaultHTTPTimeout}}
+}
+
+// Name returns the sink name
+func (s *KafkaSink) Name() string {
+	return "kafka-" + s.Topic
+}
+
+// Send produces a batch of events to the topic
+func (s *KafkaSink) Send(events []storage.ChangeEvent) error {
+	type record struct {
+		Key   string              `json:"key"`
+		Value storage.ChangeEvent `json:"value"`
+	}
+	records := make([]record, len(events))
+	for i, ev := range events {
+		records[i] = record{Key: ev.Table, Value: ev}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	endpoint := strings.TrimRight(s.RESTURL, "/") + "/topics/" + url.PathEscape(s.Topic)
+	reply, err := post(s.Client, endpoint, "application/vnd.kafka.json.v2+json", body)
+	if err != nil {
+		return err
+	}
+	return checkOffsets(reply, len(records))
+}
+
+// checkOffsets fails a produce request unless the proxy's reply holds an
+// offset for each of n records and none of them failed
+func checkOffsets(reply []byte, n int) error {
+	var produced struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(reply, &produced); err != nil {
+		return fmt.Errorf("failed to decode produce response: %w", err)
+	}
+	if len(produced.Offsets) != n {
+		return fmt.Errorf("sink acknowledged %d of %d records", len(produced.Offsets), n)
+	}
+	for i, o := range produced.Offsets {
+		if o.ErrorCode != nil || o.Error != "" {
+			code := 0
+			if o.ErrorCode != nil {
+				code = *o.ErrorCode
+			}
+			return fmt.Errorf("sink rejected record %d: error %d: %s", i, code, o.Error)
+		}
+	}
+	return nil
+}
+
+// post sends body to endpoint and returns the response body, treating any
+// non-2xx status as a failure
+func post(client *http.Client, endpoint, contentType string, body []byte) ([]byte, error) {
+	resp, err := client.Post(endpoint, contentType, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deliver changes: %w", err)
+	}
+	defer resp.Body.Close()
+	reply, err := io.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("sink rejected changes: %s", resp.Status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sink response: %w", err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,103 @@
+// internal/importer/importer_test.go
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/parser"
+)
+
+// engineExecutor runs statements directly against an embedded engine
+type engineExecutor struct {
+	engine *parser.Engine
+}
+
+func (e engineExecutor) Exec(statement string) (string, error) {
+	return e.engine.Exec(statement)
+}
+
+func TestStatements(t *testing.T) {
+	table := Table{Name: "order items", Columns: []string{"item id", "note"}}
+	if got := CreateTableStatement(table); got != `CREATE TABLE "order items" ("item id", note)` {
+		t.Errorf("create: %s", got)
+	}
+	got := InsertStatement(table, []Value{{Text: "1", Valid: true}, {}})
+	if got != `INSERT INTO "order items" VALUES ('1', NULL)` {
+		t.Errorf("insert: %s", got)
+	}
+	got = InsertStatement(table, []Value{{Text: "it's", Valid: true}, {Text: "", Valid: true}})
+	if got != `INSERT INTO "order items" VALUES ('it''s', '')` {
+		t.Errorf("insert: %s", got)
+	}
+}
+
+func TestImportSQLite(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := auth.CreateAdmin(dir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	engine := parser.NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+
+	src, err := Open("sqlite:testdata/sample.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	var out bytes.Buffer
+	im := New(engineExecutor{engine}, &out)
+	im.Tables = []string{"Order Items", "users"}
+	if err := im.Run(src); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "Imported order items: 1 rows\nImported users: 301 rows\n" {
+		t.Errorf("progress: %q", got)
+	}
+
+	if got := engine.Execute("SELECT COUNT(*) FROM users"); got != "count\n301\n" {
+		t.Errorf("count: %s", got)
+	}
+	if got := engine.Execute("SELECT * FROM users WHERE id = 1"); got != "id | name | score | avatar | note\n1 | O'Brien | -2.5 | 01ff | NULL\n" {
+		t.Errorf("row: %q", got)
+	}
+	if got := engine.Execute(`SELECT * FROM "order items"`); got != "item id | qty | price\n9007199254740993 | 3 | 0\n" {
+		t.Errorf("order items: %q", got)
+	}
+
+	// Importing again stops at the existing table
+	if err := im.Run(src); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an error for existing tables, got %v", err)
+	}
+
+	// Tables that are not in the source are reported before anything runs
+	im.Tables = []string{"missing"}
+	if err := im.Run(src); err == nil || err.Error() != "table missing not found in source" {
+		t.Errorf("missing table: %v", err)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	src, err := Open("sqlite:testdata/sample.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	var out bytes.Buffer
+	im := New(nil, &out)
+	im.DryRun = true
+	im.Tables = []string{"order items"}
+	if err := im.Run(src); err != nil {
+		t.Fatal(err)
+	}
+	want := `CREATE TABLE "order items" ("item id", qty, price)` + "\n" +
+		`INSERT INTO "order items" VALUES ('9007199254740993', '3', '0')` + "\n" +
+		"Imported order items: 1 rows\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
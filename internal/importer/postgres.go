@@ -0,0 +1,451 @@
+// internal/importer/postgres.go
+//
+// A minimal PostgreSQL client speaking the frontend/backend protocol
+// version 3: startup, password authentication (cleartext, MD5 or
+// SCRAM-SHA-256), optional TLS and simple queries, which return every
+// value as text.
+package importer
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol constants
+const (
+	pgProtocolVersion = 196608 // 3.0
+	pgSSLRequest      = 80877103
+
+	pgAuthOK              = 0
+	pgAuthCleartext       = 3
+	pgAuthMD5             = 5
+	pgAuthSASL            = 10
+	pgAuthSASLContinue    = 11
+	pgAuthSASLFinal       = 12
+	pgScramMechanism      = "SCRAM-SHA-256"
+	pgDialTimeout         = 10 * time.Second
+	pgDefaultPort         = "5432"
+	pgDefaultSSLMode      = "prefer"
+	pgUserTablesCondition = "table_type = 'BASE TABLE' AND table_schema NOT IN ('pg_catalog', 'information_schema')"
+)
+
+// postgresSource reads tables from a PostgreSQL server
+type postgresSource struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// schemas maps an imported table name to its schema
+	schemas map[string]string
+}
+
+// openPostgres connects to the server named by a postgres:// URL. The
+// sslmode parameter may be disable, prefer (the default) or require; the
+// server's certificate is not verified.
+func openPostgres(rawURL string) (*postgresSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres URL: %w", err)
+	}
+	host := u.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	port := u.Port()
+	if port == "" {
+		port = pgDefaultPort
+	}
+	user := u.User.Username()
+	password, _ := u.User.Password()
+	database := strings.TrimPrefix(u.Path, "/")
+	if database == "" {
+		database = user
+	}
+	sslMode := u.Query().Get("sslmode")
+	if sslMode == "" {
+		sslMode = pgDefaultSSLMode
+	}
+	if sslMode != "disable" && sslMode != "prefer" && sslMode != "require" {
+		return nil, fmt.Errorf("unsupported sslmode %q: use disable, prefer or require", sslMode)
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), pgDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if sslMode != "disable" {
+		if conn, err = pgStartTLS(conn, host, sslMode == "require"); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &postgresSource{conn: conn, reader: bufio.NewReader(conn)}
+	if err := s.startup(user, password, database); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// pgStartTLS asks the server to switch to TLS. A server without TLS is only
+// accepted when it is not required.
+func pgStartTLS(conn net.Conn, host string, required bool) (net.Conn, error) {
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req, 8)
+	binary.BigEndian.PutUint32(req[4:], pgSSLRequest)
+	if _, err := conn.Write(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	answer := make([]byte, 1)
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if answer[0] != 'S' {
+		if required {
+			conn.Close()
+			return nil, fmt.Errorf("server does not support TLS (sslmode=require)")
+		}
+		return conn, nil
+	}
+	return tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true}), nil
+}
+
+// Close terminates the session
+func (s *postgresSource) Close() error {
+	s.send('X', nil)
+	return s.conn.Close()
+}
+
+// Tables lists the user tables of every schema. Tables outside the public
+// schema are named schema_table.
+func (s *postgresSource) Tables() ([]Table, error) {
+	var tables []Table
+	s.schemas = make(map[string]string)
+	err := s.query("SELECT table_schema, table_name FROM information_schema.tables WHERE "+
+		pgUserTablesCondition+" ORDER BY table_schema, table_name", func(row []Value) error {
+		name := row[1].Text
+		if row[0].Text != "public" {
+			name = row[0].Text + "_" + name
+		}
+		s.schemas[name] = row[0].Text
+		tables = append(tables, Table{Name: name})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range tables {
+		err := s.query(fmt.Sprintf("SELECT column_name FROM information_schema.columns WHERE table_schema = %s AND table_name = %s ORDER BY ordinal_position",
+			pgLiteral(s.schemas[t.Name]), pgLiteral(s.sourceName(t.Name))), func(row []Value) error {
+			tables[i].Columns = append(tables[i].Columns, row[0].Text)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// ReadRows calls fn with every row of a table, streaming them from the
+// server
+func (s *postgresSource) ReadRows(table string, fn func(row []Value) error) error {
+	if s.schemas == nil {
+		if _, err := s.Tables(); err != nil {
+			return err
+		}
+	}
+	schema, ok := s.schemas[table]
+	if !ok {
+		return fmt.Errorf("table %s not found", table)
+	}
+	return s.query(fmt.Sprintf("SELECT * FROM %s.%s", pgIdentifier(schema), pgIdentifier(s.sourceName(table))), fn)
+}
+
+// sourceName returns a table's name within its schema
+func (s *postgresSource) sourceName(table string) string {
+	schema := s.schemas[table]
+	if schema == "public" {
+		return table
+	}
+	return strings.TrimPrefix(table, schema+"_")
+}
+
+// startup opens the session and authenticates
+func (s *postgresSource) startup(user, password, database string) error {
+	var body []byte
+	body = binary.BigEndian.AppendUint32(body, pgProtocolVersion)
+	for _, kv := range [][2]string{{"user", user}, {"database", database}, {"client_encoding", "UTF8"}} {
+		body = append(body, kv[0]...)
+		body = append(body, 0)
+		body = append(body, kv[1]...)
+		body = append(body, 0)
+	}
+	body = append(body, 0)
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	if _, err := s.conn.Write(append(msg, body...)); err != nil {
+		return err
+	}
+
+	var scram *scramClient
+	for {
+		typ, data, err := s.receive()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'R':
+			if len(data) < 4 {
+				return fmt.Errorf("malformed authentication message")
+			}
+			payload := data[4:]
+			switch binary.BigEndian.Uint32(data) {
+			case pgAuthOK:
+			case pgAuthCleartext:
+				err = s.send('p', append([]byte(password), 0))
+			case pgAuthMD5:
+				err = s.send('p', append([]byte(pgMD5Password(user, password, payload)), 0))
+			case pgAuthSASL:
+				if !strings.Contains(string(payload), pgScramMechanism+"\x00") {
+					return fmt.Errorf("server offers no supported SASL mechanism")
+				}
+				scram = newScramClient(password)
+				first := scram.clientFirst()
+				msg := append([]byte(pgScramMechanism+"\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(first)))...)
+				err = s.send('p', append(msg, first...))
+			case pgAuthSASLContinue:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL message")
+				}
+				var final string
+				if final, err = scram.clientFinal(string(payload)); err == nil {
+					err = s.send('p', []byte(final))
+				}
+			case pgAuthSASLFinal:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL message")
+				}
+				err = scram.verifyServer(string(payload))
+			default:
+				return fmt.Errorf("unsupported authentication method %d", binary.BigEndian.Uint32(data))
+			}
+			if err != nil {
+				return err
+			}
+		case 'E':
+			return pgError(data)
+		case 'Z':
+			return nil
+		}
+	}
+}
+
+// query runs a simple query and calls fn with each row
+func (s *postgresSource) query(sql string, fn func(row []Value) error) error {
+	if err := s.send('Q', append([]byte(sql), 0)); err != nil {
+		return err
+	}
+	var failed error
+	for {
+		typ, data, err := s.receive()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'D':
+			if failed != nil {
+				continue
+			}
+			row, err := pgDataRow(data)
+			if err == nil {
+				err = fn(row)
+			}
+			failed = err
+		case 'E':
+			failed = pgError(data)
+		case 'Z':
+			// The server is ready for the next query, even after an error
+			return failed
+		}
+	}
+}
+
+// send writes one message
+func (s *postgresSource) send(typ byte, body []byte) error {
+	msg := append([]byte{typ}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...)
+	_, err := s.conn.Write(append(msg, body...))
+	return err
+}
+
+// receive reads one message
+func (s *postgresSource) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length < 4 {
+		return 0, nil, fmt.Errorf("malformed message length %d", length)
+	}
+	data := make([]byte, length-4)
+	if _, err := io.ReadFull(s.reader, data); err != nil {
+		return 0, nil, err
+	}
+	return header[0], data, nil
+}
+
+// pgDataRow decodes a DataRow message
+func pgDataRow(data []byte) ([]Value, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("malformed data row")
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	row := make([]Value, n)
+	for i := range row {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("malformed data row")
+		}
+		size := int32(binary.BigEndian.Uint32(data))
+		data = data[4:]
+		if size < 0 {
+			continue
+		}
+		if int(size) > len(data) {
+			return nil, fmt.Errorf("malformed data row")
+		}
+		row[i] = Value{Text: string(data[:size]), Valid: true}
+		data = data[size:]
+	}
+	return row, nil
+}
+
+// pgError turns an ErrorResponse into an error
+func pgError(data []byte) error {
+	var severity, message string
+	for _, field := range strings.Split(string(data), "\x00") {
+		if field == "" {
+			continue
+		}
+		switch field[0] {
+		case 'S':
+			severity = field[1:]
+		case 'M':
+			message = field[1:]
+		}
+	}
+	return fmt.Errorf("postgres: %s: %s", severity, message)
+}
+
+// pgMD5Password returns the response to an MD5 password challenge
+func pgMD5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// pgLiteral quotes a string literal
+func pgLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// pgIdentifier quotes an identifier
+func pgIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// scramClient performs SCRAM-SHA-256 authentication (RFC 5802, RFC 7677)
+type scramClient struct {
+	password    string
+	nonce       string
+	firstBare   string
+	authMessage string
+	salted      []byte
+}
+
+// newScramClient creates a client with a random nonce
+func newScramClient(password string) *scramClient {
+	nonce := make([]byte, 18)
+	rand.Read(nonce)
+	return &scramClient{password: password, nonce: base64.StdEncoding.EncodeToString(nonce)}
+}
+
+// clientFirst returns the client-first message. PostgreSQL takes the user
+// name from the startup message, so it is left empty.
+func (c *scramClient) clientFirst() string {
+	c.firstBare = "n=,r=" + c.nonce
+	return "n,," + c.firstBare
+}
+
+// clientFinal returns the client-final message with the proof for the
+// server-first message
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	var nonce, salt string
+	var iterations int
+	for _, attr := range strings.Split(serverFirst, ",") {
+		key, value, _ := strings.Cut(attr, "=")
+		switch key {
+		case "r":
+			nonce = value
+		case "s":
+			salt = value
+		case "i":
+			fmt.Sscan(value, &iterations)
+		}
+	}
+	if !strings.HasPrefix(nonce, c.nonce) || salt == "" || iterations <= 0 {
+		return "", fmt.Errorf("invalid SCRAM server-first message")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+
+	c.salted, err = pbkdf2.Key(sha256.New, c.password, saltBytes, iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	withoutProof := "c=biws,r=" + nonce
+	c.authMessage = c.firstBare + "," + serverFirst + "," + withoutProof
+
+	clientKey := scramHMAC(c.salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	signature := scramHMAC(storedKey[:], c.authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range proof {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verifyServer checks the server-final message's signature
+func (c *scramClient) verifyServer(serverFinal string) error {
+	want := scramHMAC(scramHMAC(c.salted, "Server Key"), c.authMessage)
+	got, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(serverFinal, "v="))
+	if err != nil || !hmac.Equal(got, want) {
+		return fmt.Errorf("server failed SCRAM verification")
+	}
+	return nil
+}
+
+// scramHMAC returns HMAC-SHA-256(key, message)
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,158 @@
+// internal/importer/postgres_test.go
+package importer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The example exchange from RFC 7677
+func TestScramSHA256(t *testing.T) {
+	c := &scramClient{password: "pencil", nonce: "rOprNGfwEbeRWgbNEkqO", firstBare: "n=user,r=rOprNGfwEbeRWgbNEkqO"}
+	final, err := c.clientFinal("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+	if final != want {
+		t.Errorf("client-final:\ngot  %s\nwant %s", final, want)
+	}
+	if err := c.verifyServer("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="); err != nil {
+		t.Error(err)
+	}
+	if err := c.verifyServer("v=AAAA"); err == nil {
+		t.Error("expected a wrong server signature to fail")
+	}
+}
+
+// fakePostgres answers the startup and queries of one connection with MD5
+// authentication and canned results
+func fakePostgres(t *testing.T, results map[string][][]*string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		send := func(typ byte, body []byte) {
+			msg := append([]byte{typ}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...)
+			conn.Write(append(msg, body...))
+		}
+		auth := func(code uint32, extra []byte) {
+			send('R', append(binary.BigEndian.AppendUint32(nil, code), extra...))
+		}
+
+		var length uint32
+		binary.Read(r, binary.BigEndian, &length)
+		startup := make([]byte, length-4)
+		io.ReadFull(r, startup)
+		if binary.BigEndian.Uint32(startup) == pgSSLRequest {
+			conn.Write([]byte{'N'})
+			binary.Read(r, binary.BigEndian, &length)
+			startup = make([]byte, length-4)
+			io.ReadFull(r, startup)
+		}
+
+		salt := []byte{1, 2, 3, 4}
+		auth(pgAuthMD5, salt)
+		for {
+			typ, _ := r.ReadByte()
+			binary.Read(r, binary.BigEndian, &length)
+			body := make([]byte, length-4)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			switch typ {
+			case 'p':
+				if string(body) != pgMD5Password("alice", "secret", salt)+"\x00" {
+					send('E', []byte("SFATAL\x00Mpassword authentication failed\x00\x00"))
+					return
+				}
+				auth(pgAuthOK, nil)
+				send('Z', []byte{'I'})
+			case 'Q':
+				query := strings.TrimSuffix(string(body), "\x00")
+				for key, rows := range results {
+					if !strings.Contains(query, key) {
+						continue
+					}
+					for _, row := range rows {
+						data := binary.BigEndian.AppendUint16(nil, uint16(len(row)))
+						for _, v := range row {
+							if v == nil {
+								data = binary.BigEndian.AppendUint32(data, 0xffffffff)
+								continue
+							}
+							data = binary.BigEndian.AppendUint32(data, uint32(len(*v)))
+							data = append(data, *v...)
+						}
+						send('D', data)
+					}
+				}
+				send('C', []byte("SELECT\x00"))
+				send('Z', []byte{'I'})
+			case 'X':
+				return
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestPostgresSource(t *testing.T) {
+	s := func(v string) *string { return &v }
+	addr := fakePostgres(t, map[string][][]*string{
+		"information_schema.tables":      {{s("public"), s("users")}, {s("sales"), s("q1")}},
+		"table_name = 'users'":           {{s("id")}, {s("name")}},
+		"table_name = 'q1'":              {{s("amount")}},
+		`SELECT * FROM "public"."users"`: {{s("1"), s("Alice")}, {s("2"), nil}},
+	})
+
+	src, err := Open("postgres://alice:secret@" + addr + "/shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	tables, err := src.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Table{{Name: "users", Columns: []string{"id", "name"}}, {Name: "sales_q1", Columns: []string{"amount"}}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Fatalf("tables: got %+v", tables)
+	}
+
+	var rows [][]Value
+	if err := src.ReadRows("users", func(row []Value) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]Value{{{"1", true}, {"Alice", true}}, {{"2", true}, {}}}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows: got %v", rows)
+	}
+}
+
+func TestPostgresWrongPassword(t *testing.T) {
+	addr := fakePostgres(t, nil)
+	if _, err := Open("postgres://alice:wrong@" + addr + "/shop?sslmode=disable"); err == nil ||
+		!strings.Contains(err.Error(), "password authentication failed") {
+		t.Errorf("expected authentication error, got %v", err)
+	}
+}
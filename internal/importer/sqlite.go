@@ -0,0 +1,404 @@
+// internal/importer/sqlite.go
+//
+// A read-only reader for SQLite database files, following the file format
+// described at https://www.sqlite.org/fileformat.html. It walks table
+// b-trees directly, so no SQLite library is needed; indexes, views and
+// triggers are ignored.
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sqliteMagic starts every SQLite database file
+const sqliteMagic = "SQLite format 3\x00"
+
+// B-tree page types
+const (
+	sqliteInteriorTable = 0x05
+	sqliteLeafTable     = 0x0d
+)
+
+// sqliteSource reads tables from a SQLite database file
+type sqliteSource struct {
+	file     *os.File
+	pageSize int
+	// usable is the page size less the bytes reserved at the end of each page
+	usable int
+}
+
+// sqliteTable is a table from the schema with where its rows are stored
+type sqliteTable struct {
+	Table
+	rootPage int
+	// rowidColumn is the INTEGER PRIMARY KEY column stored as the rowid, or -1
+	rowidColumn  int
+	withoutRowid bool
+}
+
+// openSQLite opens a SQLite database file for reading. A database with
+// changes still in its -wal file is refused, since only the main file is
+// read and those changes would be silently left out.
+func openSQLite(path string) (*sqliteSource, error) {
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() > 0 {
+		return nil, fmt.Errorf("%s has changes in %s-wal that are not checkpointed; run PRAGMA wal_checkpoint(TRUNCATE) or close every connection to it, then import again", path, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 100)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:16]) != sqliteMagic {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a SQLite database", path)
+	}
+	if encoding := binary.BigEndian.Uint32(header[56:]); encoding > 1 {
+		f.Close()
+		return nil, fmt.Errorf("%s: only UTF-8 databases are supported", path)
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	return &sqliteSource{file: f, pageSize: pageSize, usable: pageSize - int(header[20])}, nil
+}
+
+// Close closes the database file
+func (s *sqliteSource) Close() error {
+	return s.file.Close()
+}
+
+// Tables lists the database's tables, skipping SQLite's internal ones
+func (s *sqliteSource) Tables() ([]Table, error) {
+	tables, err := s.schema()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Table, len(tables))
+	for i, t := range tables {
+		out[i] = t.Table
+	}
+	return out, nil
+}
+
+// ReadRows calls fn with every row of a table, in rowid order
+func (s *sqliteSource) ReadRows(name string, fn func(row []Value) error) error {
+	tables, err := s.schema()
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if t.Name != name {
+			continue
+		}
+		if t.withoutRowid {
+			return fmt.Errorf("table %s: WITHOUT ROWID tables are not supported", name)
+		}
+		return s.walkTable(t.rootPage, func(rowid int64, record []Value) error {
+			row := make([]Value, len(t.Columns))
+			copy(row, record)
+			if t.rowidColumn >= 0 {
+				row[t.rowidColumn] = Value{Text: strconv.FormatInt(rowid, 10), Valid: true}
+			}
+			return fn(row)
+		})
+	}
+	return fmt.Errorf("table %s not found", name)
+}
+
+// schema reads the tables recorded in sqlite_schema, which is stored in the
+// table b-tree rooted at page 1
+func (s *sqliteSource) schema() ([]sqliteTable, error) {
+	var tables []sqliteTable
+	err := s.walkTable(1, func(_ int64, record []Value) error {
+		// type, name, tbl_name, rootpage, sql
+		if len(record) < 5 || record[0].Text != "table" || strings.HasPrefix(record[1].Text, "sqlite_") {
+			return nil
+		}
+		rootPage, err := strconv.Atoi(record[3].Text)
+		if err != nil {
+			return fmt.Errorf("table %s: invalid root page %q", record[1].Text, record[3].Text)
+		}
+		t, err := parseCreateTable(record[4].Text)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", record[1].Text, err)
+		}
+		t.Name = record[1].Text
+		t.rootPage = rootPage
+		tables = append(tables, t)
+		return nil
+	})
+	return tables, err
+}
+
+// readPage returns page n, numbered from 1
+func (s *sqliteSource) readPage(n int) ([]byte, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid page number %d", n)
+	}
+	page := make([]byte, s.pageSize)
+	if _, err := s.file.ReadAt(page, int64(n-1)*int64(s.pageSize)); err != nil {
+		return nil, fmt.Errorf("read page %d: %w", n, err)
+	}
+	return page, nil
+}
+
+// walkTable calls fn with the rowid and record of every cell in the table
+// b-tree rooted at page n
+func (s *sqliteSource) walkTable(n int, fn func(rowid int64, record []Value) error) error {
+	page, err := s.readPage(n)
+	if err != nil {
+		return err
+	}
+	// Page 1 starts with the 100-byte file header
+	hdr := 0
+	if n == 1 {
+		hdr = 100
+	}
+	pageType := page[hdr]
+	cells := int(binary.BigEndian.Uint16(page[hdr+3:]))
+	pointers := hdr + 8
+	if pageType == sqliteInteriorTable {
+		pointers = hdr + 12
+	}
+
+	for i := 0; i < cells; i++ {
+		off := int(binary.BigEndian.Uint16(page[pointers+2*i:]))
+		switch pageType {
+		case sqliteInteriorTable:
+			if err := s.walkTable(int(binary.BigEndian.Uint32(page[off:])), fn); err != nil {
+				return err
+			}
+		case sqliteLeafTable:
+			payloadSize, k := readVarint(page[off:])
+			rowid, m := readVarint(page[off+k:])
+			payload, err := s.payload(page, off+k+m, int(payloadSize))
+			if err != nil {
+				return err
+			}
+			record, err := decodeRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d cell %d: %w", n, i, err)
+			}
+			if err := fn(int64(rowid), record); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("page %d: unexpected b-tree page type %#x", n, pageType)
+		}
+	}
+	if pageType == sqliteInteriorTable {
+		return s.walkTable(int(binary.BigEndian.Uint32(page[hdr+8:])), fn)
+	}
+	return nil
+}
+
+// payload returns a leaf cell's payload of size bytes starting at off,
+// following overflow pages when it does not fit on the page
+func (s *sqliteSource) payload(page []byte, off, size int) ([]byte, error) {
+	maxLocal := s.usable - 35
+	if size <= maxLocal {
+		return page[off : off+size], nil
+	}
+	minLocal := (s.usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(s.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, page[off:off+local]...)
+	next := int(binary.BigEndian.Uint32(page[off+local:]))
+	for len(out) < size {
+		overflow, err := s.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+		n := min(size-len(out), s.usable-4)
+		out = append(out, overflow[4:4+n]...)
+		next = int(binary.BigEndian.Uint32(overflow))
+	}
+	return out, nil
+}
+
+// decodeRecord decodes a record into text values. Integers and floats are
+// formatted in decimal and blobs in hex.
+func decodeRecord(payload []byte) ([]Value, error) {
+	headerSize, n := readVarint(payload)
+	if int(headerSize) > len(payload) {
+		return nil, fmt.Errorf("record header exceeds payload")
+	}
+	var types []uint64
+	for pos := n; pos < int(headerSize); {
+		t, k := readVarint(payload[pos:])
+		types = append(types, t)
+		pos += k
+	}
+
+	body := payload[headerSize:]
+	values := make([]Value, len(types))
+	for i, t := range types {
+		size := serialTypeSize(t)
+		if size > len(body) {
+			return nil, fmt.Errorf("record value exceeds payload")
+		}
+		data := body[:size]
+		body = body[size:]
+		switch {
+		case t == 0:
+			// NULL
+		case t <= 6:
+			v := int64(0)
+			if data[0]&0x80 != 0 {
+				v = -1
+			}
+			for _, b := range data {
+				v = v<<8 | int64(b)
+			}
+			values[i] = Value{Text: strconv.FormatInt(v, 10), Valid: true}
+		case t == 7:
+			f := math.Float64frombits(binary.BigEndian.Uint64(data))
+			values[i] = Value{Text: strconv.FormatFloat(f, 'g', -1, 64), Valid: true}
+		case t == 8 || t == 9:
+			values[i] = Value{Text: strconv.Itoa(int(t - 8)), Valid: true}
+		case t >= 12 && t%2 == 0:
+			values[i] = Value{Text: hex.EncodeToString(data), Valid: true}
+		case t >= 13:
+			values[i] = Value{Text: string(data), Valid: true}
+		default:
+			return nil, fmt.Errorf("unsupported serial type %d", t)
+		}
+	}
+	return values, nil
+}
+
+// serialTypeSize returns the size in bytes of a value of serial type t
+func serialTypeSize(t uint64) int {
+	switch {
+	case t <= 4:
+		return int(t)
+	case t == 5:
+		return 6
+	case t == 6 || t == 7:
+		return 8
+	case t >= 12:
+		return int((t - 12) / 2)
+	}
+	return 0
+}
+
+// readVarint decodes a SQLite big-endian varint and returns it with its
+// length in bytes
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8 && i < len(b); i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	if len(b) < 9 {
+		return v, len(b)
+	}
+	return v<<8 | uint64(b[8]), 9
+}
+
+// parseCreateTable reads the column names from a CREATE TABLE statement
+func parseCreateTable(sql string) (sqliteTable, error) {
+	t := sqliteTable{rowidColumn: -1}
+	open := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if open < 0 || end < open {
+		return t, fmt.Errorf("cannot parse %q", sql)
+	}
+	t.withoutRowid = strings.Contains(strings.ToUpper(sql[end:]), "WITHOUT ROWID")
+
+	for _, def := range splitTopLevel(sql[open+1 : end]) {
+		name, rest := cutSQLiteIdentifier(strings.TrimSpace(def))
+		switch strings.ToUpper(name) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			// A table constraint, not a column
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		// An INTEGER PRIMARY KEY column is an alias for the rowid
+		fields := strings.Fields(strings.ToUpper(rest))
+		if len(fields) >= 3 && fields[0] == "INTEGER" && fields[1] == "PRIMARY" && fields[2] == "KEY" &&
+			!(len(fields) > 3 && fields[3] == "DESC") {
+			t.rowidColumn = len(t.Columns)
+		}
+		t.Columns = append(t.Columns, name)
+	}
+	if len(t.Columns) == 0 {
+		return t, fmt.Errorf("no columns in %q", sql)
+	}
+	return t, nil
+}
+
+// splitTopLevel splits s on commas outside parentheses and quotes
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// cutSQLiteIdentifier splits a column definition into its name, unquoted,
+// and the rest
+func cutSQLiteIdentifier(def string) (name, rest string) {
+	if def == "" {
+		return "", ""
+	}
+	closing := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[def[0]]
+	if closing == 0 {
+		if i := strings.IndexFunc(def, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' || r == '\r' }); i >= 0 {
+			return def[:i], def[i:]
+		}
+		return def, ""
+	}
+	var b bytes.Buffer
+	for i := 1; i < len(def); i++ {
+		if def[i] != closing {
+			b.WriteByte(def[i])
+			continue
+		}
+		// A doubled closing quote stands for one
+		if closing != ']' && i+1 < len(def) && def[i+1] == closing {
+			b.WriteByte(closing)
+			i++
+			continue
+		}
+		return b.String(), def[i+1:]
+	}
+	return b.String(), ""
+}
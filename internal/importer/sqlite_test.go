@@ -0,0 +1,109 @@
+// internal/importer/sqlite_test.go
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/sample.db uses 1 KiB pages, so the users table spans interior
+// pages and its long name spills to overflow pages
+func TestSQLiteSource(t *testing.T) {
+	src, err := Open("sqlite:testdata/sample.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	tables, err := src.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Table{
+		{Name: "users", Columns: []string{"id", "name", "score", "avatar", "note"}},
+		{Name: "order items", Columns: []string{"item id", "qty", "price"}},
+		{Name: "kv", Columns: []string{"k", "v"}},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Fatalf("tables: got %+v, want %+v", tables, want)
+	}
+
+	var rows [][]Value
+	if err := src.ReadRows("users", func(row []Value) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 301 {
+		t.Fatalf("got %d users, want 301", len(rows))
+	}
+	null := Value{}
+	text := func(s string) Value { return Value{Text: s, Valid: true} }
+	for i, want := range map[int][]Value{
+		0:   {text("-7"), text("negative"), text("1e+20"), null, null},
+		1:   {text("1"), text("O'Brien"), text("-2.5"), text("01ff"), null},
+		2:   {text("2"), text(strings.Repeat("x", 3000)), null, null, null},
+		3:   {text("4"), text("user4"), text("1"), null, null},
+		300: {text("1000"), text("late"), null, null, text("added later")},
+	} {
+		if !reflect.DeepEqual(rows[i], want) {
+			t.Errorf("row %d: got %.80v, want %.80v", i, rows[i], want)
+		}
+	}
+
+	var items [][]Value
+	src.ReadRows("order items", func(row []Value) error {
+		items = append(items, row)
+		return nil
+	})
+	if want := [][]Value{{text("9007199254740993"), text("3"), text("0")}}; !reflect.DeepEqual(items, want) {
+		t.Errorf("order items: got %v", items)
+	}
+
+	if err := src.ReadRows("kv", func([]Value) error { return nil }); err == nil ||
+		!strings.Contains(err.Error(), "WITHOUT ROWID") {
+		t.Errorf("expected WITHOUT ROWID error, got %v", err)
+	}
+}
+
+func TestSQLiteRefusesUncheckpointedWAL(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty -wal file, as SQLite leaves after a checkpoint, is fine
+	if err := os.WriteFile(path+"-wal", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Open("sqlite:" + path)
+	if err != nil {
+		t.Fatalf("empty -wal file refused: %v", err)
+	}
+	src.Close()
+
+	if err := os.WriteFile(path+"-wal", make([]byte, 32), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open("sqlite:" + path); err == nil || !strings.Contains(err.Error(), "-wal") {
+		t.Errorf("expected the import to be refused while the -wal file has changes, got %v", err)
+	}
+}
+
+func TestParseCreateTable(t *testing.T) {
+	got, err := parseCreateTable(`CREATE TABLE t ("a ""b""" INTEGER PRIMARY KEY, c DECIMAL(10, 2), CONSTRAINT u UNIQUE (c))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`a "b"`, "c"}; !reflect.DeepEqual(got.Columns, want) || got.rowidColumn != 0 {
+		t.Errorf("got %q rowid column %d", got.Columns, got.rowidColumn)
+	}
+}
@@ -0,0 +1,53 @@
+// internal/logging/logging.go
+//
+// Log destinations. The server writes its log through the standard log
+// package; Open returns the writer it is pointed at: stdout, stderr, the
+// system log, or a file that is rotated by size and age.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Config selects where the server logs
+type Config struct {
+	// Output is stdout, stderr, syslog or file
+	Output string
+	// File is the log file when Output is file
+	File string
+	// MaxSize rotates the file once it would grow past this many bytes; 0
+	// disables size-based rotation
+	MaxSize int64
+	// RotateEvery rotates the file when it is this old; 0 disables
+	// time-based rotation
+	RotateEvery time.Duration
+	// MaxFiles is the number of rotated files kept; 0 keeps them all
+	MaxFiles int
+}
+
+// Open returns the writer for cfg. Closing it closes the file or syslog
+// connection; closing stdout or stderr does nothing.
+func Open(cfg Config) (io.WriteCloser, error) {
+	switch cfg.Output {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	case "syslog":
+		return openSyslog()
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("a log file is required with file output")
+		}
+		return OpenRotatingFile(cfg.File, cfg.MaxSize, cfg.RotateEvery, cfg.MaxFiles)
+	}
+	return nil, fmt.Errorf("invalid log output %q: use stdout, stderr, syslog or file", cfg.Output)
+}
+
+// nopCloser is a writer that is never closed
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,143 @@
+// internal/logging/rotate.go
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedSuffix is appended to a rotated file's name: harudb.log.20260105-101203.000000
+const rotatedSuffix = "20060102-150405.000000"
+
+// RotatingFile is a log file that is renamed aside and replaced by a new one
+// when it grows too large or too old. Only the newest rotated files are kept.
+type RotatingFile struct {
+	path     string
+	maxSize  int64
+	every    time.Duration
+	maxFiles int
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+	// now is time.Now, replaced in tests
+	now func() time.Time
+}
+
+// OpenRotatingFile opens path for appending, rotating it at maxSize bytes or
+// after every, and keeping maxFiles rotated files. Zero disables each limit.
+func OpenRotatingFile(path string, maxSize int64, every time.Duration, maxFiles int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, every: every, maxFiles: maxFiles, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file, counting what it already holds
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.f, r.size, r.opened = f, info.Size(), r.now()
+	return nil
+}
+
+// Write appends p, rotating first when p would take the file past its size
+// limit or the file has reached its age limit
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	tooBig := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	tooOld := r.every > 0 && r.now().Sub(r.opened) >= r.every
+	if tooBig || tooOld {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file aside, opens a new one and removes
+// rotated files beyond maxFiles. The caller holds r.mu.
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	r.f = nil
+	if err := os.Rename(r.path, r.path+"."+r.now().Format(rotatedSuffix)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	return r.prune()
+}
+
+// prune removes the oldest rotated files beyond maxFiles
+func (r *RotatingFile) prune() error {
+	if r.maxFiles <= 0 {
+		return nil
+	}
+	rotated, err := r.Rotated()
+	if err != nil {
+		return err
+	}
+	for len(rotated) > r.maxFiles {
+		if err := os.Remove(rotated[0]); err != nil {
+			return fmt.Errorf("failed to remove old log file: %w", err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Rotated returns the paths of the rotated files, oldest first
+func (r *RotatingFile) Rotated() ([]string, error) {
+	matches, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var rotated []string
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, r.path+".")
+		if _, err := time.Parse(rotatedSuffix, suffix); err == nil {
+			rotated = append(rotated, m)
+		}
+	}
+	// The timestamp suffix sorts in time order
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// Close closes the current file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "harudb.log")
+	r, err := OpenRotatingFile(path, 20, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	clock := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+	r.opened = clock
+
+	write := func(s string) {
+		t.Helper()
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("0123456789\n")
+	write("0123456789\n") // would pass 20 bytes: rotates first
+	rotated, _ := r.Rotated()
+	if len(rotated) != 1 {
+		t.Fatalf("expected one rotated file, got %v", rotated)
+	}
+	if data, _ := os.ReadFile(rotated[0]); string(data) != "0123456789\n" {
+		t.Errorf("rotated file holds %q", data)
+	}
+
+	// An old file is rotated even when small
+	clock = clock.Add(time.Hour)
+	write("x\n")
+	write("y\n")
+	write("z\n")
+	clock = clock.Add(time.Hour)
+	write("last\n")
+
+	rotated, _ = r.Rotated()
+	if len(rotated) != 2 {
+		t.Fatalf("expected the two newest rotated files to be kept, got %v", rotated)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "last\n" {
+		t.Errorf("current file holds %q", data)
+	}
+	if data, _ := os.ReadFile(rotated[1]); string(data) != "x\ny\nz\n" {
+		t.Errorf("newest rotated file holds %q", data)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, out := range []string{"", "stdout", "stderr"} {
+		w, err := Open(Config{Output: out})
+		if err != nil {
+			t.Errorf("%q: %v", out, err)
+			continue
+		}
+		w.Close()
+	}
+	if _, err := Open(Config{Output: "file"}); err == nil {
+		t.Error("file output without a file should fail")
+	}
+	if _, err := Open(Config{Output: "kafka"}); err == nil || !strings.Contains(err.Error(), "invalid log output") {
+		t.Errorf("expected an invalid output error, got %v", err)
+	}
+}
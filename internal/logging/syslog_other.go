@@ -0,0 +1,14 @@
+// internal/logging/syslog_other.go
+//go:build !unix
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+// openSyslog fails where there is no system log to write to
+func openSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
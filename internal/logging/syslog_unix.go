@@ -0,0 +1,14 @@
+// internal/logging/syslog_unix.go
+//go:build unix
+
+package logging
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// openSyslog connects to the local system log as harudb
+func openSyslog() (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "harudb")
+}
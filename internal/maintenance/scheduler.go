@@ -0,0 +1,87 @@
+// internal/maintenance/scheduler.go
+package maintenance
+
+import (
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// task is a job the scheduler runs periodically
+type task struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Scheduler runs background maintenance tasks, each on its own interval.
+// Every wait is randomized by up to Jitter of the interval so that tasks on
+// the same interval, or servers started together, do not fire in lockstep.
+type Scheduler struct {
+	// Jitter is the fraction of an interval each wait may vary by, e.g. 0.1
+	// for ±10%
+	Jitter float64
+	// Logf reports task failures; defaults to log.Printf
+	Logf func(format string, args ...interface{})
+
+	tasks []task
+	stop  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler with ±10% jitter
+func NewScheduler() *Scheduler {
+	return &Scheduler{Jitter: 0.1, Logf: log.Printf, stop: make(chan struct{})}
+}
+
+// Add registers a task. Tasks with a zero or negative interval are disabled.
+// Add must be called before Start.
+func (s *Scheduler) Add(name string, interval time.Duration, run func() error) {
+	if interval <= 0 {
+		return
+	}
+	s.tasks = append(s.tasks, task{name: name, interval: interval, run: run})
+}
+
+// Start runs every registered task in the background until Stop is called
+func (s *Scheduler) Start() {
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.loop(t)
+	}
+}
+
+// Stop stops scheduling new runs and waits for running tasks to finish
+func (s *Scheduler) Stop() {
+	s.once.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
+
+// loop runs t after every jittered interval
+func (s *Scheduler) loop(t task) {
+	defer s.wg.Done()
+	timer := time.NewTimer(s.wait(t.interval))
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-timer.C:
+		}
+		if err := t.run(); err != nil {
+			s.Logf("maintenance: %s failed: %v", t.name, err)
+		}
+		timer.Reset(s.wait(t.interval))
+	}
+}
+
+// wait returns interval randomized by up to ±Jitter
+func (s *Scheduler) wait(interval time.Duration) time.Duration {
+	if s.Jitter <= 0 {
+		return interval
+	}
+	spread := float64(interval) * s.Jitter
+	return interval + time.Duration((rand.Float64()*2-1)*spread)
+}
@@ -0,0 +1,75 @@
+package maintenance
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsTasksUntilStopped(t *testing.T) {
+	s := NewScheduler()
+	var fast, failing, disabled atomic.Int32
+	var logged atomic.Int32
+	s.Logf = func(string, ...interface{}) { logged.Add(1) }
+	s.Add("fast", 5*time.Millisecond, func() error { fast.Add(1); return nil })
+	s.Add("failing", 5*time.Millisecond, func() error { failing.Add(1); return errors.New("boom") })
+	s.Add("disabled", 0, func() error { disabled.Add(1); return nil })
+
+	s.Start()
+	time.Sleep(60 * time.Millisecond)
+	s.Stop()
+	s.Stop() // stopping twice is harmless
+
+	if fast.Load() < 3 {
+		t.Errorf("fast task ran %d times", fast.Load())
+	}
+	if failing.Load() == 0 || logged.Load() != failing.Load() {
+		t.Errorf("each failure should be logged: %d runs, %d logs", failing.Load(), logged.Load())
+	}
+	if disabled.Load() != 0 {
+		t.Error("a task with a zero interval should not run")
+	}
+
+	ran := fast.Load()
+	time.Sleep(20 * time.Millisecond)
+	if fast.Load() != ran {
+		t.Error("tasks should not run after Stop")
+	}
+}
+
+func TestStopWaitsForRunningTask(t *testing.T) {
+	s := NewScheduler()
+	started := make(chan struct{}, 1)
+	var runs, finished atomic.Int32
+	s.Add("slow", time.Millisecond, func() error {
+		runs.Add(1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(30 * time.Millisecond)
+		finished.Add(1)
+		return nil
+	})
+	s.Start()
+	<-started
+	s.Stop()
+	if finished.Load() != runs.Load() {
+		t.Error("Stop returned while a task was still running")
+	}
+}
+
+func TestJitterStaysWithinBounds(t *testing.T) {
+	s := NewScheduler()
+	s.Jitter = 0.25
+	for i := 0; i < 1000; i++ {
+		if w := s.wait(time.Second); w < 750*time.Millisecond || w > 1250*time.Millisecond {
+			t.Fatalf("wait %v outside ±25%%", w)
+		}
+	}
+	s.Jitter = 0
+	if w := s.wait(time.Second); w != time.Second {
+		t.Errorf("no jitter should wait exactly the interval, got %v", w)
+	}
+}
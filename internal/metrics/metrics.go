@@ -0,0 +1,55 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// counters maps each exported per-table counter to its help text
+var counters = []struct {
+	name  string
+	help  string
+	value func(storage.TableStats) uint64
+}{
+	{"harudb_table_reads_total", "Queries that read the table.",
+		func(s storage.TableStats) uint64 { return s.Reads }},
+	{"harudb_table_inserts_total", "Rows inserted into the table.",
+		func(s storage.TableStats) uint64 { return s.Inserts }},
+	{"harudb_table_updates_total", "Rows updated in the table.",
+		func(s storage.TableStats) uint64 { return s.Updates }},
+	{"harudb_table_deletes_total", "Rows deleted from the table.",
+		func(s storage.TableStats) uint64 { return s.Deletes }},
+	{"harudb_table_index_lookups_total", "Reads answered from an index.",
+		func(s storage.TableStats) uint64 { return s.IndexLookups }},
+}
+
+// Handler serves the table statistics of db in the Prometheus text format
+func Handler(db *storage.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, Format(db.TableStats()))
+	})
+}
+
+// Format renders table statistics in the Prometheus text format
+func Format(stats []storage.TableStats) string {
+	var b strings.Builder
+	for _, c := range counters {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for _, st := range stats {
+			fmt.Fprintf(&b, "%s{table=%q} %d\n", c.name, st.Table, c.value(st))
+		}
+	}
+	const lastWrite = "harudb_table_last_write_timestamp_seconds"
+	fmt.Fprintf(&b, "# HELP %s Unix time of the last write to the table.\n# TYPE %s gauge\n", lastWrite, lastWrite)
+	for _, st := range stats {
+		if !st.LastWrite.IsZero() {
+			fmt.Fprintf(&b, "%s{table=%q} %d\n", lastWrite, st.Table, st.LastWrite.Unix())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestHandlerServesTableStats(t *testing.T) {
+	db := storage.NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("users", []string{"id", "name"})
+	db.Insert("users", []string{"1", "Alice"})
+	db.Insert("users", []string{"2", "Bob"})
+	db.SelectAll("users")
+
+	srv := httptest.NewServer(Handler(db))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`harudb_table_reads_total{table="users"} 1`,
+		`harudb_table_inserts_total{table="users"} 2`,
+		`harudb_table_deletes_total{table="users"} 0`,
+		`harudb_table_last_write_timestamp_seconds{table="users"}`,
+		"# TYPE harudb_table_updates_total counter",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// internal/migrate/migrate.go
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/client"
+)
+
+// Table is the system table recording applied migrations
+const Table = "schema_migrations"
+
+// fileNamePattern matches 0001_create_users.up.sql, 0001_create_users.down.sql
+// and 0001_create_users.sql (an up migration without a down)
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_-]+?)(\.up|\.down)?\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  uint64
+	Name     string
+	Up       []string
+	Down     []string
+	HasDown  bool
+	Checksum string
+}
+
+// Applied is a migration recorded in the system table
+type Applied struct {
+	Version   uint64
+	Name      string
+	Checksum  string
+	AppliedAt string
+	// row is the row index in the system table
+	row int
+}
+
+// Executor runs one statement and returns the server's response, or an
+// error if the server rejects the statement
+type Executor interface {
+	Exec(statement string) (string, error)
+}
+
+// Runner applies migrations through an Executor
+type Runner struct {
+	exec Executor
+	out  io.Writer
+	// DryRun prints the statements that would run without executing them
+	DryRun bool
+}
+
+// NewRunner creates a runner that reports progress to out
+func NewRunner(exec Executor, out io.Writer) *Runner {
+	return &Runner{exec: exec, out: out}
+}
+
+// Load reads the migrations in dir, sorted by version
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileNamePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, exists := byVersion[version]
+		if !exists {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, mig.Name, m[2])
+		}
+
+		if m[3] == ".down" {
+			if mig.HasDown {
+				return nil, fmt.Errorf("duplicate down migration for version %d", version)
+			}
+			mig.Down, mig.HasDown = ParseStatements(string(data)), true
+			continue
+		}
+		if mig.Checksum != "" {
+			return nil, fmt.Errorf("duplicate up migration for version %d", version)
+		}
+		sum := sha256.Sum256(data)
+		mig.Up, mig.Checksum = ParseStatements(string(data)), hex.EncodeToString(sum[:8])
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has a down file but no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ParseStatements splits a migration file into single-line statements.
+// Statements end with ";", "--" starts a comment, and both are ignored inside
+// single quotes.
+func ParseStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	inQuote, inComment := false, false
+
+	flush := func() {
+		if stmt := strings.Join(strings.Fields(current.String()), " "); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case inComment:
+			if c == '\n' {
+				inComment = false
+				current.WriteByte(' ')
+			}
+		case c == '\'':
+			inQuote = !inQuote
+			current.WriteByte(c)
+		case inQuote:
+			current.WriteByte(c)
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			inComment = true
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// Applied returns the migrations recorded in the system table, oldest first
+func (r *Runner) Applied() ([]Applied, error) {
+	applied, _, err := r.applied()
+	return applied, err
+}
+
+// applied reads the system table and reports whether it exists
+func (r *Runner) applied() ([]Applied, bool, error) {
+	resp, err := r.exec.Exec("SELECT * FROM " + Table)
+	if client.CodeOf(err) == client.CodeNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var applied []Applied
+	lines := strings.Split(strings.TrimSpace(resp), "\n")
+	for i, line := range lines[1:] { // skip the column header
+		cols := strings.Split(line, " | ")
+		if len(cols) != 4 {
+			continue
+		}
+		version, err := strconv.ParseUint(strings.TrimSpace(cols[0]), 10, 64)
+		if err != nil {
+			continue
+		}
+		applied = append(applied, Applied{Version: version, Name: cols[1], Checksum: cols[2], AppliedAt: cols[3], row: i})
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Version < applied[j].Version })
+	return applied, true, nil
+}
+
+// Up applies pending migrations in version order, stopping after target
+// when it is non-zero
+func (r *Runner) Up(migrations []Migration, target uint64) error {
+	applied, tracked, err := r.applied()
+	if err != nil {
+		return err
+	}
+	done := make(map[uint64]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	if !tracked && !r.DryRun {
+		if err := r.run(fmt.Sprintf("CREATE TABLE %s (version, name, checksum, applied_at)", Table)); err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	for _, mig := range migrations {
+		if target != 0 && mig.Version > target {
+			break
+		}
+		if done[mig.Version] {
+			continue
+		}
+
+		fmt.Fprintf(r.out, "Applying %d_%s\n", mig.Version, mig.Name)
+		if err := r.runAll(mig, mig.Up); err != nil {
+			return err
+		}
+		record := fmt.Sprintf("INSERT INTO %s VALUES (%d, %s, %s, %s)",
+			Table, mig.Version, mig.Name, mig.Checksum, time.Now().UTC().Format(time.RFC3339))
+		if err := r.step(record); err != nil {
+			return fmt.Errorf("migration %d_%s was applied but could not be recorded: %w", mig.Version, mig.Name, err)
+		}
+		count++
+	}
+
+	if count == 0 {
+		fmt.Fprintln(r.out, "No pending migrations")
+	} else if r.DryRun {
+		fmt.Fprintf(r.out, "Would apply %d migration(s)\n", count)
+	} else {
+		fmt.Fprintf(r.out, "Applied %d migration(s)\n", count)
+	}
+	return nil
+}
+
+// Down reverts the most recently applied migrations, at most steps of them
+func (r *Runner) Down(migrations []Migration, steps int) error {
+	applied, err := r.Applied()
+	if err != nil {
+		return err
+	}
+	known := make(map[uint64]Migration, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = mig
+	}
+
+	count := 0
+	for i := len(applied) - 1; i >= 0 && count < steps; i-- {
+		a := applied[i]
+		mig, exists := known[a.Version]
+		if !exists {
+			return fmt.Errorf("migration %d_%s is applied but its files are missing", a.Version, a.Name)
+		}
+		if !mig.HasDown {
+			return fmt.Errorf("migration %d_%s has no down migration", mig.Version, mig.Name)
+		}
+
+		fmt.Fprintf(r.out, "Reverting %d_%s\n", mig.Version, mig.Name)
+		if err := r.runAll(mig, mig.Down); err != nil {
+			return err
+		}
+		if err := r.step(fmt.Sprintf("DELETE FROM %s ROW %d", Table, a.row)); err != nil {
+			return fmt.Errorf("migration %d_%s was reverted but could not be unrecorded: %w", mig.Version, mig.Name, err)
+		}
+		// Rows after the deleted one move up
+		for j := range applied {
+			if applied[j].row > a.row {
+				applied[j].row--
+			}
+		}
+		count++
+	}
+
+	if count == 0 {
+		fmt.Fprintln(r.out, "No migrations to revert")
+	} else if r.DryRun {
+		fmt.Fprintf(r.out, "Would revert %d migration(s)\n", count)
+	} else {
+		fmt.Fprintf(r.out, "Reverted %d migration(s)\n", count)
+	}
+	return nil
+}
+
+// Status prints every migration with whether it has been applied
+func (r *Runner) Status(migrations []Migration) error {
+	applied, err := r.Applied()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[uint64]Applied, len(applied))
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+
+	for _, mig := range migrations {
+		a, ok := byVersion[mig.Version]
+		switch {
+		case !ok:
+			fmt.Fprintf(r.out, "  pending   %d_%s\n", mig.Version, mig.Name)
+		case a.Checksum != mig.Checksum:
+			fmt.Fprintf(r.out, "  modified  %d_%s (applied %s, file changed since)\n", mig.Version, mig.Name, a.AppliedAt)
+		default:
+			fmt.Fprintf(r.out, "  applied   %d_%s (%s)\n", mig.Version, mig.Name, a.AppliedAt)
+		}
+		delete(byVersion, mig.Version)
+	}
+	for _, a := range applied {
+		if _, missing := byVersion[a.Version]; missing {
+			fmt.Fprintf(r.out, "  missing   %d_%s (applied %s, no file)\n", a.Version, a.Name, a.AppliedAt)
+		}
+	}
+	return nil
+}
+
+// runAll runs the statements of one migration direction
+func (r *Runner) runAll(mig Migration, statements []string) error {
+	for i, stmt := range statements {
+		if err := r.step(stmt); err != nil {
+			return fmt.Errorf("migration %d_%s failed at statement %d: %w", mig.Version, mig.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+// step runs a statement, or prints it in dry-run mode
+func (r *Runner) step(stmt string) error {
+	if r.DryRun {
+		fmt.Fprintf(r.out, "  %s\n", stmt)
+		return nil
+	}
+	return r.run(stmt)
+}
+
+// run executes a statement
+func (r *Runner) run(stmt string) error {
+	if _, err := r.exec.Exec(stmt); err != nil {
+		return fmt.Errorf("%s: %w", stmt, err)
+	}
+	return nil
+}
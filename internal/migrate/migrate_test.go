@@ -0,0 +1,176 @@
+// internal/migrate/migrate_test.go
+package migrate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/parser"
+)
+
+// engineExecutor runs statements directly against an embedded engine
+type engineExecutor struct {
+	engine *parser.Engine
+}
+
+func (e engineExecutor) Exec(statement string) (string, error) {
+	return e.engine.Exec(statement)
+}
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestParseStatements(t *testing.T) {
+	sql := `-- create the users table
+CREATE TABLE users (
+    id,
+    name
+);
+INSERT INTO users VALUES (1, 'semi; colon -- not a comment'); -- trailing comment
+`
+	want := []string{
+		"CREATE TABLE users ( id, name )",
+		"INSERT INTO users VALUES (1, 'semi; colon -- not a comment')",
+	}
+	if got := ParseStatements(sql); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMigrateUpDownStatus(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"0001_create_users.up.sql":   "CREATE TABLE users (id, name);",
+		"0001_create_users.down.sql": "DROP TABLE users;",
+		"0002_seed_users.up.sql":     "INSERT INTO users VALUES (1, 'Hareesh');\nINSERT INTO users VALUES (2, 'Asha');",
+		"0002_seed_users.down.sql":   "DELETE FROM users ROW 1;\nDELETE FROM users ROW 0;",
+		"0003_create_orders.sql":     "CREATE TABLE orders (id, user_id);",
+		"README.md":                  "not a migration",
+	})
+	migrations, err := Load(dir)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(migrations) != 3 || migrations[2].HasDown {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+
+	dataDir := t.TempDir()
+	if _, err := auth.CreateAdmin(dataDir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	engine := parser.NewEngine(dataDir)
+	engine.Execute("LOGIN admin admin123")
+	var out bytes.Buffer
+	runner := NewRunner(engineExecutor{engine}, &out)
+
+	t.Run("DryRun", func(t *testing.T) {
+		runner.DryRun = true
+		defer func() { runner.DryRun = false }()
+		if err := runner.Up(migrations, 0); err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "CREATE TABLE users (id, name)") {
+			t.Errorf("dry run did not print statements: %s", out.String())
+		}
+		if resp := engine.Execute("SELECT * FROM users"); !strings.Contains(resp, "not found") {
+			t.Errorf("dry run changed the database: %s", resp)
+		}
+	})
+
+	t.Run("UpToTarget", func(t *testing.T) {
+		if err := runner.Up(migrations, 2); err != nil {
+			t.Fatalf("up failed: %v", err)
+		}
+		if resp := engine.Execute("SELECT * FROM users"); !strings.Contains(resp, "Asha") {
+			t.Errorf("seed not applied: %s", resp)
+		}
+		if resp := engine.Execute("SELECT * FROM orders"); !strings.Contains(resp, "not found") {
+			t.Errorf("migration past target was applied: %s", resp)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		out.Reset()
+		if err := runner.Status(migrations); err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+		status := out.String()
+		if !strings.Contains(status, "applied   2_seed_users") || !strings.Contains(status, "pending   3_create_orders") {
+			t.Errorf("unexpected status:\n%s", status)
+		}
+	})
+
+	t.Run("UpIsIdempotent", func(t *testing.T) {
+		if err := runner.Up(migrations, 0); err != nil {
+			t.Fatalf("up failed: %v", err)
+		}
+		out.Reset()
+		if err := runner.Up(migrations, 0); err != nil {
+			t.Fatalf("second up failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "No pending migrations") {
+			t.Errorf("expected nothing to apply, got %s", out.String())
+		}
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		if err := runner.Down(migrations, 1); err == nil {
+			t.Fatal("expected error reverting a migration without a down file")
+		}
+		engine.Execute("DROP TABLE orders")
+		engine.Execute("DELETE FROM schema_migrations ROW 2")
+
+		if err := runner.Down(migrations, 2); err != nil {
+			t.Fatalf("down failed: %v", err)
+		}
+		if resp := engine.Execute("SELECT * FROM users"); !strings.Contains(resp, "not found") {
+			t.Errorf("users table should be dropped: %s", resp)
+		}
+		applied, err := runner.Applied()
+		if err != nil || len(applied) != 0 {
+			t.Errorf("expected no applied migrations, got %+v (%v)", applied, err)
+		}
+
+		// The emptied system table is reused
+		if err := runner.Up(migrations, 1); err != nil {
+			t.Fatalf("up after down failed: %v", err)
+		}
+	})
+}
+
+func TestMigrateStopsOnError(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_bad.sql": "INSERT INTO missing VALUES (1);",
+	})
+	migrations, err := Load(dir)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	dataDir := t.TempDir()
+	if _, err := auth.CreateAdmin(dataDir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	engine := parser.NewEngine(dataDir)
+	engine.Execute("LOGIN admin admin123")
+	runner := NewRunner(engineExecutor{engine}, &bytes.Buffer{})
+	if err := runner.Up(migrations, 0); err == nil || !strings.Contains(err.Error(), "1_bad failed at statement 1") {
+		t.Fatalf("expected failure, got %v", err)
+	}
+	if applied, _ := runner.Applied(); len(applied) != 0 {
+		t.Errorf("failed migration was recorded: %+v", applied)
+	}
+}
@@ -0,0 +1,127 @@
+// internal/notify/notify.go
+package notify
+
+import (
+	"sort"
+	"sync"
+)
+
+// QueueSize is how many undelivered notifications a listener buffers before
+// newer ones are dropped
+const QueueSize = 256
+
+// Notification is a payload sent to a channel with NOTIFY
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Hub routes notifications to the listeners of each channel
+type Hub struct {
+	mu        sync.RWMutex
+	listeners map[*Listener]struct{}
+}
+
+// NewHub creates a hub without listeners
+func NewHub() *Hub {
+	return &Hub{listeners: make(map[*Listener]struct{})}
+}
+
+// NewListener registers a listener, typically one per client connection.
+// It receives nothing until it listens on a channel.
+func (h *Hub) NewListener() *Listener {
+	l := &Listener{
+		hub:      h,
+		channels: make(map[string]bool),
+		c:        make(chan Notification, QueueSize),
+	}
+	h.mu.Lock()
+	h.listeners[l] = struct{}{}
+	h.mu.Unlock()
+	return l
+}
+
+// Notify sends payload to every listener on channel and returns how many
+// received it. A listener whose queue is full misses the notification.
+func (h *Hub) Notify(channel, payload string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := Notification{Channel: channel, Payload: payload}
+	delivered := 0
+	for l := range h.listeners {
+		if !l.isListening(channel) {
+			continue
+		}
+		select {
+		case l.c <- n:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+// Listener receives the notifications of the channels it listens on
+type Listener struct {
+	hub      *Hub
+	mu       sync.Mutex
+	channels map[string]bool
+	c        chan Notification
+}
+
+// C delivers notifications; it is closed by Close
+func (l *Listener) C() <-chan Notification {
+	return l.c
+}
+
+// Listen subscribes to channel
+func (l *Listener) Listen(channel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.channels[channel] = true
+}
+
+// Unlisten unsubscribes from channel
+func (l *Listener) Unlisten(channel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.channels, channel)
+}
+
+// UnlistenAll unsubscribes from every channel
+func (l *Listener) UnlistenAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.channels = make(map[string]bool)
+}
+
+// Channels returns the channels listened on, sorted
+func (l *Listener) Channels() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	channels := make([]string, 0, len(l.channels))
+	for ch := range l.channels {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Close unregisters the listener and closes C
+func (l *Listener) Close() {
+	l.hub.mu.Lock()
+	defer l.hub.mu.Unlock()
+	if _, ok := l.hub.listeners[l]; !ok {
+		return
+	}
+	delete(l.hub.listeners, l)
+	close(l.c)
+}
+
+// isListening reports whether the listener is subscribed to channel
+func (l *Listener) isListening(channel string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.channels[channel]
+}
@@ -0,0 +1,50 @@
+package notify
+
+import "testing"
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	a := hub.NewListener()
+	b := hub.NewListener()
+	a.Listen("cache")
+	b.Listen("cache")
+	b.Listen("jobs")
+
+	if n := hub.Notify("cache", "users"); n != 2 {
+		t.Fatalf("cache delivered to %d listeners, want 2", n)
+	}
+	if n := hub.Notify("jobs", ""); n != 1 {
+		t.Fatalf("jobs delivered to %d listeners, want 1", n)
+	}
+	if got := <-a.C(); got != (Notification{Channel: "cache", Payload: "users"}) {
+		t.Errorf("a got %+v", got)
+	}
+	if got := <-b.C(); got.Channel != "cache" {
+		t.Errorf("b got %+v first", got)
+	}
+	if got := <-b.C(); got.Channel != "jobs" {
+		t.Errorf("b got %+v second", got)
+	}
+
+	b.UnlistenAll()
+	a.Close()
+	if n := hub.Notify("cache", "x"); n != 0 {
+		t.Errorf("delivered to %d listeners after unlisten and close", n)
+	}
+	if _, ok := <-a.C(); ok {
+		t.Error("closed listener channel still open")
+	}
+	a.Close()
+}
+
+func TestFullQueueDrops(t *testing.T) {
+	hub := NewHub()
+	l := hub.NewListener()
+	l.Listen("c")
+	for i := 0; i < QueueSize; i++ {
+		hub.Notify("c", "")
+	}
+	if n := hub.Notify("c", "overflow"); n != 0 {
+		t.Errorf("full queue accepted a notification")
+	}
+}
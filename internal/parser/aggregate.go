@@ -0,0 +1,150 @@
+// internal/parser/aggregate.go
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// aggregateFuncs are the aggregate functions SELECT accepts
+var aggregateFuncs = []string{storage.AggCount, storage.AggSum, storage.AggMin, storage.AggMax}
+
+// parseAggregate parses a select list item such as SUM(price), reporting
+// false when it is not an aggregate call
+func parseAggregate(item string) (storage.Aggregate, bool, error) {
+	item = strings.TrimSpace(item)
+	open := strings.Index(item, "(")
+	if open < 0 || !strings.HasSuffix(item, ")") {
+		return storage.Aggregate{}, false, nil
+	}
+	fn := strings.ToUpper(strings.TrimSpace(item[:open]))
+	if !slices.Contains(aggregateFuncs, fn) {
+		return storage.Aggregate{}, false, nil
+	}
+	arg := strings.TrimSpace(item[open+1 : len(item)-1])
+	if arg == "*" {
+		if fn != storage.AggCount {
+			return storage.Aggregate{}, true, fmt.Errorf("%s expects a column, not *", fn)
+		}
+		return storage.Aggregate{Func: fn, Column: "*"}, true, nil
+	}
+	column, err := storage.UnquoteIdentifier(arg)
+	if err != nil {
+		return storage.Aggregate{}, true, err
+	}
+	if column == "" {
+		return storage.Aggregate{}, true, fmt.Errorf("%s expects a column", fn)
+	}
+	return storage.Aggregate{Func: fn, Column: column}, true, nil
+}
+
+// selectsAggregates reports whether a SELECT aggregates its rows: it
+// selects an aggregate call or groups them with GROUP BY
+func selectsAggregates(input string) bool {
+	parts := sqlFields(input)
+	for i := 1; i < len(parts); i++ {
+		if strings.EqualFold(parts[i], "FROM") {
+			for _, item := range splitIdentifiers(strings.Join(parts[1:i], " ")) {
+				if _, ok, _ := parseAggregate(item); ok {
+					return true
+				}
+			}
+			for j := i + 1; j < len(parts)-1; j++ {
+				if strings.EqualFold(parts[j], "GROUP") && strings.EqualFold(parts[j+1], "BY") {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// handleSelectAggregate handles SELECT COUNT(*) | COUNT(col) | SUM(col) |
+// MIN(col) | MAX(col), ... FROM table [WHERE conditions], and SELECT
+// col, ..., aggregate, ... FROM table [WHERE ...] GROUP BY col, ...
+// [ORDER BY col | aggregate [DESC], ...] [LIMIT n]
+func (e *Engine) handleSelectAggregate(input string) (*storage.ResultSet, string) {
+	parts := sqlFields(input)
+	fromIdx := -1
+	for i := 1; i < len(parts); i++ {
+		if strings.EqualFold(parts[i], "FROM") {
+			fromIdx = i
+			break
+		}
+	}
+	if fromIdx < 2 || fromIdx == len(parts)-1 {
+		return nil, ErrSyntaxError
+	}
+	if strings.EqualFold(parts[1], "DISTINCT") {
+		return nil, "Syntax error: DISTINCT is not supported with aggregates or GROUP BY"
+	}
+	if from, _, err := parseFromList(parts[fromIdx+1:]); err == nil && len(from) > 1 {
+		return nil, "Syntax error: aggregates and GROUP BY read from a single table"
+	}
+	tableName, err := parseTableName(parts[fromIdx+1])
+	if err != nil {
+		return nil, fmt.Sprintf("Syntax error: %v", err)
+	}
+	clauses, err := parseSelectClauses(parts[fromIdx+2:])
+	if err != nil {
+		return nil, fmt.Sprintf("Syntax error: %v", err)
+	}
+
+	var results []storage.Aggregate
+	for _, item := range splitIdentifiers(strings.Join(parts[1:fromIdx], " ")) {
+		agg, ok, err := parseAggregate(item)
+		if err != nil {
+			return nil, fmt.Sprintf("Syntax error: %v", err)
+		}
+		if !ok {
+			if clauses.group == nil {
+				return nil, fmt.Sprintf("Syntax error: %s is not an aggregate; a SELECT of aggregates cannot also select columns", strings.TrimSpace(item))
+			}
+			// A grouped column, which the table checks is grouped by
+			column, err := storage.UnquoteIdentifier(strings.TrimSpace(item))
+			if err != nil || column == "" || column == "*" || len(sqlFields(item)) != 1 {
+				return nil, fmt.Sprintf("Syntax error: invalid result column %s (expected: a GROUP BY column or an aggregate)", strings.TrimSpace(item))
+			}
+			agg = storage.Aggregate{Column: column}
+		}
+		results = append(results, agg)
+	}
+
+	var where interface{}
+	if clauses.where != "" {
+		whereExpr, err := ParseWhereClause(clauses.where)
+		if err != nil {
+			return nil, fmt.Sprintf("WHERE clause error: %v", err)
+		}
+		where = whereExpr
+	}
+	role, msg := e.maskRole()
+	if msg != "" {
+		return nil, msg
+	}
+	if clauses.group == nil {
+		if clauses.order != nil || clauses.limit >= 0 {
+			return nil, "Syntax error: ORDER BY and LIMIT are not supported with aggregates"
+		}
+		return e.DB.SelectAggregatesResult(tableName, results, where, role)
+	}
+
+	// ORDER BY names a result column by its heading or as the aggregate
+	for i, key := range clauses.order {
+		if agg, ok, _ := parseAggregate(key.Column); ok {
+			clauses.order[i].Column = agg.Label()
+		}
+	}
+	return e.DB.SelectGroupsResult(tableName, storage.GroupQuery{
+		GroupBy: clauses.group,
+		Results: results,
+		Where:   where,
+		OrderBy: clauses.order,
+		Limit:   clauses.limit,
+		Role:    role,
+	})
+}
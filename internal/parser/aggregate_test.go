@@ -0,0 +1,87 @@
+// internal/parser/aggregate_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectAggregates(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE orders (id INT, customer, total FLOAT)")
+	engine.Execute("INSERT INTO orders VALUES (1, 'ann', 20.5)")
+	engine.Execute("INSERT INTO orders VALUES (2, 'bo', 100)")
+	engine.Execute("INSERT INTO orders VALUES (3, 'ann', NULL)")
+	engine.Execute("INSERT INTO orders VALUES (4, 'cy', 7)")
+
+	for stmt, want := range map[string]string{
+		"SELECT COUNT(*) FROM orders":                                      "count\n4\n",
+		"SELECT count(total), SUM(total), MIN(total), MAX(id) FROM orders": "count(total) | sum(total) | min(total) | max(id)\n3 | 127.5 | 7 | 4\n",
+		"SELECT SUM( total ) FROM orders WHERE customer = 'ann'":           "sum(total)\n20.5\n",
+		"SELECT MIN(customer), MAX(customer) FROM orders WHERE id > 1;":    "min(customer) | max(customer)\nann | cy\n",
+		"SELECT MAX(total) FROM orders WHERE id > 10":                      "max(total)\nNULL\n",
+		"SELECT SUM(customer) FROM orders":                                 "Error: sum(customer): ann is not a number",
+		"SELECT MAX(missing) FROM orders":                                  "Column missing not found",
+		"SELECT SUM(*) FROM orders":                                        "Syntax error: SUM expects a column, not *",
+		"SELECT COUNT(*), id FROM orders":                                  "Syntax error: id is not an aggregate; a SELECT of aggregates cannot also select columns",
+		"SELECT MAX(id) FROM orders ORDER BY id":                           "Syntax error: ORDER BY and LIMIT are not supported with aggregates",
+	} {
+		if got := engine.Execute(stmt); got != want {
+			t.Errorf("%s\n got %q\nwant %q", stmt, got, want)
+		}
+	}
+
+	// Updated rows are aggregated as they are now
+	engine.Execute("UPDATE orders SET total = 50 ROW 1")
+	if got := engine.Execute("SELECT SUM(total), MAX(total) FROM orders"); got != "sum(total) | max(total)\n77.5 | 50\n" {
+		t.Errorf("after update: %q", got)
+	}
+	if got := engine.Execute("SELECT id, customer FROM orders WHERE id = 4"); !strings.Contains(got, "4 | cy") {
+		t.Errorf("plain SELECT of columns: %q", got)
+	}
+}
+
+func TestSelectGroupBy(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE sales (id INT, region, product, amount INT)")
+	for _, values := range []string{
+		"(1, 'north', 'tea', 10)",
+		"(2, 'south', 'tea', 5)",
+		"(3, 'north', 'coffee', 7)",
+		"(4, 'north', 'tea', 3)",
+		"(5, 'east', 'coffee', NULL)",
+	} {
+		engine.Execute("INSERT INTO sales VALUES " + values)
+	}
+
+	for _, tc := range []struct{ stmt, want string }{
+		{"SELECT region, COUNT(*), SUM(amount) FROM sales GROUP BY region",
+			"region | count | sum(amount)\nnorth | 3 | 20\nsouth | 1 | 5\neast | 1 | NULL\n"},
+		{"SELECT region, product, MAX(amount) FROM sales GROUP BY region, product",
+			"region | product | max(amount)\nnorth | tea | 10\nsouth | tea | 5\nnorth | coffee | 7\neast | coffee | NULL\n"},
+		{"SELECT COUNT(*), product FROM sales WHERE amount > 4 GROUP BY product",
+			"count | product\n2 | tea\n1 | coffee\n"},
+		{"SELECT region FROM sales GROUP BY region ORDER BY region",
+			"region\neast\nnorth\nsouth\n"},
+		{"SELECT region, COUNT(*) FROM sales GROUP BY region ORDER BY COUNT(*) DESC, region LIMIT 2",
+			"region | count\nnorth | 3\neast | 1\n"},
+		{"SELECT product, SUM(amount) FROM sales GROUP BY product ORDER BY sum(amount)",
+			"product | sum(amount)\ncoffee | 7\ntea | 18\n"},
+		{"SELECT region FROM sales WHERE id > 10 GROUP BY region", "region\n(no rows)\n"},
+		{"SELECT region, product FROM sales GROUP BY region",
+			"Error: column product must appear in GROUP BY or be used in an aggregate"},
+		{"SELECT * FROM sales GROUP BY region",
+			"Syntax error: invalid result column * (expected: a GROUP BY column or an aggregate)"},
+		{"SELECT region FROM sales GROUP BY missing", "Column missing not found"},
+		{"SELECT region, COUNT(*) FROM sales GROUP BY region ORDER BY amount", "Column amount not found"},
+		{"SELECT region FROM sales ORDER BY region GROUP BY region", "Syntax error: ORDER BY expects a column and optional ASC or DESC"},
+		{"SELECT DISTINCT region FROM sales GROUP BY region", "Syntax error: DISTINCT is not supported with aggregates or GROUP BY"},
+		{"EXPLAIN SELECT region FROM sales GROUP BY region", "Syntax error: GROUP BY is not supported here"},
+	} {
+		if got := engine.Execute(tc.stmt); got != tc.want {
+			t.Errorf("%s\n got %q\nwant %q", tc.stmt, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// internal/parser/alter.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleAlterTable handles ALTER TABLE old RENAME TO new,
+// ALTER TABLE t SET LOGGED | SET UNLOGGED,
+// ALTER TABLE t SET (encrypted=true|false) and
+// ALTER TABLE t SET RETENTION limits | NONE
+func (e *Engine) handleAlterTable(input string) string {
+	parts := sqlFields(input)
+	if len(parts) >= 5 && strings.EqualFold(parts[3], "SET") && strings.EqualFold(parts[4], "RETENTION") {
+		return e.handleSetRetention(parts)
+	}
+	if len(parts) >= 5 && strings.EqualFold(parts[3], "SET") && strings.HasPrefix(parts[4], "(") {
+		return e.handleSetEncryption(parts)
+	}
+	if len(parts) == 5 && strings.EqualFold(parts[3], "SET") {
+		return e.handleSetLogged(parts)
+	}
+	if len(parts) != 6 || !strings.EqualFold(parts[3], "RENAME") || !strings.EqualFold(parts[4], "TO") {
+		return "Syntax error: ALTER TABLE old_name RENAME TO new_name"
+	}
+	oldName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	newName, err := parseNewTableName(parts[5])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.RenameTableTx(oldName, newName)
+}
+
+// handleSetLogged handles ALTER TABLE t SET LOGGED and SET UNLOGGED
+func (e *Engine) handleSetLogged(parts []string) string {
+	var logged bool
+	switch strings.ToUpper(parts[4]) {
+	case "LOGGED":
+		logged = true
+	case "UNLOGGED":
+	default:
+		return "Syntax error: ALTER TABLE t SET LOGGED | SET UNLOGGED"
+	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.SetLogged(tableName, logged)
+}
+
+// handleSetRetention handles ALTER TABLE t SET RETENTION 30 DAYS, 1000 ROWS
+// and SET RETENTION NONE
+func (e *Engine) handleSetRetention(parts []string) string {
+	if len(parts) < 6 {
+		return "Syntax error: ALTER TABLE t SET RETENTION n DAYS|n ROWS|n MB[, ...] | NONE"
+	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	r, err := storage.ParseRetention(strings.Join(parts[5:], " "))
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.SetRetention(tableName, r)
+}
@@ -0,0 +1,89 @@
+// internal/parser/alter_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlterTableRename(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES (1, 'Alice')")
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"ALTER TABLE users RENAME new_users", "Syntax error: ALTER TABLE old_name RENAME TO new_name"},
+		{"ALTER TABLE missing RENAME TO x", "Table missing not found"},
+		{`ALTER TABLE "Users" RENAME TO "Members"`, "Table users renamed to members"},
+		{"SELECT * FROM users", "Table users not found"},
+		{"SELECT * FROM members", "id | name\n1 | Alice\n"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+
+	// Inside a transaction the rename is applied on commit
+	engine.Execute("BEGIN TRANSACTION")
+	if got := engine.Execute("ALTER TABLE members RENAME TO people"); !strings.Contains(got, "queued") {
+		t.Fatalf("rename in transaction: %s", got)
+	}
+	engine.Execute("COMMIT")
+	if got := engine.Execute("SELECT * FROM people"); got != "id | name\n1 | Alice\n" {
+		t.Errorf("after commit: %q", got)
+	}
+}
+
+func TestUnloggedTables(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"CREATE UNLOGGED TABLE cache (key, value)", "Unlogged table cache created"},
+		{"INSERT INTO cache VALUES ('a', '1')", "1 row inserted with secure page-based storage"},
+		{"SHOW CREATE TABLE cache", "statement\nCREATE UNLOGGED TABLE cache (key, value)\n"},
+		{"ALTER TABLE cache SET LOGGED", "Table cache set logged"},
+		{"ALTER TABLE cache SET LOGGED", "Table cache is already logged"},
+		{"ALTER TABLE cache SET UNLOGGED", "Table cache set unlogged"},
+		{"ALTER TABLE cache SET DURABLE", "Syntax error: ALTER TABLE t SET LOGGED | SET UNLOGGED"},
+		{"CREATE UNLOGGED TABLE (id)", ErrSyntaxError},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestSetRetention(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE logs (msg)")
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"ALTER TABLE logs SET RETENTION 30 DAYS, 1000 ROWS", "Retention of table logs set to 30 DAYS, 1000 ROWS"},
+		{"ALTER TABLE logs SET RETENTION 64 mb", "Retention of table logs set to 64 MB"},
+		{"ALTER TABLE logs SET RETENTION NONE", "Retention policy removed from table logs"},
+		{"ALTER TABLE missing SET RETENTION 1 DAYS", "Table missing not found"},
+		{"ALTER TABLE logs SET RETENTION", "Syntax error: ALTER TABLE t SET RETENTION n DAYS|n ROWS|n MB[, ...] | NONE"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+	if got := engine.Execute("ALTER TABLE logs SET RETENTION 3 YEARS"); !strings.HasPrefix(got, "Syntax error: unknown retention unit") {
+		t.Errorf("unknown unit: %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+// internal/parser/batch.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+// ExecuteBatch runs statements as one implicit transaction: its writes go to
+// the WAL as a single commit record and each changed table is saved once,
+// which makes bulk loads much faster than running the statements one by one. The first failing statement rolls the
+// whole batch back. Inside an open transaction the statements join it
+// instead. The batch and each of its statements get their own query IDs.
+// A failure is returned as its message.
+func (e *Engine) ExecuteBatch(statements []string) string {
+	result, err := e.ExecuteBatchQuery(e.StartQuery(fmt.Sprintf("BATCH %d", len(statements))), statements)
+	if err != nil {
+		return err.Error()
+	}
+	return result
+}
+
+// ExecuteBatchQuery is ExecuteBatch for a batch registered with StartQuery,
+// returning a failure as a *protocol.Error with the code of the statement
+// that failed. Killing the batch stops it before its next statement.
+func (e *Engine) ExecuteBatchQuery(q *RunningQuery, statements []string) (string, error) {
+	start := time.Now()
+	defer func() { e.finishQuery(q, time.Since(start)) }()
+
+	if err := e.requireAuth(); err != "" {
+		return messageResult(err)
+	}
+	if len(statements) == 0 {
+		return messageResult("Syntax error: empty batch")
+	}
+	for i, stmt := range statements {
+		cmd := lookupCommand(strings.ToUpper(strings.TrimSpace(stmt)))
+		if cmd != nil && cmd.section == "Transactions" {
+			return messageResult(fmt.Sprintf("Error: batch statement %d: %s is not allowed in a batch", i+1, cmd.prefix))
+		}
+	}
+
+	ownTx := e.DB.GetCurrentTransaction() == nil
+	if ownTx {
+		if _, err := e.DB.BeginBatch(); err != nil {
+			return messageResult(fmt.Sprintf("Failed to begin batch: %v", err))
+		}
+	}
+
+	for i, stmt := range statements {
+		_, err := e.Exec(stmt)
+		if q.isKilled() {
+			err = MessageError(KilledResult(q.ID))
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Error: batch failed at statement %d: %v", i+1, err)
+			if ownTx {
+				e.DB.RollbackTransaction()
+				e.sendPendingNotifications(false)
+				msg = fmt.Sprintf("Error: batch failed at statement %d, no changes applied: %v", i+1, err)
+			}
+			return "", &protocol.Error{Code: protocol.CodeOf(err), Message: msg}
+		}
+	}
+
+	if ownTx {
+		if err := e.DB.CommitTransaction(); err != nil {
+			e.sendPendingNotifications(false)
+			return messageResult(fmt.Sprintf("Error: batch failed, no changes applied: %v", err))
+		}
+		e.sendPendingNotifications(true)
+	}
+	return fmt.Sprintf("Batch executed (%d statement(s))", len(statements)), nil
+}
@@ -0,0 +1,56 @@
+// internal/parser/batch_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	if got := engine.ExecuteBatch([]string{"SELECT * FROM users"}); got != ErrNotAuthenticated {
+		t.Fatalf("batch before login: %s", got)
+	}
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id, name)")
+	engine.Execute("INSERT INTO items VALUES (0, 'seed')")
+
+	got := engine.ExecuteBatch([]string{
+		"INSERT INTO items VALUES (1, 'apple')",
+		"INSERT INTO items VALUES (2, 'pear');",
+		"UPDATE items SET name = 'sprout' ROW 0",
+	})
+	if got != "Batch executed (3 statement(s))" {
+		t.Fatalf("batch: %s", got)
+	}
+	if got := engine.Execute("SELECT * FROM items"); got != "id | name\n0 | sprout\n1 | apple\n2 | pear\n" {
+		t.Errorf("after batch: %q", got)
+	}
+
+	// A failing statement rolls back the whole batch
+	got = engine.ExecuteBatch([]string{
+		"INSERT INTO items VALUES (3, 'plum')",
+		"INSERT INTO missing VALUES (1)",
+	})
+	if !strings.HasPrefix(got, "Error: batch failed at statement 2, no changes applied: Table missing not found") {
+		t.Errorf("failed batch: %s", got)
+	}
+	if got := engine.Execute("SELECT COUNT(*) FROM items"); got != "count\n3\n" {
+		t.Errorf("failed batch left rows: %q", got)
+	}
+	if engine.DB.GetCurrentTransaction() != nil {
+		t.Error("failed batch left its transaction open")
+	}
+
+	if got := engine.ExecuteBatch([]string{"INSERT INTO items VALUES (3, 'plum')", "COMMIT"}); got != "Error: batch statement 2: COMMIT is not allowed in a batch" {
+		t.Errorf("COMMIT in batch: %s", got)
+	}
+
+	// Inside a transaction the batch joins it
+	engine.Execute("BEGIN")
+	engine.ExecuteBatch([]string{"INSERT INTO items VALUES (3, 'plum')"})
+	engine.Execute("ROLLBACK")
+	if got := engine.Execute("SELECT COUNT(*) FROM items"); got != "count\n3\n" {
+		t.Errorf("batch survived the enclosing rollback: %q", got)
+	}
+}
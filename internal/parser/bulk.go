@@ -0,0 +1,168 @@
+// internal/parser/bulk.go
+//
+// UPDATE and DELETE with a WHERE clause. The matching rows are collected
+// first and then changed in batches of bulk_batch_size rows. Outside a
+// transaction each batch commits on its own, so a large change writes many
+// moderate WAL commit records instead of one huge one and releases the
+// table between batches. A failure stops the statement, keeping the batches
+// already committed. Inside a transaction the rows join it, all or nothing.
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultBulkBatchSize is how many rows an UPDATE or DELETE with a WHERE
+// clause changes per batch when the session has not SET bulk_batch_size
+const DefaultBulkBatchSize = 1000
+
+// normalizeBatchSize accepts a positive number of rows
+func normalizeBatchSize(v string) (string, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("must be a positive number of rows")
+	}
+	return strconv.Itoa(n), nil
+}
+
+// normalizeProgressChannel accepts a notification channel name
+func normalizeProgressChannel(v string) (string, error) {
+	return parseChannel(v)
+}
+
+// bulkBatchSize returns the session's bulk_batch_size
+func (e *Engine) bulkBatchSize() int {
+	n, err := strconv.Atoi(e.setting("bulk_batch_size"))
+	if err != nil || n < 1 {
+		return DefaultBulkBatchSize
+	}
+	return n
+}
+
+// keywordIndex returns the index of the first of parts that is keyword, or
+// -1
+func keywordIndex(parts []string, keyword string) int {
+	for i, part := range parts {
+		if strings.EqualFold(part, keyword) {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleUpdateWhere handles UPDATE table SET col = value, ... WHERE ...
+func (e *Engine) handleUpdateWhere(tableName, setClause, whereClause string) string {
+	if strings.TrimSpace(setClause) == "" {
+		return "Syntax error: missing SET clause"
+	}
+	return e.bulkWrite("UPDATE", tableName, whereClause, func(id int64, columns, row []string) string {
+		if msg := e.applyAssignments(tableName, columns, row, setClause); msg != "" {
+			return msg
+		}
+		return e.DB.UpdateByIDTx(tableName, id, row)
+	})
+}
+
+// handleDeleteWhere handles DELETE FROM table WHERE ...
+func (e *Engine) handleDeleteWhere(tableName, whereClause string) string {
+	return e.bulkWrite("DELETE", tableName, whereClause, func(id int64, columns, row []string) string {
+		return e.DB.DeleteByIDTx(tableName, id)
+	})
+}
+
+// bulkWrite runs write on every row of tableName matching whereClause, in
+// batches. A row changed since the rows were collected is written only if
+// it still matches.
+func (e *Engine) bulkWrite(verb, tableName, whereClause string, write func(id int64, columns, row []string) string) string {
+	if strings.TrimSpace(whereClause) == "" {
+		return fmt.Sprintf("Syntax error: %s expects a condition after WHERE", verb)
+	}
+	whereExpr, err := ParseWhereClause(whereClause)
+	if err != nil {
+		return fmt.Sprintf("WHERE clause error: %v", err)
+	}
+	ids, msg := e.DB.MatchingRowIDs(tableName, whereExpr)
+	if msg != "" {
+		return msg
+	}
+
+	ownTx := e.DB.GetCurrentTransaction() == nil
+	size := e.bulkBatchSize()
+	if !ownTx {
+		size = max(len(ids), 1)
+	}
+	past := map[string]string{"UPDATE": "updated", "DELETE": "deleted"}[verb]
+	written := 0
+	for start := 0; start < len(ids); start += size {
+		batch := ids[start:min(start+size, len(ids))]
+		if ownTx {
+			if _, err := e.DB.BeginBatch(); err != nil {
+				return fmt.Sprintf("Failed to begin batch: %v", err)
+			}
+		}
+		n, failure := e.writeBatch(tableName, whereExpr, batch, write)
+		if failure == "" && ownTx {
+			if err := e.DB.CommitTransaction(); err != nil {
+				failure = fmt.Sprintf("commit failed: %v", err)
+				e.sendPendingNotifications(false)
+			} else {
+				e.sendPendingNotifications(true)
+			}
+		}
+		if failure != "" {
+			if !ownTx {
+				return fmt.Sprintf("Error: %s failed: %s", verb, failure)
+			}
+			if e.DB.GetCurrentTransaction() != nil {
+				e.DB.RollbackTransaction()
+				e.sendPendingNotifications(false)
+			}
+			return fmt.Sprintf("Error: %s failed after %d rows were %s and committed: %s", verb, written, past, failure)
+		}
+		written += n
+		e.notifyBulkProgress(verb, tableName, start+len(batch), len(ids))
+	}
+	if !ownTx {
+		return fmt.Sprintf("%d row %ss queued in transaction", written, strings.ToLower(verb))
+	}
+	return fmt.Sprintf("%d rows %s", written, past)
+}
+
+// writeBatch runs write on the rows with the given IDs that still match
+// whereExpr, and returns how many it wrote or why it failed
+func (e *Engine) writeBatch(tableName string, whereExpr *WhereExpression, ids []int64, write func(id int64, columns, row []string) string) (int, string) {
+	columns, rows, msg := e.DB.ReadRowsByID(tableName, ids)
+	if msg != "" {
+		return 0, msg
+	}
+	columnIndexes := make(map[string]int, len(columns))
+	for i, col := range columns {
+		columnIndexes[col] = i
+	}
+	n := 0
+	for i, row := range rows {
+		if row == nil {
+			continue // deleted since the rows were collected
+		}
+		if ok, err := whereExpr.EvaluateExpression(row, columnIndexes); err != nil {
+			return n, err.Error()
+		} else if !ok {
+			continue
+		}
+		if result := write(ids[i], columns, row); MessageError(result) != nil {
+			return n, result
+		}
+		n++
+	}
+	return n, ""
+}
+
+// notifyBulkProgress sends "UPDATE table done/total" to the session's
+// bulk_progress_channel, if it has one
+func (e *Engine) notifyBulkProgress(verb, tableName string, done, total int) {
+	if channel := e.setting("bulk_progress_channel"); channel != "" {
+		e.Notifications.Notify(channel, fmt.Sprintf("%s %s %d/%d", verb, tableName, done, total))
+	}
+}
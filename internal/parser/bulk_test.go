@@ -0,0 +1,105 @@
+// internal/parser/bulk_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBulkUpdateDelete(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id INT, kind TEXT, qty INT)")
+	for _, stmt := range []string{
+		"INSERT INTO items VALUES (1, 'a', 10)",
+		"INSERT INTO items VALUES (2, 'b', 20)",
+		"INSERT INTO items VALUES (3, 'a', 30)",
+		"INSERT INTO items VALUES (4, 'a', 40)",
+		"INSERT INTO items VALUES (5, 'b', 50)",
+	} {
+		engine.Execute(stmt)
+	}
+
+	l := engine.Notifications.NewListener()
+	defer l.Close()
+	l.Listen("bulk")
+
+	steps := []struct{ input, want string }{
+		{"SET bulk_batch_size = 2", "SET bulk_batch_size = 2"},
+		{"SET bulk_batch_size = 0", "Error: invalid value for bulk_batch_size: must be a positive number of rows"},
+		{"SET bulk_progress_channel = Bulk", "SET bulk_progress_channel = bulk"},
+		{"UPDATE items SET qty = 0, kind = 'z' WHERE kind = 'a'", "3 rows updated"},
+		{"SELECT id, kind, qty FROM items", "id | kind | qty\n1 | z | 0\n2 | b | 20\n3 | z | 0\n4 | z | 0\n5 | b | 50\n"},
+		{"UPDATE items SET qty = 1 WHERE kind = 'none'", "0 rows updated"},
+		{"UPDATE items SET qty = 1 WHERE", "Syntax error: UPDATE expects a condition after WHERE"},
+		{"UPDATE items SET nope = 1 WHERE id = 1", "Error: UPDATE failed after 0 rows were updated and committed: Column nope not found"},
+		{"DELETE FROM items WHERE qty = 0", "3 rows deleted"},
+		{"SELECT id FROM items", "id\n2\n5\n"},
+		{"DELETE FROM missing WHERE id = 1", "Table missing not found"},
+	}
+	for _, step := range steps {
+		if got := engine.Execute(step.input); got != step.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", step.input, got, step.want)
+		}
+	}
+
+	var progress []string
+	for len(l.C()) > 0 {
+		progress = append(progress, (<-l.C()).Payload)
+	}
+	want := "UPDATE items 2/3,UPDATE items 3/3,DELETE items 2/3,DELETE items 3/3"
+	if got := strings.Join(progress, ","); got != want {
+		t.Errorf("progress %s, want %s", got, want)
+	}
+}
+
+func TestBulkDeleteInTransaction(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id INT, qty INT)")
+	for _, stmt := range []string{
+		"INSERT INTO items VALUES (1, 10)",
+		"INSERT INTO items VALUES (2, 20)",
+		"INSERT INTO items VALUES (3, 30)",
+	} {
+		engine.Execute(stmt)
+	}
+
+	steps := []struct{ input, want string }{
+		{"SET bulk_batch_size = 1", "SET bulk_batch_size = 1"},
+		{"BEGIN TRANSACTION", ""},
+		{"DELETE FROM items WHERE qty > 10", "2 row deletes queued in transaction"},
+		{"ROLLBACK", ""},
+		{"SELECT id FROM items", "id\n1\n2\n3\n"},
+	}
+	for _, step := range steps {
+		got := engine.Execute(step.input)
+		if step.want != "" && got != step.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", step.input, got, step.want)
+		}
+	}
+}
+
+func TestBulkKeepsCommittedBatches(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE customers (id INT, name)")
+	engine.Execute("CREATE TABLE orders (id INT, customer_id INT, FOREIGN KEY (customer_id) REFERENCES customers(id))")
+	for _, stmt := range []string{
+		"INSERT INTO customers VALUES (1, 'ann')",
+		"INSERT INTO customers VALUES (2, 'bob')",
+		"INSERT INTO customers VALUES (3, 'cy')",
+		"INSERT INTO orders VALUES (1, 2)",
+	} {
+		engine.Execute(stmt)
+	}
+	engine.Execute("SET bulk_batch_size = 1")
+
+	got := engine.Execute("DELETE FROM customers WHERE id > 0")
+	if !strings.HasPrefix(got, "Error: DELETE failed after 1 rows were deleted and committed: ") {
+		t.Fatalf("DELETE: %s", got)
+	}
+	if got := engine.Execute("SELECT id FROM customers"); got != "id\n2\n3\n" {
+		t.Errorf("after failed DELETE: %q", got)
+	}
+}
@@ -0,0 +1,427 @@
+// internal/parser/commands.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// command is a statement the engine understands. Execute runs the first
+// command whose prefix starts the statement, and HELP is generated from the
+// same list.
+type command struct {
+	// prefix is the upper-case start of the statement
+	prefix  string
+	section string
+	// syntax is a one-line usage summary; details are further usage lines
+	syntax  string
+	details []string
+	summary string
+	// public commands run without logging in
+	public bool
+	// privilege is what the session's role must hold to run the command
+	privilege privilege
+	// secrets are keywords followed by a secret, such as PASSPHRASE, which
+	// the query stats and the process list never show
+	secrets []string
+	// run executes the statement; nil for commands handled by the server
+	run func(e *Engine, input string) string
+	// query executes a statement that returns rows, in place of run. A nil
+	// result set means the message describes why there are none.
+	query func(e *Engine, input string) (*storage.ResultSet, string)
+}
+
+// helpSections orders the sections of HELP
+var helpSections = []string{
+	"Authentication", "Database Operations", "Transactions", "Procedures", "Backup & Restore",
+	"Cursors", "Key-Value", "Notifications", "Session", "Server", "Replication", "Export", "Other",
+}
+
+// passphrase is the secret of the commands reading or writing encrypted
+// backups
+var passphrase = []string{"PASSPHRASE"}
+
+// commands lists every statement in matching order: a prefix must come
+// before any shorter prefix it extends, such as BACKUP INFO before BACKUP
+var commands []command
+
+func init() {
+	commands = []command{
+		{prefix: "SELECT VERSION", section: "Server", public: true,
+			syntax: "SELECT VERSION()", summary: "Show the server version",
+			query: (*Engine).handleVersion},
+		{prefix: "SHOW SERVER INFO", section: "Server",
+			syntax: "SHOW SERVER INFO", summary: "Show version, build, uptime and configuration",
+			query: (*Engine).handleShowServerInfo},
+		{prefix: "SHOW STATUS", section: "Server",
+			syntax: "SHOW STATUS", summary: "Show whether the server is healthy or running degraded",
+			details: []string{"Without a WAL the server is degraded; --wal-failure-policy decides whether writes are rejected or accepted with a warning"},
+			query:   (*Engine).handleShowStatus},
+
+		{prefix: "CHECKPOINT", section: "Server", privilege: privAdmin,
+			syntax: "CHECKPOINT", summary: "Write dirty pages to disk and truncate the WAL",
+			run: (*Engine).handleCheckpoint},
+		{prefix: "FLUSH TABLES", section: "Server", privilege: privAdmin,
+			syntax: "FLUSH TABLES", summary: "Rewrite and sync every table file, then checkpoint",
+			details: []string{"Run before taking a filesystem-level snapshot of the data directory"},
+			run:     (*Engine).handleFlushTables},
+		{prefix: "FLUSH PAGE CACHE", section: "Server", privilege: privAdmin,
+			syntax: "FLUSH PAGE CACHE", summary: "Checkpoint, then drop every cached page (Admin only)",
+			details: []string{"Following reads load pages from disk, e.g. to measure cold-cache performance"},
+			run:     (*Engine).handleFlushCaches},
+		{prefix: "FLUSH PATTERN CACHE", section: "Server", privilege: privAdmin,
+			syntax: "FLUSH PATTERN CACHE", summary: "Drop compiled ~ regular expressions (Admin only)",
+			run: (*Engine).handleFlushCaches},
+		{prefix: "FLUSH CACHES", section: "Server", privilege: privAdmin,
+			syntax: "FLUSH CACHES", summary: "Flush the page and pattern caches (Admin only)",
+			run: (*Engine).handleFlushCaches},
+		{prefix: "PRELOAD TABLE", section: "Server", privilege: privAdmin,
+			syntax: "PRELOAD TABLE table", summary: "Load a table into memory ahead of queries (Admin only)",
+			details: []string{"Reads a table's pages into the page cache; an external table's rows",
+				"are kept in memory and read again only when its file changes"},
+			run: (*Engine).handlePreload},
+		{prefix: "SHOW QUERY STATS", section: "Server", privilege: privAdmin,
+			syntax: "SHOW QUERY STATS [n]", summary: "Show per-statement call counts, latency and rows (Admin only)",
+			details: []string{"Literals are replaced with ? so statements differing only in values are counted together",
+				"Sorted by total time; n limits the output to the top n statements"},
+			query: (*Engine).handleShowQueryStats},
+		{prefix: "RESET QUERY STATS", section: "Server", privilege: privAdmin,
+			syntax: "RESET QUERY STATS", summary: "Clear the query stats (Admin only)",
+			run: (*Engine).handleResetQueryStats},
+		{prefix: "SHOW LOCKS", section: "Server", privilege: privAdmin,
+			syntax: "SHOW LOCKS", summary: "List table locks held and waited for (Admin only)",
+			details: []string{"Shows each lock's mode, the user that asked for it and how long it was waited for and held"},
+			query:   (*Engine).handleShowLocks},
+		{prefix: "SHOW PROCESSLIST", section: "Server", privilege: privAdmin,
+			syntax: "SHOW PROCESSLIST", summary: "List running statements with their query IDs (Admin only)",
+			details: []string{"Every statement gets a query ID, sent back with the prompt after its response"},
+			query:   (*Engine).handleShowProcesslist},
+		{prefix: "SHOW CONNECTIONS", section: "Server", privilege: privAdmin,
+			syntax: "SHOW CONNECTIONS", summary: "List client connections and whether each is idle (Admin only)",
+			details: []string{"A connection is active, idle, or idle in transaction when it began the open one;",
+				"a transaction left open by a connection that goes away is rolled back"},
+			query: (*Engine).handleShowConnections},
+		{prefix: "KILL", section: "Server", privilege: privAdmin,
+			syntax: "KILL query_id", summary: "Stop waiting for a running statement (Admin only)",
+			details: []string{"Its client gets an error at once; a batch stops before its next statement and is rolled back"},
+			run:     (*Engine).handleKill},
+		{prefix: "SHOW WAL RECORDS", section: "Server", privilege: privAdmin,
+			syntax: "SHOW WAL RECORDS [n]", summary: "Show the last n WAL records (default 20)",
+			query: (*Engine).handleShowWALRecords},
+		{prefix: "SHOW WAL", section: "Server", privilege: privAdmin,
+			syntax: "SHOW WAL", summary: "Show WAL size, LSNs and replay lag",
+			query: (*Engine).handleShowWAL},
+		{prefix: "REKEY", section: "Server", privilege: privAdmin,
+			syntax: "REKEY [TABLE t]", summary: "Re-encrypt encrypted tables with a new key (Admin only)",
+			details: []string{"Keys no table uses any more are removed from keys.json"},
+			run:     (*Engine).handleRekey},
+		{prefix: "SHOW ENCRYPTION KEYS", section: "Server", privilege: privAdmin,
+			syntax: "SHOW ENCRYPTION KEYS", summary: "List encryption key IDs",
+			query: (*Engine).handleShowEncryptionKeys},
+		{prefix: "SHOW ENCRYPTION", section: "Server", privilege: privAdmin,
+			syntax: "SHOW ENCRYPTION", summary: "Show which tables are encrypted and with which key",
+			query: (*Engine).handleShowEncryption},
+
+		{prefix: "SHOW REPLICATION STATUS", section: "Replication", privilege: privAdmin,
+			syntax: "SHOW REPLICATION STATUS", summary: "Show peers, LSNs and lag",
+			run: func(e *Engine, input string) string { return e.handleShowReplicationStatus() }},
+
+		{prefix: "BEGIN", section: "Transactions",
+			syntax: "BEGIN TRANSACTION", summary: "Start transaction",
+			details: []string{"[ISOLATION LEVEL READ COMMITTED|REPEATABLE READ|SERIALIZABLE]"},
+			run:     (*Engine).handleBeginTransaction},
+		{prefix: "COMMIT", section: "Transactions",
+			syntax: "COMMIT", summary: "Commit transaction",
+			run: func(e *Engine, input string) string { return e.handleCommitTransaction() }},
+		{prefix: "ROLLBACK", section: "Transactions",
+			syntax: "ROLLBACK", summary: "Rollback transaction",
+			details: []string{"[TO SAVEPOINT name] - Undo changes since a savepoint"},
+			run:     (*Engine).handleRollbackTransaction},
+		{prefix: "SAVEPOINT", section: "Transactions",
+			syntax: "SAVEPOINT name", summary: "Create savepoint",
+			run: (*Engine).handleSavepoint},
+		{prefix: "BATCH", section: "Transactions",
+			syntax: "BATCH n", summary: "Run the next n lines as one transaction",
+			details: []string{"Logged to the WAL once; each table is saved once"}},
+
+		{prefix: "CREATE PROCEDURE", section: "Procedures", privilege: privWrite,
+			syntax: "CREATE PROCEDURE p(a, b) AS BEGIN stmt; ... END", summary: "Store statements using :a, :b",
+			run: (*Engine).handleCreateProcedure},
+		{prefix: "DROP PROCEDURE", section: "Procedures", privilege: privWrite,
+			syntax: "DROP PROCEDURE p", summary: "Drop a procedure",
+			run: (*Engine).handleDropProcedure},
+		{prefix: "CALL", section: "Procedures",
+			syntax: "CALL p(1, 'x')", summary: "Run a procedure atomically",
+			run: (*Engine).handleCall},
+		{prefix: "SHOW PROCEDURES", section: "Procedures",
+			syntax: "SHOW PROCEDURES", summary: "List procedures",
+			run: func(e *Engine, input string) string { return e.handleShowProcedures() }},
+
+		{prefix: "CREATE TABLE", section: "Database Operations", privilege: privWrite,
+			syntax: "CREATE TABLE name (col1, col2)", summary: "Create table",
+			details: []string{"col INT|FLOAT|TEXT|BOOL - Column type checked on INSERT and UPDATE (default: any value)",
+				"col NOT NULL - Reject NULL values",
+				"col DEFAULT 'text'|42|NULL - Value for columns an INSERT leaves out",
+				`col COLLATE NOCASE|<locale> - Column collation (default BINARY)`,
+				"FOREIGN KEY (col) REFERENCES t(col) [ON DELETE RESTRICT|CASCADE] - Only allow values present in t",
+				`"quoted name" - Names with spaces or reserved words`,
+				"... WITH (encrypted=true|false) - Encrypt the table's files at rest",
+				"... WITH (versioned=true) - Add a _version column for optimistic locking",
+				"... WITH (timestamps=true) - Add created_at and updated_at columns"},
+			run: (*Engine).handleCreateTable},
+		{prefix: "CREATE EXTERNAL TABLE", section: "Database Operations", privilege: privAdmin,
+			syntax: "CREATE EXTERNAL TABLE t (col, ...)", summary: "Query a CSV file in place (Admin only)",
+			details: []string{"LOCATION 'file.csv' [HEADER] - Read-only, re-read on every scan"},
+			run:     (*Engine).handleCreateExternalTable},
+		{prefix: "CREATE UNLOGGED TABLE", section: "Database Operations", privilege: privWrite,
+			syntax: "CREATE UNLOGGED TABLE name (col1, col2)", summary: "Create a table whose writes skip the WAL",
+			details: []string{"Faster writes; recent rows may be lost on a crash"},
+			run:     (*Engine).handleCreateUnloggedTable},
+		{prefix: "DROP TABLE", section: "Database Operations", privilege: privWrite,
+			syntax: "DROP TABLE name", summary: "Drop table",
+			run: (*Engine).handleDropTable},
+		{prefix: "ALTER TABLE", section: "Database Operations", privilege: privWrite,
+			syntax: "ALTER TABLE old RENAME TO new", summary: "Rename table",
+			details: []string{"ALTER TABLE t SET LOGGED|UNLOGGED - Switch WAL logging",
+				"ALTER TABLE t SET RETENTION 30 DAYS|10000 ROWS|100 MB[, ...]|NONE - Delete the oldest rows beyond a limit",
+				"ALTER TABLE t SET (encrypted=true|false) - Encrypt or decrypt (Admin only)"},
+			run: (*Engine).handleAlterTable},
+		{prefix: "COMMENT ON", section: "Database Operations", privilege: privWrite,
+			syntax: "COMMENT ON TABLE t IS 'text'", summary: "Describe a table (IS NULL removes)",
+			details: []string{"COMMENT ON COLUMN t.col IS 'text' - Describe a column"},
+			run:     (*Engine).handleComment},
+		{prefix: "CREATE MASK", section: "Database Operations", privilege: privAdmin,
+			syntax: "CREATE MASK ON t (col) FOR ROLE role USING 'method'", summary: "Redact a column in query results for a role",
+			details: []string{"role: user|readonly; method: 'full', 'partial' or 'hash'"},
+			run:     (*Engine).handleCreateMask},
+		{prefix: "DROP MASK", section: "Database Operations", privilege: privAdmin,
+			syntax: "DROP MASK ON t (col) FOR ROLE role", summary: "Remove a column mask",
+			run: (*Engine).handleDropMask},
+		{prefix: "DESCRIBE", section: "Database Operations",
+			syntax: "DESCRIBE table", summary: "Show columns, indexes and comments",
+			query: (*Engine).handleDescribe},
+		{prefix: "SHOW CREATE TABLE", section: "Database Operations",
+			syntax: "SHOW CREATE TABLE table", summary: "Show statements that recreate a table",
+			query: (*Engine).handleShowCreateTable},
+		{prefix: "SHOW TABLE STATS", section: "Database Operations",
+			syntax: "SHOW TABLE STATS [table]", summary: "Show reads, writes and index lookups per table",
+			query: (*Engine).handleShowTableStats},
+		{prefix: "SHOW TABLES", section: "Database Operations",
+			syntax: "SHOW TABLES", summary: "List tables with row counts, sizes and index cardinalities",
+			query: (*Engine).handleShowTables},
+		{prefix: "INSERT INTO", section: "Database Operations", privilege: privWrite,
+			syntax: "INSERT INTO table VALUES (...)", summary: "Insert data",
+			details: []string{"'text', 'it''s', 42, NULL, DEFAULT",
+				"INSERT INTO table (col, ...) VALUES (...) - Other columns take their defaults"},
+			run: (*Engine).handleInsert},
+		{prefix: "SELECT ROWID", section: "Database Operations",
+			syntax: "SELECT ROWID, * FROM table", summary: "Query data with row IDs",
+			details: []string{"[WHERE ...] [ORDER BY col [DESC], ...] [LIMIT n]"},
+			query:   (*Engine).handleSelectRowID},
+		{prefix: "SELECT * FROM", section: "Database Operations",
+			syntax: "SELECT * FROM table", summary: "Query data",
+			details: []string{"[WHERE ...] [ORDER BY col [DESC], ...] [LIMIT n]"},
+			query:   (*Engine).handleSelect},
+		{prefix: "SELECT COUNT(*) FROM", section: "Database Operations",
+			syntax: "SELECT COUNT(*) FROM table", summary: "Count rows",
+			details: []string{"[WHERE ...]",
+				"SELECT COUNT(col), SUM(col), MIN(col), MAX(col), ... FROM table - Aggregate columns",
+				"SELECT col, ..., COUNT(*), ... FROM table [WHERE ...] GROUP BY col, ... [ORDER BY ...] [LIMIT n] - Aggregate groups"},
+			query: (*Engine).handleSelectAggregate},
+		{prefix: "SELECT ", section: "Database Operations",
+			syntax: "SELECT col [AS a], ... FROM t", summary: "Query columns",
+			details: []string{"FROM table [[AS] alias] [WHERE ...] [ORDER BY col [DESC], ...] [LIMIT n]",
+				"WHERE and ORDER BY may use alias.col and the result aliases",
+				"SELECT DISTINCT col, ... | * FROM t - Drop duplicate result rows",
+				"FROM a x, b y | a CROSS JOIN b - Pair the rows; WHERE x.col = y.col joins"},
+			query: (*Engine).handleSelect},
+		{prefix: "UPDATE", section: "Database Operations", privilege: privWrite,
+			syntax: "UPDATE table SET col=val ROW n", summary: "Update row",
+			details: []string{"ROWID id instead of ROW n targets the row by its stable ID",
+				"SET col = DEFAULT - Reset a column to its default",
+				"... WHERE _version = v - Only if a versioned row is unchanged",
+				"UPDATE table SET col=val, ... WHERE ... - Update matching rows in batches"},
+			run: (*Engine).handleUpdate},
+		{prefix: "DELETE FROM", section: "Database Operations", privilege: privWrite,
+			syntax: "DELETE FROM table ROW n", summary: "Delete row",
+			details: []string{"ROWID id instead of ROW n targets the row by its stable ID",
+				"DELETE FROM table WHERE ... - Delete matching rows in batches"},
+			run: (*Engine).handleDelete},
+		{prefix: "CREATE INDEX", section: "Database Operations", privilege: privWrite,
+			syntax: "CREATE INDEX ON table (col)", summary: "Create index",
+			run: (*Engine).handleCreateIndex},
+		{prefix: "ANALYZE", section: "Database Operations", privilege: privWrite,
+			syntax: "ANALYZE [table]", summary: "Collect statistics for choosing indexes",
+			run: (*Engine).handleAnalyze},
+		{prefix: "EXPLAIN", section: "Database Operations",
+			syntax: "EXPLAIN SELECT ... FROM table ...", summary: "Show how a query reads its rows",
+			query: (*Engine).handleExplain},
+
+		{prefix: "LOGIN", section: "Authentication", public: true,
+			syntax: "LOGIN username password", summary: "Login to database",
+			run: (*Engine).handleLogin},
+		{prefix: "LOGOUT", section: "Authentication", public: true,
+			syntax: "LOGOUT", summary: "Logout from database",
+			run: func(e *Engine, input string) string { return e.handleLogout() }},
+		{prefix: "CREATE USER", section: "Authentication", privilege: privAdmin, public: true,
+			syntax: "CREATE USER user pass [role]", summary: "Create new user (Admin only)",
+			run: (*Engine).handleCreateUser},
+		{prefix: "DROP USER", section: "Authentication", privilege: privAdmin, public: true,
+			syntax: "DROP USER username", summary: "Delete user (Admin only)",
+			run: (*Engine).handleDropUser},
+		{prefix: "SHOW GRANTS", section: "Authentication",
+			syntax: "SHOW GRANTS [FOR user]", summary: "Show the privileges of your role, or of a user (Admin only)",
+			query: (*Engine).handleShowGrants},
+		{prefix: "LIST USERS", section: "Authentication", privilege: privAdmin, public: true,
+			syntax: "LIST USERS", summary: "List all users (Admin only)",
+			run: func(e *Engine, input string) string { return e.handleListUsers() }},
+
+		{prefix: "BACKUP INFO", section: "Backup & Restore", secrets: passphrase,
+			syntax: "BACKUP INFO path", summary: "Show backup info",
+			run: (*Engine).handleBackupInfo},
+		{prefix: "BACKUP VERIFY", section: "Backup & Restore", secrets: passphrase,
+			syntax: "BACKUP VERIFY path", summary: "Verify backup checksums",
+			run: (*Engine).handleBackupVerify},
+		{prefix: "BACKUP", section: "Backup & Restore", privilege: privAdmin, secrets: passphrase,
+			syntax: "BACKUP [TO path] [DESC desc]", summary: "Create backup (Admin only)",
+			details: []string{"[PASSPHRASE secret] - Encrypt the backup archive",
+				"[INCLUDE CREDENTIALS] - Add users, TLS and table encryption keys",
+				"TO STDOUT [> file] - Stream backup to the client"},
+			run: (*Engine).handleBackup},
+		{prefix: "RESTORE", section: "Backup & Restore", privilege: privAdmin, secrets: passphrase,
+			syntax: "RESTORE FROM path", summary: "Restore from backup",
+			run: (*Engine).handleRestore},
+		{prefix: "REPAIR TABLE", section: "Backup & Restore", privilege: privAdmin, secrets: passphrase,
+			syntax: "REPAIR TABLE t [FROM dir]", summary: "Repair damaged pages from backups (Admin only)",
+			details: []string{"[PASSPHRASE secret] - Decrypt encrypted backups",
+				"Pages failing their checksum are replaced by their copy in the newest",
+				"verified backup holding them unchanged",
+				"dir defaults to the server's --repair-backup-dir, else ./backups"},
+			run: (*Engine).handleRepair},
+		{prefix: "LIST BACKUPS", section: "Backup & Restore",
+			syntax: "LIST BACKUPS [dir]", summary: "List backups",
+			run: (*Engine).handleListBackups},
+
+		{prefix: "EXPORT TABLE", section: "Export", privilege: privWrite,
+			syntax: "EXPORT TABLE t TO 'file.parquet'", summary: "Export table as Parquet",
+			run: (*Engine).handleExport},
+
+		{prefix: "CHANGE PASSWORD", section: "Authentication", public: true,
+			syntax: "CHANGE PASSWORD old new", summary: "Change your password",
+			run: (*Engine).handleChangePassword},
+
+		{prefix: "DECLARE", section: "Cursors",
+			syntax: "DECLARE c CURSOR FOR SELECT ... FROM t [...]", summary: "Open a cursor over a query",
+			run: (*Engine).handleDeclareCursor},
+		{prefix: "FETCH", section: "Cursors",
+			syntax: "FETCH [n|NEXT|ALL] FROM c", summary: "Fetch the next rows",
+			query: (*Engine).handleFetch},
+		{prefix: "CLOSE", section: "Cursors",
+			syntax: "CLOSE c", summary: "Close a cursor",
+			run: (*Engine).handleCloseCursor},
+
+		{prefix: "KV SET", section: "Key-Value", privilege: privWrite,
+			syntax: "KV SET key value", summary: "Store a value under a key",
+			details: []string{"'quoted strings' for keys and values with spaces"},
+			run:     (*Engine).handleKVSet},
+		{prefix: "KV GET", section: "Key-Value",
+			syntax: "KV GET key", summary: "Read the value of a key",
+			query: (*Engine).handleKVGet},
+		{prefix: "KV DEL", section: "Key-Value", privilege: privWrite,
+			syntax: "KV DEL key", summary: "Delete a key",
+			run: (*Engine).handleKVDel},
+
+		{prefix: "LISTEN", section: "Notifications",
+			syntax: "LISTEN channel", summary: "Receive notifications sent to a channel"},
+		{prefix: "UNLISTEN", section: "Notifications",
+			syntax: "UNLISTEN channel | UNLISTEN *", summary: "Stop receiving notifications"},
+		{prefix: "NOTIFY", section: "Notifications",
+			syntax: "NOTIFY channel [, 'payload']", summary: "Notify listeners (on COMMIT inside a transaction)",
+			run: (*Engine).handleNotify},
+
+		{prefix: "SET ", section: "Session",
+			syntax: "SET name = value", summary: "Set a session variable (DEFAULT resets)",
+			details: []string{"output_format text|csv|json, statement_timeout 30s,",
+				"default_transaction_isolation 'repeatable read',",
+				"bulk_batch_size 1000, bulk_progress_channel name"},
+			run: (*Engine).handleSet},
+		{prefix: "SHOW ", section: "Session",
+			syntax: "SHOW name | SHOW ALL", summary: "Show session variables",
+			run: (*Engine).handleShowSetting},
+
+		{prefix: "HELP", section: "Other", public: true,
+			syntax: "HELP [command]", summary: "Show this help, or help on one command",
+			run: (*Engine).handleHelp},
+		{prefix: "EXIT", section: "Other", public: true,
+			syntax: "EXIT", summary: "Exit database"},
+	}
+}
+
+// lookupCommand returns the command that handles an upper-cased statement
+func lookupCommand(upper string) *command {
+	for i := range commands {
+		if strings.HasPrefix(upper, commands[i].prefix) {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// handleHelp handles HELP, which lists every command, and HELP command,
+// which shows the commands starting with the given words. Once the
+// connection has logged in, only the commands its role may run are shown.
+func (e *Engine) handleHelp(input string) string {
+	topic := strings.Join(strings.Fields(strings.ToUpper(input))[1:], " ")
+	if topic == "" {
+		var b strings.Builder
+		b.WriteString("HaruDB Commands:\n")
+		for _, section := range helpSections {
+			var lines strings.Builder
+			for _, cmd := range commands {
+				if cmd.section == section && e.mayRun(cmd) {
+					writeCommandHelp(&lines, cmd)
+				}
+			}
+			if lines.Len() > 0 {
+				fmt.Fprintf(&b, "\n%s:\n%s", section, lines.String())
+			}
+		}
+		b.WriteString("\nType HELP command for one command, e.g. HELP SELECT")
+		return b.String()
+	}
+
+	var b strings.Builder
+	denied := false
+	for _, cmd := range commands {
+		prefix := strings.TrimSpace(cmd.prefix)
+		if strings.HasPrefix(prefix, topic) || strings.HasPrefix(topic, prefix) {
+			if !e.mayRun(cmd) {
+				denied = true
+				continue
+			}
+			writeCommandHelp(&b, cmd)
+		}
+	}
+	if b.Len() == 0 && denied {
+		return fmt.Sprintf("%s: %s is not available to the %s role", ErrInsufficientPermissions, topic, roleName(e.CurrentSession.Role))
+	}
+	if b.Len() == 0 {
+		return fmt.Sprintf("Unknown command %s: type HELP for a list of commands", topic)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeCommandHelp writes a command's help lines
+func writeCommandHelp(b *strings.Builder, cmd command) {
+	const width = 31
+	if len(cmd.syntax) <= width {
+		fmt.Fprintf(b, "  %-*s - %s\n", width, cmd.syntax, cmd.summary)
+	} else {
+		fmt.Fprintf(b, "  %s\n  %*s - %s\n", cmd.syntax, width, "", cmd.summary)
+	}
+	for _, detail := range cmd.details {
+		fmt.Fprintf(b, "    %s\n", detail)
+	}
+}
@@ -0,0 +1,54 @@
+// internal/parser/commands_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandOrder(t *testing.T) {
+	// A command listed after a shorter prefix of itself could never run
+	for i, later := range commands {
+		for _, earlier := range commands[:i] {
+			if strings.HasPrefix(later.prefix, earlier.prefix) {
+				t.Errorf("%q is shadowed by %q", later.prefix, earlier.prefix)
+			}
+		}
+	}
+	for _, cmd := range commands {
+		found := false
+		for _, section := range helpSections {
+			found = found || cmd.section == section
+		}
+		if !found {
+			t.Errorf("%q has unknown section %q", cmd.prefix, cmd.section)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	engine := NewEngine(t.TempDir())
+
+	// HELP works before logging in
+	help := engine.Execute("HELP")
+	if !strings.HasPrefix(help, "HaruDB Commands:") {
+		t.Fatalf("unexpected help:\n%s", help)
+	}
+	for _, cmd := range commands {
+		if !strings.Contains(help, cmd.syntax) {
+			t.Errorf("help is missing %s", cmd.syntax)
+		}
+	}
+
+	selectHelp := engine.Execute("help select")
+	if !strings.Contains(selectHelp, "SELECT * FROM table") || !strings.Contains(selectHelp, "SELECT COUNT(*) FROM table") ||
+		strings.Contains(selectHelp, "INSERT") {
+		t.Errorf("HELP SELECT should show only SELECT statements:\n%s", selectHelp)
+	}
+	if got := engine.Execute("HELP BACKUP INFO"); !strings.Contains(got, "BACKUP INFO path") || strings.Contains(got, "RESTORE") {
+		t.Errorf("unexpected HELP BACKUP INFO:\n%s", got)
+	}
+	if got, err := engine.Exec("HELP FROBNICATE"); err == nil {
+		t.Errorf("expected an error for an unknown command, got %s", got)
+	}
+}
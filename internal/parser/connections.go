@@ -0,0 +1,158 @@
+// internal/parser/connections.go
+//
+// Client connections. The server registers each connection with Connect and
+// runs its statements on the connection's own session, started with
+// StartQueryOn, so each client logs in separately and has a transaction of
+// its own. When the connection goes away with Disconnect, whether the
+// client said exit or the network dropped, its transaction is rolled back
+// instead of lingering with its uncommitted changes, and its cursors, queued
+// notifications and login are released. SHOW CONNECTIONS lists the
+// connections and what each is doing.
+package parser
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// Connection is a client connected to the server
+type Connection struct {
+	ID        uint64
+	Addr      string
+	Connected time.Time
+
+	// engine is the connection's session
+	engine *Engine
+
+	mu sync.Mutex
+	// user is the user of the connection's last statement
+	user string
+	// running counts the connection's statements still executing
+	running int
+	// lastActive is when the connection's last statement finished
+	lastActive time.Time
+	// inTransaction is set while the connection has a transaction open
+	inTransaction bool
+}
+
+// ConnectionList numbers connections and tracks the open ones
+type ConnectionList struct {
+	lastID atomic.Uint64
+	mu     sync.Mutex
+	open   map[uint64]*Connection
+}
+
+// NewConnectionList returns an empty connection list
+func NewConnectionList() *ConnectionList {
+	return &ConnectionList{open: make(map[uint64]*Connection)}
+}
+
+// list returns the open connections in ID order
+func (cl *ConnectionList) list() []*Connection {
+	cl.mu.Lock()
+	conns := make([]*Connection, 0, len(cl.open))
+	for _, c := range cl.open {
+		conns = append(conns, c)
+	}
+	cl.mu.Unlock()
+	sort.Slice(conns, func(i, j int) bool { return conns[i].ID < conns[j].ID })
+	return conns
+}
+
+// Connect registers a client connected from addr, with a session of its own
+// that is not logged in
+func (e *Engine) Connect(addr string) *Connection {
+	now := time.Now()
+	c := &Connection{ID: e.Connections.lastID.Add(1), Addr: addr, Connected: now, lastActive: now, engine: e.newSession()}
+	e.Connections.mu.Lock()
+	e.Connections.open[c.ID] = c
+	e.Connections.mu.Unlock()
+	return c
+}
+
+// Disconnect forgets a connection and ends its session: the transaction it
+// left open is rolled back, and its cursors and login are released
+func (e *Engine) Disconnect(c *Connection) {
+	e.Connections.mu.Lock()
+	delete(e.Connections.open, c.ID)
+	e.Connections.mu.Unlock()
+
+	session := c.engine
+	if tx := session.DB.GetCurrentTransaction(); tx != nil {
+		if err := session.DB.RollbackTransaction(); err != nil {
+			log.Printf("Warning: failed to roll back the transaction of disconnected connection %d: %v\n", c.ID, err)
+		} else {
+			log.Printf("↩️  Rolled back transaction %s left open by disconnected connection %d (%s)\n", tx.ID, c.ID, c.Addr)
+		}
+	}
+	session.sendPendingNotifications(false)
+	session.closeAllCursors()
+	if session.CurrentSession != nil {
+		e.UserManager.LogoutSession(session.CurrentSession.SessionID)
+		session.CurrentSession = nil
+	}
+}
+
+// Engine returns the connection's session, which runs its statements
+func (c *Connection) Engine() *Engine {
+	return c.engine
+}
+
+// StartQueryOn is StartQuery for a statement sent on connection c, run by
+// c's session
+func (e *Engine) StartQueryOn(c *Connection, statement string) *RunningQuery {
+	q := c.engine.StartQuery(statement)
+	q.Conn = c
+	c.mu.Lock()
+	c.user = q.User
+	c.running++
+	c.mu.Unlock()
+	return q
+}
+
+// endQuery marks a statement of connection c finished, leaving a
+// transaction open if inTransaction is set
+func (c *Connection) endQuery(inTransaction bool) {
+	c.mu.Lock()
+	c.running--
+	c.lastActive = time.Now()
+	c.inTransaction = inTransaction
+	c.mu.Unlock()
+}
+
+// handleShowConnections handles SHOW CONNECTIONS
+func (e *Engine) handleShowConnections(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 2 {
+		return nil, "Syntax error: SHOW CONNECTIONS"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return nil, err
+	}
+
+	rs := &storage.ResultSet{Columns: []string{"id", "user", "address", "state", "idle_ms"}}
+	now := time.Now()
+	for _, c := range e.Connections.list() {
+		c.mu.Lock()
+		user, running, lastActive, inTx := c.user, c.running, c.lastActive, c.inTransaction
+		c.mu.Unlock()
+		if user == "" {
+			user = "-"
+		}
+		state, idle := "idle", strconv.FormatFloat(float64(now.Sub(lastActive))/float64(time.Millisecond), 'f', 3, 64)
+		switch {
+		case running > 0:
+			state, idle = "active", "0.000"
+		case inTx:
+			state = "idle in transaction"
+		}
+		rs.Rows = append(rs.Rows, []string{strconv.FormatUint(c.ID, 10), user, c.Addr, state, idle})
+	}
+	return rs, ""
+}
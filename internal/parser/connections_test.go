@@ -0,0 +1,145 @@
+// internal/parser/connections_test.go
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runOn executes statement as connection c sends it, returning a failure as
+// its message
+func runOn(engine *Engine, c *Connection, statement string) string {
+	result, err := c.Engine().ExecuteQuery(engine.StartQueryOn(c, statement), statement)
+	if err != nil {
+		return err.Error()
+	}
+	return result
+}
+
+func TestDisconnectRollsBackTransaction(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	a, b := engine.Connect("10.0.0.1:5000"), engine.Connect("10.0.0.2:5000")
+	runOn(engine, a, "LOGIN admin admin123")
+	runOn(engine, a, "CREATE TABLE t (id)")
+	runOn(engine, a, "BEGIN")
+	runOn(engine, a, "INSERT INTO t VALUES (1)")
+	runOn(engine, a, "DECLARE c CURSOR FOR SELECT * FROM t")
+	runOn(engine, b, "LOGIN admin admin123")
+
+	got := runOn(engine, b, "SHOW CONNECTIONS")
+	if !strings.HasPrefix(got, "id | user | address | state | idle_ms\n") ||
+		!strings.Contains(got, "\n1 | admin | 10.0.0.1:5000 | idle in transaction | ") ||
+		!strings.Contains(got, "\n2 | admin | 10.0.0.2:5000 | active | 0.000\n") {
+		t.Errorf("connections:\n%s", got)
+	}
+
+	// Another connection going away leaves the transaction alone
+	engine.Disconnect(b)
+	if a.Engine().DB.GetCurrentTransaction() == nil {
+		t.Fatal("transaction rolled back when another connection closed")
+	}
+
+	session := a.Engine()
+	engine.Disconnect(a)
+	if session.DB.GetCurrentTransaction() != nil {
+		t.Fatal("transaction still open after its connection closed")
+	}
+	if session.cursors != nil || session.CurrentSession != nil {
+		t.Error("cursors or login kept after the connection closed")
+	}
+	engine.Execute("LOGIN admin admin123")
+	if got := engine.Execute("SELECT * FROM t"); got != "id\n(no rows)\n" {
+		t.Errorf("rolled back insert visible: %q", got)
+	}
+	if got := engine.Execute("SHOW CONNECTIONS"); got != "id | user | address | state | idle_ms\n(no rows)\n" {
+		t.Errorf("connections after disconnect:\n%s", got)
+	}
+}
+
+func TestDisconnectAfterCommit(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	c := engine.Connect("10.0.0.1:5000")
+	runOn(engine, c, "LOGIN admin admin123")
+	runOn(engine, c, "CREATE TABLE t (id)")
+	runOn(engine, c, "BEGIN")
+	runOn(engine, c, "INSERT INTO t VALUES (1)")
+	runOn(engine, c, "COMMIT")
+
+	// A transaction of another session is not the connection's
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("BEGIN")
+	engine.Disconnect(c)
+	if engine.DB.GetCurrentTransaction() == nil {
+		t.Error("another transaction rolled back")
+	}
+	engine.Execute("ROLLBACK")
+	if got := engine.Execute("SELECT * FROM t"); got != "id\n1\n" {
+		t.Errorf("committed insert: %q", got)
+	}
+}
+
+func TestConnectionsHaveTheirOwnTransactions(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	a, b := engine.Connect("10.0.0.1:5000"), engine.Connect("10.0.0.2:5000")
+	runOn(engine, a, "LOGIN admin admin123")
+	runOn(engine, b, "LOGIN admin admin123")
+	runOn(engine, a, "CREATE TABLE t (id)")
+
+	runOn(engine, a, "BEGIN")
+	runOn(engine, a, "INSERT INTO t VALUES (1)")
+
+	// b does not join a's transaction, see its queued insert or end it
+	if got := runOn(engine, b, "INSERT INTO t VALUES (2)"); got != "1 row inserted with secure page-based storage" {
+		t.Errorf("insert outside the transaction: %q", got)
+	}
+	if got := runOn(engine, b, "SELECT * FROM t"); got != "id\n2\n" {
+		t.Errorf("b sees a's uncommitted insert: %q", got)
+	}
+	if got := runOn(engine, b, "COMMIT"); !strings.Contains(got, "no active transaction") {
+		t.Errorf("b committed a's transaction: %q", got)
+	}
+	runOn(engine, b, "BEGIN")
+	runOn(engine, b, "INSERT INTO t VALUES (3)")
+	runOn(engine, b, "ROLLBACK")
+	if a.Engine().DB.GetCurrentTransaction() == nil {
+		t.Fatal("b's ROLLBACK ended a's transaction")
+	}
+
+	if got := runOn(engine, a, "COMMIT"); got != "Transaction committed successfully" {
+		t.Errorf("commit: %q", got)
+	}
+	if got := runOn(engine, b, "SELECT * FROM t"); got != "id\n2\n1\n" {
+		t.Errorf("after commit: %q", got)
+	}
+}
+
+func TestSessionsFollowRestore(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	a, b := engine.Connect("10.0.0.1:5000"), engine.Connect("10.0.0.2:5000")
+	runOn(engine, a, "LOGIN admin admin123")
+	runOn(engine, b, "LOGIN admin admin123")
+	runOn(engine, a, "CREATE TABLE t (id)")
+	runOn(engine, a, "INSERT INTO t VALUES (1)")
+	backup := filepath.Join(t.TempDir(), "b.tar.gz")
+	if got := runOn(engine, a, "BACKUP TO "+backup); strings.HasPrefix(got, "Error") || strings.Contains(got, "failed") {
+		t.Fatalf("backup: %s", got)
+	}
+	runOn(engine, a, "INSERT INTO t VALUES (2)")
+
+	if got := runOn(engine, b, "RESTORE FROM "+backup); !strings.HasPrefix(got, "Database restored") {
+		t.Fatalf("restore: %s", got)
+	}
+	for _, c := range []*Connection{a, b} {
+		if got := runOn(engine, c, "SELECT * FROM t"); got != "id\n1\n" {
+			t.Errorf("connection %d after restore: %q", c.ID, got)
+		}
+	}
+	if !engine.DB.SameDatabase(a.Engine().DB) {
+		t.Error("the first session's handle was not restored")
+	}
+}
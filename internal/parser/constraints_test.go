@@ -0,0 +1,85 @@
+// internal/parser/constraints_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestNotNullAndDefault(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	const inserted = "1 row inserted with secure page-based storage"
+	steps := []struct{ stmt, want string }{
+		{"CREATE TABLE users (id INT NOT NULL, name TEXT DEFAULT 'anon, new', active BOOL DEFAULT true)", "Table users created with secure page-based storage"},
+		{"INSERT INTO users (id) VALUES (1)", inserted},
+		{`INSERT INTO users ("name", id) VALUES ('Bob', 2)`, inserted},
+		{"INSERT INTO users VALUES (3, DEFAULT, false)", inserted},
+		{"INSERT INTO users VALUES (NULL, 'Cy', true)", "Error: NULL value in column id violates NOT NULL"},
+		{"INSERT INTO users (name) VALUES ('Cy')", "Error: NULL value in column id violates NOT NULL"},
+		{"INSERT INTO users (id, nope) VALUES (4, 1)", "Column nope not found"},
+		{"INSERT INTO users (id, name) VALUES (4)", "Column count does not match"},
+		{"UPDATE users SET name = DEFAULT ROW 1", "1 row updated"},
+		{"SELECT * FROM users", "id | name | active\n1 | anon, new | true\n2 | anon, new | true\n3 | anon, new | false\n"},
+		{"SHOW CREATE TABLE users", "statement\nCREATE TABLE users (id INT NOT NULL, name TEXT DEFAULT 'anon, new', active BOOL DEFAULT true)\n"},
+		// Inside a transaction the row is checked when the statement runs
+		{"BEGIN", ""},
+		{"INSERT INTO users (active) VALUES (false)", "Error: NULL value in column id violates NOT NULL"},
+		{"ROLLBACK", ""},
+	}
+	for _, s := range steps {
+		got := engine.Execute(s.stmt)
+		if s.want != "" && got != s.want {
+			t.Errorf("%s:\n got %q\nwant %q", s.stmt, got, s.want)
+		}
+	}
+	if got := engine.Execute("DESCRIBE users"); !strings.Contains(got, "id | column | INT | no |  | BINARY") || !strings.Contains(got, "name | column | TEXT | yes | 'anon, new' | BINARY") {
+		t.Errorf("DESCRIBE: %s", got)
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	const inserted = "1 row inserted with secure page-based storage"
+	steps := []struct{ stmt, want string }{
+		{"CREATE TABLE customers (id INT, name)", "Table customers created with secure page-based storage"},
+		{"CREATE TABLE orders (id INT, customer_id INT, FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE)", "Table orders created with secure page-based storage"},
+		{"INSERT INTO customers VALUES (1, 'Ann')", inserted},
+		{"INSERT INTO orders VALUES (10, 1)", inserted},
+		{"INSERT INTO orders VALUES (11, 2)", "Error: orders.customer_id = 2 violates FOREIGN KEY: no customers row has id = 2"},
+		{"DROP TABLE customers", "Error: table customers is referenced by FOREIGN KEY orders.customer_id"},
+		{"SHOW CREATE TABLE orders", "statement\nCREATE TABLE orders (id INT, customer_id INT, FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE)\n"},
+		{"DELETE FROM customers ROW 0", ""},
+		{"SELECT * FROM orders", "id | customer_id\n(no rows)\n"},
+		{"CREATE TABLE pairs (a, b, FOREIGN KEY (a, b) REFERENCES orders(id, customer_id))", "Error: foreign keys of several columns are not supported"},
+	}
+	for _, s := range steps {
+		got := engine.Execute(s.stmt)
+		if s.want != "" && got != s.want {
+			t.Errorf("%s:\n got %q\nwant %q", s.stmt, got, s.want)
+		}
+	}
+}
+
+func TestReferencedParentIsConstraint(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE p (id INT)")
+	engine.Execute("CREATE TABLE c (id INT, pid INT, FOREIGN KEY (pid) REFERENCES p(id))")
+
+	for stmt, want := range map[string]string{
+		"DROP TABLE p":                        "ERROR CONSTRAINT: Error: table p is referenced by FOREIGN KEY c.pid",
+		"ALTER TABLE p SET RETENTION 30 DAYS": "ERROR CONSTRAINT: Error: table p is referenced by FOREIGN KEY c.pid and cannot have a retention policy",
+		"INSERT INTO c VALUES (1, 9)":         "ERROR CONSTRAINT: Error: c.pid = 9 violates FOREIGN KEY: no p row has id = 9",
+	} {
+		got, err := engine.Exec(stmt)
+		if encoded := protocol.EncodeResult(got, err); encoded != want {
+			t.Errorf("%s:\n got %q\nwant %q", stmt, encoded, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// internal/parser/cursor.go
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleDeclareCursor handles DECLARE name CURSOR FOR SELECT ... FROM ...
+func (e *Engine) handleDeclareCursor(input string) string {
+	const usage = "Syntax error: DECLARE name CURSOR FOR SELECT ... FROM table ..."
+
+	fields := strings.Fields(input)
+	if len(fields) < 5 || !strings.EqualFold(fields[2], "CURSOR") || !strings.EqualFold(fields[3], "FOR") {
+		return usage
+	}
+	name := strings.ToLower(fields[1])
+	if !identifierPattern.MatchString(name) {
+		return fmt.Sprintf("Syntax error: invalid cursor name %s", fields[1])
+	}
+	selectIdx := strings.Index(strings.ToUpper(input), " FOR ") + len(" FOR ")
+	tableName, query, msg := parseSelect(strings.TrimSpace(input[selectIdx:]))
+	if msg != "" {
+		return msg
+	}
+
+	e.cursorsMu.Lock()
+	defer e.cursorsMu.Unlock()
+	if _, exists := e.cursors[name]; exists {
+		return fmt.Sprintf("Error: cursor %s already exists", name)
+	}
+	if query.Role, msg = e.maskRole(); msg != "" {
+		return msg
+	}
+	cursor, msg := e.DB.OpenCursor(tableName, query)
+	if msg != "" {
+		return msg
+	}
+	if e.cursors == nil {
+		e.cursors = make(map[string]*storage.Cursor)
+	}
+	e.cursors[name] = cursor
+	return fmt.Sprintf("Cursor %s declared", name)
+}
+
+// handleFetch handles FETCH [n|NEXT|ALL] FROM name
+func (e *Engine) handleFetch(input string) (*storage.ResultSet, string) {
+	const usage = "Syntax error: FETCH [n|NEXT|ALL] FROM cursor"
+
+	fields := strings.Fields(input)
+	count := 1
+	switch {
+	case len(fields) == 3 && strings.EqualFold(fields[1], "FROM"):
+	case len(fields) == 4 && strings.EqualFold(fields[2], "FROM"):
+		switch strings.ToUpper(fields[1]) {
+		case "NEXT":
+		case "ALL":
+			count = -1
+		default:
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 0 {
+				return nil, usage
+			}
+			count = n
+		}
+	default:
+		return nil, usage
+	}
+	name := strings.ToLower(fields[len(fields)-1])
+
+	e.cursorsMu.Lock()
+	defer e.cursorsMu.Unlock()
+	cursor, exists := e.cursors[name]
+	if !exists {
+		return nil, fmt.Sprintf("Error: cursor %s not found", name)
+	}
+	return cursor.FetchResult(count)
+}
+
+// handleCloseCursor handles CLOSE name
+func (e *Engine) handleCloseCursor(input string) string {
+	fields := strings.Fields(input)
+	if len(fields) != 2 {
+		return "Syntax error: CLOSE cursor"
+	}
+	name := strings.ToLower(fields[1])
+
+	e.cursorsMu.Lock()
+	defer e.cursorsMu.Unlock()
+	if _, exists := e.cursors[name]; !exists {
+		return fmt.Sprintf("Error: cursor %s not found", name)
+	}
+	delete(e.cursors, name)
+	return fmt.Sprintf("Cursor %s closed", name)
+}
+
+// closeAllCursors drops the session's cursors when it ends
+func (e *Engine) closeAllCursors() {
+	e.cursorsMu.Lock()
+	defer e.cursorsMu.Unlock()
+	e.cursors = nil
+}
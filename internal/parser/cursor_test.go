@@ -0,0 +1,85 @@
+// internal/parser/cursor_test.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCursors(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id, score)")
+	for i := 0; i < 10; i++ {
+		engine.Execute(fmt.Sprintf("INSERT INTO items VALUES ('%d', '%d')", i, (i*7)%10))
+	}
+
+	t.Run("FetchInBatches", func(t *testing.T) {
+		if got := engine.Execute("DECLARE c CURSOR FOR SELECT * FROM items WHERE score >= 3"); got != "Cursor c declared" {
+			t.Fatalf("declare failed: %s", got)
+		}
+		// Rows written after DECLARE are not visible to the cursor
+		engine.Execute("INSERT INTO items VALUES ('10', '9')")
+
+		var ids []string
+		for {
+			batch := engine.Execute("FETCH 3 FROM c")
+			if strings.Contains(batch, "(no rows)") {
+				break
+			}
+			lines := strings.Split(strings.TrimSpace(batch), "\n")
+			if lines[0] != "id | score" || len(lines) > 4 {
+				t.Fatalf("unexpected batch:\n%s", batch)
+			}
+			for _, line := range lines[1:] {
+				ids = append(ids, strings.Split(line, " | ")[0])
+			}
+		}
+		if got := strings.Join(ids, ","); got != "1,2,4,5,7,8,9" {
+			t.Errorf("cursor returned %s", got)
+		}
+		if got := engine.Execute("CLOSE c"); got != "Cursor c closed" {
+			t.Errorf("close failed: %s", got)
+		}
+		if got, err := engine.Exec("FETCH NEXT FROM c"); err == nil {
+			t.Errorf("closed cursor should be gone, got %s", got)
+		}
+	})
+
+	t.Run("OrderedWithLimit", func(t *testing.T) {
+		engine.Execute("DECLARE top CURSOR FOR SELECT * FROM items ORDER BY score DESC LIMIT 4")
+		if got := engine.Execute("FETCH FROM top"); got != "id | score\n7 | 9\n" {
+			t.Fatalf("unexpected fetch: %s", got)
+		}
+		rest := engine.Execute("FETCH ALL FROM top")
+		if rest != "id | score\n10 | 9\n4 | 8\n1 | 7\n" {
+			t.Errorf("expected the next 3 rows, got:\n%s", rest)
+		}
+		if got := engine.Execute("FETCH ALL FROM top"); got != "id | score\n(no rows)\n" {
+			t.Errorf("exhausted cursor should return no rows, got %q", got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		engine.Execute("DECLARE dup CURSOR FOR SELECT * FROM items")
+		for _, stmt := range []string{
+			"DECLARE dup CURSOR FOR SELECT * FROM items",
+			"DECLARE x CURSOR FOR SELECT * FROM missing",
+			"DECLARE y CURSOR FOR SELECT * FROM items ORDER BY nope",
+			"DECLARE z CURSOR SELECT * FROM items",
+			"FETCH -1 FROM dup",
+			"FETCH 2 FROM nope",
+			"CLOSE nope",
+		} {
+			if got, err := engine.Exec(stmt); err == nil {
+				t.Errorf("%s: expected error, got %s", stmt, got)
+			}
+		}
+		engine.Execute("LOGOUT")
+		engine.Execute("LOGIN admin admin123")
+		if got, err := engine.Exec("FETCH FROM dup"); err == nil {
+			t.Errorf("cursors should close on logout, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// internal/parser/encryption.go
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleSetEncryption handles ALTER TABLE t SET (encrypted=true|false)
+func (e *Engine) handleSetEncryption(parts []string) string {
+	opts, errMsg := parseTableOptions(strings.Join(parts[4:], " "))
+	if errMsg != "" {
+		return errMsg
+	}
+	if opts.versioned {
+		return "Error: versioned can only be set by CREATE TABLE"
+	}
+	if opts.timestamps {
+		return "Error: timestamps can only be set by CREATE TABLE"
+	}
+	if opts.encrypted == nil {
+		return "Syntax error: ALTER TABLE t SET (encrypted=true|false)"
+	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	if msg := e.requireAdmin(); msg != "" {
+		return msg
+	}
+	return e.DB.SetEncryption(tableName, *opts.encrypted)
+}
+
+// handleRekey handles REKEY [TABLE t]
+func (e *Engine) handleRekey(input string) string {
+	parts := sqlFields(input)
+	var tableName string
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 3 && strings.EqualFold(parts[1], "TABLE"):
+		name, err := parseTableName(parts[2])
+		if err != nil {
+			return fmt.Sprintf("Syntax error: %v", err)
+		}
+		tableName = name
+	default:
+		return "Syntax error: REKEY [TABLE table]"
+	}
+	if msg := e.requireAdmin(); msg != "" {
+		return msg
+	}
+	return e.DB.Rekey(tableName)
+}
+
+// handleShowEncryption handles SHOW ENCRYPTION, listing the key each table
+// is sealed with
+func (e *Engine) handleShowEncryption(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 2 {
+		return nil, "Syntax error: SHOW ENCRYPTION"
+	}
+	if msg := e.requireAdmin(); msg != "" {
+		return nil, msg
+	}
+	tables := e.DB.TableKeyIDs()
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rs := &storage.ResultSet{Columns: []string{"table", "encrypted", "key_id"}}
+	for _, name := range names {
+		keyID := tables[name]
+		rs.Rows = append(rs.Rows, []string{name, strconv.FormatBool(keyID != ""), keyID})
+	}
+	return rs, ""
+}
+
+// handleShowEncryptionKeys handles SHOW ENCRYPTION KEYS, listing the keys in
+// the keyring but never their secrets
+func (e *Engine) handleShowEncryptionKeys(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 3 {
+		return nil, "Syntax error: SHOW ENCRYPTION KEYS"
+	}
+	if msg := e.requireAdmin(); msg != "" {
+		return nil, msg
+	}
+	rs := &storage.ResultSet{Columns: []string{"key_id", "created", "current"}}
+	for _, key := range e.DB.Keys.Keys() {
+		rs.Rows = append(rs.Rows, []string{key.ID, key.Created.Format(time.RFC3339), strconv.FormatBool(key.Current)})
+	}
+	return rs, ""
+}
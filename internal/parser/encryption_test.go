@@ -0,0 +1,95 @@
+// internal/parser/encryption_test.go
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// filesContain reports whether any file of the data directory, other than
+// the WAL, holds s
+func filesContain(t *testing.T, dir, s string) bool {
+	t.Helper()
+	found := false
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.Contains(path, "wal") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err == nil && strings.Contains(string(data), s) {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+func TestTableEncryption(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+
+	for _, tc := range []struct{ stmt, want string }{
+		{"CREATE TABLE secrets (id, note) WITH (encrypted=true)", "Encrypted table secrets created with key k1"},
+		{"CREATE TABLE plain (id, note) WITH (encrypted = false)", "Table plain created with secure page-based storage"},
+		{"CREATE TABLE bad (id) WITH (compressed=true)", "Error: unknown table option 'compressed=true'; use encrypted, versioned or timestamps"},
+		{"CREATE TABLE bad (id) WITH (encrypted=maybe)", "Syntax error: WITH (encrypted=true|false, versioned=true|false, timestamps=true|false)"},
+		{"REKEY TABLE plain", "Error: table plain is not encrypted"},
+	} {
+		if got := engine.Execute(tc.stmt); got != tc.want {
+			t.Errorf("%s: %q", tc.stmt, got)
+		}
+	}
+	engine.Execute("INSERT INTO secrets VALUES ('1', 'launch-code-7731')")
+	engine.Execute("INSERT INTO plain VALUES ('1', 'visible-note-42')")
+	engine.Execute("CHECKPOINT")
+	if filesContain(t, dir, "launch-code-7731") {
+		t.Error("encrypted table stored in the clear")
+	}
+	if !filesContain(t, dir, "visible-note-42") {
+		t.Error("plain table not stored in the clear")
+	}
+	if got := engine.Execute("SHOW CREATE TABLE secrets"); !strings.Contains(got, "CREATE TABLE secrets (id, note) WITH (encrypted=true)") {
+		t.Errorf("show create table: %q", got)
+	}
+	if got := engine.Execute("SHOW ENCRYPTION"); got != "table | encrypted | key_id\nplain | false | \nsecrets | true | k1\n" {
+		t.Errorf("show encryption: %q", got)
+	}
+
+	if got := engine.Execute("REKEY"); got != "Rekeyed 1 tables with key k2; retired keys: k1" {
+		t.Errorf("rekey: %q", got)
+	}
+	if got := engine.Execute("ALTER TABLE plain SET (encrypted=true)"); got != "Table plain encrypted with key k2" {
+		t.Errorf("encrypt plain: %q", got)
+	}
+	if got := engine.Execute("SHOW ENCRYPTION KEYS"); !strings.HasPrefix(got, "key_id | created | current\nk2 | ") || strings.Count(got, "\n") != 2 {
+		t.Errorf("show encryption keys: %q", got)
+	}
+	if filesContain(t, dir, "visible-note-42") {
+		t.Error("table stored in the clear after ALTER TABLE SET (encrypted=true)")
+	}
+	engine.DB.Close()
+
+	reopened := NewEngine(dir)
+	reopened.Execute("LOGIN admin admin123")
+	if got := reopened.Execute("SELECT * FROM secrets"); !strings.Contains(got, "1 | launch-code-7731") {
+		t.Errorf("select after restart: %q", got)
+	}
+	if got := reopened.Execute("ALTER TABLE secrets SET (encrypted=false)"); got != "Table secrets decrypted" {
+		t.Errorf("decrypt: %q", got)
+	}
+	if !filesContain(t, dir, "launch-code-7731") {
+		t.Error("decrypted table not stored in the clear")
+	}
+	if got := reopened.Execute("REKEY TABLE plain"); got != "Rekeyed 1 tables with key k3; retired keys: k2" {
+		t.Errorf("rekey table: %q", got)
+	}
+
+	reopened.Execute("CREATE USER bob pass123 user")
+	reopened.Execute("LOGIN bob pass123")
+	if got := reopened.Execute("REKEY"); !strings.Contains(got, "Insufficient permissions") {
+		t.Errorf("rekey as user: %q", got)
+	}
+}
@@ -3,11 +3,18 @@ package parser
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/notify"
+	"github.com/Hareesh108/haruDB/internal/protocol"
+	"github.com/Hareesh108/haruDB/internal/replication"
 	"github.com/Hareesh108/haruDB/internal/storage"
 )
 
@@ -17,19 +24,96 @@ const (
 	ErrInsufficientPermissions = "Insufficient permissions for this operation"
 )
 
+// Engine runs statements for one session on a database: NewEngine returns
+// the first, and Connect starts another for each client connection. The
+// sessions share the database and the server's state.
 type Engine struct {
-	DB             *storage.Database
-	UserManager    *auth.UserManager
-	BackupManager  *storage.BackupManager
+	*shared
+	// DB is the session's handle on the database, holding its open
+	// transaction
+	DB *storage.Database
+	// CurrentSession is the user logged in on this session, or nil
 	CurrentSession *auth.Session
+
+	// cursors holds the session's open cursors by name
+	cursors   map[string]*storage.Cursor
+	cursorsMu sync.Mutex
+	// pendingNotifications are sent when the current transaction commits
+	pendingNotifications []notify.Notification
+	// notifySavepoints maps savepoint name -> pending notifications when it
+	// was created, so ROLLBACK TO SAVEPOINT can drop later ones
+	notifySavepoints map[string]int
+	notifyMu         sync.Mutex
+	// stream, if set, writes output such as BACKUP TO STDOUT to the client
+	// while the statement runs (see SetStream)
+	stream func(send func(io.Writer) error) error
+}
+
+// shared is the state an Engine's sessions have in common
+type shared struct {
+	// DB is the first session's handle on the database; RESTORE replaces
+	// the database it refers to
+	DB            *storage.Database
+	UserManager   *auth.UserManager
+	BackupManager *storage.BackupManager
+	// Replication is set when this server is a primary or a replica
+	Replication replication.Node
+	// ReadOnly rejects statements that change table data, as on replicas
+	ReadOnly bool
+	// Info is reported by SELECT VERSION() and SHOW SERVER INFO
+	Info ServerInfo
+	// Notifications routes NOTIFY to the connections that LISTEN
+	Notifications *notify.Hub
+	// Writes bounds the writes in flight (see writequeue.go); nil admits all
+	Writes *WriteQueue
+	// Scheduler bounds the statements running at once and orders waiting
+	// ones by priority (see scheduler.go); nil admits all
+	Scheduler *Scheduler
+	// QueryStats aggregates executed statements (see querystats.go)
+	QueryStats *QueryStats
+	// Processes numbers statements and lists the running ones (see
+	// processlist.go)
+	Processes *ProcessList
+	// Connections lists the clients connected to the server (see
+	// connections.go)
+	Connections *ConnectionList
+	// SlowQueryThreshold logs statements running at least this long; 0
+	// logs none
+	SlowQueryThreshold time.Duration
+
+	// statementHooks see every statement before it runs (see hooks.go)
+	statementHooks statementHooks
 }
 
 func NewEngine(dataDir string) *Engine {
-	return &Engine{
-		DB:            storage.NewDatabase(dataDir),
+	return NewEngineWithProgress(dataDir, &storage.RecoveryProgress{})
+}
+
+// NewEngineWithProgress is NewEngine counting the database's recovery in
+// progress
+func NewEngineWithProgress(dataDir string, progress *storage.RecoveryProgress) *Engine {
+	db := storage.NewDatabaseWithProgress(dataDir, progress)
+	backupManager := storage.NewBackupManager(dataDir)
+	backupManager.SetSnapshotter(db)
+
+	return &Engine{DB: db, shared: &shared{
+		DB:            db,
 		UserManager:   auth.NewUserManager(dataDir),
-		BackupManager: storage.NewBackupManager(dataDir),
-	}
+		BackupManager: backupManager,
+		Info:          newServerInfo(),
+		Notifications: notify.NewHub(),
+		Writes:        NewWriteQueue(DefaultWriteQueueDepth),
+		Scheduler:     NewScheduler(DefaultQuerySlots),
+		QueryStats:    NewQueryStats(),
+		Processes:     NewProcessList(),
+		Connections:   NewConnectionList(),
+	}}
+}
+
+// newSession returns a session on the same database, not logged in and
+// with no transaction open
+func (e *Engine) newSession() *Engine {
+	return &Engine{shared: e.shared, DB: e.shared.DB.Session()}
 }
 
 // requireAuth checks if user is authenticated
@@ -51,19 +135,53 @@ func (e *Engine) requireAdmin() string {
 	return ""
 }
 
-// isAuthCommand checks if the command is authentication-related
+// isAuthCommand checks if the command may run without logging in
 func (e *Engine) isAuthCommand(upper string) bool {
-	return strings.HasPrefix(upper, "LOGIN") ||
-		strings.HasPrefix(upper, "LOGOUT") ||
-		strings.HasPrefix(upper, "CREATE USER") ||
-		strings.HasPrefix(upper, "DROP USER") ||
-		strings.HasPrefix(upper, "LIST USERS") ||
-		strings.HasPrefix(upper, "CHANGE PASSWORD") ||
-		strings.HasPrefix(upper, "HELP") ||
-		strings.HasPrefix(upper, "EXIT")
+	cmd := lookupCommand(upper)
+	return cmd != nil && cmd.public
 }
 
+// Execute runs one statement under a new query ID and records it in the
+// query stats. A failure is returned as its message.
 func (e *Engine) Execute(input string) string {
+	result, err := e.Exec(input)
+	if err != nil {
+		return err.Error()
+	}
+	return result
+}
+
+// Exec is Execute returning a failure as a *protocol.Error with its code
+func (e *Engine) Exec(input string) (string, error) {
+	return e.ExecuteQuery(e.StartQuery(input), input)
+}
+
+// ExecArgs is Exec for statement with args bound to its ? placeholders,
+// encoded by their codecs as a client's ExecArgs encodes them
+func (e *Engine) ExecArgs(statement string, args ...any) (string, error) {
+	bound, err := protocol.Bind(statement, args...)
+	if err != nil {
+		return "", err
+	}
+	return e.Exec(bound)
+}
+
+// ExecuteQuery is Exec for a statement registered with StartQuery
+func (e *Engine) ExecuteQuery(q *RunningQuery, input string) (string, error) {
+	start := time.Now()
+	result, err := e.execute(input)
+	elapsed := time.Since(start)
+	e.QueryStats.Record(input, elapsed, result, err)
+	e.finishQuery(q, elapsed)
+	return result, err
+}
+
+// execute is Exec without the query stats
+func (e *Engine) execute(input string) (string, error) {
+	// After a RESTORE the session continues on the restored database
+	if !e.DB.SameDatabase(e.shared.DB) {
+		e.DB = e.shared.DB.Session()
+	}
 	input = strings.TrimSpace(input)
 	input = strings.TrimSuffix(input, ";") // remove trailing semicolon
 
@@ -72,281 +190,427 @@ func (e *Engine) Execute(input string) string {
 	// Check if command requires authentication
 	if !e.isAuthCommand(upper) {
 		if err := e.requireAuth(); err != "" {
-			return err
+			return messageResult(err)
+		}
+		e.DB.SetLockSession(e.CurrentSession.Username)
+		var denied string
+		if input, denied = e.runStatementHooks(input); denied != "" {
+			return messageResult(denied)
+		}
+		upper = strings.ToUpper(input)
+		if cmd := lookupCommand(upper); cmd != nil && !e.mayRun(*cmd) {
+			return messageResult(ErrInsufficientPermissions)
 		}
 	}
 
-	switch {
-	case strings.HasPrefix(upper, "BEGIN"):
-		// BEGIN TRANSACTION [ISOLATION LEVEL level]
-		return e.handleBeginTransaction(input)
-
-	case strings.HasPrefix(upper, "COMMIT"):
-		// COMMIT [TRANSACTION]
-		return e.handleCommitTransaction()
-
-	case strings.HasPrefix(upper, "ROLLBACK"):
-		// ROLLBACK [TRANSACTION] [TO SAVEPOINT name]
-		return e.handleRollbackTransaction(input)
-
-	case strings.HasPrefix(upper, "SAVEPOINT"):
-		// SAVEPOINT name
-		return e.handleSavepoint(input)
-
-	case strings.HasPrefix(upper, "CREATE INDEX"):
-		// CREATE INDEX ON users (email)
-		parts := strings.SplitN(input, "(", 2)
-		if len(parts) < 2 {
-			return ErrSyntaxError
-		}
-		header := strings.TrimSpace(parts[0])
-		seg := strings.Fields(header)
-		if len(seg) < 4 { // CREATE INDEX ON <table>
-			return ErrSyntaxError
-		}
-		tableName := strings.ToLower(seg[3])
-		col := strings.TrimSpace(parts[1])
-		col = strings.TrimSuffix(col, ")")
-		col = strings.TrimSpace(col)
-		return e.DB.CreateIndex(tableName, col)
-
-	case strings.HasPrefix(upper, "CREATE TABLE"):
-		// CREATE TABLE users (id, name)
-		parts := strings.SplitN(input, "(", 2)
-		if len(parts) < 2 {
-			return ErrSyntaxError
-		}
-		header := strings.TrimSpace(parts[0])
-		fields := strings.Fields(header)
-		if len(fields) < 3 {
-			return ErrSyntaxError
-		}
-		tableName := fields[2]
-
-		colsRaw := strings.TrimSuffix(parts[1], ")")
-		columns := strings.Split(colsRaw, ",")
-		for i := range columns {
-			columns[i] = strings.TrimSpace(columns[i])
-		}
-		return e.DB.CreateTableTx(tableName, columns)
-
-	case strings.HasPrefix(upper, "INSERT INTO"):
-		// INSERT INTO users VALUES (1, 'Hareesh')
-		parts := strings.SplitN(input, "VALUES", 2)
-		if len(parts) < 2 {
-			return ErrSyntaxError
-		}
-		tableName := strings.Fields(parts[0])[2]
-		tableName = strings.ToLower(tableName)
-
-		valRaw := strings.Trim(parts[1], " ();")
-		values := strings.Split(valRaw, ",")
-		for i := range values {
-			values[i] = strings.TrimSpace(values[i])
-			values[i] = strings.Trim(values[i], "'")
-		}
-		return e.DB.InsertTx(tableName, values)
+	if e.ReadOnly && isDataWrite(upper) {
+		return messageResult("Error: server is a read-only replica; send writes to the primary")
+	}
 
-	case strings.HasPrefix(upper, "SELECT * FROM"):
-		// SELECT * FROM users [WHERE conditions]
-		parts := strings.Fields(input)
-		if len(parts) < 4 {
-			return ErrSyntaxError
+	// Without a WAL, writes follow the server's WAL failure policy
+	degradedWrite := e.DB.Degraded() && isDataWrite(upper)
+	if degradedWrite && e.DB.WALFailurePolicy != storage.WALFailWarn {
+		return messageResult(fmt.Sprintf(storage.ErrWALUnavailable, e.DB.WALError()))
+	}
+
+	if writesKVTable(input, upper) {
+		return messageResult(fmt.Sprintf("Error: table %s is written only by the KV commands", storage.KVTable))
+	}
+
+	if isDataWrite(upper) && !freesSpace(upper) {
+		if msg := e.DB.CheckDiskSpace(); msg != "" {
+			return messageResult(msg)
 		}
-		tableName := strings.ToLower(parts[3])
+	}
 
-		// Check for WHERE clause
-		whereIdx := -1
-		for i, p := range parts {
-			if strings.ToUpper(p) == "WHERE" {
-				whereIdx = i
-				break
-			}
+	cmd := lookupCommand(upper)
+	switch {
+	case cmd != nil && cmd.query != nil:
+		// Rows are the statement's result whatever their text
+		rs, msg := cmd.query(e, input)
+		if rs == nil {
+			return messageResult(msg)
 		}
-		if whereIdx == -1 {
-			return e.DB.SelectAll(tableName)
+		return e.formatResult(rs), nil
+	case cmd != nil && cmd.run != nil:
+		result, err := messageResult(cmd.run(e, input))
+		if degradedWrite && err == nil {
+			result += "\n" + storage.WarnWALUnavailable
 		}
+		return result, err
+	}
+	return messageResult("Unknown command")
+}
 
-		// Extract WHERE clause
-		whereClause := strings.Join(parts[whereIdx+1:], " ")
+// handleCreateIndex handles CREATE INDEX ON table (col)
+func (e *Engine) handleCreateIndex(input string) string {
+	parts := strings.SplitN(input, "(", 2)
+	if len(parts) < 2 {
+		return ErrSyntaxError
+	}
+	header := strings.TrimSpace(parts[0])
+	seg := sqlFields(header)
+	if len(seg) < 4 { // CREATE INDEX ON <table>
+		return ErrSyntaxError
+	}
+	tableName, err := parseTableName(seg[3])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	col := strings.TrimSpace(parts[1])
+	col = strings.TrimSuffix(col, ")")
+	col, err = storage.UnquoteIdentifier(strings.TrimSpace(col))
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.CreateIndex(tableName, col)
+}
 
-		// Parse advanced WHERE clause
-		whereExpr, err := ParseWhereClause(whereClause)
-		if err != nil {
-			return fmt.Sprintf("WHERE clause error: %v", err)
-		}
+// handleCreateTable handles CREATE TABLE table (col [type] [NOT NULL] [DEFAULT
+// value] [COLLATE collation], ...)
+func (e *Engine) handleCreateTable(input string) string {
+	input, opts, errMsg := splitTableOptions(input)
+	if errMsg != "" {
+		return errMsg
+	}
+	tableName, columns, errMsg := parseCreateTable(input, 2)
+	if errMsg != "" {
+		return errMsg
+	}
+	return e.createTableWithOptions(tableName, columns, false, opts)
+}
 
-		// Use advanced WHERE evaluation
-		return e.DB.SelectWhereAdvanced(tableName, whereExpr)
+// handleCreateUnloggedTable handles CREATE UNLOGGED TABLE name (col, ...)
+func (e *Engine) handleCreateUnloggedTable(input string) string {
+	input, opts, errMsg := splitTableOptions(input)
+	if errMsg != "" {
+		return errMsg
+	}
+	tableName, columns, errMsg := parseCreateTable(input, 3)
+	if errMsg != "" {
+		return errMsg
+	}
+	return e.createTableWithOptions(tableName, columns, true, opts)
+}
 
-	case strings.HasPrefix(upper, "UPDATE"):
-		// Example: UPDATE users SET name = 'NewName', email = 'new@example.com' ROW 0
-		parts := strings.Fields(input)
-		if len(parts) < 6 {
-			return "Syntax error: UPDATE table SET column = value ROW index"
-		}
-		tableName := strings.ToLower(parts[1])
+// parseCreateTable returns the table name, found at field nameField, and
+// column specs of a CREATE TABLE statement
+func parseCreateTable(input string, nameField int) (string, []string, string) {
+	parts := strings.SplitN(input, "(", 2)
+	if len(parts) < 2 {
+		return "", nil, ErrSyntaxError
+	}
+	header := strings.TrimSpace(parts[0])
+	fields := sqlFields(header)
+	if len(fields) < nameField+1 {
+		return "", nil, ErrSyntaxError
+	}
+	tableName, err := parseNewTableName(fields[nameField])
+	if err != nil {
+		return "", nil, fmt.Sprintf("Syntax error: %v", err)
+	}
 
-		// Find SET clause
-		setIndex := -1
-		for i, part := range parts {
-			if strings.ToUpper(part) == "SET" {
-				setIndex = i
-				break
-			}
-		}
-		if setIndex == -1 {
-			return "Syntax error: missing SET clause"
-		}
+	colsRaw := strings.TrimSuffix(parts[1], ")")
+	columns := splitIdentifiers(colsRaw)
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	return tableName, columns, ""
+}
 
-		// Find ROW clause
-		rowIndex := -1
-		for i, part := range parts {
-			if strings.ToUpper(part) == "ROW" && i+1 < len(parts) {
-				if idx, err := strconv.Atoi(parts[i+1]); err == nil {
-					rowIndex = idx
-					break
-				}
-			}
-		}
-		if rowIndex == -1 {
-			return "Syntax error: missing ROW index"
-		}
+// handleInsert handles INSERT INTO table [(col, ...)] VALUES (value, ...)
+func (e *Engine) handleInsert(input string) string {
+	upper := strings.ToUpper(input)
+	i := strings.Index(upper, "VALUES")
+	if i < 0 {
+		return ErrSyntaxError
+	}
+	head, columnList, hasColumns := cutColumnList(input[:i])
+	fields := sqlFields(head)
+	if len(fields) != 3 {
+		return ErrSyntaxError
+	}
+	tableName, err := parseTableName(fields[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
 
-		// Get table
-		table, exists := e.DB.Tables[tableName]
-		if !exists {
-			return fmt.Sprintf("Table %s not found", tableName)
-		}
-		if rowIndex < 0 || rowIndex >= len(table.Rows) {
-			return "Row index out of bounds"
-		}
+	values, err := parseValueList(strings.TrimSuffix(strings.TrimSpace(input[i+len("VALUES"):]), ";"))
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	if !hasColumns {
+		return e.DB.InsertTx(tableName, values)
+	}
 
-		// Reconstruct SET clause (everything between SET and ROW)
-		setClause := strings.Join(parts[setIndex+1:], " ")
-		rowClauseIndex := strings.Index(strings.ToUpper(setClause), "ROW")
-		if rowClauseIndex != -1 {
-			setClause = setClause[:rowClauseIndex]
+	// Columns left out of the list take their defaults
+	var columns []string
+	for _, col := range splitIdentifiers(columnList) {
+		name, err := storage.UnquoteIdentifier(strings.TrimSpace(col))
+		if err != nil || name == "" {
+			return fmt.Sprintf("Syntax error: invalid column list (%s)", columnList)
 		}
-		setClause = strings.TrimSpace(setClause)
+		columns = append(columns, name)
+	}
+	return e.DB.InsertColumnsTx(tableName, columns, values)
+}
 
-		// Split multiple assignments by comma
-		assignments := strings.Split(setClause, ",")
-		newRow := make([]string, len(table.Rows[rowIndex]))
-		copy(newRow, table.Rows[rowIndex])
+// cutColumnList separates a trailing parenthesized column list, as in
+// "INSERT INTO t (a, b)", from the text before it
+func cutColumnList(s string) (head, list string, found bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, ")") {
+		return s, "", false
+	}
+	inQuote := false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == '(' && !inQuote:
+			return s[:i], s[i+1 : len(s)-1], true
+		}
+	}
+	return s, "", false
+}
 
-		for _, assign := range assignments {
-			assign = strings.TrimSpace(assign)
-			if assign == "" {
-				continue
-			}
-			kv := strings.SplitN(assign, "=", 2)
-			if len(kv) != 2 {
-				return fmt.Sprintf("Invalid assignment: %s", assign)
-			}
-			columnName := strings.TrimSpace(kv[0])
-			value := strings.TrimSpace(kv[1])
-			value = strings.Trim(value, "'")
-
-			// Find column index
-			columnIndex := -1
-			for i, col := range table.Columns {
-				if col == columnName {
-					columnIndex = i
-					break
-				}
-			}
-			if columnIndex == -1 {
-				return fmt.Sprintf("Column %s not found", columnName)
-			}
+// handleSelect handles SELECT * | col [AS alias], ... FROM table [[AS]
+// alias], ... [WHERE conditions] [ORDER BY col [ASC|DESC], ...] [LIMIT n]
+func (e *Engine) handleSelect(input string) (*storage.ResultSet, string) {
+	if selectsAggregates(input) {
+		return e.handleSelectAggregate(input)
+	}
+	from, query, msg := parseSelectFrom(input)
+	if msg != "" {
+		return nil, msg
+	}
 
-			// Apply update
-			newRow[columnIndex] = value
-		}
+	if query.Role, msg = e.maskRole(); msg != "" {
+		return nil, msg
+	}
+	if len(from) > 1 {
+		return e.DB.SelectJoinResult(from, query)
+	}
+	tableName := from[0].Name
+	if query.Columns == nil && query.OrderBy == "" && query.Limit < 0 && query.Where == nil && !query.Distinct {
+		return e.DB.SelectAllAsResult(tableName, query.Role)
+	}
+	return e.DB.SelectQueryResult(tableName, query)
+}
 
-		return e.DB.UpdateTx(tableName, rowIndex, newRow)
+// handleUpdate handles UPDATE table SET col = value, ... ROW n | ROWID id
+// [WHERE _version = v], and UPDATE table SET col = value, ... WHERE ... (see
+// bulk.go)
+func (e *Engine) handleUpdate(input string) string {
+	parts := sqlFields(input)
+	if len(parts) < 6 {
+		return "Syntax error: UPDATE table SET column = value ROW index | ROWID id"
+	}
+	tableName, err := parseTableName(parts[1])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
 
-	case strings.HasPrefix(upper, "DELETE FROM"):
-		// DELETE FROM users ROW 0
-		parts := strings.Fields(input)
-		if len(parts) < 4 {
-			return "Syntax error: DELETE FROM table ROW index"
+	// Find SET clause
+	setIndex := -1
+	for i, part := range parts {
+		if strings.ToUpper(part) == "SET" {
+			setIndex = i
+			break
 		}
-		tableName := strings.ToLower(parts[2])
+	}
+	if setIndex == -1 {
+		return "Syntax error: missing SET clause"
+	}
 
-		// Find ROW clause
-		rowIndex := -1
-		for i, part := range parts {
-			if strings.ToUpper(part) == "ROW" && i+1 < len(parts) {
-				// Parse row index
-				if idx, err := strconv.Atoi(parts[i+1]); err == nil {
-					rowIndex = idx
-					break
-				}
-			}
-		}
-		if rowIndex == -1 {
-			return "Syntax error: missing ROW index"
+	target, ok := parseRowTarget(parts)
+	if !ok {
+		if whereIdx := keywordIndex(parts, "WHERE"); whereIdx > setIndex {
+			return e.handleUpdateWhere(tableName, strings.Join(parts[setIndex+1:whereIdx], " "), strings.Join(parts[whereIdx+1:], " "))
 		}
+		return "Syntax error: missing ROW index or ROWID"
+	}
+	version, errMsg := parseVersionGuard(parts)
+	if errMsg != "" {
+		return errMsg
+	}
 
-		return e.DB.DeleteTx(tableName, rowIndex)
-
-	case strings.HasPrefix(upper, "DROP TABLE"):
-		// DROP TABLE users
-		parts := strings.Fields(input)
-		if len(parts) < 3 {
-			return "Syntax error: DROP TABLE table_name"
-		}
-		tableName := strings.ToLower(parts[2])
-		return e.DB.DropTableTx(tableName)
+	// Get the current row
+	var columns, newRow []string
+	var msg string
+	if target.byID {
+		columns, newRow, msg = e.DB.ReadRowByID(tableName, target.id)
+	} else {
+		columns, newRow, msg = e.DB.ReadRow(tableName, target.index)
+	}
+	if msg != "" {
+		return msg
+	}
 
-	case strings.HasPrefix(upper, "LOGIN"):
-		// LOGIN username password
-		return e.handleLogin(input)
+	// The SET clause is everything between SET and ROW or ROWID
+	if target.at <= setIndex+1 {
+		return "Syntax error: UPDATE table SET column = value ROW index | ROWID id"
+	}
+	setClause := strings.Join(parts[setIndex+1:target.at], " ")
+	if msg := e.applyAssignments(tableName, columns, newRow, setClause); msg != "" {
+		return msg
+	}
 
-	case strings.HasPrefix(upper, "LOGOUT"):
-		// LOGOUT
-		return e.handleLogout()
+	switch {
+	case version != 0 && target.byID:
+		return e.DB.UpdateByIDAtVersionTx(tableName, target.id, newRow, version)
+	case version != 0:
+		return e.DB.UpdateAtVersionTx(tableName, target.index, newRow, version)
+	case target.byID:
+		return e.DB.UpdateByIDTx(tableName, target.id, newRow)
+	}
+	return e.DB.UpdateTx(tableName, target.index, newRow)
+}
 
-	case strings.HasPrefix(upper, "CREATE USER"):
-		// CREATE USER username password [role]
-		return e.handleCreateUser(input)
+// applyAssignments sets the columns a SET clause assigns in row, whose
+// columns are columns, and returns an error message if one is invalid
+func (e *Engine) applyAssignments(tableName string, columns, row []string, setClause string) string {
+	for _, assign := range splitIdentifiers(setClause) {
+		assign = strings.TrimSpace(assign)
+		if assign == "" {
+			continue
+		}
+		name, raw, ok := cutAssignment(assign)
+		if !ok {
+			return fmt.Sprintf("Invalid assignment: %s", assign)
+		}
+		columnName, err := storage.UnquoteIdentifier(strings.TrimSpace(name))
+		if err != nil {
+			return fmt.Sprintf("Syntax error: %v", err)
+		}
+		value := storage.DefaultValue
+		if !strings.EqualFold(strings.TrimSpace(raw), "DEFAULT") {
+			if value, err = parseLiteral(raw); err != nil {
+				return fmt.Sprintf("Invalid assignment: %s: %v", assign, err)
+			}
+		}
 
-	case strings.HasPrefix(upper, "DROP USER"):
-		// DROP USER username
-		return e.handleDropUser(input)
+		// Find column index
+		columnIndex := -1
+		for i, col := range columns {
+			if strings.EqualFold(col, columnName) {
+				columnIndex = i
+				break
+			}
+		}
+		if columnIndex == -1 {
+			return fmt.Sprintf("Column %s not found", columnName)
+		}
+		if e.DB.AutoColumn(tableName, columns[columnIndex]) {
+			return fmt.Sprintf("Error: %s is maintained automatically and cannot be set", columns[columnIndex])
+		}
 
-	case strings.HasPrefix(upper, "LIST USERS"):
-		// LIST USERS
-		return e.handleListUsers()
+		// Apply update
+		row[columnIndex] = value
+	}
+	return ""
+}
 
-	case strings.HasPrefix(upper, "BACKUP"):
-		// BACKUP [TO path] [DESCRIPTION description]
-		return e.handleBackup(input)
+// cutAssignment splits column = value at the first = outside quotes, so
+// that a quoted column name may contain one
+func cutAssignment(assign string) (name, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(assign); i++ {
+		switch c := assign[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return assign[:i], assign[i+1:], true
+		}
+	}
+	return "", "", false
+}
 
-	case strings.HasPrefix(upper, "RESTORE"):
-		// RESTORE FROM path
-		return e.handleRestore(input)
+// parseVersionGuard returns v from a trailing WHERE _version = v of an
+// UPDATE, or 0 when there is none
+func parseVersionGuard(parts []string) (int64, string) {
+	for i, part := range parts {
+		if !strings.EqualFold(part, "WHERE") {
+			continue
+		}
+		guard := strings.Join(parts[i+1:], "")
+		name, value, ok := strings.Cut(guard, "=")
+		version, err := strconv.ParseInt(strings.Trim(value, "'"), 10, 64)
+		if !ok || !strings.EqualFold(name, storage.VersionColumn) || err != nil || version < 1 {
+			return 0, "Syntax error: UPDATE supports only WHERE _version = n after ROW or ROWID"
+		}
+		return version, ""
+	}
+	return 0, ""
+}
 
-	case strings.HasPrefix(upper, "BACKUP INFO"):
-		// BACKUP INFO path
-		return e.handleBackupInfo(input)
+// handleDelete handles DELETE FROM table ROW n | ROWID id and DELETE FROM
+// table WHERE ... (see bulk.go)
+func (e *Engine) handleDelete(input string) string {
+	parts := sqlFields(input)
+	if len(parts) < 4 {
+		return "Syntax error: DELETE FROM table ROW index | ROWID id"
+	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
 
-	case strings.HasPrefix(upper, "LIST BACKUPS"):
-		// LIST BACKUPS [directory]
-		return e.handleListBackups(input)
+	if strings.EqualFold(parts[3], "WHERE") {
+		return e.handleDeleteWhere(tableName, strings.Join(parts[4:], " "))
+	}
+	target, ok := parseRowTarget(parts)
+	if !ok {
+		return "Syntax error: missing ROW index or ROWID"
+	}
+	if target.byID {
+		return e.DB.DeleteByIDTx(tableName, target.id)
+	}
+	return e.DB.DeleteTx(tableName, target.index)
+}
 
-	case strings.HasPrefix(upper, "CHANGE PASSWORD"):
-		// CHANGE PASSWORD old_password new_password
-		return e.handleChangePassword(input)
+// rowTarget is the row a ROW n or ROWID id clause names
+type rowTarget struct {
+	index int
+	id    int64
+	byID  bool
+	at    int // position of the ROW or ROWID keyword in parts
+}
 
-	case strings.HasPrefix(upper, "HELP"):
-		// HELP
-		return e.handleHelp()
+// parseRowTarget finds the ROW or ROWID clause in parts. A row index is a
+// position that shifts as earlier rows are deleted; a row ID is assigned at
+// insert and never changes.
+func parseRowTarget(parts []string) (rowTarget, bool) {
+	for i := 0; i+1 < len(parts); i++ {
+		switch strings.ToUpper(parts[i]) {
+		case "ROW":
+			if idx, err := strconv.Atoi(parts[i+1]); err == nil {
+				return rowTarget{index: idx, at: i}, true
+			}
+		case "ROWID":
+			if id, err := strconv.ParseInt(parts[i+1], 10, 64); err == nil && id > 0 {
+				return rowTarget{id: id, byID: true, at: i}, true
+			}
+		}
+	}
+	return rowTarget{}, false
+}
 
-	default:
-		return "Unknown command"
+// handleDropTable handles DROP TABLE table
+func (e *Engine) handleDropTable(input string) string {
+	parts := sqlFields(input)
+	if len(parts) < 3 {
+		return "Syntax error: DROP TABLE table_name"
 	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.DropTableTx(tableName)
 }
 
 // Transaction handler methods
@@ -356,7 +620,7 @@ func (e *Engine) handleBeginTransaction(input string) string {
 	parts := strings.Fields(input)
 
 	// Default isolation level
-	isolationLevel := storage.ReadCommitted
+	isolationLevel := isolationLevels[e.setting("default_transaction_isolation")]
 
 	// Parse isolation level if specified
 	if len(parts) >= 3 && strings.ToUpper(parts[1]) == "TRANSACTION" {
@@ -391,16 +655,13 @@ func (e *Engine) handleBeginTransaction(input string) string {
 
 // handleCommitTransaction handles COMMIT commands
 func (e *Engine) handleCommitTransaction() string {
-
-	fmt.Printf("Hello")
-
-	err := e.DB.CommitTransaction()
-
-	fmt.Printf("commit err = %#v", err)
-
-	if err != nil {
+	if err := e.DB.CommitTransaction(); err != nil {
+		if e.DB.GetCurrentTransaction() == nil {
+			e.sendPendingNotifications(false)
+		}
 		return fmt.Sprintf("Failed to commit transaction: %v", err)
 	}
+	e.sendPendingNotifications(true)
 	return "Transaction committed successfully"
 }
 
@@ -416,6 +677,7 @@ func (e *Engine) handleRollbackTransaction(input string) string {
 		if err != nil {
 			return fmt.Sprintf("Failed to rollback to savepoint %s: %v", savepointName, err)
 		}
+		e.rollbackNotifications(savepointName)
 		return fmt.Sprintf("Rolled back to savepoint %s", savepointName)
 	}
 
@@ -424,6 +686,7 @@ func (e *Engine) handleRollbackTransaction(input string) string {
 	if err != nil {
 		return fmt.Sprintf("Failed to rollback transaction: %v", err)
 	}
+	e.sendPendingNotifications(false)
 	return "Transaction rolled back successfully"
 }
 
@@ -439,6 +702,7 @@ func (e *Engine) handleSavepoint(input string) string {
 	if err != nil {
 		return fmt.Sprintf("Failed to create savepoint %s: %v", savepointName, err)
 	}
+	e.markNotifySavepoint(savepointName)
 	return fmt.Sprintf("Savepoint %s created", savepointName)
 }
 
@@ -465,6 +729,7 @@ func (e *Engine) handleLogin(input string) string {
 	}
 
 	e.CurrentSession = session
+	e.closeAllCursors()
 	return fmt.Sprintf("Login successful. Welcome, %s!", username)
 }
 
@@ -480,6 +745,7 @@ func (e *Engine) handleLogout() string {
 	}
 
 	e.CurrentSession = nil
+	e.closeAllCursors()
 	return "Logout successful"
 }
 
@@ -553,15 +819,8 @@ func (e *Engine) handleListUsers() string {
 
 	result := "Users:\n"
 	for _, user := range users {
-		roleStr := "USER"
-		switch user.Role {
-		case auth.RoleAdmin:
-			roleStr = "ADMIN"
-		case auth.RoleReadOnly:
-			roleStr = "READONLY"
-		}
 		result += fmt.Sprintf("- %s (%s) - Created: %s, Last Login: %s\n",
-			user.Username, roleStr, user.CreatedAt.Format("2006-01-02 15:04:05"),
+			user.Username, roleName(user.Role), user.CreatedAt.Format("2006-01-02 15:04:05"),
 			user.LastLogin.Format("2006-01-02 15:04:05"))
 	}
 
@@ -570,20 +829,85 @@ func (e *Engine) handleListUsers() string {
 
 // Backup handler methods
 
+// isDataWrite reports whether a statement changes table data
+func isDataWrite(upper string) bool {
+	for _, prefix := range []string{"CREATE TABLE", "CREATE EXTERNAL TABLE", "CREATE INDEX", "INSERT", "UPDATE", "DELETE", "DROP TABLE",
+		"ALTER TABLE", "COMMENT ON", "CREATE MASK", "DROP MASK", "BEGIN", "COMMIT", "ROLLBACK", "SAVEPOINT", "RESTORE", "CREATE PROCEDURE", "DROP PROCEDURE", "CALL",
+		"KV SET", "KV DEL"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// freesSpace reports whether a write may run when disk space is low: it
+// removes data, or ends a transaction whose writes were already admitted
+func freesSpace(upper string) bool {
+	for _, prefix := range []string{"DELETE", "DROP ", "COMMIT", "ROLLBACK", "KV DEL"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleShowReplicationStatus handles SHOW REPLICATION STATUS
+func (e *Engine) handleShowReplicationStatus() string {
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	if e.Replication == nil {
+		return "Replication Status:\n  Role: standalone"
+	}
+
+	st := e.Replication.Status()
+	var sb strings.Builder
+	sb.WriteString("Replication Status:\n")
+	sb.WriteString(fmt.Sprintf("  Role: %s\n", st.Role))
+
+	if st.Role == replication.RoleReplica {
+		sb.WriteString(fmt.Sprintf("  Primary: %s\n", st.PrimaryAddr))
+		sb.WriteString(fmt.Sprintf("  Connected: %t\n", st.Connected))
+		sb.WriteString(fmt.Sprintf("  Primary LSN: %d\n", st.PrimaryLSN))
+		sb.WriteString(fmt.Sprintf("  Last Received LSN: %d\n", st.ReceivedLSN))
+		sb.WriteString(fmt.Sprintf("  Last Applied LSN: %d\n", st.AppliedLSN))
+		sb.WriteString(fmt.Sprintf("  Byte Lag: %d\n", st.ByteLag))
+		sb.WriteString(fmt.Sprintf("  Time Lag: %s\n", st.TimeLag.Round(time.Millisecond)))
+		if st.LastError != "" {
+			sb.WriteString(fmt.Sprintf("  Last Error: %s\n", st.LastError))
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("  Last LSN: %d\n", st.LastLSN))
+	sb.WriteString(fmt.Sprintf("  Connected Replicas: %d\n", len(st.Peers)))
+	for _, p := range st.Peers {
+		sb.WriteString(fmt.Sprintf("  - %s (user %s, connected %s)\n", p.Addr, p.User, p.ConnectedAt.Format("2006-01-02 15:04:05")))
+		sb.WriteString(fmt.Sprintf("    Last Sent LSN: %d\n", p.SentLSN))
+		sb.WriteString(fmt.Sprintf("    Last Applied LSN: %d\n", p.AppliedLSN))
+		sb.WriteString(fmt.Sprintf("    Byte Lag: %d\n", p.ByteLag))
+		sb.WriteString(fmt.Sprintf("    Time Lag: %s\n", p.TimeLag.Round(time.Millisecond)))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
 // handleBackup handles BACKUP commands
 func (e *Engine) handleBackup(input string) string {
-	if e.CurrentSession == nil || e.CurrentSession.Role == auth.RoleReadOnly {
-		return "Access denied: Write privileges required"
+	// A backup holds every table whole, whatever the column masks and
+	// grants of the user asking
+	if e.CurrentSession == nil || e.CurrentSession.Role != auth.RoleAdmin {
+		return "Access denied: Admin privileges required"
 	}
 
 	parts := strings.Fields(input)
 	if len(parts) < 2 {
-		return "Syntax error: BACKUP [TO path] [DESCRIPTION description]"
+		return "Syntax error: BACKUP [TO path|STDOUT] [DESCRIPTION description] [PASSPHRASE secret] [INCLUDE CREDENTIALS]"
 	}
 
 	// Default backup path
 	backupPath := fmt.Sprintf("./backups/harudb_backup_%s.backup", time.Now().Format("20060102_150405"))
-	description := "Manual backup"
+	opts := storage.BackupOptions{Description: "Manual backup"}
 
 	// Parse optional parameters
 	for i := 1; i < len(parts); i++ {
@@ -591,19 +915,83 @@ func (e *Engine) handleBackup(input string) string {
 			backupPath = parts[i+1]
 			i++
 		} else if strings.ToUpper(parts[i]) == "DESCRIPTION" && i+1 < len(parts) {
-			description = parts[i+1]
+			opts.Description = parts[i+1]
+			i++
+		} else if strings.ToUpper(parts[i]) == "PASSPHRASE" && i+1 < len(parts) {
+			opts.Passphrase = parts[i+1]
+			i++
+		} else if strings.ToUpper(parts[i]) == "INCLUDE" && i+1 < len(parts) &&
+			strings.ToUpper(parts[i+1]) == "CREDENTIALS" {
+			opts.IncludeCredentials, opts.IncludeKeys = true, true
+			i++
+		} else if strings.ToUpper(parts[i]) == "EXCLUDE" && i+1 < len(parts) &&
+			strings.ToUpper(parts[i+1]) == "CREDENTIALS" {
+			// The default, still accepted
 			i++
 		}
 	}
 
-	err := e.BackupManager.CreateBackup(backupPath, description)
+	// BACKUP TO STDOUT streams the archive back over the connection so a
+	// remote client can save it locally
+	if strings.EqualFold(backupPath, "STDOUT") {
+		return e.streamBackup(opts)
+	}
+
+	err := e.BackupManager.CreateBackupWithOptions(backupPath, opts)
 	if err != nil {
 		return fmt.Sprintf("Backup failed: %v", err)
 	}
 
+	if opts.Passphrase != "" {
+		return fmt.Sprintf("Encrypted backup created successfully: %s", backupPath)
+	}
 	return fmt.Sprintf("Backup created successfully: %s", backupPath)
 }
 
+// SetStream makes statements that stream output, such as BACKUP TO STDOUT,
+// send it through stream as it is produced. stream calls send with the
+// client's connection, holding back anything else written to it meanwhile.
+// Without one, the output is returned as the statement's result.
+func (e *Engine) SetStream(stream func(send func(io.Writer) error) error) {
+	e.stream = stream
+}
+
+// streamBackup sends a backup framed as a protocol backup stream
+func (e *Engine) streamBackup(opts storage.BackupOptions) string {
+	var result strings.Builder
+	stream := e.stream
+	if stream == nil {
+		stream = func(send func(io.Writer) error) error { return send(&result) }
+	}
+
+	var size int64
+	err := stream(func(w io.Writer) error {
+		sw := protocol.NewBackupStreamWriter(w)
+		if err := e.BackupManager.WriteBackup(sw, opts); err != nil {
+			return err
+		}
+		size = sw.Size()
+		return sw.Close()
+	})
+	if err != nil {
+		return fmt.Sprintf("Backup failed: %v", err)
+	}
+	if e.stream == nil {
+		return result.String()
+	}
+	return fmt.Sprintf("Backup streamed (%d bytes)", size)
+}
+
+// parsePassphrase returns the value following a PASSPHRASE keyword, if any
+func parsePassphrase(parts []string) string {
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.ToUpper(parts[i]) == "PASSPHRASE" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
 // handleRestore handles RESTORE commands
 func (e *Engine) handleRestore(input string) string {
 	if e.CurrentSession == nil || e.CurrentSession.Role != auth.RoleAdmin {
@@ -612,14 +1000,38 @@ func (e *Engine) handleRestore(input string) string {
 
 	parts := strings.Fields(input)
 	if len(parts) < 3 || strings.ToUpper(parts[1]) != "FROM" {
-		return "Syntax error: RESTORE FROM path"
+		return "Syntax error: RESTORE FROM path [PASSPHRASE secret]"
 	}
 
 	backupPath := parts[2]
-	err := e.BackupManager.RestoreBackup(backupPath)
+
+	// Release the WAL and reload all in-memory state from the restored files,
+	// otherwise the next write would overwrite them with the old tables. The
+	// first session's handle is reused, so everything holding it, and
+	// through it every session, sees the restored database.
+	db := e.shared.DB
+	dataDir := db.DataDir
+	changeCapture := db.Changes != nil
+	repairDir, repairPassphrase := db.RepairBackups()
+	db.Close()
+	err := e.BackupManager.RestoreBackupWithPassphrase(backupPath, parsePassphrase(parts))
+	*db = *storage.NewDatabase(dataDir)
+	db.SetRepairBackups(repairDir, repairPassphrase)
+	e.BackupManager.SetSnapshotter(db)
+	if changeCapture {
+		if cdcErr := db.EnableChangeLog(); cdcErr != nil {
+			log.Printf("Warning: failed to re-enable change capture: %v\n", cdcErr)
+		}
+	}
+	if !e.DB.SameDatabase(db) {
+		e.DB = db.Session()
+	}
 	if err != nil {
 		return fmt.Sprintf("Restore failed: %v", err)
 	}
+	if err := e.UserManager.Reload(); err != nil {
+		return fmt.Sprintf("Database restored from %s (warning: failed to reload users: %v)", backupPath, err)
+	}
 
 	return fmt.Sprintf("Database restored successfully from: %s", backupPath)
 }
@@ -628,11 +1040,11 @@ func (e *Engine) handleRestore(input string) string {
 func (e *Engine) handleBackupInfo(input string) string {
 	parts := strings.Fields(input)
 	if len(parts) < 3 {
-		return "Syntax error: BACKUP INFO path"
+		return "Syntax error: BACKUP INFO path [PASSPHRASE secret]"
 	}
 
 	backupPath := parts[2]
-	info, err := e.BackupManager.GetBackupInfo(backupPath)
+	info, err := e.BackupManager.GetBackupInfoWithPassphrase(backupPath, parsePassphrase(parts))
 	if err != nil {
 		return fmt.Sprintf("Failed to get backup info: %v", err)
 	}
@@ -642,14 +1054,34 @@ func (e *Engine) handleBackupInfo(input string) string {
 		"Version: %s\n"+
 		"Table Count: %d\n"+
 		"Backup Size: %d bytes\n"+
+		"Encrypted: %t\n"+
+		"Manifest Files: %d\n"+
 		"Description: %s",
 		info.Timestamp.Format("2006-01-02 15:04:05"),
 		info.Version,
 		info.TableCount,
 		info.BackupSize,
+		info.Encrypted,
+		len(info.Files),
 		info.Description)
 }
 
+// handleBackupVerify handles BACKUP VERIFY commands
+func (e *Engine) handleBackupVerify(input string) string {
+	parts := strings.Fields(input)
+	if len(parts) < 3 {
+		return "Syntax error: BACKUP VERIFY path [PASSPHRASE secret]"
+	}
+
+	backupPath := parts[2]
+	info, err := e.BackupManager.VerifyBackup(backupPath, parsePassphrase(parts))
+	if err != nil {
+		return fmt.Sprintf("Backup verification failed: %v", err)
+	}
+
+	return fmt.Sprintf("Backup verified: %d files match manifest (%s)", len(info.Files), backupPath)
+}
+
 // handleListBackups handles LIST BACKUPS commands
 func (e *Engine) handleListBackups(input string) string {
 	parts := strings.Fields(input)
@@ -676,6 +1108,38 @@ func (e *Engine) handleListBackups(input string) string {
 	return result
 }
 
+// handleExport handles EXPORT TABLE commands
+func (e *Engine) handleExport(input string) string {
+	if e.CurrentSession == nil || e.CurrentSession.Role == auth.RoleReadOnly {
+		return "Access denied: Write privileges required"
+	}
+
+	parts := sqlFields(input)
+	if len(parts) < 5 || strings.ToUpper(parts[3]) != "TO" {
+		return "Syntax error: EXPORT TABLE table TO 'file.parquet'"
+	}
+
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	exportPath := strings.Trim(strings.Join(parts[4:], " "), "'\"")
+	if msg := e.maskedTableError(tableName); msg != "" {
+		return msg
+	}
+
+	if !strings.EqualFold(filepath.Ext(exportPath), ".parquet") {
+		return "Unsupported export format: only .parquet files are supported"
+	}
+
+	rows, err := e.DB.ExportParquet(tableName, exportPath)
+	if err != nil {
+		return fmt.Sprintf("Export failed: %v", err)
+	}
+
+	return fmt.Sprintf("Exported %d rows from %s to %s", rows, tableName, exportPath)
+}
+
 // handleChangePassword handles CHANGE PASSWORD commands
 func (e *Engine) handleChangePassword(input string) string {
 	parts := strings.Fields(input)
@@ -700,46 +1164,3 @@ func (e *Engine) handleChangePassword(input string) string {
 
 	return "Password changed successfully"
 }
-
-// handleHelp handles HELP commands
-func (e *Engine) handleHelp() string {
-	helpText := `HaruDB Commands:
-
-Authentication:
-  LOGIN username password          - Login to database
-  LOGOUT                          - Logout from database
-  CHANGE PASSWORD old new         - Change your password
-  CREATE USER user pass [role]    - Create new user (Admin only)
-  DROP USER username              - Delete user (Admin only)
-  LIST USERS                      - List all users (Admin only)
-
-Database Operations:
-  CREATE TABLE name (col1, col2)  - Create table
-  DROP TABLE name                 - Drop table
-  INSERT INTO table VALUES (...)  - Insert data
-  SELECT * FROM table             - Query data
-  UPDATE table SET col=val ROW n  - Update row
-  DELETE FROM table ROW n         - Delete row
-  CREATE INDEX ON table (col)     - Create index
-
-Transactions:
-  BEGIN TRANSACTION               - Start transaction
-  COMMIT                         - Commit transaction
-  ROLLBACK                       - Rollback transaction
-  SAVEPOINT name                  - Create savepoint
-
-Backup & Restore:
-  BACKUP [TO path] [DESC desc]   - Create backup
-  RESTORE FROM path               - Restore from backup
-  LIST BACKUPS [dir]              - List backups
-  BACKUP INFO path                - Show backup info
-
-Other:
-  HELP                           - Show this help
-  EXIT                           - Exit database
-
-Default admin: admin / admin123
-Please change the default password after first login!`
-
-	return helpText
-}
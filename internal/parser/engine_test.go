@@ -0,0 +1,138 @@
+// internal/parser/engine_test.go
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+// testDataDir returns a new data directory whose admin logs in with admin123
+func testDataDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := auth.CreateAdmin(dir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestEngineCreatesNoDefaultAdmin(t *testing.T) {
+	engine := NewEngine(t.TempDir())
+	defer engine.DB.Close()
+	if got := engine.Execute("LOGIN admin admin123"); !strings.HasPrefix(got, "Login failed") {
+		t.Errorf("logged in with the well-known password: %s", got)
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE events (at, note)")
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, note := range []string{"time:2024-01-01T00:00:00Z", "time: 10am", "it's"} {
+		if _, err := engine.ExecArgs("INSERT INTO events VALUES (?, ?)", at, note); err != nil {
+			t.Fatalf("insert %q: %v", note, err)
+		}
+	}
+	if _, err := engine.ExecArgs("INSERT INTO events VALUES (?)", 1, 2); err == nil {
+		t.Error("bound more arguments than placeholders")
+	}
+
+	lines := strings.Split(strings.TrimSpace(engine.Execute("SELECT * FROM events")), "\n")
+	for i, want := range []string{"time:2024-01-01T00:00:00Z", "time: 10am", "it's"} {
+		values := strings.Split(lines[i+1], " | ")
+		if got, err := protocol.DecodeValue(values[0]); err != nil || got != at {
+			t.Errorf("row %d at: %#v, %v", i+1, got, err)
+		}
+		if got, err := protocol.DecodeValue(values[1]); err != nil || got != want {
+			t.Errorf("row %d note: %#v, %v", i+1, got, err)
+		}
+	}
+}
+
+func TestBackupLeavesSecretsOut(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE t (id)")
+
+	backupDir := t.TempDir()
+	for name, stmt := range map[string]string{
+		"default": "BACKUP TO " + filepath.Join(backupDir, "default.backup"),
+		"with":    "BACKUP TO " + filepath.Join(backupDir, "with.backup") + " INCLUDE CREDENTIALS",
+	} {
+		if got := engine.Execute(stmt); !strings.HasPrefix(got, "Backup created") {
+			t.Fatalf("%s: %s", stmt, got)
+		}
+		info, err := engine.BackupManager.VerifyBackup(filepath.Join(backupDir, name+".backup"), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hasUsers := false
+		for _, f := range info.Files {
+			hasUsers = hasUsers || f.Name == "users.json"
+		}
+		if hasUsers != (name == "with") {
+			t.Errorf("%s: users.json backed up: %t", stmt, hasUsers)
+		}
+	}
+}
+
+func TestBackupIsAdminOnly(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE USER writer pass123 user")
+	engine.Execute("LOGOUT")
+	engine.Execute("LOGIN writer pass123")
+
+	backup := filepath.Join(t.TempDir(), "b.backup")
+	for _, stmt := range []string{"BACKUP TO STDOUT", "BACKUP TO " + backup, "BACKUP TO STDOUT INCLUDE CREDENTIALS"} {
+		got, err := engine.Exec(stmt)
+		if protocol.CodeOf(err) != protocol.CodeAuth {
+			t.Errorf("%s as a user: %q, %v", stmt, got, err)
+		}
+	}
+}
+
+func TestBackupToStdoutStreams(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE t (id)")
+	engine.Execute("INSERT INTO t VALUES (1)")
+
+	var sent bytes.Buffer
+	engine.SetStream(func(send func(io.Writer) error) error { return send(&sent) })
+	got := engine.Execute("BACKUP TO STDOUT")
+	if !strings.HasPrefix(got, "Backup streamed") {
+		t.Fatalf("BACKUP TO STDOUT: %s", got)
+	}
+
+	r := bufio.NewReader(&sent)
+	begin, _ := r.ReadString('\n')
+	backup := filepath.Join(t.TempDir(), "streamed.backup")
+	f, err := os.Create(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = protocol.DecodeBackupStream(begin, r, f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := engine.BackupManager.VerifyBackup(backup, ""); err != nil || info.TableCount != 1 {
+		t.Errorf("streamed backup: %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,118 @@
+// internal/parser/errors.go
+//
+// Statement errors. Handlers answer with a message whether they succeed or
+// fail; the engine turns failure messages into a *protocol.Error with a code
+// the server sends to clients. Only messages are classified: rows a
+// statement returns are never taken for a failure, whatever they contain.
+package parser
+
+import (
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// errorPrefixes are the message prefixes the engine uses for failures
+var errorPrefixes = []string{
+	"Syntax error", "Error", "Failed", "Invalid", "Unknown command", "Access denied",
+	"Insufficient permissions", "Please login first", "WHERE clause error",
+	"Column count does not match", "Row index out of bounds", "Unsupported",
+	"Login failed", "Logout failed", "Backup failed", "Backup verification failed",
+	"Export failed", "Restore failed",
+}
+
+// authPrefixes start failures caused by authentication or authorization
+var authPrefixes = []string{
+	"Access denied", "Insufficient permissions", "Please login first", "Login failed",
+	"Invalid old password",
+}
+
+// syntaxPrefixes start failures caused by malformed statements
+var syntaxPrefixes = []string{
+	"Syntax error", "WHERE clause error", "Unknown command", "Invalid", "Unsupported",
+}
+
+// constraintMarkers appear in failures caused by conflicting data
+var constraintMarkers = []string{
+	"already exists", "Column count does not match", "Row index out of bounds",
+	"duplicate", "rejected", "version conflict", "violates",
+}
+
+// MessageError returns the error for an engine message that reports a
+// failure, such as a rejected AdmitQuery, or nil for a success message
+func MessageError(msg string) error {
+	if !isFailure(msg) {
+		return nil
+	}
+	return &protocol.Error{Code: classify(msg), Message: msg}
+}
+
+// messageResult returns a handler's message as the statement's result, or
+// as its error if it reports a failure
+func messageResult(msg string) (string, error) {
+	if err := MessageError(msg); err != nil {
+		return "", err
+	}
+	return msg, nil
+}
+
+// isFailure reports whether a message describes a failure
+func isFailure(msg string) bool {
+	if hasAnyPrefix(msg, errorPrefixes) {
+		return true
+	}
+	// "Table users created" is a success, "Table users not found" is not
+	for _, object := range []string{"Table ", "Column ", "Procedure ", "User ", "Cursor "} {
+		if strings.HasPrefix(msg, object) {
+			line, _, _ := strings.Cut(msg, "\n")
+			return strings.Contains(line, "not found") || strings.Contains(line, "already exists") ||
+				strings.Contains(line, " failed")
+		}
+	}
+	return false
+}
+
+// classify returns the code of a failure message. Storage failures are
+// coded by their format; the engine's own by the words they contain.
+func classify(msg string) protocol.ErrorCode {
+	if code := storage.FailureCode(msg); code != "" {
+		return code
+	}
+	// "Error: invalid value ..." is classified by the text after "Error: "
+	line, _, _ := strings.Cut(msg, "\n")
+	lower := strings.ToLower(line)
+	if rest, ok := strings.CutPrefix(line, "Error: "); ok && rest != "" {
+		line = strings.ToUpper(rest[:1]) + rest[1:]
+	}
+	switch {
+	case strings.Contains(lower, "timed out"):
+		return protocol.CodeTimeout
+	case strings.Contains(lower, "retry later"):
+		return protocol.CodeBusy
+	case strings.Contains(lower, "could not serialize"):
+		return protocol.CodeSerialization
+	case hasAnyPrefix(line, authPrefixes):
+		return protocol.CodeAuth
+	case hasAnyPrefix(line, syntaxPrefixes):
+		return protocol.CodeSyntax
+	case strings.Contains(lower, "not found") || strings.Contains(lower, "does not exist"):
+		return protocol.CodeNotFound
+	}
+	for _, marker := range constraintMarkers {
+		if strings.Contains(lower, strings.ToLower(marker)) {
+			return protocol.CodeConstraint
+		}
+	}
+	return protocol.CodeInternal
+}
+
+// hasAnyPrefix reports whether s starts with any of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// internal/parser/errors_test.go
+package parser
+
+import (
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestMessageError(t *testing.T) {
+	for msg, want := range map[string]protocol.ErrorCode{
+		"Syntax error: UPDATE table SET column = value ROW index":                                                       protocol.CodeSyntax,
+		"WHERE clause error: incomplete condition":                                                                      protocol.CodeSyntax,
+		"Error: invalid value for output_format: must be text":                                                          protocol.CodeSyntax,
+		"Table users not found":                                                                                         protocol.CodeNotFound,
+		"Error: procedure p not found":                                                                                  protocol.CodeNotFound,
+		"Table users already exists":                                                                                    protocol.CodeConstraint,
+		"Column email already exists":                                                                                   protocol.CodeConstraint,
+		"Column count does not match. Expected 2, got 3":                                                                protocol.CodeConstraint,
+		"Error: NULL value in column id violates NOT NULL":                                                              protocol.CodeConstraint,
+		"Failed to commit transaction: could not serialize access":                                                      protocol.CodeSerialization,
+		"Error: could not serialize access: table t was changed by another transaction":                                 protocol.CodeSerialization,
+		"Error: version conflict on row ID 1 of t: the row is at version 3, not 2":                                      protocol.CodeConstraint,
+		"Error: WAL is unavailable (disk failed); writes are rejected until the server is restarted with a working WAL": protocol.CodeInternal,
+		"Error evaluating WHERE condition: column nope not found":                                                       protocol.CodeNotFound,
+		"Access denied: Write privileges required":                                                                      protocol.CodeAuth,
+		"Please login first":                                                                                            protocol.CodeAuth,
+		"Error: Command timed out after 10s":                                                                            protocol.CodeTimeout,
+		"Error: write queue is full (8 writes in flight); retry later":                                                  protocol.CodeBusy,
+		"Backup failed: disk full":                                                                                      protocol.CodeInternal,
+		"Table users created":                                                                                           "",
+		"Column email added to table users":                                                                             "",
+		"1 row inserted into users":                                                                                     "",
+	} {
+		err := MessageError(msg)
+		if got := protocol.CodeOf(err); got != want {
+			t.Errorf("%q: got %q, want %q", msg, got, want)
+		}
+		if err != nil && err.Error() != msg {
+			t.Errorf("%q: error message is %q", msg, err.Error())
+		}
+	}
+}
+
+func TestRowsAreNeverErrors(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE t (id, name)")
+	engine.Execute("INSERT INTO t VALUES (1, 'Syntax error: none')")
+
+	for stmt, want := range map[string]string{
+		"SELECT name AS Invalid FROM t":           "Invalid\nSyntax error: none\n",
+		`SELECT id AS "Table x not found" FROM t`: "Table x not found\n1\n",
+		"SELECT name FROM t WHERE name = 'Error'": "name\n(no rows)\n",
+	} {
+		got, err := engine.Exec(stmt)
+		if err != nil {
+			t.Errorf("%s: rows taken for an error: %v", stmt, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", stmt, got, want)
+		}
+		if encoded := protocol.EncodeResult(got, err); encoded != got {
+			t.Errorf("%s: rows encoded as %q", stmt, encoded)
+		}
+	}
+
+	_, err := engine.Exec("SELECT * FROM missing")
+	if protocol.CodeOf(err) != protocol.CodeNotFound {
+		t.Errorf("expected NOT_FOUND for a missing table, got %v", err)
+	}
+	if encoded := protocol.EncodeResult("", err); encoded != "ERROR NOT_FOUND: Table missing not found" {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+}
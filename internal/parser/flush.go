@@ -0,0 +1,72 @@
+// internal/parser/flush.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleCheckpoint handles CHECKPOINT
+func (e *Engine) handleCheckpoint(input string) string {
+	if len(strings.Fields(input)) != 1 {
+		return "Syntax error: CHECKPOINT"
+	}
+	return e.forceCheckpoint(false)
+}
+
+// handleFlushTables handles FLUSH TABLES
+func (e *Engine) handleFlushTables(input string) string {
+	if len(strings.Fields(input)) != 2 {
+		return "Syntax error: FLUSH TABLES"
+	}
+	return e.forceCheckpoint(true)
+}
+
+// forceCheckpoint writes everything to disk and truncates the WAL, first
+// rewriting every table file when flushTables is set
+func (e *Engine) forceCheckpoint(flushTables bool) string {
+	// Both block every writer until the data is on disk
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	result, err := e.DB.ForceCheckpoint(flushTables)
+	if err != nil {
+		return fmt.Sprintf("Error: checkpoint failed: %v", err)
+	}
+	msg := fmt.Sprintf("Checkpoint complete: %d dirty pages written, %d bytes of WAL truncated", result.Pages, result.WALBytes)
+	if flushTables {
+		msg = fmt.Sprintf("%d tables flushed. %s", result.Tables, msg)
+	}
+	return msg
+}
+
+// handleFlushCaches handles FLUSH PAGE CACHE, FLUSH PATTERN CACHE and
+// FLUSH CACHES, which empties both
+func (e *Engine) handleFlushCaches(input string) string {
+	var page, pattern bool
+	switch strings.ToUpper(strings.Join(strings.Fields(input), " ")) {
+	case "FLUSH PAGE CACHE":
+		page = true
+	case "FLUSH PATTERN CACHE":
+		pattern = true
+	case "FLUSH CACHES":
+		page, pattern = true, true
+	default:
+		return "Syntax error: FLUSH PAGE CACHE | FLUSH PATTERN CACHE | FLUSH CACHES"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	var results []string
+	if page {
+		dropped, err := e.DB.FlushPageCache()
+		if err != nil {
+			return fmt.Sprintf("Error: failed to flush page cache: %v", err)
+		}
+		results = append(results, fmt.Sprintf("Page cache flushed: %d pages dropped", dropped))
+	}
+	if pattern {
+		results = append(results, fmt.Sprintf("Pattern cache flushed: %d compiled patterns dropped", flushRegexCache()))
+	}
+	return strings.Join(results, "\n")
+}
@@ -0,0 +1,92 @@
+// internal/parser/flush_test.go
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointAndFlushTables(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("CREATE UNLOGGED TABLE hits (path)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'Ann')")
+	engine.Execute("INSERT INTO hits VALUES ('/')")
+
+	got := engine.Execute("CHECKPOINT")
+	if !strings.HasPrefix(got, "Checkpoint complete: 2 dirty pages written") {
+		t.Errorf("CHECKPOINT: %s", got)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "wal.log")); err != nil || info.Size() != 0 {
+		t.Errorf("WAL after CHECKPOINT: %v %v", info, err)
+	}
+
+	engine.Execute("INSERT INTO users VALUES ('2', 'Bo')")
+	got = engine.Execute("FLUSH TABLES")
+	if !strings.HasPrefix(got, "2 tables flushed. Checkpoint complete:") || strings.Contains(got, " 0 bytes") {
+		t.Errorf("FLUSH TABLES: %s", got)
+	}
+	for stmt, want := range map[string]string{
+		"CHECKPOINT now":     "Syntax error: CHECKPOINT",
+		"FLUSH TABLES users": "Syntax error: FLUSH TABLES",
+	} {
+		if got := engine.Execute(stmt); got != want {
+			t.Errorf("%s: %s", stmt, got)
+		}
+	}
+
+	// The data is in the table files alone
+	reopened := NewEngine(dir)
+	reopened.Execute("LOGIN admin admin123")
+	if got := reopened.Execute("SELECT * FROM users"); got != "id | name\n1 | Ann\n2 | Bo\n" {
+		t.Errorf("users after reopen: %q", got)
+	}
+	if got := reopened.Execute("SELECT * FROM hits"); got != "path\n/\n" {
+		t.Errorf("unlogged table after reopen: %q", got)
+	}
+
+	engine.Execute("CREATE USER writer pass123 user")
+	engine.Execute("LOGIN writer pass123")
+	if got := engine.Execute("CHECKPOINT"); got != ErrInsufficientPermissions {
+		t.Errorf("non-admin CHECKPOINT: %s", got)
+	}
+}
+
+func TestFlushCaches(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'Ann')")
+	engine.Execute("SELECT * FROM users WHERE name ~ '^A'")
+
+	want := "Page cache flushed: 1 pages dropped\nPattern cache flushed: 1 compiled patterns dropped"
+	if got := engine.Execute("FLUSH CACHES"); got != want {
+		t.Errorf("FLUSH CACHES: %q", got)
+	}
+	if n := engine.DB.PageStorage.DirtyPages(); n != 0 {
+		t.Errorf("%d dirty pages after FLUSH CACHES", n)
+	}
+
+	// Pages are read back from their files for the next insert
+	engine.Execute("INSERT INTO users VALUES ('2', 'Bo')")
+	rows, err := engine.DB.PageStorage.ReadRows("users", 0, 10)
+	if err != nil || len(rows) != 2 {
+		t.Errorf("page rows after flush: %v %v", rows, err)
+	}
+	if got := engine.Execute("FLUSH PAGE CACHE"); !strings.HasPrefix(got, "Page cache flushed: ") || strings.HasSuffix(got, ": 0 pages dropped") {
+		t.Errorf("FLUSH PAGE CACHE: %q", got)
+	}
+	for stmt, want := range map[string]string{
+		"FLUSH PATTERN CACHE": "Pattern cache flushed: 0 compiled patterns dropped",
+		"FLUSH CACHES now":    "Syntax error: FLUSH PAGE CACHE | FLUSH PATTERN CACHE | FLUSH CACHES",
+	} {
+		if got := engine.Execute(stmt); got != want {
+			t.Errorf("%s: %q", stmt, got)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// internal/parser/functions.go
+//
+// Conditional and temporal functions. COALESCE, IFNULL, NULLIF, GREATEST,
+// LEAST, NOW, DATE_ADD and DATE_SUB can be selected, as in SELECT
+// COALESCE(nickname, name) AS display FROM users, and compared in WHERE, as
+// in WHERE COALESCE(score, 0) > 10. Their arguments are columns, literals or
+// further calls; NULL, TRUE and FALSE are the bare keywords, and quoted
+// arguments are text. DATE_ADD and DATE_SUB take an INTERVAL (see
+// interval.go) as their second argument.
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// ValueExpr is a call of a conditional function, or a column or literal
+// among its arguments
+type ValueExpr struct {
+	// Func is the function called, in upper case; "" for a column or a
+	// literal
+	Func string
+	Args []*ValueExpr
+	// Column names the column of a column reference
+	Column string
+	// Value is the value of a literal, the time of a NOW() call, or the
+	// text of an INTERVAL
+	Value string
+	// interval is set for an INTERVAL argument
+	interval *interval
+}
+
+// valueFuncs maps each conditional function to its least and greatest
+// number of arguments; -1 means any number
+var valueFuncs = map[string][2]int{
+	"COALESCE": {1, -1},
+	"IFNULL":   {2, 2},
+	"NULLIF":   {2, 2},
+	"GREATEST": {1, -1},
+	"LEAST":    {1, -1},
+	"NOW":      {0, 0},
+	"DATE_ADD": {2, 2},
+	"DATE_SUB": {2, 2},
+}
+
+// isValueCall reports whether the tokens at i call a conditional function
+func isValueCall(tokens []string, quoted []bool, i int) bool {
+	if i+1 >= len(tokens) || quoted[i] || tokens[i+1] != "(" || quoted[i+1] {
+		return false
+	}
+	_, ok := valueFuncs[strings.ToUpper(tokens[i])]
+	return ok
+}
+
+// ParseValueExpr parses a call of a conditional function such as
+// COALESCE(nickname, name, 'anonymous')
+func ParseValueExpr(text string) (*ValueExpr, error) {
+	tokens, quoted := tokenizeWhereQuoted(text)
+	if !isValueCall(tokens, quoted, 0) {
+		return nil, fmt.Errorf("expected a call of COALESCE, IFNULL, NULLIF, GREATEST, LEAST, NOW, DATE_ADD or DATE_SUB, got %s", strings.TrimSpace(text))
+	}
+	expr, end, err := parseValueExpr(tokens, quoted, 0)
+	if err != nil {
+		return nil, err
+	}
+	if end < len(tokens) {
+		return nil, fmt.Errorf("unexpected %s after %s(...)", tokens[end], expr.Func)
+	}
+	return expr, nil
+}
+
+// parseValueExpr parses a call, column or literal starting at token i and
+// returns it with the position of the token after it
+func parseValueExpr(tokens []string, quoted []bool, i int) (*ValueExpr, int, error) {
+	if i >= len(tokens) {
+		return nil, i, fmt.Errorf("incomplete function call")
+	}
+	token := tokens[i]
+	switch {
+	case quoted[i]:
+		return &ValueExpr{Value: token}, i + 1, nil
+	case isValueCall(tokens, quoted, i):
+		return parseValueCall(tokens, quoted, i)
+	case token == "(" || token == ")" || token == ",":
+		return nil, i, fmt.Errorf("unexpected %s in function call", token)
+	case strings.EqualFold(token, "NULL"):
+		return &ValueExpr{Value: storage.NullValue}, i + 1, nil
+	case strings.EqualFold(token, "TRUE"), strings.EqualFold(token, "FALSE"):
+		return &ValueExpr{Value: strings.ToLower(token)}, i + 1, nil
+	case strings.EqualFold(token, "INTERVAL"):
+		return parseIntervalArg(tokens, quoted, i)
+	}
+	if _, err := strconv.ParseFloat(token, 64); err == nil {
+		return &ValueExpr{Value: token}, i + 1, nil
+	}
+	return &ValueExpr{Column: token}, i + 1, nil
+}
+
+// parseIntervalArg parses INTERVAL '7 days', or INTERVAL 7 DAY as MySQL
+// writes it
+func parseIntervalArg(tokens []string, quoted []bool, i int) (*ValueExpr, int, error) {
+	var text string
+	switch {
+	case i+1 < len(tokens) && quoted[i+1]:
+		text, i = tokens[i+1], i+2
+	case i+2 < len(tokens) && !quoted[i+1] && !quoted[i+2]:
+		text, i = tokens[i+1]+" "+tokens[i+2], i+3
+	default:
+		return nil, i, fmt.Errorf("INTERVAL expects a quoted interval such as '7 days'")
+	}
+	iv, err := parseInterval(text)
+	if err != nil {
+		return nil, i, err
+	}
+	return &ValueExpr{Value: text, interval: &iv}, i, nil
+}
+
+// parseValueCall parses fn(arg, ...) starting at the function name
+func parseValueCall(tokens []string, quoted []bool, i int) (*ValueExpr, int, error) {
+	expr := &ValueExpr{Func: strings.ToUpper(tokens[i])}
+	if expr.Func == "NOW" {
+		if i+2 >= len(tokens) || tokens[i+2] != ")" || quoted[i+2] {
+			return nil, i, fmt.Errorf("NOW expects no arguments")
+		}
+		expr.Value = storage.FormatTimestamp(time.Now())
+		return expr, i + 3, nil
+	}
+	i += 2
+	for {
+		arg, next, err := parseValueExpr(tokens, quoted, i)
+		if err != nil {
+			return nil, next, err
+		}
+		expr.Args = append(expr.Args, arg)
+		if next >= len(tokens) || quoted[next] {
+			return nil, next, fmt.Errorf("%s expects a closing parenthesis", expr.Func)
+		}
+		i = next + 1
+		if tokens[next] == ")" {
+			break
+		}
+		if tokens[next] != "," {
+			return nil, next, fmt.Errorf("unexpected %s in %s arguments", tokens[next], expr.Func)
+		}
+	}
+	counts := valueFuncs[expr.Func]
+	switch {
+	case counts[0] == counts[1] && len(expr.Args) != counts[0]:
+		return nil, i, fmt.Errorf("%s expects %d arguments", expr.Func, counts[0])
+	case len(expr.Args) < counts[0]:
+		return nil, i, fmt.Errorf("%s expects at least %d argument", expr.Func, counts[0])
+	}
+	// An INTERVAL is the second argument of DATE_ADD and DATE_SUB, and no
+	// other
+	for k, arg := range expr.Args {
+		dateArith := expr.Func == "DATE_ADD" || expr.Func == "DATE_SUB"
+		if wantInterval := dateArith && k == 1; wantInterval != (arg.interval != nil) {
+			if wantInterval {
+				return nil, i, fmt.Errorf("%s expects an INTERVAL such as INTERVAL '7 days' as its second argument", expr.Func)
+			}
+			return nil, i, fmt.Errorf("INTERVAL is an argument of DATE_ADD and DATE_SUB only")
+		}
+	}
+	return expr, i, nil
+}
+
+// Label returns the heading of a selected call without an alias: the
+// function's name in lower case
+func (ve *ValueExpr) Label() string {
+	return strings.ToLower(ve.Func)
+}
+
+// String returns the expression as it would be written
+func (ve *ValueExpr) String() string {
+	switch {
+	case ve.Func == "NOW":
+		return "NOW()"
+	case ve.interval != nil:
+		return "INTERVAL '" + ve.Value + "'"
+	case ve.Func != "":
+		args := make([]string, len(ve.Args))
+		for i, arg := range ve.Args {
+			args[i] = arg.String()
+		}
+		return ve.Func + "(" + strings.Join(args, ", ") + ")"
+	case ve.Column != "":
+		return ve.Column
+	case storage.IsNull(ve.Value):
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(ve.Value, "'", "''") + "'"
+}
+
+// ColumnRefs returns the columns the expression reads
+func (ve *ValueExpr) ColumnRefs() []string {
+	if ve.Column != "" {
+		return []string{ve.Column}
+	}
+	var refs []string
+	for _, arg := range ve.Args {
+		refs = append(refs, arg.ColumnRefs()...)
+	}
+	return refs
+}
+
+// ResolveColumns replaces each column reference with the column resolve
+// returns for it
+func (ve *ValueExpr) ResolveColumns(resolve func(string) string) {
+	if ve.Column != "" {
+		ve.Column = resolve(ve.Column)
+	}
+	for _, arg := range ve.Args {
+		arg.ResolveColumns(resolve)
+	}
+}
+
+// EvaluateValue returns the value of the expression for a row whose columns
+// were checked with ColumnRefs; a missing column reads as NULL
+func (ve *ValueExpr) EvaluateValue(row []string, columnIndexes map[string]int) string {
+	value, err := ve.value(row, columnIndexes)
+	if err != nil {
+		return storage.NullValue
+	}
+	return value
+}
+
+// value returns the value of the expression for a row
+func (ve *ValueExpr) value(row []string, columnIndexes map[string]int) (string, error) {
+	if ve.Func == "" {
+		if ve.Column == "" {
+			return ve.Value, nil
+		}
+		idx, exists := columnIndexes[ve.Column]
+		if !exists || idx >= len(row) {
+			return "", fmt.Errorf("column %s not found", ve.Column)
+		}
+		return row[idx], nil
+	}
+
+	args := make([]string, len(ve.Args))
+	for i, arg := range ve.Args {
+		value, err := arg.value(row, columnIndexes)
+		if err != nil {
+			return "", err
+		}
+		args[i] = value
+	}
+	switch ve.Func {
+	case "NOW":
+		return ve.Value, nil
+	case "DATE_ADD", "DATE_SUB":
+		// A value that is not a time gives NULL. Whole days added to a
+		// date give a date.
+		t, ok := storage.ParseTime(args[0])
+		if !ok {
+			return storage.NullValue, nil
+		}
+		iv := ve.Args[1].interval
+		sign := 1
+		if ve.Func == "DATE_SUB" {
+			sign = -1
+		}
+		t = iv.addTo(t, sign)
+		if len(strings.TrimSpace(args[0])) == len(storage.DateFormat) && iv.dur == 0 {
+			return t.Format(storage.DateFormat), nil
+		}
+		return storage.FormatTimestamp(t), nil
+	case "NULLIF":
+		// The first argument, or NULL when it equals the second
+		if !storage.IsNull(args[0]) && !storage.IsNull(args[1]) && compareCells(args[0], args[1], nil) == 0 {
+			return storage.NullValue, nil
+		}
+		return args[0], nil
+	case "GREATEST", "LEAST":
+		// NULL arguments are ignored; the result is NULL when all are
+		result := storage.NullValue
+		for _, arg := range args {
+			if storage.IsNull(arg) {
+				continue
+			}
+			cmp := compareCells(arg, result, nil)
+			if storage.IsNull(result) || ve.Func == "GREATEST" && cmp > 0 || ve.Func == "LEAST" && cmp < 0 {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+	// COALESCE and IFNULL return the first argument that is not NULL
+	for _, arg := range args {
+		if !storage.IsNull(arg) {
+			return arg, nil
+		}
+	}
+	return storage.NullValue, nil
+}
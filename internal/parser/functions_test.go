@@ -0,0 +1,70 @@
+// internal/parser/functions_test.go
+package parser
+
+import (
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestValueExpr(t *testing.T) {
+	row := []string{"Ann", storage.NullValue, "7", "10", "0"}
+	columns := map[string]int{"name": 0, "nick": 1, "a": 2, "b": 3, "zero": 4}
+	for text, want := range map[string]string{
+		"COALESCE(nick, name)":                      "Ann",
+		"coalesce(nick, NULL)":                      storage.NullValue,
+		"IFNULL(nick, 'none')":                      "none",
+		"NULLIF(zero, 0)":                           storage.NullValue,
+		"NULLIF(a, b)":                              "7",
+		"GREATEST(a, b, nick)":                      "10",
+		"LEAST(a, b, '9')":                          "7",
+		"LEAST(nick, NULL)":                         storage.NullValue,
+		"GREATEST('pear', 'apple')":                 "pear",
+		"COALESCE(NULLIF(name, 'Ann'), nick, 'x')":  "x",
+		"DATE_ADD('2025-01-31', INTERVAL 1 MONTH)":  "2025-03-03",
+		"DATE_SUB('2025-01-01', INTERVAL '1 hour')": "2024-12-31T23:00:00.000000Z",
+		"DATE_ADD(name, INTERVAL '1 day')":          storage.NullValue,
+	} {
+		expr, err := ParseValueExpr(text)
+		if err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if got := expr.EvaluateValue(row, columns); got != want {
+			t.Errorf("%s: %q, want %q", text, got, want)
+		}
+	}
+	for _, bad := range []string{"COALESCE()", "NULLIF(a)", "IFNULL(a, b, c)", "COALESCE(a, b", "COALESCE(a b)", "COALESCE(a) x", "UPPER(a)", "NOW(a)", "DATE_ADD(a, 1)", "COALESCE(INTERVAL '1 day')", "DATE_SUB(a, INTERVAL 'soon')"} {
+		if _, err := ParseValueExpr(bad); err == nil {
+			t.Errorf("%s should not parse", bad)
+		}
+	}
+}
+
+func TestConditionalFunctions(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id INT, name TEXT, nick TEXT, score INT)")
+	engine.Execute("INSERT INTO users VALUES (1, 'Ann', 'annie', 5)")
+	engine.Execute("INSERT INTO users VALUES (2, 'Bob', NULL, NULL)")
+	engine.Execute("INSERT INTO users VALUES (3, 'Cy', '', 20)")
+	engine.Execute("CREATE TABLE teams (user_id INT, team TEXT)")
+	engine.Execute("INSERT INTO teams VALUES (2, 'red')")
+
+	for input, want := range map[string]string{
+		"SELECT id, COALESCE(nick, name) AS display FROM users":                           "id | display\n1 | annie\n2 | Bob\n3 | \n",
+		"SELECT COALESCE(NULLIF(u.nick, ''), u.name) FROM users u ORDER BY id DESC":       "coalesce\nCy\nBob\nannie\n",
+		"SELECT id, IFNULL(score, 0) score FROM users WHERE IFNULL(score, 0) < 10":        "id | score\n1 | 5\n2 | 0\n",
+		"SELECT id FROM users WHERE COALESCE(score, 0) > 4":                               "id\n1\n3\n",
+		"SELECT id FROM users WHERE GREATEST(score, 10) = 10 AND LEAST(id, 2) = 2":        "id\n2\n",
+		"SELECT id FROM users WHERE NULLIF(nick, '') IS NULL":                             "id\n2\n3\n",
+		"SELECT DISTINCT GREATEST(id, 2) AS g FROM users":                                 "g\n2\n3\n",
+		"SELECT u.id, COALESCE(t.team, 'none') AS team FROM users u, teams t ORDER BY id": "id | team\n1 | red\n2 | red\n3 | red\n",
+		"SELECT COALESCE(missing, name) FROM users":                                       "Column missing not found",
+		"SELECT COALESCE(nick FROM users":                                                 "Syntax error: COALESCE expects a closing parenthesis",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// internal/parser/grants.go
+//
+// Privileges. Every command needs one of three privileges: read, which
+// every role holds, write, held by admins and users, or admin. Statements
+// the session's role does not hold the privilege for are refused, HELP
+// lists only the commands the role may run, and SHOW GRANTS shows the
+// privileges a role holds.
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// privilege is what a role must hold to run a command
+type privilege int
+
+const (
+	privRead privilege = iota
+	privWrite
+	privAdmin
+)
+
+func (p privilege) String() string {
+	switch p {
+	case privRead:
+		return "read"
+	case privWrite:
+		return "write"
+	case privAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("privilege(%d)", int(p))
+}
+
+// roleHolds reports whether a role holds privilege p
+func roleHolds(role auth.UserRole, p privilege) bool {
+	switch role {
+	case auth.RoleAdmin:
+		return true
+	case auth.RoleUser:
+		return p <= privWrite
+	}
+	return p == privRead
+}
+
+// roleName returns a role as CREATE USER spells it
+func roleName(role auth.UserRole) string {
+	switch role {
+	case auth.RoleAdmin:
+		return "ADMIN"
+	case auth.RoleReadOnly:
+		return "READONLY"
+	}
+	return "USER"
+}
+
+// mayRun reports whether the session may run cmd. Before logging in the
+// role is not known, so every command is allowed here; commands that are
+// not public then ask for a login.
+func (e *Engine) mayRun(cmd command) bool {
+	if e.CurrentSession == nil {
+		return true
+	}
+	return roleHolds(e.CurrentSession.Role, cmd.privilege)
+}
+
+// handleShowGrants handles SHOW GRANTS, the privileges of the session's
+// role, and SHOW GRANTS FOR user, those of another user (admins only)
+func (e *Engine) handleShowGrants(input string) (*storage.ResultSet, string) {
+	fields := strings.Fields(input)
+	username, role := e.CurrentSession.Username, e.CurrentSession.Role
+	switch {
+	case len(fields) == 2:
+	case len(fields) == 4 && strings.EqualFold(fields[2], "FOR"):
+		if fields[3] != username {
+			if err := e.requireAdmin(); err != "" {
+				return nil, err
+			}
+			found := false
+			for _, user := range e.UserManager.ListUsers() {
+				if user.Username == fields[3] {
+					username, role, found = user.Username, user.Role, true
+				}
+			}
+			if !found {
+				return nil, fmt.Sprintf("Error: user %s not found", fields[3])
+			}
+		}
+	default:
+		return nil, "Syntax error: SHOW GRANTS [FOR user]"
+	}
+
+	rs := &storage.ResultSet{Columns: []string{"user", "role", "privilege"}}
+	for p := privRead; p <= privAdmin; p++ {
+		if roleHolds(role, p) {
+			rs.Rows = append(rs.Rows, []string{username, roleName(role), p.String()})
+		}
+	}
+	return rs, ""
+}
@@ -0,0 +1,99 @@
+// internal/parser/grants_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRolePrivileges(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id, name)")
+	engine.Execute("INSERT INTO items VALUES (1, 'pen')")
+	engine.Execute("CREATE USER viewer secret123 READONLY")
+	engine.Execute("CREATE USER clerk secret123 USER")
+
+	if got, want := engine.Execute("SHOW GRANTS FOR viewer"), "user | role | privilege\nviewer | READONLY | read\n"; got != want {
+		t.Errorf("SHOW GRANTS FOR viewer:\n got %q\nwant %q", got, want)
+	}
+	if got := engine.Execute("SHOW GRANTS FOR nobody"); got != "Error: user nobody not found" {
+		t.Errorf("SHOW GRANTS FOR a missing user: %q", got)
+	}
+
+	// Read-only users can read but not write, and are not shown how to
+	engine.Execute("LOGIN viewer secret123")
+	for stmt, want := range map[string]string{
+		"SELECT * FROM items":                 "id | name\n1 | pen\n",
+		"INSERT INTO items VALUES (2, 'ink')": ErrInsufficientPermissions,
+		"DROP TABLE items":                    ErrInsufficientPermissions,
+		"CHECKPOINT":                          ErrInsufficientPermissions,
+		"SHOW GRANTS FOR admin":               ErrInsufficientPermissions,
+		"SHOW GRANTS":                         "user | role | privilege\nviewer | READONLY | read\n",
+		"SHOW GRANTS FOR viewer":              "user | role | privilege\nviewer | READONLY | read\n",
+	} {
+		if got := engine.Execute(stmt); got != want {
+			t.Errorf("%s as viewer:\n got %q\nwant %q", stmt, got, want)
+		}
+	}
+	help := engine.Execute("HELP")
+	if !strings.Contains(help, "SELECT * FROM table") || strings.Contains(help, "INSERT INTO") || strings.Contains(help, "CHECKPOINT") {
+		t.Errorf("HELP for a read-only user:\n%s", help)
+	}
+	if got := engine.Execute("HELP REKEY"); got != ErrInsufficientPermissions+": REKEY is not available to the READONLY role" {
+		t.Errorf("HELP REKEY as viewer: %q", got)
+	}
+
+	// Users write but do not administer
+	engine.Execute("LOGIN clerk secret123")
+	if got := engine.Execute("INSERT INTO items VALUES (2, 'ink')"); !strings.HasPrefix(got, "1 row inserted") {
+		t.Errorf("insert as clerk: %q", got)
+	}
+	if got := engine.Execute("SHOW LOCKS"); got != ErrInsufficientPermissions {
+		t.Errorf("SHOW LOCKS as clerk: %q", got)
+	}
+	help = engine.Execute("HELP")
+	if !strings.Contains(help, "INSERT INTO") || strings.Contains(help, "REKEY") || strings.Contains(help, "\nServer:\n  SHOW LOCKS") {
+		t.Errorf("HELP for a user:\n%s", help)
+	}
+	if got, want := engine.Execute("SHOW GRANTS"), "user | role | privilege\nclerk | USER | read\nclerk | USER | write\n"; got != want {
+		t.Errorf("SHOW GRANTS as clerk:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestPrivilegesFollowTheConnectionsSession(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id)")
+	engine.Execute("CREATE USER viewer secret123 READONLY")
+
+	admin, viewer, anonymous := engine.Connect("10.0.0.1:5000"), engine.Connect("10.0.0.2:5000"), engine.Connect("10.0.0.3:5000")
+	runOn(engine, admin, "LOGIN admin admin123")
+	runOn(engine, viewer, "LOGIN viewer secret123")
+
+	// The viewer logging in last does not change what the admin may do
+	if got := runOn(engine, admin, "INSERT INTO items VALUES (1)"); !strings.HasPrefix(got, "1 row inserted") {
+		t.Errorf("insert as admin: %q", got)
+	}
+	if help := runOn(engine, admin, "HELP"); !strings.Contains(help, "CHECKPOINT") {
+		t.Errorf("HELP for admin:\n%s", help)
+	}
+	if got := runOn(engine, viewer, "INSERT INTO items VALUES (2)"); got != ErrInsufficientPermissions {
+		t.Errorf("insert as viewer: %q", got)
+	}
+	if help := runOn(engine, viewer, "HELP"); strings.Contains(help, "CHECKPOINT") || strings.Contains(help, "INSERT INTO") {
+		t.Errorf("HELP for viewer:\n%s", help)
+	}
+	if got, want := runOn(engine, viewer, "SHOW GRANTS"), "user | role | privilege\nviewer | READONLY | read\n"; got != want {
+		t.Errorf("SHOW GRANTS as viewer: %q", got)
+	}
+
+	// A connection that has not logged in runs nothing but the public commands
+	if got := runOn(engine, anonymous, "SHOW GRANTS"); got != ErrNotAuthenticated {
+		t.Errorf("SHOW GRANTS before login: %q", got)
+	}
+	if got := runOn(engine, anonymous, "CHECKPOINT"); got != ErrNotAuthenticated {
+		t.Errorf("CHECKPOINT before login: %q", got)
+	}
+}
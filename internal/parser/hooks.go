@@ -0,0 +1,138 @@
+// internal/parser/hooks.go
+//
+// Statement hooks. Programs embedding the engine can register functions
+// that see every statement before it runs, with the session that
+// sent it, and either let it through, rewrite it or deny it. This is where
+// custom policy belongs, such as confining tenants to their own tables or
+// masking columns, instead of in the command handlers.
+package parser
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+)
+
+// Statement describes a statement about to run
+type Statement struct {
+	// Text is the statement as it will run. A hook may replace it; the rest
+	// of the fields are then worked out again for the next hook.
+	Text string
+	// Command is the prefix of the command that runs it, such as
+	// "INSERT INTO" or "SHOW TABLES", or "" for an unknown command
+	Command string
+	// Section is the HELP section of the command, such as "Session"
+	Section string
+	// Write reports whether the statement changes table data
+	Write bool
+	// Tables lists the tables the statement names, in lowercase
+	Tables []string
+}
+
+// StatementHook runs before a statement. It may change stmt.Text, and
+// returns an error to deny the statement, which is reported as "Access
+// denied:" followed by the error. session is the logged-in session.
+type StatementHook func(session *auth.Session, stmt *Statement) error
+
+// statementHooks holds the registered statement hooks
+type statementHooks struct {
+	mu    sync.RWMutex
+	hooks []StatementHook
+}
+
+// OnStatement registers a hook run before every statement, after those
+// registered earlier. Public commands such as LOGIN and HELP skip the hooks.
+func (e *Engine) OnStatement(fn StatementHook) {
+	e.statementHooks.mu.Lock()
+	defer e.statementHooks.mu.Unlock()
+	e.statementHooks.hooks = append(e.statementHooks.hooks, fn)
+}
+
+// runStatementHooks passes a statement through every hook and returns the
+// statement to run, or the message denying it
+func (e *Engine) runStatementHooks(input string) (string, string) {
+	e.statementHooks.mu.RLock()
+	hooks := e.statementHooks.hooks
+	e.statementHooks.mu.RUnlock()
+	if len(hooks) == 0 {
+		return input, ""
+	}
+
+	stmt := describeStatement(input)
+	for _, hook := range hooks {
+		if err := hook(e.CurrentSession, stmt); err != nil {
+			return "", "Access denied: " + err.Error()
+		}
+		if stmt.Text != input {
+			input = strings.TrimSuffix(strings.TrimSpace(stmt.Text), ";")
+			stmt = describeStatement(input)
+		}
+	}
+	return input, ""
+}
+
+// describeStatement fills in a Statement for input
+func describeStatement(input string) *Statement {
+	upper := strings.ToUpper(input)
+	stmt := &Statement{Text: input, Write: isDataWrite(upper), Tables: statementTables(input)}
+	if cmd := lookupCommand(upper); cmd != nil {
+		stmt.Command = cmd.prefix
+		stmt.Section = cmd.section
+	}
+	return stmt
+}
+
+// statementTables returns the names following FROM, JOIN, INTO, UPDATE,
+// TABLE and INDEX ON in a statement. It is a scan of the words rather than a
+// parse, so a column or value spelled like one of these keywords can add
+// a name that is not a table.
+func statementTables(input string) []string {
+	fields := sqlFields(input)
+	var tables []string
+	seen := map[string]bool{}
+	for i := 0; i < len(fields)-1; i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "FROM":
+			// A FROM list names every table it joins
+			if from, _, err := parseFromList(fields[i+1:]); err == nil && len(from) > 1 {
+				for _, jt := range from {
+					if !seen[jt.Name] {
+						seen[jt.Name] = true
+						tables = append(tables, jt.Name)
+					}
+				}
+				continue
+			}
+		case "JOIN", "INTO", "UPDATE", "TABLE":
+		case "ON":
+			if i == 0 || strings.ToUpper(fields[i-1]) != "INDEX" {
+				continue
+			}
+		default:
+			continue
+		}
+		j := i + 1
+		for j < len(fields)-1 && isTableNameModifier(strings.ToUpper(fields[j])) {
+			j++
+		}
+		ident, _, _ := strings.Cut(fields[j], "(")
+		ident = strings.TrimSuffix(ident, ",")
+		if name, err := parseTableName(ident); err == nil && !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+		i = j
+	}
+	return tables
+}
+
+// isTableNameModifier reports whether word may stand between a keyword and
+// the table name, as in CREATE TABLE IF NOT EXISTS
+func isTableNameModifier(word string) bool {
+	switch word {
+	case "IF", "NOT", "EXISTS", "ONLY":
+		return true
+	}
+	return false
+}
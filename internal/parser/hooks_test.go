@@ -0,0 +1,82 @@
+// internal/parser/hooks_test.go
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+)
+
+func TestStatementHooks(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE notes (owner, body)")
+	engine.Execute("CREATE TABLE secrets (value)")
+	engine.Execute("INSERT INTO notes VALUES ('writer', 'hi')")
+	engine.Execute("INSERT INTO notes VALUES ('admin', 'private')")
+	engine.Execute("CREATE USER writer pass123 user")
+
+	var seen []Statement
+	engine.OnStatement(func(session *auth.Session, stmt *Statement) error {
+		seen = append(seen, *stmt)
+		return nil
+	})
+	// Only admins may touch secrets
+	engine.OnStatement(func(session *auth.Session, stmt *Statement) error {
+		for _, table := range stmt.Tables {
+			if table == "secrets" && session.Role != auth.RoleAdmin {
+				return errors.New("table secrets is restricted")
+			}
+		}
+		return nil
+	})
+	// Users read their own notes only
+	engine.OnStatement(func(session *auth.Session, stmt *Statement) error {
+		if stmt.Command == "SELECT * FROM" && session.Role != auth.RoleAdmin && reflect.DeepEqual(stmt.Tables, []string{"notes"}) {
+			stmt.Text = "SELECT * FROM notes WHERE owner = '" + session.Username + "'"
+		}
+		return nil
+	})
+
+	if got := engine.Execute("INSERT INTO secrets VALUES ('x')"); strings.HasPrefix(got, "Access denied") {
+		t.Errorf("admin insert into secrets: %s", got)
+	}
+	want := Statement{Text: "INSERT INTO secrets VALUES ('x')", Command: "INSERT INTO", Section: "Database Operations",
+		Write: true, Tables: []string{"secrets"}}
+	if len(seen) != 1 || !reflect.DeepEqual(seen[0], want) {
+		t.Errorf("statement seen by the hook: %+v", seen)
+	}
+
+	engine.Execute("LOGIN writer pass123")
+	if got := engine.Execute("SELECT * FROM secrets"); got != "Access denied: table secrets is restricted" {
+		t.Errorf("user select from secrets: %s", got)
+	}
+	if got := engine.Execute("SELECT * FROM notes;"); got != "owner | body\nwriter | hi\n" {
+		t.Errorf("rewritten select: %q", got)
+	}
+	if got := engine.Execute("HELP"); !strings.HasPrefix(got, "HaruDB Commands:") {
+		t.Errorf("HELP: %s", got)
+	}
+	if n := len(seen); n != 3 {
+		t.Errorf("hook saw %d statements, want 3 (public commands skip hooks)", n)
+	}
+}
+
+func TestStatementTables(t *testing.T) {
+	for stmt, want := range map[string][]string{
+		"SELECT * FROM a JOIN b ON a.id = b.id":       {"a", "b"},
+		"SELECT * FROM a x, b WHERE x.id = b.id":      {"a", "b"},
+		"CREATE TABLE IF NOT EXISTS Users (id, name)": {"users"},
+		"CREATE INDEX ON users (id)":                  {"users"},
+		"UPDATE users SET name = 'x' ROW 0":           {"users"},
+		`INSERT INTO "Order" VALUES ('1')`:            {"order"},
+		"SHOW TABLES":                                 nil,
+	} {
+		if got := statementTables(stmt); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v, want %v", stmt, got, want)
+		}
+	}
+}
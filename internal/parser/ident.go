@@ -0,0 +1,100 @@
+// internal/parser/ident.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// sqlFields splits s around whitespace like strings.Fields, but keeps quoted
+// identifiers ("first name") and string literals ('a b') in one field,
+// quotes included
+func sqlFields(s string) []string {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			// A doubled quote closes and reopens, so it stays in the field
+			if r == quote {
+				quote = 0
+			}
+			field.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			field.WriteRune(r)
+		case unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// splitIdentifiers splits s on commas outside double quotes, string
+// literals and parentheses, such as those of FOREIGN KEY (a, b)
+func splitIdentifiers(s string) []string {
+	var parts []string
+	var part strings.Builder
+	var quote rune
+	depth := 0
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			part.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			part.WriteRune(r)
+		case r == '(' || r == ')':
+			if r == '(' {
+				depth++
+			} else if depth > 0 {
+				depth--
+			}
+			part.WriteRune(r)
+		case r == ',' && depth == 0:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(parts, part.String())
+}
+
+// parseTableName parses a table name, quoted or not. Table names are
+// case-insensitive and stored in lowercase; each table is saved in a file
+// named after it, so names may not contain path separators.
+func parseTableName(ident string) (string, error) {
+	name, err := storage.UnquoteIdentifier(ident)
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.ContainsAny(name, "/\\\x00") {
+		return "", fmt.Errorf("invalid table name %s", ident)
+	}
+	return strings.ToLower(name), nil
+}
+
+// parseNewTableName parses the name of a table being created or renamed,
+// which may be a reserved word only when quoted
+func parseNewTableName(ident string) (string, error) {
+	if !strings.HasPrefix(ident, `"`) && storage.IsReservedWord(ident) {
+		return "", fmt.Errorf("table name %s is a reserved word; quote it as %s", ident, storage.QuoteIdentifier(ident))
+	}
+	return parseTableName(ident)
+}
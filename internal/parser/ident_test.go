@@ -0,0 +1,100 @@
+// internal/parser/ident_test.go
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSQLFields(t *testing.T) {
+	got := sqlFields(`SELECT * FROM "my table" WHERE "first name" = 'a  b'`)
+	want := []string{"SELECT", "*", "FROM", `"my table"`, "WHERE", `"first name"`, "=", "'a  b'"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestQuotedIdentifiers(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	for _, stmt := range []string{
+		`CREATE TABLE "Order" (id, "select", "First Name" COLLATE NOCASE)`,
+		`INSERT INTO "order" VALUES (1, 'a', 'Bob')`,
+		`INSERT INTO ORDER VALUES (2, 'b', 'alice')`,
+		`CREATE INDEX ON "ORDER" ("first name")`,
+		`UPDATE "Order" SET "SELECT" = 'c' ROW 0`,
+	} {
+		if _, err := engine.Exec(stmt); err != nil {
+			t.Fatalf("%s: %s", stmt, err)
+		}
+	}
+
+	want := "id | select | First Name\n2 | b | alice\n1 | c | Bob\n"
+	if got := engine.Execute(`SELECT * FROM "order" ORDER BY "first name"`); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := engine.Execute(`SELECT * FROM "Order" WHERE "first name" = 'BOB'`); got != "id | select | First Name\n1 | c | Bob\n" {
+		t.Errorf("quoted WHERE column: %s", got)
+	}
+	if got := engine.Execute(`SELECT COUNT(*) FROM order WHERE SELECT = 'b'`); got != "count\n1\n" {
+		t.Errorf("unquoted column should match case-insensitively: %s", got)
+	}
+
+	for _, stmt := range []string{
+		`CREATE TABLE t (a, "A")`,
+		`CREATE TABLE "a/b" (id)`,
+		`CREATE TABLE "unterminated (id)`,
+		`SELECT * FROM "order`,
+		`DROP TABLE ""`,
+	} {
+		if got, err := engine.Exec(stmt); err == nil {
+			t.Errorf("%s: expected error, got %s", stmt, got)
+		}
+	}
+	if _, err := engine.Exec(`DROP TABLE "ORDER"`); err != nil {
+		t.Errorf("drop failed: %s", err)
+	}
+}
+
+func TestReservedWords(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	for _, stmt := range []string{
+		`CREATE TABLE kw (select TEXT)`,
+		`CREATE TABLE kw (id, From)`,
+		`CREATE TABLE order (id)`,
+		`CREATE UNLOGGED TABLE where (id)`,
+	} {
+		if got, err := engine.Exec(stmt); err == nil || !strings.Contains(err.Error(), "reserved word") {
+			t.Errorf("%s: expected a reserved word error, got %q, %v", stmt, got, err)
+		}
+	}
+
+	// Quoted, they name tables and columns, and SHOW CREATE TABLE quotes
+	// them so its statement runs back in
+	if _, err := engine.Exec(`CREATE TABLE kw2 ("from" TEXT, "where" INT)`); err != nil {
+		t.Fatal(err)
+	}
+	want := `CREATE TABLE kw2 ("from" TEXT, "where" INT)`
+	got, _ := engine.Exec("SHOW CREATE TABLE kw2")
+	if got != "statement\n"+want+"\n" {
+		t.Fatalf("SHOW CREATE TABLE: %q", got)
+	}
+	engine.Execute("DROP TABLE kw2")
+	if _, err := engine.Exec(want); err != nil {
+		t.Errorf("statement from SHOW CREATE TABLE failed: %v", err)
+	}
+
+	if _, err := engine.Exec(`ALTER TABLE kw2 RENAME TO select`); err == nil {
+		t.Error("renamed a table to a reserved word")
+	}
+	if _, err := engine.Exec(`ALTER TABLE kw2 RENAME TO "select"`); err != nil {
+		t.Errorf("rename to a quoted reserved word: %v", err)
+	}
+	if got, _ := engine.Exec(`SHOW CREATE TABLE "select"`); !strings.HasPrefix(got, "statement\nCREATE TABLE \"select\" (") {
+		t.Errorf("SHOW CREATE TABLE: %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+// internal/parser/interval.go
+//
+// Date arithmetic in WHERE. The value a column is compared with may be a
+// point in time moved by intervals, as in created_at > NOW() - INTERVAL
+// '7 days' or due < '2025-01-31' + INTERVAL '1 month'. NOW() and
+// CURRENT_TIMESTAMP are the time the statement started, and CURRENT_DATE
+// the start of its day in UTC. The expression is worked out once, when the
+// clause is parsed, into a time written as created_at values are, and the
+// rows' values are compared with it as times.
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// interval is a span of calendar months and days and a fixed duration, kept
+// apart because months and days vary in length
+type interval struct {
+	months int
+	days   int
+	dur    time.Duration
+}
+
+// intervalUnit is a unit of an interval: months, days or a duration per unit
+type intervalUnit struct {
+	names  []string
+	months int
+	days   int
+	dur    time.Duration
+}
+
+var intervalUnits = []intervalUnit{
+	{names: []string{"year", "years"}, months: 12},
+	{names: []string{"month", "months", "mon", "mons"}, months: 1},
+	{names: []string{"week", "weeks"}, days: 7},
+	{names: []string{"day", "days"}, days: 1},
+	{names: []string{"hour", "hours"}, dur: time.Hour},
+	{names: []string{"minute", "minutes", "min", "mins"}, dur: time.Minute},
+	{names: []string{"second", "seconds", "sec", "secs"}, dur: time.Second},
+	{names: []string{"millisecond", "milliseconds", "ms"}, dur: time.Millisecond},
+	{names: []string{"microsecond", "microseconds", "us"}, dur: time.Microsecond},
+}
+
+// parseInterval parses the text of INTERVAL '...': one or more pairs of a
+// whole number and a unit, such as '7 days' or '1 hour 30 minutes'
+func parseInterval(text string) (interval, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return interval{}, fmt.Errorf("invalid interval '%s' (expected: n unit, as in '7 days')", text)
+	}
+	var iv interval
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return interval{}, fmt.Errorf("invalid interval '%s': %s is not a whole number", text, fields[i])
+		}
+		name := strings.ToLower(fields[i+1])
+		u := slices.IndexFunc(intervalUnits, func(u intervalUnit) bool { return slices.Contains(u.names, name) })
+		if u < 0 {
+			return interval{}, fmt.Errorf("unknown interval unit %s (use YEARS, MONTHS, WEEKS, DAYS, HOURS, MINUTES, SECONDS, MILLISECONDS or MICROSECONDS)", fields[i+1])
+		}
+		unit := intervalUnits[u]
+		iv.months += n * unit.months
+		iv.days += n * unit.days
+		iv.dur += time.Duration(n) * unit.dur
+	}
+	return iv, nil
+}
+
+// addTo returns t moved by the interval, backwards when sign is negative
+func (iv interval) addTo(t time.Time, sign int) time.Time {
+	return t.AddDate(0, sign*iv.months, sign*iv.days).Add(time.Duration(sign) * iv.dur)
+}
+
+// parseTimeExpression parses a point in time moved by intervals, starting at
+// token i: NOW(), CURRENT_TIMESTAMP, CURRENT_DATE or a time value, followed
+// by any number of + INTERVAL '...' or - INTERVAL '...'. A time value alone
+// is not an expression. It returns the time written as created_at values
+// are and the number of tokens used; ok is false when the tokens hold no
+// expression.
+func (p *whereParser) parseTimeExpression(i int, now time.Time) (value string, consumed int, ok bool, err error) {
+	start := i
+	var t time.Time
+	switch {
+	case !p.quoted[i] && strings.EqualFold(p.tokens[i], "NOW") && i+2 < len(p.tokens) && p.tokens[i+1] == "(" && p.tokens[i+2] == ")":
+		t, i = now, i+3
+	case !p.quoted[i] && strings.EqualFold(p.tokens[i], "CURRENT_TIMESTAMP"):
+		t, i = now, i+1
+	case !p.quoted[i] && strings.EqualFold(p.tokens[i], "CURRENT_DATE"):
+		t, i = now.UTC().Truncate(24*time.Hour), i+1
+	default:
+		if _, _, found := p.intervalAt(i + 1); !found {
+			return "", 0, false, nil
+		}
+		var parsed bool
+		if t, parsed = storage.ParseTime(p.tokens[i]); !parsed {
+			return "", 0, false, fmt.Errorf("INTERVAL arithmetic needs a time, got %s", p.tokens[i])
+		}
+		i++
+	}
+
+	for {
+		sign, used, found := p.intervalAt(i)
+		if !found {
+			break
+		}
+		if i+used >= len(p.tokens) || !p.quoted[i+used] {
+			return "", 0, false, fmt.Errorf("INTERVAL expects a quoted interval such as '7 days'")
+		}
+		iv, err := parseInterval(p.tokens[i+used])
+		if err != nil {
+			return "", 0, false, err
+		}
+		t = iv.addTo(t, sign)
+		i += used + 1
+	}
+	return storage.FormatTimestamp(t), i - start, true, nil
+}
+
+// intervalAt reports whether the tokens at i are + INTERVAL or - INTERVAL,
+// written apart or together, returning the sign and the tokens it spans
+func (p *whereParser) intervalAt(i int) (sign, used int, found bool) {
+	if i >= len(p.tokens) || p.quoted[i] {
+		return 0, 0, false
+	}
+	token := strings.ToUpper(p.tokens[i])
+	switch {
+	case token == "+INTERVAL":
+		return 1, 1, true
+	case token == "-INTERVAL":
+		return -1, 1, true
+	case (token == "+" || token == "-") && i+1 < len(p.tokens) && !p.quoted[i+1] && strings.EqualFold(p.tokens[i+1], "INTERVAL"):
+		if token == "-" {
+			return -1, 2, true
+		}
+		return 1, 2, true
+	}
+	return 0, 0, false
+}
+
+// compareTimes compares a value with the time of a condition as times when
+// the value is one, and as text under coll otherwise
+func compareTimes(value, compareValue string, coll *storage.Collation) int {
+	a, ok := storage.ParseTime(value)
+	b, ok2 := storage.ParseTime(compareValue)
+	if !ok || !ok2 {
+		return coll.Compare(value, compareValue)
+	}
+	return a.Compare(b)
+}
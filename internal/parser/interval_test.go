@@ -0,0 +1,66 @@
+// internal/parser/interval_test.go
+package parser
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestParseInterval(t *testing.T) {
+	base := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	for text, want := range map[string]time.Time{
+		"7 days":               time.Date(2025, 2, 7, 12, 0, 0, 0, time.UTC),
+		"1 hour 30 minutes":    time.Date(2025, 1, 31, 13, 30, 0, 0, time.UTC),
+		"2 WEEKS":              time.Date(2025, 2, 14, 12, 0, 0, 0, time.UTC),
+		"1 year":               time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC),
+		"-1 day":               time.Date(2025, 1, 30, 12, 0, 0, 0, time.UTC),
+		"90 seconds":           time.Date(2025, 1, 31, 12, 1, 30, 0, time.UTC),
+		"1 month":              time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC),
+		"1 day 500 ms":         time.Date(2025, 2, 1, 12, 0, 0, 5e8, time.UTC),
+		"3 microseconds 1 min": time.Date(2025, 1, 31, 12, 1, 0, 3000, time.UTC),
+	} {
+		iv, err := parseInterval(text)
+		if err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if got := iv.addTo(base, 1); !got.Equal(want) {
+			t.Errorf("%s: %v, want %v", text, got, want)
+		}
+	}
+	for _, bad := range []string{"", "7", "days 7", "7 fortnights", "1.5 days"} {
+		if _, err := parseInterval(bad); err == nil {
+			t.Errorf("%q should not parse", bad)
+		}
+	}
+}
+
+func TestIntervalArithmetic(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE events (id, created_at)")
+	now := time.Now()
+	for i, age := range []time.Duration{time.Hour, 3 * 24 * time.Hour, 10 * 24 * time.Hour} {
+		engine.Execute(fmt.Sprintf("INSERT INTO events VALUES (%d, '%s')", i+1, storage.FormatTimestamp(now.Add(-age))))
+	}
+	engine.Execute("INSERT INTO events VALUES (4, '2020-06-01')")
+	engine.Execute("INSERT INTO events VALUES (5, NULL)")
+
+	for input, want := range map[string]string{
+		"SELECT id FROM events WHERE created_at > NOW() - INTERVAL '7 days'":                      "id\n1\n2\n",
+		"SELECT id FROM events WHERE created_at > NOW()-INTERVAL '2 hours'":                       "id\n1\n",
+		"SELECT id FROM events WHERE created_at <= CURRENT_TIMESTAMP - INTERVAL '1 week'":         "id\n3\n4\n",
+		"SELECT id FROM events WHERE created_at < '2020-06-01' + INTERVAL '1 day' AND id > 3":     "id\n4\n",
+		"SELECT id FROM events WHERE created_at = '2020-05-31' + INTERVAL '24 hours'":             "id\n4\n",
+		"SELECT id FROM events WHERE created_at < NOW() - INTERVAL '1 year' - INTERVAL '1 month'": "id\n4\n",
+		"SELECT id FROM events WHERE created_at > NOW() - INTERVAL 'soon'":                        "WHERE clause error: invalid interval 'soon' (expected: n unit, as in '7 days')",
+		"SELECT id FROM events WHERE created_at > 'later' + INTERVAL '1 day'":                     "WHERE clause error: INTERVAL arithmetic needs a time, got later",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+}
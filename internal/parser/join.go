@@ -0,0 +1,94 @@
+// internal/parser/join.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// parseFromList parses the tables of a FROM clause, each table [[AS] alias],
+// separated by commas or CROSS JOIN. It returns them and the tokens that
+// follow the list.
+func parseFromList(tokens []string) ([]storage.JoinTable, []string, error) {
+	// The list ends at the first clause keyword
+	end := 0
+	for end < len(tokens) && !isClauseKeyword(tokens[end]) {
+		end++
+	}
+
+	var from []storage.JoinTable
+	for _, item := range splitIdentifiers(strings.Join(tokens[:end], " ")) {
+		fields := sqlFields(item)
+		start := 0
+		for i := 0; i <= len(fields); i++ {
+			if i < len(fields) && !strings.EqualFold(fields[i], "JOIN") {
+				continue
+			}
+			ref := fields[start:i]
+			if i < len(fields) {
+				// Only CROSS JOIN is supported: it ends the table before it
+				if i == 0 || !strings.EqualFold(fields[i-1], "CROSS") {
+					return nil, nil, fmt.Errorf("only CROSS JOIN is supported; join on a condition with WHERE")
+				}
+				ref = fields[start : i-1]
+			}
+			jt, err := parseFromTable(ref)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, other := range from {
+				if strings.EqualFold(other.Qualifier, jt.Qualifier) {
+					return nil, nil, fmt.Errorf("table %s appears twice in FROM; give one an alias", jt.Qualifier)
+				}
+			}
+			from = append(from, jt)
+			start = i + 1
+		}
+	}
+	if len(from) == 0 {
+		return nil, nil, fmt.Errorf("FROM expects a table")
+	}
+	return from, tokens[end:], nil
+}
+
+// isClauseKeyword reports whether tok starts a clause that follows FROM
+func isClauseKeyword(tok string) bool {
+	switch strings.ToUpper(tok) {
+	case "WHERE", "GROUP", "ORDER", "LIMIT":
+		return true
+	}
+	return false
+}
+
+// parseFromTable parses one table of a FROM list: table [[AS] alias]
+func parseFromTable(fields []string) (storage.JoinTable, error) {
+	if len(fields) == 0 {
+		return storage.JoinTable{}, fmt.Errorf("FROM expects a table before and after each comma or CROSS JOIN")
+	}
+	name, err := parseTableName(fields[0])
+	if err != nil {
+		return storage.JoinTable{}, err
+	}
+	jt := storage.JoinTable{Name: name, Qualifier: name}
+	rest := fields[1:]
+	if len(rest) > 0 && strings.EqualFold(rest[0], "AS") {
+		if len(rest) < 2 {
+			return jt, fmt.Errorf("AS expects a table alias")
+		}
+		rest = rest[1:]
+	}
+	switch len(rest) {
+	case 0:
+	case 1:
+		alias, err := storage.UnquoteIdentifier(rest[0])
+		if err != nil || alias == "" {
+			return jt, fmt.Errorf("invalid table alias %s", rest[0])
+		}
+		jt.Qualifier = alias
+	default:
+		return jt, fmt.Errorf("unexpected %s", strings.Join(rest[1:], " "))
+	}
+	return jt, nil
+}
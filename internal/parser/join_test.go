@@ -0,0 +1,96 @@
+// internal/parser/join_test.go
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestParseFromList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []storage.JoinTable
+		rest  string
+		err   bool
+	}{
+		{input: "users", want: []storage.JoinTable{{Name: "users", Qualifier: "users"}}},
+		{input: "Users u WHERE u.id = 1", want: []storage.JoinTable{{Name: "users", Qualifier: "u"}}, rest: "WHERE u.id = 1"},
+		{input: "a, b AS x LIMIT 2", want: []storage.JoinTable{{Name: "a", Qualifier: "a"}, {Name: "b", Qualifier: "x"}}, rest: "LIMIT 2"},
+		{input: "a,b", want: []storage.JoinTable{{Name: "a", Qualifier: "a"}, {Name: "b", Qualifier: "b"}}},
+		{input: "a x CROSS JOIN b cross join c", want: []storage.JoinTable{{Name: "a", Qualifier: "x"}, {Name: "b", Qualifier: "b"}, {Name: "c", Qualifier: "c"}}},
+		{input: "a, a", err: true},
+		{input: "a, a other", want: []storage.JoinTable{{Name: "a", Qualifier: "a"}, {Name: "a", Qualifier: "other"}}},
+		{input: "a JOIN b", err: true},
+		{input: "a CROSS JOIN", err: true},
+		{input: "a,", err: true},
+		{input: "a AS", err: true},
+		{input: "a x y", err: true},
+	}
+	for _, tt := range tests {
+		from, rest, err := parseFromList(strings.Fields(tt.input))
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", tt.input, from)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(from, tt.want) || strings.Join(rest, " ") != tt.rest {
+			t.Errorf("%s: %v %q, want %v %q", tt.input, from, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestSelectJoin(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	for _, stmt := range []string{
+		"CREATE TABLE customers (id INT, name TEXT)",
+		"CREATE TABLE orders (id INT, customer_id INT, total INT)",
+		"INSERT INTO customers VALUES (1, 'ann')",
+		"INSERT INTO customers VALUES (2, 'bob')",
+		"INSERT INTO orders VALUES (10, 1, 5)",
+		"INSERT INTO orders VALUES (11, 2, 7)",
+		"INSERT INTO orders VALUES (12, 1, 9)",
+		"INSERT INTO orders VALUES (13, NULL, 1)",
+	} {
+		engine.Execute(stmt)
+	}
+
+	steps := []struct{ input, want string }{
+		{"SELECT c.name, o.id FROM customers c, orders o WHERE o.customer_id = c.id ORDER BY o.id",
+			"name | id\nann | 10\nbob | 11\nann | 12\n"},
+		{"SELECT name, total FROM customers CROSS JOIN orders WHERE customer_id = customers.id AND total > 6",
+			"name | total\nann | 9\nbob | 7\n"},
+		{"SELECT * FROM customers, orders WHERE customers.id = 2 AND orders.id = 11",
+			"customers.id | name | orders.id | customer_id | total\n2 | bob | 11 | 2 | 7\n"},
+		{"SELECT c.* FROM customers c, orders o WHERE o.total < 6",
+			"id | name\n1 | ann\n1 | ann\n2 | bob\n2 | bob\n"},
+		{"SELECT DISTINCT c.* FROM customers c, orders o WHERE o.total < 6",
+			"id | name\n1 | ann\n2 | bob\n"},
+		{"SELECT a.name AS first, b.name AS second FROM customers a, customers b WHERE a.id < b.id",
+			"first | second\nann | bob\n"},
+		{"SELECT c.name AS who FROM customers c, orders o WHERE o.customer_id = c.id ORDER BY who DESC, o.total DESC LIMIT 2",
+			"who\nbob\nann\n"},
+		{"SELECT o.id FROM customers c, orders o LIMIT 3", "id\n10\n11\n12\n"},
+		{"SELECT id FROM customers, orders", "Error: column id is ambiguous; qualify it with its table"},
+		{"SELECT x.id FROM customers, orders", "Syntax error: unknown table x in x.id"},
+		{"SELECT name FROM customers, orders WHERE nope = 1", "Column nope not found"},
+		{"SELECT name FROM customers, missing", "Table missing not found"},
+		{"SELECT * FROM customers, customers", "Syntax error: table customers appears twice in FROM; give one an alias"},
+		{"SELECT * FROM customers JOIN orders", "Syntax error: only CROSS JOIN is supported; join on a condition with WHERE"},
+		{"SELECT COUNT(*) FROM customers, orders", "Syntax error: aggregates and GROUP BY read from a single table"},
+		{"EXPLAIN SELECT * FROM customers, orders", "Syntax error: expected a single table after FROM"},
+	}
+	for _, step := range steps {
+		if got := engine.Execute(step.input); got != step.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", step.input, got, step.want)
+		}
+	}
+}
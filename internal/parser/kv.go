@@ -0,0 +1,96 @@
+// internal/parser/kv.go
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// parseKVArgs parses the n arguments of KV SET, KV GET or KV DEL, each a
+// bare word or a quoted string
+func parseKVArgs(input string, n int, usage string) ([]string, string) {
+	fields := sqlFields(input)
+	if len(fields) != n+2 {
+		return nil, usage
+	}
+	args := make([]string, n)
+	for i, raw := range fields[2:] {
+		value, err := parseLiteral(raw)
+		if err != nil {
+			return nil, fmt.Sprintf("Syntax error: %v", err)
+		}
+		if value == storage.NullValue {
+			return nil, "Syntax error: KV keys and values cannot be NULL"
+		}
+		args[i] = value
+	}
+	return args, ""
+}
+
+// handleKVSet handles KV SET key value
+func (e *Engine) handleKVSet(input string) string {
+	args, msg := parseKVArgs(input, 2, "Syntax error: KV SET key value")
+	if msg != "" {
+		return msg
+	}
+	if err := e.DB.KVSet(args[0], args[1]); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "OK"
+}
+
+// handleKVGet handles KV GET key
+func (e *Engine) handleKVGet(input string) (*storage.ResultSet, string) {
+	args, msg := parseKVArgs(input, 1, "Syntax error: KV GET key")
+	if msg != "" {
+		return nil, msg
+	}
+	value, found, err := e.DB.KVGet(args[0])
+	if err != nil {
+		return nil, fmt.Sprintf("Error: %v", err)
+	}
+	rs := &storage.ResultSet{Columns: []string{"value"}}
+	if found {
+		rs.Rows = [][]string{{value}}
+	}
+	return rs, ""
+}
+
+// handleKVDel handles KV DEL key
+func (e *Engine) handleKVDel(input string) string {
+	args, msg := parseKVArgs(input, 1, "Syntax error: KV DEL key")
+	if msg != "" {
+		return msg
+	}
+	deleted, err := e.DB.KVDelete(args[0])
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if !deleted {
+		return "0 keys deleted"
+	}
+	return "1 key deleted"
+}
+
+// writesKVTable reports whether a statement other than a KV command would
+// change the KV commands' table, which only they may write, or rename
+// another table to its name
+func writesKVTable(input, upper string) bool {
+	if strings.HasPrefix(upper, "KV ") || !isDataWrite(upper) {
+		return false
+	}
+	if slices.Contains(statementTables(input), storage.KVTable) {
+		return true
+	}
+	fields := sqlFields(input)
+	for i := 0; i < len(fields)-1; i++ {
+		if strings.EqualFold(fields[i], "RENAME") && strings.EqualFold(fields[i+1], "TO") && i+2 < len(fields) {
+			name, err := parseTableName(fields[i+2])
+			return err == nil && name == storage.KVTable
+		}
+	}
+	return false
+}
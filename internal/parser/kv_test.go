@@ -0,0 +1,52 @@
+// internal/parser/kv_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKVCommands(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"KV GET session:1", "value\n(no rows)\n"},
+		{"KV SET session:1 alice", "OK"},
+		{"KV SET greeting 'hello, world'", "OK"},
+		{"KV GET session:1", "value\nalice\n"},
+		{"kv get 'greeting'", "value\nhello, world\n"},
+		{"KV SET session:1 bob", "OK"},
+		{"KV GET session:1", "value\nbob\n"},
+		{"KV DEL session:1", "1 key deleted"},
+		{"KV DEL session:1", "0 keys deleted"},
+		{"KV SET k", "Syntax error: KV SET key value"},
+		{"KV SET k NULL", "Syntax error: KV keys and values cannot be NULL"},
+		{"KV GET a b", "Syntax error: KV GET key"},
+		{"SELECT * FROM _kv", "key | value\ngreeting | hello, world\n"},
+		{"INSERT INTO _kv VALUES ('greeting', 'again')", "Error: table _kv is written only by the KV commands"},
+		{"DELETE FROM _kv ROW 0", "Error: table _kv is written only by the KV commands"},
+		{"DROP TABLE _kv", "Error: table _kv is written only by the KV commands"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+	engine.Execute("CREATE TABLE cache (k, v)")
+	if got := engine.Execute("ALTER TABLE cache RENAME TO _kv"); !strings.Contains(got, "written only by the KV commands") {
+		t.Errorf("renaming a table to _kv: %q", got)
+	}
+
+	engine.Execute("CREATE USER reader secret123 READONLY")
+	engine.Execute("LOGIN reader secret123")
+	if got := engine.Execute("KV GET greeting"); got != "value\nhello, world\n" {
+		t.Errorf("read-only KV GET: %q", got)
+	}
+	if got := engine.Execute("KV SET greeting bye"); got != ErrInsufficientPermissions {
+		t.Errorf("read-only KV SET: %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+// internal/parser/literal.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// parseValueList parses a parenthesized, comma-separated list of literals
+// such as (1, 'Doe, John', NULL)
+func parseValueList(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		return nil, fmt.Errorf("expected ( before values")
+	}
+
+	var values []string
+	var item strings.Builder
+	inQuote := false
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			// '' inside a string toggles out and back in, keeping both quotes
+			inQuote = !inQuote
+			item.WriteByte(c)
+		case inQuote:
+			item.WriteByte(c)
+		case c == ',' || c == ')':
+			value := storage.DefaultValue
+			if !strings.EqualFold(strings.TrimSpace(item.String()), "DEFAULT") {
+				var err error
+				if value, err = parseLiteral(item.String()); err != nil {
+					return nil, err
+				}
+			}
+			values = append(values, value)
+			item.Reset()
+			if c == ')' {
+				if rest := strings.TrimSpace(s[i+1:]); rest != "" {
+					return nil, fmt.Errorf("unexpected %q after values", rest)
+				}
+				return values, nil
+			}
+		default:
+			item.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	return nil, fmt.Errorf("expected ) after values")
+}
+
+// parseLiteral parses one literal: a quoted string in which two quotes
+// stand for one, NULL, TRUE or FALSE, or a bare token such as a number. Bare words are
+// taken as text for compatibility with earlier versions.
+func parseLiteral(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, "'"):
+		return parseQuoted(raw)
+	case strings.EqualFold(raw, "NULL"):
+		return storage.NullValue, nil
+	case strings.EqualFold(raw, "TRUE"), strings.EqualFold(raw, "FALSE"):
+		return strings.ToLower(raw), nil
+	}
+	for _, r := range raw {
+		if r == '\'' || unicode.IsSpace(r) {
+			return "", fmt.Errorf("invalid value %s: quote text values", raw)
+		}
+	}
+	return raw, nil
+}
+
+// parseQuoted returns the text of a single-quoted string literal
+func parseQuoted(raw string) (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(raw); i++ {
+		if raw[i] != '\'' {
+			b.WriteByte(raw[i])
+			continue
+		}
+		if i+1 < len(raw) && raw[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		if i != len(raw)-1 {
+			return "", fmt.Errorf("unexpected %q after string %s", raw[i+1:], raw[:i+1])
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unterminated string %s", raw)
+}
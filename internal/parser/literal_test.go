@@ -0,0 +1,100 @@
+// internal/parser/literal_test.go
+package parser
+
+import (
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestParseValueList(t *testing.T) {
+	got, err := parseValueList("(1, 'Doe, John', 'O''Brien', null, -2.5, '', TRUE, False, 'TRUE')")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"1", "Doe, John", "O'Brien", storage.NullValue, "-2.5", "", "true", "false", "TRUE"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{
+		"1, 2",
+		"(1, 'open",
+		"(1, 2",
+		"(1, , 2)",
+		"(1, 'a'b)",
+		"(1, two words)",
+		"(1) extra",
+	} {
+		if _, err := parseValueList(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestInsertQuotedValues(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE people (id, name, note)")
+
+	for _, stmt := range []string{
+		"INSERT INTO people VALUES (1, 'Doe, John', 'O''Brien')",
+		"insert into people values (2, 'x', NULL);",
+	} {
+		if _, err := engine.Exec(stmt); err != nil {
+			t.Fatalf("%s: %s", stmt, err)
+		}
+	}
+	want := "id | name | note\n1 | Doe, John | O'Brien\n2 | x | NULL\n"
+	if got := engine.Execute("SELECT * FROM people"); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got, err := engine.Exec("INSERT INTO people VALUES (3, 'unterminated, 'x')"); err == nil {
+		t.Errorf("expected syntax error, got %s", got)
+	}
+}
+
+func TestUpdateQuotedValues(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE people (id INT, name, note)")
+	engine.Execute("INSERT INTO people VALUES (1, 'Ann', 'x')")
+	engine.Execute("INSERT INTO people VALUES (2, 'Bob', 'y')")
+
+	for _, stmt := range []string{
+		"UPDATE people SET name = 'a, b', note = 'O''Neil' ROW 0",
+		"UPDATE people SET note = 'Grower = ROW 1' WHERE id = 2",
+		`UPDATE people SET "name" = 'it''s, fine', note = NULL ROWID 2`,
+	} {
+		if _, err := engine.Exec(stmt); err != nil {
+			t.Fatalf("%s: %s", stmt, err)
+		}
+	}
+	want := "id | name | note\n1 | a, b | O'Neil\n2 | it's, fine | NULL\n"
+	if got := engine.Execute("SELECT * FROM people"); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// UPDATE stores a string exactly as INSERT does
+	engine.Execute("INSERT INTO people VALUES (3, 'O''Neil', 'z')")
+	engine.Execute("UPDATE people SET name = 'O''Neil' ROW 0")
+	if got := engine.Execute("SELECT name FROM people ORDER BY id"); got != "name\nO'Neil\nit's, fine\nO'Neil\n" {
+		t.Errorf("got %q", got)
+	}
+
+	for _, stmt := range []string{
+		"UPDATE people SET name = 'open ROW 0",
+		"UPDATE people SET name = two words ROW 0",
+		"UPDATE people SET name ROW 0",
+	} {
+		if got, err := engine.Exec(stmt); err == nil {
+			t.Errorf("%s: expected error, got %s", stmt, got)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// internal/parser/locks.go
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleShowLocks handles SHOW LOCKS: the table locks held and waited for,
+// to debug contention
+func (e *Engine) handleShowLocks(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 2 {
+		return nil, "Syntax error: SHOW LOCKS"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return nil, err
+	}
+
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	}
+	rs := &storage.ResultSet{Columns: []string{"table", "mode", "state", "user", "waited_ms", "held_ms"}}
+	for _, l := range e.DB.Locks() {
+		state, held := "waiting", "-"
+		if l.Granted {
+			state, held = "held", ms(l.Held)
+		}
+		user := l.Session
+		if user == "" {
+			user = "-"
+		}
+		rs.Rows = append(rs.Rows, []string{l.Table, l.Mode(), state, user, ms(l.Waited), held})
+	}
+	return rs, ""
+}
@@ -0,0 +1,26 @@
+// internal/parser/locks_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShowLocks(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE orders (id)")
+
+	if got := engine.Execute("SHOW LOCKS"); got != "table | mode | state | user | waited_ms | held_ms\n(no rows)\n" {
+		t.Errorf("unexpected locks %q", got)
+	}
+	if got := engine.Execute("SHOW LOCKS orders"); !strings.HasPrefix(got, "Syntax error") {
+		t.Errorf("expected a syntax error, got %q", got)
+	}
+
+	engine.Execute("CREATE USER viewer secret123 USER")
+	engine.Execute("LOGIN viewer secret123")
+	if got := engine.Execute("SHOW LOCKS"); !strings.Contains(got, "Insufficient permissions") {
+		t.Errorf("expected SHOW LOCKS to be admin only, got %q", got)
+	}
+}
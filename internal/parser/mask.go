@@ -0,0 +1,95 @@
+// internal/parser/mask.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleCreateMask handles CREATE MASK ON t (col) FOR ROLE role USING 'method'
+func (e *Engine) handleCreateMask(input string) string {
+	const usage = "Syntax error: CREATE MASK ON table (column) FOR ROLE role USING 'full'|'partial'|'hash'"
+	tableName, column, rest, ok := parseMaskTarget(input)
+	if !ok || len(rest) != 5 || !strings.EqualFold(rest[3], "USING") || !strings.HasPrefix(rest[4], "'") {
+		return usage
+	}
+	method, err := parseLiteral(rest[4])
+	if err != nil || method == "" {
+		return usage
+	}
+	if msg := e.requireAdmin(); msg != "" {
+		return msg
+	}
+	return e.DB.SetMask(tableName, column, rest[2], method)
+}
+
+// handleDropMask handles DROP MASK ON t (col) FOR ROLE role
+func (e *Engine) handleDropMask(input string) string {
+	tableName, column, rest, ok := parseMaskTarget(input)
+	if !ok || len(rest) != 3 {
+		return "Syntax error: DROP MASK ON table (column) FOR ROLE role"
+	}
+	if msg := e.requireAdmin(); msg != "" {
+		return msg
+	}
+	return e.DB.SetMask(tableName, column, rest[2], "")
+}
+
+// parseMaskTarget parses the ON table (column) FOR ROLE role part of CREATE
+// and DROP MASK, returning the words from FOR on
+func parseMaskTarget(input string) (tableName, column string, rest []string, ok bool) {
+	open := strings.Index(input, "(")
+	closing := strings.Index(input, ")")
+	if open < 0 || closing < open {
+		return "", "", nil, false
+	}
+	head := sqlFields(input[:open])
+	if len(head) != 4 || !strings.EqualFold(head[2], "ON") {
+		return "", "", nil, false
+	}
+	tableName, err := parseTableName(head[3])
+	if err != nil {
+		return "", "", nil, false
+	}
+	column, err = storage.UnquoteIdentifier(strings.TrimSpace(input[open+1 : closing]))
+	if err != nil || column == "" {
+		return "", "", nil, false
+	}
+	rest = sqlFields(input[closing+1:])
+	if len(rest) < 3 || !strings.EqualFold(rest[0], "FOR") || !strings.EqualFold(rest[1], "ROLE") {
+		return "", "", nil, false
+	}
+	return tableName, column, rest, true
+}
+
+// maskRole returns the role whose masks apply to the session, "" for
+// admins, who see real values. A session not logged in has no role, so the
+// error to refuse its statement with is returned instead.
+func (e *Engine) maskRole() (string, string) {
+	if e.CurrentSession == nil {
+		return "", ErrNotAuthenticated
+	}
+	switch e.CurrentSession.Role {
+	case auth.RoleUser:
+		return "user", ""
+	case auth.RoleReadOnly:
+		return "readonly", ""
+	}
+	return "", ""
+}
+
+// maskedTableError returns the error for exporting a table whose columns
+// are masked for the session, or ""
+func (e *Engine) maskedTableError(tableName string) string {
+	role, msg := e.maskRole()
+	if msg != "" {
+		return msg
+	}
+	if role != "" && e.DB.HasMasks(tableName, role) {
+		return fmt.Sprintf("Access denied: table %s has masked columns for role %s", tableName, role)
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+// internal/parser/mask_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColumnMasks(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, email, card)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'jane@example.com', '4111111111111111')")
+	engine.Execute("INSERT INTO users VALUES ('2', NULL, '42')")
+	engine.Execute("CREATE USER viewer pass123 readonly")
+	engine.Execute("CREATE USER writer pass123 user")
+
+	for stmt, want := range map[string]string{
+		"CREATE MASK ON users(email) FOR ROLE readonly USING 'partial'": "Mask partial created on users.email for role readonly",
+		"CREATE MASK ON users (card) FOR ROLE readonly USING 'full'":    "Mask full created on users.card for role readonly",
+		"CREATE MASK ON users (card) FOR ROLE user USING 'partial'":     "Mask partial created on users.card for role user",
+		"CREATE MASK ON users (card) FOR ROLE admin USING 'full'":       "Error: invalid role admin for a mask; use user or readonly",
+		"CREATE MASK ON users (card) FOR ROLE user USING 'blur'":        "Error: unknown mask method 'blur'; use 'full', 'partial' or 'hash'",
+		"CREATE MASK ON users (phone) FOR ROLE user USING 'full'":       "Column phone not found",
+		"CREATE MASK ON users (card) FOR ROLE user":                     "Syntax error: CREATE MASK ON table (column) FOR ROLE role USING 'full'|'partial'|'hash'",
+		"DROP MASK ON users (id) FOR ROLE user":                         "Error: no mask on users.id for role user",
+	} {
+		if got := engine.Execute(stmt); got != want {
+			t.Errorf("%s: %s", stmt, got)
+		}
+	}
+
+	if got := engine.Execute("SELECT * FROM users"); !strings.Contains(got, "jane@example.com | 4111111111111111") {
+		t.Errorf("admin sees masked values: %q", got)
+	}
+
+	check := func(e *Engine, user string) {
+		t.Helper()
+		e.Execute("LOGIN " + user + " pass123")
+		want := map[string]string{
+			"viewer": "id | email | card\n1 | j***@example.com | ****\n2 | NULL | ****\n",
+			"writer": "id | email | card\n1 | jane@example.com | ************1111\n2 | NULL | ****\n",
+		}[user]
+		for _, stmt := range []string{"SELECT * FROM users", "SELECT * FROM users ORDER BY id", "SELECT * FROM users WHERE id != '3'"} {
+			if got := e.Execute(stmt); got != want {
+				t.Errorf("%s as %s: %q", stmt, user, got)
+			}
+		}
+		// Conditions see the real values
+		if got := e.Execute("SELECT COUNT(*) FROM users WHERE email = 'jane@example.com'"); !strings.HasSuffix(got, "1\n") {
+			t.Errorf("count as %s: %q", user, got)
+		}
+		maxCard := map[string]string{"viewer": "****", "writer": "************1111"}[user]
+		if got := e.Execute("SELECT MAX(card) FROM users"); got != "max(card)\n"+maxCard+"\n" {
+			t.Errorf("MAX(card) as %s: %q", user, got)
+		}
+		e.Execute("DECLARE c CURSOR FOR SELECT * FROM users")
+		lines := strings.SplitAfter(want, "\n")
+		if got := e.Execute("FETCH 1 FROM c"); got != lines[0]+lines[1] {
+			t.Errorf("cursor as %s: %q", user, got)
+		}
+		e.Execute("CLOSE c")
+	}
+	check(engine, "viewer")
+	check(engine, "writer")
+	if got := engine.Execute("SELECT ROWID, * FROM users LIMIT 1"); got != "rowid | id | email | card\n1 | 1 | jane@example.com | ************1111\n" {
+		t.Errorf("SELECT ROWID as writer: %q", got)
+	}
+	if got := engine.Execute("EXPORT TABLE users TO '" + dir + "/users.parquet'"); got != "Access denied: table users has masked columns for role user" {
+		t.Errorf("export as writer: %s", got)
+	}
+	if got := engine.Execute("CREATE MASK ON users (id) FOR ROLE user USING 'hash'"); got != ErrInsufficientPermissions {
+		t.Errorf("CREATE MASK as writer: %s", got)
+	}
+
+	// Masks survive a restart and can be dropped
+	reopened := NewEngine(dir)
+	reopened.Execute("LOGIN admin admin123")
+	if got := reopened.Execute("SHOW CREATE TABLE users"); !strings.Contains(got, "CREATE MASK ON users (email) FOR ROLE readonly USING 'partial'\n") {
+		t.Errorf("SHOW CREATE TABLE: %q", got)
+	}
+	if got := reopened.Execute("DROP MASK ON users (card) FOR ROLE readonly"); got != "Mask dropped from users.card for role readonly" {
+		t.Errorf("DROP MASK: %s", got)
+	}
+	reopened.Execute("LOGIN viewer pass123")
+	if got := reopened.Execute("SELECT * FROM users LIMIT 1"); got != "id | email | card\n1 | j***@example.com | 4111111111111111\n" {
+		t.Errorf("after restart and DROP MASK: %q", got)
+	}
+}
+
+func TestMasksFollowTheConnectionsSession(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, card)")
+	engine.Execute("INSERT INTO users VALUES ('1', '4111111111111111')")
+	engine.Execute("CREATE USER writer pass123 user")
+	engine.Execute("CREATE MASK ON users (card) FOR ROLE user USING 'full'")
+
+	admin, writer, anonymous := engine.Connect("10.0.0.1:5000"), engine.Connect("10.0.0.2:5000"), engine.Connect("10.0.0.3:5000")
+	runOn(engine, admin, "LOGIN admin admin123")
+	runOn(engine, writer, "LOGIN writer pass123")
+
+	// Each connection sees the values of its own role, whoever logged in last
+	if got := runOn(engine, writer, "SELECT * FROM users"); got != "id | card\n1 | ****\n" {
+		t.Errorf("writer: %q", got)
+	}
+	if got := runOn(engine, admin, "SELECT * FROM users"); got != "id | card\n1 | 4111111111111111\n" {
+		t.Errorf("admin: %q", got)
+	}
+	if got := runOn(engine, writer, "SELECT MAX(card) FROM users"); got != "max(card)\n****\n" {
+		t.Errorf("writer aggregate: %q", got)
+	}
+
+	// A connection that has not logged in is refused, not given another's
+	for _, stmt := range []string{"SELECT * FROM users", "SELECT MAX(card) FROM users", "DECLARE c CURSOR FOR SELECT * FROM users"} {
+		if got := runOn(engine, anonymous, stmt); got != ErrNotAuthenticated {
+			t.Errorf("%s before login: %q", stmt, got)
+		}
+	}
+	if _, msg := anonymous.Engine().maskRole(); msg != ErrNotAuthenticated {
+		t.Errorf("mask role before login: %q", msg)
+	}
+}
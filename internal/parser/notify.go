@@ -0,0 +1,126 @@
+// internal/parser/notify.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/notify"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleNotify handles NOTIFY channel [, 'payload']. Inside a transaction
+// the notification is sent when the transaction commits.
+func (e *Engine) handleNotify(input string) string {
+	const usage = "Syntax error: NOTIFY channel [, 'payload']"
+	rest := strings.TrimSpace(input[len("NOTIFY"):])
+	name, payloadPart, hasPayload := strings.Cut(rest, ",")
+	channel, err := parseChannel(name)
+	if err != nil {
+		return usage
+	}
+	payload := ""
+	if hasPayload {
+		payloadPart = strings.TrimSpace(payloadPart)
+		if !strings.HasPrefix(payloadPart, "'") {
+			return usage
+		}
+		if payload, err = parseQuoted(payloadPart); err != nil {
+			return fmt.Sprintf("Syntax error: %v", err)
+		}
+	}
+
+	if e.DB.GetCurrentTransaction() != nil {
+		e.notifyMu.Lock()
+		e.pendingNotifications = append(e.pendingNotifications, notify.Notification{Channel: channel, Payload: payload})
+		e.notifyMu.Unlock()
+		return "NOTIFY queued in transaction"
+	}
+	n := e.Notifications.Notify(channel, payload)
+	return fmt.Sprintf("NOTIFY %s delivered to %d listener(s)", channel, n)
+}
+
+// sendPendingNotifications sends the notifications of a committed
+// transaction; a rolled back transaction discards them instead
+func (e *Engine) sendPendingNotifications(committed bool) {
+	e.notifyMu.Lock()
+	pending := e.pendingNotifications
+	e.pendingNotifications = nil
+	e.notifySavepoints = nil
+	e.notifyMu.Unlock()
+
+	if !committed {
+		return
+	}
+	for _, n := range pending {
+		e.Notifications.Notify(n.Channel, n.Payload)
+	}
+}
+
+// markNotifySavepoint remembers which notifications were queued before
+// savepoint name
+func (e *Engine) markNotifySavepoint(name string) {
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	if e.notifySavepoints == nil {
+		e.notifySavepoints = make(map[string]int)
+	}
+	e.notifySavepoints[name] = len(e.pendingNotifications)
+}
+
+// rollbackNotifications discards the notifications queued since savepoint
+// name
+func (e *Engine) rollbackNotifications(name string) {
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	if n, ok := e.notifySavepoints[name]; ok && n <= len(e.pendingNotifications) {
+		e.pendingNotifications = e.pendingNotifications[:n]
+	}
+}
+
+// HandleListen runs LISTEN channel, UNLISTEN channel and UNLISTEN * for the
+// connection that owns l. It reports false for any other statement, which
+// the caller passes to Execute instead.
+func (e *Engine) HandleListen(input string, l *notify.Listener) (string, bool) {
+	input = strings.TrimSuffix(strings.TrimSpace(input), ";")
+	cmd := lookupCommand(strings.ToUpper(input))
+	if cmd == nil || (cmd.prefix != "LISTEN" && cmd.prefix != "UNLISTEN") {
+		return "", false
+	}
+	if err := e.requireAuth(); err != "" {
+		return err, true
+	}
+
+	fields := sqlFields(input)
+	if len(fields) != 2 {
+		return fmt.Sprintf("Syntax error: %s channel", cmd.prefix), true
+	}
+	if cmd.prefix == "UNLISTEN" && fields[1] == "*" {
+		l.UnlistenAll()
+		return "Stopped listening on all channels", true
+	}
+	channel, err := parseChannel(fields[1])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err), true
+	}
+	if cmd.prefix == "LISTEN" {
+		l.Listen(channel)
+		return fmt.Sprintf("Listening on %s", channel), true
+	}
+	l.Unlisten(channel)
+	return fmt.Sprintf("Stopped listening on %s", channel), true
+}
+
+// parseChannel parses a channel name. Names are case-insensitive and may
+// not contain whitespace, since notifications carry them unquoted.
+func parseChannel(ident string) (string, error) {
+	ident = strings.TrimSpace(ident)
+	name, err := storage.UnquoteIdentifier(ident)
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.ContainsAny(name, " \t\"'") {
+		return "", fmt.Errorf("invalid channel name %s", ident)
+	}
+	return strings.ToLower(name), nil
+}
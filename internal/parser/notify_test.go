@@ -0,0 +1,90 @@
+// internal/parser/notify_test.go
+package parser
+
+import (
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/notify"
+)
+
+func TestListenNotify(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	l := engine.Notifications.NewListener()
+	defer l.Close()
+
+	if got, ok := engine.HandleListen("LISTEN cache", l); !ok || got != ErrNotAuthenticated {
+		t.Fatalf("LISTEN before login: %q %v", got, ok)
+	}
+	engine.Execute("LOGIN admin admin123")
+
+	if _, ok := engine.HandleListen("LIST USERS", l); ok {
+		t.Fatal("LIST USERS handled as LISTEN")
+	}
+	if got, _ := engine.HandleListen("LISTEN Cache;", l); got != "Listening on cache" {
+		t.Fatalf("LISTEN: %s", got)
+	}
+	if got, _ := engine.HandleListen("LISTEN", l); got != "Syntax error: LISTEN channel" {
+		t.Errorf("LISTEN without channel: %s", got)
+	}
+
+	if got := engine.Execute("NOTIFY cache, 'users changed'"); got != "NOTIFY cache delivered to 1 listener(s)" {
+		t.Fatalf("NOTIFY: %s", got)
+	}
+	if got := <-l.C(); got != (notify.Notification{Channel: "cache", Payload: "users changed"}) {
+		t.Errorf("received %+v", got)
+	}
+	if got := engine.Execute("NOTIFY other"); got != "NOTIFY other delivered to 0 listener(s)" {
+		t.Errorf("NOTIFY without listeners: %s", got)
+	}
+	if got := engine.Execute("NOTIFY cache, unquoted"); got != "Syntax error: NOTIFY channel [, 'payload']" {
+		t.Errorf("unquoted payload: %s", got)
+	}
+
+	// Inside a transaction notifications wait for COMMIT and are dropped
+	// by ROLLBACK
+	engine.Execute("BEGIN")
+	if got := engine.Execute("NOTIFY cache, 'rolled back'"); got != "NOTIFY queued in transaction" {
+		t.Fatalf("NOTIFY in transaction: %s", got)
+	}
+	engine.Execute("ROLLBACK")
+	engine.Execute("BEGIN")
+	engine.Execute("NOTIFY cache, 'committed'")
+	if len(l.C()) != 0 {
+		t.Fatal("notification delivered before COMMIT")
+	}
+	engine.Execute("COMMIT")
+	if got := <-l.C(); got.Payload != "committed" {
+		t.Errorf("after COMMIT received %+v", got)
+	}
+
+	if got, _ := engine.HandleListen("UNLISTEN *", l); got != "Stopped listening on all channels" {
+		t.Errorf("UNLISTEN *: %s", got)
+	}
+	if got := engine.Execute("NOTIFY cache"); got != "NOTIFY cache delivered to 0 listener(s)" {
+		t.Errorf("NOTIFY after UNLISTEN: %s", got)
+	}
+}
+
+func TestRollbackToSavepointDropsNotifications(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	l := engine.Notifications.NewListener()
+	defer l.Close()
+	l.Listen("jobs")
+
+	engine.Execute("BEGIN")
+	engine.Execute("NOTIFY jobs, 'kept'")
+	engine.Execute("SAVEPOINT sp")
+	engine.Execute("NOTIFY jobs, 'dropped'")
+	if got := engine.Execute("ROLLBACK TO SAVEPOINT sp"); got != "Rolled back to savepoint sp" {
+		t.Fatalf("ROLLBACK TO SAVEPOINT: %s", got)
+	}
+	engine.Execute("COMMIT")
+
+	if got := <-l.C(); got.Payload != "kept" {
+		t.Errorf("received %+v", got)
+	}
+	if len(l.C()) != 0 {
+		t.Errorf("notification after the savepoint was delivered: %+v", <-l.C())
+	}
+}
@@ -0,0 +1,23 @@
+// internal/parser/preload.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handlePreload handles PRELOAD TABLE table
+func (e *Engine) handlePreload(input string) string {
+	parts := sqlFields(strings.TrimSuffix(strings.TrimSpace(input), ";"))
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "TABLE") {
+		return "Syntax error: PRELOAD TABLE table"
+	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	return e.DB.Preload(tableName)
+}
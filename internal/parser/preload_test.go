@@ -0,0 +1,31 @@
+// internal/parser/preload_test.go
+package parser
+
+import "testing"
+
+func TestPreloadTable(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("CREATE INDEX ON users (id)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'Ann')")
+	engine.Execute("FLUSH PAGE CACHE")
+
+	for input, want := range map[string]string{
+		"PRELOAD TABLE users;":  "Table users preloaded: 1 rows, 1 pages, 1 indexes",
+		"preload table Users":   "Table users preloaded: 1 rows, 1 pages, 1 indexes",
+		"PRELOAD TABLE missing": "Table missing not found",
+		"PRELOAD TABLE":         "Syntax error: PRELOAD TABLE table",
+		"PRELOAD TABLE a b":     "Syntax error: PRELOAD TABLE table",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+
+	engine.Execute("CREATE USER reader pass123 user")
+	engine.Execute("LOGIN reader pass123")
+	if got := engine.Execute("PRELOAD TABLE users"); got != ErrInsufficientPermissions {
+		t.Errorf("non-admin PRELOAD: %s", got)
+	}
+}
@@ -0,0 +1,259 @@
+// internal/parser/procedure.go
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// procedureParamPattern matches :name parameter references in procedure bodies
+var procedureParamPattern = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// identifierPattern matches procedure and parameter names
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// procedureStatements lists the statements allowed inside a procedure body.
+// Everything else either manages transactions itself or is not transactional.
+var procedureStatements = []string{"SELECT", "INSERT INTO", "UPDATE", "DELETE FROM", "CREATE TABLE", "DROP TABLE"}
+
+// handleCreateProcedure handles
+// CREATE PROCEDURE name[(param, ...)] AS BEGIN stmt; stmt; ... END
+func (e *Engine) handleCreateProcedure(input string) string {
+	const usage = "Syntax error: CREATE PROCEDURE name(params) AS BEGIN statement; ... END"
+
+	rest := strings.TrimSpace(input[len("CREATE PROCEDURE"):])
+	upperRest := strings.ToUpper(rest)
+
+	asIdx := strings.Index(upperRest, " AS ")
+	if asIdx == -1 {
+		return usage
+	}
+	header := strings.TrimSpace(rest[:asIdx])
+	body := strings.TrimSpace(rest[asIdx+len(" AS "):])
+
+	upperBody := strings.ToUpper(body)
+	if !strings.HasPrefix(upperBody, "BEGIN") || !strings.HasSuffix(upperBody, "END") {
+		return usage
+	}
+	body = strings.TrimSpace(body[len("BEGIN") : len(body)-len("END")])
+
+	name, params, err := parseProcedureHeader(header)
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+
+	statements := splitStatements(body)
+	if len(statements) == 0 {
+		return "Syntax error: procedure body is empty"
+	}
+
+	known := make(map[string]bool, len(params))
+	for _, p := range params {
+		known[strings.ToLower(p)] = true
+	}
+	for _, stmt := range statements {
+		if !isProcedureStatement(strings.ToUpper(stmt)) {
+			return fmt.Sprintf("Error: statement not allowed in a procedure: %s", stmt)
+		}
+		for _, m := range procedureParamPattern.FindAllStringSubmatch(stmt, -1) {
+			if !known[strings.ToLower(m[1])] {
+				return fmt.Sprintf("Error: unknown parameter :%s in procedure %s", m[1], name)
+			}
+		}
+	}
+
+	proc := storage.Procedure{Name: name, Params: params, Statements: statements}
+	if err := e.DB.CreateProcedure(proc); err != nil {
+		return fmt.Sprintf("Failed to create procedure: %v", err)
+	}
+	return fmt.Sprintf("Procedure %s created", strings.ToLower(name))
+}
+
+// handleDropProcedure handles DROP PROCEDURE name
+func (e *Engine) handleDropProcedure(input string) string {
+	parts := strings.Fields(input)
+	if len(parts) != 3 {
+		return "Syntax error: DROP PROCEDURE name"
+	}
+
+	if err := e.DB.DropProcedure(parts[2]); err != nil {
+		return fmt.Sprintf("Failed to drop procedure: %v", err)
+	}
+	return fmt.Sprintf("Procedure %s dropped", strings.ToLower(parts[2]))
+}
+
+// handleShowProcedures handles SHOW PROCEDURES
+func (e *Engine) handleShowProcedures() string {
+	procs, err := e.DB.ListProcedures()
+	if err != nil {
+		return fmt.Sprintf("Failed to list procedures: %v", err)
+	}
+	if len(procs) == 0 {
+		return "No procedures defined"
+	}
+
+	var result strings.Builder
+	result.WriteString("Procedures:\n")
+	for _, proc := range procs {
+		result.WriteString(fmt.Sprintf("  %s(%s) - %d statement(s)\n",
+			proc.Name, strings.Join(proc.Params, ", "), len(proc.Statements)))
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
+// handleCall handles CALL name(args). The procedure runs in its own
+// transaction, or inside the current one if a transaction is already open,
+// so either every statement takes effect or none does.
+func (e *Engine) handleCall(input string) string {
+	rest := strings.TrimSpace(input[len("CALL"):])
+	name := rest
+	var args []string
+	if open := strings.Index(rest, "("); open != -1 {
+		if !strings.HasSuffix(rest, ")") {
+			return "Syntax error: CALL name(args)"
+		}
+		name = strings.TrimSpace(rest[:open])
+		args = splitArguments(rest[open+1 : len(rest)-1])
+	}
+	if !identifierPattern.MatchString(name) {
+		return "Syntax error: CALL name(args)"
+	}
+
+	proc, err := e.DB.GetProcedure(name)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if len(args) != len(proc.Params) {
+		return fmt.Sprintf("Error: procedure %s expects %d argument(s), got %d", proc.Name, len(proc.Params), len(args))
+	}
+
+	values := make(map[string]string, len(args))
+	for i, p := range proc.Params {
+		values[strings.ToLower(p)] = args[i]
+	}
+
+	ownTx := e.DB.GetCurrentTransaction() == nil
+	if ownTx {
+		if _, err := e.DB.BeginTransaction(storage.ReadCommitted); err != nil {
+			return fmt.Sprintf("Failed to begin transaction: %v", err)
+		}
+	}
+
+	var results []string
+	for i, stmt := range proc.Statements {
+		stmt = procedureParamPattern.ReplaceAllStringFunc(stmt, func(ref string) string {
+			return values[strings.ToLower(ref[1:])]
+		})
+
+		result, err := e.Exec(stmt)
+		if err != nil {
+			if ownTx {
+				e.DB.RollbackTransaction()
+				e.sendPendingNotifications(false)
+				return fmt.Sprintf("Procedure %s failed at statement %d, no changes applied: %v", proc.Name, i+1, err)
+			}
+			return fmt.Sprintf("Procedure %s failed at statement %d: %v", proc.Name, i+1, err)
+		}
+		results = append(results, result)
+	}
+
+	if ownTx {
+		if err := e.DB.CommitTransaction(); err != nil {
+			e.sendPendingNotifications(false)
+			return fmt.Sprintf("Procedure %s failed, no changes applied: %v", proc.Name, err)
+		}
+		e.sendPendingNotifications(true)
+	}
+
+	results = append(results, fmt.Sprintf("Procedure %s executed (%d statement(s))", proc.Name, len(proc.Statements)))
+	return strings.Join(results, "\n")
+}
+
+// parseProcedureHeader parses "name" or "name(param, ...)"
+func parseProcedureHeader(header string) (string, []string, error) {
+	name := header
+	var params []string
+	if open := strings.Index(header, "("); open != -1 {
+		if !strings.HasSuffix(header, ")") {
+			return "", nil, fmt.Errorf("unclosed parameter list")
+		}
+		name = strings.TrimSpace(header[:open])
+		for _, p := range strings.Split(header[open+1:len(header)-1], ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				params = append(params, p)
+			}
+		}
+	}
+
+	if !identifierPattern.MatchString(name) {
+		return "", nil, fmt.Errorf("invalid procedure name %q", name)
+	}
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !identifierPattern.MatchString(p) {
+			return "", nil, fmt.Errorf("invalid parameter name %q", p)
+		}
+		if seen[strings.ToLower(p)] {
+			return "", nil, fmt.Errorf("duplicate parameter %q", p)
+		}
+		seen[strings.ToLower(p)] = true
+	}
+	return name, params, nil
+}
+
+// isProcedureStatement reports whether a statement may appear in a procedure body
+func isProcedureStatement(upper string) bool {
+	for _, prefix := range procedureStatements {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits a body on semicolons outside single quotes
+func splitStatements(body string) []string {
+	var statements []string
+	for _, stmt := range splitOutsideQuotes(body, ';') {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+// splitArguments splits CALL arguments on commas outside single quotes,
+// keeping quotes so values substitute into statements verbatim
+func splitArguments(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	args := splitOutsideQuotes(raw, ',')
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+	return args
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside single quotes
+func splitOutsideQuotes(s string, sep rune) []string {
+	var parts []string
+	var current strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+			current.WriteRune(r)
+		case r == sep && !inQuote:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, current.String())
+}
@@ -0,0 +1,73 @@
+// internal/parser/procedure_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoredProcedures(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("CREATE TABLE audit (id, action)")
+
+	result := engine.Execute("CREATE PROCEDURE add_user(id, name) AS BEGIN INSERT INTO users VALUES (:id, :name); INSERT INTO audit VALUES (:id, 'created; ok') END")
+	if result != "Procedure add_user created" {
+		t.Fatalf("create procedure failed: %s", result)
+	}
+
+	t.Run("Call", func(t *testing.T) {
+		result, err := engine.Exec("CALL add_user(1, 'Hareesh')")
+		if err != nil || !strings.Contains(result, "Procedure add_user executed") {
+			t.Fatalf("call failed: %s %v", result, err)
+		}
+		if rows := engine.Execute("SELECT * FROM users"); !strings.Contains(rows, "Hareesh") {
+			t.Errorf("expected inserted user, got %s", rows)
+		}
+		if rows := engine.Execute("SELECT * FROM audit"); !strings.Contains(rows, "created; ok") {
+			t.Errorf("expected audit row, got %s", rows)
+		}
+	})
+
+	t.Run("FailureAppliesNothing", func(t *testing.T) {
+		engine.Execute("CREATE PROCEDURE broken(id) AS BEGIN INSERT INTO audit VALUES (:id, 'x'); INSERT INTO missing VALUES (:id) END")
+		result := engine.Execute("CALL broken(2)")
+		if !strings.Contains(result, "no changes applied") {
+			t.Fatalf("expected failure, got %s", result)
+		}
+		if rows := engine.Execute("SELECT * FROM audit"); strings.Contains(rows, "2") {
+			t.Errorf("failed call left changes behind: %s", rows)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		cases := map[string]string{
+			"CALL add_user(1)": "expects 2 argument(s)",
+			"CALL nope()":      "not found",
+			"CREATE PROCEDURE add_user(id) AS BEGIN SELECT * FROM users END":         "already exists",
+			"CREATE PROCEDURE p(a) AS BEGIN COMMIT END":                              "not allowed",
+			"CREATE PROCEDURE p(a) AS BEGIN INSERT INTO users VALUES (:a, :b) END":   "unknown parameter :b",
+			"CREATE PROCEDURE p(a, a) AS BEGIN INSERT INTO users VALUES (:a, 1) END": "duplicate parameter",
+		}
+		for stmt, want := range cases {
+			if result := engine.Execute(stmt); !strings.Contains(result, want) {
+				t.Errorf("%s: expected %q, got %q", stmt, want, result)
+			}
+		}
+	})
+
+	t.Run("PersistAndDrop", func(t *testing.T) {
+		reopened := NewEngine(engine.DB.DataDir)
+		reopened.Execute("LOGIN admin admin123")
+		if list := reopened.Execute("SHOW PROCEDURES"); !strings.Contains(list, "add_user(id, name)") {
+			t.Errorf("procedure not persisted: %s", list)
+		}
+		if result := reopened.Execute("DROP PROCEDURE add_user"); result != "Procedure add_user dropped" {
+			t.Errorf("drop failed: %s", result)
+		}
+		if result := reopened.Execute("CALL add_user(3, 'x')"); !strings.Contains(result, "not found") {
+			t.Errorf("expected dropped procedure to be gone, got %s", result)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+// internal/parser/processlist.go
+//
+// Every statement the engine executes is numbered with a query ID that
+// grows for the life of the server. The server reports each statement's ID
+// to the client with the prompt that follows its response, the slow query
+// log names it, SHOW PROCESSLIST lists the running ones and KILL stops
+// waiting for one.
+package parser
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// RunningQuery is a statement being executed
+type RunningQuery struct {
+	ID        uint64
+	User      string
+	Statement string
+	Started   time.Time
+	// Conn is the connection that sent the statement, or nil
+	Conn *Connection
+
+	killed   chan struct{}
+	killOnce sync.Once
+}
+
+// Killed is closed when the query is killed
+func (q *RunningQuery) Killed() <-chan struct{} {
+	return q.killed
+}
+
+// isKilled reports whether the query was killed
+func (q *RunningQuery) isKilled() bool {
+	select {
+	case <-q.killed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProcessList numbers statements and tracks the running ones
+type ProcessList struct {
+	lastID  atomic.Uint64
+	mu      sync.Mutex
+	running map[uint64]*RunningQuery
+}
+
+// NewProcessList returns an empty process list
+func NewProcessList() *ProcessList {
+	return &ProcessList{running: make(map[uint64]*RunningQuery)}
+}
+
+// start registers statement, run by user, under the next query ID
+func (pl *ProcessList) start(user, statement string) *RunningQuery {
+	q := &RunningQuery{
+		ID:        pl.lastID.Add(1),
+		User:      user,
+		Statement: redactStatement(statement),
+		Started:   time.Now(),
+		killed:    make(chan struct{}),
+	}
+	pl.mu.Lock()
+	pl.running[q.ID] = q
+	pl.mu.Unlock()
+	return q
+}
+
+// finish removes a query from the running ones
+func (pl *ProcessList) finish(q *RunningQuery) {
+	pl.mu.Lock()
+	delete(pl.running, q.ID)
+	pl.mu.Unlock()
+}
+
+// kill marks the running query id as killed, reporting false if no such
+// query is running
+func (pl *ProcessList) kill(id uint64) bool {
+	pl.mu.Lock()
+	q, ok := pl.running[id]
+	pl.mu.Unlock()
+	if ok {
+		q.killOnce.Do(func() { close(q.killed) })
+	}
+	return ok
+}
+
+// list returns the running queries in ID order
+func (pl *ProcessList) list() []*RunningQuery {
+	pl.mu.Lock()
+	queries := make([]*RunningQuery, 0, len(pl.running))
+	for _, q := range pl.running {
+		queries = append(queries, q)
+	}
+	pl.mu.Unlock()
+	sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+	return queries
+}
+
+// redactStatement returns statement as shown in the process list and the
+// logs: statements that carry passwords are reduced to their command, and a
+// secret such as the argument of PASSPHRASE becomes ?
+func redactStatement(statement string) string {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	cmd := lookupCommand(strings.ToUpper(statement))
+	if cmd == nil {
+		return statement
+	}
+	if cmd.section == "Authentication" {
+		return cmd.prefix
+	}
+	if len(cmd.secrets) == 0 {
+		return statement
+	}
+
+	var b strings.Builder
+	runes := []rune(statement)
+	for i := 0; i < len(runes); i++ {
+		start := i
+		switch {
+		case unicode.IsSpace(runes[i]):
+		case i > 0 && unicode.IsSpace(runes[i-1]) && endsWithKeyword(strings.ToUpper(b.String()), cmd.secrets):
+			// A secret, even a bare word
+			i = argumentEnd(runes, i)
+			b.WriteByte('?')
+			continue
+		case runes[i] == '\'' || runes[i] == '"':
+			// Keywords in literals and quoted identifiers are not secrets
+			i = argumentEnd(runes, i)
+		}
+		b.WriteString(string(runes[start : i+1]))
+	}
+	return b.String()
+}
+
+// StartQuery registers statement as running for the current session and
+// returns it with its query ID. Run it with ExecuteQuery or
+// ExecuteBatchQuery.
+func (e *Engine) StartQuery(statement string) *RunningQuery {
+	user := ""
+	if e.CurrentSession != nil {
+		user = e.CurrentSession.Username
+	}
+	return e.Processes.start(user, statement)
+}
+
+// finishQuery removes q from the process list and logs it if it ran for
+// SlowQueryThreshold or longer
+func (e *Engine) finishQuery(q *RunningQuery, elapsed time.Duration) {
+	e.Processes.finish(q)
+	if q.Conn != nil {
+		q.Conn.endQuery(e.DB.GetCurrentTransaction() != nil)
+	}
+	if e.SlowQueryThreshold > 0 && elapsed >= e.SlowQueryThreshold {
+		user := q.User
+		if user == "" {
+			user = "-"
+		}
+		log.Printf("🐢 Slow query %d took %s (user %s): %s\n", q.ID, elapsed.Round(time.Millisecond), user, q.Statement)
+	}
+}
+
+// handleShowProcesslist handles SHOW PROCESSLIST
+func (e *Engine) handleShowProcesslist(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 2 {
+		return nil, "Syntax error: SHOW PROCESSLIST"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return nil, err
+	}
+
+	rs := &storage.ResultSet{Columns: []string{"id", "user", "running_ms", "state", "statement"}}
+	now := time.Now()
+	for _, q := range e.Processes.list() {
+		user, state := q.User, "running"
+		if user == "" {
+			user = "-"
+		}
+		if q.isKilled() {
+			state = "killed"
+		}
+		elapsed := strconv.FormatFloat(float64(now.Sub(q.Started))/float64(time.Millisecond), 'f', 3, 64)
+		rs.Rows = append(rs.Rows, []string{strconv.FormatUint(q.ID, 10), user, elapsed, state, q.Statement})
+	}
+	return rs, ""
+}
+
+// handleKill handles KILL id
+func (e *Engine) handleKill(input string) string {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		return "Syntax error: KILL query_id"
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || id == 0 {
+		return "Error: query ID must be a positive integer"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	if !e.Processes.kill(id) {
+		return fmt.Sprintf("Error: query %d not found; it may have finished", id)
+	}
+	log.Printf("Query %d killed by %s\n", id, e.CurrentSession.Username)
+	return fmt.Sprintf("Query %d killed", id)
+}
+
+// KilledResult is the response to a statement killed while running
+func KilledResult(id uint64) string {
+	return fmt.Sprintf("Error: query %d was killed", id)
+}
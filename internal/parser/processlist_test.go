@@ -0,0 +1,117 @@
+// internal/parser/processlist_test.go
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestProcessList(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	login := engine.StartQuery("LOGIN admin admin123")
+	engine.ExecuteQuery(login, "LOGIN admin admin123")
+	engine.Execute("CREATE TABLE t (id)")
+
+	// SHOW PROCESSLIST lists itself under the next query ID
+	got := engine.Execute("SHOW PROCESSLIST")
+	if !strings.HasPrefix(got, "id | user | running_ms | state | statement\n") ||
+		!strings.Contains(got, fmt.Sprintf("\n%d | admin | ", login.ID+2)) || !strings.HasSuffix(got, "| running | SHOW PROCESSLIST\n") {
+		t.Errorf("processlist:\n%s", got)
+	}
+	if login.Statement != "LOGIN" {
+		t.Errorf("password in the process list: %q", login.Statement)
+	}
+
+	if got := engine.Execute("KILL 999"); got != "Error: query 999 not found; it may have finished" {
+		t.Errorf("kill unknown: %q", got)
+	}
+	if got := engine.Execute("KILL x"); got != "Error: query ID must be a positive integer" {
+		t.Errorf("kill x: %q", got)
+	}
+
+	// A killed batch stops and rolls back
+	batch := engine.StartQuery("BATCH 2")
+	if got := engine.Execute(fmt.Sprintf("KILL %d", batch.ID)); got != fmt.Sprintf("Query %d killed", batch.ID) {
+		t.Fatalf("kill: %q", got)
+	}
+	if list := engine.Execute("SHOW PROCESSLIST"); !strings.Contains(list, "| killed | BATCH 2\n") {
+		t.Errorf("killed batch not shown:\n%s", list)
+	}
+	want := fmt.Sprintf("Error: batch failed at statement 1, no changes applied: Error: query %d was killed", batch.ID)
+	if _, err := engine.ExecuteBatchQuery(batch, []string{"INSERT INTO t VALUES (1)", "INSERT INTO t VALUES (2)"}); err == nil || err.Error() != want {
+		t.Errorf("killed batch: %v", err)
+	}
+	if got := engine.Execute("SELECT * FROM t"); got != "id\n(no rows)\n" {
+		t.Errorf("killed batch applied: %q", got)
+	}
+	if list := engine.Execute("SHOW PROCESSLIST"); strings.Contains(list, "BATCH") {
+		t.Errorf("finished batch still listed:\n%s", list)
+	}
+
+	engine.Execute("CREATE USER bob pw")
+	engine.Execute("LOGIN bob pw")
+	if got := engine.Execute("SHOW PROCESSLIST"); got != ErrInsufficientPermissions {
+		t.Errorf("non-admin: %q", got)
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.SlowQueryThreshold = 1
+	q := engine.StartQuery("CREATE TABLE slow (id)")
+	engine.ExecuteQuery(q, "CREATE TABLE slow (id)")
+	if want := fmt.Sprintf("Slow query %d took ", q.ID); !strings.Contains(buf.String(), want) ||
+		!strings.Contains(buf.String(), "(user admin): CREATE TABLE slow (id)") {
+		t.Errorf("log:\n%s", buf.String())
+	}
+}
+
+func TestRedactStatement(t *testing.T) {
+	for statement, want := range map[string]string{
+		"LOGIN admin admin123":                                    "LOGIN",
+		"CREATE USER bob pw user":                                 "CREATE USER",
+		"BACKUP TO /tmp/b.tar PASSPHRASE s3cret":                  "BACKUP TO /tmp/b.tar PASSPHRASE ?",
+		"backup to /tmp/b.tar passphrase 'two words' DESC 'x';":   "backup to /tmp/b.tar passphrase ? DESC 'x'",
+		"BACKUP INFO /tmp/b.tar PASSPHRASE s3cret":                "BACKUP INFO /tmp/b.tar PASSPHRASE ?",
+		"BACKUP VERIFY /tmp/b.tar\tPASSPHRASE\ts3cret":            "BACKUP VERIFY /tmp/b.tar\tPASSPHRASE\t?",
+		"RESTORE FROM /tmp/b.tar PASSPHRASE 's3cret'":             "RESTORE FROM /tmp/b.tar PASSPHRASE ?",
+		"REPAIR TABLE users FROM /backups PASSPHRASE s3cret":      "REPAIR TABLE users FROM /backups PASSPHRASE ?",
+		"BACKUP TO '/tmp/PASSPHRASE x' DESC 'PASSPHRASE kept'":    "BACKUP TO '/tmp/PASSPHRASE x' DESC 'PASSPHRASE kept'",
+		"SELECT passphrase FROM keys WHERE passphrase = 'public'": "SELECT passphrase FROM keys WHERE passphrase = 'public'",
+	} {
+		if got := redactStatement(statement); got != want {
+			t.Errorf("%q: got %q, want %q", statement, got, want)
+		}
+	}
+}
+
+func TestProcessListRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.SlowQueryThreshold = 1
+
+	statement := "BACKUP INFO missing.tar PASSPHRASE s3cret"
+	q := engine.StartQuery(statement)
+	if list := engine.Execute("SHOW PROCESSLIST"); !strings.Contains(list, "| running | BACKUP INFO missing.tar PASSPHRASE ?\n") || strings.Contains(list, "s3cret") {
+		t.Errorf("processlist:\n%s", list)
+	}
+	engine.ExecuteQuery(q, statement)
+	if !strings.Contains(buf.String(), "BACKUP INFO missing.tar PASSPHRASE ?") || strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("log:\n%s", buf.String())
+	}
+}
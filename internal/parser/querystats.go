@@ -0,0 +1,306 @@
+// internal/parser/querystats.go
+//
+// Statement statistics, in the spirit of PostgreSQL's pg_stat_statements.
+// Every statement is normalized into a fingerprint by replacing its string
+// and number literals with ?, so "SELECT * FROM t WHERE id = 1" and
+// "... id = 2" are counted together. For each fingerprint the server keeps
+// the number of calls and errors, the total, minimum and maximum latency,
+// and the rows returned or changed. SHOW QUERY STATS lists them and
+// RESET QUERY STATS clears them.
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// maxQueryStats is the number of fingerprints kept; when it is reached the
+// least called one is dropped to make room
+const maxQueryStats = 5000
+
+// queryStat aggregates the executions of one fingerprint
+type queryStat struct {
+	query  string
+	calls  int64
+	errors int64
+	rows   int64
+	total  time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// QueryStats aggregates statement executions by fingerprint
+type QueryStats struct {
+	mu    sync.Mutex
+	stats map[string]*queryStat
+}
+
+// NewQueryStats returns empty statistics
+func NewQueryStats() *QueryStats {
+	return &QueryStats{stats: make(map[string]*queryStat)}
+}
+
+// Record adds one execution of statement, which took elapsed and returned
+// result or failed with err
+func (q *QueryStats) Record(statement string, elapsed time.Duration, result string, err error) {
+	if q == nil {
+		return
+	}
+	query := fingerprint(statement)
+	if query == "" {
+		return
+	}
+	failed := err != nil
+	rows := int64(0)
+	if !failed {
+		rows = resultRows(result)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st, ok := q.stats[query]
+	if !ok {
+		if len(q.stats) >= maxQueryStats {
+			q.evictLocked()
+		}
+		st = &queryStat{query: query, min: elapsed}
+		q.stats[query] = st
+	}
+	st.calls++
+	st.total += elapsed
+	st.rows += rows
+	if failed {
+		st.errors++
+	}
+	if elapsed < st.min {
+		st.min = elapsed
+	}
+	if elapsed > st.max {
+		st.max = elapsed
+	}
+}
+
+// evictLocked drops the least called fingerprint. The caller holds q.mu.
+func (q *QueryStats) evictLocked() {
+	var victim *queryStat
+	for _, st := range q.stats {
+		if victim == nil || st.calls < victim.calls {
+			victim = st
+		}
+	}
+	if victim != nil {
+		delete(q.stats, victim.query)
+	}
+}
+
+// Reset clears the statistics and returns how many fingerprints were dropped
+func (q *QueryStats) Reset() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.stats)
+	q.stats = make(map[string]*queryStat)
+	return n
+}
+
+// top returns copies of the n fingerprints with the most total time, all of
+// them when n is 0
+func (q *QueryStats) top(n int) []queryStat {
+	q.mu.Lock()
+	stats := make([]queryStat, 0, len(q.stats))
+	for _, st := range q.stats {
+		stats = append(stats, *st)
+	}
+	q.mu.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].total != stats[j].total {
+			return stats[i].total > stats[j].total
+		}
+		return stats[i].query < stats[j].query
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// endsWithKeyword reports whether s, upper case, ends with one of keywords
+// as a whole word, ignoring trailing whitespace
+func endsWithKeyword(s string, keywords []string) bool {
+	s = strings.TrimRightFunc(s, unicode.IsSpace)
+	for _, kw := range keywords {
+		if rest, ok := strings.CutSuffix(s, kw); ok && (rest == "" || strings.TrimRightFunc(rest, unicode.IsSpace) != rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// argumentEnd returns the index of the last rune of the argument starting
+// at runes[i]: a string literal, a quoted identifier or a bare word
+func argumentEnd(runes []rune, i int) int {
+	switch runes[i] {
+	case '\'':
+		// '' stands for a quote
+		for i++; i < len(runes); i++ {
+			if runes[i] == '\'' {
+				if i+1 < len(runes) && runes[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i
+			}
+		}
+		return len(runes) - 1
+	case '"':
+		for i++; i < len(runes) && runes[i] != '"'; i++ {
+		}
+		return min(i, len(runes)-1)
+	}
+	for i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+		i++
+	}
+	return i
+}
+
+// fingerprint normalizes a statement: string and number literals become ?,
+// runs of whitespace a single space, and everything outside quoted
+// identifiers upper case. Statements that carry passwords are reduced to
+// their command, and a secret such as the argument of PASSPHRASE becomes ?
+// like a literal, so credentials never reach the statistics.
+func fingerprint(statement string) string {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	var secrets []string
+	if cmd := lookupCommand(strings.ToUpper(statement)); cmd != nil {
+		if cmd.section == "Authentication" {
+			return cmd.prefix
+		}
+		secrets = cmd.secrets
+	}
+
+	var b strings.Builder
+	runes := []rune(statement)
+	identChar := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	space := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case space && endsWithKeyword(b.String(), secrets):
+			// A secret, even a bare word
+			i = argumentEnd(runes, i)
+			r = '?'
+		case r == '\'':
+			// A string literal
+			i = argumentEnd(runes, i)
+			r = '?'
+		case r == '"':
+			// A quoted identifier is kept as written
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end < len(runes) {
+				end++
+			}
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteString(string(runes[i:end]))
+			i = end - 1
+			continue
+		case unicode.IsDigit(r) && (i == 0 || !identChar(runes[i-1])):
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			r = '?'
+		default:
+			r = unicode.ToUpper(r)
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// resultRows returns the rows a successful statement returned or changed:
+// N for "N rows updated" style messages, or the rows of a result set in any
+// output_format
+func resultRows(result string) int64 {
+	if n, rest, ok := strings.Cut(result, " "); ok && strings.HasPrefix(rest, "row") {
+		if v, err := strconv.ParseInt(n, 10, 64); err == nil {
+			return v
+		}
+	}
+	if strings.HasPrefix(result, "[") {
+		var rows []json.RawMessage
+		if json.Unmarshal([]byte(result), &rows) == nil {
+			return int64(len(rows))
+		}
+		return 0
+	}
+	// A result set is a header line followed by one line per row
+	if !strings.HasSuffix(result, "\n") {
+		return 0
+	}
+	lines := strings.Split(strings.TrimSuffix(result, "\n"), "\n")
+	rows := int64(len(lines) - 1)
+	if rows == 1 && lines[1] == "(no rows)" {
+		return 0
+	}
+	return rows
+}
+
+// handleShowQueryStats handles SHOW QUERY STATS [n]
+func (e *Engine) handleShowQueryStats(input string) (*storage.ResultSet, string) {
+	parts := strings.Fields(input)
+	n := 0
+	switch len(parts) {
+	case 3:
+	case 4:
+		v, err := strconv.Atoi(parts[3])
+		if err != nil || v < 1 {
+			return nil, "Error: statement count must be a positive integer"
+		}
+		n = v
+	default:
+		return nil, "Syntax error: SHOW QUERY STATS [n]"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return nil, err
+	}
+
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	}
+	rs := &storage.ResultSet{Columns: []string{"query", "calls", "total_ms", "mean_ms", "min_ms", "max_ms", "rows", "errors"}}
+	for _, st := range e.QueryStats.top(n) {
+		rs.Rows = append(rs.Rows, []string{st.query, strconv.FormatInt(st.calls, 10),
+			ms(st.total), ms(st.total / time.Duration(st.calls)), ms(st.min), ms(st.max), strconv.FormatInt(st.rows, 10), strconv.FormatInt(st.errors, 10)})
+	}
+	return rs, ""
+}
+
+// handleResetQueryStats handles RESET QUERY STATS
+func (e *Engine) handleResetQueryStats(input string) string {
+	if len(strings.Fields(input)) != 3 {
+		return "Syntax error: RESET QUERY STATS"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	return fmt.Sprintf("Query stats reset: %d statements dropped", e.QueryStats.Reset())
+}
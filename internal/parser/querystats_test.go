@@ -0,0 +1,80 @@
+// internal/parser/querystats_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"select * from t where id = 1;", "SELECT * FROM T WHERE ID = ?"},
+		{"SELECT  *\tFROM t WHERE name = 'O''Brien'", "SELECT * FROM T WHERE NAME = ?"},
+		{"INSERT INTO t2 VALUES (42, 'x', 3.5)", "INSERT INTO T2 VALUES (?, ?, ?)"},
+		{`SELECT * FROM "Mixed Case" WHERE col1 = 7`, `SELECT * FROM "Mixed Case" WHERE COL1 = ?`},
+		{"UPDATE t SET a = 'b' ROW 12", "UPDATE T SET A = ? ROW ?"},
+		{"LOGIN admin s3cret", "LOGIN"},
+		{"CHANGE PASSWORD old new", "CHANGE PASSWORD"},
+		{"BACKUP TO 'b.backup' PASSPHRASE hunter2", "BACKUP TO ? PASSPHRASE ?"},
+		{"backup to 'b.backup' passphrase 'correct horse'", "BACKUP TO ? PASSPHRASE ?"},
+		{`RESTORE FROM 'b.backup' PASSPHRASE "my secret" AS new`, "RESTORE FROM ? PASSPHRASE ? AS NEW"},
+		{"REPAIR TABLE t PASSPHRASE s3cret;", "REPAIR TABLE T PASSPHRASE ?"},
+		{"SELECT passphrase FROM keys", "SELECT PASSPHRASE FROM KEYS"},
+	} {
+		if got := fingerprint(tt.in); got != tt.want {
+			t.Errorf("fingerprint(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE t (id, name)")
+	engine.Execute("INSERT INTO t VALUES (1, 'a')")
+	engine.Execute("INSERT INTO t VALUES (2, 'b')")
+	engine.Execute("INSERT INTO t VALUES (3)")
+	engine.Execute("SELECT * FROM t WHERE id = 1")
+	engine.Execute("select * from t where id = 5")
+
+	stats := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(engine.Execute("SHOW QUERY STATS")), "\n")[1:] {
+		cols := strings.Split(line, " | ")
+		stats[cols[0]] = cols[1:]
+	}
+	// calls | total_ms | mean_ms | min_ms | max_ms | rows | errors
+	insert := stats["INSERT INTO T VALUES (?, ?)"]
+	if insert == nil || insert[0] != "2" || insert[5] != "2" || insert[6] != "0" {
+		t.Errorf("unexpected insert stats: %v", insert)
+	}
+	if failed := stats["INSERT INTO T VALUES (?)"]; failed == nil || failed[0] != "1" || failed[6] != "1" {
+		t.Errorf("unexpected failed insert stats: %v", failed)
+	}
+	if sel := stats["SELECT * FROM T WHERE ID = ?"]; sel == nil || sel[0] != "2" || sel[5] != "1" {
+		t.Errorf("unexpected select stats: %v", sel)
+	}
+	if login := stats["LOGIN"]; login == nil {
+		t.Error("LOGIN should be counted without its password")
+	}
+	for query := range stats {
+		if strings.Contains(query, "ADMIN123") {
+			t.Errorf("password leaked into the stats: %s", query)
+		}
+	}
+
+	if got := engine.Execute("SHOW QUERY STATS 1"); strings.Count(got, "\n") != 2 {
+		t.Errorf("expected one statement, got %q", got)
+	}
+	if got := engine.Execute("RESET QUERY STATS"); !strings.HasPrefix(got, "Query stats reset: ") {
+		t.Errorf("unexpected reset result: %q", got)
+	}
+	if got := engine.Execute("SHOW QUERY STATS"); strings.Contains(got, "INSERT") {
+		t.Errorf("stats should be empty after a reset, got %q", got)
+	}
+
+	engine.Execute("CREATE USER bob pw123 user")
+	engine.Execute("LOGIN bob pw123")
+	if got := engine.Execute("SHOW QUERY STATS"); !strings.HasPrefix(got, "Insufficient permissions") {
+		t.Errorf("query stats should be admin only, got %q", got)
+	}
+}
@@ -0,0 +1,40 @@
+// internal/parser/repair.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleRepair handles REPAIR TABLE table [FROM dir] [PASSPHRASE secret]
+func (e *Engine) handleRepair(input string) string {
+	parts := strings.Fields(strings.TrimSuffix(strings.TrimSpace(input), ";"))
+	if len(parts) < 3 || !strings.EqualFold(parts[1], "TABLE") {
+		return "Syntax error: REPAIR TABLE table [FROM dir] [PASSPHRASE secret]"
+	}
+	tableName, err := parseTableName(parts[2])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	dir, passphrase := e.DB.RepairBackups()
+	if dir == "" {
+		dir = "./backups"
+	}
+	for i := 3; i < len(parts); i += 2 {
+		if i+1 >= len(parts) {
+			return "Syntax error: REPAIR TABLE table [FROM dir] [PASSPHRASE secret]"
+		}
+		switch strings.ToUpper(parts[i]) {
+		case "FROM":
+			dir = parts[i+1]
+		case "PASSPHRASE":
+			passphrase = parts[i+1]
+		default:
+			return "Syntax error: REPAIR TABLE table [FROM dir] [PASSPHRASE secret]"
+		}
+	}
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+	return e.DB.RepairTable(tableName, dir, passphrase)
+}
@@ -0,0 +1,35 @@
+// internal/parser/repair_test.go
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRepairTable(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'Ann')")
+	engine.Execute("FLUSH PAGE CACHE")
+	backupDir := t.TempDir()
+	engine.Execute("BACKUP TO " + filepath.Join(backupDir, "users.backup"))
+
+	for input, want := range map[string]string{
+		"REPAIR TABLE users FROM " + backupDir + ";": "Table users: 1 pages checked, none damaged",
+		"REPAIR TABLE missing":                       "Table missing not found",
+		"REPAIR TABLE":                               "Syntax error: REPAIR TABLE table [FROM dir] [PASSPHRASE secret]",
+		"REPAIR TABLE users FROM":                    "Syntax error: REPAIR TABLE table [FROM dir] [PASSPHRASE secret]",
+		"REPAIR TABLE users WITH x":                  "Syntax error: REPAIR TABLE table [FROM dir] [PASSPHRASE secret]",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+
+	engine.Execute("CREATE USER reader pass123 user")
+	engine.Execute("LOGIN reader pass123")
+	if got := engine.Execute("REPAIR TABLE users"); got != ErrInsufficientPermissions {
+		t.Errorf("non-admin REPAIR: %s", got)
+	}
+}
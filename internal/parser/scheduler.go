@@ -0,0 +1,235 @@
+// internal/parser/scheduler.go
+//
+// Query priorities. Every statement takes one of a bounded number of
+// execution slots before it runs. When all slots are busy, statements wait
+// in one queue per priority class, and freed slots go to the classes by
+// weighted round robin: high gets most of them, but normal and low still
+// get their share, so neither is starved. A session's class comes from its
+// role (admins run at high, other users at normal), and SET query_priority
+// can lower it, e.g. for a bulk load that should yield to everyone else.
+package parser
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+)
+
+// DefaultQuerySlots is the number of statements that may run at once
+const DefaultQuerySlots = 64
+
+// Priority is a query priority class
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	numPriorities
+)
+
+// priorityWeights is the share of freed slots each class gets while
+// statements of several classes wait
+var priorityWeights = [numPriorities]int{PriorityLow: 1, PriorityNormal: 3, PriorityHigh: 10}
+
+// ParsePriority parses high, normal or low
+func ParsePriority(s string) (Priority, error) {
+	switch s {
+	case "high":
+		return PriorityHigh, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q: use high, normal or low", s)
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// rolePriority returns the class a role's statements run at by default
+func rolePriority(role auth.UserRole) Priority {
+	if role == auth.RoleAdmin {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+// Scheduler bounds the statements running at once and orders waiting ones
+// by priority. A nil Scheduler admits every statement.
+type Scheduler struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+	// queues holds the waiting statements of each class, oldest first; a
+	// waiter's channel is closed when it is handed a slot
+	queues [numPriorities][]chan struct{}
+	// credits are the smooth weighted round robin counters of each class
+	credits [numPriorities]int
+}
+
+// NewScheduler returns a scheduler running slots statements at once, or nil
+// for no limit when slots is 0 or less
+func NewScheduler(slots int) *Scheduler {
+	if slots <= 0 {
+		return nil
+	}
+	return &Scheduler{slots: slots}
+}
+
+// Acquire takes a slot for a statement of class p, waiting up to timeout
+// (0 = no limit) when all are busy
+func (s *Scheduler) Acquire(p Priority, timeout time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.running < s.slots && s.waitingLocked() == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	granted := make(chan struct{})
+	s.queues[p] = append(s.queues[p], granted)
+	s.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-granted:
+		return nil
+	case <-expired:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, ch := range s.queues[p] {
+		if ch == granted {
+			s.queues[p] = append(s.queues[p][:i], s.queues[p][i+1:]...)
+			return fmt.Errorf("wait for a query slot timed out after %s", timeout)
+		}
+	}
+	// The slot was handed over just as the wait expired
+	return nil
+}
+
+// Release gives back a slot taken by Acquire, handing it to the next
+// waiting statement
+func (s *Scheduler) Release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.nextLocked()
+	if !ok {
+		s.running--
+		return
+	}
+	close(s.queues[p][0])
+	s.queues[p] = s.queues[p][1:]
+}
+
+// nextLocked picks the class whose oldest waiter gets a freed slot, by
+// smooth weighted round robin over the classes with waiters. The caller
+// holds s.mu.
+func (s *Scheduler) nextLocked() (Priority, bool) {
+	total := 0
+	best := Priority(-1)
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(s.queues[p]) == 0 {
+			s.credits[p] = 0
+			continue
+		}
+		s.credits[p] += priorityWeights[p]
+		total += priorityWeights[p]
+		if best < 0 || s.credits[p] > s.credits[best] {
+			best = p
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	s.credits[best] -= total
+	return best, true
+}
+
+// waitingLocked counts the waiting statements. The caller holds s.mu.
+func (s *Scheduler) waitingLocked() int {
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Slots returns the number of slots, 0 when statements are not limited
+func (s *Scheduler) Slots() int {
+	if s == nil {
+		return 0
+	}
+	return s.slots
+}
+
+// Running returns the number of slots taken
+func (s *Scheduler) Running() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Waiting returns the number of statements of class p waiting for a slot
+func (s *Scheduler) Waiting(p Priority) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queues[p])
+}
+
+// QueryPriority returns the class the session's statements run at: that of
+// its role, or lower when SET query_priority asks for it. A session not
+// logged in runs only its LOGIN and the other public commands, at low.
+func (e *Engine) QueryPriority() Priority {
+	if e.CurrentSession == nil {
+		return PriorityLow
+	}
+	p := rolePriority(e.CurrentSession.Role)
+	if set, err := ParsePriority(e.setting("query_priority")); err == nil && set < p {
+		p = set
+	}
+	return p
+}
+
+// AdmitQuery takes an execution slot at the session's priority. It returns
+// the function that gives the slot back, or the error to send instead of
+// running the statement.
+func (e *Engine) AdmitQuery() (func(), string) {
+	if e.Scheduler == nil {
+		return func() {}, ""
+	}
+	if err := e.Scheduler.Acquire(e.QueryPriority(), e.StatementTimeout()); err != nil {
+		return nil, "Error: " + err.Error()
+	}
+	return e.Scheduler.Release, ""
+}
@@ -0,0 +1,111 @@
+// internal/parser/scheduler_test.go
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestSchedulerPriorities(t *testing.T) {
+	s := NewScheduler(1)
+	if err := s.Acquire(PriorityNormal, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queue 14 statements of each class behind the busy slot
+	granted := make(chan Priority, 42)
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		for i := 0; i < 14; i++ {
+			go func() {
+				if err := s.Acquire(p, 0); err == nil {
+					granted <- p
+				}
+			}()
+		}
+		for s.Waiting(p) < 14 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// While all classes wait, every 14 slots go 10 to high, 3 to normal and
+	// 1 to low
+	counts := map[Priority]int{}
+	for i := 0; i < 14; i++ {
+		s.Release()
+		counts[<-granted]++
+	}
+	if counts[PriorityHigh] != 10 || counts[PriorityNormal] != 3 || counts[PriorityLow] != 1 {
+		t.Errorf("unexpected shares %v", counts)
+	}
+
+	// Nothing is starved: the rest drain in turn
+	for i := 0; i < 28; i++ {
+		s.Release()
+		<-granted
+	}
+	s.Release()
+	if s.Running() != 0 || s.Waiting(PriorityLow)+s.Waiting(PriorityNormal)+s.Waiting(PriorityHigh) != 0 {
+		t.Errorf("%d running and statements still waiting after draining", s.Running())
+	}
+
+	if err := NewScheduler(0).Acquire(PriorityLow, 0); err != nil {
+		t.Errorf("a nil scheduler should admit everything: %v", err)
+	}
+}
+
+func TestAdmitQuery(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Scheduler = NewScheduler(1)
+	engine.Execute("LOGIN admin admin123")
+
+	if p := engine.QueryPriority(); p != PriorityHigh {
+		t.Errorf("admins should run at high, got %s", p)
+	}
+	engine.Execute("SET query_priority = low")
+	if p := engine.QueryPriority(); p != PriorityLow {
+		t.Errorf("query_priority should lower the class, got %s", p)
+	}
+	engine.Execute("SET query_priority = DEFAULT")
+
+	engine.Execute("CREATE USER bulk secret123 USER")
+	engine.Execute("LOGIN bulk secret123")
+	engine.Execute("SET query_priority = high")
+	if p := engine.QueryPriority(); p != PriorityNormal {
+		t.Errorf("query_priority should not raise a user above normal, got %s", p)
+	}
+	engine.Execute("SET query_priority = DEFAULT")
+
+	release, rejected := engine.AdmitQuery()
+	if rejected != "" {
+		t.Fatalf("first statement rejected: %s", rejected)
+	}
+	engine.Execute("SET statement_timeout = 20ms")
+	if _, rejected := engine.AdmitQuery(); protocol.CodeOf(MessageError(rejected)) != protocol.CodeTimeout {
+		t.Errorf("expected a TIMEOUT error, got %q", rejected)
+	}
+	engine.Execute("SET statement_timeout = DEFAULT")
+	release()
+	if engine.Scheduler.Running() != 0 || engine.Scheduler.Waiting(PriorityNormal) != 0 {
+		t.Error("slot still taken after release")
+	}
+}
+
+func TestQueryPriorityFollowsTheConnectionsSession(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	defer engine.DB.Close()
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE USER bulk secret123 USER")
+
+	admin, bulk, anonymous := engine.Connect("10.0.0.1:5000"), engine.Connect("10.0.0.2:5000"), engine.Connect("10.0.0.3:5000")
+	runOn(engine, admin, "LOGIN admin admin123")
+	runOn(engine, bulk, "LOGIN bulk secret123")
+	runOn(engine, bulk, "SET query_priority = low")
+
+	for c, want := range map[*Connection]Priority{admin: PriorityHigh, bulk: PriorityLow, anonymous: PriorityLow} {
+		if p := c.Engine().QueryPriority(); p != want {
+			t.Errorf("connection %d runs at %s, want %s", c.ID, p, want)
+		}
+	}
+}
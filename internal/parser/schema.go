@@ -0,0 +1,152 @@
+// internal/parser/schema.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// handleComment handles COMMENT ON TABLE t IS '...' and
+// COMMENT ON COLUMN t.col IS '...'; IS NULL removes the comment
+func (e *Engine) handleComment(input string) string {
+	const usage = "Syntax error: COMMENT ON TABLE t IS 'text' | COMMENT ON COLUMN t.col IS 'text'"
+	parts := sqlFields(input)
+	if len(parts) != 6 || !strings.EqualFold(parts[4], "IS") {
+		return usage
+	}
+	comment, err := parseLiteral(parts[5])
+	if err != nil || (!strings.HasPrefix(parts[5], "'") && !storage.IsNull(comment)) {
+		return usage
+	}
+
+	var target, column string
+	switch strings.ToUpper(parts[2]) {
+	case "TABLE":
+		target = parts[3]
+	case "COLUMN":
+		var ok bool
+		target, column, ok = cutQualified(parts[3])
+		if !ok {
+			return usage
+		}
+		if column, err = storage.UnquoteIdentifier(column); err != nil {
+			return fmt.Sprintf("Syntax error: %v", err)
+		}
+	default:
+		return usage
+	}
+	tableName, err := parseTableName(target)
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.SetComment(tableName, column, comment)
+}
+
+// cutQualified splits table.column at the first dot outside double quotes
+func cutQualified(s string) (table, column string, ok bool) {
+	inQuote := false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == '.' && !inQuote:
+			return s[:i], s[i+1:], i > 0 && i < len(s)-1
+		}
+	}
+	return "", "", false
+}
+
+// handleDescribe handles DESCRIBE table
+func (e *Engine) handleDescribe(input string) (*storage.ResultSet, string) {
+	parts := sqlFields(input)
+	if len(parts) != 2 {
+		return nil, "Syntax error: DESCRIBE table_name"
+	}
+	tableName, err := parseTableName(parts[1])
+	if err != nil {
+		return nil, fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.DescribeTableResult(tableName)
+}
+
+// handleShowCreateTable handles SHOW CREATE TABLE table
+func (e *Engine) handleShowCreateTable(input string) (*storage.ResultSet, string) {
+	parts := sqlFields(input)
+	if len(parts) != 4 {
+		return nil, "Syntax error: SHOW CREATE TABLE table_name"
+	}
+	tableName, err := parseTableName(parts[3])
+	if err != nil {
+		return nil, fmt.Sprintf("Syntax error: %v", err)
+	}
+	return e.DB.ShowCreateTableResult(tableName)
+}
+
+// handleShowTableStats handles SHOW TABLE STATS [table]
+func (e *Engine) handleShowTableStats(input string) (*storage.ResultSet, string) {
+	parts := sqlFields(input)
+	switch len(parts) {
+	case 3:
+		return e.DB.ShowTableStatsResult("")
+	case 4:
+		tableName, err := parseTableName(parts[3])
+		if err != nil {
+			return nil, fmt.Sprintf("Syntax error: %v", err)
+		}
+		return e.DB.ShowTableStatsResult(tableName)
+	default:
+		return nil, "Syntax error: SHOW TABLE STATS [table_name]"
+	}
+}
+
+// handleCreateExternalTable handles
+// CREATE EXTERNAL TABLE t (col, ...) LOCATION 'file.csv' [HEADER]
+func (e *Engine) handleCreateExternalTable(input string) string {
+	const usage = "Syntax error: CREATE EXTERNAL TABLE t (col, ...) LOCATION 'file.csv' [HEADER]"
+	// External tables read files on the server, so only admins create them
+	if err := e.requireAdmin(); err != "" {
+		return err
+	}
+
+	open := strings.Index(input, "(")
+	end := strings.LastIndex(input, ")")
+	if open < 0 || end < open {
+		return usage
+	}
+	head := sqlFields(input[:open])
+	if len(head) != 4 {
+		return usage
+	}
+	tableName, err := parseTableName(head[3])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	columns := splitIdentifiers(input[open+1 : end])
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	tail := sqlFields(input[end+1:])
+	if len(tail) < 2 || len(tail) > 3 || !strings.EqualFold(tail[0], "LOCATION") || !strings.HasPrefix(tail[1], "'") {
+		return usage
+	}
+	location, err := parseQuoted(tail[1])
+	if err != nil {
+		return fmt.Sprintf("Syntax error: %v", err)
+	}
+	header := len(tail) == 3
+	if header && !strings.EqualFold(tail[2], "HEADER") {
+		return usage
+	}
+	return e.DB.CreateExternalTable(tableName, columns, location, header)
+}
+
+// handleShowTables handles SHOW TABLES
+func (e *Engine) handleShowTables(input string) (*storage.ResultSet, string) {
+	if len(sqlFields(input)) != 2 {
+		return nil, "Syntax error: SHOW TABLES"
+	}
+	return e.DB.ShowTablesResult()
+}
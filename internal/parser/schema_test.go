@@ -0,0 +1,119 @@
+// internal/parser/schema_test.go
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommentOn(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute(`CREATE TABLE "Orders" (id, "Ship To")`)
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"COMMENT ON TABLE orders IS 'Customer orders'", "Comment set on table orders"},
+		{`COMMENT ON COLUMN "ORDERS"."ship to" IS 'It''s the address'`, "Comment set on column orders.Ship To"},
+		{"COMMENT ON COLUMN orders.id IS 'Key'", "Comment set on column orders.id"},
+		{"COMMENT ON COLUMN orders.id IS NULL", "Comment set on column orders.id"},
+		{"COMMENT ON COLUMN orders.missing IS 'x'", "Column missing not found"},
+		{"COMMENT ON TABLE missing IS 'x'", "Table missing not found"},
+		{"COMMENT ON TABLE orders IS bare", "Syntax error: COMMENT ON TABLE t IS 'text' | COMMENT ON COLUMN t.col IS 'text'"},
+		{"COMMENT ON COLUMN orders IS 'x'", "Syntax error: COMMENT ON TABLE t IS 'text' | COMMENT ON COLUMN t.col IS 'text'"},
+		{"DESCRIBE orders", "name | kind | type | nullable | default | collation | indexed | comment\n" +
+			"orders | table |  |  |  |  |  | Customer orders\n" +
+			"id | column |  | yes |  | BINARY | no | \n" +
+			"Ship To | column |  | yes |  | BINARY | no | It's the address\n"},
+		{"SHOW CREATE TABLE orders", "statement\n" +
+			`CREATE TABLE orders (id, "Ship To")` + "\n" +
+			"COMMENT ON TABLE orders IS 'Customer orders'\n" +
+			`COMMENT ON COLUMN orders."Ship To" IS 'It''s the address'` + "\n"},
+		{"DESCRIBE missing", "Table missing not found"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestCreateExternalTable(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "it's.csv")
+	if err := os.WriteFile(csvPath, []byte("id,name\n1,Alice\n2,Bob\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	location := "'" + strings.ReplaceAll(csvPath, "'", "''") + "'"
+
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	if got := engine.Execute("CREATE EXTERNAL TABLE people (id, name) LOCATION " + location + " HEADER"); !strings.HasPrefix(got, "External table people created") {
+		t.Fatalf("create: %s", got)
+	}
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"SELECT * FROM people WHERE name = 'Bob'", "id | name\n2 | Bob\n"},
+		{"SELECT COUNT(*) FROM people", "count\n2\n"},
+		{"INSERT INTO people VALUES (3, 'Carol')", "Error: table people is external and read-only"},
+		{"SHOW CREATE TABLE people", "statement\nCREATE EXTERNAL TABLE people (id, name) LOCATION " + location + " HEADER\n"},
+		{"CREATE EXTERNAL TABLE t (a) FROM 'x.csv'", "Syntax error: CREATE EXTERNAL TABLE t (col, ...) LOCATION 'file.csv' [HEADER]"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.stmt, got, tt.want)
+		}
+	}
+
+	engine.Execute("CREATE USER reader pass123 user")
+	engine.Execute("LOGIN reader pass123")
+	if got := engine.Execute("CREATE EXTERNAL TABLE t2 (a) LOCATION " + location); got != ErrInsufficientPermissions {
+		t.Errorf("non-admin create: %s", got)
+	}
+}
+
+func TestShowTableStats(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES (1, 'Alice')")
+	engine.Execute("SELECT * FROM users")
+
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"SHOW TABLE STATS users", "users | 1 | 1 | 0 | 0 | 0 | "},
+		{"SHOW TABLE STATS", "users | 1 | 1 | 0 | 0 | 0 | "},
+		{"SHOW TABLE STATS missing", "Table missing not found"},
+		{"SHOW TABLE STATS users extra", "Syntax error: SHOW TABLE STATS [table_name]"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.stmt); !strings.Contains(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestShowTables(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES (1, 'Alice')")
+	engine.Execute("INSERT INTO users VALUES (2, 'Bob')")
+	engine.Execute("CREATE INDEX ON users (name)")
+	engine.Execute("CREATE TABLE empty (a)")
+
+	if got := engine.Execute("SHOW TABLES"); got != "table | rows | bytes | indexes\nempty | 0 | 0 | \nusers | 2 | 10 | name (2 keys)\n" {
+		t.Errorf("SHOW TABLES: %q", got)
+	}
+	if got := engine.Execute("SHOW TABLES users"); got != "Syntax error: SHOW TABLES" {
+		t.Errorf("extra argument: %q", got)
+	}
+}
@@ -0,0 +1,414 @@
+// internal/parser/select.go
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// selectClauses holds the trailing clauses of SELECT ... FROM table ...
+type selectClauses struct {
+	where string
+	group []string
+	order []storage.SortKey
+	limit int // -1 when there is no LIMIT
+}
+
+// parseSelectClauses splits the tokens after the table name into the WHERE,
+// GROUP BY, ORDER BY and LIMIT clauses, which must appear in that order
+func parseSelectClauses(tokens []string) (selectClauses, error) {
+	clauses := selectClauses{limit: -1}
+
+	whereIdx, groupIdx, orderIdx, limitIdx := -1, -1, -1, -1
+	for i, tok := range tokens {
+		switch strings.ToUpper(tok) {
+		case "WHERE":
+			if whereIdx == -1 && groupIdx == -1 && orderIdx == -1 && limitIdx == -1 {
+				whereIdx = i
+			}
+		case "GROUP":
+			if groupIdx == -1 && orderIdx == -1 && limitIdx == -1 && i+1 < len(tokens) && strings.ToUpper(tokens[i+1]) == "BY" {
+				groupIdx = i
+			}
+		case "ORDER":
+			if orderIdx == -1 && limitIdx == -1 && i+1 < len(tokens) && strings.ToUpper(tokens[i+1]) == "BY" {
+				orderIdx = i
+			}
+		case "LIMIT":
+			if limitIdx == -1 {
+				limitIdx = i
+			}
+		}
+	}
+
+	end := len(tokens)
+	if limitIdx != -1 {
+		if limitIdx != len(tokens)-2 {
+			return clauses, fmt.Errorf("LIMIT expects a single row count")
+		}
+		n, err := strconv.Atoi(tokens[limitIdx+1])
+		if err != nil || n < 0 {
+			return clauses, fmt.Errorf("invalid LIMIT %s", tokens[limitIdx+1])
+		}
+		clauses.limit = n
+		end = limitIdx
+	}
+	if orderIdx != -1 {
+		// ORDER BY col [ASC|DESC], col [ASC|DESC], ...
+		for _, item := range splitIdentifiers(strings.Join(tokens[orderIdx+2:end], " ")) {
+			key, err := parseSortKey(item)
+			if err != nil {
+				return clauses, err
+			}
+			clauses.order = append(clauses.order, key)
+		}
+		if len(clauses.order) == 0 {
+			return clauses, fmt.Errorf("ORDER BY expects a column and optional ASC or DESC")
+		}
+		end = orderIdx
+	}
+	if groupIdx != -1 {
+		// GROUP BY col, col, ...
+		for _, item := range splitIdentifiers(strings.Join(tokens[groupIdx+2:end], " ")) {
+			column, err := storage.UnquoteIdentifier(strings.TrimSpace(item))
+			if err != nil {
+				return clauses, err
+			}
+			if column == "" || len(sqlFields(item)) != 1 {
+				return clauses, fmt.Errorf("GROUP BY expects columns")
+			}
+			clauses.group = append(clauses.group, column)
+		}
+		if len(clauses.group) == 0 {
+			return clauses, fmt.Errorf("GROUP BY expects columns")
+		}
+		end = groupIdx
+	}
+	if whereIdx != -1 {
+		clauses.where = strings.Join(tokens[whereIdx+1:end], " ")
+		end = whereIdx
+	}
+	if end != 0 {
+		return clauses, fmt.Errorf("unexpected %s", strings.Join(tokens[:end], " "))
+	}
+	return clauses, nil
+}
+
+// parseSortKey parses one column of ORDER BY: col [ASC|DESC]
+func parseSortKey(item string) (storage.SortKey, error) {
+	fields := sqlFields(item)
+	var key storage.SortKey
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 2 && strings.ToUpper(fields[1]) == "ASC":
+	case len(fields) == 2 && strings.ToUpper(fields[1]) == "DESC":
+		key.Desc = true
+	default:
+		return key, fmt.Errorf("ORDER BY expects a column and optional ASC or DESC")
+	}
+	column, err := storage.UnquoteIdentifier(fields[0])
+	if err != nil {
+		return key, err
+	}
+	key.Column = column
+	return key, nil
+}
+
+// selectScope resolves the column references of a SELECT: table.column or
+// alias.column names a column of the queried table, and a result column's
+// alias names the column it heads
+type selectScope struct {
+	// qualifier is the table's alias, or its name when it has none; it is
+	// empty for a query of several tables
+	qualifier string
+	// aliases maps each result column alias to its column
+	aliases map[string]string
+}
+
+// resolve returns the table column a reference in WHERE or ORDER BY names.
+// References the scope does not know are returned unchanged, for the table
+// to resolve or reject.
+func (s *selectScope) resolve(ref string) string {
+	if qualifier, column, found := strings.Cut(ref, "."); found {
+		if strings.EqualFold(qualifier, s.qualifier) {
+			return column
+		}
+		return ref
+	}
+	for alias, column := range s.aliases {
+		if strings.EqualFold(alias, ref) {
+			return column
+		}
+	}
+	return ref
+}
+
+// parseResultColumn parses an item of a SELECT list: *, column,
+// table.column, or a call of a conditional function such as
+// COALESCE(a, b), each optionally followed by [AS] alias. from lists the
+// tables of the query; with several, a qualified column keeps its table.
+func parseResultColumn(item string, from []storage.JoinTable) (storage.ResultColumn, error) {
+	fields := sqlFields(item)
+	var rc storage.ResultColumn
+	var expr *ValueExpr
+	if call, rest, ok := cutCall(item); ok {
+		var err error
+		if expr, err = ParseValueExpr(call); err != nil {
+			return rc, err
+		}
+		fields = append([]string{call}, sqlFields(rest)...)
+	}
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 2 && !strings.EqualFold(fields[1], "AS"):
+		rc.As = fields[1]
+	case len(fields) == 3 && strings.EqualFold(fields[1], "AS"):
+		rc.As = fields[2]
+	default:
+		return rc, fmt.Errorf("invalid result column %s (expected: column [AS alias])", strings.TrimSpace(item))
+	}
+	if rc.As != "" {
+		alias, err := storage.UnquoteIdentifier(rc.As)
+		if err != nil || alias == "" {
+			return rc, fmt.Errorf("invalid alias %s", rc.As)
+		}
+		rc.As = alias
+	}
+	if expr != nil {
+		rc.Name, rc.Expr = expr.Label(), expr
+		return rc, nil
+	}
+
+	ref := fields[0]
+	if qual, column, found := cutQualifier(ref); found {
+		name, err := storage.UnquoteIdentifier(qual)
+		if err != nil {
+			return rc, err
+		}
+		known := false
+		for _, jt := range from {
+			known = known || strings.EqualFold(name, jt.Qualifier)
+		}
+		if !known {
+			return rc, fmt.Errorf("unknown table %s in %s", qual, ref)
+		}
+		if len(from) > 1 {
+			rc.Table = name
+		}
+		ref = column
+	}
+	if ref == "*" {
+		if rc.As != "" {
+			return rc, fmt.Errorf("* cannot have an alias")
+		}
+		rc.Name = "*"
+		return rc, nil
+	}
+	name, err := storage.UnquoteIdentifier(ref)
+	if err != nil {
+		return rc, err
+	}
+	if name == "" {
+		return rc, fmt.Errorf("invalid result column %s", strings.TrimSpace(item))
+	}
+	rc.Name = name
+	return rc, nil
+}
+
+// cutCall splits a select list item that starts with a call of a
+// conditional function into the call and the rest of the item
+func cutCall(item string) (string, string, bool) {
+	item = strings.TrimSpace(item)
+	open := strings.Index(item, "(")
+	if open < 0 {
+		return "", "", false
+	}
+	if _, ok := valueFuncs[strings.ToUpper(strings.TrimSpace(item[:open]))]; !ok {
+		return "", "", false
+	}
+	var quote rune
+	depth := 0
+	for i, r := range item[open:] {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth == 0 {
+				end := open + i + 1
+				return item[:end], item[end:], true
+			}
+		}
+	}
+	// Unclosed; ParseValueExpr reports it
+	return item, "", true
+}
+
+// cutQualifier splits table.column at the first dot outside double quotes
+func cutQualifier(ref string) (string, string, bool) {
+	inQuote := false
+	for i, r := range ref {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == '.' && !inQuote:
+			return ref[:i], ref[i+1:], true
+		}
+	}
+	return ref, "", false
+}
+
+// parseSelect parses SELECT [DISTINCT] * | column [AS alias], ... FROM table
+// [[AS] alias] [WHERE ...] [ORDER BY col [DESC], ...] [LIMIT n] into a table name and query,
+// or returns an error message. WHERE and ORDER BY may refer to columns by
+// their result alias or as alias.column.
+func parseSelect(input string) (string, storage.Query, string) {
+	from, query, msg := parseSelectFrom(input)
+	if msg != "" {
+		return "", storage.Query{}, msg
+	}
+	if len(from) > 1 {
+		return "", storage.Query{}, "Syntax error: expected a single table after FROM"
+	}
+	return from[0].Name, query, ""
+}
+
+// parseSelectFrom parses a SELECT like parseSelect, allowing several tables
+// separated by commas or CROSS JOIN in FROM (see storage.SelectJoin)
+func parseSelectFrom(input string) ([]storage.JoinTable, storage.Query, string) {
+	parts := sqlFields(input)
+	first := 1
+	distinct := len(parts) > 1 && strings.EqualFold(parts[1], "DISTINCT")
+	if distinct {
+		first = 2
+	}
+	fromIdx := -1
+	for i := first; i < len(parts); i++ {
+		if strings.EqualFold(parts[i], "FROM") {
+			fromIdx = i
+			break
+		}
+	}
+	if len(parts) < first+3 || !strings.EqualFold(parts[0], "SELECT") || fromIdx <= first || fromIdx == len(parts)-1 {
+		return nil, storage.Query{}, ErrSyntaxError
+	}
+	from, rest, err := parseFromList(parts[fromIdx+1:])
+	if err != nil {
+		return nil, storage.Query{}, fmt.Sprintf("Syntax error: %v", err)
+	}
+
+	// With one table, alias.column names its column; with several, the
+	// tables resolve qualified names themselves
+	scope := selectScope{aliases: map[string]string{}}
+	if len(from) == 1 {
+		scope.qualifier = from[0].Qualifier
+	}
+
+	var columns []storage.ResultColumn
+	for _, item := range splitIdentifiers(strings.Join(parts[first:fromIdx], " ")) {
+		rc, err := parseResultColumn(item, from)
+		if err != nil {
+			return nil, storage.Query{}, fmt.Sprintf("Syntax error: %v", err)
+		}
+		if expr, ok := rc.Expr.(*ValueExpr); ok {
+			expr.ResolveColumns(scope.resolve)
+		}
+		if rc.As != "" && rc.Expr == nil {
+			scope.aliases[rc.As] = rc.Name
+			if rc.Table != "" {
+				scope.aliases[rc.As] = rc.Table + "." + rc.Name
+			}
+		}
+		columns = append(columns, rc)
+	}
+	if len(columns) == 1 && columns[0].Name == "*" && columns[0].Table == "" {
+		columns = nil
+	}
+
+	clauses, err := parseSelectClauses(rest)
+	if err != nil {
+		return nil, storage.Query{}, fmt.Sprintf("Syntax error: %v", err)
+	}
+	if clauses.group != nil {
+		return nil, storage.Query{}, "Syntax error: GROUP BY is not supported here"
+	}
+
+	query := storage.Query{Columns: columns, Limit: clauses.limit, Distinct: distinct}
+	for i, key := range clauses.order {
+		key.Column = scope.resolve(key.Column)
+		if i == 0 {
+			query.OrderBy, query.Desc = key.Column, key.Desc
+		} else {
+			query.ThenBy = append(query.ThenBy, key)
+		}
+	}
+	if clauses.where != "" {
+		// Parse advanced WHERE clause
+		whereExpr, err := ParseWhereClause(clauses.where)
+		if err != nil {
+			return nil, storage.Query{}, fmt.Sprintf("WHERE clause error: %v", err)
+		}
+		whereExpr.ResolveColumns(scope.resolve)
+		query.Where = whereExpr
+	}
+	return from, query, ""
+}
+
+// handleSelectRowID handles SELECT ROWID, * FROM table ..., which adds each
+// row's ID as a leading rowid column
+func (e *Engine) handleSelectRowID(input string) (*storage.ResultSet, string) {
+	rest := strings.TrimSpace(input[len("SELECT"):])
+	rest = strings.TrimSpace(rest[len("ROWID"):])
+	if !strings.HasPrefix(rest, ",") {
+		return nil, "Syntax error: SELECT ROWID, * FROM table ..."
+	}
+	tableName, query, msg := parseSelect("SELECT " + strings.TrimSpace(rest[1:]))
+	if msg != "" {
+		return nil, msg
+	}
+	query.RowIDs = true
+	if query.Role, msg = e.maskRole(); msg != "" {
+		return nil, msg
+	}
+	return e.DB.SelectQueryResult(tableName, query)
+}
+
+// handleExplain handles EXPLAIN SELECT ... FROM table ..., showing how the
+// query would read its rows without running it
+func (e *Engine) handleExplain(input string) (*storage.ResultSet, string) {
+	stmt := strings.TrimSpace(input[len("EXPLAIN"):])
+	if !strings.HasPrefix(strings.ToUpper(stmt), "SELECT ") {
+		return nil, "Syntax error: EXPLAIN SELECT ... FROM table [WHERE ...] [ORDER BY col [DESC], ...] [LIMIT n]"
+	}
+	tableName, query, msg := parseSelect(stmt)
+	if msg != "" {
+		return nil, msg
+	}
+	return e.DB.ExplainResult(tableName, query)
+}
+
+// handleAnalyze handles ANALYZE [table], collecting the statistics the
+// planner uses to decide between index lookups and scans
+func (e *Engine) handleAnalyze(input string) string {
+	parts := sqlFields(input)
+	switch len(parts) {
+	case 1:
+		return e.DB.Analyze("")
+	case 2:
+		tableName, err := parseTableName(parts[1])
+		if err != nil {
+			return fmt.Sprintf("Syntax error: %v", err)
+		}
+		return e.DB.Analyze(tableName)
+	default:
+		return "Syntax error: ANALYZE [table]"
+	}
+}
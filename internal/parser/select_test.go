@@ -0,0 +1,311 @@
+// internal/parser/select_test.go
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestParseSelectClauses(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    selectClauses
+		expectError bool
+	}{
+		{input: "", expected: selectClauses{limit: -1}},
+		{input: "WHERE age > 18", expected: selectClauses{where: "age > 18", limit: -1}},
+		{input: "ORDER BY age DESC", expected: selectClauses{order: []storage.SortKey{{Column: "age", Desc: true}}, limit: -1}},
+		{input: "WHERE name LIKE 'A%' ORDER BY age ASC LIMIT 5", expected: selectClauses{where: "name LIKE 'A%'", order: []storage.SortKey{{Column: "age"}}, limit: 5}},
+		{input: "ORDER BY city, age DESC, name ASC", expected: selectClauses{order: []storage.SortKey{{Column: "city"}, {Column: "age", Desc: true}, {Column: "name"}}, limit: -1}},
+		{input: "ORDER BY city DESC,age", expected: selectClauses{order: []storage.SortKey{{Column: "city", Desc: true}, {Column: "age"}}, limit: -1}},
+		{input: "WHERE age > 18 GROUP BY city, \"Zip Code\" ORDER BY city LIMIT 3", expected: selectClauses{where: "age > 18", group: []string{"city", "Zip Code"}, order: []storage.SortKey{{Column: "city"}}, limit: 3}},
+		{input: "limit 0", expected: selectClauses{limit: 0}},
+		{input: "GROUP BY", expectError: true},
+		{input: "GROUP BY city DESC", expectError: true},
+		{input: "LIMIT -1", expectError: true},
+		{input: "LIMIT 5 ORDER BY age", expectError: true},
+		{input: "ORDER BY", expectError: true},
+		{input: "ORDER BY age sideways", expectError: true},
+		{input: "ORDER BY age,", expectError: true},
+		{input: "garbage", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			clauses, err := parseSelectClauses(strings.Fields(tt.input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %+v", clauses)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(clauses, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, clauses)
+			}
+		})
+	}
+}
+
+func TestSelectOrderByLimit(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE products (name, price)")
+	for _, values := range []string{"('Laptop', '999.99')", "('Mouse', '29.99')", "('Desk', '250')", "('Cable', '5')"} {
+		engine.Execute("INSERT INTO products VALUES " + values)
+	}
+
+	expect := func(query, want string) {
+		t.Helper()
+		result := engine.Execute(query)
+		var names []string
+		for _, line := range strings.Split(strings.TrimSpace(result), "\n")[1:] {
+			names = append(names, strings.Split(line, " | ")[0])
+		}
+		if got := strings.Join(names, ","); got != want {
+			t.Errorf("%s\n got %s, want %s", query, got, want)
+		}
+	}
+
+	expect("SELECT * FROM products ORDER BY price", "Cable,Mouse,Desk,Laptop")
+	expect("SELECT * FROM products ORDER BY price DESC LIMIT 2", "Laptop,Desk")
+	expect("SELECT * FROM products WHERE price > 10 ORDER BY name LIMIT 2;", "Desk,Laptop")
+	expect("SELECT * FROM products LIMIT 1", "Laptop")
+
+	engine.Execute("CREATE INDEX ON products (price)")
+	expect("SELECT * FROM products ORDER BY price DESC LIMIT 3", "Laptop,Desk,Mouse")
+
+	// Later columns break ties, each in its own direction
+	engine.Execute("INSERT INTO products VALUES ('Pen', '5')")
+	engine.Execute("INSERT INTO products VALUES ('Lamp', '29.99')")
+	expect("SELECT * FROM products ORDER BY price, name DESC", "Pen,Cable,Mouse,Lamp,Desk,Laptop")
+	expect("SELECT * FROM products ORDER BY price DESC, name LIMIT 4", "Laptop,Desk,Lamp,Mouse")
+	expect("SELECT * FROM products p WHERE p.price < 100 ORDER BY p.price ASC,p.name", "Cable,Pen,Lamp,Mouse")
+	if result := engine.Execute("SELECT * FROM products ORDER BY price, missing"); !strings.Contains(result, "missing not found") {
+		t.Errorf("expected missing column error, got %s", result)
+	}
+
+	if result := engine.Execute("SELECT * FROM products LIMIT many"); !strings.HasPrefix(result, "Syntax error") {
+		t.Errorf("expected syntax error, got %s", result)
+	}
+}
+
+func TestSelectCount(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, age)")
+	engine.Execute("INSERT INTO users VALUES (1, 20)")
+	engine.Execute("INSERT INTO users VALUES (2, 30)")
+	engine.Execute("INSERT INTO users VALUES (3, 40)")
+
+	if result := engine.Execute("SELECT COUNT(*) FROM users"); result != "count\n3\n" {
+		t.Errorf("unexpected count: %q", result)
+	}
+	if result := engine.Execute("select count(*) from users where age > 25;"); result != "count\n2\n" {
+		t.Errorf("unexpected filtered count: %q", result)
+	}
+	if result := engine.Execute("SELECT COUNT(*) FROM missing"); !strings.Contains(result, "not found") {
+		t.Errorf("expected missing table error, got %q", result)
+	}
+
+	// Queued inserts and deletes are visible inside the transaction only
+	engine.Execute("BEGIN TRANSACTION")
+	engine.Execute("INSERT INTO users VALUES (4, 50)")
+	engine.Execute("INSERT INTO users VALUES (5, 60)")
+	engine.Execute("DELETE FROM users ROW 0")
+	if result := engine.Execute("SELECT COUNT(*) FROM users"); result != "count\n4\n" {
+		t.Errorf("expected count to include pending changes, got %q", result)
+	}
+	if rows := engine.Execute("SELECT * FROM users"); !strings.Contains(rows, "5 | 60") {
+		t.Errorf("expected pending insert in transaction view, got %s", rows)
+	}
+	engine.Execute("ROLLBACK")
+	if result := engine.Execute("SELECT COUNT(*) FROM users"); result != "count\n3\n" {
+		t.Errorf("expected rollback to restore count, got %q", result)
+	}
+}
+
+func TestKeysetPagination(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE events (id, kind)")
+	for i := 1; i <= 60; i++ {
+		engine.Execute(fmt.Sprintf("INSERT INTO events VALUES ('%d', 'k%d')", i, i%3))
+	}
+	engine.Execute("CREATE INDEX ON events (id)")
+
+	ids := func(result string) []string {
+		var out []string
+		for _, line := range strings.Split(strings.TrimSpace(result), "\n")[1:] {
+			out = append(out, strings.Split(line, " | ")[0])
+		}
+		return out
+	}
+
+	// Page forwards with the last id of each page, then backwards
+	var seen []string
+	last := "0"
+	for page := 0; page < 10; page++ {
+		result := engine.Execute(fmt.Sprintf("SELECT * FROM events WHERE (id) > (%s) AND kind != 'k0' ORDER BY id LIMIT 7", last))
+		if strings.Contains(result, "(no rows)") {
+			break
+		}
+		got := ids(result)
+		seen = append(seen, got...)
+		last = got[len(got)-1]
+	}
+	if len(seen) != 40 || seen[0] != "1" || seen[39] != "59" {
+		t.Fatalf("forward pages returned %d rows: %v", len(seen), seen)
+	}
+	if got := ids(engine.Execute("SELECT * FROM events WHERE id < 10 ORDER BY id DESC LIMIT 3")); strings.Join(got, ",") != "9,8,7" {
+		t.Errorf("backward page: %v", got)
+	}
+
+	// Row comparisons compare the columns in order
+	got := ids(engine.Execute("SELECT * FROM events WHERE (kind, id) > ('k1', 55) ORDER BY id"))
+	if strings.Join(got, ",") != "2,5,8,11,14,17,20,23,26,29,32,35,38,41,44,47,50,53,56,58,59" {
+		t.Errorf("row comparison: %v", got)
+	}
+	if result := engine.Execute("SELECT * FROM events WHERE (kind, id) > ('k1') ORDER BY id"); !strings.HasPrefix(result, "WHERE clause error") {
+		t.Errorf("mismatched row comparison: %s", result)
+	}
+}
+
+func TestAnalyzeAndExplain(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE tasks (id, state)")
+	for i := 1; i <= 20; i++ {
+		state := "done"
+		if i == 7 {
+			state = "open"
+		}
+		engine.Execute(fmt.Sprintf("INSERT INTO tasks VALUES ('%d', '%s')", i, state))
+	}
+	engine.Execute("CREATE INDEX ON tasks (state)")
+
+	if got := engine.Execute("ANALYZE tasks"); got != "Table tasks analyzed (20 rows)" {
+		t.Fatalf("ANALYZE: %s", got)
+	}
+	if got := engine.Execute("EXPLAIN SELECT * FROM tasks WHERE state = 'open'"); got != "plan\nIndex Lookup on tasks using state (estimated 5.0% of 20 rows)\n" {
+		t.Errorf("selective: %q", got)
+	}
+	if got := engine.Execute("EXPLAIN SELECT * FROM tasks WHERE state = 'done' AND id > 3"); !strings.Contains(got, "Seq Scan on tasks (index on state skipped: estimated 95.0% of 20 rows match)") {
+		t.Errorf("unselective: %q", got)
+	}
+	if got := engine.Execute("EXPLAIN SELECT * FROM tasks WHERE state = 'open' OR id = 1"); got != "plan\nSeq Scan on tasks\n" {
+		t.Errorf("OR: %q", got)
+	}
+	if got := engine.Execute("SELECT * FROM tasks WHERE state = 'open'"); got != "id | state\n7 | open\n" {
+		t.Errorf("indexed select: %q", got)
+	}
+	if got := engine.Execute("EXPLAIN DELETE FROM tasks ROW 1"); !strings.HasPrefix(got, "Syntax error") {
+		t.Errorf("EXPLAIN of a non-SELECT: %s", got)
+	}
+	if got := engine.Execute("ANALYZE missing"); got != "Table missing not found" {
+		t.Errorf("ANALYZE missing table: %s", got)
+	}
+}
+
+func TestRowIDTargeting(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (name, role)")
+	for _, name := range []string{"ann", "bob", "cid"} {
+		engine.Execute(fmt.Sprintf("INSERT INTO users VALUES ('%s', 'user')", name))
+	}
+
+	engine.Execute("DELETE FROM users ROWID 1")
+	engine.Execute("UPDATE users SET role = 'admin' ROWID 3")
+	if got := engine.Execute("SELECT ROWID, * FROM users"); got != "rowid | name | role\n2 | bob | user\n3 | cid | admin\n" {
+		t.Errorf("rows by ID: %q", got)
+	}
+	if got := engine.Execute("SELECT ROWID, * FROM users WHERE role = 'admin'"); got != "rowid | name | role\n3 | cid | admin\n" {
+		t.Errorf("filtered rows by ID: %q", got)
+	}
+	if got := engine.Execute("DELETE FROM users ROWID 1"); got != "Row ID 1 not found" {
+		t.Errorf("delete of a deleted ID: %q", got)
+	}
+	if got := engine.Execute("UPDATE users SET role = 'x' ROWID"); !strings.HasPrefix(got, "Syntax error") {
+		t.Errorf("missing ID: %q", got)
+	}
+	if got := engine.Execute("SELECT ROWID * FROM users"); !strings.HasPrefix(got, "Syntax error") {
+		t.Errorf("missing comma: %q", got)
+	}
+}
+
+func TestSelectAliases(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name, email)")
+	engine.Execute("INSERT INTO users VALUES (1, 'Alice', 'a@x')")
+	engine.Execute("INSERT INTO users VALUES (2, 'Bob', 'b@x')")
+	engine.Execute("INSERT INTO users VALUES (3, 'Carol', 'c@x')")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT name AS n FROM users u", "n\nAlice\nBob\nCarol\n"},
+		{"SELECT u.name AS n, u.id FROM users AS u WHERE n = 'Bob'", "n | id\nBob | 2\n"},
+		{"SELECT name n, id FROM users u WHERE u.id > 1 ORDER BY n DESC LIMIT 1", "n | id\nCarol | 3\n"},
+		{"SELECT users.email, \"NAME\" AS \"Full Name\" FROM users WHERE users.id = 1", "email | Full Name\na@x | Alice\n"},
+		{"SELECT u.* FROM users u WHERE u.id = 2", "id | name | email\n2 | Bob | b@x\n"},
+		{"SELECT *, name AS n FROM users LIMIT 1", "id | name | email | n\n1 | Alice | a@x | Alice\n"},
+		{"SELECT * FROM users u ORDER BY u.name DESC LIMIT 1", "id | name | email\n3 | Carol | c@x\n"},
+		{"SELECT ROWID, name AS who FROM users u WHERE who = 'Alice'", "rowid | who\n1 | Alice\n"},
+		{"SELECT missing FROM users", "Column missing not found"},
+		{"SELECT x.email FROM users u", "Syntax error: unknown table x in x.email"},
+		{"SELECT * AS x FROM users", "Syntax error: * cannot have an alias"},
+		{"SELECT FROM users", ErrSyntaxError},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.query); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.query, got, tt.want)
+		}
+	}
+
+	engine.Execute("DECLARE c CURSOR FOR SELECT name AS n FROM users u WHERE u.id >= 2")
+	if got := engine.Execute("FETCH ALL FROM c"); got != "n\nBob\nCarol\n" {
+		t.Errorf("cursor over aliased columns: %q", got)
+	}
+}
+
+func TestSelectDistinct(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE orders (id, customer, status)")
+	engine.Execute("INSERT INTO orders VALUES (1, 'alice', 'open')")
+	engine.Execute("INSERT INTO orders VALUES (2, 'bob', 'open')")
+	engine.Execute("INSERT INTO orders VALUES (3, 'alice', 'open')")
+	engine.Execute("INSERT INTO orders VALUES (4, 'alice', 'shipped')")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT DISTINCT customer FROM orders", "customer\nalice\nbob\n"},
+		{"select distinct customer, status AS s FROM orders o WHERE o.id > 1", "customer | s\nbob | open\nalice | open\nalice | shipped\n"},
+		{"SELECT DISTINCT status FROM orders ORDER BY status DESC LIMIT 1", "status\nshipped\n"},
+		{"SELECT DISTINCT status FROM orders LIMIT 2", "status\nopen\nshipped\n"},
+		{"SELECT DISTINCT * FROM orders WHERE customer = 'alice' LIMIT 1", "id | customer | status\n1 | alice | open\n"},
+		{"SELECT DISTINCT FROM orders", ErrSyntaxError},
+		{"EXPLAIN SELECT DISTINCT customer FROM orders", "plan\nDistinct\nSeq Scan on orders\n"},
+	}
+	for _, tt := range tests {
+		if got := engine.Execute(tt.query); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.query, got, tt.want)
+		}
+	}
+
+	engine.Execute("DECLARE c CURSOR FOR SELECT DISTINCT customer FROM orders")
+	if got := engine.Execute("FETCH ALL FROM c"); got != "customer\nalice\nbob\n" {
+		t.Errorf("distinct cursor: %q", got)
+	}
+}
@@ -0,0 +1,135 @@
+// internal/parser/serverinfo.go
+package parser
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// ServerInfo describes the running server for SELECT VERSION() and
+// SHOW SERVER INFO
+type ServerInfo struct {
+	Version string
+	// Commit is the VCS revision the binary was built from
+	Commit  string
+	Started time.Time
+	// TLS is set when any listen address is served with TLS
+	TLS bool
+	// Listen lists the addresses the server accepts connections on
+	Listen []string
+}
+
+// newServerInfo returns the info known without the server's configuration
+func newServerInfo() ServerInfo {
+	return ServerInfo{Version: "dev", Commit: buildCommit(), Started: time.Now()}
+}
+
+// buildCommit returns the revision recorded by the Go toolchain, marked
+// -dirty for builds with uncommitted changes
+func buildCommit() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision == "" {
+		return "unknown"
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// handleVersion handles SELECT VERSION()
+func (e *Engine) handleVersion(input string) (*storage.ResultSet, string) {
+	if !strings.EqualFold(strings.Join(strings.Fields(input), ""), "SELECTVERSION()") {
+		return nil, "Syntax error: SELECT VERSION()"
+	}
+	return &storage.ResultSet{Columns: []string{"version"}, Rows: [][]string{{"HaruDB " + e.Info.Version}}}, ""
+}
+
+// handleShowServerInfo handles SHOW SERVER INFO
+func (e *Engine) handleShowServerInfo(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 3 {
+		return nil, "Syntax error: SHOW SERVER INFO"
+	}
+	tls := "off"
+	if e.Info.TLS {
+		tls = "on"
+	}
+	encryptTables := "off"
+	if e.DB.EncryptTables {
+		encryptTables = "on"
+	}
+	size := e.DB.DatabaseSize()
+	return nameValues([][2]string{
+		{"version", e.Info.Version},
+		{"commit", e.Info.Commit},
+		{"go_version", runtime.Version()},
+		{"uptime", time.Since(e.Info.Started).Round(time.Second).String()},
+		{"data_directory", e.DB.DataDir},
+		{"storage_engine", e.DB.StorageMode.String()},
+		{"tls", tls},
+		{"encrypt_tables", encryptTables},
+		{"listen", strings.Join(e.Info.Listen, ", ")},
+		{"tables", strconv.Itoa(size.Tables)},
+		{"rows", strconv.FormatInt(size.Rows, 10)},
+		{"data_bytes", strconv.FormatInt(size.Bytes, 10)},
+		{"indexes", strconv.Itoa(size.Indexes)},
+		{"write_queue_depth", strconv.Itoa(e.Writes.Depth())},
+		{"writes_in_flight", strconv.Itoa(e.Writes.InFlight())},
+		{"writes_waiting", strconv.Itoa(e.Writes.Waiting())},
+		{"query_slots", strconv.Itoa(e.Scheduler.Slots())},
+		{"queries_running", strconv.Itoa(e.Scheduler.Running())},
+		{"queries_waiting_high", strconv.Itoa(e.Scheduler.Waiting(PriorityHigh))},
+		{"queries_waiting_normal", strconv.Itoa(e.Scheduler.Waiting(PriorityNormal))},
+		{"queries_waiting_low", strconv.Itoa(e.Scheduler.Waiting(PriorityLow))},
+	}), ""
+}
+
+// handleShowStatus handles SHOW STATUS
+func (e *Engine) handleShowStatus(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 2 {
+		return nil, "Syntax error: SHOW STATUS"
+	}
+	status, wal, writes := "ok", "available", "accepted"
+	if err := e.DB.WALError(); err != nil {
+		status, wal = "degraded", "unavailable: "+err.Error()
+		writes = "accepted with warning"
+		if e.DB.WALFailurePolicy != storage.WALFailWarn {
+			writes = "rejected"
+		}
+	}
+	diskFree := "unknown"
+	if free, err := e.DB.DiskFree(); err == nil {
+		diskFree = strconv.FormatInt(free, 10)
+	}
+	if e.DB.LowDiskSpace() {
+		status, writes = "degraded", "rejected (low disk space)"
+	}
+	if e.ReadOnly {
+		writes = "rejected (read-only replica)"
+	}
+	return nameValues([][2]string{
+		{"status", status},
+		{"wal", wal},
+		{"wal_failure_policy", e.DB.WALFailurePolicy.String()},
+		{"writes", writes},
+		{"disk_free_bytes", diskFree},
+		{"min_free_bytes", strconv.FormatInt(e.DB.MinFreeBytes, 10)},
+	}), ""
+}
@@ -0,0 +1,147 @@
+// internal/parser/serverinfo_test.go
+package parser
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+func TestServerInfo(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Info.Version = "v9.9.9"
+
+	// The version is available before logging in so clients can check it
+	if got := engine.Execute("select version();"); got != "version\nHaruDB v9.9.9\n" {
+		t.Errorf("unexpected version: %q", got)
+	}
+	if got, err := engine.Exec("SHOW SERVER INFO"); err == nil {
+		t.Errorf("server info should require login, got %s", got)
+	}
+
+	engine.Execute("LOGIN admin admin123")
+	info := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(engine.Execute("SHOW SERVER INFO")), "\n")[1:] {
+		name, value, _ := strings.Cut(line, " | ")
+		info[name] = value
+	}
+	for name, want := range map[string]string{
+		"version":        "v9.9.9",
+		"data_directory": dir,
+		"storage_engine": "hybrid (json + page)",
+		"tls":            "off",
+	} {
+		if info[name] != want {
+			t.Errorf("%s: got %q, want %q", name, info[name], want)
+		}
+	}
+	for _, name := range []string{"commit", "uptime", "go_version"} {
+		if info[name] == "" {
+			t.Errorf("%s is missing", name)
+		}
+	}
+
+	engine.Execute("SET output_format = json")
+	if got := engine.Execute("SELECT VERSION()"); got != `[{"version":"HaruDB v9.9.9"}]`+"\n" {
+		t.Errorf("VERSION() should follow output_format, got %s", got)
+	}
+	if got, err := engine.Exec("SELECT VERSION() FROM users"); err == nil {
+		t.Errorf("expected syntax error, got %s", got)
+	}
+}
+
+func TestDegradedWAL(t *testing.T) {
+	dir := testDataDir(t)
+	// A directory in the WAL's place keeps it from being opened
+	if err := os.Mkdir(filepath.Join(dir, "wal.log"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+	if !engine.DB.Degraded() {
+		t.Fatal("database should be degraded without a WAL")
+	}
+
+	status := func() map[string]string {
+		rows := make(map[string]string)
+		for _, line := range strings.Split(strings.TrimSpace(engine.Execute("SHOW STATUS")), "\n")[1:] {
+			name, value, _ := strings.Cut(line, " | ")
+			rows[name] = value
+		}
+		return rows
+	}
+	if st := status(); st["status"] != "degraded" || !strings.HasPrefix(st["wal"], "unavailable: ") ||
+		st["wal_failure_policy"] != "warn" || st["writes"] != "accepted with warning" {
+		t.Errorf("unexpected status: %v", st)
+	}
+
+	// The default policy accepts writes and warns with each one
+	got := engine.Execute("CREATE TABLE t (a)")
+	if !strings.HasPrefix(got, "Table t created") || !strings.HasSuffix(got, storage.WarnWALUnavailable) {
+		t.Errorf("expected a warning with the write, got %q", got)
+	}
+	if got := engine.Execute("INSERT INTO t VALUES (1)"); !strings.HasSuffix(got, storage.WarnWALUnavailable) {
+		t.Errorf("expected a warning with the write, got %q", got)
+	}
+	if got := engine.Execute("SELECT * FROM t"); strings.Contains(got, "Warning") {
+		t.Errorf("reads should not warn, got %q", got)
+	}
+
+	engine.DB.WALFailurePolicy = storage.WALFailReadOnly
+	if got := engine.Execute("INSERT INTO t VALUES (2)"); !strings.HasPrefix(got, "Error: WAL is unavailable") {
+		t.Errorf("expected the write to be rejected, got %q", got)
+	}
+	if got := engine.Execute("SELECT * FROM t"); strings.Count(got, "\n") != 2 {
+		t.Errorf("expected one row, got %q", got)
+	}
+	if st := status(); st["writes"] != "rejected" {
+		t.Errorf("unexpected status: %v", st)
+	}
+
+	healthy := NewEngine(testDataDir(t))
+	healthy.Execute("LOGIN admin admin123")
+	if got := healthy.Execute("SHOW STATUS"); !strings.Contains(got, "status | ok") || !strings.Contains(got, "wal | available") {
+		t.Errorf("unexpected status: %q", got)
+	}
+}
+
+func TestLowDiskSpace(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE t (a)")
+	engine.Execute("INSERT INTO t VALUES (1)")
+	engine.Execute("INSERT INTO t VALUES (2)")
+	if _, err := engine.DB.DiskFree(); err != nil {
+		t.Skip(err)
+	}
+
+	engine.DB.MinFreeBytes = math.MaxInt64
+	for _, stmt := range []string{"INSERT INTO t VALUES (3)", "UPDATE t SET a = 9 ROW 0", "CREATE TABLE u (a)"} {
+		if _, err := engine.Exec(stmt); err == nil || !strings.HasPrefix(err.Error(), "Error: only ") {
+			t.Errorf("%s: expected the write to be refused, got %v", stmt, err)
+		}
+	}
+	// Reads and writes that free space still run
+	if _, err := engine.Exec("DELETE FROM t ROW 0"); err != nil {
+		t.Errorf("DELETE should run, err %q", err)
+	}
+	if got := engine.Execute("SELECT * FROM t"); !strings.Contains(got, "2") {
+		t.Errorf("SELECT should run, got %q", got)
+	}
+	if _, err := engine.Exec("CHECKPOINT"); err != nil {
+		t.Errorf("CHECKPOINT should run, err %q", err)
+	}
+	if got := engine.Execute("SHOW STATUS"); !strings.Contains(got, "writes | rejected (low disk space)") {
+		t.Errorf("unexpected status: %q", got)
+	}
+
+	engine.DB.MinFreeBytes = 0
+	if _, err := engine.Exec("INSERT INTO t VALUES (3)"); err != nil {
+		t.Errorf("INSERT should run once space is available, err %q", err)
+	}
+}
@@ -0,0 +1,269 @@
+// internal/parser/session.go
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// DefaultStatementTimeout bounds a statement when the session has not SET
+// statement_timeout
+const DefaultStatementTimeout = 10 * time.Second
+
+// sessionSetting describes a variable that SET and SHOW accept
+type sessionSetting struct {
+	def string
+	// normalize validates a value and returns its canonical form
+	normalize func(string) (string, error)
+	// apply, when set, acts on a new value as soon as it is SET
+	apply func(e *Engine, value string)
+}
+
+// sessionSettings lists the per-session variables
+var sessionSettings = map[string]sessionSetting{
+	// output_format controls how SELECT results are rendered
+	"output_format": {def: "text", normalize: oneOf("text", "csv", "json")},
+	// statement_timeout bounds how long a statement may run; 0 disables it
+	"statement_timeout": {def: DefaultStatementTimeout.String(), normalize: normalizeTimeout},
+	// default_transaction_isolation applies to BEGIN without ISOLATION LEVEL
+	"default_transaction_isolation": {def: "read committed", normalize: normalizeIsolation},
+	// bulk_load defers index maintenance until it is turned off, when each
+	// written table's indexes are rebuilt once
+	"bulk_load": {def: "off", normalize: oneOf("on", "off"), apply: applyBulkLoad},
+	// write_queue_policy decides whether a write blocks or fails when the
+	// write queue is full
+	"write_queue_policy": {def: "block", normalize: oneOf("block", "error")},
+	// query_priority lowers the priority class of the session's statements
+	// below that of its role (see scheduler.go)
+	"query_priority": {def: "default", normalize: oneOf("default", "high", "normal", "low")},
+	// bulk_batch_size is how many rows an UPDATE or DELETE with a WHERE
+	// clause changes per batch (see bulk.go)
+	"bulk_batch_size": {def: strconv.Itoa(DefaultBulkBatchSize), normalize: normalizeBatchSize},
+	// bulk_progress_channel receives a notification after each such batch;
+	// empty sends none
+	"bulk_progress_channel": {def: "", normalize: normalizeProgressChannel},
+}
+
+// applyBulkLoad switches the database's bulk load mode
+func applyBulkLoad(e *Engine, value string) {
+	e.DB.SetBulkLoad(value == "on")
+}
+
+// isolationLevels maps isolation level names to storage levels
+var isolationLevels = map[string]storage.IsolationLevel{
+	"read uncommitted": storage.ReadUncommitted,
+	"read committed":   storage.ReadCommitted,
+	"repeatable read":  storage.RepeatableRead,
+	"serializable":     storage.Serializable,
+}
+
+// oneOf accepts any of the given values, case-insensitively
+func oneOf(values ...string) func(string) (string, error) {
+	return func(v string) (string, error) {
+		v = strings.ToLower(v)
+		for _, allowed := range values {
+			if v == allowed {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+// normalizeTimeout accepts a duration such as 30s or a number of milliseconds
+func normalizeTimeout(v string) (string, error) {
+	if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+		return (time.Duration(ms) * time.Millisecond).String(), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return "", fmt.Errorf("must be a duration such as 30s or a number of milliseconds")
+	}
+	return d.String(), nil
+}
+
+// normalizeIsolation accepts an isolation level name
+func normalizeIsolation(v string) (string, error) {
+	v = strings.ToLower(strings.Join(strings.Fields(v), " "))
+	if _, ok := isolationLevels[v]; !ok {
+		return "", fmt.Errorf("must be read uncommitted, read committed, repeatable read or serializable")
+	}
+	return v, nil
+}
+
+// setting returns the session's value for a variable, or its default
+func (e *Engine) setting(name string) string {
+	if e.CurrentSession != nil {
+		if v, ok := e.CurrentSession.Setting(name); ok {
+			return v
+		}
+	}
+	return sessionSettings[name].def
+}
+
+// StatementTimeout returns how long the current session's statements may
+// run; 0 means no limit
+func (e *Engine) StatementTimeout() time.Duration {
+	d, err := time.ParseDuration(e.setting("statement_timeout"))
+	if err != nil {
+		return DefaultStatementTimeout
+	}
+	return d
+}
+
+// handleSet handles SET name = value, SET name TO value and
+// SET name = DEFAULT
+func (e *Engine) handleSet(input string) string {
+	const usage = "Syntax error: SET name = value"
+
+	rest := strings.TrimSpace(input[len("SET"):])
+	var name, value string
+	if i := strings.Index(rest, "="); i >= 0 {
+		name, value = rest[:i], rest[i+1:]
+	} else if fields := strings.Fields(rest); len(fields) >= 3 && strings.EqualFold(fields[1], "TO") {
+		name = fields[0]
+		value = strings.TrimSpace(rest[strings.Index(strings.ToUpper(rest), " TO ")+len(" TO "):])
+	} else {
+		return usage
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.Trim(strings.TrimSpace(value), "'\"")
+	if name == "" || value == "" {
+		return usage
+	}
+
+	s, ok := sessionSettings[name]
+	if !ok {
+		return fmt.Sprintf("Error: unknown setting %s", name)
+	}
+	if strings.EqualFold(value, "DEFAULT") {
+		e.CurrentSession.ResetSetting(name)
+		if s.apply != nil {
+			s.apply(e, s.def)
+		}
+		return fmt.Sprintf("SET %s = %s", name, s.def)
+	}
+	value, err := s.normalize(value)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid value for %s: %v", name, err)
+	}
+	e.CurrentSession.SetSetting(name, value)
+	if s.apply != nil {
+		s.apply(e, value)
+	}
+	return fmt.Sprintf("SET %s = %s", name, value)
+}
+
+// handleShowSetting handles SHOW name and SHOW ALL
+func (e *Engine) handleShowSetting(input string) string {
+	fields := strings.Fields(input)
+	if len(fields) != 2 {
+		return "Syntax error: SHOW name | SHOW ALL"
+	}
+	name := strings.ToLower(fields[1])
+	if name == "all" {
+		names := make([]string, 0, len(sessionSettings))
+		for n := range sessionSettings {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		b.WriteString("name | value\n")
+		for _, n := range names {
+			fmt.Fprintf(&b, "%s | %s\n", n, e.setting(n))
+		}
+		return b.String()
+	}
+	if _, ok := sessionSettings[name]; !ok {
+		return fmt.Sprintf("Error: unknown setting %s", name)
+	}
+	return fmt.Sprintf("%s\n%s\n", name, e.setting(name))
+}
+
+// formatResult renders a query result in the session's output_format
+func (e *Engine) formatResult(rs *storage.ResultSet) string {
+	switch e.setting("output_format") {
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(rs.Columns)
+		for _, row := range rs.Rows {
+			// NULL is an empty field
+			record := make([]string, len(row))
+			for i, v := range row {
+				if !storage.IsNull(v) {
+					record[i] = v
+				}
+			}
+			w.Write(record)
+		}
+		w.Flush()
+		return buf.String()
+	case "json":
+		// One object per row, keys in column order, NULL as null and
+		// values of numeric and boolean columns as JSON numbers and booleans
+		var buf bytes.Buffer
+		buf.WriteString("[")
+		for i, row := range rs.Rows {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("{")
+			for j, col := range rs.Columns {
+				if j > 0 {
+					buf.WriteString(",")
+				}
+				key, _ := json.Marshal(col)
+				buf.Write(key)
+				buf.WriteString(":")
+				if j < len(row) {
+					var ct storage.ColumnType
+					if j < len(rs.Types) {
+						ct = rs.Types[j]
+					}
+					buf.Write(jsonValue(row[j], ct))
+				} else {
+					buf.WriteString("null")
+				}
+			}
+			buf.WriteString("}")
+		}
+		buf.WriteString("]\n")
+		return buf.String()
+	}
+	return rs.String()
+}
+
+// jsonValue encodes a result value of a column of type ct. A value that is
+// not of the type, as a masked one may not be, is encoded as a string.
+func jsonValue(v string, ct storage.ColumnType) []byte {
+	switch {
+	case storage.IsNull(v):
+		return []byte("null")
+	case ct == storage.TypeInt || ct == storage.TypeFloat:
+		if _, err := strconv.ParseFloat(v, 64); err == nil && json.Valid([]byte(v)) {
+			return []byte(v)
+		}
+	case ct == storage.TypeBool && (v == "true" || v == "false"):
+		return []byte(v)
+	}
+	value, _ := json.Marshal(v)
+	return value
+}
+
+// nameValues returns a name | value result holding pairs
+func nameValues(pairs [][2]string) *storage.ResultSet {
+	rs := &storage.ResultSet{Columns: []string{"name", "value"}}
+	for _, kv := range pairs {
+		rs.Rows = append(rs.Rows, []string{kv[0], kv[1]})
+	}
+	return rs
+}
@@ -0,0 +1,171 @@
+// internal/parser/session_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionSettings(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	if result := engine.Execute("SET output_format = csv"); result != ErrNotAuthenticated {
+		t.Errorf("SET should require login, got %s", result)
+	}
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'Ann')")
+	engine.Execute("INSERT INTO users VALUES ('2', 'Bo \"B\"')")
+
+	t.Run("SetAndShow", func(t *testing.T) {
+		if got := engine.Execute("SHOW statement_timeout"); got != "statement_timeout\n10s\n" {
+			t.Errorf("unexpected default: %q", got)
+		}
+		if got := engine.Execute("SET statement_timeout TO 1500"); got != "SET statement_timeout = 1.5s" {
+			t.Errorf("unexpected SET result: %s", got)
+		}
+		if got := engine.StatementTimeout(); got != 1500*time.Millisecond {
+			t.Errorf("StatementTimeout = %v", got)
+		}
+		engine.Execute("SET statement_timeout = DEFAULT")
+		if got := engine.StatementTimeout(); got != DefaultStatementTimeout {
+			t.Errorf("DEFAULT should reset the timeout, got %v", got)
+		}
+
+		all := engine.Execute("SHOW ALL")
+		for _, want := range []string{"default_transaction_isolation | read committed", "output_format | text", "statement_timeout | 10s"} {
+			if !strings.Contains(all, want) {
+				t.Errorf("SHOW ALL missing %q:\n%s", want, all)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, stmt := range []string{"SET nope = 1", "SET output_format = xml", "SET statement_timeout = -5s", "SHOW nope", "SET output_format"} {
+			if result, err := engine.Exec(stmt); err == nil {
+				t.Errorf("%s: expected error, got %s", stmt, result)
+			}
+		}
+		if engine.Execute("SHOW REPLICATION STATUS") == "Error: unknown setting replication" {
+			t.Error("SHOW REPLICATION STATUS should not be treated as a setting")
+		}
+	})
+
+	t.Run("OutputFormat", func(t *testing.T) {
+		engine.Execute("SET output_format = 'csv'")
+		if got := engine.Execute("SELECT * FROM users ORDER BY id"); got != "id,name\n1,Ann\n2,\"Bo \"\"B\"\"\"\n" {
+			t.Errorf("unexpected csv: %q", got)
+		}
+		engine.Execute("SET output_format = JSON")
+		if got := engine.Execute("SELECT * FROM users WHERE id = '1'"); got != `[{"id":"1","name":"Ann"}]`+"\n" {
+			t.Errorf("unexpected json: %q", got)
+		}
+		if got := engine.Execute("SELECT COUNT(*) FROM users"); got != `[{"count":2}]`+"\n" {
+			t.Errorf("unexpected json count: %q", got)
+		}
+		if got := engine.Execute("SELECT * FROM users WHERE id = '9'"); got != "[]\n" {
+			t.Errorf("empty result should be an empty array, got %q", got)
+		}
+		if got, err := engine.Exec("SELECT * FROM missing"); err == nil {
+			t.Errorf("errors should not be reformatted, got %q", got)
+		}
+		engine.Execute("SET output_format = text")
+	})
+
+	t.Run("DefaultIsolation", func(t *testing.T) {
+		engine.Execute("SET default_transaction_isolation = 'REPEATABLE  read'")
+		if got := engine.Execute("SHOW default_transaction_isolation"); got != "default_transaction_isolation\nrepeatable read\n" {
+			t.Errorf("unexpected isolation: %q", got)
+		}
+		result := engine.Execute("BEGIN TRANSACTION")
+		if !strings.HasSuffix(result, "isolation level 2") {
+			t.Errorf("BEGIN should use the session default, got %s", result)
+		}
+		engine.Execute("ROLLBACK")
+	})
+
+	t.Run("ClearedOnLogout", func(t *testing.T) {
+		engine.Execute("SET output_format = csv")
+		engine.Execute("LOGOUT")
+		engine.Execute("LOGIN admin admin123")
+		if got := engine.Execute("SHOW output_format"); got != "output_format\ntext\n" {
+			t.Errorf("a new session should start with defaults, got %q", got)
+		}
+	})
+}
+
+func TestOutputFormatKeepsValues(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE notes (id, body)")
+	engine.Execute("INSERT INTO notes VALUES ('1', 'a | b')")
+	engine.Execute("INSERT INTO notes VALUES ('2', NULL)")
+
+	engine.Execute("SET output_format = csv")
+	if got := engine.Execute("SELECT * FROM notes ORDER BY id"); got != "id,body\n1,a | b\n2,\n" {
+		t.Errorf("unexpected csv: %q", got)
+	}
+	engine.Execute("SET output_format = json")
+	want := `[{"id":"1","body":"a | b"},{"id":"2","body":null}]` + "\n"
+	if got := engine.Execute("SELECT * FROM notes ORDER BY id"); got != want {
+		t.Errorf("unexpected json: %q", got)
+	}
+	if got := engine.Execute("SELECT body FROM notes WHERE id = '2'"); got != `[{"body":null}]`+"\n" {
+		t.Errorf("NULL should be null, got %q", got)
+	}
+	engine.Execute("SET output_format = text")
+	if got := engine.Execute("SELECT * FROM notes ORDER BY id"); got != "id | body\n1 | a | b\n2 | NULL\n" {
+		t.Errorf("unexpected text: %q", got)
+	}
+}
+
+func TestJSONOutputTypes(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id INT, price FLOAT, active BOOL, name TEXT, note)")
+	engine.Execute("CREATE TABLE tags (item_id INT, tag)")
+	engine.Execute("INSERT INTO items VALUES (1, 2.5, true, '7', '8')")
+	engine.Execute("INSERT INTO items VALUES (2, NULL, false, 'b', NULL)")
+	engine.Execute("INSERT INTO tags VALUES (1, 'new')")
+	engine.Execute("SET output_format = json")
+
+	for stmt, want := range map[string]string{
+		"SELECT * FROM items WHERE id = 1":                               `[{"id":1,"price":2.5,"active":true,"name":"7","note":"8"}]`,
+		"SELECT ROWID, active AS on_sale FROM items WHERE id = 2":        `[{"rowid":2,"on_sale":false}]`,
+		"SELECT COUNT(*), SUM(price), MAX(id), MIN(name) FROM items":     `[{"count":2,"sum(price)":2.5,"max(id)":2,"min(name)":"7"}]`,
+		"SELECT active, COUNT(*) FROM items GROUP BY active":             `[{"active":true,"count":1},{"active":false,"count":1}]`,
+		"SELECT i.id, t.tag FROM items i, tags t WHERE i.id = t.item_id": `[{"id":1,"tag":"new"}]`,
+		"SELECT COALESCE(note, 'none') AS n FROM items ORDER BY id":      `[{"n":"8"},{"n":"none"}]`,
+	} {
+		if got := engine.Execute(stmt); got != want+"\n" {
+			t.Errorf("%s:\n got %s\nwant %s", stmt, got, want)
+		}
+	}
+}
+
+func TestBulkLoadSetting(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE items (id, name)")
+	engine.Execute("CREATE INDEX ON items (id)")
+
+	if got := engine.Execute("SET bulk_load = on"); got != "SET bulk_load = on" {
+		t.Fatalf("unexpected SET result: %s", got)
+	}
+	if !engine.DB.BulkLoad() {
+		t.Fatal("SET bulk_load = on did not enable bulk load mode")
+	}
+	engine.Execute("INSERT INTO items VALUES ('1', 'a')")
+	engine.Execute("INSERT INTO items VALUES ('2', 'b')")
+	if got := engine.Execute("SELECT * FROM items WHERE id = '2'"); !strings.Contains(got, "2 | b") {
+		t.Errorf("select during bulk load: %q", got)
+	}
+
+	engine.Execute("SET bulk_load = DEFAULT")
+	if engine.DB.BulkLoad() {
+		t.Fatal("SET bulk_load = DEFAULT did not turn bulk load off")
+	}
+	if got := engine.Execute("SELECT * FROM items WHERE id = '1'"); !strings.Contains(got, "1 | a") {
+		t.Errorf("select after bulk load: %q", got)
+	}
+}
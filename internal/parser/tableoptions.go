@@ -0,0 +1,86 @@
+// internal/parser/tableoptions.go
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tableOptions are the options in CREATE TABLE ... WITH (...)
+type tableOptions struct {
+	// encrypted is nil when the statement leaves it to the server default
+	encrypted  *bool
+	versioned  bool
+	timestamps bool
+}
+
+// splitTableOptions separates a trailing WITH (name=value, ...) clause from
+// a CREATE TABLE statement, returning the rest of the statement and the
+// options
+func splitTableOptions(input string) (string, tableOptions, string) {
+	upper := strings.ToUpper(input)
+	i := strings.LastIndex(upper, " WITH ")
+	if i < 0 || !strings.HasSuffix(strings.TrimSpace(input[:i]), ")") {
+		return input, tableOptions{}, ""
+	}
+	opts, errMsg := parseTableOptions(input[i+len(" WITH "):])
+	if errMsg != "" {
+		return "", tableOptions{}, errMsg
+	}
+	return strings.TrimSpace(input[:i]), opts, ""
+}
+
+// parseTableOptions parses a table options list such as
+// (encrypted=true, versioned=true, timestamps=true)
+func parseTableOptions(clause string) (tableOptions, string) {
+	const usage = "Syntax error: WITH (encrypted=true|false, versioned=true|false, timestamps=true|false)"
+	var opts tableOptions
+	clause = strings.TrimSpace(clause)
+	if !strings.HasPrefix(clause, "(") || !strings.HasSuffix(clause, ")") {
+		return opts, usage
+	}
+	for _, option := range strings.Split(clause[1:len(clause)-1], ",") {
+		name, value, ok := strings.Cut(option, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || (name != "encrypted" && name != "versioned" && name != "timestamps") {
+			return opts, fmt.Sprintf("Error: unknown table option '%s'; use encrypted, versioned or timestamps", strings.TrimSpace(option))
+		}
+		on, ok := parseBoolOption(strings.TrimSpace(value))
+		if !ok {
+			return opts, usage
+		}
+		switch name {
+		case "encrypted":
+			opts.encrypted = &on
+		case "versioned":
+			opts.versioned = on
+		default:
+			opts.timestamps = on
+		}
+	}
+	return opts, ""
+}
+
+// parseBoolOption parses true/false, on/off or 1/0
+func parseBoolOption(value string) (bool, bool) {
+	switch strings.ToLower(strings.Trim(value, "'")) {
+	case "true", "on", "1":
+		return true, true
+	case "false", "off", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// createTableWithOptions creates a table with the statement's options,
+// leaving encryption to the server's default when it does not say
+func (e *Engine) createTableWithOptions(tableName string, columns []string, unlogged bool, opts tableOptions) string {
+	options := e.DB.DefaultTableOptions()
+	options.Unlogged = unlogged
+	options.Versioned = opts.versioned
+	options.Timestamps = opts.timestamps
+	if opts.encrypted != nil {
+		options.Encrypted = *opts.encrypted
+	}
+	return e.DB.CreateTableWithOptionsTx(tableName, columns, options)
+}
@@ -0,0 +1,66 @@
+// internal/parser/timestamps_test.go
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampedTable(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+
+	for _, tc := range []struct{ stmt, want string }{
+		{"CREATE TABLE events (id, name) WITH (timestamps=true)", "Table events created with secure page-based storage"},
+		{"CREATE TABLE bad (id, updated_at) WITH (timestamps=true)", "Error: updated_at is added by timestamps=true; leave it out of the column list"},
+		{"INSERT INTO events VALUES ('1', 'signup')", "1 row inserted with secure page-based storage"},
+		{"INSERT INTO events (name, id) VALUES ('login', '2')", "1 row inserted with secure page-based storage"},
+		{"INSERT INTO events (id, created_at) VALUES ('3', 'x')", "Error: created_at is maintained automatically and cannot be set"},
+		{"UPDATE events SET updated_at = 'x' ROWID 1", "Error: updated_at is maintained automatically and cannot be set"},
+		{"ALTER TABLE events SET (timestamps=true)", "Error: timestamps can only be set by CREATE TABLE"},
+		{"SHOW CREATE TABLE events", "statement\nCREATE TABLE events (id, name) WITH (timestamps=true)\n"},
+	} {
+		if got := engine.Execute(tc.stmt); got != tc.want {
+			t.Errorf("%s: %q", tc.stmt, got)
+		}
+	}
+
+	stamp := `\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z`
+	row := regexp.MustCompile(`(?m)^1 \| signup \| (` + stamp + `) \| (` + stamp + `)$`)
+	m := row.FindStringSubmatch(engine.Execute("SELECT * FROM events"))
+	if m == nil || m[1] != m[2] {
+		t.Fatalf("inserted row: %v", m)
+	}
+	created := m[1]
+
+	time.Sleep(2 * time.Millisecond)
+	if got := engine.Execute("UPDATE events SET name = 'signed up' ROWID 1"); got != "1 row updated" {
+		t.Fatalf("update: %q", got)
+	}
+	updated := regexp.MustCompile(`(?m)^1 \| signed up \| (` + stamp + `) \| (` + stamp + `)$`)
+	m = updated.FindStringSubmatch(engine.Execute("SELECT * FROM events"))
+	if m == nil || m[1] != created || m[2] <= created {
+		t.Fatalf("updated row: %v, created %s", m, created)
+	}
+
+	// A versioned table keeps _version last
+	engine.Execute("CREATE TABLE docs (id) WITH (versioned=true, timestamps=true)")
+	engine.Execute("INSERT INTO docs VALUES ('1')")
+	if got := engine.Execute("SELECT * FROM docs"); !strings.HasPrefix(got, "id | created_at | updated_at | _version\n") || !strings.HasSuffix(got, " | 1\n") {
+		t.Errorf("versioned and timestamped: %q", got)
+	}
+	engine.DB.Close()
+
+	reopened := NewEngine(dir)
+	reopened.Execute("LOGIN admin admin123")
+	if got := reopened.Execute("UPDATE events SET name = 'joined' ROWID 1"); got != "1 row updated" {
+		t.Fatalf("update after restart: %q", got)
+	}
+	m = regexp.MustCompile(`(?m)^1 \| joined \| (` + stamp + `) \| (` + stamp + `)$`).FindStringSubmatch(reopened.Execute("SELECT * FROM events"))
+	if m == nil || m[1] != created {
+		t.Errorf("row after restart: %v, created %s", m, created)
+	}
+}
@@ -0,0 +1,93 @@
+// internal/parser/types_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestTypedColumns(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+
+	steps := []struct{ stmt, want string }{
+		{"CREATE TABLE users (id INT, name TEXT, active BOOL)", "Table users created with secure page-based storage"},
+		{"INSERT INTO users VALUES (1, 'Ann', true)", "1 row inserted with secure page-based storage"},
+		{"INSERT INTO users VALUES ('two', 'Bob', false)", "Error: value 'two' does not match type INT of column id"},
+		{"INSERT INTO users VALUES (2, 'Bob', NULL)", "1 row inserted with secure page-based storage"},
+		{"UPDATE users SET active = 'nope' ROW 0", "Error: value 'nope' does not match type BOOL of column active"},
+		{"UPDATE users SET active = 0 ROW 0", "1 row updated"},
+		{"SELECT * FROM users WHERE id > 1", "id | name | active\n2 | Bob | NULL\n"},
+		{"SELECT * FROM users WHERE active = false", "id | name | active\n1 | Ann | false\n"},
+		{"SHOW CREATE TABLE users", "statement\nCREATE TABLE users (id INT, name TEXT, active BOOL)\n"},
+		{"CREATE TABLE bad (id BLOB)", "Error: unknown column type BLOB (use INT, FLOAT, TEXT, BOOL, POINT, DATE or TIMESTAMP)"},
+		// Inside a transaction the value is checked when the statement runs
+		{"BEGIN", ""},
+		{"INSERT INTO users VALUES (3.5, 'Cy', true)", "Error: value '3.5' does not match type INT of column id"},
+		{"ROLLBACK", ""},
+	}
+	for _, s := range steps {
+		got := engine.Execute(s.stmt)
+		if s.want != "" && got != s.want {
+			t.Errorf("%s:\n got %q\nwant %q", s.stmt, got, s.want)
+		}
+	}
+	if got := engine.Execute("DESCRIBE users"); !strings.Contains(got, "active | column | BOOL | yes |  | BINARY | no") {
+		t.Errorf("DESCRIBE: %s", got)
+	}
+}
+
+func TestTypeMismatchIsConstraint(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id INT, active BOOL)")
+	engine.Execute("INSERT INTO users VALUES (1, true)")
+
+	for stmt, want := range map[string]string{
+		"INSERT INTO users VALUES ('x', true)":   "ERROR CONSTRAINT: Error: value 'x' does not match type INT of column id",
+		"UPDATE users SET active = 'nope' ROW 0": "ERROR CONSTRAINT: Error: value 'nope' does not match type BOOL of column active",
+	} {
+		got, err := engine.Exec(stmt)
+		if encoded := protocol.EncodeResult(got, err); encoded != want {
+			t.Errorf("%s:\n got %q\nwant %q", stmt, encoded, want)
+		}
+	}
+}
+
+func TestTemporalColumns(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE events (id INT, day DATE, at TIMESTAMP)")
+	for _, stmt := range []string{
+		"INSERT INTO events VALUES (1, '2025-01-15', '2025-01-15 09:30:00')",
+		"INSERT INTO events VALUES (2, '2025-02-01', '2025-01-09T23:00:00-02:00')",
+		"INSERT INTO events VALUES (3, '2024-12-31T08:00:00Z', '2025-01-10')",
+		"INSERT INTO events VALUES (4, NULL, NULL)",
+	} {
+		if got := engine.Execute(stmt); !strings.HasPrefix(got, "1 row inserted") {
+			t.Fatalf("%s: %s", stmt, got)
+		}
+	}
+	engine.Execute("CREATE INDEX ON events (at)")
+
+	for input, want := range map[string]string{
+		"INSERT INTO events VALUES (5, 'soon', NULL)":                                                                                           "Error: value 'soon' does not match type DATE of column day",
+		"SELECT id, day, at FROM events WHERE id = 3":                                                                                           "id | day | at\n3 | 2024-12-31 | 2025-01-10T00:00:00.000000Z\n",
+		"SELECT id FROM events WHERE at = '2025-01-10'":                                                                                         "id\n3\n",
+		"SELECT id FROM events WHERE at >= '2025-01-10 00:00:00' ORDER BY at":                                                                   "id\n3\n2\n1\n",
+		"SELECT id FROM events WHERE day < '2025-01-15T12:00:00Z'":                                                                              "id\n1\n3\n",
+		"SELECT id FROM events WHERE day <= '2025-01-15'":                                                                                       "id\n1\n3\n",
+		"SELECT id FROM events WHERE day > CURRENT_DATE - INTERVAL '100 years'":                                                                 "id\n1\n2\n3\n",
+		"SELECT id FROM events WHERE DATE_ADD(day, INTERVAL 1 MONTH) > '2025-02-20'":                                                            "id\n2\n",
+		"SELECT id, DATE_ADD(day, INTERVAL '1 day') AS next, DATE_SUB(at, INTERVAL '30 minutes') AS early FROM events WHERE id < 3 ORDER BY id": "id | next | early\n1 | 2025-01-16 | 2025-01-15T09:00:00.000000Z\n2 | 2025-02-02 | 2025-01-10T00:30:00.000000Z\n",
+		"SELECT id FROM events ORDER BY day DESC LIMIT 2":                                                                                       "id\n2\n1\n",
+		"SELECT id FROM events WHERE at < NOW() AND at > NOW() - INTERVAL '100 years' ORDER BY id":                                              "id\n1\n2\n3\n",
+		"SELECT DATE_ADD(day, 1) FROM events":                                                                                                   "Syntax error: DATE_ADD expects an INTERVAL such as INTERVAL '7 days' as its second argument",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s:\n got %q\nwant %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// internal/parser/version_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersionedTable(t *testing.T) {
+	dir := testDataDir(t)
+	engine := NewEngine(dir)
+	engine.Execute("LOGIN admin admin123")
+
+	for _, tc := range []struct{ stmt, want string }{
+		{"CREATE TABLE accounts (id, balance) WITH (versioned=true)", "Table accounts created with secure page-based storage"},
+		{"CREATE TABLE bad (id, _version) WITH (versioned=true)", "Error: _version is added by versioned=true; leave it out of the column list"},
+		{"INSERT INTO accounts VALUES ('1', '100')", "1 row inserted with secure page-based storage"},
+		{"UPDATE accounts SET balance = '90' ROWID 1 WHERE _version = 1", "1 row updated"},
+		{"UPDATE accounts SET balance = '80' ROWID 1 WHERE _version = 1", "Error: version conflict on row ID 1 of accounts: the row is at version 2, not 1"},
+		{"UPDATE accounts SET balance = '80' ROWID 1", "1 row updated"},
+		{"UPDATE accounts SET _version = '9' ROWID 1", "Error: _version is maintained automatically and cannot be set"},
+		{"UPDATE accounts SET balance = '80' ROWID 1 WHERE id = 1", "Syntax error: UPDATE supports only WHERE _version = n after ROW or ROWID"},
+		{"ALTER TABLE accounts SET (versioned=true)", "Error: versioned can only be set by CREATE TABLE"},
+		{"SELECT * FROM accounts", "id | balance | _version\n1 | 80 | 3\n"},
+		{"SHOW CREATE TABLE accounts", "statement\nCREATE TABLE accounts (id, balance) WITH (versioned=true)\n"},
+	} {
+		if got := engine.Execute(tc.stmt); got != tc.want {
+			t.Errorf("%s: %q", tc.stmt, got)
+		}
+	}
+
+	engine.Execute("CREATE TABLE plain (id)")
+	engine.Execute("INSERT INTO plain VALUES ('1')")
+	if got := engine.Execute("UPDATE plain SET id = '2' ROW 0 WHERE _version = 1"); got != "Error: table plain is not versioned" {
+		t.Errorf("guard on an unversioned table: %q", got)
+	}
+
+	// A queued update fails at commit when the row changed in between
+	engine.Execute("BEGIN TRANSACTION")
+	if got := engine.Execute("UPDATE accounts SET balance = '70' ROWID 1 WHERE _version = 3"); got != "1 row update queued in transaction" {
+		t.Fatalf("queued update: %q", got)
+	}
+	engine.DB.Update("accounts", 0, []string{"1", "75", "3"})
+	if got := engine.Execute("COMMIT"); !strings.Contains(got, "could not serialize access: row ID 1 of table accounts") {
+		t.Errorf("commit after a concurrent update: %q", got)
+	}
+	engine.DB.Close()
+
+	reopened := NewEngine(dir)
+	reopened.Execute("LOGIN admin admin123")
+	if got := reopened.Execute("UPDATE accounts SET balance = '60' ROWID 1 WHERE _version = 4"); got != "1 row updated" {
+		t.Errorf("update after restart: %q", got)
+	}
+	if got := reopened.Execute("SELECT * FROM accounts"); got != "id | balance | _version\n1 | 60 | 5\n" {
+		t.Errorf("select after restart: %q", got)
+	}
+}
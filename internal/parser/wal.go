@@ -0,0 +1,84 @@
+// internal/parser/wal.go
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+const (
+	// defaultWALRecords is the number of records SHOW WAL RECORDS shows
+	defaultWALRecords = 20
+	// maxWALRecords caps SHOW WAL RECORDS n
+	maxWALRecords = 1000
+	// maxWALDataLen truncates the data shown for each record
+	maxWALDataLen = 200
+)
+
+// handleShowWAL handles SHOW WAL
+func (e *Engine) handleShowWAL(input string) (*storage.ResultSet, string) {
+	if len(strings.Fields(input)) != 2 {
+		return nil, "Syntax error: SHOW WAL"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return nil, err
+	}
+	st, err := e.DB.WALStatus()
+	if err != nil {
+		return nil, fmt.Sprintf("Error: %v", err)
+	}
+	return nameValues([][2]string{
+		{"path", st.Path},
+		{"size_bytes", strconv.FormatInt(st.Bytes, 10)},
+		{"segments", strconv.Itoa(st.Segments)},
+		{"records", strconv.Itoa(st.Records)},
+		{"last_lsn", strconv.FormatUint(st.LastLSN, 10)},
+		{"checkpoint_lsn", strconv.FormatUint(st.CheckpointLSN, 10)},
+		{"replay_lag_records", strconv.Itoa(st.LagRecords)},
+		{"replay_lag_bytes", strconv.FormatInt(st.LagBytes, 10)},
+	}), ""
+}
+
+// handleShowWALRecords handles SHOW WAL RECORDS [n]
+func (e *Engine) handleShowWALRecords(input string) (*storage.ResultSet, string) {
+	parts := strings.Fields(input)
+	n := defaultWALRecords
+	switch len(parts) {
+	case 3:
+	case 4:
+		v, err := strconv.Atoi(parts[3])
+		if err != nil || v < 1 || v > maxWALRecords {
+			return nil, fmt.Sprintf("Error: record count must be between 1 and %d", maxWALRecords)
+		}
+		n = v
+	default:
+		return nil, "Syntax error: SHOW WAL RECORDS [n]"
+	}
+	if err := e.requireAdmin(); err != "" {
+		return nil, err
+	}
+	entries, err := e.DB.RecentWALEntries(n)
+	if err != nil {
+		return nil, fmt.Sprintf("Error: %v", err)
+	}
+
+	rs := &storage.ResultSet{Columns: []string{"lsn", "time", "type", "table", "data"}}
+	for _, entry := range entries {
+		data := ""
+		if entry.Data != nil {
+			raw, _ := json.Marshal(entry.Data)
+			data = string(raw)
+			if len(data) > maxWALDataLen {
+				data = data[:maxWALDataLen] + "..."
+			}
+		}
+		rs.Rows = append(rs.Rows, []string{strconv.FormatUint(entry.LSN, 10),
+			entry.Timestamp.Format(time.RFC3339Nano), entry.Type.String(), entry.TableName, data})
+	}
+	return rs, ""
+}
@@ -0,0 +1,59 @@
+// internal/parser/wal_test.go
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShowWAL(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name)")
+	engine.Execute("INSERT INTO users VALUES ('1', 'Ann')")
+	engine.Execute("CHECKPOINT")
+	engine.Execute("INSERT INTO users VALUES ('2', 'Cy')")
+	engine.Execute("DELETE FROM users ROW 0")
+
+	status := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(engine.Execute("SHOW WAL")), "\n")[1:] {
+		name, value, _ := strings.Cut(line, " | ")
+		status[name] = value
+	}
+	// Each write is followed by a checkpoint
+	if status["segments"] != "1" || status["records"] != "4" || status["size_bytes"] == "0" ||
+		status["last_lsn"] != status["checkpoint_lsn"] || status["replay_lag_records"] != "0" {
+		t.Errorf("SHOW WAL: %v", status)
+	}
+
+	got := engine.Execute("SHOW WAL RECORDS 2")
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "lsn | time | type | table | data" ||
+		!strings.Contains(lines[1], " | DELETE | users | {") || !strings.Contains(lines[2], " | CHECKPOINT |  | ") {
+		t.Errorf("SHOW WAL RECORDS 2: %q", got)
+	}
+	if got := engine.Execute("SHOW WAL RECORDS"); strings.Count(got, "\n") != 5 {
+		t.Errorf("SHOW WAL RECORDS: %q", got)
+	}
+
+	engine.Execute("CHECKPOINT")
+	if got := engine.Execute("SHOW WAL RECORDS"); got != "lsn | time | type | table | data\n(no rows)\n" {
+		t.Errorf("SHOW WAL RECORDS after CHECKPOINT: %q", got)
+	}
+
+	for stmt, want := range map[string]string{
+		"SHOW WAL now":         "Syntax error: SHOW WAL",
+		"SHOW WAL RECORDS 0":   "Error: record count must be between 1 and 1000",
+		"SHOW WAL RECORDS 1 2": "Syntax error: SHOW WAL RECORDS [n]",
+	} {
+		if got := engine.Execute(stmt); got != want {
+			t.Errorf("%s: %s", stmt, got)
+		}
+	}
+
+	engine.Execute("CREATE USER writer pass123 user")
+	engine.Execute("LOGIN writer pass123")
+	if got := engine.Execute("SHOW WAL"); got != ErrInsufficientPermissions {
+		t.Errorf("non-admin SHOW WAL: %s", got)
+	}
+}
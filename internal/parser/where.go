@@ -3,9 +3,15 @@ package parser
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
 )
 
 // WhereOperator represents comparison operators
@@ -19,20 +25,248 @@ const (
 	OpLessThanOrEqual
 	OpGreaterThanOrEqual
 	OpLike
+	// OpILike is LIKE matching case-insensitively whatever the collation
+	OpILike
+	OpRegexMatch
+	OpRegexNotMatch
+	// OpWithin matches points inside Box: column WITHIN BOX(x1, y1, x2, y2)
+	OpWithin
+	// OpIsNull and OpIsNotNull match NULL and non-NULL values: column IS
+	// [NOT] NULL, or IS [NOT] UNKNOWN
+	OpIsNull
+	OpIsNotNull
+	// OpIsTrue, OpIsNotTrue, OpIsFalse and OpIsNotFalse test a boolean
+	// column: column IS [NOT] TRUE|FALSE. They are never unknown.
+	OpIsTrue
+	OpIsNotTrue
+	OpIsFalse
+	OpIsNotFalse
+	// OpTruth is a boolean column used as a condition on its own, as in
+	// WHERE active, or bare TRUE or FALSE
+	OpTruth
+)
+
+// ilikeCollation is the collation ILIKE matches under
+var ilikeCollation = &storage.Collation{Name: storage.CollationNoCase}
+
+// truth is the result of a condition under SQL's three-valued logic. A
+// comparison with NULL is unknown, neither true nor false; NOT unknown is
+// unknown, and a row matches only when the whole expression is true. The
+// values are ordered so that AND takes the least of its operands and OR
+// the greatest.
+type truth int8
+
+const (
+	truthFalse truth = iota
+	truthUnknown
+	truthTrue
 )
 
+// truthOf returns true or false
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+// parseBool parses a boolean value as a BOOL column accepts it
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "t", "1":
+		return true, true
+	case "false", "f", "0":
+		return false, true
+	}
+	return false, false
+}
+
 // WhereCondition represents a single condition
 type WhereCondition struct {
 	Column   string
 	Operator WhereOperator
 	Value    string
+	// Columns and Values hold a row comparison such as (a, b) > (1, 'x'),
+	// which compares the columns in order like a multi-column sort key.
+	// Column and Value then hold the first pair.
+	Columns []string
+	Values  []string
+	// Box is the box of an OpWithin condition
+	Box storage.Box
+	// ValueColumn is set when Value names another column to compare with,
+	// as in o.customer_id = c.id across the tables of a join
+	ValueColumn string
+	// Escape is the ESCAPE character of a LIKE or ILIKE pattern, or 0
+	Escape rune
+	// Temporal is set when Value is a time worked out from NOW() or INTERVAL
+	// arithmetic (see interval.go); values are compared with it as times
+	Temporal bool
+	// Expr is set, and Column empty, when a function call such as
+	// COALESCE(a, b) stands where the column would (see functions.go)
+	Expr *ValueExpr
+}
+
+// subject names the column or function call a condition tests
+func (wc *WhereCondition) subject() string {
+	if wc.Expr != nil {
+		return wc.Expr.String()
+	}
+	return "column " + wc.Column
 }
 
-// WhereExpression represents a WHERE clause with support for AND/OR logic
+// WhereExpression represents a WHERE clause: conditions combined with AND,
+// OR, NOT and parentheses
 type WhereExpression struct {
+	// Conditions holds the conditions in the order they are written
 	Conditions []WhereCondition
-	LogicOps   []string // "AND" or "OR" between conditions
-	Groups     []int    // Parentheses grouping (0 = no group, 1+ = group level)
+	// Root combines the conditions; NOT binds tighter than AND, and AND
+	// tighter than OR. A nil Root joins them all with AND.
+	Root *WhereNode
+
+	// collations maps column name -> collation used to compare its values
+	collations map[string]*storage.Collation
+}
+
+// WhereNode is a node of a WHERE expression tree: a condition, or the AND,
+// OR or NOT of its children
+type WhereNode struct {
+	// Op is "AND", "OR" or "NOT", or empty for a condition
+	Op string
+	// Condition is the index in Conditions of a condition node
+	Condition int
+	Children  []*WhereNode
+}
+
+// SetCollations sets the column collations used when evaluating the expression
+func (we *WhereExpression) SetCollations(collations map[string]*storage.Collation) {
+	we.collations = collations
+}
+
+// SetTypes writes the values compared with DATE and TIMESTAMP columns in
+// the columns' canonical form, which sorts as text in time order, so that
+// the comparisons and index lookups of those columns compare times. A DATE
+// compared with a time of day is compared as a time instead; values that
+// are not times are left to compare as text.
+func (we *WhereExpression) SetTypes(types map[string]storage.ColumnType) {
+	for i := range we.Conditions {
+		cond := &we.Conditions[i]
+		// Times worked out from NOW() compare as times already
+		if cond.Expr != nil || cond.ValueColumn != "" || cond.Temporal || cond.Operator > OpGreaterThanOrEqual {
+			continue
+		}
+		if len(cond.Columns) == 0 {
+			var ok bool
+			cond.Value, ok = types[cond.Column].ComparisonValue(cond.Value)
+			cond.Temporal = !ok
+			continue
+		}
+		for j, col := range cond.Columns {
+			cond.Values[j], _ = types[col].ComparisonValue(cond.Values[j])
+		}
+		cond.Value = cond.Values[0]
+	}
+}
+
+// ResolveColumns rewrites each condition's column with resolve, which maps a
+// column reference to the table's spelling of it
+func (we *WhereExpression) ResolveColumns(resolve func(string) string) {
+	for i := range we.Conditions {
+		if expr := we.Conditions[i].Expr; expr != nil {
+			expr.ResolveColumns(resolve)
+			continue
+		}
+		we.Conditions[i].Column = resolve(we.Conditions[i].Column)
+		for j, col := range we.Conditions[i].Columns {
+			we.Conditions[i].Columns[j] = resolve(col)
+		}
+	}
+}
+
+// ResolveValueColumns marks the comparisons whose value resolve recognizes
+// as a column reference, recording the column it names
+func (we *WhereExpression) ResolveValueColumns(resolve func(string) (string, bool)) {
+	for i := range we.Conditions {
+		cond := &we.Conditions[i]
+		if len(cond.Columns) > 0 || cond.Operator > OpGreaterThanOrEqual || cond.Temporal {
+			continue
+		}
+		if column, ok := resolve(cond.Value); ok {
+			cond.ValueColumn = column
+		}
+	}
+}
+
+// conjuncts returns the conditions every matching row satisfies: those the
+// root joins by AND, outside any OR or NOT
+func (we *WhereExpression) conjuncts() []WhereCondition {
+	var conds []WhereCondition
+	var walk func(n *WhereNode)
+	walk = func(n *WhereNode) {
+		switch n.Op {
+		case "":
+			conds = append(conds, we.Conditions[n.Condition])
+		case "AND":
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+	}
+	if we.Root == nil {
+		return we.Conditions
+	}
+	walk(we.Root)
+	return conds
+}
+
+// SeekBound returns a value that every row matching the expression sorts at
+// or after in column, or at or before when desc is set. A condition joined
+// to the rest by AND implies one, such as column > v, or a row comparison
+// such as (column, id) > (v, 7).
+func (we *WhereExpression) SeekBound(column string, desc bool) (string, bool) {
+	for _, cond := range we.conjuncts() {
+		// Times may be written in several ways that sort apart as text
+		if cond.Column != column || cond.ValueColumn != "" || cond.Temporal {
+			continue
+		}
+		switch cond.Operator {
+		case OpEquals:
+			if len(cond.Columns) == 0 {
+				return cond.Value, true
+			}
+		case OpGreaterThan, OpGreaterThanOrEqual:
+			if !desc {
+				return cond.Value, true
+			}
+		case OpLessThan, OpLessThanOrEqual:
+			if desc {
+				return cond.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// EqualityValue returns the value every row matching the expression holds in
+// column, through a condition column = v joined to the rest by AND
+func (we *WhereExpression) EqualityValue(column string) (string, bool) {
+	for _, cond := range we.conjuncts() {
+		if cond.Column == column && cond.Operator == OpEquals && len(cond.Columns) == 0 && cond.ValueColumn == "" && !cond.Temporal {
+			return cond.Value, true
+		}
+	}
+	return "", false
+}
+
+// WithinBox returns a box every row matching the expression has its column
+// point in, through a condition column WITHIN BOX(...) joined to the rest by
+// AND
+func (we *WhereExpression) WithinBox(column string) (storage.Box, bool) {
+	for _, cond := range we.conjuncts() {
+		if cond.Column == column && cond.Operator == OpWithin {
+			return cond.Box, true
+		}
+	}
+	return storage.Box{}, false
 }
 
 // ParseWhereClause parses a WHERE clause string into a WhereExpression
@@ -42,28 +276,55 @@ func ParseWhereClause(whereClause string) (*WhereExpression, error) {
 		return nil, fmt.Errorf("empty WHERE clause")
 	}
 
-	expr := &WhereExpression{
-		Conditions: []WhereCondition{},
-		LogicOps:   []string{},
-		Groups:     []int{},
-	}
-
 	// Tokenize the WHERE clause
-	tokens := tokenizeWhere(whereClause)
+	tokens, quoted := tokenizeWhereQuoted(whereClause)
 	if len(tokens) == 0 {
 		return nil, fmt.Errorf("no valid tokens in WHERE clause")
 	}
 
-	// Parse tokens into conditions and logic operators
-	return parseWhereTokens(tokens, expr)
+	// Parse tokens into conditions and the tree combining them
+	p := &whereParser{tokens: tokens, quoted: quoted, now: time.Now(), expr: &WhereExpression{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(tokens) {
+		if tokens[p.pos] == ")" {
+			return nil, fmt.Errorf("unmatched closing parenthesis")
+		}
+		return nil, fmt.Errorf("unexpected %s after condition", tokens[p.pos])
+	}
+	p.expr.Root = root
+	return p.expr, nil
 }
 
 // tokenizeWhere splits WHERE clause into tokens, handling quoted strings
 func tokenizeWhere(whereClause string) []string {
+	tokens, _ := tokenizeWhereQuoted(whereClause)
+	return tokens
+}
+
+// tokenizeWhereQuoted is tokenizeWhere also reporting which tokens were
+// quoted, so that 'NULL' and 'AND' stay text. Quoted tokens are returned
+// without their quotes.
+func tokenizeWhereQuoted(whereClause string) ([]string, []bool) {
 	var tokens []string
+	var quoted []bool
 	var current strings.Builder
 	inQuotes := false
 	quoteChar := '"'
+	// depth counts open parentheses; commas inside them separate the
+	// members of a row comparison
+	depth := 0
+
+	// flush ends the current bare token, if any
+	flush := func() {
+		if token := strings.TrimSpace(current.String()); token != "" {
+			tokens = append(tokens, token)
+			quoted = append(quoted, false)
+		}
+		current.Reset()
+	}
 
 	for _, char := range whereClause {
 		switch char {
@@ -71,35 +332,39 @@ func tokenizeWhere(whereClause string) []string {
 			if !inQuotes {
 				inQuotes = true
 				quoteChar = char
-				if current.Len() > 0 {
-					tokens = append(tokens, strings.TrimSpace(current.String()))
-					current.Reset()
-				}
+				flush()
 			} else if char == quoteChar {
 				inQuotes = false
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
+				tokens = append(tokens, current.String())
+				quoted = append(quoted, true)
+				current.Reset()
 			} else {
 				current.WriteRune(char)
 			}
 		case ' ', '\t', '\n':
 			if !inQuotes {
-				if current.Len() > 0 {
-					tokens = append(tokens, strings.TrimSpace(current.String()))
-					current.Reset()
-				}
+				flush()
 			} else {
 				current.WriteRune(char)
 			}
 		case '(', ')':
 			if !inQuotes {
-				if current.Len() > 0 {
-					tokens = append(tokens, strings.TrimSpace(current.String()))
-					current.Reset()
-				}
+				flush()
 				tokens = append(tokens, string(char))
+				quoted = append(quoted, false)
+				if char == '(' {
+					depth++
+				} else {
+					depth--
+				}
+			} else {
+				current.WriteRune(char)
+			}
+		case ',':
+			if !inQuotes && depth > 0 {
+				flush()
+				tokens = append(tokens, ",")
+				quoted = append(quoted, false)
 			} else {
 				current.WriteRune(char)
 			}
@@ -107,71 +372,218 @@ func tokenizeWhere(whereClause string) []string {
 			current.WriteRune(char)
 		}
 	}
+	flush()
 
-	if current.Len() > 0 {
-		tokens = append(tokens, strings.TrimSpace(current.String()))
-	}
+	return tokens, quoted
+}
 
-	// Filter out empty tokens
-	var result []string
-	for _, token := range tokens {
-		if token != "" {
-			result = append(result, token)
-		}
+// whereParser parses WHERE tokens by recursive descent, one function per
+// precedence level
+type whereParser struct {
+	tokens []string
+	// quoted marks the tokens that were quoted strings
+	quoted []bool
+	// now is the time NOW() stands for
+	now  time.Time
+	pos  int
+	expr *WhereExpression
+}
+
+// peek returns the next token in upper case, "" at the end and "'" for a
+// quoted string, which is never a keyword
+func (p *whereParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
 	}
+	if p.quoted[p.pos] {
+		return "'"
+	}
+	return strings.ToUpper(p.tokens[p.pos])
+}
 
-	return result
+// literal returns the value of token i: bare NULL is NULL, and bare TRUE
+// and FALSE are true and false as BOOL columns store them
+func (p *whereParser) literal(i int) string {
+	token := p.tokens[i]
+	if p.quoted[i] {
+		return token
+	}
+	switch strings.ToUpper(token) {
+	case "NULL":
+		return storage.NullValue
+	case "TRUE", "FALSE":
+		return strings.ToLower(token)
+	}
+	return token
 }
 
-// parseWhereTokens parses tokenized WHERE clause
-func parseWhereTokens(tokens []string, expr *WhereExpression) (*WhereExpression, error) {
-	i := 0
-	groupLevel := 0
+// parseOr parses operands joined by OR
+func (p *whereParser) parseOr() (*WhereNode, error) {
+	return p.parseJoined("OR", p.parseAnd)
+}
 
-	for i < len(tokens) {
-		token := tokens[i]
+// parseAnd parses operands joined by AND
+func (p *whereParser) parseAnd() (*WhereNode, error) {
+	return p.parseJoined("AND", p.parseNot)
+}
 
-		switch strings.ToUpper(token) {
-		case "AND", "OR":
-			if len(expr.Conditions) == 0 {
-				return nil, fmt.Errorf("logic operator %s at start of expression", token)
-			}
-			expr.LogicOps = append(expr.LogicOps, strings.ToUpper(token))
-			expr.Groups = append(expr.Groups, groupLevel)
-			i++
+// parseJoined parses operands, each parsed by operand, joined by op
+func (p *whereParser) parseJoined(op string, operand func() (*WhereNode, error)) (*WhereNode, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	node := first
+	for p.peek() == op {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if node == first {
+			node = &WhereNode{Op: op, Children: []*WhereNode{first}}
+		}
+		node.Children = append(node.Children, next)
+	}
+	return node, nil
+}
 
-		case "(":
-			groupLevel++
-			i++
+// parseNot parses NOT operand, or an operand
+func (p *whereParser) parseNot() (*WhereNode, error) {
+	if p.peek() != "NOT" {
+		return p.parseOperand()
+	}
+	p.pos++
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &WhereNode{Op: "NOT", Children: []*WhereNode{operand}}, nil
+}
 
-		case ")":
-			if groupLevel <= 0 {
-				return nil, fmt.Errorf("unmatched closing parenthesis")
+// parseOperand parses a condition, a row comparison or a parenthesized
+// expression
+func (p *whereParser) parseOperand() (*WhereNode, error) {
+	switch p.peek() {
+	case "":
+		if len(p.expr.Conditions) == 0 {
+			return nil, fmt.Errorf("no conditions found in WHERE clause")
+		}
+		return nil, fmt.Errorf("expected a condition at end of WHERE clause")
+	case "AND", "OR":
+		return nil, fmt.Errorf("logic operator %s without a condition before it", p.tokens[p.pos])
+	case ")":
+		return nil, fmt.Errorf("unmatched closing parenthesis")
+	case "(":
+		if condition, consumed, ok := parseRowComparison(p.tokens, p.pos); ok {
+			// The values follow the columns and the operator
+			first := p.pos + 2*len(condition.Columns) + 3
+			for k := range condition.Values {
+				condition.Values[k] = p.literal(first + 2*k)
 			}
-			groupLevel--
-			i++
-
-		default:
-			// Parse condition: column operator value
-			condition, consumed, err := parseCondition(tokens, i)
-			if err != nil {
-				return nil, err
+			condition.Value = condition.Values[0]
+			p.pos += consumed
+			return p.addCondition(condition), nil
+		}
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("unmatched parentheses")
+		}
+		p.pos++
+		return node, nil
+	}
+	// A function call stands where a column would
+	var call *ValueExpr
+	start := p.pos
+	if isValueCall(p.tokens, p.quoted, p.pos) {
+		expr, end, err := parseValueExpr(p.tokens, p.quoted, p.pos)
+		if err != nil {
+			return nil, err
+		}
+		call, start = expr, end-1
+	}
+	// A column alone is a boolean condition, and bare TRUE or FALSE a
+	// constant one
+	if next := start + 1; next == len(p.tokens) || !p.quoted[next] && isLogicToken(p.tokens[next]) {
+		p.pos = next
+		if call == nil && !p.quoted[start] {
+			if word := strings.ToUpper(p.tokens[start]); word == "TRUE" || word == "FALSE" {
+				call = &ValueExpr{Value: p.literal(start)}
 			}
-			expr.Conditions = append(expr.Conditions, condition)
-			expr.Groups = append(expr.Groups, groupLevel)
-			i += consumed
+		}
+		if call != nil {
+			return p.addCondition(WhereCondition{Operator: OpTruth, Expr: call}), nil
+		}
+		return p.addCondition(WhereCondition{Column: p.tokens[start], Operator: OpTruth}), nil
+	}
+	condition, consumed, err := parseCondition(p.tokens, start)
+	if err != nil {
+		return nil, err
+	}
+	if call != nil {
+		condition.Column, condition.Expr = "", call
+	}
+	if consumed == 3 && condition.Operator <= OpILike {
+		condition.Value = p.literal(start + 2)
+	}
+	if consumed == 3 && condition.Operator <= OpGreaterThanOrEqual {
+		value, used, ok, err := p.parseTimeExpression(start+2, p.now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			condition.Value, condition.Temporal = value, true
+			consumed = 2 + used
 		}
 	}
+	p.pos = start + consumed
+	if condition.Operator == OpLike || condition.Operator == OpILike {
+		if err := p.parseEscape(&condition); err != nil {
+			return nil, err
+		}
+	}
+	return p.addCondition(condition), nil
+}
 
-	if groupLevel != 0 {
-		return nil, fmt.Errorf("unmatched parentheses")
+// parseEscape parses the optional ESCAPE 'c' after a LIKE pattern, and
+// checks the pattern
+func (p *whereParser) parseEscape(condition *WhereCondition) error {
+	if p.peek() == "ESCAPE" {
+		if p.pos+1 >= len(p.tokens) || !p.quoted[p.pos+1] {
+			return fmt.Errorf("ESCAPE expects a quoted character")
+		}
+		escape := []rune(p.tokens[p.pos+1])
+		switch len(escape) {
+		case 0:
+		case 1:
+			condition.Escape = escape[0]
+		default:
+			return fmt.Errorf("ESCAPE expects a single character, got %q", p.tokens[p.pos+1])
+		}
+		p.pos += 2
 	}
+	// Reject bad patterns up front rather than on the first row
+	_, err := (*storage.Collation)(nil).LikeEscape("", condition.Value, condition.Escape)
+	return err
+}
 
-	if len(expr.Conditions) == 0 {
-		return nil, fmt.Errorf("no conditions found in WHERE clause")
+// isLogicToken reports whether token joins or closes conditions
+func isLogicToken(token string) bool {
+	switch strings.ToUpper(token) {
+	case "AND", "OR", ")":
+		return true
 	}
+	return false
+}
 
-	return expr, nil
+// addCondition records a condition and returns its node
+func (p *whereParser) addCondition(condition WhereCondition) *WhereNode {
+	p.expr.Conditions = append(p.expr.Conditions, condition)
+	return &WhereNode{Condition: len(p.expr.Conditions) - 1}
 }
 
 // parseCondition parses a single condition from tokens
@@ -181,12 +593,88 @@ func parseCondition(tokens []string, start int) (WhereCondition, int, error) {
 	}
 
 	column := tokens[start]
-	operatorStr := strings.ToUpper(tokens[start+1])
+	if strings.EqualFold(tokens[start+1], "WITHIN") {
+		return parseWithin(tokens, start)
+	}
+	if strings.EqualFold(tokens[start+1], "IS") {
+		return parseIs(tokens, start)
+	}
+	operator, err := parseOperator(tokens[start+1])
+	if err != nil {
+		return WhereCondition{}, 0, err
+	}
 	value := tokens[start+2]
 
-	// Parse operator
+	// Remove quotes from value
+	value = strings.Trim(value, "'\"")
+
+	// Reject bad patterns up front rather than on the first row
+	if operator == OpRegexMatch || operator == OpRegexNotMatch {
+		if _, err := compileRegex(value); err != nil {
+			return WhereCondition{}, 0, err
+		}
+	}
+
+	return WhereCondition{
+		Column:   column,
+		Operator: operator,
+		Value:    value,
+	}, 3, nil
+}
+
+// parseWithin parses column WITHIN BOX(x1, y1, x2, y2)
+func parseWithin(tokens []string, start int) (WhereCondition, int, error) {
+	const usage = "WITHIN expects BOX(x1, y1, x2, y2)"
+	if start+3 >= len(tokens) || !strings.EqualFold(tokens[start+2], "BOX") || tokens[start+3] != "(" {
+		return WhereCondition{}, 0, fmt.Errorf(usage)
+	}
+	items, end := parseParenList(tokens, start+3)
+	if len(items) != 4 {
+		return WhereCondition{}, 0, fmt.Errorf(usage)
+	}
+	var coords [4]float64
+	for i, item := range items {
+		f, err := strconv.ParseFloat(item, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return WhereCondition{}, 0, fmt.Errorf("invalid BOX coordinate %s", item)
+		}
+		coords[i] = f
+	}
+	box := storage.NewBox(coords[0], coords[1], coords[2], coords[3])
+	return WhereCondition{Column: tokens[start], Operator: OpWithin, Value: box.String(), Box: box}, end - start, nil
+}
+
+// parseIs parses column IS [NOT] NULL|UNKNOWN|TRUE|FALSE
+func parseIs(tokens []string, start int) (WhereCondition, int, error) {
+	negated := strings.EqualFold(tokens[start+2], "NOT")
+	consumed := 3
+	if negated {
+		consumed = 4
+	}
+	if start+consumed > len(tokens) {
+		return WhereCondition{}, 0, fmt.Errorf("IS expects [NOT] NULL, TRUE, FALSE or UNKNOWN")
+	}
+	operators := map[string][2]WhereOperator{
+		"NULL":    {OpIsNull, OpIsNotNull},
+		"UNKNOWN": {OpIsNull, OpIsNotNull},
+		"TRUE":    {OpIsTrue, OpIsNotTrue},
+		"FALSE":   {OpIsFalse, OpIsNotFalse},
+	}
+	ops, ok := operators[strings.ToUpper(tokens[start+consumed-1])]
+	if !ok {
+		return WhereCondition{}, 0, fmt.Errorf("IS expects [NOT] NULL, TRUE, FALSE or UNKNOWN")
+	}
+	cond := WhereCondition{Column: tokens[start], Operator: ops[0]}
+	if negated {
+		cond.Operator = ops[1]
+	}
+	return cond, consumed, nil
+}
+
+// parseOperator parses a comparison operator
+func parseOperator(token string) (WhereOperator, error) {
 	var operator WhereOperator
-	switch operatorStr {
+	switch strings.ToUpper(token) {
 	case "=":
 		operator = OpEquals
 	case "!=", "<>":
@@ -201,141 +689,324 @@ func parseCondition(tokens []string, start int) (WhereCondition, int, error) {
 		operator = OpGreaterThanOrEqual
 	case "LIKE":
 		operator = OpLike
+	case "ILIKE":
+		operator = OpILike
+	case "~":
+		operator = OpRegexMatch
+	case "!~":
+		operator = OpRegexNotMatch
 	default:
-		return WhereCondition{}, 0, fmt.Errorf("unsupported operator: %s", operatorStr)
+		return 0, fmt.Errorf("unsupported operator: %s", token)
 	}
+	return operator, nil
+}
 
-	// Remove quotes from value
-	value = strings.Trim(value, "'\"")
-
+// parseRowComparison parses (col, ...) op (value, ...) starting at the
+// opening parenthesis. ok is false when the parenthesis opens a group
+// instead.
+func parseRowComparison(tokens []string, start int) (WhereCondition, int, bool) {
+	columns, i := parseParenList(tokens, start)
+	if columns == nil || i+1 >= len(tokens) || tokens[i+1] != "(" {
+		return WhereCondition{}, 0, false
+	}
+	operator, err := parseOperator(tokens[i])
+	if err != nil || operator > OpGreaterThanOrEqual {
+		return WhereCondition{}, 0, false
+	}
+	values, end := parseParenList(tokens, i+1)
+	if len(values) != len(columns) {
+		return WhereCondition{}, 0, false
+	}
 	return WhereCondition{
-		Column:   column,
+		Column:   columns[0],
 		Operator: operator,
-		Value:    value,
-	}, 3, nil
+		Value:    values[0],
+		Columns:  columns,
+		Values:   values,
+	}, end - start, true
 }
 
-// EvaluateCondition evaluates a single condition against a row
-func (wc *WhereCondition) EvaluateCondition(row []string, columnIndexes map[string]int) (bool, error) {
-	colIdx, exists := columnIndexes[wc.Column]
-	if !exists {
-		return false, fmt.Errorf("column %s not found", wc.Column)
+// parseParenList parses ( item, item, ... ) starting at the opening
+// parenthesis, returning the items and the index after the closing one. It
+// returns nil when the tokens are not such a list.
+func parseParenList(tokens []string, start int) ([]string, int) {
+	var items []string
+	for i := start + 1; i+1 < len(tokens); i += 2 {
+		item := tokens[i]
+		if item == "(" || item == ")" || item == "," {
+			return nil, 0
+		}
+		items = append(items, item)
+		switch tokens[i+1] {
+		case ")":
+			return items, i + 2
+		case ",":
+		default:
+			return nil, 0
+		}
 	}
+	return nil, 0
+}
 
-	if colIdx >= len(row) {
-		return false, fmt.Errorf("column index out of bounds")
+// EvaluateCondition evaluates a single condition against a row, reporting
+// whether it is true
+func (wc *WhereCondition) EvaluateCondition(row []string, columnIndexes map[string]int) (bool, error) {
+	result, err := wc.evaluate(row, columnIndexes, nil)
+	return result == truthTrue, err
+}
+
+// evaluate evaluates a condition comparing text under coll (nil for BINARY).
+// A comparison with NULL, in the row or in the condition, is unknown.
+func (wc *WhereCondition) evaluate(row []string, columnIndexes map[string]int, coll *storage.Collation) (truth, error) {
+	var cellValue string
+	if wc.Expr != nil {
+		value, err := wc.Expr.value(row, columnIndexes)
+		if err != nil {
+			return truthFalse, err
+		}
+		cellValue = value
+	} else {
+		colIdx, exists := columnIndexes[wc.Column]
+		if !exists {
+			return truthFalse, fmt.Errorf("column %s not found", wc.Column)
+		}
+		if colIdx >= len(row) {
+			return truthFalse, fmt.Errorf("column index out of bounds")
+		}
+		cellValue = row[colIdx]
+	}
+	if wc.ValueColumn != "" {
+		// Compare with the other column
+		otherIdx, exists := columnIndexes[wc.ValueColumn]
+		if !exists || otherIdx >= len(row) {
+			return truthFalse, fmt.Errorf("column %s not found", wc.ValueColumn)
+		}
+		other := row[otherIdx]
+		switch {
+		case storage.IsNull(cellValue) || storage.IsNull(other):
+			return truthUnknown, nil
+		case wc.Operator == OpEquals:
+			return truthOf(coll.Equal(cellValue, other)), nil
+		case wc.Operator == OpNotEquals:
+			return truthOf(!coll.Equal(cellValue, other)), nil
+		}
+		return truthOf(operatorHolds(wc.Operator, compareCells(cellValue, other, coll))), nil
 	}
 
-	cellValue := row[colIdx]
+	// The IS tests are never unknown
+	value, isBool := parseBool(cellValue)
+	switch wc.Operator {
+	case OpIsNull:
+		return truthOf(storage.IsNull(cellValue)), nil
+	case OpIsNotNull:
+		return truthOf(!storage.IsNull(cellValue)), nil
+	case OpIsTrue, OpIsNotTrue, OpIsFalse, OpIsNotFalse:
+		if !isBool && !storage.IsNull(cellValue) {
+			return truthFalse, fmt.Errorf("%s is not boolean: %s", wc.subject(), cellValue)
+		}
+		want := wc.Operator == OpIsTrue || wc.Operator == OpIsNotTrue
+		holds := isBool && value == want
+		return truthOf(holds == (wc.Operator == OpIsTrue || wc.Operator == OpIsFalse)), nil
+	}
 
+	if storage.IsNull(cellValue) || storage.IsNull(wc.Value) {
+		return truthUnknown, nil
+	}
+	if wc.Temporal {
+		return truthOf(operatorHolds(wc.Operator, compareTimes(cellValue, wc.Value, coll))), nil
+	}
 	switch wc.Operator {
+	case OpTruth:
+		if !isBool {
+			return truthFalse, fmt.Errorf("%s is not boolean: %s", wc.subject(), cellValue)
+		}
+		return truthOf(value), nil
 	case OpEquals:
-		return cellValue == wc.Value, nil
+		return truthOf(coll.Equal(cellValue, wc.Value)), nil
 	case OpNotEquals:
-		return cellValue != wc.Value, nil
+		return truthOf(!coll.Equal(cellValue, wc.Value)), nil
 	case OpLike:
-		return evaluateLike(cellValue, wc.Value)
+		// % matches any run of characters and _ matches one
+		matched, err := coll.LikeEscape(cellValue, wc.Value, wc.Escape)
+		return truthOf(matched), err
+	case OpILike:
+		matched, err := ilikeCollation.LikeEscape(cellValue, wc.Value, wc.Escape)
+		return truthOf(matched), err
+	case OpRegexMatch, OpRegexNotMatch:
+		re, err := compileRegex(wc.Value)
+		if err != nil {
+			return truthFalse, err
+		}
+		return truthOf(re.MatchString(cellValue) == (wc.Operator == OpRegexMatch)), nil
+	case OpWithin:
+		// Values that are not points are in no box
+		x, y, ok := storage.ParsePoint(cellValue)
+		return truthOf(ok && wc.Box.Contains(x, y)), nil
 	default:
 		// For numeric comparisons, try to convert to numbers
-		return evaluateNumericComparison(cellValue, wc.Value, wc.Operator)
+		holds, err := evaluateNumericComparison(cellValue, wc.Value, wc.Operator, coll)
+		return truthOf(holds), err
 	}
 }
 
-// evaluateLike evaluates LIKE pattern matching
-func evaluateLike(value, pattern string) (bool, error) {
-	// Convert SQL LIKE pattern to Go regex
-	// % -> .*
-	// _ -> .
-	// Escape other regex special characters
-	regexPattern := regexp.QuoteMeta(pattern)
-	regexPattern = strings.ReplaceAll(regexPattern, "%", ".*")
-	regexPattern = strings.ReplaceAll(regexPattern, "_", ".")
-	regexPattern = "^" + regexPattern + "$"
-
-	matched, err := regexp.MatchString(regexPattern, value)
-	return matched, err
+// evaluateNumericComparison evaluates numeric comparisons, falling back to
+// comparing text under coll
+func evaluateNumericComparison(value, compareValue string, operator WhereOperator, coll *storage.Collation) (bool, error) {
+	switch operator {
+	case OpLessThan, OpGreaterThan, OpLessThanOrEqual, OpGreaterThanOrEqual:
+		return operatorHolds(operator, compareCells(value, compareValue, coll)), nil
+	}
+	return false, fmt.Errorf("unsupported operator for comparison")
 }
 
-// evaluateNumericComparison evaluates numeric comparisons
-func evaluateNumericComparison(value, compareValue string, operator WhereOperator) (bool, error) {
-	// Try to parse as numbers
+// compareCells compares two values as numbers when both are numbers, and
+// otherwise as text under coll
+func compareCells(value, compareValue string, coll *storage.Collation) int {
 	valNum, err1 := strconv.ParseFloat(value, 64)
 	compareNum, err2 := strconv.ParseFloat(compareValue, 64)
-
-	// If both are numbers, do numeric comparison
 	if err1 == nil && err2 == nil {
-		switch operator {
-		case OpLessThan:
-			return valNum < compareNum, nil
-		case OpGreaterThan:
-			return valNum > compareNum, nil
-		case OpLessThanOrEqual:
-			return valNum <= compareNum, nil
-		case OpGreaterThanOrEqual:
-			return valNum >= compareNum, nil
+		switch {
+		case valNum < compareNum:
+			return -1
+		case valNum > compareNum:
+			return 1
 		}
+		return 0
 	}
+	return coll.Compare(value, compareValue)
+}
 
-	// Fallback to string comparison
+// operatorHolds reports whether a comparison result satisfies operator
+func operatorHolds(operator WhereOperator, cmp int) bool {
 	switch operator {
+	case OpEquals:
+		return cmp == 0
+	case OpNotEquals:
+		return cmp != 0
 	case OpLessThan:
-		return value < compareValue, nil
+		return cmp < 0
 	case OpGreaterThan:
-		return value > compareValue, nil
+		return cmp > 0
 	case OpLessThanOrEqual:
-		return value <= compareValue, nil
+		return cmp <= 0
 	case OpGreaterThanOrEqual:
-		return value >= compareValue, nil
+		return cmp >= 0
 	}
+	return false
+}
 
-	return false, fmt.Errorf("unsupported operator for comparison")
+// evaluateRow evaluates a row comparison: the first pair of values that
+// differ decides it, as in (a, b) > (x, y) meaning a > x OR (a = x AND b > y).
+// A NULL reached before the comparison is decided makes it unknown.
+func (wc *WhereCondition) evaluateRow(row []string, columnIndexes map[string]int, collations map[string]*storage.Collation) (truth, error) {
+	cmp := 0
+	for i, col := range wc.Columns {
+		colIdx, exists := columnIndexes[col]
+		if !exists {
+			return truthFalse, fmt.Errorf("column %s not found", col)
+		}
+		if colIdx >= len(row) {
+			return truthFalse, fmt.Errorf("column index out of bounds")
+		}
+		if storage.IsNull(row[colIdx]) || storage.IsNull(wc.Values[i]) {
+			return truthUnknown, nil
+		}
+		if cmp = compareCells(row[colIdx], wc.Values[i], collations[col]); cmp != 0 {
+			break
+		}
+	}
+	return truthOf(operatorHolds(wc.Operator, cmp)), nil
+}
+
+// maxCachedRegexes bounds the compiled pattern cache
+const maxCachedRegexes = 256
+
+var (
+	regexCacheMu sync.Mutex
+	regexCache   = make(map[string]*regexp.Regexp)
+)
+
+// compileRegex compiles a ~ pattern, reusing earlier compilations of the same
+// pattern so that each row does not pay for regexp.Compile
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCacheMu.Lock()
+	defer regexCacheMu.Unlock()
+
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+	}
+	if len(regexCache) >= maxCachedRegexes {
+		regexCache = make(map[string]*regexp.Regexp)
+	}
+	regexCache[pattern] = re
+	return re, nil
 }
 
-// EvaluateExpression evaluates the entire WHERE expression against a row
+// flushRegexCache drops every compiled pattern and returns how many there were
+func flushRegexCache() int {
+	regexCacheMu.Lock()
+	defer regexCacheMu.Unlock()
+	n := len(regexCache)
+	regexCache = make(map[string]*regexp.Regexp)
+	return n
+}
+
+// EvaluateExpression evaluates the entire WHERE expression against a row,
+// reporting whether it is true: a row for which it is false or unknown does
+// not match
 func (we *WhereExpression) EvaluateExpression(row []string, columnIndexes map[string]int) (bool, error) {
 	if len(we.Conditions) == 0 {
 		return true, nil
 	}
 
-	// Evaluate all conditions
-	results := make([]bool, len(we.Conditions))
+	// Evaluate all conditions, so that a bad column is reported whichever
+	// branch it is in
+	results := make([]truth, len(we.Conditions))
 	for i, condition := range we.Conditions {
-		result, err := condition.EvaluateCondition(row, columnIndexes)
+		if len(condition.Columns) > 0 {
+			result, err := condition.evaluateRow(row, columnIndexes, we.collations)
+			if err != nil {
+				return false, err
+			}
+			results[i] = result
+			continue
+		}
+		result, err := condition.evaluate(row, columnIndexes, we.collations[condition.Column])
 		if err != nil {
 			return false, err
 		}
 		results[i] = result
 	}
 
-	// Apply logic operators
-	return we.evaluateLogic(results), nil
-}
-
-// evaluateLogic applies AND/OR logic with parentheses grouping
-func (we *WhereExpression) evaluateLogic(results []bool) bool {
-	if len(results) == 1 {
-		return results[0]
+	if we.Root == nil {
+		return slices.Min(results) == truthTrue, nil
 	}
+	return we.Root.evaluate(results) == truthTrue, nil
+}
 
-	// Simple evaluation: process left to right with proper precedence
-	// For now, we'll implement a basic version without full parentheses support
-	// This can be enhanced later with a proper expression tree
-	result := results[0]
-
-	for i := 0; i < len(we.LogicOps); i++ {
-		if i+1 >= len(results) {
-			break
+// evaluate combines the results of the expression's conditions as the tree
+// under n says: AND is the least of its operands, OR the greatest, and NOT
+// leaves unknown unknown
+func (n *WhereNode) evaluate(results []truth) truth {
+	switch n.Op {
+	case "AND":
+		result := truthTrue
+		for _, child := range n.Children {
+			result = min(result, child.evaluate(results))
 		}
-
-		nextResult := results[i+1]
-
-		switch we.LogicOps[i] {
-		case "AND":
-			result = result && nextResult
-		case "OR":
-			result = result || nextResult
+		return result
+	case "OR":
+		result := truthFalse
+		for _, child := range n.Children {
+			result = max(result, child.evaluate(results))
 		}
+		return result
+	case "NOT":
+		return truthTrue - n.Children[0].evaluate(results)
 	}
-
-	return result
+	return results[n.Condition]
 }
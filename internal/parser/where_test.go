@@ -2,7 +2,10 @@
 package parser
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
 )
 
 func TestParseWhereClause(t *testing.T) {
@@ -54,6 +57,23 @@ func TestParseWhereClause(t *testing.T) {
 			expectError: false,
 			expectedOps: []WhereOperator{OpLike},
 		},
+		{
+			name:        "regex match",
+			whereClause: "email ~ '^[a-z]+@example\\.com$'",
+			expectError: false,
+			expectedOps: []WhereOperator{OpRegexMatch},
+		},
+		{
+			name:        "regex not match",
+			whereClause: "name !~ '(?i)^j'",
+			expectError: false,
+			expectedOps: []WhereOperator{OpRegexNotMatch},
+		},
+		{
+			name:        "invalid regex",
+			whereClause: "name ~ 'a(b'",
+			expectError: true,
+		},
 		{
 			name:        "and condition",
 			whereClause: "age > 18 AND status = 'active'",
@@ -165,6 +185,24 @@ func TestEvaluateCondition(t *testing.T) {
 			condition: WhereCondition{Column: "email", Operator: OpLike, Value: "jane%"},
 			expected:  false,
 		},
+		{
+			name:      "regex match",
+			row:       []string{"John", "25", "john@example.com"},
+			condition: WhereCondition{Column: "email", Operator: OpRegexMatch, Value: `@example\.com$`},
+			expected:  true,
+		},
+		{
+			name:      "regex is case-sensitive",
+			row:       []string{"John", "25", "john@example.com"},
+			condition: WhereCondition{Column: "name", Operator: OpRegexMatch, Value: "^john"},
+			expected:  false,
+		},
+		{
+			name:      "regex not match",
+			row:       []string{"John", "25", "john@example.com"},
+			condition: WhereCondition{Column: "age", Operator: OpRegexNotMatch, Value: `^\d+$`},
+			expected:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,8 +239,6 @@ func TestEvaluateExpression(t *testing.T) {
 				Conditions: []WhereCondition{
 					{Column: "age", Operator: OpGreaterThan, Value: "20"},
 				},
-				LogicOps: []string{},
-				Groups:   []int{0},
 			},
 			expected: true,
 		},
@@ -213,8 +249,6 @@ func TestEvaluateExpression(t *testing.T) {
 				Conditions: []WhereCondition{
 					{Column: "age", Operator: OpLessThan, Value: "20"},
 				},
-				LogicOps: []string{},
-				Groups:   []int{0},
 			},
 			expected: false,
 		},
@@ -226,8 +260,7 @@ func TestEvaluateExpression(t *testing.T) {
 					{Column: "age", Operator: OpGreaterThan, Value: "20"},
 					{Column: "status", Operator: OpEquals, Value: "active"},
 				},
-				LogicOps: []string{"AND"},
-				Groups:   []int{0, 0},
+				Root: &WhereNode{Op: "AND", Children: []*WhereNode{{Condition: 0}, {Condition: 1}}},
 			},
 			expected: true,
 		},
@@ -239,8 +272,7 @@ func TestEvaluateExpression(t *testing.T) {
 					{Column: "age", Operator: OpGreaterThan, Value: "20"},
 					{Column: "status", Operator: OpEquals, Value: "active"},
 				},
-				LogicOps: []string{"AND"},
-				Groups:   []int{0, 0},
+				Root: &WhereNode{Op: "AND", Children: []*WhereNode{{Condition: 0}, {Condition: 1}}},
 			},
 			expected: false,
 		},
@@ -252,8 +284,7 @@ func TestEvaluateExpression(t *testing.T) {
 					{Column: "age", Operator: OpGreaterThan, Value: "20"},
 					{Column: "status", Operator: OpEquals, Value: "active"},
 				},
-				LogicOps: []string{"OR"},
-				Groups:   []int{0, 0},
+				Root: &WhereNode{Op: "OR", Children: []*WhereNode{{Condition: 0}, {Condition: 1}}},
 			},
 			expected: true,
 		},
@@ -265,8 +296,7 @@ func TestEvaluateExpression(t *testing.T) {
 					{Column: "age", Operator: OpGreaterThan, Value: "20"},
 					{Column: "status", Operator: OpEquals, Value: "active"},
 				},
-				LogicOps: []string{"OR"},
-				Groups:   []int{0, 0},
+				Root: &WhereNode{Op: "OR", Children: []*WhereNode{{Condition: 0}, {Condition: 1}}},
 			},
 			expected: false,
 		},
@@ -317,6 +347,11 @@ func TestTokenizeWhere(t *testing.T) {
 			input:    "(age > 18) AND (status = 'active')",
 			expected: []string{"(", "age", ">", "18", ")", "AND", "(", "status", "=", "active", ")"},
 		},
+		{
+			name:     "row comparison",
+			input:    "(kind, id) >= ('a,b', 7)",
+			expected: []string{"(", "kind", ",", "id", ")", ">=", "(", "a,b", ",", "7", ")"},
+		},
 		{
 			name:     "like with spaces",
 			input:    "name LIKE 'John%' AND age > 25",
@@ -339,3 +374,323 @@ func TestTokenizeWhere(t *testing.T) {
 		})
 	}
 }
+
+func TestCollatedWhere(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (id, name COLLATE NOCASE, code)")
+	engine.Execute("INSERT INTO users VALUES (1, Hareesh, AB)")
+	engine.Execute("INSERT INTO users VALUES (2, bhittam, ab)")
+
+	tests := []struct {
+		where string
+		want  []string
+	}{
+		{"name = 'HAREESH'", []string{"Hareesh"}},
+		{"name LIKE 'BHI%'", []string{"bhittam"}},
+		{"name > 'Cat'", []string{"Hareesh"}},
+		{"code = 'ab'", []string{"bhittam"}},
+	}
+	for _, tt := range tests {
+		out := engine.Execute("SELECT * FROM users WHERE " + tt.where)
+		rows := strings.Count(out, "\n") - 1
+		if rows != len(tt.want) {
+			t.Errorf("%s: expected %d row(s), got:\n%s", tt.where, len(tt.want), out)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: expected %s in:\n%s", tt.where, want, out)
+			}
+		}
+	}
+}
+
+func TestWithinBox(t *testing.T) {
+	expr, err := ParseWhereClause("location WITHIN BOX(10, -2.5, 0, 4) AND name != 'x'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box, ok := expr.WithinBox("location"); !ok || box.String() != "BOX(0, -2.5, 10, 4)" {
+		t.Errorf("WithinBox = %v, %v", box, ok)
+	}
+	columns := map[string]int{"name": 0, "location": 1}
+	for value, want := range map[string]bool{"(0, 0)": true, "(10, 4)": true, "(10.5, 0)": false, "NULL": false, "": false} {
+		if got, err := expr.EvaluateExpression([]string{"a", value}, columns); got != want || err != nil {
+			t.Errorf("%q: got %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	if expr, _ := ParseWhereClause("location WITHIN BOX(0, 0, 1, 1) OR name = 'x'"); expr != nil {
+		if _, ok := expr.WithinBox("location"); ok {
+			t.Error("a box joined by OR does not bound the matches")
+		}
+	}
+	for _, clause := range []string{"location WITHIN BOX(0, 0, 1)", "location WITHIN CIRCLE(0, 0, 1)", "location WITHIN BOX(0, 0, 1, y)", "location WITHIN"} {
+		if _, err := ParseWhereClause(clause); err == nil {
+			t.Errorf("%s should not parse", clause)
+		}
+	}
+
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE places (name TEXT, location POINT)")
+	engine.Execute("INSERT INTO places VALUES ('cafe', '1.5 2')")
+	engine.Execute("INSERT INTO places VALUES ('park', 'POINT(5 5)')")
+	engine.Execute("INSERT INTO places VALUES ('museum', '(-3, 8.25)')")
+	engine.Execute("CREATE INDEX ON places (location)")
+	if got := engine.Execute("INSERT INTO places VALUES ('bad', 'north')"); !strings.Contains(got, "does not match type POINT") {
+		t.Errorf("non-point insert: %q", got)
+	}
+	if got, want := engine.Execute("SELECT * FROM places WHERE location WITHIN BOX(0, 0, 6, 6)"), "name | location\ncafe | (1.5, 2)\npark | (5, 5)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := engine.Execute("EXPLAIN SELECT * FROM places WHERE location WITHIN BOX(-5, 0, 2, 10)"); got != "plan\nGrid Index Lookup on places using location within BOX(-5, 0, 2, 10)\n" {
+		t.Errorf("unexpected plan %q", got)
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	expr, err := ParseWhereClause("name IS NULL OR age is not null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expr.Conditions) != 2 || expr.Conditions[0].Operator != OpIsNull || expr.Conditions[1].Operator != OpIsNotNull {
+		t.Fatalf("conditions: %+v", expr.Conditions)
+	}
+	for _, clause := range []string{"name IS", "name IS NOT", "name IS 'x'", "name IS NOT 'x'"} {
+		if _, err := ParseWhereClause(clause); err == nil {
+			t.Errorf("%s should not parse", clause)
+		}
+	}
+
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE people (id, name)")
+	engine.Execute("INSERT INTO people VALUES (1, NULL)")
+	engine.Execute("INSERT INTO people VALUES (2, 'NULL')")
+	engine.Execute("INSERT INTO people VALUES (3, '')")
+
+	// NULL is not the text NULL, nor empty text
+	for input, want := range map[string]string{
+		"SELECT id FROM people WHERE name IS NULL":                "id\n1\n",
+		"SELECT id FROM people WHERE name IS NOT NULL":            "id\n2\n3\n",
+		"SELECT id FROM people WHERE name IS NOT NULL AND id < 3": "id\n2\n",
+		"SELECT COUNT(*) FROM people WHERE name IS NULL":          "count\n1\n",
+		"SELECT id FROM people WHERE (name IS NULL OR id = 3)":    "id\n1\n3\n",
+		"SELECT id FROM people WHERE name IS 'x'":                 "WHERE clause error: IS expects [NOT] NULL, TRUE, FALSE or UNKNOWN",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+	if got := engine.Execute("UPDATE people SET name = 'Ann' WHERE name IS NULL"); got != "1 rows updated" {
+		t.Errorf("update: %q", got)
+	}
+	if got := engine.Execute("SELECT id FROM people WHERE name IS NULL"); got != "id\n(no rows)\n" {
+		t.Errorf("after update: %q", got)
+	}
+}
+
+func TestWherePrecedence(t *testing.T) {
+	columns := map[string]int{"a": 0, "b": 1, "c": 2}
+	row := []string{"1", "0", "0"}
+	for clause, want := range map[string]bool{
+		// AND binds tighter than OR
+		"a = 1 OR b = 1 AND c = 1":   true,
+		"b = 1 AND c = 1 OR a = 1":   true,
+		"(a = 1 OR b = 1) AND c = 1": false,
+		"a = 1 AND (b = 1 OR c = 0)": true,
+		// NOT binds tighter than AND
+		"NOT a = 1":                      false,
+		"not b = 1 AND c = 0":            true,
+		"NOT (a = 1 AND b = 1)":          true,
+		"NOT (a = 1 OR b = 1)":           false,
+		"NOT NOT a = 1":                  true,
+		"NOT a = 1 OR NOT b = 1":         true,
+		"((a = 1))":                      true,
+		"(b = 1 OR (c = 1 OR (a = 1)))":  true,
+		"NOT (b = 1) AND NOT (c = 1)":    true,
+		"a = 1 AND NOT (b = 0 OR c = 1)": false,
+	} {
+		expr, err := ParseWhereClause(clause)
+		if err != nil {
+			t.Errorf("%s: %v", clause, err)
+			continue
+		}
+		if got, err := expr.EvaluateExpression(row, columns); err != nil || got != want {
+			t.Errorf("%s: got %v, %v; want %v", clause, got, err, want)
+		}
+	}
+
+	for _, clause := range []string{"a = 1 AND", "AND a = 1", "(a = 1", "a = 1)", "NOT", "a = 1 b = 2", "a = 1 OR OR b = 2", "()"} {
+		if _, err := ParseWhereClause(clause); err == nil {
+			t.Errorf("%s should not parse", clause)
+		}
+	}
+
+	// Bounds come from conditions joined to the rest by AND, outside NOT
+	expr, _ := ParseWhereClause("a > 5 AND (b = 1 OR c = 2)")
+	if v, ok := expr.SeekBound("a", false); !ok || v != "5" {
+		t.Errorf("SeekBound = %q, %v", v, ok)
+	}
+	for _, clause := range []string{"NOT a > 5", "a > 5 OR b = 1", "NOT (a = 5 AND b = 1)"} {
+		expr, _ := ParseWhereClause(clause)
+		if _, ok := expr.SeekBound("a", false); ok {
+			t.Errorf("%s: SeekBound found a bound", clause)
+		}
+		if _, ok := expr.EqualityValue("a"); ok {
+			t.Errorf("%s: EqualityValue found a value", clause)
+		}
+	}
+
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE users (name, age, role)")
+	engine.Execute("CREATE INDEX ON users (age)")
+	engine.Execute("INSERT INTO users VALUES ('ann', 30, 'admin')")
+	engine.Execute("INSERT INTO users VALUES ('bob', 20, 'user')")
+	engine.Execute("INSERT INTO users VALUES ('cy', 40, 'user')")
+	for input, want := range map[string]string{
+		"SELECT name FROM users WHERE role = 'admin' OR role = 'user' AND age > 35": "name\nann\ncy\n",
+		"SELECT name FROM users WHERE NOT role = 'admin' ORDER BY age DESC":         "name\ncy\nbob\n",
+		"SELECT name FROM users WHERE NOT (age > 25 AND role = 'user')":             "name\nann\nbob\n",
+		"SELECT name FROM users WHERE age = 20 OR NOT age < 35 AND role = 'user'":   "name\nbob\ncy\n",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestBooleanLogic(t *testing.T) {
+	expr, err := ParseWhereClause("active IS NOT TRUE OR flag IS FALSE OR note IS UNKNOWN OR ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := []WhereOperator{OpIsNotTrue, OpIsFalse, OpIsNull, OpTruth}
+	for i, op := range ops {
+		if expr.Conditions[i].Operator != op {
+			t.Errorf("condition %d: %+v", i, expr.Conditions[i])
+		}
+	}
+	expr, err = ParseWhereClause("a = TRUE AND b = 'TRUE' AND c = null AND d = 'null'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"true", "TRUE", storage.NullValue, "null"} {
+		if got := expr.Conditions[i].Value; got != want {
+			t.Errorf("value %d: %q, want %q", i, got, want)
+		}
+	}
+
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE flags (id INT, active BOOL, note TEXT)")
+	engine.Execute("INSERT INTO flags VALUES (1, TRUE, 'a')")
+	engine.Execute("INSERT INTO flags VALUES (2, false, NULL)")
+	engine.Execute("INSERT INTO flags VALUES (3, NULL, 'c')")
+
+	// A comparison with NULL is unknown, and only true rows match
+	for input, want := range map[string]string{
+		"SELECT id FROM flags WHERE active":                      "id\n1\n",
+		"SELECT id FROM flags WHERE NOT active":                  "id\n2\n",
+		"SELECT id FROM flags WHERE active = TRUE":               "id\n1\n",
+		"SELECT id FROM flags WHERE active IS NOT TRUE":          "id\n2\n3\n",
+		"SELECT id FROM flags WHERE active IS FALSE":             "id\n2\n",
+		"SELECT id FROM flags WHERE active IS UNKNOWN":           "id\n3\n",
+		"SELECT id FROM flags WHERE note != 'a'":                 "id\n3\n",
+		"SELECT id FROM flags WHERE NOT note = 'a'":              "id\n3\n",
+		"SELECT id FROM flags WHERE note = 'x' OR active":        "id\n1\n",
+		"SELECT id FROM flags WHERE NOT (note = 'c' AND active)": "id\n1\n2\n",
+		"SELECT id FROM flags WHERE note != 'a' OR note IS NULL": "id\n2\n3\n",
+		"SELECT id FROM flags WHERE note = NULL":                 "id\n(no rows)\n",
+		"SELECT id FROM flags WHERE (id, note) >= (2, 'a')":      "id\n3\n",
+		"SELECT id FROM flags WHERE note":                        "Error evaluating WHERE condition: column note is not boolean: a",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestBareTruthValues(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE flags (id INT, active BOOL, note TEXT)")
+	engine.Execute("INSERT INTO flags VALUES (1, TRUE, 'a')")
+	engine.Execute("INSERT INTO flags VALUES (2, false, NULL)")
+	engine.Execute("INSERT INTO flags VALUES (3, NULL, 'c')")
+
+	for input, want := range map[string]string{
+		"SELECT id FROM flags WHERE TRUE":                        "id\n1\n2\n3\n",
+		"SELECT id FROM flags WHERE true":                        "id\n1\n2\n3\n",
+		"SELECT id FROM flags WHERE FALSE":                       "id\n(no rows)\n",
+		"SELECT id FROM flags WHERE NOT TRUE":                    "id\n(no rows)\n",
+		"SELECT id FROM flags WHERE NOT FALSE":                   "id\n1\n2\n3\n",
+		"SELECT id FROM flags WHERE TRUE AND id > 1":             "id\n2\n3\n",
+		"SELECT id FROM flags WHERE FALSE AND id > 1":            "id\n(no rows)\n",
+		"SELECT id FROM flags WHERE TRUE OR id > 1":              "id\n1\n2\n3\n",
+		"SELECT id FROM flags WHERE FALSE OR id > 1":             "id\n2\n3\n",
+		"SELECT id FROM flags WHERE FALSE OR active":             "id\n1\n",
+		"SELECT id FROM flags WHERE NOT FALSE AND NOT TRUE":      "id\n(no rows)\n",
+		"SELECT id FROM flags WHERE (FALSE OR TRUE) AND id < 3":  "id\n1\n2\n",
+		"SELECT id FROM flags WHERE NOT (note = NULL AND FALSE)": "id\n1\n2\n3\n",
+		"SELECT id FROM flags WHERE NOT (note = NULL OR FALSE)":  "id\n(no rows)\n",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+
+	engine.Execute("DELETE FROM flags WHERE FALSE")
+	engine.Execute("UPDATE flags SET note = 'x' WHERE TRUE AND id = 2")
+	if got := engine.Execute("SELECT id, note FROM flags WHERE NOT FALSE"); got != "id | note\n1 | a\n2 | x\n3 | c\n" {
+		t.Errorf("after DELETE WHERE FALSE and UPDATE WHERE TRUE: %q", got)
+	}
+}
+
+func TestLikeEscapeAndILike(t *testing.T) {
+	expr, err := ParseWhereClause(`code LIKE '50\%' ESCAPE '\' AND name ILIKE 'a%'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := expr.Conditions[0]; c.Operator != OpLike || c.Escape != '\\' || c.Value != `50\%` {
+		t.Errorf("LIKE ESCAPE: %+v", c)
+	}
+	if c := expr.Conditions[1]; c.Operator != OpILike || c.Escape != 0 {
+		t.Errorf("ILIKE: %+v", c)
+	}
+	for _, clause := range []string{
+		`code LIKE 'x' ESCAPE`,
+		`code LIKE 'x' ESCAPE '!!'`,
+		`code LIKE 'x!' ESCAPE '!'`,
+	} {
+		if _, err := ParseWhereClause(clause); err == nil {
+			t.Errorf("%s should not parse", clause)
+		}
+	}
+
+	engine := NewEngine(testDataDir(t))
+	engine.Execute("LOGIN admin admin123")
+	engine.Execute("CREATE TABLE codes (id, code)")
+	engine.Execute("INSERT INTO codes VALUES (1, '50%')")
+	engine.Execute("INSERT INTO codes VALUES (2, '500')")
+	engine.Execute("INSERT INTO codes VALUES (3, 'A_B')")
+	engine.Execute("INSERT INTO codes VALUES (4, 'axb')")
+	engine.Execute("INSERT INTO codes VALUES (5, NULL)")
+
+	for input, want := range map[string]string{
+		"SELECT id FROM codes WHERE code LIKE '50%'":              "id\n1\n2\n",
+		"SELECT id FROM codes WHERE code LIKE '50!%' ESCAPE '!'":  "id\n1\n",
+		"SELECT id FROM codes WHERE code LIKE 'a_b'":              "id\n4\n",
+		"SELECT id FROM codes WHERE code ILIKE 'a_b'":             "id\n3\n4\n",
+		"SELECT id FROM codes WHERE code ILIKE 'a#_b' ESCAPE '#'": "id\n3\n",
+		"SELECT id FROM codes WHERE code ILIKE 'A%' AND id > 3":   "id\n4\n",
+		"SELECT id FROM codes WHERE NOT code ILIKE '%b'":          "id\n1\n2\n",
+		"SELECT id FROM codes WHERE code LIKE '%x' ESCAPE 'x'":    "WHERE clause error: LIKE pattern ends with its escape character",
+	} {
+		if got := engine.Execute(input); got != want {
+			t.Errorf("%s: %q, want %q", input, got, want)
+		}
+	}
+}
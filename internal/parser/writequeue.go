@@ -0,0 +1,119 @@
+// internal/parser/writequeue.go
+//
+// Write admission. A statement that changes data takes a slot in the write
+// queue before it runs and gives it back when it finishes, so a flood of
+// inserts waits at its connection, with further input left unread in the
+// socket, instead of piling up goroutines and rows in memory. When every
+// slot is taken, the session's write_queue_policy decides whether the
+// connection blocks until one frees up or the statement fails at once.
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWriteQueueDepth is the number of writes that may run or wait for
+// locks at once
+const DefaultWriteQueueDepth = 1024
+
+// WriteQueue bounds the number of writes in flight. A nil WriteQueue admits
+// every write.
+type WriteQueue struct {
+	slots chan struct{}
+	// waiting counts connections blocked for a slot
+	waiting atomic.Int64
+}
+
+// NewWriteQueue returns a queue admitting depth writes at once, or nil for
+// an unbounded queue when depth is 0 or less
+func NewWriteQueue(depth int) *WriteQueue {
+	if depth <= 0 {
+		return nil
+	}
+	return &WriteQueue{slots: make(chan struct{}, depth)}
+}
+
+// Acquire takes a slot. When the queue is full it fails at once unless block
+// is set, in which case it waits up to timeout (0 = no limit).
+func (q *WriteQueue) Acquire(block bool, timeout time.Duration) error {
+	if q == nil {
+		return nil
+	}
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if !block {
+		return fmt.Errorf("write queue is full (%d writes in flight); retry later", cap(q.slots))
+	}
+
+	q.waiting.Add(1)
+	defer q.waiting.Add(-1)
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	case <-expired:
+		return fmt.Errorf("wait for the write queue timed out after %s", timeout)
+	}
+}
+
+// Release gives back a slot taken by Acquire
+func (q *WriteQueue) Release() {
+	if q != nil {
+		<-q.slots
+	}
+}
+
+// Depth returns the number of slots, 0 for an unbounded queue
+func (q *WriteQueue) Depth() int {
+	if q == nil {
+		return 0
+	}
+	return cap(q.slots)
+}
+
+// InFlight returns the number of slots taken
+func (q *WriteQueue) InFlight() int {
+	if q == nil {
+		return 0
+	}
+	return len(q.slots)
+}
+
+// Waiting returns the number of connections blocked for a slot
+func (q *WriteQueue) Waiting() int {
+	if q == nil {
+		return 0
+	}
+	return int(q.waiting.Load())
+}
+
+// AdmitWrite takes a write queue slot when any of statements changes data,
+// following the session's write_queue_policy. It returns the function that
+// gives the slot back, or the error to send instead of running them.
+func (e *Engine) AdmitWrite(statements ...string) (func(), string) {
+	write := false
+	for _, stmt := range statements {
+		if isDataWrite(strings.ToUpper(strings.TrimSpace(stmt))) {
+			write = true
+			break
+		}
+	}
+	if !write || e.Writes == nil {
+		return func() {}, ""
+	}
+	if err := e.Writes.Acquire(e.setting("write_queue_policy") == "block", e.StatementTimeout()); err != nil {
+		return nil, "Error: " + err.Error()
+	}
+	return e.Writes.Release, ""
+}
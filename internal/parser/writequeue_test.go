@@ -0,0 +1,62 @@
+// internal/parser/writequeue_test.go
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestWriteQueue(t *testing.T) {
+	engine := NewEngine(testDataDir(t))
+	engine.Writes = NewWriteQueue(1)
+	engine.Execute("LOGIN admin admin123")
+
+	release, rejected := engine.AdmitWrite("INSERT INTO users VALUES ('1')")
+	if rejected != "" {
+		t.Fatalf("first write rejected: %s", rejected)
+	}
+
+	// Reads never take a slot
+	if _, rejected := engine.AdmitWrite("SELECT * FROM users"); rejected != "" {
+		t.Errorf("read rejected: %s", rejected)
+	}
+
+	t.Run("ErrorPolicy", func(t *testing.T) {
+		engine.Execute("SET write_queue_policy = error")
+		defer engine.Execute("SET write_queue_policy = DEFAULT")
+		_, rejected := engine.AdmitWrite("SELECT 1", "DELETE FROM users ROW 0")
+		if protocol.CodeOf(MessageError(rejected)) != protocol.CodeBusy {
+			t.Errorf("expected a BUSY error, got %q", rejected)
+		}
+	})
+
+	t.Run("BlockPolicy", func(t *testing.T) {
+		engine.Execute("SET statement_timeout = 20ms")
+		defer engine.Execute("SET statement_timeout = DEFAULT")
+		if _, rejected := engine.AdmitWrite("UPDATE users SET id = '2' ROW 0"); protocol.CodeOf(MessageError(rejected)) != protocol.CodeTimeout {
+			t.Errorf("expected a TIMEOUT error, got %q", rejected)
+		}
+
+		admitted := make(chan func())
+		go func() {
+			next, _ := engine.AdmitWrite("INSERT INTO users VALUES ('2')")
+			admitted <- next
+		}()
+		for engine.Writes.Waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		release()
+		select {
+		case next := <-admitted:
+			next()
+		case <-time.After(time.Second):
+			t.Fatal("blocked write was not admitted after the slot was released")
+		}
+	})
+
+	if n := engine.Writes.InFlight(); n != 0 {
+		t.Errorf("%d slots still taken", n)
+	}
+}
@@ -0,0 +1,198 @@
+// internal/protocol/backup_stream.go
+package protocol
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The server protocol is line oriented, so a backup streamed with
+// BACKUP TO STDOUT is sent as base64 lines between a begin marker and an end
+// marker carrying the archive size and its SHA-256:
+//
+//	BACKUP STREAM BEGIN
+//	<base64 data, 76 characters per line>
+//	BACKUP STREAM END <size> <sha256>
+//
+// Older servers sent the size on the begin marker and only the SHA-256 on
+// the end marker; DecodeBackupStream reads both.
+const (
+	BackupStreamBegin = "BACKUP STREAM BEGIN"
+	BackupStreamEnd   = "BACKUP STREAM END"
+
+	backupStreamLineWidth = 76
+)
+
+// BackupStreamWriter frames the archive written to it for sending to a
+// client, writing lines as the archive is produced rather than holding it
+// in memory. The begin marker is written with the first data, so a backup
+// that fails before writing anything sends nothing.
+type BackupStreamWriter struct {
+	out     *bufio.Writer
+	lines   *lineWrapper
+	encoder io.WriteCloser
+	hash    hash.Hash
+	size    int64
+	started bool
+}
+
+// NewBackupStreamWriter returns a BackupStreamWriter writing to w. Close
+// must be called to write the end marker.
+func NewBackupStreamWriter(w io.Writer) *BackupStreamWriter {
+	out := bufio.NewWriter(w)
+	lines := &lineWrapper{w: out}
+	return &BackupStreamWriter{
+		out:     out,
+		lines:   lines,
+		encoder: base64.NewEncoder(base64.StdEncoding, lines),
+		hash:    sha256.New(),
+	}
+}
+
+// Write encodes part of the archive
+func (s *BackupStreamWriter) Write(p []byte) (int, error) {
+	if err := s.start(); err != nil {
+		return 0, err
+	}
+	n, err := s.encoder.Write(p)
+	s.hash.Write(p[:n])
+	s.size += int64(n)
+	return n, err
+}
+
+// Close writes the rest of the archive and the end marker
+func (s *BackupStreamWriter) Close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.encoder.Close(); err != nil {
+		return err
+	}
+	if err := s.lines.flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "%s %d %s\n", BackupStreamEnd, s.size, hex.EncodeToString(s.hash.Sum(nil)))
+	return s.out.Flush()
+}
+
+// Size returns the number of archive bytes written so far
+func (s *BackupStreamWriter) Size() int64 {
+	return s.size
+}
+
+// start writes the begin marker once
+func (s *BackupStreamWriter) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	_, err := fmt.Fprintf(s.out, "%s\n", BackupStreamBegin)
+	return err
+}
+
+// lineWrapper writes base64 text in lines of backupStreamLineWidth
+type lineWrapper struct {
+	w *bufio.Writer
+	// column is the length of the line being written
+	column int
+}
+
+func (l *lineWrapper) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), backupStreamLineWidth-l.column)
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+		if l.column += n; l.column == backupStreamLineWidth {
+			if err := l.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush ends the line being written, if any
+func (l *lineWrapper) flush() error {
+	if l.column == 0 {
+		return nil
+	}
+	l.column = 0
+	return l.w.WriteByte('\n')
+}
+
+// IsBackupStreamBegin reports whether line starts a streamed backup
+func IsBackupStreamBegin(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == BackupStreamBegin || strings.HasPrefix(line, BackupStreamBegin+" ")
+}
+
+// DecodeBackupStream reads a streamed backup from r, whose begin marker line
+// has already been consumed and is passed as begin, and writes the decoded
+// archive to w. It returns the number of bytes written after checking the
+// size and checksum sent by the server.
+func DecodeBackupStream(begin string, r *bufio.Reader, w io.Writer) (int64, error) {
+	size := int64(-1)
+	if sizeField := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(begin), BackupStreamBegin)); sizeField != "" {
+		n, err := strconv.ParseInt(sizeField, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid backup stream header: %q", strings.TrimSpace(begin))
+		}
+		size = n
+	}
+
+	hash := sha256.New()
+	out := io.MultiWriter(w, hash)
+	var written int64
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return written, fmt.Errorf("backup stream interrupted: %w", err)
+		}
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, BackupStreamEnd) {
+			fields := strings.Fields(strings.TrimPrefix(line, BackupStreamEnd))
+			if len(fields) == 2 {
+				n, err := strconv.ParseInt(fields[0], 10, 64)
+				if err != nil || n < 0 {
+					return written, fmt.Errorf("invalid backup stream trailer: %q", line)
+				}
+				size, fields = n, fields[1:]
+			}
+			if len(fields) != 1 || size < 0 {
+				return written, fmt.Errorf("invalid backup stream trailer: %q", line)
+			}
+			if written != size {
+				return written, fmt.Errorf("backup stream size mismatch: expected %d bytes, got %d", size, written)
+			}
+			if got := hex.EncodeToString(hash.Sum(nil)); got != fields[0] {
+				return written, fmt.Errorf("backup stream checksum mismatch")
+			}
+			return written, nil
+		}
+
+		chunk, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			// The server reports a backup failing part way through
+			// after the data sent so far
+			return written, fmt.Errorf("backup stream interrupted: %s", line)
+		}
+		n, err := out.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+}
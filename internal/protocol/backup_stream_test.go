@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// encodeBackupStream frames data as a server streams it
+func encodeBackupStream(t *testing.T, data []byte) string {
+	t.Helper()
+	var sb strings.Builder
+	s := NewBackupStreamWriter(&sb)
+	// Written in uneven pieces, as an archive is produced
+	for len(data) > 0 {
+		n := min(len(data), 1000)
+		if _, err := s.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestBackupStreamRoundTrip(t *testing.T) {
+	for _, data := range [][]byte{
+		bytes.Repeat([]byte("haruDB backup\x00\x01\x02"), 50),
+		bytes.Repeat([]byte{7}, 57),
+		nil,
+	} {
+		stream := encodeBackupStream(t, data)
+		for _, line := range strings.Split(strings.TrimSuffix(stream, "\n"), "\n") {
+			if len(line) > backupStreamLineWidth && !strings.HasPrefix(line, BackupStreamEnd) {
+				t.Fatalf("line longer than %d characters: %q", backupStreamLineWidth, line)
+			}
+		}
+
+		r := bufio.NewReader(strings.NewReader(stream + "haruDB> \n"))
+		begin, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read begin marker: %v", err)
+		}
+		if !IsBackupStreamBegin(begin) {
+			t.Fatalf("expected begin marker, got %q", begin)
+		}
+
+		var out bytes.Buffer
+		n, err := DecodeBackupStream(begin, r, &out)
+		if err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("decoded %d bytes that do not match the original %d", n, len(data))
+		}
+
+		// The prompt following the stream must be left for the caller
+		if rest, _ := r.ReadString('\n'); rest != "haruDB> \n" {
+			t.Errorf("expected prompt after stream, got %q", rest)
+		}
+	}
+}
+
+func TestBackupStreamIsWrittenAsProduced(t *testing.T) {
+	var out bytes.Buffer
+	s := NewBackupStreamWriter(&out)
+	if out.Len() != 0 {
+		t.Fatal("begin marker written before any data")
+	}
+	if _, err := s.Write(bytes.Repeat([]byte("x"), 64<<10)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), BackupStreamBegin+"\n") || out.Len() < 64<<10 {
+		t.Errorf("only %d bytes sent before Close", out.Len())
+	}
+	if s.Size() != 64<<10 {
+		t.Errorf("size %d", s.Size())
+	}
+}
+
+func TestBackupStreamReadsOlderFraming(t *testing.T) {
+	data := []byte("archive from an older server")
+	sum := sha256.Sum256(data)
+	stream := fmt.Sprintf("%s %d\n%s\n%s %s\n", BackupStreamBegin, len(data),
+		base64.StdEncoding.EncodeToString(data), BackupStreamEnd, hex.EncodeToString(sum[:]))
+
+	r := bufio.NewReader(strings.NewReader(stream))
+	begin, _ := r.ReadString('\n')
+	var out bytes.Buffer
+	if _, err := DecodeBackupStream(begin, r, &out); err != nil || out.String() != string(data) {
+		t.Errorf("decoded %q, %v", out.String(), err)
+	}
+}
+
+func TestBackupStreamDetectsCorruption(t *testing.T) {
+	stream := encodeBackupStream(t, []byte("some archive bytes"))
+	lines := strings.Split(stream, "\n")
+	lines[1] = "AAAA" + lines[1][4:]
+
+	interrupted := encodeBackupStream(t, bytes.Repeat([]byte("y"), 500))
+	interrupted = interrupted[:strings.Index(interrupted, BackupStreamEnd)] + "ERROR INTERNAL: Backup failed: disk\n"
+
+	for _, stream := range []string{strings.Join(lines, "\n"), interrupted} {
+		r := bufio.NewReader(strings.NewReader(stream))
+		begin, _ := r.ReadString('\n')
+		if _, err := DecodeBackupStream(begin, r, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected stream to fail verification:\n%s", stream)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// internal/protocol/batch.go
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A batch is sent as a header line followed by its statements, one per
+// line, without waiting for prompts in between. The server answers once,
+// after running the whole batch:
+//
+//	BATCH <n>
+//	<statement 1>
+//	...
+//	<statement n>
+const BatchCommand = "BATCH"
+
+// MaxBatchStatements caps the statements in one batch
+const MaxBatchStatements = 100000
+
+// EncodeBatch frames statements as a batch
+func EncodeBatch(statements []string) (string, error) {
+	if len(statements) == 0 || len(statements) > MaxBatchStatements {
+		return "", fmt.Errorf("a batch holds 1 to %d statements, got %d", MaxBatchStatements, len(statements))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d\n", BatchCommand, len(statements))
+	for i, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" || strings.ContainsAny(stmt, "\r\n") {
+			return "", fmt.Errorf("batch statement %d must be a single non-empty line", i+1)
+		}
+		b.WriteString(stmt)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// ParseBatchHeader returns the statement count of a BATCH header line. ok
+// is false when line is not a batch header at all. A malformed header
+// fails with a CodeSyntax *Error.
+func ParseBatchHeader(line string) (n int, ok bool, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], BatchCommand) {
+		return 0, false, nil
+	}
+	if len(fields) != 2 {
+		return 0, true, &Error{Code: CodeSyntax, Message: fmt.Sprintf("Syntax error: %s n", BatchCommand)}
+	}
+	n, err = strconv.Atoi(fields[1])
+	if err != nil || n < 1 || n > MaxBatchStatements {
+		return 0, true, &Error{Code: CodeSyntax, Message: fmt.Sprintf("Syntax error: batch size must be between 1 and %d", MaxBatchStatements)}
+	}
+	return n, true, nil
+}
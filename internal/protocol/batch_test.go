@@ -0,0 +1,39 @@
+package protocol
+
+import "testing"
+
+func TestBatchFrame(t *testing.T) {
+	frame, err := EncodeBatch([]string{"INSERT INTO t VALUES (1)", " INSERT INTO t VALUES (2); "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "BATCH 2\nINSERT INTO t VALUES (1)\nINSERT INTO t VALUES (2);\n"; frame != want {
+		t.Errorf("frame = %q, want %q", frame, want)
+	}
+	for _, bad := range [][]string{nil, {"INSERT\nINTO t"}, {"  "}} {
+		if _, err := EncodeBatch(bad); err == nil {
+			t.Errorf("EncodeBatch(%q) succeeded", bad)
+		}
+	}
+
+	tests := []struct {
+		line   string
+		n      int
+		ok     bool
+		hasErr bool
+	}{
+		{"BATCH 2", 2, true, false},
+		{"batch 10", 10, true, false},
+		{"BATCH", 0, true, true},
+		{"BATCH 0", 0, true, true},
+		{"BATCH many", 0, true, true},
+		{"SELECT * FROM batch", 0, false, false},
+		{"BATCHES", 0, false, false},
+	}
+	for _, tt := range tests {
+		n, ok, err := ParseBatchHeader(tt.line)
+		if n != tt.n || ok != tt.ok || (err != nil) != tt.hasErr {
+			t.Errorf("ParseBatchHeader(%q) = %d, %v, %v", tt.line, n, ok, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// internal/protocol/errors.go
+package protocol
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorCode classifies a failed statement
+type ErrorCode string
+
+// Error codes sent with failed statements
+const (
+	// CodeSyntax: the statement could not be parsed
+	CodeSyntax ErrorCode = "SYNTAX"
+	// CodeNotFound: a table, column, user or other object does not exist
+	CodeNotFound ErrorCode = "NOT_FOUND"
+	// CodeConstraint: the statement conflicts with existing data, such as a
+	// duplicate name or a wrong column count
+	CodeConstraint ErrorCode = "CONSTRAINT"
+	// CodeSerialization: a transaction lost a conflict with a concurrent one
+	// and was rolled back; it may be retried from the start
+	CodeSerialization ErrorCode = "SERIALIZATION"
+	// CodeAuth: the session is not logged in or lacks the privilege
+	CodeAuth ErrorCode = "AUTH"
+	// CodeTimeout: the statement exceeded its timeout
+	CodeTimeout ErrorCode = "TIMEOUT"
+	// CodeBusy: the server is overloaded and the statement may be retried
+	CodeBusy ErrorCode = "BUSY"
+	// CodeInternal: any other failure
+	CodeInternal ErrorCode = "INTERNAL"
+)
+
+// errorMarker starts a coded error response: "ERROR SYNTAX: Syntax error: ..."
+const errorMarker = "ERROR "
+
+// Error is a failed statement with its code. The engine returns it for
+// failures, so a result is never taken for one because of its text.
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeOf returns the code of a statement error: the Code of an *Error,
+// CodeInternal for any other error and "" for nil
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var stmtErr *Error
+	if errors.As(err, &stmtErr) {
+		return stmtErr.Code
+	}
+	return CodeInternal
+}
+
+// Retryable reports whether a statement that failed with code may succeed
+// when sent again unchanged: the server was busy, the statement timed out
+// waiting, or its transaction lost a serialization conflict
+func Retryable(code ErrorCode) bool {
+	switch code {
+	case CodeBusy, CodeTimeout, CodeSerialization:
+		return true
+	}
+	return false
+}
+
+// EncodeResult prepares a statement response for the wire: a failure is
+// sent as its message prefixed with its code, a success unchanged
+func EncodeResult(result string, err error) string {
+	if err == nil {
+		return result
+	}
+	return errorMarker + string(CodeOf(err)) + ": " + err.Error()
+}
+
+// ParseError splits a coded error response into its code and message
+func ParseError(resp string) (ErrorCode, string, bool) {
+	rest, ok := strings.CutPrefix(resp, errorMarker)
+	if !ok {
+		return "", "", false
+	}
+	code, message, ok := strings.Cut(rest, ": ")
+	if !ok || code == "" || strings.ToUpper(code) != code || strings.Contains(code, " ") {
+		return "", "", false
+	}
+	return ErrorCode(code), message, true
+}
@@ -0,0 +1,55 @@
+// internal/protocol/errors_test.go
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRetryable(t *testing.T) {
+	for code, want := range map[ErrorCode]bool{
+		CodeBusy: true, CodeTimeout: true, CodeSerialization: true,
+		CodeSyntax: false, CodeNotFound: false, CodeConstraint: false, CodeAuth: false, CodeInternal: false, "": false,
+	} {
+		if got := Retryable(code); got != want {
+			t.Errorf("Retryable(%q) = %v", code, got)
+		}
+	}
+}
+
+func TestEncodeResult(t *testing.T) {
+	// A result is sent unchanged whatever its text
+	for _, result := range []string{"1 row inserted", "invalid\n(no rows)\n", "Table x not found\n1\n"} {
+		if got := EncodeResult(result, nil); got != result {
+			t.Errorf("success %q should be unchanged, got %q", result, got)
+		}
+	}
+	encoded := EncodeResult("", &Error{Code: CodeNotFound, Message: "Table users not found"})
+	if encoded != "ERROR NOT_FOUND: Table users not found" {
+		t.Fatalf("unexpected encoding %q", encoded)
+	}
+	code, message, ok := ParseError(encoded)
+	if !ok || code != CodeNotFound || message != "Table users not found" {
+		t.Errorf("round trip gave %q, %q, %v", code, message, ok)
+	}
+	if got := EncodeResult("", errors.New("disk full")); got != "ERROR INTERNAL: disk full" {
+		t.Errorf("an uncoded error should be INTERNAL, got %q", got)
+	}
+	if _, _, ok := ParseError("ERROR report | count\n"); ok {
+		t.Error("a result that merely starts with ERROR should not parse as an error")
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	wrapped := fmt.Errorf("batch: %w", &Error{Code: CodeBusy, Message: "retry later"})
+	for err, want := range map[error]ErrorCode{
+		nil:                     "",
+		wrapped:                 CodeBusy,
+		errors.New("disk full"): CodeInternal,
+	} {
+		if got := CodeOf(err); got != want {
+			t.Errorf("CodeOf(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
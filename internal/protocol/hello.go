@@ -0,0 +1,105 @@
+// internal/protocol/hello.go
+package protocol
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The server opens every connection with one HELLO line describing itself,
+// followed by the first prompt, so clients can adapt to the server instead
+// of scraping a welcome text:
+//
+//	HELLO server=<version> protocol=<n> tls=<true|false> features=<a,b,...>
+//
+// Clients ignore keys and features they do not know, so servers may add
+// them without raising the protocol version.
+const HelloMarker = "HELLO"
+
+// ProtocolVersion is the version of the line protocol this server speaks. It
+// is raised only for changes that old clients cannot ignore.
+const ProtocolVersion = 1
+
+// Features a server advertises in its HELLO line
+const (
+	// FeatureBatch: BATCH frames (see batch.go)
+	FeatureBatch = "batch"
+	// FeatureNotify: LISTEN and asynchronous notifications (see notification.go)
+	FeatureNotify = "notify"
+	// FeatureBackupStream: BACKUP TO STDOUT streams (see backup_stream.go)
+	FeatureBackupStream = "backup-stream"
+	// FeatureErrorCodes: failures are sent as coded ERROR lines (see errors.go)
+	FeatureErrorCodes = "error-codes"
+	// FeatureQueryIDs: prompts carry the query ID of the statement they
+	// follow (see prompt.go)
+	FeatureQueryIDs = "query-ids"
+)
+
+// ServerFeatures lists the features this server supports
+var ServerFeatures = []string{FeatureBatch, FeatureNotify, FeatureBackupStream, FeatureErrorCodes, FeatureQueryIDs}
+
+// Hello is the description a server sends when a connection opens
+type Hello struct {
+	// Server is the server version, such as v0.0.5
+	Server   string
+	Protocol int
+	// TLS reports whether the connection is encrypted
+	TLS      bool
+	Features []string
+}
+
+// EncodeHello frames a HELLO line for sending to a client
+func EncodeHello(h Hello) string {
+	return fmt.Sprintf("%s server=%s protocol=%d tls=%t features=%s\n",
+		HelloMarker, h.Server, h.Protocol, h.TLS, strings.Join(h.Features, ","))
+}
+
+// IsHello reports whether line is a HELLO line
+func IsHello(line string) bool {
+	return strings.HasPrefix(line, HelloMarker+" ")
+}
+
+// DecodeHello parses a HELLO line
+func DecodeHello(line string) (Hello, error) {
+	var h Hello
+	line = strings.TrimRight(line, "\r\n")
+	if !IsHello(line) {
+		return h, fmt.Errorf("invalid HELLO: %q", line)
+	}
+	for _, field := range strings.Fields(strings.TrimPrefix(line, HelloMarker+" ")) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return h, fmt.Errorf("invalid HELLO field %q", field)
+		}
+		var err error
+		switch key {
+		case "server":
+			h.Server = value
+		case "protocol":
+			h.Protocol, err = strconv.Atoi(value)
+		case "tls":
+			h.TLS, err = strconv.ParseBool(value)
+		case "features":
+			h.Features = nil
+			for _, f := range strings.Split(value, ",") {
+				if f != "" {
+					h.Features = append(h.Features, f)
+				}
+			}
+		}
+		if err != nil {
+			return h, fmt.Errorf("invalid HELLO field %q", field)
+		}
+	}
+	if h.Protocol < 1 {
+		return h, fmt.Errorf("invalid HELLO: %q has no protocol version", line)
+	}
+	return h, nil
+}
+
+// Supports reports whether the server advertised feature
+func (h Hello) Supports(feature string) bool {
+	return slices.Contains(h.Features, feature)
+}
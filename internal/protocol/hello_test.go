@@ -0,0 +1,39 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHelloRoundTrip(t *testing.T) {
+	sent := Hello{Server: "v0.0.5", Protocol: ProtocolVersion, TLS: true, Features: ServerFeatures}
+	line := EncodeHello(sent)
+	if want := "HELLO server=v0.0.5 protocol=1 tls=true features=batch,notify,backup-stream,error-codes,query-ids\n"; line != want {
+		t.Errorf("encoded %q, want %q", line, want)
+	}
+	if !IsHello(line) {
+		t.Fatalf("not recognized: %q", line)
+	}
+	got, err := DecodeHello(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, sent) {
+		t.Errorf("decoded %+v, want %+v", got, sent)
+	}
+	if !got.Supports(FeatureNotify) || got.Supports("telepathy") {
+		t.Errorf("Supports: %v", got.Features)
+	}
+
+	// Later servers may add keys and features
+	got, err = DecodeHello("HELLO server=v9 protocol=2 region=eu tls=false features=batch,compression\r\n")
+	if err != nil || got.Protocol != 2 || got.TLS || !got.Supports("compression") {
+		t.Errorf("decoded %+v, %v", got, err)
+	}
+
+	for _, bad := range []string{"HELLO server=v1", "HELLO protocol=x", "HELLO protocol=1 tls=maybe", "HELLO protocol=1 junk", "haruDB> \n"} {
+		if _, err := DecodeHello(bad); err == nil {
+			t.Errorf("%q decoded without error", bad)
+		}
+	}
+}
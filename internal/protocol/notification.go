@@ -0,0 +1,39 @@
+// internal/protocol/notification.go
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A NOTIFY sent to a channel a connection LISTENs on is delivered as one
+// line that may arrive at any time, including between a response and the
+// next prompt, so clients must look for it wherever they read:
+//
+//	ASYNC NOTIFY <channel> "<payload, Go-quoted>"
+const NotificationMarker = "ASYNC NOTIFY"
+
+// EncodeNotification frames a notification for sending to a client
+func EncodeNotification(channel, payload string) string {
+	return fmt.Sprintf("%s %s %s\n", NotificationMarker, channel, strconv.Quote(payload))
+}
+
+// IsNotification reports whether line is a notification
+func IsNotification(line string) bool {
+	return strings.HasPrefix(line, NotificationMarker+" ")
+}
+
+// DecodeNotification returns the channel and payload of a notification line
+func DecodeNotification(line string) (channel, payload string, err error) {
+	rest := strings.TrimPrefix(strings.TrimRight(line, "\r\n"), NotificationMarker+" ")
+	channel, quoted, ok := strings.Cut(rest, " ")
+	if !ok || channel == "" {
+		return "", "", fmt.Errorf("invalid notification: %q", line)
+	}
+	payload, err = strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid notification payload: %q", line)
+	}
+	return channel, payload, nil
+}
@@ -0,0 +1,26 @@
+package protocol
+
+import "testing"
+
+func TestNotificationRoundTrip(t *testing.T) {
+	line := EncodeNotification("cache", "users \"1\"\nrefreshed")
+	if !IsNotification(line) {
+		t.Fatalf("not recognized: %q", line)
+	}
+	channel, payload, err := DecodeNotification(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel != "cache" || payload != "users \"1\"\nrefreshed" {
+		t.Errorf("decoded %q %q", channel, payload)
+	}
+
+	for _, bad := range []string{"ASYNC NOTIFY cache", "ASYNC NOTIFY cache unquoted\n"} {
+		if _, _, err := DecodeNotification(bad); err == nil {
+			t.Errorf("%q decoded without error", bad)
+		}
+	}
+	if IsNotification("haruDB> \n") {
+		t.Error("prompt recognized as a notification")
+	}
+}
@@ -0,0 +1,50 @@
+// internal/protocol/prompt.go
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The server sends a prompt line when it is ready for the next statement:
+// after the HELLO line and after every response. The prompt ending a
+// statement's response carries the statement's query ID, which names it in
+// the server's logs, SHOW PROCESSLIST and KILL:
+//
+//	haruDB> query=<id>
+//
+// Clients must recognize a prompt by its Prompt prefix alone.
+const Prompt = "haruDB> "
+
+// EncodePrompt frames a prompt for sending to a client. queryID is the ID
+// of the statement whose response it ends, or 0 for none.
+func EncodePrompt(queryID uint64) string {
+	if queryID == 0 {
+		return Prompt + "\n"
+	}
+	return fmt.Sprintf("%squery=%d\n", Prompt, queryID)
+}
+
+// IsPrompt reports whether line is a prompt
+func IsPrompt(line string) bool {
+	return strings.HasPrefix(line, Prompt)
+}
+
+// PromptQueryID returns the query ID a prompt line carries, or 0 if it has
+// none
+func PromptQueryID(line string) uint64 {
+	rest, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), Prompt)
+	if !ok {
+		return 0
+	}
+	value, ok := strings.CutPrefix(strings.TrimSpace(rest), "query=")
+	if !ok {
+		return 0
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,28 @@
+package protocol
+
+import "testing"
+
+func TestPrompt(t *testing.T) {
+	if got := EncodePrompt(0); got != "haruDB> \n" {
+		t.Errorf("plain prompt %q", got)
+	}
+	line := EncodePrompt(42)
+	if line != "haruDB> query=42\n" {
+		t.Errorf("prompt %q", line)
+	}
+	if !IsPrompt(line) || IsPrompt("id | name\n") {
+		t.Error("IsPrompt")
+	}
+	for line, want := range map[string]uint64{
+		"haruDB> query=42\n":  42,
+		"haruDB> query=7\r\n": 7,
+		"haruDB> \n":          0,
+		"haruDB> query=x\n":   0,
+		"haruDB> region=eu\n": 0,
+		"query=3\n":           0,
+	} {
+		if got := PromptQueryID(line); got != want {
+			t.Errorf("PromptQueryID(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
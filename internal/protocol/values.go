@@ -0,0 +1,211 @@
+// internal/protocol/values.go
+//
+// Value codecs. HaruDB stores every value as text, so a Go value of a type
+// such as time.Time, a UUID or a decimal has to be written out and parsed
+// back. A codec registered for a type does both, and values of the type are
+// stored as tag:text, with the codec's tag in front, so DecodeValue can tell
+// which type to rebuild from a result. A string that would read as tag:text
+// is stored with a ':' in front, so it is never taken for a tagged value.
+// Statement arguments are bound to ? placeholders with Bind, encoded by the
+// codec of their type; the client and embedded engines share the codecs.
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codec converts the values of one Go type to and from stored text
+type codec struct {
+	tag    string
+	typ    reflect.Type
+	encode func(any) (string, error)
+	decode func(string) (any, error)
+}
+
+// codecs holds the registered codecs by type and by tag
+var codecs = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*codec
+	byTag  map[string]*codec
+}{byType: map[reflect.Type]*codec{}, byTag: map[string]*codec{}}
+
+func init() {
+	RegisterCodec("time",
+		func(t time.Time) (string, error) { return t.Format(time.RFC3339Nano), nil },
+		func(s string) (time.Time, error) { return time.Parse(time.RFC3339Nano, s) })
+}
+
+// RegisterCodec makes values of type T usable as statement arguments,
+// stored as tag:text where encode gives the text, and rebuilt by decode.
+// Tags are lowercase letters, digits and underscores, and each tag and type
+// is registered once. The time tag is registered for time.Time.
+func RegisterCodec[T any](tag string, encode func(T) (string, error), decode func(string) (T, error)) error {
+	if !validTag(tag) {
+		return fmt.Errorf("harudb: invalid codec tag %q", tag)
+	}
+	typ := reflect.TypeFor[T]()
+	c := &codec{
+		tag:    tag,
+		typ:    typ,
+		encode: func(v any) (string, error) { return encode(v.(T)) },
+		decode: func(s string) (any, error) { return decode(s) },
+	}
+
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, exists := codecs.byTag[tag]; exists {
+		return fmt.Errorf("harudb: codec tag %q is already registered", tag)
+	}
+	if existing, exists := codecs.byType[typ]; exists {
+		return fmt.Errorf("harudb: %s already has codec %q", typ, existing.tag)
+	}
+	codecs.byTag[tag] = c
+	codecs.byType[typ] = c
+	return nil
+}
+
+// validTag reports whether tag may name a codec
+func validTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, r := range tag {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// codecFor returns the codec registered for typ, or nil
+func codecFor(typ reflect.Type) *codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byType[typ]
+}
+
+// EncodeValue returns v as a SQL literal: NULL for nil, a number or boolean
+// as written, a string quoted, and a value of a type with a codec as the
+// quoted tag:text. A string starting with ':' or with the tag of a codec
+// gets a ':' in front.
+func EncodeValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		if strings.HasPrefix(v, ":") || taggedCodec(v) != nil {
+			v = ":" + v
+		}
+		return quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+	c := codecFor(reflect.TypeOf(v))
+	if c == nil {
+		return "", fmt.Errorf("harudb: no codec registered for %T", v)
+	}
+	text, err := c.encode(v)
+	if err != nil {
+		return "", fmt.Errorf("harudb: encoding %T: %w", v, err)
+	}
+	return quote(c.tag + ":" + text), nil
+}
+
+// quote returns s as a SQL string literal
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// taggedCodec returns the codec whose tag s starts with, as in tag:text,
+// or nil
+func taggedCodec(s string) *codec {
+	tag, _, found := strings.Cut(s, ":")
+	if !found {
+		return nil
+	}
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byTag[tag]
+}
+
+// DecodeValue rebuilds the value a codec stored as tag:text, and the string
+// EncodeValue stored with a ':' in front. Other values are returned
+// unchanged, as strings.
+func DecodeValue(s string) (any, error) {
+	if text, escaped := strings.CutPrefix(s, ":"); escaped {
+		return text, nil
+	}
+	c := taggedCodec(s)
+	if c == nil {
+		return s, nil
+	}
+	text := s[len(c.tag)+1:]
+	v, err := c.decode(text)
+	if err != nil {
+		return nil, fmt.Errorf("harudb: decoding %s value %q: %w", c.tag, text, err)
+	}
+	return v, nil
+}
+
+// Decode rebuilds a value of type T stored by its codec as tag:text
+func Decode[T any](s string) (T, error) {
+	var zero T
+	c := codecFor(reflect.TypeFor[T]())
+	if c == nil {
+		return zero, fmt.Errorf("harudb: no codec registered for %s", reflect.TypeFor[T]())
+	}
+	text, ok := strings.CutPrefix(s, c.tag+":")
+	if !ok {
+		return zero, fmt.Errorf("harudb: %q is not a %s value", s, c.tag)
+	}
+	v, err := c.decode(text)
+	if err != nil {
+		return zero, fmt.Errorf("harudb: decoding %s value %q: %w", c.tag, text, err)
+	}
+	return v.(T), nil
+}
+
+// Bind replaces each ? placeholder of statement outside quotes with the
+// next of args, encoded with EncodeValue
+func Bind(statement string, args ...any) (string, error) {
+	var b strings.Builder
+	var quoteChar rune
+	next := 0
+	for _, r := range statement {
+		switch {
+		case quoteChar != 0:
+			if r == quoteChar {
+				quoteChar = 0
+			}
+		case r == '\'' || r == '"':
+			quoteChar = r
+		case r == '?':
+			if next == len(args) {
+				return "", fmt.Errorf("harudb: statement has more placeholders than the %d arguments", len(args))
+			}
+			literal, err := EncodeValue(args[next])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(literal)
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if next < len(args) {
+		return "", fmt.Errorf("harudb: statement has %d placeholders for %d arguments", next, len(args))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,31 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlainStringsAreNotTagged(t *testing.T) {
+	paid := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"time:2024-01-01T00:00:00Z", "time: 10am", ":", "::x", ":time:x", "rent: march", "plain", ""} {
+		literal, err := EncodeValue(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored := literal[1 : len(literal)-1]
+		if got, err := DecodeValue(stored); err != nil || got != s {
+			t.Errorf("%q stored as %s decodes to %#v, %v", s, literal, got, err)
+		}
+	}
+	if got, _ := EncodeValue("rent: march"); got != "'rent: march'" {
+		t.Errorf("string without a codec's tag escaped: %s", got)
+	}
+
+	literal, _ := EncodeValue(paid)
+	if got, err := DecodeValue(literal[1 : len(literal)-1]); err != nil || got != paid {
+		t.Errorf("time stored as %s decodes to %#v, %v", literal, got, err)
+	}
+	if _, err := Decode[time.Time](":time:2024-01-01T00:00:00Z"); err == nil {
+		t.Error("decoded an escaped string as a time")
+	}
+}
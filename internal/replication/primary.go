@@ -0,0 +1,297 @@
+// internal/replication/primary.go
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+const (
+	pollInterval      = 100 * time.Millisecond
+	heartbeatInterval = time.Second
+	streamBatchSize   = 500
+	// replicationConsumer acknowledges the changes every connected replica
+	// has applied to the change log
+	replicationConsumer = "replication"
+)
+
+// Primary streams committed changes from the change log to replicas.
+//
+// A replica opens a connection and sends
+//
+//	REPLICATE <user> <password> <lsn|SNAPSHOT>
+//
+// For SNAPSHOT the primary first sends "SNAPSHOT <lsn> <size>" followed by a
+// <size> byte backup archive taken consistently at <lsn>. It then answers
+// "OK <last lsn>" and streams JSON lines with every change after <lsn>, plus
+// periodic heartbeats. The replica acknowledges applied changes with
+// "ACK <lsn>" lines.
+type Primary struct {
+	changes *storage.ChangeLog
+	users   *auth.UserManager
+	backups *storage.BackupManager
+	peers   map[*peer]bool
+	mu      sync.Mutex
+}
+
+// peer is the primary's view of one connected replica
+type peer struct {
+	addr        string
+	user        string
+	connectedAt time.Time
+	sentLSN     uint64
+	appliedLSN  uint64
+	// pending holds changes sent but not yet acknowledged, oldest first
+	pending []pendingChange
+}
+
+// pendingChange is a sent change awaiting acknowledgement
+type pendingChange struct {
+	lsn  uint64
+	size int64
+	at   time.Time
+}
+
+// NewPrimary creates a primary streaming from changes. Replicas must log in
+// as an admin user. Base snapshots for new replicas are taken with backups,
+// which should have the database attached as its snapshotter.
+func NewPrimary(changes *storage.ChangeLog, users *auth.UserManager, backups *storage.BackupManager) *Primary {
+	// Nothing is truncated from the change log until replicas acknowledge it
+	if err := changes.Acknowledge(replicationConsumer, 0); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return &Primary{changes: changes, users: users, backups: backups, peers: make(map[*peer]bool)}
+}
+
+// Serve accepts replica connections until ln is closed
+func (p *Primary) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handleReplica(conn)
+	}
+}
+
+// Status reports the primary's LSN and its connected replicas
+func (p *Primary) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := Status{Role: RolePrimary, LastLSN: p.changes.LastSeq()}
+	now := time.Now()
+	for pr := range p.peers {
+		ps := PeerStatus{
+			Addr:        pr.addr,
+			User:        pr.user,
+			ConnectedAt: pr.connectedAt,
+			SentLSN:     pr.sentLSN,
+			AppliedLSN:  pr.appliedLSN,
+		}
+		for _, pc := range pr.pending {
+			ps.ByteLag += pc.size
+		}
+		if len(pr.pending) > 0 {
+			ps.TimeLag = now.Sub(pr.pending[0].at)
+		}
+		status.Peers = append(status.Peers, ps)
+	}
+	sort.Slice(status.Peers, func(i, j int) bool { return status.Peers[i].Addr < status.Peers[j].Addr })
+	return status
+}
+
+// handleReplica authenticates a replica and streams changes to it
+func (p *Primary) handleReplica(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	fields := strings.Fields(line)
+	if len(fields) != 4 || strings.ToUpper(fields[0]) != "REPLICATE" {
+		fmt.Fprintf(conn, "ERR expected REPLICATE user password lsn|SNAPSHOT\n")
+		return
+	}
+	user, err := p.users.AuthenticateUser(fields[1], fields[2])
+	if err != nil || user.Role != auth.RoleAdmin {
+		fmt.Fprintf(conn, "ERR replication requires an admin user\n")
+		return
+	}
+
+	var from uint64
+	if strings.ToUpper(fields[3]) == "SNAPSHOT" {
+		if from, err = p.sendSnapshot(conn); err != nil {
+			log.Printf("Failed to send snapshot to replica %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	} else if from, err = strconv.ParseUint(fields[3], 10, 64); err != nil {
+		fmt.Fprintf(conn, "ERR invalid lsn %q\n", fields[3])
+		return
+	} else if first := p.changes.FirstSeq(); from+1 < first {
+		fmt.Fprintf(conn, "ERR changes before LSN %d were truncated, replicate from SNAPSHOT\n", first)
+		return
+	}
+
+	pr := &peer{addr: conn.RemoteAddr().String(), user: user.Username, connectedAt: time.Now(), sentLSN: from, appliedLSN: from}
+	p.mu.Lock()
+	p.peers[pr] = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.peers, pr)
+		p.mu.Unlock()
+	}()
+
+	log.Printf("Replica %s connected as %s from LSN %d", pr.addr, pr.user, from)
+	if _, err := fmt.Fprintf(conn, "OK %d\n", p.changes.LastSeq()); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go p.readAcks(reader, pr, done)
+
+	if err := p.stream(conn, pr, done); err != nil {
+		log.Printf("Replica %s disconnected: %v", pr.addr, err)
+	}
+}
+
+// sendSnapshot ships a consistent base backup and returns the LSN it was
+// taken at. Credentials are left out; replicas keep their own users. The
+// keyring is sent so that replicas can read encrypted tables.
+func (p *Primary) sendSnapshot(conn net.Conn) (uint64, error) {
+	var lsn uint64
+	var archive bytes.Buffer
+	opts := storage.BackupOptions{
+		Description: "replica base snapshot",
+		IncludeKeys: true,
+		AtSnapshot:  func() { lsn = p.changes.LastSeq() },
+	}
+	if err := p.backups.WriteBackup(&archive, opts); err != nil {
+		fmt.Fprintf(conn, "ERR snapshot failed: %v\n", err)
+		return 0, err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Minute))
+	defer conn.SetWriteDeadline(time.Time{})
+	if _, err := fmt.Fprintf(conn, "SNAPSHOT %d %d\n", lsn, archive.Len()); err != nil {
+		return 0, err
+	}
+	if _, err := conn.Write(archive.Bytes()); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Sent %d byte base snapshot at LSN %d to replica %s", archive.Len(), lsn, conn.RemoteAddr())
+	return lsn, nil
+}
+
+// stream sends changes after the peer's position until the connection fails
+func (p *Primary) stream(conn net.Conn, pr *peer, done <-chan struct{}) error {
+	writer := bufio.NewWriter(conn)
+	encoder := json.NewEncoder(writer)
+	lastHeartbeat := time.Time{}
+
+	for {
+		select {
+		case <-done:
+			return fmt.Errorf("connection closed")
+		default:
+		}
+
+		p.mu.Lock()
+		after := pr.sentLSN
+		p.mu.Unlock()
+
+		events, err := p.changes.ReadAfter(after, streamBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range events {
+			ev := events[i]
+			data, err := json.Marshal(message{Type: msgChange, LSN: ev.Seq, Event: &ev})
+			if err != nil {
+				return err
+			}
+			if _, err := writer.Write(append(data, '\n')); err != nil {
+				return err
+			}
+
+			p.mu.Lock()
+			pr.sentLSN = ev.Seq
+			pr.pending = append(pr.pending, pendingChange{lsn: ev.Seq, size: int64(len(data)), at: ev.Timestamp})
+			p.mu.Unlock()
+		}
+
+		if len(events) == 0 && time.Since(lastHeartbeat) >= heartbeatInterval {
+			if err := encoder.Encode(message{Type: msgHeartbeat, LSN: p.changes.LastSeq(), Time: time.Now()}); err != nil {
+				return err
+			}
+			lastHeartbeat = time.Now()
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+
+		if len(events) < streamBatchSize {
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+// readAcks records the LSNs a replica reports as applied
+func (p *Primary) readAcks(reader *bufio.Reader, pr *peer, done chan<- struct{}) {
+	defer close(done)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "ACK" {
+			continue
+		}
+		lsn, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		p.mu.Lock()
+		if lsn > pr.appliedLSN {
+			pr.appliedLSN = lsn
+		}
+		i := 0
+		for i < len(pr.pending) && pr.pending[i].lsn <= lsn {
+			i++
+		}
+		pr.pending = pr.pending[i:]
+		applied := pr.appliedLSN
+		for other := range p.peers {
+			applied = min(applied, other.appliedLSN)
+		}
+		p.mu.Unlock()
+
+		if err := p.changes.Acknowledge(replicationConsumer, applied); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
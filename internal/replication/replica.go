@@ -0,0 +1,401 @@
+// internal/replication/replica.go
+package replication
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+const (
+	// replicaLSNName stores the last LSN a replica applied
+	replicaLSNName = "replica.lsn"
+
+	// replicaReadTimeout drops a primary that stopped sending heartbeats
+	replicaReadTimeout = 30 * heartbeatInterval
+)
+
+// Replica follows a primary, applying its changes to the local database
+type Replica struct {
+	primaryAddr string
+	user        string
+	password    string
+	dataDir     string
+	lsnPath     string
+	hasLSN      bool
+	db          *storage.Database
+	// pending is the session opened by Bootstrap, picked up by Run
+	pending *session
+
+	mu          sync.Mutex
+	connected   bool
+	primaryLSN  uint64
+	receivedLSN uint64
+	appliedLSN  uint64
+	byteLag     int64
+	behindSince time.Time
+	lastError   string
+}
+
+// session is an established replication connection
+type session struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	primaryLSN uint64
+}
+
+// NewReplica creates a replica of the primary at primaryAddr keeping its data
+// in dataDir. It resumes from the LSN recorded there, if any.
+func NewReplica(primaryAddr, user, password, dataDir string) (*Replica, error) {
+	r := &Replica{
+		primaryAddr: primaryAddr,
+		user:        user,
+		password:    password,
+		dataDir:     dataDir,
+		lsnPath:     filepath.Join(dataDir, replicaLSNName),
+	}
+
+	data, err := os.ReadFile(r.lsnPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read replica LSN: %w", err)
+	}
+	if len(data) > 0 {
+		lsn, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replica LSN file %s: %w", r.lsnPath, err)
+		}
+		r.appliedLSN, r.receivedLSN, r.hasLSN = lsn, lsn, true
+	}
+
+	return r, nil
+}
+
+// NeedsSnapshot reports whether this replica has never been bootstrapped
+func (r *Replica) NeedsSnapshot() bool {
+	return !r.hasLSN
+}
+
+// Bootstrap copies a consistent base snapshot from the primary into the data
+// directory when the replica has never been bootstrapped, replacing any
+// tables there. It must run before the data directory is opened. The
+// connection is kept and continues with change streaming in Run.
+func (r *Replica) Bootstrap() error {
+	if r.hasLSN {
+		return nil
+	}
+
+	sess, err := r.connect("SNAPSHOT")
+	if err != nil {
+		return fmt.Errorf("failed to bootstrap from %s: %w", r.primaryAddr, err)
+	}
+	r.pending = sess
+	return nil
+}
+
+// Run applies changes to db until stop is closed, reconnecting on failure
+func (r *Replica) Run(db *storage.Database, stop <-chan struct{}) {
+	r.db = db
+	backoff := time.Second
+	for {
+		err := r.follow(stop)
+
+		r.mu.Lock()
+		r.connected = false
+		if err != nil {
+			r.lastError = err.Error()
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if err != nil {
+			log.Printf("Replication from %s failed: %v (retrying in %s)", r.primaryAddr, err, backoff)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// Status reports how far this replica is behind its primary
+func (r *Replica) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := Status{
+		Role:        RoleReplica,
+		PrimaryAddr: r.primaryAddr,
+		Connected:   r.connected,
+		PrimaryLSN:  r.primaryLSN,
+		ReceivedLSN: r.receivedLSN,
+		AppliedLSN:  r.appliedLSN,
+		ByteLag:     r.byteLag,
+		LastError:   r.lastError,
+	}
+	if !r.behindSince.IsZero() {
+		status.TimeLag = time.Since(r.behindSince)
+	}
+	return status
+}
+
+// connect opens a replication session starting at from, which is an LSN or
+// SNAPSHOT to receive a base snapshot first
+func (r *Replica) connect(from string) (*session, error) {
+	conn, err := net.DialTimeout("tcp", r.primaryAddr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := r.handshake(conn, from)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return sess, nil
+}
+
+// handshake logs in, restores the base snapshot if one is sent and waits for
+// the primary to start streaming
+func (r *Replica) handshake(conn net.Conn, from string) (*session, error) {
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := fmt.Fprintf(conn, "REPLICATE %s %s %s\n", r.user, r.password, from); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(reply)
+	if len(fields) == 3 && fields[0] == "SNAPSHOT" {
+		if err := r.receiveSnapshot(reader, fields[1], fields[2]); err != nil {
+			return nil, err
+		}
+		if reply, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		fields = strings.Fields(reply)
+	}
+
+	if len(fields) < 2 || fields[0] != "OK" {
+		return nil, fmt.Errorf("primary refused replication: %s", strings.TrimSpace(reply))
+	}
+	primaryLSN, _ := strconv.ParseUint(fields[1], 10, 64)
+	return &session{conn: conn, reader: reader, primaryLSN: primaryLSN}, nil
+}
+
+// receiveSnapshot reads a base snapshot and restores it into the data directory
+func (r *Replica) receiveSnapshot(reader *bufio.Reader, lsnField, sizeField string) error {
+	lsn, err := strconv.ParseUint(lsnField, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot LSN %q", lsnField)
+	}
+	size, err := strconv.ParseInt(sizeField, 10, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("invalid snapshot size %q", sizeField)
+	}
+
+	archive := make([]byte, size)
+	if _, err := io.ReadFull(reader, archive); err != nil {
+		return fmt.Errorf("snapshot transfer interrupted: %w", err)
+	}
+
+	snapshotPath := filepath.Join(r.dataDir, "replica-snapshot.tmp")
+	if err := os.WriteFile(snapshotPath, archive, 0600); err != nil {
+		return fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	defer os.Remove(snapshotPath)
+
+	if err := storage.NewBackupManager(r.dataDir).RestoreBackup(snapshotPath); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	if err := r.saveLSN(lsn); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.appliedLSN, r.receivedLSN, r.hasLSN = lsn, lsn, true
+	r.mu.Unlock()
+	log.Printf("Restored %d byte base snapshot from %s at LSN %d", size, r.primaryAddr, lsn)
+	return nil
+}
+
+// follow runs one replication session
+func (r *Replica) follow(stop <-chan struct{}) error {
+	sess := r.pending
+	r.pending = nil
+	if sess == nil {
+		if !r.hasLSN {
+			return fmt.Errorf("replica has no base snapshot; run Bootstrap first")
+		}
+		r.mu.Lock()
+		from := r.appliedLSN
+		r.mu.Unlock()
+
+		var err error
+		if sess, err = r.connect(strconv.FormatUint(from, 10)); err != nil {
+			return err
+		}
+	}
+	conn, reader := sess.conn, sess.reader
+	defer conn.Close()
+
+	// Unblock reads when asked to stop
+	sessionDone := make(chan struct{})
+	defer close(sessionDone)
+	go func() {
+		select {
+		case <-stop:
+			conn.Close()
+		case <-sessionDone:
+		}
+	}()
+
+	r.mu.Lock()
+	r.connected = true
+	r.lastError = ""
+	r.notePrimaryLSN(sess.primaryLSN)
+	from := r.appliedLSN
+	r.mu.Unlock()
+	log.Printf("Replicating from %s starting after LSN %d", r.primaryAddr, from)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(replicaReadTimeout))
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return fmt.Errorf("invalid replication message: %w", err)
+		}
+
+		switch msg.Type {
+		case msgHeartbeat:
+			r.mu.Lock()
+			r.notePrimaryLSN(msg.LSN)
+			r.mu.Unlock()
+
+		case msgChange:
+			if msg.Event == nil {
+				continue
+			}
+			r.mu.Lock()
+			r.receivedLSN = msg.LSN
+			r.byteLag += int64(len(line))
+			r.notePrimaryLSN(msg.LSN)
+			r.mu.Unlock()
+
+			if err := r.apply(*msg.Event); err != nil {
+				return err
+			}
+
+			r.mu.Lock()
+			r.byteLag -= int64(len(line))
+			r.notePrimaryLSN(r.primaryLSN)
+			r.mu.Unlock()
+
+			if _, err := fmt.Fprintf(conn, "ACK %d\n", msg.LSN); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// notePrimaryLSN records the primary's newest LSN and tracks since when this
+// replica has been behind it; callers hold r.mu
+func (r *Replica) notePrimaryLSN(lsn uint64) {
+	if lsn > r.primaryLSN {
+		r.primaryLSN = lsn
+	}
+	switch {
+	case r.appliedLSN >= r.primaryLSN:
+		r.behindSince = time.Time{}
+	case r.behindSince.IsZero():
+		r.behindSince = time.Now()
+	}
+}
+
+// apply replays one change from the primary and records its LSN
+func (r *Replica) apply(ev storage.ChangeEvent) error {
+	r.mu.Lock()
+	applied := r.appliedLSN
+	r.mu.Unlock()
+	if ev.Seq <= applied {
+		return nil
+	}
+
+	var result string
+	switch ev.Op {
+	case storage.ChangeCreateTable:
+		result = r.db.CreateTable(ev.Table, ev.ColumnSpecs())
+	case storage.ChangeInsert:
+		result = r.db.Insert(ev.Table, ev.Values)
+	case storage.ChangeUpdate:
+		if ev.RowIndex == nil {
+			return fmt.Errorf("change %d: UPDATE without row index", ev.Seq)
+		}
+		result = r.db.Update(ev.Table, *ev.RowIndex, ev.Values)
+	case storage.ChangeDelete:
+		if ev.RowIndex == nil {
+			return fmt.Errorf("change %d: DELETE without row index", ev.Seq)
+		}
+		result = r.db.Delete(ev.Table, *ev.RowIndex)
+	case storage.ChangeDropTable:
+		result = r.db.DropTable(ev.Table)
+	case storage.ChangeRenameTable:
+		result = r.db.RenameTable(ev.Table, ev.NewName)
+	case storage.ChangeComment:
+		result = r.db.SetComment(ev.Table, ev.Column, ev.Comment)
+	default:
+		return fmt.Errorf("change %d: unknown operation %q", ev.Seq, ev.Op)
+	}
+	if strings.Contains(result, "warning") || strings.Contains(result, "not found") ||
+		strings.Contains(result, "out of bounds") || strings.Contains(result, "does not match") {
+		log.Printf("Replica applying change %d (%s %s): %s", ev.Seq, ev.Op, ev.Table, result)
+	}
+
+	if err := r.saveLSN(ev.Seq); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.appliedLSN = ev.Seq
+	r.mu.Unlock()
+	return nil
+}
+
+// saveLSN atomically persists the last applied LSN
+func (r *Replica) saveLSN(lsn uint64) error {
+	tmpPath := r.lsnPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.FormatUint(lsn, 10)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write replica LSN: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.lsnPath); err != nil {
+		return fmt.Errorf("failed to save replica LSN: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package replication
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/auth"
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestReplicaFollowsPrimary(t *testing.T) {
+	primaryDir := t.TempDir()
+	primaryDB := storage.NewDatabase(primaryDir)
+	defer primaryDB.Close()
+
+	// Rows written before the change log existed only reach the replica
+	// through the base snapshot
+	_ = primaryDB.CreateTable("users", []string{"id", "name"})
+	_ = primaryDB.Insert("users", []string{"1", "Alice"})
+	if err := primaryDB.EnableChangeLog(); err != nil {
+		t.Fatalf("failed to enable change log: %v", err)
+	}
+	_ = primaryDB.Insert("users", []string{"2", "Bob"})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	backups := storage.NewBackupManager(primaryDir)
+	backups.SetSnapshotter(primaryDB)
+	if _, err := auth.CreateAdmin(primaryDir, "admin123"); err != nil {
+		t.Fatal(err)
+	}
+	primary := NewPrimary(primaryDB.Changes, auth.NewUserManager(primaryDir), backups)
+	go primary.Serve(ln)
+
+	replicaDir := t.TempDir()
+	replica, err := NewReplica(ln.Addr().String(), "admin", "admin123", replicaDir)
+	if err != nil {
+		t.Fatalf("failed to create replica: %v", err)
+	}
+	if !replica.NeedsSnapshot() {
+		t.Fatal("new replica should need a base snapshot")
+	}
+	if err := replica.Bootstrap(); err != nil {
+		t.Fatalf("bootstrap failed: %v", err)
+	}
+	if got := replica.Status().AppliedLSN; got != 1 {
+		t.Fatalf("expected snapshot at LSN 1, got %d", got)
+	}
+
+	replicaDB := storage.NewDatabase(replicaDir)
+	defer replicaDB.Close()
+	if rows := len(replicaDB.Tables["users"].Rows); rows != 2 {
+		t.Fatalf("expected 2 rows from snapshot, got %d", rows)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go replica.Run(replicaDB, stop)
+
+	_ = primaryDB.Insert("users", []string{"3", "Carol"})
+	_ = primaryDB.Update("users", 0, []string{"1", "Alicia"})
+	waitFor(t, "streamed changes", func() bool { return replica.Status().AppliedLSN == 3 })
+
+	rows := replicaDB.Tables["users"].Rows
+	if len(rows) != 3 || rows[0][1] != "Alicia" || rows[2][1] != "Carol" {
+		t.Fatalf("replica rows diverged: %v", rows)
+	}
+
+	rs := replica.Status()
+	if rs.Role != RoleReplica || !rs.Connected || rs.PrimaryLSN != 3 || rs.TimeLag != 0 {
+		t.Errorf("unexpected replica status %+v", rs)
+	}
+
+	waitFor(t, "primary to see the ack", func() bool {
+		ps := primary.Status()
+		return len(ps.Peers) == 1 && ps.Peers[0].AppliedLSN == 3
+	})
+	ps := primary.Status()
+	if ps.Role != RolePrimary || ps.LastLSN != 3 || ps.Peers[0].SentLSN != 3 || ps.Peers[0].ByteLag != 0 {
+		t.Errorf("unexpected primary status %+v", ps)
+	}
+
+	// A restarted replica resumes from its recorded LSN without a snapshot
+	resumed, err := NewReplica(ln.Addr().String(), "admin", "admin123", replicaDir)
+	if err != nil {
+		t.Fatalf("failed to reopen replica: %v", err)
+	}
+	if resumed.NeedsSnapshot() || resumed.Status().AppliedLSN != 3 {
+		t.Errorf("expected resumed replica at LSN 3, got %+v", resumed.Status())
+	}
+}
+
+func TestPrimaryRejectsNonAdmin(t *testing.T) {
+	dir := t.TempDir()
+	db := storage.NewDatabase(dir)
+	defer db.Close()
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatalf("failed to enable change log: %v", err)
+	}
+	users := auth.NewUserManager(dir)
+	if err := users.CreateUser("reader", "pw", auth.RoleReadOnly); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go NewPrimary(db.Changes, users, storage.NewBackupManager(dir)).Serve(ln)
+
+	replica, err := NewReplica(ln.Addr().String(), "reader", "pw", t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create replica: %v", err)
+	}
+	if err := replica.Bootstrap(); err == nil {
+		t.Fatal("expected non-admin replication login to be refused")
+	}
+}
@@ -0,0 +1,66 @@
+// internal/replication/status.go
+package replication
+
+import (
+	"time"
+
+	"github.com/Hareesh108/haruDB/internal/storage"
+)
+
+// Roles reported by SHOW REPLICATION STATUS
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// Node is a primary or replica that can report its replication state
+type Node interface {
+	Status() Status
+}
+
+// Status is a point-in-time view of replication on this server. LSNs are
+// change log sequence numbers.
+type Status struct {
+	Role string
+
+	// Primary side: the newest LSN and one entry per connected replica
+	LastLSN uint64
+	Peers   []PeerStatus
+
+	// Replica side: the upstream primary and how far behind it we are
+	PrimaryAddr string
+	Connected   bool
+	PrimaryLSN  uint64
+	ReceivedLSN uint64
+	AppliedLSN  uint64
+	ByteLag     int64
+	TimeLag     time.Duration
+	LastError   string
+}
+
+// PeerStatus describes a replica connected to this primary
+type PeerStatus struct {
+	Addr        string
+	User        string
+	ConnectedAt time.Time
+	SentLSN     uint64
+	AppliedLSN  uint64
+	// ByteLag is the size of the changes sent but not yet applied
+	ByteLag int64
+	// TimeLag is the age of the oldest change sent but not yet applied
+	TimeLag time.Duration
+}
+
+// message is one line of the replication stream from primary to replica
+type message struct {
+	Type  string               `json:"type"`
+	LSN   uint64               `json:"lsn"`
+	Time  time.Time            `json:"time,omitempty"`
+	Event *storage.ChangeEvent `json:"event,omitempty"`
+}
+
+// Stream message types
+const (
+	msgChange    = "change"
+	msgHeartbeat = "heartbeat"
+)
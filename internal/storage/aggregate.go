@@ -0,0 +1,458 @@
+// internal/storage/aggregate.go
+//
+// Aggregates. SELECT COUNT(*), COUNT(col), SUM(col), MIN(col) and MAX(col)
+// fold the rows into one result row without building a result set. When a
+// query has no WHERE clause and the table's pages in PageStorage hold
+// exactly its rows, the aggregates are computed during the page scan: each
+// row is read in place from the page, only the aggregated fields are
+// looked at, and no row is decoded into a []string. Pages only record
+// inserts, so a table qualifies from its creation until its first update,
+// delete or other rewrite of its rows; the others are aggregated from the
+// rows in memory.
+package storage
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Aggregate functions
+const (
+	AggCount = "COUNT"
+	AggSum   = "SUM"
+	AggMin   = "MIN"
+	AggMax   = "MAX"
+)
+
+// Aggregate is an aggregate of a SELECT: COUNT(*) when Column is "*", or
+// Func of Column. COUNT, SUM, MIN and MAX of a column skip its NULLs.
+type Aggregate struct {
+	Func   string
+	Column string
+}
+
+// Label returns the aggregate's result column heading
+func (a Aggregate) Label() string {
+	if a.Column == "*" {
+		return strings.ToLower(a.Func)
+	}
+	return strings.ToLower(a.Func) + "(" + a.Column + ")"
+}
+
+// aggState accumulates one aggregate
+type aggState struct {
+	Aggregate
+	// col is the aggregated column's index, -1 for COUNT(*)
+	col  int
+	coll *Collation
+	n    int64
+	// SUM adds integers exactly in isum until a value is not an integer or
+	// the sum overflows, then continues in fsum
+	isum   int64
+	fsum   float64
+	floats bool
+	// best is the MIN or MAX so far; the page scan keeps it in bestField
+	best      string
+	bestField []byte
+	err       error
+}
+
+// accept counts v and adds it to SUM, reporting false when SUM cannot
+// add it. v does not escape, so the page scan can pass a value still in
+// its page.
+func (a *aggState) accept(v string) bool {
+	if a.col >= 0 && v == NullValue {
+		return true
+	}
+	a.n++
+	if a.Func != AggSum {
+		return true
+	}
+	if !a.floats {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if s := a.isum + i; (s > a.isum) == (i > 0) || i == 0 {
+				a.isum = s
+				return true
+			}
+		}
+		a.floats, a.fsum = true, float64(a.isum)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) {
+		return false
+	}
+	a.fsum += f
+	return true
+}
+
+// better reports whether a value comparing c to the MIN or MAX so far
+// replaces it
+func (a *aggState) better(c int) bool {
+	if a.Func == AggMin {
+		return c < 0
+	}
+	return c > 0
+}
+
+// keeps reports whether the aggregate is a MIN or MAX that keeps v, which
+// is not NULL
+func (a *aggState) keeps(v string) bool {
+	return (a.Func == AggMin || a.Func == AggMax) && v != NullValue
+}
+
+// add adds a value of a row in memory
+func (a *aggState) add(v string) {
+	switch {
+	case !a.accept(v):
+		if a.err == nil {
+			a.err = fmt.Errorf("%s: %s is not a number", a.Label(), v)
+		}
+	case a.keeps(v) && (a.n == 1 || a.better(compareValues(v, a.best, a.coll))):
+		a.best = v
+	}
+}
+
+// addField adds a value read in place from a page, copying it only when
+// it is kept. Values under a collation other than BINARY are copied to be
+// compared; a BINARY MIN or MAX is kept in bestField.
+func (a *aggState) addField(b []byte) {
+	if b == nil && a.col >= 0 {
+		return
+	}
+	if a.coll != nil && !a.coll.IsBinary() {
+		a.add(string(b))
+		return
+	}
+	switch {
+	case !a.accept(string(b)):
+		if a.err == nil {
+			a.err = fmt.Errorf("%s: %s is not a number", a.Label(), b)
+		}
+	case a.keeps(string(b)) && (a.n == 1 || a.better(compareField(b, a.bestField))):
+		a.bestField = append(a.bestField[:0], b...)
+	}
+}
+
+// compareField is compareKeys for values in pages, which it compares
+// without copying them
+func compareField(x, y []byte) int {
+	xn, xNum := parseOrderNumber(string(x))
+	yn, yNum := parseOrderNumber(string(y))
+	switch {
+	case xNum && yNum:
+		if c := cmp.Compare(xn, yn); c != 0 {
+			return c
+		}
+	case xNum:
+		return -1
+	case yNum:
+		return 1
+	}
+	return bytes.Compare(x, y)
+}
+
+// result returns the aggregate's value; SUM, MIN and MAX of no values are
+// NULL
+func (a *aggState) result() string {
+	switch {
+	case a.Func == AggCount:
+		return strconv.FormatInt(a.n, 10)
+	case a.n == 0:
+		return NullValue
+	case a.Func != AggSum:
+		return a.best
+	case a.floats:
+		return strconv.FormatFloat(a.fsum, 'g', -1, 64)
+	}
+	return strconv.FormatInt(a.isum, 10)
+}
+
+// SelectAggregatesResult returns one row holding the aggregates over the rows of
+// a table, or of the rows matching whereExpr when it is not nil. MIN and
+// MAX of a column masked for role are masked like the column.
+func (db *Database) SelectAggregatesResult(tableName string, aggs []Aggregate, whereExpr interface{}, role string) (*ResultSet, string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if msg := table.externalError(); msg != "" {
+		return nil, msg
+	}
+	table.stats.reads.Add(1)
+
+	states, msg := table.aggStates(aggs)
+	if msg != "" {
+		return nil, msg
+	}
+	countOnly := true
+	labels := make([]string, len(states))
+	types := make([]ColumnType, len(states))
+	for i, s := range states {
+		countOnly = countOnly && s.col < 0
+		labels[i] = s.Label()
+		types[i] = table.aggType(s)
+	}
+
+	switch {
+	case whereExpr == nil && countOnly:
+		// Counting needs no rows at all
+		for _, s := range states {
+			s.n = int64(len(db.visibleRows(table)))
+		}
+	case whereExpr == nil && db.aggregatePages(table, states):
+	case whereExpr == nil:
+		for _, row := range db.visibleRows(table) {
+			aggregateRow(states, row)
+		}
+	default:
+		rows, matched, msg := db.whereRows(table, whereExpr)
+		if msg != "" {
+			return nil, msg
+		}
+		for _, ri := range matched {
+			aggregateRow(states, rows[ri])
+		}
+	}
+
+	// Without a WHERE clause COUNT(*) includes the open transaction's
+	// pending inserts and deletes
+	delta := 0
+	if whereExpr == nil {
+		delta = db.pendingRowDelta(tableName)
+	}
+	values := make([]string, len(states))
+	for i, s := range states {
+		if s.err != nil {
+			return nil, fmt.Sprintf("Error: %v", s.err)
+		}
+		if s.col < 0 {
+			s.n += int64(delta)
+		}
+		values[i] = table.aggResult(s, role)
+	}
+	return &ResultSet{Columns: labels, Rows: [][]string{values}, Types: types}, ""
+}
+
+// SelectAggregates is SelectAggregatesResult rendered as text
+func (db *Database) SelectAggregates(tableName string, aggs []Aggregate, whereExpr interface{}, role string) string {
+	return resultText(db.SelectAggregatesResult(tableName, aggs, whereExpr, role))
+}
+
+// aggResult returns an aggregate's value, masking a MIN or MAX of a column
+// masked for role like the column
+func (t *Table) aggResult(s *aggState, role string) string {
+	v := s.result()
+	if (s.Func == AggMin || s.Func == AggMax) && !IsNull(v) {
+		if mask := t.columnMask(s.Column, role); mask != nil {
+			v = mask(v)
+		}
+	}
+	return v
+}
+
+// aggType returns the type of an aggregate's result: COUNT is an INT, SUM a
+// number and MIN and MAX have the type of their column
+func (t *Table) aggType(s *aggState) ColumnType {
+	switch {
+	case s.Func == AggCount:
+		return TypeInt
+	case s.Func == AggSum && t.Type(t.Columns[s.col]) == TypeInt:
+		return TypeInt
+	case s.Func == AggSum:
+		return TypeFloat
+	}
+	return t.Type(t.Columns[s.col])
+}
+
+// aggStates resolves aggregates against the table's columns, returning
+// their empty states or an error message
+func (t *Table) aggStates(aggs []Aggregate) ([]*aggState, string) {
+	states := make([]*aggState, len(aggs))
+	for i, agg := range aggs {
+		agg.Func = strings.ToUpper(agg.Func)
+		switch agg.Func {
+		case AggCount, AggSum, AggMin, AggMax:
+		default:
+			return nil, fmt.Sprintf("Error: unknown aggregate %s", agg.Func)
+		}
+		s := &aggState{Aggregate: agg, col: -1}
+		if agg.Column != "*" {
+			if s.col = t.columnIndex(agg.Column); s.col < 0 {
+				return nil, fmt.Sprintf("Column %s not found", agg.Column)
+			}
+			s.Column = t.Columns[s.col]
+			s.coll = t.Collation(s.Column)
+		} else if agg.Func != AggCount {
+			return nil, fmt.Sprintf("Error: %s(*) is not an aggregate; use %s(column)", agg.Func, agg.Func)
+		}
+		states[i] = s
+	}
+	return states, ""
+}
+
+// whereRows returns the table's visible rows and the indexes of those
+// matching whereExpr, or an error message
+func (db *Database) whereRows(table *Table, whereExpr interface{}) ([][]string, []int, string) {
+	columnIndexes := make(map[string]int)
+	for i, col := range table.Columns {
+		columnIndexes[col] = i
+	}
+	bindWhere(whereExpr, table)
+	expr, ok := whereExpr.(rowEvaluator)
+	if !ok {
+		return nil, nil, "Invalid WHERE expression type"
+	}
+	rows := db.visibleRows(table)
+	matched, err := db.matchRows(rows, func(row []string) (bool, error) {
+		return expr.EvaluateExpression(row, columnIndexes)
+	})
+	if err != nil {
+		return nil, nil, fmt.Sprintf("Error evaluating WHERE condition: %v", err)
+	}
+	return rows, matched, ""
+}
+
+// aggregateRow adds a row in memory to each aggregate
+func aggregateRow(states []*aggState, row []string) {
+	for _, s := range states {
+		v := NullValue
+		if s.col >= 0 && s.col < len(row) {
+			v = row[s.col]
+		}
+		s.add(v)
+	}
+}
+
+// aggregatePages computes the aggregates during a scan of the table's
+// pages, reporting false, with states untouched, when the pages do not hold
+// exactly the rows the query sees or cannot be read
+func (db *Database) aggregatePages(table *Table, states []*aggState) bool {
+	if db.PageStorage == nil {
+		return false
+	}
+	if _, ok := db.snapshotOf(table); ok {
+		return false
+	}
+	// Holding the lock keeps inserts from adding to the pages mid-scan
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+	if !table.pagesMirrorRows() {
+		return false
+	}
+
+	scanned := make([]*aggState, len(states))
+	cols := make([]int, len(states))
+	for i, s := range states {
+		copied := *s
+		scanned[i], cols[i] = &copied, s.col
+	}
+	err := db.PageStorage.scanFields(table.Name, cols, func(fields [][]byte) {
+		for i, s := range scanned {
+			s.addField(fields[i])
+		}
+	})
+	if err != nil {
+		return false
+	}
+	for i, s := range scanned {
+		if s.bestField != nil {
+			s.best = string(s.bestField)
+		}
+		*states[i] = *s
+	}
+	return true
+}
+
+// pagesMirrorRows reports whether the table's pages hold exactly its rows.
+// The caller holds t.lock.
+func (t *Table) pagesMirrorRows() bool {
+	return t.pagesExact && t.pagesVersion == t.version.Load()
+}
+
+// columnMask returns the mask of column for role, or nil
+func (t *Table) columnMask(column, role string) func(string) string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if i := t.maskIndex(column, role); i >= 0 {
+		return maskFunc(t.Masks[i].Method)
+	}
+	return nil
+}
+
+// scanFields calls visit with the fields at cols of every row of a table,
+// read in place from its pages: a field is only valid during the call, and
+// is nil for a column index of -1 or past the end of the row
+func (ps *PageStorage) scanFields(tableName string, cols []int, visit func(fields [][]byte)) error {
+	metadata, err := ps.loadMetadata(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+	if metadata.PageCount == 0 {
+		return nil
+	}
+	fields := make([][]byte, len(cols))
+	for pageID := metadata.FirstPageID; pageID <= metadata.LastPageID; pageID++ {
+		page, err := ps.loadPage(tableName, pageID)
+		if err != nil {
+			return fmt.Errorf("failed to load page %d: %w", pageID, err)
+		}
+		page.mu.RLock()
+		err = scanPageFields(page, cols, fields, visit)
+		page.mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("page %d: %w", pageID, err)
+		}
+	}
+	return nil
+}
+
+// scanPageFields calls visit for each row of a page, as scanFields does.
+// The caller holds page.mu.
+func scanPageFields(page *Page, cols []int, fields [][]byte, visit func([][]byte)) error {
+	data := page.Data
+	off := 0
+	for r := 0; r < int(page.Header.RowCount); r++ {
+		// Each row is its length, its field count, then each field's length
+		// and bytes (see serializeRow)
+		if off+2 > len(data) {
+			return fmt.Errorf("row %d is truncated", r)
+		}
+		rowLen := int(binary.LittleEndian.Uint16(data[off:]))
+		off += 2
+		if off+rowLen > len(data) || rowLen < 2 {
+			return fmt.Errorf("row %d is truncated", r)
+		}
+		row := data[off : off+rowLen]
+		off += rowLen
+
+		for i := range fields {
+			fields[i] = nil
+		}
+		count := int(binary.LittleEndian.Uint16(row))
+		pos := 2
+		for c := 0; c < count; c++ {
+			if pos+2 > len(row) {
+				return fmt.Errorf("row %d is truncated", r)
+			}
+			n := int(binary.LittleEndian.Uint16(row[pos:]))
+			pos += 2
+			if pos+n > len(row) {
+				return fmt.Errorf("row %d is truncated", r)
+			}
+			for i, col := range cols {
+				if col == c {
+					fields[i] = row[pos : pos+n]
+				}
+			}
+			pos += n
+		}
+		visit(fields)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSelectAggregates(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("items", []string{"name", "price", "qty", "note COLLATE NOCASE"})
+	for _, row := range [][]string{
+		{"pen", "1.5", "10", "b"},
+		{"desk", "250", "1", NullValue},
+		{"lamp", "30", "4", "A"},
+		{"cup", "9", NullValue, "c"},
+	} {
+		db.Insert("items", row)
+	}
+
+	aggs := []Aggregate{
+		{Func: AggCount, Column: "*"}, {Func: AggCount, Column: "qty"},
+		{Func: AggSum, Column: "price"}, {Func: AggSum, Column: "qty"},
+		{Func: AggMin, Column: "price"}, {Func: AggMax, Column: "name"},
+		{Func: AggMin, Column: "note"},
+	}
+	want := "count | count(qty) | sum(price) | sum(qty) | min(price) | max(name) | min(note)\n" +
+		"4 | 3 | 290.5 | 15 | 1.5 | pen | A\n"
+
+	// Only inserts so far, so the pages hold the rows
+	table, _ := db.lookupTable("items")
+	states := make([]*aggState, 1)
+	states[0] = &aggState{Aggregate: Aggregate{Func: AggSum, Column: "qty"}, col: 2, coll: table.Collation("qty")}
+	if !db.aggregatePages(table, states) || states[0].result() != "15" {
+		t.Fatalf("page scan did not aggregate: %+v", states[0])
+	}
+	if got := db.SelectAggregates("items", aggs, nil, ""); got != want {
+		t.Errorf("from pages:\n%s\nwant:\n%s", got, want)
+	}
+
+	// After an update the pages are stale and the rows are aggregated
+	db.Update("items", 0, []string{"pen", "1.5", "10", "b"})
+	states[0] = &aggState{Aggregate: Aggregate{Func: AggSum, Column: "qty"}, col: 2}
+	if db.aggregatePages(table, states) {
+		t.Error("page scan used after an update")
+	}
+	if got := db.SelectAggregates("items", aggs, nil, ""); got != want {
+		t.Errorf("from memory:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := db.SelectAggregates("items", []Aggregate{{Func: AggSum, Column: "name"}}, nil, ""); !strings.Contains(got, "sum(name): pen is not a number") {
+		t.Errorf("expected SUM of text to fail, got %q", got)
+	}
+	if got := db.SelectAggregates("items", []Aggregate{{Func: AggMax, Column: "missing"}}, nil, ""); !strings.Contains(got, "missing not found") {
+		t.Errorf("expected unknown column error, got %q", got)
+	}
+
+	db.CreateTable("empty", []string{"n"})
+	got := db.SelectAggregates("empty", []Aggregate{{Func: AggCount, Column: "n"}, {Func: AggSum, Column: "n"}, {Func: AggMax, Column: "n"}}, nil, "")
+	if got != "count(n) | sum(n) | max(n)\n0 | NULL | NULL\n" {
+		t.Errorf("aggregates of no rows = %q", got)
+	}
+}
+
+func TestAggregatePagesSum(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("big", []string{"n"})
+	db.Insert("big", []string{"9223372036854775807"})
+	db.Insert("big", []string{"1"})
+	db.Insert("big", []string{"-0.5"})
+	if got := db.SelectAggregates("big", []Aggregate{{Func: AggSum, Column: "n"}}, nil, ""); got != "sum(n)\n9.223372036854776e+18\n" {
+		t.Errorf("overflowing sum = %q", got)
+	}
+}
+
+func TestAggregatePagesAllocations(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("nums", []string{"id", "label", "n"})
+	const rows = 200
+	for i := 0; i < rows; i++ {
+		db.Insert("nums", []string{fmt.Sprint(i), fmt.Sprintf("label-%d", i), fmt.Sprint(i * 3)})
+	}
+	table, _ := db.lookupTable("nums")
+
+	var states []*aggState
+	allocs := testing.AllocsPerRun(5, func() {
+		states = []*aggState{
+			{Aggregate: Aggregate{Func: AggSum, Column: "n"}, col: 2, coll: table.Collation("n")},
+			{Aggregate: Aggregate{Func: AggMax, Column: "n"}, col: 2, coll: table.Collation("n")},
+		}
+		if !db.aggregatePages(table, states) {
+			t.Fatal("page scan not used")
+		}
+	})
+	if states[0].result() != fmt.Sprint(3*rows*(rows-1)/2) || states[1].result() != fmt.Sprint(3*(rows-1)) {
+		t.Errorf("sum = %s, max = %s", states[0].result(), states[1].result())
+	}
+	// The page scan allocates per query, not per row
+	if allocs > rows/4 {
+		t.Errorf("page scan made %.0f allocations for %d rows", allocs, rows)
+	}
+}
@@ -3,28 +3,106 @@ package storage
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
+const (
+	// backupInfoName is the archive entry holding BackupInfo
+	backupInfoName = "backup_info.json"
+
+	// Encrypted backups start with this magic, followed by a format version,
+	// the PBKDF2 salt, the AES-GCM nonce and the sealed tar.gz archive.
+	backupEncryptionMagic   = "HDBE"
+	backupEncryptionVersion = 1
+	backupSaltSize          = 16
+	backupKDFIterations     = 100000
+)
+
+// credentialFiles are data directory files holding login secrets and TLS key
+// material. They are left out of backups unless
+// BackupOptions.IncludeCredentials is set.
+var credentialFiles = map[string]bool{
+	"users.json": true,
+	"server.crt": true,
+	"server.key": true,
+}
+
+// isSecretFile reports whether a data directory file holds secrets: the
+// credential files and the table encryption keyring
+func isSecretFile(name string) bool {
+	return credentialFiles[name] || name == KeyringName
+}
+
 // BackupManager handles database backup and restore operations
 type BackupManager struct {
-	dataDir string
+	dataDir     string
+	snapshotter Snapshotter
+}
+
+// Snapshotter pauses writes while fn runs so the data directory can be copied
+// in a consistent state. *Database implements it.
+type Snapshotter interface {
+	Snapshot(fn func() error) error
+}
+
+// snapshotFile is a data directory file captured for a backup
+type snapshotFile struct {
+	name    string
+	mode    os.FileMode
+	modTime time.Time
+	content []byte
 }
 
 // BackupInfo contains information about a backup
 type BackupInfo struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Version     string    `json:"version"`
-	TableCount  int       `json:"table_count"`
-	BackupSize  int64     `json:"backup_size"`
-	Description string    `json:"description"`
+	Timestamp           time.Time    `json:"timestamp"`
+	Version             string       `json:"version"`
+	TableCount          int          `json:"table_count"`
+	BackupSize          int64        `json:"backup_size"`
+	Description         string       `json:"description"`
+	Encrypted           bool         `json:"encrypted"`
+	CredentialsExcluded bool         `json:"credentials_excluded,omitempty"`
+	KeysExcluded        bool         `json:"keys_excluded,omitempty"`
+	Files               []BackupFile `json:"files,omitempty"`
+}
+
+// BackupFile is a manifest entry describing one file stored in a backup
+type BackupFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupOptions controls how a backup is created
+type BackupOptions struct {
+	Description string
+	// Passphrase encrypts the archive with AES-256-GCM when non-empty
+	Passphrase string
+	// IncludeCredentials adds users.json and TLS key material, which backups
+	// leave out by default
+	IncludeCredentials bool
+	// IncludeKeys adds the table encryption keyring, which backups leave out
+	// by default; encrypted tables cannot be read from a backup without it
+	IncludeKeys bool
+	// AtSnapshot, if set, runs while writes are paused for the copy, e.g. to
+	// record the change log position the backup corresponds to
+	AtSnapshot func()
 }
 
 // NewBackupManager creates a new backup manager
@@ -34,62 +112,166 @@ func NewBackupManager(dataDir string) *BackupManager {
 	}
 }
 
-// CreateBackup creates a backup of the database
+// SetSnapshotter makes backups quiesce writes through s while files are copied.
+// Without one, files are read as-is and may be torn by concurrent writers.
+func (bm *BackupManager) SetSnapshotter(s Snapshotter) {
+	bm.snapshotter = s
+}
+
+// CreateBackup creates an unencrypted backup of the database
 func (bm *BackupManager) CreateBackup(backupPath string, description string) error {
+	return bm.CreateBackupWithOptions(backupPath, BackupOptions{Description: description})
+}
+
+// CreateBackupWithOptions creates a backup of the database using the given options
+func (bm *BackupManager) CreateBackupWithOptions(backupPath string, opts BackupOptions) error {
 	// Create backup directory if it doesn't exist
 	backupDir := filepath.Dir(backupPath)
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	// Create backup file
-	backupFile, err := os.Create(backupPath)
-	if err != nil {
+	var data bytes.Buffer
+	if err := bm.WriteBackup(&data, opts); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(backupPath, data.Bytes(), 0600); err != nil {
 		return fmt.Errorf("failed to create backup file: %w", err)
 	}
-	defer backupFile.Close()
 
-	// Create gzip writer
-	gzipWriter := gzip.NewWriter(backupFile)
-	defer gzipWriter.Close()
+	return nil
+}
 
-	// Create tar writer
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
+// WriteBackup writes a complete backup, encrypted when opts.Passphrase is set,
+// to w. The output is byte-for-byte what CreateBackupWithOptions stores on disk.
+// An unencrypted archive is written to w as it is compressed; an encrypted
+// one is sealed whole and written at the end.
+func (bm *BackupManager) WriteBackup(w io.Writer, opts BackupOptions) error {
+	files, err := bm.snapshotFiles(opts)
+	if err != nil {
+		return err
+	}
 
-	// Get all .harudb files
-	entries, err := os.ReadDir(bm.dataDir)
+	// Compression and encryption run after writers have resumed
+	if opts.Passphrase == "" {
+		return writeArchive(w, files, opts)
+	}
+	var archive bytes.Buffer
+	if err := writeArchive(&archive, files, opts); err != nil {
+		return err
+	}
+
+	sealed, err := encryptBackup(archive.Bytes(), opts.Passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to read data directory: %w", err)
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	if _, err := w.Write(sealed); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
 	}
 
-	tableCount := 0
-	totalSize := int64(0)
+	return nil
+}
 
-	// Add all .harudb files to backup
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".harudb") {
-			continue
+// snapshotFiles copies every file that makes up the database state into
+// memory: table data, page files and metadata, the WAL and (optionally)
+// credentials and keys. With a snapshotter attached, writers are paused and the WAL is
+// checkpointed for the duration of the copy only.
+func (bm *BackupManager) snapshotFiles(opts BackupOptions) ([]snapshotFile, error) {
+	var files []snapshotFile
+	collect := func() error {
+		if opts.AtSnapshot != nil {
+			opts.AtSnapshot()
+		}
+		var err error
+		files, err = bm.readDataFiles(opts)
+		return err
+	}
+
+	if bm.snapshotter == nil {
+		return files, collect()
+	}
+	if err := bm.snapshotter.Snapshot(collect); err != nil {
+		return nil, fmt.Errorf("failed to snapshot data directory: %w", err)
+	}
+	return files, nil
+}
+
+// readDataFiles reads the backup files currently in the data directory and
+// its table directories. Names are slash-separated paths relative to it.
+func (bm *BackupManager) readDataFiles(opts BackupOptions) ([]snapshotFile, error) {
+	var files []snapshotFile
+	err := filepath.WalkDir(bm.dataDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == bm.dataDir {
+				return err
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(bm.dataDir, path)
+		name := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			// Only the table directories hold database files
+			if path != bm.dataDir && name != TablesDirName && !strings.HasPrefix(name, TablesDirName+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isBackupFile(name) {
+			return nil
+		}
+		if credentialFiles[name] && !opts.IncludeCredentials || name == KeyringName && !opts.IncludeKeys {
+			return nil
 		}
 
-		filePath := filepath.Join(bm.dataDir, entry.Name())
 		fileInfo, err := entry.Info()
 		if err != nil {
-			continue
+			return nil
 		}
 
-		// Read file content
-		fileContent, err := os.ReadFile(filePath)
+		content, err := os.ReadFile(path)
 		if err != nil {
-			continue
+			return nil
 		}
 
-		// Create tar header
+		files = append(files, snapshotFile{
+			name:    name,
+			mode:    fileInfo.Mode(),
+			modTime: fileInfo.ModTime(),
+			content: content,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	// Table files and the WAL come before the table directories
+	sort.SliceStable(files, func(i, j int) bool {
+		return !strings.Contains(files[i].name, "/") && strings.Contains(files[j].name, "/")
+	})
+	return files, nil
+}
+
+// writeArchive writes a tar.gz archive of the snapshotted files to w
+func writeArchive(w io.Writer, snapshot []snapshotFile, opts BackupOptions) error {
+	// Create gzip writer
+	gzipWriter := gzip.NewWriter(w)
+
+	// Create tar writer
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	tableCount := 0
+	totalSize := int64(0)
+	var files []BackupFile
+
+	for _, f := range snapshot {
+		// Create tar header from the bytes actually read
 		header := &tar.Header{
-			Name:    entry.Name(),
-			Size:    fileInfo.Size(),
-			Mode:    int64(fileInfo.Mode()),
-			ModTime: fileInfo.ModTime(),
+			Name:    f.name,
+			Size:    int64(len(f.content)),
+			Mode:    int64(f.mode),
+			ModTime: f.modTime,
 		}
 
 		// Write header
@@ -98,21 +280,34 @@ func (bm *BackupManager) CreateBackup(backupPath string, description string) err
 		}
 
 		// Write file content
-		if _, err := tarWriter.Write(fileContent); err != nil {
+		if _, err := tarWriter.Write(f.content); err != nil {
 			return fmt.Errorf("failed to write file content: %w", err)
 		}
 
-		tableCount++
-		totalSize += fileInfo.Size()
+		sum := sha256.Sum256(f.content)
+		files = append(files, BackupFile{
+			Name:   f.name,
+			Size:   int64(len(f.content)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+
+		if strings.HasSuffix(f.name, ".harudb") {
+			tableCount++
+		}
+		totalSize += int64(len(f.content))
 	}
 
-	// Create backup info
+	// Create backup info with the integrity manifest
 	backupInfo := BackupInfo{
-		Timestamp:   time.Now(),
-		Version:     "v0.0.5",
-		TableCount:  tableCount,
-		BackupSize:  totalSize,
-		Description: description,
+		Timestamp:           time.Now(),
+		Version:             "v0.0.5",
+		TableCount:          tableCount,
+		BackupSize:          totalSize,
+		Description:         opts.Description,
+		Encrypted:           opts.Passphrase != "",
+		CredentialsExcluded: !opts.IncludeCredentials,
+		KeysExcluded:        !opts.IncludeKeys,
+		Files:               files,
 	}
 
 	// Serialize backup info
@@ -123,7 +318,7 @@ func (bm *BackupManager) CreateBackup(backupPath string, description string) err
 
 	// Add backup info to tar
 	infoHeader := &tar.Header{
-		Name:    "backup_info.json",
+		Name:    backupInfoName,
 		Size:    int64(len(infoData)),
 		Mode:    0644,
 		ModTime: time.Now(),
@@ -137,36 +332,47 @@ func (bm *BackupManager) CreateBackup(backupPath string, description string) err
 		return fmt.Errorf("failed to write backup info: %w", err)
 	}
 
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finalize tar archive: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finalize gzip stream: %w", err)
+	}
+
 	return nil
 }
 
-// RestoreBackup restores a database from a backup
+// RestoreBackup restores a database from an unencrypted backup
 func (bm *BackupManager) RestoreBackup(backupPath string) error {
-	// Open backup file
-	backupFile, err := os.Open(backupPath)
-	if err != nil {
-		return fmt.Errorf("failed to open backup file: %w", err)
-	}
-	defer backupFile.Close()
+	return bm.RestoreBackupWithPassphrase(backupPath, "")
+}
 
-	// Create gzip reader
-	gzipReader, err := gzip.NewReader(backupFile)
+// RestoreBackupWithPassphrase restores a database from a backup, decrypting it
+// with passphrase if needed. The archive is verified before any existing data
+// is touched.
+func (bm *BackupManager) RestoreBackupWithPassphrase(backupPath, passphrase string) error {
+	contents, info, err := bm.readArchive(backupPath, passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return err
 	}
-	defer gzipReader.Close()
 
-	// Create tar reader
-	tarReader := tar.NewReader(gzipReader)
+	if err := verifyManifest(contents, info); err != nil {
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
 
-	// Clear existing data directory (except WAL and users)
+	// Clear the existing database state. The WAL is always replaced so that
+	// changes made after the backup are not replayed on top of it; credentials
+	// and keys are kept when the backup was taken without them.
 	entries, err := os.ReadDir(bm.dataDir)
 	if err != nil {
 		return fmt.Errorf("failed to read data directory: %w", err)
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".harudb") {
+		if entry.IsDir() || !isBackupFile(entry.Name()) {
+			continue
+		}
+		if isSecretFile(entry.Name()) && contents[entry.Name()] == nil {
 			continue
 		}
 
@@ -175,92 +381,274 @@ func (bm *BackupManager) RestoreBackup(backupPath string) error {
 			return fmt.Errorf("failed to remove existing file %s: %w", entry.Name(), err)
 		}
 	}
+	if err := os.RemoveAll(filepath.Join(bm.dataDir, TablesDirName)); err != nil {
+		return fmt.Errorf("failed to remove existing table directories: %w", err)
+	}
 
 	// Extract files from backup
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("failed to read tar header: %w", err)
-		}
-
-		// Skip backup info file
-		if header.Name == "backup_info.json" {
+	for name, content := range contents {
+		if !isBackupFile(name) {
 			continue
 		}
 
-		// Only restore .harudb files
-		if !strings.HasSuffix(header.Name, ".harudb") {
-			continue
+		mode := os.FileMode(0644)
+		if isSecretFile(name) {
+			mode = 0600
 		}
 
-		// Create file
-		filePath := filepath.Join(bm.dataDir, header.Name)
-		file, err := os.Create(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", header.Name, err)
+		filePath := filepath.Join(bm.dataDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
 		}
-
-		// Copy file content
-		if _, err := io.Copy(file, tarReader); err != nil {
-			file.Close()
-			return fmt.Errorf("failed to copy file content for %s: %w", header.Name, err)
+		if err := os.WriteFile(filePath, content, mode); err != nil {
+			return fmt.Errorf("failed to create file %s: %w", name, err)
 		}
-
-		file.Close()
 	}
 
 	return nil
 }
 
+// isBackupFile reports whether a data directory file is part of the database
+// state captured by backups. Temp files from in-flight atomic writes are skipped.
+func isBackupFile(name string) bool {
+	if isTempFile(name) {
+		return false
+	}
+	if strings.Contains(name, "/") {
+		return isTableFile(name)
+	}
+	switch {
+	case strings.HasSuffix(name, ".harudb"),
+		strings.HasSuffix(name, ".meta"),
+		strings.Contains(name, ".page."),
+		name == "wal.log",
+		name == PageControlName,
+		name == proceduresFileName,
+		isSecretFile(name):
+		return true
+	}
+	return false
+}
+
 // GetBackupInfo returns information about a backup file
 func (bm *BackupManager) GetBackupInfo(backupPath string) (*BackupInfo, error) {
-	// Open backup file
-	backupFile, err := os.Open(backupPath)
+	return bm.GetBackupInfoWithPassphrase(backupPath, "")
+}
+
+// GetBackupInfoWithPassphrase returns information about a possibly encrypted backup file
+func (bm *BackupManager) GetBackupInfoWithPassphrase(backupPath, passphrase string) (*BackupInfo, error) {
+	_, info, err := bm.readArchive(backupPath, passphrase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open backup file: %w", err)
+		return nil, err
+	}
+	return info, nil
+}
+
+// VerifyBackup validates a backup archive against its embedded manifest
+// without restoring it.
+func (bm *BackupManager) VerifyBackup(backupPath, passphrase string) (*BackupInfo, error) {
+	contents, info, err := bm.readArchive(backupPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.Files) == 0 && info.TableCount > 0 {
+		return nil, fmt.Errorf("backup has no integrity manifest")
+	}
+
+	if err := verifyManifest(contents, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// readArchive opens a backup, decrypting it if needed, and returns the
+// contents of every file in the archive together with its BackupInfo.
+func (bm *BackupManager) readArchive(backupPath, passphrase string) (map[string][]byte, *BackupInfo, error) {
+	raw, err := os.ReadFile(backupPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+
+	if isEncryptedBackup(raw) {
+		if passphrase == "" {
+			return nil, nil, fmt.Errorf("backup is encrypted: passphrase required")
+		}
+		raw, err = decryptBackup(raw, passphrase)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	defer backupFile.Close()
 
 	// Create gzip reader
-	gzipReader, err := gzip.NewReader(backupFile)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(raw))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		return nil, nil, fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 	defer gzipReader.Close()
 
 	// Create tar reader
 	tarReader := tar.NewReader(gzipReader)
 
-	// Find backup info file
+	contents := make(map[string][]byte)
+	var info *BackupInfo
+
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to read tar header: %w", err)
+			return nil, nil, fmt.Errorf("failed to read tar header: %w", err)
 		}
 
-		if header.Name == "backup_info.json" {
-			// Read backup info
-			infoData := make([]byte, header.Size)
-			if _, err := io.ReadFull(tarReader, infoData); err != nil {
-				return nil, fmt.Errorf("failed to read backup info: %w", err)
-			}
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s from backup: %w", header.Name, err)
+		}
 
-			var backupInfo BackupInfo
-			if err := json.Unmarshal(infoData, &backupInfo); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal backup info: %w", err)
+		if header.Name == backupInfoName {
+			info = &BackupInfo{}
+			if err := json.Unmarshal(content, info); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal backup info: %w", err)
 			}
+			continue
+		}
 
-			return &backupInfo, nil
+		// Never let archive entries escape the data directory: only table
+		// directory files keep their path
+		name := header.Name
+		if !isTableFile(name) {
+			name = filepath.Base(name)
 		}
+		if _, ok := contents[name]; ok {
+			return nil, nil, fmt.Errorf("file %s appears twice in backup", name)
+		}
+		contents[name] = content
+	}
+
+	if info == nil {
+		return nil, nil, fmt.Errorf("backup info not found in backup file")
 	}
 
-	return nil, fmt.Errorf("backup info not found in backup file")
+	return contents, info, nil
+}
+
+// verifyManifest checks archive contents against the manifest in info: every
+// listed file must be intact, and no file may be there that is not listed.
+// Backups created before manifests existed have no entries and pass.
+func verifyManifest(contents map[string][]byte, info *BackupInfo) error {
+	if len(info.Files) == 0 {
+		return nil
+	}
+	listed := make(map[string]bool, len(info.Files))
+	for _, f := range info.Files {
+		listed[f.Name] = true
+	}
+	for name := range contents {
+		if !listed[name] {
+			return fmt.Errorf("file %s is not listed in the manifest", name)
+		}
+	}
+	for _, f := range info.Files {
+		content, ok := contents[f.Name]
+		if !ok {
+			return fmt.Errorf("file %s listed in manifest is missing", f.Name)
+		}
+		if int64(len(content)) != f.Size {
+			return fmt.Errorf("file %s size mismatch: expected %d, got %d", f.Name, f.Size, len(content))
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return fmt.Errorf("file %s checksum mismatch", f.Name)
+		}
+	}
+	return nil
+}
+
+// isEncryptedBackup reports whether data starts with the encrypted backup magic
+func isEncryptedBackup(data []byte) bool {
+	return len(data) >= len(backupEncryptionMagic) &&
+		string(data[:len(backupEncryptionMagic)]) == backupEncryptionMagic
+}
+
+// deriveBackupKey derives an AES-256 key from a passphrase and salt
+func deriveBackupKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, backupKDFIterations, 32)
+}
+
+// encryptBackup seals a backup archive with a passphrase-derived key
+func encryptBackup(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, backupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveBackupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteString(backupEncryptionMagic)
+	out.WriteByte(backupEncryptionVersion)
+	out.Write(salt)
+	out.Write(nonce)
+	out.Write(gcm.Seal(nil, nonce, data, []byte(backupEncryptionMagic)))
+	return out.Bytes(), nil
+}
+
+// decryptBackup opens an encrypted backup archive
+func decryptBackup(data []byte, passphrase string) ([]byte, error) {
+	headerSize := len(backupEncryptionMagic) + 1
+	if len(data) < headerSize+backupSaltSize {
+		return nil, fmt.Errorf("encrypted backup too short")
+	}
+	if data[len(backupEncryptionMagic)] != backupEncryptionVersion {
+		return nil, fmt.Errorf("unsupported backup encryption version %d", data[len(backupEncryptionMagic)])
+	}
+
+	salt := data[headerSize : headerSize+backupSaltSize]
+	key, err := deriveBackupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[headerSize+backupSaltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted backup too short")
+	}
+
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(backupEncryptionMagic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup: wrong passphrase or corrupted archive")
+	}
+	return plain, nil
 }
 
 // ListBackups lists all backup files in a directory
@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupManifestAndVerify(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.Insert("users", []string{"1", "Alice"})
+
+	bm := NewBackupManager(dataDir)
+	backupPath := filepath.Join(t.TempDir(), "plain.backup")
+	if err := bm.CreateBackup(backupPath, "test"); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	info, err := bm.VerifyBackup(backupPath, "")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if len(info.Files) == 0 || info.Files[0].Name != "users.harudb" {
+		t.Fatalf("unexpected manifest: %+v", info.Files)
+	}
+	if info.Encrypted {
+		t.Error("plain backup reported as encrypted")
+	}
+
+	// A tampered file must be detected by the manifest check
+	contents, info, err := bm.readArchive(backupPath, "")
+	if err != nil {
+		t.Fatalf("read archive failed: %v", err)
+	}
+	contents["users.harudb"] = append(contents["users.harudb"], ' ')
+	if err := verifyManifest(contents, info); err == nil {
+		t.Error("expected manifest verification to fail for tampered file")
+	}
+}
+
+func TestBackupRejectsUnlistedFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.Insert("users", []string{"1", "Alice"})
+
+	bm := NewBackupManager(dataDir)
+	backupPath := filepath.Join(t.TempDir(), "plain.backup")
+	if err := bm.CreateBackup(backupPath, "test"); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+
+	for _, extra := range []string{"evil.harudb", "users.json", "../wal.log"} {
+		tampered := filepath.Join(t.TempDir(), "tampered.backup")
+		addArchiveEntry(t, backupPath, tampered, extra, []byte("planted"))
+
+		if _, err := bm.VerifyBackup(tampered, ""); err == nil {
+			t.Errorf("%s: verify accepted a file missing from the manifest", extra)
+		}
+		if err := bm.RestoreBackup(tampered); err == nil {
+			t.Errorf("%s: restore accepted a file missing from the manifest", extra)
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, "users.harudb")); err != nil {
+			t.Errorf("%s: rejected restore touched the data directory: %v", extra, err)
+		}
+	}
+	for _, planted := range []string{"evil.harudb", "users.json"} {
+		if _, err := os.Stat(filepath.Join(dataDir, planted)); err == nil {
+			t.Errorf("rejected restore wrote %s", planted)
+		}
+	}
+}
+
+// addArchiveEntry copies the backup at src to dst with one more file
+func addArchiveEntry(t *testing.T, src, dst, name string, content []byte) {
+	t.Helper()
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(zw)
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0644}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	zw.Close()
+	if err := os.WriteFile(dst, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedBackupRoundTrip(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+	_ = db.CreateTable("secrets", []string{"k", "v"})
+	_ = db.Insert("secrets", []string{"token", "s3cr3t-value"})
+
+	bm := NewBackupManager(dataDir)
+	backupPath := filepath.Join(t.TempDir(), "enc.backup")
+	opts := BackupOptions{Description: "encrypted", Passphrase: "correct horse"}
+	if err := bm.CreateBackupWithOptions(backupPath, opts); err != nil {
+		t.Fatalf("encrypted backup failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if !isEncryptedBackup(raw) {
+		t.Fatal("backup file is not marked as encrypted")
+	}
+	if strings.Contains(string(raw), "s3cr3t-value") {
+		t.Fatal("encrypted backup leaks plaintext data")
+	}
+
+	if _, err := bm.VerifyBackup(backupPath, ""); err == nil {
+		t.Error("expected verify without passphrase to fail")
+	}
+	if _, err := bm.VerifyBackup(backupPath, "wrong"); err == nil {
+		t.Error("expected verify with wrong passphrase to fail")
+	}
+	info, err := bm.VerifyBackup(backupPath, "correct horse")
+	if err != nil {
+		t.Fatalf("verify with passphrase failed: %v", err)
+	}
+	if !info.Encrypted {
+		t.Error("encrypted backup info not marked encrypted")
+	}
+
+	// Restore over modified data and confirm the original row comes back
+	_ = db.Insert("secrets", []string{"extra", "row"})
+	db.Close()
+	if err := bm.RestoreBackupWithPassphrase(backupPath, "correct horse"); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	restored := NewDatabase(dataDir)
+	defer restored.Close()
+	if rows := len(restored.Tables["secrets"].Rows); rows != 1 {
+		t.Fatalf("expected 1 row after restore, got %d", rows)
+	}
+}
+
+func TestBackupIncludesFullDataDirectory(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+	_ = db.CreateTable("orders", []string{"id", "item"})
+	_ = db.Insert("orders", []string{"1", "Laptop"})
+	for _, name := range []string{"users.json", "server.key", KeyringName} {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(`{"admin":{}}`), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	// Leftover temp files must never be captured
+	if err := os.WriteFile(filepath.Join(dataDir, "orders.harudb.tmp-123"), []byte("partial"), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	bm := NewBackupManager(dataDir)
+	backupNames := func(path string) (map[string]bool, *BackupInfo) {
+		t.Helper()
+		info, err := bm.VerifyBackup(path, "")
+		if err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+		names := make(map[string]bool)
+		for _, f := range info.Files {
+			names[f.Name] = true
+		}
+		return names, info
+	}
+
+	// Secrets are left out unless asked for
+	noCreds := filepath.Join(t.TempDir(), "nocreds.backup")
+	if err := bm.CreateBackup(noCreds, "default"); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	names, info := backupNames(noCreds)
+	for _, want := range []string{"orders.harudb", "tables/orders/manifest.json", "wal.log"} {
+		if !names[want] {
+			t.Errorf("expected %s in backup, got %v", want, names)
+		}
+	}
+	for _, secret := range []string{"users.json", "server.key", KeyringName} {
+		if names[secret] {
+			t.Errorf("%s backed up without being asked for", secret)
+		}
+	}
+	if !info.CredentialsExcluded || !info.KeysExcluded {
+		t.Error("expected backup to record that credentials and keys were excluded")
+	}
+	if names["orders.harudb.tmp-123"] {
+		t.Error("temp file should not be backed up")
+	}
+
+	full := filepath.Join(t.TempDir(), "full.backup")
+	if err := bm.CreateBackupWithOptions(full, BackupOptions{IncludeCredentials: true, IncludeKeys: true}); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	names, info = backupNames(full)
+	for _, want := range []string{"orders.harudb", "users.json", "server.key", KeyringName} {
+		if !names[want] {
+			t.Errorf("expected %s in backup, got %v", want, names)
+		}
+	}
+	if info.CredentialsExcluded || info.KeysExcluded {
+		t.Error("backup with credentials and keys recorded as excluding them")
+	}
+
+	// Restoring a backup without secrets keeps the current ones
+	db.Close()
+	if err := bm.RestoreBackup(noCreds); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	for _, secret := range []string{"users.json", "server.key", KeyringName} {
+		if _, err := os.Stat(filepath.Join(dataDir, secret)); err != nil {
+			t.Errorf("%s should survive restore of a backup without it: %v", secret, err)
+		}
+	}
+}
+
+func TestSnapshotBlocksWriters(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("events", []string{"id"})
+
+	inserted := make(chan struct{})
+	err := db.Snapshot(func() error {
+		go func() {
+			_ = db.Insert("events", []string{"1"})
+			close(inserted)
+		}()
+		select {
+		case <-inserted:
+			t.Error("insert completed while snapshot was held")
+		case <-time.After(50 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	<-inserted
+	if rows := len(db.Tables["events"].Rows); rows != 1 {
+		t.Fatalf("expected 1 row after snapshot released, got %d", rows)
+	}
+}
+
+func TestOnlineBackupUnderLoad(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+	_ = db.CreateTable("events", []string{"id", "payload"})
+
+	bm := NewBackupManager(dataDir)
+	bm.SetSnapshotter(db)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = db.Insert("events", []string{strconv.Itoa(i), strings.Repeat("x", 64)})
+			}
+		}
+	}()
+
+	backupPath := filepath.Join(t.TempDir(), "online.backup")
+	err := bm.CreateBackup(backupPath, "under load")
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if _, err := bm.VerifyBackup(backupPath, ""); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+
+	db.Close()
+	if err := bm.RestoreBackup(backupPath); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	restored := NewDatabase(dataDir)
+	defer restored.Close()
+	table, ok := restored.Tables["events"]
+	if !ok {
+		t.Fatal("events table missing after restore")
+	}
+	for i, row := range table.Rows {
+		if row[0] != strconv.Itoa(i) {
+			t.Fatalf("row %d is %v, backup is not a consistent prefix of writes", i, row)
+		}
+	}
+}
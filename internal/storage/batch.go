@@ -0,0 +1,11 @@
+// internal/storage/batch.go
+//
+// Batch transactions are for bulk loads. Like every transaction, a batch
+// commits through a single commit record (see commit.go); it always runs at
+// READ COMMITTED, so a large load does not copy table snapshots.
+package storage
+
+// BeginBatch starts a READ COMMITTED transaction for a batch
+func (db *Database) BeginBatch() (*Transaction, error) {
+	return db.BeginTransaction(ReadCommitted)
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBatchCommit(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("items", []string{"id", "name"})
+	_ = db.Insert("items", []string{"1", "apple"})
+
+	if _, err := db.BeginBatch(); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.CreateTableTx("tags", []string{"name"})
+	_ = db.InsertTx("items", []string{"2", "pear"})
+	_ = db.InsertTx("items", []string{"3", "plum"})
+	_ = db.UpdateTx("items", 0, []string{"1", "apricot"})
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	want := "id | name\n1 | apricot\n2 | pear\n3 | plum\n"
+	if got := db.SelectAll("items"); got != want {
+		t.Fatalf("after commit: %q", got)
+	}
+	if got := db.SelectAll("tags"); !strings.HasPrefix(got, "name\n") {
+		t.Errorf("table created in batch: %q", got)
+	}
+
+	// The WAL replays the batch after the CREATE TABLE it follows
+	_ = db.Close()
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.SelectAll("items"); got != want {
+		t.Errorf("after restart: %q", got)
+	}
+}
+
+func TestBatchRollback(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("items", []string{"id"})
+
+	if _, err := db.BeginBatch(); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.InsertTx("items", []string{"1"})
+	if err := db.RollbackTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SelectAll("items"); got != "id\n(no rows)\n" {
+		t.Errorf("after rollback: %q", got)
+	}
+}
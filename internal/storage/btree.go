@@ -2,7 +2,8 @@
 //
 // This file implements a simple B-tree (order = 4) for string keys that maps
 // each key to one or more row indexes ([]int). The B-tree is used by HaruDB to
-// accelerate equality lookups and prepare for future range queries.
+// accelerate equality lookups and prepare for future range queries; HaruDB's
+// indexes store row IDs (see rowid.go) rather than positions.
 //
 // High-level design (read this first):
 // - A B-tree is a multi-way, balanced search tree.
@@ -12,17 +13,28 @@
 //   navigation (here we use linear search for simplicity).
 // - Leaf nodes keep values parallel to keys: each key maps to []int row indexes.
 // - Internal nodes keep only keys and child pointers; values live only in leaves.
+//   When a leaf splits, its middle key is copied up as a separator and stays in
+//   the right leaf, so every key is found in exactly one leaf.
+// - Keys are ordered by compareKeys: numbers first in numeric order, then text.
 //
 // What is implemented here:
 // - Insert(key, rowIndex): O(log n) insertion with node splitting as needed.
 // - GetEqual(key): O(log n) lookup that returns []int of row positions.
+// - Ascend/Descend: ordered traversal used for index-assisted ORDER BY.
+// - AscendFrom/DescendFrom: ordered traversal starting at a key, skipping the
+//   subtrees before it, used for keyset pagination.
 //
 // Not implemented (future work):
-// - Range search (e.g., BETWEEN) and ordered traversal APIs.
 // - Deletion (we currently rebuild or append as needed in HaruDB flows).
 
 package storage
 
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
 // btreeOrder sets max children per node. order=4 => up to 3 keys per node.
 const btreeOrder = 4
 
@@ -50,7 +62,7 @@ func (t *BTree) GetEqual(key string) []int {
 	for {
 		// Linear search within node (small node sizes keep this simple & fast)
 		i := 0
-		for i < len(n.keys) && key > n.keys[i] {
+		for i < len(n.keys) && compareKeys(key, n.keys[i]) > 0 {
 			i++
 		}
 
@@ -103,7 +115,7 @@ func (t *BTree) insertNonFull(n *btreeNode, key string, rowIndex int) {
 	if n.leaf {
 		// In a leaf: find insertion point
 		i := 0
-		for i < len(n.keys) && key > n.keys[i] {
+		for i < len(n.keys) && compareKeys(key, n.keys[i]) > 0 {
 			i++
 		}
 		// If key exists, append rowIndex to its value list
@@ -133,16 +145,17 @@ func (t *BTree) insertNonFull(n *btreeNode, key string, rowIndex int) {
 		return
 	}
 
-	// Internal node: find child to descend into
+	// Internal node: find child to descend into. A key equal to a separator
+	// lives in the right child.
 	i := 0
-	for i < len(n.keys) && key > n.keys[i] {
+	for i < len(n.keys) && compareKeys(key, n.keys[i]) >= 0 {
 		i++
 	}
 	// If target child is full, split it first, then decide which child to go to
 	if len(n.children[i].keys) == btreeOrder-1 {
 		t.splitChild(n, i)
 		// After split, decide which of the two children to descend into
-		if key > n.keys[i] {
+		if compareKeys(key, n.keys[i]) >= 0 {
 			i++
 		}
 	}
@@ -154,24 +167,24 @@ func (t *BTree) insertNonFull(n *btreeNode, key string, rowIndex int) {
 func (t *BTree) splitChild(n *btreeNode, i int) {
 	// c is the full child to split
 	c := n.children[i]
-	mid := (btreeOrder - 1) / 2 // with order=4, mid=1 (2 keys => promote index 1)
+	mid := (btreeOrder - 1) / 2 // with order=4, mid=1
+	separator := c.keys[mid]
 
 	// Create new node that will receive the upper half of c's keys
 	newNode := &btreeNode{leaf: c.leaf}
 
-	// Move keys to newNode: keys after mid
-	newNode.keys = append(newNode.keys, c.keys[mid+1:]...)
-	c.keys = c.keys[:mid]
-
-	// If leaf, move values parallel to keys
 	if c.leaf {
-		if len(c.values) > 0 {
-			newNode.values = append(newNode.values, c.values[mid+1:]...)
-			c.values = c.values[:mid]
-		}
+		// Leaves keep every key: the middle key and its values move to the
+		// right leaf and a copy of the key becomes the separator
+		newNode.keys = append(newNode.keys, c.keys[mid:]...)
+		newNode.values = append(newNode.values, c.values[mid:]...)
+		c.keys = c.keys[:mid]
+		c.values = c.values[:mid]
 	} else {
-		// If internal node, split children pointers accordingly
+		// Internal nodes move the middle key up into the parent
+		newNode.keys = append(newNode.keys, c.keys[mid+1:]...)
 		newNode.children = append(newNode.children, c.children[mid+1:]...)
+		c.keys = c.keys[:mid]
 		c.children = c.children[:mid+1]
 	}
 
@@ -180,10 +193,121 @@ func (t *BTree) splitChild(n *btreeNode, i int) {
 	copy(n.children[i+2:], n.children[i+1:])
 	n.children[i+1] = newNode
 
-	// Promote middle key into parent n at position i
+	// Promote the separator into parent n at position i
 	n.keys = append(n.keys, "")
 	copy(n.keys[i+1:], n.keys[i:])
-	n.keys[i] = c.keys[mid]
+	n.keys[i] = separator
+}
+
+// Ascend calls fn for each key in ascending order with its row indexes,
+// stopping early when fn returns false.
+func (t *BTree) Ascend(fn func(key string, rows []int) bool) {
+	t.root.walk(false, fn)
+}
+
+// Descend is like Ascend but visits keys in descending order.
+func (t *BTree) Descend(fn func(key string, rows []int) bool) {
+	t.root.walk(true, fn)
+}
+
+// AscendFrom is like Ascend but starts at the first key not before start.
+// Numbers equal to start, however spelled, are included.
+func (t *BTree) AscendFrom(start string, fn func(key string, rows []int) bool) {
+	t.root.walkFrom(false, &start, fn)
+}
+
+// DescendFrom is like Descend but starts at the last key not after start
+func (t *BTree) DescendFrom(start string, fn func(key string, rows []int) bool) {
+	t.root.walkFrom(true, &start, fn)
+}
+
+// walk visits the leaves below n in key order, reporting whether to continue
+func (n *btreeNode) walk(reverse bool, fn func(key string, rows []int) bool) bool {
+	return n.walkFrom(reverse, nil, fn)
+}
+
+// walkFrom is walk skipping keys before start, or after it when reverse.
+// Child j holds keys from separator j-1 up to, but not including,
+// separator j, so whole children outside the range are skipped.
+func (n *btreeNode) walkFrom(reverse bool, start *string, fn func(key string, rows []int) bool) bool {
+	if !n.leaf {
+		for j := range n.children {
+			if reverse {
+				j = len(n.children) - 1 - j
+			}
+			if start != nil {
+				if !reverse && j < len(n.keys) && compareBound(n.keys[j], *start) < 0 {
+					continue
+				}
+				if reverse && j > 0 && compareBound(n.keys[j-1], *start) > 0 {
+					continue
+				}
+			}
+			if !n.children[j].walkFrom(reverse, start, fn) {
+				return false
+			}
+		}
+		return true
+	}
+	for j := range n.keys {
+		if reverse {
+			j = len(n.keys) - 1 - j
+		}
+		if start != nil {
+			cmp := compareBound(n.keys[j], *start)
+			if (!reverse && cmp < 0) || (reverse && cmp > 0) {
+				continue
+			}
+		}
+		var rows []int
+		for _, group := range n.values[j] {
+			rows = append(rows, group...)
+		}
+		if !fn(n.keys[j], rows) {
+			return false
+		}
+	}
+	return true
+}
 
-	// For leaves, values for the promoted key stay in the left child (c)
+// compareKeys orders values the way ORDER BY does: numbers sort before text
+// and compare numerically, text compares byte by byte. Keys that are equal as
+// numbers but spelled differently ("1" and "1.0") are still distinct.
+func compareKeys(a, b string) int {
+	an, aNum := parseOrderNumber(a)
+	bn, bNum := parseOrderNumber(b)
+	switch {
+	case aNum && bNum:
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareBound is compareKeys treating numbers that are equal but spelled
+// differently as equal, so a walk from "5" includes "05" and "5.0"
+func compareBound(key, bound string) int {
+	kn, kNum := parseOrderNumber(key)
+	bn, bNum := parseOrderNumber(bound)
+	if kNum && bNum && kn == bn {
+		return 0
+	}
+	return compareKeys(key, bound)
+}
+
+// parseOrderNumber parses a value that should sort as a number
+func parseOrderNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
 }
@@ -0,0 +1,57 @@
+// internal/storage/bulk.go
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchingRowIDs returns the IDs of the rows the current statement sees that
+// match whereExpr, in table order. An UPDATE or DELETE with a WHERE clause
+// collects them first and then changes the rows in batches.
+func (db *Database) MatchingRowIDs(tableName string, whereExpr interface{}) ([]int64, string) {
+	q := Query{Where: whereExpr, Limit: -1}
+	p, msg := db.prepareQuery(tableName, q)
+	if msg != "" {
+		return nil, msg
+	}
+	rs, matched, msg := db.runQuery(p, q)
+	if msg != "" {
+		return nil, msg
+	}
+	ids := make([]int64, len(matched))
+	for i, ri := range matched {
+		ids[i] = rs.ids[ri]
+	}
+	return ids, ""
+}
+
+// ReadRowsByID returns copies of the rows with the given IDs among the rows
+// the current statement sees. Rows that are gone are nil.
+func (db *Database) ReadRowsByID(tableName string, ids []int64) (columns []string, rows [][]string, msg string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if msg := table.externalError(); msg != "" {
+		return nil, nil, msg
+	}
+	visible, visibleIDs := db.visibleRowSet(table)
+	positions := make(map[int64]int, len(ids))
+	for _, id := range ids {
+		positions[id] = -1
+	}
+	for ri, id := range visibleIDs {
+		if _, ok := positions[id]; ok {
+			positions[id] = ri
+		}
+	}
+	rows = make([][]string, len(ids))
+	for i, id := range ids {
+		if ri := positions[id]; ri >= 0 {
+			rows[i] = append([]string(nil), visible[ri]...)
+		}
+	}
+	return table.Columns, rows, ""
+}
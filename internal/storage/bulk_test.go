@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchingRowIDs(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("items", []string{"id", "kind"})
+	for _, row := range [][]string{{"1", "a"}, {"2", "b"}, {"3", "a"}} {
+		db.Insert("items", row)
+	}
+
+	ids, msg := db.MatchingRowIDs("items", &equalWhere{col: "kind", value: "a"})
+	if msg != "" || len(ids) != 2 {
+		t.Fatalf("MatchingRowIDs: %v %q", ids, msg)
+	}
+	if _, msg := db.MatchingRowIDs("missing", &equalWhere{col: "kind", value: "a"}); msg != "Table missing not found" {
+		t.Errorf("missing table: %q", msg)
+	}
+
+	db.DeleteByIDTx("items", ids[0])
+	columns, rows, msg := db.ReadRowsByID("items", ids)
+	if msg != "" {
+		t.Fatal(msg)
+	}
+	if !reflect.DeepEqual(columns, []string{"id", "kind"}) {
+		t.Errorf("columns %v", columns)
+	}
+	if !reflect.DeepEqual(rows, [][]string{nil, {"3", "a"}}) {
+		t.Errorf("rows %v", rows)
+	}
+	rows[1][1] = "changed"
+	if _, again, _ := db.ReadRowsByID("items", ids[1:]); again[0][1] != "a" {
+		t.Error("ReadRowsByID returned the stored row, not a copy")
+	}
+}
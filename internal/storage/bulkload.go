@@ -0,0 +1,73 @@
+// internal/storage/bulkload.go
+package storage
+
+// Bulk load mode defers index maintenance: while it is on, writes only mark
+// a table's indexes stale, and reads of that table scan its rows instead.
+// Turning it off rebuilds each stale table's indexes once, which is far
+// cheaper than updating them row by row during a large import.
+
+// SetBulkLoad turns bulk load mode on or off. Turning it off rebuilds the
+// indexes of every table written to while it was on.
+func (db *Database) SetBulkLoad(on bool) {
+	db.bulkLoad.Store(on)
+	if on {
+		return
+	}
+
+	db.catalog.RLock()
+	tables := make([]*Table, 0, len(db.Tables))
+	for _, table := range db.Tables {
+		tables = append(tables, table)
+	}
+	db.catalog.RUnlock()
+
+	for _, table := range tables {
+		if !table.staleIndexes.Load() {
+			continue
+		}
+		table.lock.Lock()
+		if table.staleIndexes.Load() {
+			db.rebuildAllIndexes(table)
+			table.staleIndexes.Store(false)
+		}
+		table.lock.Unlock()
+	}
+}
+
+// BulkLoad reports whether bulk load mode is on
+func (db *Database) BulkLoad() bool {
+	return db.bulkLoad.Load()
+}
+
+// indexInsert adds the row at rowIndex to table's indexes, or marks them
+// stale in bulk load mode. The caller holds table.lock.
+func (db *Database) indexInsert(table *Table, rowIndex int) {
+	if len(table.IndexedColumns) == 0 {
+		return
+	}
+	if db.bulkLoad.Load() || table.staleIndexes.Load() {
+		table.staleIndexes.Store(true)
+		return
+	}
+	db.applyIndexesOnInsert(table, rowIndex)
+}
+
+// reindex rebuilds table's indexes after rows were changed or removed, or
+// marks them stale in bulk load mode. The caller holds table.lock.
+func (db *Database) reindex(table *Table) {
+	if len(table.IndexedColumns) == 0 {
+		return
+	}
+	if db.bulkLoad.Load() {
+		table.staleIndexes.Store(true)
+		return
+	}
+	db.rebuildAllIndexes(table)
+	table.staleIndexes.Store(false)
+}
+
+// indexesUsable reports whether table's indexes describe its rows. The
+// caller holds table.lock.
+func (table *Table) indexesUsable() bool {
+	return !table.staleIndexes.Load()
+}
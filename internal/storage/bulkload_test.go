@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBulkLoadDefersIndexes(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("items", []string{"id", "name"})
+	_ = db.CreateIndex("items", "id")
+
+	db.SetBulkLoad(true)
+	for i := 0; i < 50; i++ {
+		_ = db.Insert("items", []string{fmt.Sprint(i), fmt.Sprintf("item%d", i)})
+	}
+	table, _ := db.lookupTable("items")
+	if !table.staleIndexes.Load() {
+		t.Fatal("bulk load should leave the indexes stale")
+	}
+	if n := len(table.BTreeIndexes["id"].GetEqual("7")); n != 0 {
+		t.Errorf("index was updated during bulk load: %d entries", n)
+	}
+
+	// Reads scan instead of using the stale index
+	lookups := table.stats.indexLookups.Load()
+	if got := db.SelectWhere("items", "id", "7"); got != "id | name\n7 | item7\n" {
+		t.Errorf("select during bulk load: %q", got)
+	}
+	if table.stats.indexLookups.Load() != lookups {
+		t.Error("select used the stale index")
+	}
+	_ = db.Delete("items", 0)
+
+	db.SetBulkLoad(false)
+	if table.staleIndexes.Load() {
+		t.Fatal("turning bulk load off should rebuild the indexes")
+	}
+	if got := db.SelectWhere("items", "id", "7"); got != "id | name\n7 | item7\n" {
+		t.Errorf("select after bulk load: %q", got)
+	}
+	if got := db.SelectWhere("items", "id", "0"); got != "id | name\n(no rows)\n" {
+		t.Errorf("deleted row still indexed: %q", got)
+	}
+	if table.stats.indexLookups.Load() == lookups {
+		t.Error("select did not use the rebuilt index")
+	}
+}
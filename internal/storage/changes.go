@@ -0,0 +1,588 @@
+// internal/storage/changes.go
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ChangeLogName is the file committed changes are appended to when change
+// capture is enabled
+const ChangeLogName = "changes.log"
+
+// Change operations recorded in the change log
+const (
+	ChangeCreateTable = "CREATE_TABLE"
+	ChangeInsert      = "INSERT"
+	ChangeUpdate      = "UPDATE"
+	ChangeDelete      = "DELETE"
+	ChangeDropTable   = "DROP_TABLE"
+	ChangeRenameTable = "RENAME_TABLE"
+	ChangeComment     = "COMMENT"
+)
+
+// ChangeEvent describes one committed change. Seq increases by one for every
+// event and is never reused, so consumers can deduplicate redelivered events.
+type ChangeEvent struct {
+	Seq       uint64    `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Op        string    `json:"op"`
+	Table     string    `json:"table"`
+	Columns   []string  `json:"columns,omitempty"`
+	RowIndex  *int      `json:"row_index,omitempty"`
+	// RowID is the changed row's ID (see rowid.go); 0 for table changes
+	RowID     int64        `json:"row_id,omitempty"`
+	Values    ChangeValues `json:"values,omitempty"`
+	OldValues ChangeValues `json:"old_values,omitempty"`
+	// Collations lists non-BINARY column collations of a created table
+	Collations map[string]string `json:"collations,omitempty"`
+	// Types lists the column types of a created table's typed columns
+	Types map[string]string `json:"types,omitempty"`
+	// NotNull lists a created table's NOT NULL columns, and Defaults the
+	// defaults of its columns that have one
+	NotNull  []string          `json:"not_null,omitempty"`
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// ForeignKeys lists a created table's FOREIGN KEY constraints
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+	// NewName is the new name of a renamed table
+	NewName string `json:"new_name,omitempty"`
+	// Column and Comment describe a comment set on a table or column; an
+	// empty Comment removes it
+	Column  string `json:"column,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// WALLSN is the LSN of the WAL entry the change was written in, used to
+	// log changes a crash kept out of the change log
+	WALLSN uint64 `json:"wal_lsn,omitempty"`
+}
+
+// ColumnSpecs returns the column definitions of a created table, including
+// types, NOT NULL, defaults, collations and foreign keys, as accepted by
+// CreateTable
+func (ev ChangeEvent) ColumnSpecs() []string {
+	specs := make([]string, len(ev.Columns), len(ev.Columns)+len(ev.ForeignKeys))
+	for i, col := range ev.Columns {
+		specs[i] = QuoteIdentifier(col)
+		typeName, typed := ev.Types[col]
+		if typed {
+			specs[i] += " " + typeName
+		}
+		if slices.Contains(ev.NotNull, col) {
+			specs[i] += " NOT NULL"
+		}
+		if def, ok := ev.Defaults[col]; ok {
+			specs[i] += " DEFAULT " + defaultLiteral(ColumnType(typeName), def)
+		}
+		if coll, ok := ev.Collations[col]; ok {
+			specs[i] += " COLLATE " + coll
+		}
+	}
+	for _, fk := range ev.ForeignKeys {
+		specs = append(specs, fk.String())
+	}
+	return specs
+}
+
+// createTableEvent describes the creation of table t
+func createTableEvent(t *Table) ChangeEvent {
+	ev := ChangeEvent{Op: ChangeCreateTable, Table: t.Name, Columns: t.Columns}
+	ev.describeColumns(t)
+	return ev
+}
+
+// describeColumns sets the column attributes of a created table
+func (ev *ChangeEvent) describeColumns(t *Table) {
+	ev.Collations = collationNames(t.Collations)
+	ev.Types = typeNames(t.Types)
+	ev.NotNull = t.notNullColumns()
+	ev.Defaults = t.Defaults
+	ev.ForeignKeys = t.ForeignKeys
+}
+
+// ChangeValues are the values of a changed row. NULL is written as JSON
+// null and read back as NULL.
+type ChangeValues []string
+
+// MarshalJSON writes the values as a JSON array with null for NULL
+func (v ChangeValues) MarshalJSON() ([]byte, error) {
+	out := make([]*string, len(v))
+	for i := range v {
+		if !IsNull(v[i]) {
+			out[i] = &v[i]
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads values written by MarshalJSON
+func (v *ChangeValues) UnmarshalJSON(data []byte) error {
+	var in []*string
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in == nil {
+		*v = nil
+		return nil
+	}
+	*v = make(ChangeValues, len(in))
+	for i, s := range in {
+		(*v)[i] = NullValue
+		if s != nil {
+			(*v)[i] = *s
+		}
+	}
+	return nil
+}
+
+// ErrChangesTruncated is returned when the changes asked for were removed
+// from the change log after every consumer acknowledged them
+var ErrChangesTruncated = errors.New("changes were truncated from the change log")
+
+// DefaultCompactAfter is how many acknowledged changes the change log keeps
+// before it is truncated
+const DefaultCompactAfter = 1000
+
+// ChangeLog is an append-only JSON-lines log of committed changes used for
+// change data capture. Consumers acknowledge the changes they have
+// processed, and once every consumer has acknowledged CompactAfter changes
+// they are truncated from the start of the log. The newest change is always
+// kept, so sequence numbers continue after a restart.
+type ChangeLog struct {
+	path string
+	file *os.File
+	// firstSeq is the sequence number of the first change in the file and
+	// offsets[i] the file offset of change firstSeq+i, so reads seek to
+	// the changes they want; size is the length of the file
+	firstSeq uint64
+	lastSeq  uint64
+	offsets  []int64
+	size     int64
+	// walLSN is the LSN of the newest WAL entry changes were logged from,
+	// and walEvents how many changes were logged from it
+	walLSN    uint64
+	walEvents int
+	// acked maps each consumer to the last change it acknowledged
+	acked map[string]uint64
+	// err is the error of a failed append. Later changes are refused, so
+	// none is logged after a missing one; reopening the log after a
+	// restart logs them all from the WAL.
+	err error
+	// CompactAfter is how many changes every consumer has acknowledged
+	// before they are truncated; 0 or less never truncates
+	CompactAfter int
+	mu           sync.Mutex
+}
+
+// OpenChangeLog opens or creates the change log in dataDir. A last line cut
+// short by a crash is removed.
+func OpenChangeLog(dataDir string) (*ChangeLog, error) {
+	cl := &ChangeLog{path: filepath.Join(dataDir, ChangeLogName), acked: make(map[string]uint64), CompactAfter: DefaultCompactAfter}
+	if err := cl.index(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(cl.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change log: %w", err)
+	}
+	cl.file = file
+	return cl, nil
+}
+
+// index reads the change log once, recording where each change starts
+func (cl *ChangeLog) index() error {
+	file, err := os.Open(cl.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			cl.firstSeq = 1
+			return nil
+		}
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if len(line) > 0 {
+				log.Printf("Warning: discarding incomplete change log entry at offset %d\n", offset)
+				if err := os.Truncate(cl.path, offset); err != nil {
+					return fmt.Errorf("failed to truncate change log: %w", err)
+				}
+			}
+			break
+		}
+		var ev ChangeEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return fmt.Errorf("corrupt change log entry: %w", err)
+		}
+		if len(cl.offsets) == 0 {
+			cl.firstSeq = ev.Seq
+		}
+		cl.offsets = append(cl.offsets, offset)
+		cl.lastSeq = ev.Seq
+		cl.noteWAL(ev.WALLSN)
+		offset += int64(len(line))
+	}
+	if len(cl.offsets) == 0 {
+		cl.firstSeq = cl.lastSeq + 1
+	}
+	cl.size = offset
+	return nil
+}
+
+// noteWAL records that a change was logged from the WAL entry at lsn
+func (cl *ChangeLog) noteWAL(lsn uint64) {
+	switch {
+	case lsn == 0:
+	case lsn == cl.walLSN:
+		cl.walEvents++
+	case lsn > cl.walLSN:
+		cl.walLSN, cl.walEvents = lsn, 1
+	}
+}
+
+// Append assigns the next sequence number to ev and durably records it
+func (cl *ChangeLog) Append(ev ChangeEvent) (uint64, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.err != nil {
+		return 0, cl.err
+	}
+	ev.Seq = cl.lastSeq + 1
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal change event: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := cl.file.Write(data); err != nil {
+		cl.err = fmt.Errorf("failed to write change event: %w", err)
+		return 0, cl.err
+	}
+	if err := cl.file.Sync(); err != nil {
+		cl.err = fmt.Errorf("failed to sync change log: %w", err)
+		return 0, cl.err
+	}
+
+	cl.offsets = append(cl.offsets, cl.size)
+	cl.size += int64(len(data))
+	cl.lastSeq = ev.Seq
+	cl.noteWAL(ev.WALLSN)
+	return ev.Seq, nil
+}
+
+// LastSeq returns the sequence number of the newest event
+func (cl *ChangeLog) LastSeq() uint64 {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.lastSeq
+}
+
+// FirstSeq returns the sequence number of the oldest event still in the
+// log; older ones were truncated
+func (cl *ChangeLog) FirstSeq() uint64 {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.firstSeq
+}
+
+// ReadAfter returns up to limit events with a sequence number greater than
+// after. A limit of 0 returns all of them. It returns ErrChangesTruncated
+// when some of those events are no longer in the log.
+func (cl *ChangeLog) ReadAfter(after uint64, limit int) ([]ChangeEvent, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if after+1 < cl.firstSeq {
+		return nil, fmt.Errorf("%w: the oldest is %d, asked for those after %d", ErrChangesTruncated, cl.firstSeq, after)
+	}
+	if after >= cl.lastSeq {
+		return nil, nil
+	}
+	file, err := os.Open(cl.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(cl.offsets[after+1-cl.firstSeq], io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read change log: %w", err)
+	}
+
+	var events []ChangeEvent
+	reader := bufio.NewReader(io.LimitReader(file, cl.size))
+	for limit == 0 || len(events) < limit {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var ev ChangeEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, fmt.Errorf("corrupt change log entry: %w", err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// Acknowledge records that consumer has processed every change up to seq;
+// the first call registers the consumer, holding the changes after seq in
+// the log for it. Once every consumer has acknowledged CompactAfter changes
+// they are truncated.
+func (cl *ChangeLog) Acknowledge(consumer string, seq uint64) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.acked[consumer] = seq
+	lowest := seq
+	for _, acked := range cl.acked {
+		lowest = min(lowest, acked)
+	}
+	if cl.CompactAfter <= 0 || lowest+1 < cl.firstSeq+uint64(cl.CompactAfter) {
+		return nil
+	}
+	return cl.truncate(lowest)
+}
+
+// Forget unregisters a consumer, so the log is no longer kept for it
+func (cl *ChangeLog) Forget(consumer string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	delete(cl.acked, consumer)
+}
+
+// truncate removes the changes up to seq, keeping at least the newest one.
+// The rest of the log is copied to a new file that replaces it. The caller
+// holds cl.mu.
+func (cl *ChangeLog) truncate(seq uint64) error {
+	keep := min(seq+1, cl.lastSeq)
+	if keep <= cl.firstSeq {
+		return nil
+	}
+	cut := cl.offsets[keep-cl.firstSeq]
+
+	src, err := os.Open(cl.path)
+	if err != nil {
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer src.Close()
+	tempPath := cl.path + ".tmp"
+	dst, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create truncated change log: %w", err)
+	}
+	_, err = io.Copy(dst, io.NewSectionReader(src, cut, cl.size-cut))
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, cl.path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to truncate change log: %w", err)
+	}
+
+	file, err := os.OpenFile(cl.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen change log: %w", err)
+	}
+	cl.file.Close()
+	cl.file = file
+	offsets := make([]int64, 0, len(cl.offsets)-int(keep-cl.firstSeq))
+	for _, offset := range cl.offsets[keep-cl.firstSeq:] {
+		offsets = append(offsets, offset-cut)
+	}
+	cl.offsets, cl.size, cl.firstSeq = offsets, cl.size-cut, keep
+	return nil
+}
+
+// walPosition returns the LSN of the newest WAL entry changes were logged
+// from and how many changes were logged from it
+func (cl *ChangeLog) walPosition() (uint64, int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.walLSN, cl.walEvents
+}
+
+// Close closes the change log file
+func (cl *ChangeLog) Close() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.file.Close()
+}
+
+// EnableChangeLog starts recording committed changes to changes.log
+func (db *Database) EnableChangeLog() error {
+	if db.Changes != nil {
+		return nil
+	}
+	cl, err := OpenChangeLog(db.DataDir)
+	if err != nil {
+		return err
+	}
+	db.Changes = cl
+	return nil
+}
+
+// appendChange appends a change to the change log, if enabled. Writes call
+// it right after their WAL entry, so a change missing from the log after a
+// crash is logged from the WAL on restart (see logReplayedChanges).
+func (db *Database) appendChange(ev ChangeEvent) {
+	if db.Changes == nil {
+		return
+	}
+	if _, err := db.Changes.Append(ev); err != nil {
+		log.Printf("Warning: failed to record change for %s: %v\n", ev.Table, err)
+	}
+}
+
+// recordOperation records a committed transaction operation, written to the
+// WAL in the commit record at lsn, in the change log and returns its events
+// for the commit hooks
+func (db *Database) recordOperation(op TransactionOperation, lsn uint64) []ChangeEvent {
+	if db.Changes == nil && !db.hasCommitHooks() {
+		return nil
+	}
+	events := db.operationEvents(op)
+	for i := range events {
+		events[i].WALLSN = lsn
+		db.appendChange(events[i])
+	}
+	return events
+}
+
+// operationEvents returns the changes an operation makes, described with
+// the current columns of its table
+func (db *Database) operationEvents(op TransactionOperation) []ChangeEvent {
+	ev := ChangeEvent{Timestamp: op.Timestamp, Table: op.TableName}
+	data, _ := op.Data.(map[string]interface{})
+
+	switch op.Type {
+	case WAL_CREATE_TABLE:
+		ev.Op = ChangeCreateTable
+	case WAL_INSERT:
+		ev.Op = ChangeInsert
+		ev.Values = interfaceStrings(data["values"])
+	case WAL_UPDATE:
+		ev.Op = ChangeUpdate
+		ev.Values = interfaceStrings(data["values"])
+	case WAL_DELETE:
+		ev.Op = ChangeDelete
+	case WAL_DROP_TABLE:
+		ev.Op = ChangeDropTable
+	case WAL_RENAME_TABLE:
+		ev.Op = ChangeRenameTable
+		ev.NewName, _ = data["new_name"].(string)
+	case WAL_COMMENT:
+		ev.Op = ChangeComment
+		ev.Column, _ = data["column"].(string)
+		ev.Comment, _ = data["comment"].(string)
+	case WAL_PURGE:
+		ev.Op = ChangeDelete
+	default:
+		return nil
+	}
+	if ri, ok := data["row_index"].(float64); ok {
+		idx := int(ri)
+		ev.RowIndex = &idx
+	}
+	if id, ok := data["row_id"].(float64); ok {
+		ev.RowID = int64(id)
+	}
+	if table, ok := db.lookupTable(op.TableName); ok && op.Type != WAL_COMMENT {
+		ev.Columns = table.Columns
+		if op.Type == WAL_CREATE_TABLE {
+			ev.describeColumns(table)
+		}
+	}
+
+	if op.Type != WAL_PURGE {
+		return []ChangeEvent{ev}
+	}
+	ids, _ := data["row_ids"].([]interface{})
+	events := make([]ChangeEvent, 0, len(ids))
+	for _, id := range ids {
+		if f, ok := id.(float64); ok {
+			ev.RowID = int64(f)
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// walEntryEvents returns the changes a WAL entry makes
+func (db *Database) walEntryEvents(entry *WALEntry) []ChangeEvent {
+	if entry.Type != WAL_COMMIT_TRANSACTION && entry.Type != WAL_BATCH {
+		return db.operationEvents(TransactionOperation{Type: entry.Type, TableName: entry.TableName, Data: entry.Data, Timestamp: entry.Timestamp})
+	}
+	data, _ := entry.Data.(map[string]interface{})
+	ops, _ := data["operations"].([]interface{})
+	var events []ChangeEvent
+	for _, raw := range ops {
+		op, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		opType, _ := op["type"].(float64)
+		tableName, _ := op["table_name"].(string)
+		events = append(events, db.operationEvents(TransactionOperation{Type: WALEntryType(opType), TableName: tableName, Data: op["data"], Timestamp: entry.Timestamp})...)
+	}
+	return events
+}
+
+// logReplayedChanges appends the changes of a replayed WAL entry that the
+// change log does not have yet. A crash between a write's WAL entry and its
+// change log entry leaves the change out of the log; replay puts it back,
+// so every change is delivered at least once.
+func (db *Database) logReplayedChanges(entry *WALEntry) {
+	if db.Changes == nil {
+		return
+	}
+	walLSN, logged := db.Changes.walPosition()
+	if walLSN == 0 || entry.LSN < walLSN {
+		return
+	}
+	events := db.walEntryEvents(entry)
+	if entry.LSN == walLSN {
+		events = events[min(logged, len(events)):]
+	}
+	for _, ev := range events {
+		ev.WALLSN = entry.LSN
+		db.appendChange(ev)
+	}
+}
+
+// interfaceStrings converts a []string or []interface{} holding strings
+func interfaceStrings(v interface{}) []string {
+	switch vals := v.(type) {
+	case []string:
+		return vals
+	case []interface{}:
+		out := make([]string, 0, len(vals))
+		for _, val := range vals {
+			if s, ok := val.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChangeLogWritesNullAsJSONNull(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	defer db.Close()
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.Insert("users", []string{"1", NullValue})
+
+	data, err := os.ReadFile(filepath.Join(dir, ChangeLogName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"values":["1",null]`) || strings.Contains(string(data), `\u0000`) {
+		t.Errorf("NULL not written as JSON null:\n%s", data)
+	}
+
+	events, err := db.Changes.ReadAfter(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || !IsNull(events[0].Values[1]) || events[0].Values[0] != "1" {
+		t.Errorf("expected the insert with a NULL name, got %+v", events)
+	}
+}
+
+func TestChangeLogTruncatesAcknowledgedChanges(t *testing.T) {
+	dir := t.TempDir()
+	cl, err := OpenChangeLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.CompactAfter = 2
+	for i := 0; i < 5; i++ {
+		if _, err := cl.Append(ChangeEvent{Op: ChangeInsert, Table: "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The slowest consumer holds the log
+	_ = cl.Acknowledge("slow", 1)
+	_ = cl.Acknowledge("fast", 4)
+	if first := cl.FirstSeq(); first != 1 {
+		t.Fatalf("log truncated past the slow consumer to %d", first)
+	}
+	if err := cl.Acknowledge("slow", 3); err != nil {
+		t.Fatal(err)
+	}
+	if first := cl.FirstSeq(); first != 4 {
+		t.Fatalf("expected changes up to 3 truncated, first is %d", first)
+	}
+
+	events, err := cl.ReadAfter(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Seq != 4 || events[1].Seq != 5 {
+		t.Errorf("expected changes 4 and 5, got %+v", events)
+	}
+	if _, err := cl.ReadAfter(2, 0); !errors.Is(err, ErrChangesTruncated) {
+		t.Errorf("expected ErrChangesTruncated reading truncated changes, got %v", err)
+	}
+
+	// Sequence numbers continue after reopening
+	cl.Close()
+	if cl, err = OpenChangeLog(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	if cl.FirstSeq() != 4 || cl.LastSeq() != 5 {
+		t.Errorf("reopened log has changes %d to %d, want 4 to 5", cl.FirstSeq(), cl.LastSeq())
+	}
+	if seq, _ := cl.Append(ChangeEvent{Op: ChangeDelete, Table: "t"}); seq != 6 {
+		t.Errorf("expected the next change to be 6, got %d", seq)
+	}
+	if events, _ := cl.ReadAfter(5, 0); len(events) != 1 || events[0].Op != ChangeDelete {
+		t.Errorf("expected the appended delete, got %+v", events)
+	}
+}
+
+func TestChangeLogRecoversChangesLostInCrash(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.CreateTable("accounts", []string{"id", "balance"})
+	_ = db.Insert("accounts", []string{"1", "100"})
+
+	// Crash after the commit record is written but before the change log
+	// has the transaction's changes
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.UpdateTx("accounts", 0, []string{"1", "70"})
+	_ = db.InsertTx("accounts", []string{"2", "30"})
+	tx := db.GetCurrentTransaction()
+	tx.mu.Lock()
+	_, err := db.TransactionManager.logCommit(tx)
+	tx.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	if db.Changes == nil {
+		t.Fatal("change log not reopened at startup")
+	}
+	events, err := db.Changes.ReadAfter(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{ChangeCreateTable, ChangeInsert, ChangeUpdate, ChangeInsert}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), events)
+	}
+	for i, op := range want {
+		if events[i].Op != op {
+			t.Errorf("change %d is %s, want %s", i+1, events[i].Op, op)
+		}
+	}
+	if got := events[3].Values; len(got) != 2 || got[0] != "2" {
+		t.Errorf("recovered insert has values %v", got)
+	}
+}
+
+func TestColumnSpecsQuoteNames(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatal(err)
+	}
+	if msg := db.CreateTable("t", []string{`"my col" INT NOT NULL`, `"say ""hi""" TEXT DEFAULT 'x'`, "id"}); strings.Contains(msg, "rror") {
+		t.Fatal(msg)
+	}
+	events, err := db.Changes.ReadAfter(0, 0)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected the create, got %+v, %v", events, err)
+	}
+
+	// A replica creates the table from the specs
+	replica := NewDatabase(t.TempDir())
+	defer replica.Close()
+	if msg := replica.CreateTable("t", events[0].ColumnSpecs()); strings.Contains(msg, "rror") {
+		t.Fatalf("specs %q: %s", events[0].ColumnSpecs(), msg)
+	}
+	got := replica.Tables["t"]
+	if want := []string{"my col", `say "hi"`, "id"}; !slices.Equal(got.Columns, want) {
+		t.Errorf("columns %q, want %q", got.Columns, want)
+	}
+	if got.Types["my col"] != TypeInt || got.Types[`say "hi"`] != TypeText {
+		t.Errorf("types %v", got.Types)
+	}
+}
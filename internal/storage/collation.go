@@ -0,0 +1,305 @@
+// internal/storage/collation.go
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Built-in collation names. Any other name is read as a BCP 47 locale such
+// as de, sv-SE or fr_CA.
+const (
+	CollationBinary = "BINARY"
+	CollationNoCase = "NOCASE"
+)
+
+// Collation decides how a text column compares, matches LIKE patterns and
+// builds index keys. A nil *Collation behaves as BINARY.
+type Collation struct {
+	Name string
+
+	// collator is set for locale collations; it is not safe for concurrent use
+	collator *collate.Collator
+	mu       sync.Mutex
+}
+
+// ParseCollation resolves a collation name
+func ParseCollation(name string) (*Collation, error) {
+	name = strings.Trim(strings.TrimSpace(name), "'\"")
+	switch strings.ToUpper(name) {
+	case CollationBinary:
+		return &Collation{Name: CollationBinary}, nil
+	case CollationNoCase:
+		return &Collation{Name: CollationNoCase}, nil
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown collation %s (use BINARY, NOCASE or a locale such as de-DE)", name)
+	}
+	return &Collation{Name: tag.String(), collator: collate.New(tag)}, nil
+}
+
+// IsBinary reports whether values compare byte by byte
+func (c *Collation) IsBinary() bool {
+	return c == nil || c.Name == CollationBinary
+}
+
+// Compare orders two values, returning -1, 0 or 1
+func (c *Collation) Compare(a, b string) int {
+	switch {
+	case c.IsBinary():
+		return strings.Compare(a, b)
+	case c.collator == nil:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.collator.CompareString(a, b)
+}
+
+// Equal reports whether two values are the same under the collation
+func (c *Collation) Equal(a, b string) bool {
+	if c.IsBinary() {
+		return a == b
+	}
+	return c.Compare(a, b) == 0
+}
+
+// Key normalizes a value for use as an index key, so that equal values share
+// a key and keys sort in collation order
+func (c *Collation) Key(value string) string {
+	switch {
+	case c.IsBinary():
+		return value
+	case c.collator == nil:
+		return strings.ToLower(value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var buf collate.Buffer
+	return string(c.collator.KeyFromString(&buf, value))
+}
+
+// Like matches value against a SQL LIKE pattern where % matches any run of
+// characters and _ matches one. NOCASE matches case-insensitively; locale
+// collations match characters exactly, like BINARY.
+func (c *Collation) Like(value, pattern string) (bool, error) {
+	return c.LikeEscape(value, pattern, 0)
+}
+
+// LikeEscape is Like with an escape character: in pattern, escape followed
+// by any character, such as % or _, matches that character literally. An escape of 0
+// means none.
+func (c *Collation) LikeEscape(value, pattern string, escape rune) (bool, error) {
+	regexPattern, err := likeRegex(pattern, escape)
+	if err != nil {
+		return false, err
+	}
+	if c != nil && c.Name == CollationNoCase {
+		regexPattern = "(?is)" + regexPattern
+	} else {
+		regexPattern = "(?s)" + regexPattern
+	}
+	return regexp.MatchString(regexPattern, value)
+}
+
+// likeRegex translates a LIKE pattern into an anchored regular expression
+func likeRegex(pattern string, escape rune) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case escape != 0 && r == escape:
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		return "", fmt.Errorf("LIKE pattern ends with its escape character")
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// columnDefs are the parsed column definitions of a table
+type columnDefs struct {
+	names      []string
+	collations map[string]*Collation
+	types      map[string]ColumnType
+	notNull    map[string]bool
+	defaults   map[string]string
+	// foreignKeys are the FOREIGN KEY constraints among the definitions
+	foreignKeys []ForeignKey
+}
+
+// apply gives t the defined columns and their attributes
+func (d columnDefs) apply(t *Table) {
+	t.Columns = d.names
+	t.Collations = d.collations
+	t.Types = d.types
+	t.NotNull = d.notNull
+	t.Defaults = d.defaults
+	t.ForeignKeys = d.foreignKeys
+}
+
+// parseColumnSpecs parses column definitions such as "name TEXT NOT NULL
+// DEFAULT 'x' COLLATE NOCASE" into column names and their attributes, and
+// FOREIGN KEY constraints among them (see foreignkey.go)
+func parseColumnSpecs(specs []string) (columnDefs, error) {
+	d := columnDefs{names: make([]string, 0, len(specs))}
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			return columnDefs{}, fmt.Errorf("empty column definition")
+		}
+		if isForeignKeySpec(spec) {
+			fk, err := parseForeignKey(spec)
+			if err != nil {
+				return columnDefs{}, err
+			}
+			d.foreignKeys = append(d.foreignKeys, fk)
+			continue
+		}
+		invalid := fmt.Errorf("invalid column definition %q (expected: name [type] [NOT NULL] [DEFAULT value] [COLLATE collation])", spec)
+		// The name may be a quoted identifier containing spaces
+		var fields []string
+		if strings.HasPrefix(spec, `"`) {
+			name, rest, err := cutQuotedIdentifier(spec)
+			if err != nil {
+				return columnDefs{}, err
+			}
+			if rest != "" && !strings.HasPrefix(rest, " ") {
+				return columnDefs{}, invalid
+			}
+			fields = append([]string{name}, specFields(rest)...)
+		} else {
+			fields = specFields(spec)
+			if strings.Contains(fields[0], `"`) {
+				return columnDefs{}, fmt.Errorf("invalid column name %s", fields[0])
+			}
+			if IsReservedWord(fields[0]) {
+				return columnDefs{}, fmt.Errorf("column name %s is a reserved word; quote it as %s", fields[0], QuoteIdentifier(fields[0]))
+			}
+		}
+		name := fields[0]
+		d.names = append(d.names, name)
+		if seen[strings.ToLower(name)] {
+			return columnDefs{}, fmt.Errorf("duplicate column %s", name)
+		}
+		seen[strings.ToLower(name)] = true
+
+		rest := fields[1:]
+		var ct ColumnType
+		if len(rest) > 0 && !isColumnAttribute(rest[0]) {
+			var err error
+			if ct, err = ParseColumnType(rest[0]); err != nil {
+				return columnDefs{}, err
+			}
+			if d.types == nil {
+				d.types = make(map[string]ColumnType)
+			}
+			d.types[name] = ct
+			rest = rest[1:]
+		}
+		var def *string
+		for len(rest) > 0 {
+			if len(rest) < 2 {
+				return columnDefs{}, invalid
+			}
+			switch strings.ToUpper(rest[0]) {
+			case "COLLATE":
+				if ct != "" && ct != TypeText {
+					return columnDefs{}, fmt.Errorf("COLLATE applies only to TEXT columns, not %s %s", name, ct)
+				}
+				coll, err := ParseCollation(rest[1])
+				if err != nil {
+					return columnDefs{}, err
+				}
+				if d.collations == nil {
+					d.collations = make(map[string]*Collation)
+				}
+				d.collations[name] = coll
+			case "NOT":
+				if !strings.EqualFold(rest[1], "NULL") {
+					return columnDefs{}, invalid
+				}
+				if d.notNull == nil {
+					d.notNull = make(map[string]bool)
+				}
+				d.notNull[name] = true
+			case "DEFAULT":
+				v, err := parseDefault(rest[1])
+				if err != nil {
+					return columnDefs{}, fmt.Errorf("column %s: %w", name, err)
+				}
+				def = &v
+			default:
+				return columnDefs{}, invalid
+			}
+			rest = rest[2:]
+		}
+
+		if def == nil {
+			continue
+		}
+		v, ok := ct.Normalize(*def)
+		switch {
+		case !ok:
+			return columnDefs{}, fmt.Errorf("default %s does not match type %s of column %s", quoteString(*def), ct, name)
+		case IsNull(v) && d.notNull[name]:
+			return columnDefs{}, fmt.Errorf("column %s is NOT NULL but its default is NULL", name)
+		case !IsNull(v):
+			// DEFAULT NULL is what columns without a default get
+			if d.defaults == nil {
+				d.defaults = make(map[string]string)
+			}
+			d.defaults[name] = v
+		}
+	}
+	return d, nil
+}
+
+// isColumnAttribute reports whether word starts a column attribute rather
+// than naming a type
+func isColumnAttribute(word string) bool {
+	switch strings.ToUpper(word) {
+	case "COLLATE", "NOT", "DEFAULT":
+		return true
+	}
+	return false
+}
+
+// collationNames returns the persisted form of a table's collations
+func collationNames(collations map[string]*Collation) map[string]string {
+	if len(collations) == 0 {
+		return nil
+	}
+	names := make(map[string]string, len(collations))
+	for col, coll := range collations {
+		names[col] = coll.Name
+	}
+	return names
+}
+
+// Collation returns the collation of a column, nil meaning BINARY
+func (t *Table) Collation(column string) *Collation {
+	return t.Collations[column]
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollationCompare(t *testing.T) {
+	nocase, _ := ParseCollation("nocase")
+	if !nocase.Equal("Hareesh", "HAREESH") || nocase.Key("Hareesh") != nocase.Key("hAREESH") {
+		t.Error("NOCASE should ignore case")
+	}
+	var binary *Collation
+	if binary.Equal("a", "A") || binary.Compare("B", "a") >= 0 {
+		t.Error("BINARY should compare bytes")
+	}
+
+	// Swedish sorts ä after z, German sorts it with a
+	sv, err := ParseCollation("sv_SE")
+	if err != nil {
+		t.Fatalf("failed to parse locale: %v", err)
+	}
+	de, _ := ParseCollation("de")
+	if sv.Compare("ä", "z") <= 0 || de.Compare("ä", "z") >= 0 {
+		t.Errorf("unexpected locale ordering: sv=%d de=%d", sv.Compare("ä", "z"), de.Compare("ä", "z"))
+	}
+	if (sv.Key("ä") < sv.Key("z")) != (sv.Compare("ä", "z") < 0) {
+		t.Error("index keys should sort in collation order")
+	}
+
+	if match, _ := nocase.Like("Hareesh", "har%"); !match {
+		t.Error("NOCASE LIKE should ignore case")
+	}
+	for _, tc := range []struct {
+		value, pattern string
+		want           bool
+	}{
+		{"50%", `50\%`, true},
+		{"500", `50\%`, false},
+		{"a_b", `a\_b`, true},
+		{"axb", `a\_b`, false},
+		{`a\b`, `a\\b`, true},
+		{"100% sure", `%\%%`, true},
+	} {
+		if match, err := (*Collation)(nil).LikeEscape(tc.value, tc.pattern, '\\'); err != nil || match != tc.want {
+			t.Errorf("%q LIKE %q ESCAPE '\\': %v, %v", tc.value, tc.pattern, match, err)
+		}
+	}
+	if _, err := (*Collation)(nil).LikeEscape("x", `x\`, '\\'); err == nil {
+		t.Error("expected a pattern ending in its escape character to be rejected")
+	}
+	if _, err := ParseCollation("not a collation"); err == nil {
+		t.Error("expected invalid collation to be rejected")
+	}
+}
+
+func TestCollatedColumns(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+
+	if msg := db.CreateTable("users", []string{"id", "email COLLATE NOCASE"}); !strings.Contains(msg, "created") {
+		t.Fatalf("create table failed: %s", msg)
+	}
+	if msg := db.CreateTable("bad", []string{"id COLLATE"}); !strings.HasPrefix(msg, "Error") {
+		t.Errorf("expected invalid column definition to fail, got %s", msg)
+	}
+	if cols := db.Tables["users"].Columns; cols[1] != "email" {
+		t.Fatalf("collation should not be part of the column name: %v", cols)
+	}
+
+	db.Insert("users", []string{"1", "Hareesh@Example.com"})
+	db.CreateIndex("users", "email")
+	if out := db.SelectWhere("users", "email", "hareesh@example.com"); !strings.Contains(out, "Hareesh@Example.com") {
+		t.Fatalf("index lookup should ignore case, got:\n%s", out)
+	}
+
+	// Collations survive a restart
+	db = NewDatabase(dataDir)
+	if coll := db.Tables["users"].Collation("email"); coll == nil || coll.Name != CollationNoCase {
+		t.Fatalf("expected NOCASE after reload, got %v", coll)
+	}
+	if out := db.SelectWhere("users", "email", "HAREESH@EXAMPLE.COM"); !strings.Contains(out, "Hareesh@Example.com") {
+		t.Errorf("rebuilt index should ignore case, got:\n%s", out)
+	}
+}
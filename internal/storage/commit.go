@@ -0,0 +1,121 @@
+// internal/storage/commit.go
+//
+// Commit records. Committing a transaction first writes all of its
+// operations to the WAL as one WAL_COMMIT_TRANSACTION entry, then applies
+// them in memory and saves each changed table once. A crash before the
+// record is written loses the whole transaction; a crash after it replays
+// the whole transaction, so tables never recover with only some of its
+// writes, even when several tables were changed. A transaction writes
+// nothing else to the WAL but its savepoints, after a BEGIN record, so
+// recovery has nothing to undo for one still in flight at a crash.
+package storage
+
+import (
+	"fmt"
+	"log"
+)
+
+// commitOperation is one operation in a commit record
+type commitOperation struct {
+	Type      WALEntryType `json:"type"`
+	TableName string       `json:"table_name"`
+	Data      interface{}  `json:"data"`
+}
+
+// logCommit writes the operations of tx to the WAL as one commit record.
+// Writes to unlogged tables are left out. Inserts into existing tables
+// reserve their row IDs first, so that replaying the record does not insert
+// rows that were saved before a crash again. It returns the record's LSN,
+// or 0 when nothing was written. The caller holds tx.mu.
+func (tm *TransactionManager) logCommit(tx *Transaction) (uint64, error) {
+	if tm.db.WAL == nil {
+		return 0, nil
+	}
+	ops := make([]commitOperation, 0, len(tx.Operations))
+	for _, op := range tx.Operations {
+		table, exists := tm.db.lookupTable(op.TableName)
+		if exists && table.Unlogged &&
+			(op.Type == WAL_INSERT || op.Type == WAL_UPDATE || op.Type == WAL_DELETE) {
+			continue
+		}
+		if data, ok := op.Data.(map[string]interface{}); ok && exists && op.Type == WAL_INSERT {
+			table.lock.Lock()
+			data["row_id"] = float64(table.allocRowID())
+			table.lock.Unlock()
+		}
+		ops = append(ops, commitOperation{Type: op.Type, TableName: op.TableName, Data: op.Data})
+	}
+	// A transaction that logged its BEGIN (for a savepoint) is closed even
+	// when it wrote nothing, so recovery does not count it as in flight. A
+	// commit record needs no BEGIN before it.
+	if len(ops) == 0 && !tx.logged {
+		return 0, nil
+	}
+	data := map[string]interface{}{"transaction_id": tx.ID, "operations": ops}
+	lsn, err := tm.db.WAL.AppendEntry(WAL_COMMIT_TRANSACTION, "", data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write transaction commit to WAL: %w", err)
+	}
+	return lsn, nil
+}
+
+// persist saves table now, or adds it to deferred when that is not nil
+func (db *Database) persist(table *Table, deferred map[*Table]bool) error {
+	if deferred != nil {
+		deferred[table] = true
+		return nil
+	}
+	return db.saveTable(table)
+}
+
+// saveDeferred saves the tables a commit changed and writes a checkpoint.
+// Tables dropped later in the transaction are skipped. The commit record is
+// already in the WAL, so a failed save is only a warning, but it leaves the
+// WAL without a checkpoint so recovery replays the commit. A commit that
+// changed nothing writes nothing.
+func (tm *TransactionManager) saveDeferred(deferred map[*Table]bool) {
+	if len(deferred) == 0 {
+		return
+	}
+	saved := true
+	for table := range deferred {
+		if current, ok := tm.db.lookupTable(table.Name); !ok || current != table {
+			continue
+		}
+		table.lock.RLock()
+		err := tm.db.saveTable(table)
+		table.lock.RUnlock()
+		if err != nil {
+			log.Printf("Warning: failed to persist table %s after commit: %v\n", table.Name, err)
+			saved = false
+		}
+	}
+	if saved && tm.db.WAL != nil {
+		if err := tm.db.WAL.WriteCheckpoint(); err != nil {
+			log.Printf(ErrWALCheckpoint, err)
+		}
+	}
+}
+
+// replayCommit replays the operations of a commit record in order. WAL_BATCH
+// entries have the same layout.
+func (wm *WALManager) replayCommit(db *Database, entry *WALEntry) error {
+	data, ok := entry.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ops, _ := data["operations"].([]interface{})
+	for _, raw := range ops {
+		op, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		opType, _ := op["type"].(float64)
+		tableName, _ := op["table_name"].(string)
+		sub := &WALEntry{Timestamp: entry.Timestamp, Type: WALEntryType(opType), TableName: tableName, Data: op["data"]}
+		if err := wm.replayEntry(db, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
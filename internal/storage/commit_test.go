@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitWritesOneRecord(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("accounts", []string{"id", "balance"})
+	_ = db.CreateTable("ledger", []string{"account", "amount"})
+	_ = db.Insert("accounts", []string{"1", "100"})
+
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.UpdateTx("accounts", 0, []string{"1", "70"})
+	_ = db.InsertTx("ledger", []string{"1", "-30"})
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := db.RecentWALEntries(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []interface{}
+	for _, entry := range entries {
+		switch entry.Type {
+		case WAL_COMMIT_TRANSACTION:
+			data, _ := entry.Data.(map[string]interface{})
+			ops, _ = data["operations"].([]interface{})
+		case WAL_INSERT, WAL_UPDATE:
+			if entry.TableName == "ledger" {
+				t.Errorf("transaction write logged on its own: %+v", entry)
+			}
+		}
+	}
+	if len(ops) != 2 {
+		t.Errorf("expected both operations in the commit record, got %v", ops)
+	}
+}
+
+func TestCommitRecordRecoversAllTables(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("accounts", []string{"id", "balance"})
+	_ = db.CreateTable("ledger", []string{"account", "amount"})
+	_ = db.Insert("accounts", []string{"1", "100"})
+
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.UpdateTx("accounts", 0, []string{"1", "70"})
+	_ = db.InsertTx("ledger", []string{"1", "-30"})
+
+	// Crash after the commit record is written but before any table is
+	// changed or saved
+	tx := db.GetCurrentTransaction()
+	tx.mu.Lock()
+	_, err := db.TransactionManager.logCommit(tx)
+	tx.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.SelectAll("accounts"); got != "id | balance\n1 | 70\n" {
+		t.Errorf("accounts after recovery: %q", got)
+	}
+	if got := db.SelectAll("ledger"); got != "account | amount\n1 | -30\n" {
+		t.Errorf("ledger after recovery: %q", got)
+	}
+}
+
+func TestIdleTransactionWritesNoRecords(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("accounts", []string{"id", "balance"})
+	before := db.WAL.LastLSN()
+
+	for _, end := range []func() error{db.CommitTransaction, db.RollbackTransaction} {
+		if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+			t.Fatal(err)
+		}
+		if err := end(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := db.WAL.LastLSN(); after != before {
+		t.Errorf("idle transactions logged %d records", after-before)
+	}
+}
+
+func TestRecoveryDiscardsInFlightTransactions(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("accounts", []string{"id", "balance"})
+	_ = db.Insert("accounts", []string{"1", "100"})
+
+	// A committed transaction larger than the WAL reader's buffer
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	for i := 2; i <= 200; i++ {
+		_ = db.InsertTx("accounts", []string{fmt.Sprint(i), "0"})
+	}
+	tx := db.GetCurrentTransaction()
+	tx.mu.Lock()
+	_, err := db.TransactionManager.logCommit(tx)
+	tx.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.RollbackTransaction()
+
+	// One in flight at the crash, with a savepoint logged
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.UpdateTx("accounts", 0, []string{"1", "0"})
+	if err := db.CreateSavepoint("sp"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.InsertTx("accounts", []string{"999", "1"})
+
+	// and the crash cut the last entry short
+	f, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{200, 0, 0, 0, '{'})
+	f.Close()
+
+	progress := &RecoveryProgress{}
+	db = NewDatabaseWithProgress(dir, progress)
+	defer db.Close()
+	if n := progress.Discarded.Load(); n != 1 {
+		t.Errorf("expected 1 discarded transaction, got %d (%s)", n, progress)
+	}
+	if got := db.SelectWhere("accounts", "id", "1"); got != "id | balance\n1 | 100\n" {
+		t.Errorf("in-flight update recovered: %q", got)
+	}
+	if got := db.SelectWhere("accounts", "id", "999"); got != "id | balance\n(no rows)\n" {
+		t.Errorf("in-flight insert recovered: %q", got)
+	}
+	if got := db.SelectWhere("accounts", "id", "200"); got != "id | balance\n200 | 0\n" {
+		t.Errorf("committed transaction lost: %q", got)
+	}
+}
+
+func TestFailedSaveAfterCommitSkipsCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("accounts", []string{"id", "balance"})
+	_ = db.Insert("accounts", []string{"1", "100"})
+
+	// A directory in place of the table file makes the save fail
+	path := filepath.Join(dir, "accounts.harudb")
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocked"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.UpdateTx("accounts", 0, []string{"1", "70"})
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := db.RecentWALEntries(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := entries[len(entries)-1]; last.Type != WAL_COMMIT_TRANSACTION {
+		t.Fatalf("expected the commit record to be the last entry, got %s", last.Type)
+	}
+
+	// The stale table file is back; recovery must replay the commit
+	db.Close()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, saved, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.SelectAll("accounts"); got != "id | balance\n1 | 70\n" {
+		t.Errorf("accounts after recovery: %q", got)
+	}
+}
@@ -0,0 +1,186 @@
+// internal/storage/constraints.go
+//
+// NOT NULL and DEFAULT column attributes. A column declared NOT NULL
+// rejects NULL on insert and update. A column with a DEFAULT takes that
+// value when an insert leaves it out or writes DEFAULT for it; columns
+// without one take NULL.
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultValue stands for a column's default in the values of an insert or
+// update. Like NullValue, no SQL literal can produce it.
+const DefaultValue = "\x00DEFAULT"
+
+// ErrNotNull rejects a NULL written to a NOT NULL column
+const ErrNotNull = "Error: NULL value in column %s violates NOT NULL"
+
+// specFields splits a column definition into words, keeping single-quoted
+// strings such as 'a b' in one word
+func specFields(s string) []string {
+	var fields []string
+	var field strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			// A doubled quote closes and reopens, so it stays in the field
+			inQuote = !inQuote
+			field.WriteRune(r)
+		case !inQuote && unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// parseDefault parses the literal of a DEFAULT clause: a quoted string in
+// which two quotes stand for one, NULL, or a bare token such as a number
+func parseDefault(raw string) (string, error) {
+	if strings.EqualFold(raw, "NULL") {
+		return NullValue, nil
+	}
+	if !strings.HasPrefix(raw, "'") {
+		if strings.Contains(raw, "'") {
+			return "", fmt.Errorf("invalid default %s", raw)
+		}
+		return raw, nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(raw); i++ {
+		if raw[i] != '\'' {
+			b.WriteByte(raw[i])
+			continue
+		}
+		if i+1 < len(raw) && raw[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		if i != len(raw)-1 {
+			return "", fmt.Errorf("invalid default %s", raw)
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unterminated default %s", raw)
+}
+
+// defaultLiteral returns a column default as it is written in CREATE TABLE
+func defaultLiteral(ct ColumnType, value string) string {
+	switch ct {
+	case TypeInt, TypeFloat, TypeBool:
+		return value
+	}
+	return quoteString(value)
+}
+
+// notNullColumns returns the NOT NULL columns in table order
+func (t *Table) notNullColumns() []string {
+	var cols []string
+	for _, col := range t.Columns {
+		if t.NotNull[col] {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// Default returns the default of a column: its DEFAULT value, or NULL
+func (t *Table) Default(column string) string {
+	if v, ok := t.Defaults[column]; ok {
+		return v
+	}
+	return NullValue
+}
+
+// constrainedRow returns values with DEFAULT replaced by the column
+// defaults, or the error to fail the write with when a NOT NULL column
+// would hold NULL
+func (t *Table) constrainedRow(values []string) ([]string, string) {
+	var filled []string
+	for i, v := range values {
+		if i >= len(t.Columns) {
+			break
+		}
+		col := t.Columns[i]
+		if v == DefaultValue {
+			if filled == nil {
+				filled = append([]string(nil), values...)
+			}
+			v = t.Default(col)
+			filled[i] = v
+		}
+		if IsNull(v) && t.NotNull[col] {
+			return nil, fmt.Sprintf(ErrNotNull, col)
+		}
+	}
+	if filled != nil {
+		return filled, ""
+	}
+	return values, ""
+}
+
+// rowFromColumns returns the row an insert naming columns writes: values
+// in the named columns and DEFAULT in the others. Versioned and timestamped
+// tables get no _version, created_at or updated_at values; the insert adds
+// them.
+func (t *Table) rowFromColumns(columns, values []string) ([]string, string) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if len(columns) != len(values) {
+		return nil, "Column count does not match"
+	}
+	n := len(t.Columns)
+	if ti := t.timestampIndex(); ti >= 0 {
+		n = ti
+	} else if vi := t.versionIndex(); vi >= 0 {
+		n = vi
+	}
+	row := make([]string, n)
+	for i := range row {
+		row[i] = DefaultValue
+	}
+	given := make([]bool, n)
+	for i, name := range columns {
+		idx := t.columnIndex(name)
+		switch {
+		case idx == -1:
+			return nil, fmt.Sprintf("Column %s not found", name)
+		case t.autoColumn(idx):
+			return nil, fmt.Sprintf("Error: %s is maintained automatically and cannot be set", t.Columns[idx])
+		case given[idx]:
+			return nil, fmt.Sprintf("Error: column %s is given more than once", t.Columns[idx])
+		}
+		given[idx] = true
+		row[idx] = values[i]
+	}
+	return row, ""
+}
+
+// InsertColumnsTx inserts a row with values for the named columns only; the
+// others take their defaults
+func (db *Database) InsertColumnsTx(tableName string, columns, values []string) string {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	row, msg := table.rowFromColumns(columns, values)
+	if msg != "" {
+		return msg
+	}
+	return db.InsertTx(tableName, row)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotNullAndDefault(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	if msg := db.CreateTable("users", []string{"id INT NOT NULL", "name TEXT DEFAULT 'it''s, me'", "score INT DEFAULT 10 NOT NULL", "note"}); strings.HasPrefix(msg, "Error") {
+		t.Fatal(msg)
+	}
+
+	const inserted = "1 row inserted with secure page-based storage"
+	if msg := db.Insert("users", []string{"1", DefaultValue, DefaultValue, DefaultValue}); msg != inserted {
+		t.Fatalf("insert with defaults: %s", msg)
+	}
+	if msg := db.InsertColumnsTx("users", []string{"NOTE", "id"}, []string{"hi", "2"}); msg != inserted {
+		t.Fatalf("insert naming columns: %s", msg)
+	}
+	for _, tt := range []struct {
+		values []string
+		want   string
+	}{
+		{[]string{NullValue, "a", "1", ""}, "Error: NULL value in column id violates NOT NULL"},
+		{[]string{DefaultValue, "a", "1", ""}, "Error: NULL value in column id violates NOT NULL"},
+		{[]string{"3", "a", NullValue, ""}, "Error: NULL value in column score violates NOT NULL"},
+	} {
+		if got := db.Insert("users", tt.values); got != tt.want {
+			t.Errorf("Insert(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+		if got := db.InsertTx("users", tt.values); got != tt.want {
+			t.Errorf("InsertTx(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+	if got := db.InsertColumnsTx("users", []string{"name"}, []string{"x"}); got != "Error: NULL value in column id violates NOT NULL" {
+		t.Errorf("insert leaving out a NOT NULL column: %q", got)
+	}
+	if got := db.InsertColumnsTx("users", []string{"id", "ID"}, []string{"1", "2"}); got != "Error: column id is given more than once" {
+		t.Errorf("insert naming a column twice: %q", got)
+	}
+	if got := db.InsertColumnsTx("users", []string{"id", "missing"}, []string{"1", "2"}); got != "Column missing not found" {
+		t.Errorf("insert naming a missing column: %q", got)
+	}
+	if got := db.Update("users", 0, []string{"1", "a", NullValue, ""}); got != "Error: NULL value in column score violates NOT NULL" {
+		t.Errorf("update to NULL: %q", got)
+	}
+	if got := db.Update("users", 0, []string{"1", "a", "5", ""}); got != "1 row updated" {
+		t.Errorf("update: %q", got)
+	}
+	if got := db.Update("users", 0, []string{"1", DefaultValue, "5", ""}); got != "1 row updated" {
+		t.Errorf("update to the default: %q", got)
+	}
+
+	check := func(db *Database) {
+		t.Helper()
+		want := "id | name | score | note\n1 | it's, me | 5 | \n2 | it's, me | 10 | hi\n"
+		if got := db.SelectAll("users"); got != want {
+			t.Errorf("rows:\n got %q\nwant %q", got, want)
+		}
+		if got := db.ShowCreateTable("users"); !strings.Contains(got, "(id INT NOT NULL, name TEXT DEFAULT 'it''s, me', score INT NOT NULL DEFAULT 10, note)") {
+			t.Errorf("SHOW CREATE TABLE: %s", got)
+		}
+		if got := db.Insert("users", []string{NullValue, "a", "1", ""}); !strings.Contains(got, "violates NOT NULL") {
+			t.Errorf("NOT NULL lost: %q", got)
+		}
+	}
+	check(db)
+	db.Close()
+
+	// The attributes survive a restart
+	db = NewDatabase(dir)
+	defer db.Close()
+	check(db)
+}
+
+func TestParseColumnSpecsConstraints(t *testing.T) {
+	defs, err := parseColumnSpecs([]string{"a DEFAULT NULL", "b BOOL DEFAULT 'T'", "c FLOAT DEFAULT 1.50", `"d e" NOT NULL DEFAULT 'x y'`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := defs.defaults["a"]; ok {
+		t.Error("DEFAULT NULL should leave the column without a default")
+	}
+	if defs.defaults["b"] != "true" || defs.defaults["c"] != "1.5" || defs.defaults["d e"] != "x y" || !defs.notNull["d e"] {
+		t.Errorf("unexpected defaults %q and NOT NULL %v", defs.defaults, defs.notNull)
+	}
+
+	for _, tt := range []struct {
+		spec, err string
+	}{
+		{"id INT DEFAULT 'x'", "default 'x' does not match type INT of column id"},
+		{"id NOT NULL DEFAULT NULL", "column id is NOT NULL but its default is NULL"},
+		{"id DEFAULT 'open", "column id: unterminated default 'open"},
+		{"id NOT", `invalid column definition "id NOT" (expected: name [type] [NOT NULL] [DEFAULT value] [COLLATE collation])`},
+		{"id NOT EMPTY", `invalid column definition "id NOT EMPTY" (expected: name [type] [NOT NULL] [DEFAULT value] [COLLATE collation])`},
+	} {
+		_, err := parseColumnSpecs([]string{tt.spec})
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: got error %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
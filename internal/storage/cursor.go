@@ -0,0 +1,118 @@
+// internal/storage/cursor.go
+package storage
+
+import "fmt"
+
+// Cursor returns a query's rows in batches. It reads the rows published when
+// it was opened, so it neither copies the table nor sees later writes. A
+// query without ORDER BY is evaluated lazily as rows are fetched; an ordered
+// query keeps only the positions of its result rows.
+type Cursor struct {
+	columns []string
+	types   []ColumnType
+	rows    [][]string
+	// order lists the result rows of an ordered query; nil scans rows in
+	// table order, filtering with match
+	order []int
+	match func([]string) (bool, error)
+	// limit caps the rows returned; negative means no limit
+	limit    int
+	pos      int
+	returned int
+	// mask redacts the masked columns of each row returned and keeps the
+	// result columns, or is nil
+	mask func([]string) []string
+	// distinct skips result rows equal to one returned before, or is nil
+	distinct *distinctFilter
+}
+
+// OpenCursor prepares q for fetching
+func (db *Database) OpenCursor(tableName string, q Query) (*Cursor, string) {
+	p, msg := db.prepareQuery(tableName, q)
+	if msg != "" {
+		return nil, msg
+	}
+	c := &Cursor{columns: p.resultColumns(), types: p.resultTypes(), limit: q.Limit, mask: p.transform(q.Role)}
+	if q.Distinct {
+		c.distinct = p.distinctFilter()
+		q.Limit = -1
+	}
+	if p.orderIdx < 0 {
+		c.rows = db.visibleRows(p.table)
+		c.match = p.match
+		return c, ""
+	}
+	rs, matched, msg := db.runQuery(p, q)
+	if msg != "" {
+		return nil, msg
+	}
+	c.rows = rs.rows
+	c.order = matched
+	if c.order == nil {
+		c.order = []int{}
+	}
+	return c, ""
+}
+
+// FetchResult returns up to n more rows, or every remaining row when n is
+// negative. An exhausted cursor returns no rows.
+func (c *Cursor) FetchResult(n int) (*ResultSet, string) {
+	var batch []int
+	for n < 0 || len(batch) < n {
+		if c.limit >= 0 && c.returned >= c.limit {
+			break
+		}
+		ri, ok, err := c.next()
+		if err != nil {
+			return nil, fmt.Sprintf("Error evaluating WHERE condition: %v", err)
+		}
+		if !ok {
+			break
+		}
+		if c.distinct != nil && !c.distinct.first(c.resultRow(ri)) {
+			continue
+		}
+		batch = append(batch, ri)
+		c.returned++
+	}
+	rs, batch := maskRows(rowSet{rows: c.rows}, batch, c.mask)
+	out := newResultSet(c.columns, rs.rows, batch)
+	out.Types = c.types
+	return out, ""
+}
+
+// Fetch is FetchResult rendered as text
+func (c *Cursor) Fetch(n int) string {
+	return resultText(c.FetchResult(n))
+}
+
+// resultRow returns row ri as it is returned
+func (c *Cursor) resultRow(ri int) []string {
+	if c.mask != nil {
+		return c.mask(c.rows[ri])
+	}
+	return c.rows[ri]
+}
+
+// next returns the next result row
+func (c *Cursor) next() (int, bool, error) {
+	if c.order != nil {
+		if c.pos >= len(c.order) {
+			return 0, false, nil
+		}
+		c.pos++
+		return c.order[c.pos-1], true, nil
+	}
+	for c.pos < len(c.rows) {
+		ri := c.pos
+		c.pos++
+		ok, err := c.match(c.rows[ri])
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			return ri, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,63 @@
+// internal/storage/degraded.go
+//
+// Degraded mode. When the WAL cannot be opened the database still starts,
+// but writes are no longer crash-safe. WALFailurePolicy decides what the
+// server does about it: refuse to start, reject writes, or accept them with
+// a warning.
+package storage
+
+import "fmt"
+
+// WALFailurePolicy is what the server does when the WAL is unavailable
+type WALFailurePolicy int
+
+const (
+	// WALFailWarn accepts writes and warns with each one
+	WALFailWarn WALFailurePolicy = iota
+	// WALFailReadOnly rejects writes
+	WALFailReadOnly
+	// WALFailFast stops the server from starting
+	WALFailFast
+)
+
+// ParseWALFailurePolicy parses fail-fast, read-only or warn
+func ParseWALFailurePolicy(s string) (WALFailurePolicy, error) {
+	switch s {
+	case "fail-fast":
+		return WALFailFast, nil
+	case "read-only":
+		return WALFailReadOnly, nil
+	case "warn":
+		return WALFailWarn, nil
+	}
+	return 0, fmt.Errorf("invalid WAL failure policy %q: use fail-fast, read-only or warn", s)
+}
+
+func (p WALFailurePolicy) String() string {
+	switch p {
+	case WALFailFast:
+		return "fail-fast"
+	case WALFailReadOnly:
+		return "read-only"
+	case WALFailWarn:
+		return "warn"
+	}
+	return fmt.Sprintf("WALFailurePolicy(%d)", int(p))
+}
+
+// ErrWALUnavailable rejects writes under the read-only policy
+const ErrWALUnavailable = "Error: WAL is unavailable (%v); writes are rejected until the server is restarted with a working WAL"
+
+// WarnWALUnavailable is appended to writes accepted under the warn policy
+const WarnWALUnavailable = "Warning: WAL is unavailable; this write will be lost if the server crashes before the next checkpoint"
+
+// WALError returns why the WAL could not be opened, or nil when it is
+// available
+func (db *Database) WALError() error {
+	return db.walErr
+}
+
+// Degraded reports whether the database runs without its WAL
+func (db *Database) Degraded() bool {
+	return db.walErr != nil
+}
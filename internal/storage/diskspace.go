@@ -0,0 +1,62 @@
+// internal/storage/diskspace.go
+//
+// Disk space protection. A write that runs out of space halfway can leave a
+// table file or page half written, so once free space in the data directory
+// drops below MinFreeBytes new writes are refused up front. Reads, deletes,
+// drops and checkpoints still run, since they free space or need none.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// diskCheckInterval is how long a free space reading is reused
+const diskCheckInterval = time.Second
+
+// ErrLowDiskSpace refuses a write while free space is below the minimum
+const ErrLowDiskSpace = "Error: only %d MB free in the data directory, below the minimum of %d MB; writes are refused until space is freed (DELETE and DROP still work)"
+
+// errDiskSpaceUnsupported is returned where free space cannot be measured
+var errDiskSpaceUnsupported = errors.New("free space cannot be measured on this platform")
+
+// diskSpace caches the free space of the data directory
+type diskSpace struct {
+	mu      sync.Mutex
+	checked time.Time
+	free    int64
+	err     error
+}
+
+// DiskFree returns the bytes free in the data directory, measured at most
+// once per second
+func (db *Database) DiskFree() (int64, error) {
+	db.disk.mu.Lock()
+	defer db.disk.mu.Unlock()
+	if time.Since(db.disk.checked) >= diskCheckInterval {
+		db.disk.free, db.disk.err = freeBytes(db.DataDir)
+		db.disk.checked = time.Now()
+	}
+	return db.disk.free, db.disk.err
+}
+
+// CheckDiskSpace returns the error to refuse a write with when free space is
+// below MinFreeBytes, or "" when the write may go ahead. Writes are allowed
+// when the check is disabled or free space cannot be measured.
+func (db *Database) CheckDiskSpace() string {
+	if db.MinFreeBytes <= 0 {
+		return ""
+	}
+	free, err := db.DiskFree()
+	if err != nil || free >= db.MinFreeBytes {
+		return ""
+	}
+	return fmt.Sprintf(ErrLowDiskSpace, free>>20, db.MinFreeBytes>>20)
+}
+
+// LowDiskSpace reports whether writes are being refused for lack of space
+func (db *Database) LowDiskSpace() bool {
+	return db.CheckDiskSpace() != ""
+}
@@ -0,0 +1,9 @@
+// internal/storage/diskspace_other.go
+//go:build !unix
+
+package storage
+
+// freeBytes cannot measure free space here, so the check is skipped
+func freeBytes(dir string) (int64, error) {
+	return 0, errDiskSpaceUnsupported
+}
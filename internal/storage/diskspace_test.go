@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	free, err := db.DiskFree()
+	if err == errDiskSpaceUnsupported {
+		t.Skip(err)
+	}
+	if err != nil || free <= 0 {
+		t.Fatalf("DiskFree() = %d, %v", free, err)
+	}
+
+	if msg := db.CheckDiskSpace(); msg != "" {
+		t.Errorf("the check should be off by default, got %s", msg)
+	}
+	db.MinFreeBytes = 1
+	if db.LowDiskSpace() {
+		t.Error("space should not be low")
+	}
+	db.MinFreeBytes = math.MaxInt64
+	if msg := db.CheckDiskSpace(); !strings.HasPrefix(msg, "Error: only ") {
+		t.Errorf("expected writes to be refused, got %q", msg)
+	}
+}
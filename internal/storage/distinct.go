@@ -0,0 +1,60 @@
+// internal/storage/distinct.go
+//
+// SELECT DISTINCT. Duplicates are found among the result rows, after the
+// result columns are picked and masked, so a query returns no two rows that
+// look the same. Values compare like ORDER BY compares them for equality:
+// under the column's collation, so NOCASE columns collapse 'a' and 'A', and
+// numbers by spelling, so '1' and '1.0' both stay. The first row of each
+// set of duplicates is kept, in result order.
+package storage
+
+// distinctFilter remembers the result rows seen so far
+type distinctFilter struct {
+	keys []orderKey
+	seen map[string]struct{}
+}
+
+// distinctFilter returns a filter for the result rows of the query
+func (p *queryPlan) distinctFilter() *distinctFilter {
+	f := &distinctFilter{seen: make(map[string]struct{})}
+	if p.output == nil {
+		for i, col := range p.table.Columns {
+			f.keys = append(f.keys, orderKey{col: i, coll: p.table.Collation(col)})
+		}
+		return f
+	}
+	for i, col := range p.output {
+		key := orderKey{col: i}
+		if col >= 0 {
+			key.coll = p.table.Collation(p.table.Columns[col])
+		}
+		f.keys = append(f.keys, key)
+	}
+	return f
+}
+
+// first reports whether no result row equal to row was seen before
+func (f *distinctFilter) first(row []string) bool {
+	key := string(rowOrder{rows: [][]string{row}, keys: f.keys}.rowKey(0))
+	if _, dup := f.seen[key]; dup {
+		return false
+	}
+	f.seen[key] = struct{}{}
+	return true
+}
+
+// distinctRows returns the first of each set of equal result rows among
+// matched, up to limit unless it is negative
+func (p *queryPlan) distinctRows(rows [][]string, matched []int, limit int) []int {
+	f := p.distinctFilter()
+	kept := make([]int, 0, len(matched))
+	for _, ri := range matched {
+		if limit >= 0 && len(kept) >= limit {
+			break
+		}
+		if f.first(rows[ri]) {
+			kept = append(kept, ri)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,70 @@
+package storage
+
+import "testing"
+
+func TestSelectQueryDistinct(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("visits", []string{"id", "city COLLATE NOCASE", "country", "score"})
+	for _, row := range [][]string{
+		{"1", "Paris", "FR", "1"},
+		{"2", "Lyon", "FR", "1.0"},
+		{"3", "paris", "FR", "1"},
+		{"4", "Berlin", "DE", "2"},
+		{"5", "Lyon", "FR", "3"},
+	} {
+		db.Insert("visits", row)
+	}
+	columns := func(names ...string) []ResultColumn {
+		rcs := make([]ResultColumn, len(names))
+		for i, name := range names {
+			rcs[i] = ResultColumn{Name: name}
+		}
+		return rcs
+	}
+
+	for _, tc := range []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{"full rows", Query{Distinct: true, Limit: -1},
+			"id | city | country | score\n1 | Paris | FR | 1\n2 | Lyon | FR | 1.0\n3 | paris | FR | 1\n4 | Berlin | DE | 2\n5 | Lyon | FR | 3\n"},
+		{"one column", Query{Columns: columns("country"), Distinct: true, Limit: -1},
+			"country\nFR\nDE\n"},
+		// NOCASE collapses Paris and paris; numbers compare by spelling
+		{"collation", Query{Columns: columns("city", "score"), Distinct: true, Limit: -1},
+			"city | score\nParis | 1\nLyon | 1.0\nBerlin | 2\nLyon | 3\n"},
+		{"limit after duplicates", Query{Columns: columns("city"), Distinct: true, Limit: 2},
+			"city\nParis\nLyon\n"},
+		{"ordered", Query{Columns: columns("city"), Distinct: true, OrderBy: "id", Desc: true, Limit: -1},
+			"city\nLyon\nBerlin\nparis\n"},
+		{"where", Query{Columns: columns("country"), Distinct: true, Where: &equalWhere{col: "city", value: "Lyon"}, Limit: -1},
+			"country\nFR\n"},
+	} {
+		if got := db.SelectQuery("visits", tc.q); got != tc.want {
+			t.Errorf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+
+	// Masked values are compared as they are returned
+	db.SetMask("visits", "country", "user", MaskFull)
+	q := Query{Columns: columns("country"), Distinct: true, Role: "user", Limit: -1}
+	if got, want := db.SelectQuery("visits", q), "country\n****\n"; got != want {
+		t.Errorf("masked: %q, want %q", got, want)
+	}
+
+	cursor, msg := db.OpenCursor("visits", Query{Columns: columns("country"), Distinct: true, Limit: -1})
+	if msg != "" {
+		t.Fatal(msg)
+	}
+	if got := cursor.Fetch(1); got != "country\nFR\n" {
+		t.Errorf("first fetch: %q", got)
+	}
+	if got := cursor.Fetch(-1); got != "country\nDE\n" {
+		t.Errorf("second fetch: %q", got)
+	}
+
+	if got, want := db.Explain("visits", Query{Distinct: true, Limit: 3}), "plan\nLimit 3\nDistinct\nSeq Scan on visits\n"; got != want {
+		t.Errorf("explain: %q, want %q", got, want)
+	}
+}
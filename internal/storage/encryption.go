@@ -0,0 +1,228 @@
+// internal/storage/encryption.go
+//
+// Turning encryption on or off for an existing table and moving tables to a
+// new key. Both rewrite the table's file and every page file, so they block
+// writes while they run; the WAL is checkpointed first so that no page is
+// dirty and no logged change is left to replay into pages sealed the old way.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetEncryption seals a table with the current key, or stores it in the
+// clear again
+func (db *Database) SetEncryption(tableName string, encrypted bool) string {
+	db.writeGate.Lock()
+	defer db.writeGate.Unlock()
+
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if table.External != nil {
+		return fmt.Sprintf("Error: external table %s cannot be encrypted", tableName)
+	}
+	if encrypted && table.KeyID != "" {
+		return fmt.Sprintf("Table %s is already encrypted with key %s", tableName, table.KeyID)
+	}
+	if !encrypted && table.KeyID == "" {
+		return fmt.Sprintf("Table %s is not encrypted", tableName)
+	}
+
+	var keyID string
+	if encrypted {
+		var err error
+		if keyID, err = db.Keys.Current(); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
+	if err := db.checkpointPages(); err != nil {
+		return fmt.Sprintf("Error: failed to checkpoint: %v", err)
+	}
+	if err := db.reencryptTable(table, keyID); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if encrypted {
+		return fmt.Sprintf("Table %s encrypted with key %s", tableName, keyID)
+	}
+	return fmt.Sprintf("Table %s decrypted", tableName)
+}
+
+// Rekey creates a key, re-encrypts the named table, or every encrypted table
+// when tableName is empty, with it and retires the keys no table uses any
+// more
+func (db *Database) Rekey(tableName string) string {
+	db.writeGate.Lock()
+	defer db.writeGate.Unlock()
+
+	tableName = strings.ToLower(tableName)
+	var tables []*Table
+	if tableName != "" {
+		table, exists := db.lookupTable(tableName)
+		if !exists {
+			return fmt.Sprintf(ErrTableNotFound, tableName)
+		}
+		if table.KeyID == "" {
+			return fmt.Sprintf("Error: table %s is not encrypted", tableName)
+		}
+		tables = append(tables, table)
+	} else {
+		db.catalog.RLock()
+		for _, table := range db.Tables {
+			if table.KeyID != "" {
+				tables = append(tables, table)
+			}
+		}
+		db.catalog.RUnlock()
+		sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	}
+
+	keyID, err := db.Keys.Rotate()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := db.checkpointPages(); err != nil {
+		return fmt.Sprintf("Error: failed to checkpoint: %v", err)
+	}
+	for i, table := range tables {
+		if err := db.reencryptTable(table, keyID); err != nil {
+			return fmt.Sprintf("Error: rekeyed %d of %d tables with key %s: %v", i, len(tables), keyID, err)
+		}
+	}
+
+	msg := fmt.Sprintf("Rekeyed %d tables with key %s", len(tables), keyID)
+	retired, err := db.retireKeys()
+	if err != nil {
+		return msg + fmt.Sprintf(" (warning: failed to retire old keys: %v)", err)
+	}
+	if len(retired) == 0 {
+		return msg + "; no keys retired"
+	}
+	return msg + "; retired keys: " + strings.Join(retired, ", ")
+}
+
+// retireKeys removes every key no table is sealed with. Nothing is removed
+// while a table could not be decrypted, as its key may be among them.
+func (db *Database) retireKeys() ([]string, error) {
+	db.unreadableMu.Lock()
+	unreadable := len(db.unreadable)
+	db.unreadableMu.Unlock()
+	if unreadable > 0 {
+		return nil, nil
+	}
+	inUse := make(map[string]bool)
+	db.catalog.RLock()
+	for _, table := range db.Tables {
+		if table.KeyID != "" {
+			inUse[table.KeyID] = true
+		}
+	}
+	db.catalog.RUnlock()
+	return db.Keys.Retire(inUse)
+}
+
+// TableKeyIDs returns the key each table is sealed with, "" for tables
+// stored in the clear
+func (db *Database) TableKeyIDs() map[string]string {
+	db.catalog.RLock()
+	defer db.catalog.RUnlock()
+	keys := make(map[string]string, len(db.Tables))
+	for name, table := range db.Tables {
+		keys[name] = table.KeyID
+	}
+	return keys
+}
+
+// reencryptTable rewrites a table's pages and file sealed with keyID, or in
+// the clear when it is empty. The caller blocks writes and has checkpointed.
+func (db *Database) reencryptTable(table *Table, keyID string) error {
+	if db.PageStorage != nil {
+		if err := db.PageStorage.Reencrypt(table.Name, keyID); err != nil {
+			return fmt.Errorf("failed to rewrite pages of %s: %w", table.Name, err)
+		}
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	previous := table.KeyID
+	table.KeyID = keyID
+	if err := db.writeTable(table, true); err != nil {
+		table.KeyID = previous
+		return fmt.Errorf("failed to rewrite table %s: %w", table.Name, err)
+	}
+	return nil
+}
+
+// Reencrypt rewrites every page of a table sealed with keyID, or in the clear
+// when it is empty, and records the sealing in its manifest. The pages and
+// manifest are staged and committed through the control file like a
+// checkpoint, so a crash leaves either the old or the new sealing. The caller
+// blocks writes and has checkpointed, so no page is dirty.
+func (ps *PageStorage) Reencrypt(tableName, keyID string) error {
+	ps.checkpointMu.Lock()
+	defer ps.checkpointMu.Unlock()
+
+	metadata, err := ps.loadMetadata(tableName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	seal := pageSeal{mode: PageEncryptionNone}
+	if keyID != "" {
+		seal = pageSeal{mode: PageEncryptionAES, keyID: keyID}
+	}
+
+	var pending []string
+	for pageID := metadata.FirstPageID; pageID <= metadata.LastPageID; pageID++ {
+		page, err := ps.loadPage(tableName, pageID)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		page.mu.RLock()
+		_, err = ps.stageSealed(tableName, page, seal)
+		page.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		pending = append(pending, pageFile(tableName, pageID))
+	}
+
+	metadata.Encryption, metadata.KeyID = seal.mode, seal.keyID
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(ps.manifestPath(tableName)+".tmp", data); err != nil {
+		return err
+	}
+	pending = append(pending, filepath.Join(TablesDirName, tableName, ManifestName))
+
+	if err := ps.writeControl(pageControl{CheckpointLSN: ps.checkpointLSN, Pending: pending}); err != nil {
+		return err
+	}
+	// Readers wait for the new sealing while the files are swapped
+	ps.sealsMu.Lock()
+	err = ps.finishPending(pending)
+	if err == nil {
+		ps.seals[tableName] = seal
+	} else {
+		delete(ps.seals, tableName)
+	}
+	ps.sealsMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ps.writeControl(pageControl{CheckpointLSN: ps.checkpointLSN})
+}
@@ -0,0 +1,192 @@
+// internal/storage/errors.go
+//
+// Failure codes. Storage operations report failures as messages, like their
+// other outcomes; failureFormats lists the format of every failure message
+// the package returns with the code of the failure, so that callers code a
+// failure by the message it is rather than by words it happens to contain.
+package storage
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+// failureFormats maps failure message formats to their codes. Errors
+// returned by fmt.Errorf reach clients as "Error: " and their text, so
+// their formats are listed with that prefix. I/O failures ("failed to
+// ...") are left out and are internal.
+var failureFormats = []struct {
+	format string
+	code   protocol.ErrorCode
+}{
+	// Conflicts with the data or with the state of the database
+	{ErrNotNull, protocol.CodeConstraint},
+	{ErrForeignKeyMissing, protocol.CodeConstraint},
+	{ErrForeignKeyRestrict, protocol.CodeConstraint},
+	{ErrReferencedTable + " and cannot have a retention policy", protocol.CodeConstraint},
+	{ErrReferencedTable, protocol.CodeConstraint},
+	{"Error: value %s does not match type %s of column %s", protocol.CodeConstraint},
+	{"Error: %s is maintained automatically and cannot be set", protocol.CodeConstraint},
+	{ErrVersionInvalid, protocol.CodeConstraint},
+	{"Error: %s: %s is not a number", protocol.CodeConstraint},
+	{"Error: %s on %s rejected: %v", protocol.CodeConstraint},
+	{"Error: version conflict on row ID %d of %s: the row is at version %d, not %d", protocol.CodeConstraint},
+	{"Error: cannot delete %s rows inserted in this transaction by ON DELETE CASCADE; commit them first", protocol.CodeConstraint},
+	{"Error: foreign keys cannot reference external table %s", protocol.CodeConstraint},
+	{"Error: foreign keys cannot reference table %s, which has a retention policy", protocol.CodeConstraint},
+	{"Column count does not match%s", protocol.CodeConstraint},
+	{"Error: column count mismatch%s", protocol.CodeConstraint},
+	{"Table %s already exists", protocol.CodeConstraint},
+	{"Error: table %s already exists", protocol.CodeConstraint},
+	{"Error: procedure %s already exists", protocol.CodeConstraint},
+	{"Error: table %s exists but is not a KV table", protocol.CodeConstraint},
+	{"Error: table %s is external and read-only", protocol.CodeConstraint},
+	{"Error: table %s is external and has no pages", protocol.CodeConstraint},
+	{"Error: table %s is not encrypted", protocol.CodeConstraint},
+	{"Error: table %s is not versioned", protocol.CodeConstraint},
+	{"Error: table %s has no retention policy", protocol.CodeConstraint},
+	{"Error: row too large for page", protocol.CodeConstraint},
+	{"Error: no active transaction", protocol.CodeConstraint},
+	{"Error: transaction %s is not active%s", protocol.CodeConstraint},
+
+	// Lost conflicts with concurrent transactions
+	{"Error: could not serialize access: %s", protocol.CodeSerialization},
+
+	// Missing objects
+	{"Table %s not found", protocol.CodeNotFound},
+	{"Column %s not found", protocol.CodeNotFound},
+	{"Error: column %s referenced by foreign key %s not found in %s", protocol.CodeNotFound},
+	{"Error: table %s referenced by foreign key %s not found", protocol.CodeNotFound},
+	{"Error: foreign key column %s not found", protocol.CodeNotFound},
+	{"Error: no mask on %s.%s for role %s", protocol.CodeNotFound},
+	{"Error: unknown table %s in %s", protocol.CodeNotFound},
+	{"Error: changes were truncated from the change log: %s", protocol.CodeNotFound},
+
+	// Malformed or invalid statements
+	{"Error: %s is added by timestamps=true; leave it out of the column list", protocol.CodeSyntax},
+	{"Error: %s is added by versioned=true; leave it out of the column list", protocol.CodeSyntax},
+	{"Error: %s(*) is not an aggregate; use %s(column)", protocol.CodeSyntax},
+	{"Error: unknown aggregate %s", protocol.CodeSyntax},
+	{"Error: COLLATE applies only to TEXT columns, not %s %s", protocol.CodeSyntax},
+	{"Error: LIKE pattern ends with its escape character", protocol.CodeSyntax},
+	{"Error: ROWID is not available when selecting from several tables", protocol.CodeSyntax},
+	{"Error: column %s is NOT NULL but its default is NULL", protocol.CodeSyntax},
+	{"Error: column %s is ambiguous; qualify it with its table", protocol.CodeSyntax},
+	{"Error: column %s is given more than once", protocol.CodeSyntax},
+	{"Error: column %s must appear in GROUP BY or be used in an aggregate", protocol.CodeSyntax},
+	{"Error: column name %s is a reserved word; quote it as %s", protocol.CodeSyntax},
+	{"Error: columns of external table %s cannot have types, NOT NULL or DEFAULT", protocol.CodeSyntax},
+	{"Error: default %s does not match type %s of column %s", protocol.CodeSyntax},
+	{"Error: duplicate column %s", protocol.CodeSyntax},
+	{"Error: empty column definition", protocol.CodeSyntax},
+	{"Error: empty identifier", protocol.CodeSyntax},
+	{"Error: external table %s cannot be encrypted", protocol.CodeSyntax},
+	{"Error: external table %s cannot have foreign keys", protocol.CodeSyntax},
+	{"Error: foreign keys of several columns are not supported", protocol.CodeSyntax},
+	{"Error: invalid column definition %q (expected: %s)", protocol.CodeSyntax},
+	{"Error: invalid column name %s", protocol.CodeSyntax},
+	{"Error: invalid default %s", protocol.CodeSyntax},
+	{"Error: invalid foreign key %q (expected: %s)", protocol.CodeSyntax},
+	{"Error: invalid identifier %s", protocol.CodeSyntax},
+	{"Error: invalid location %s: %v", protocol.CodeSyntax},
+	{"Error: %s is a directory", protocol.CodeSyntax},
+	{"Error: invalid retention limit %q%s", protocol.CodeSyntax},
+	{"Error: retention limit %q repeats a limit given before", protocol.CodeSyntax},
+	{"Error: invalid role %s for a mask; use %s", protocol.CodeSyntax},
+	{"Error: invalid WAL failure policy %q: use fail-fast, read-only or warn", protocol.CodeSyntax},
+	{"Error: unexpected %q after identifier %s", protocol.CodeSyntax},
+	{"Error: unknown collation %s (use %s)", protocol.CodeSyntax},
+	{"Error: unknown column type %s (use %s)", protocol.CodeSyntax},
+	{"Error: unknown mask method '%s'; use %s", protocol.CodeSyntax},
+	{"Error: unknown retention unit %s (use %s)", protocol.CodeSyntax},
+	{"Error: unsupported ON DELETE %s (use RESTRICT or CASCADE)", protocol.CodeSyntax},
+	{"Error: unterminated default %s", protocol.CodeSyntax},
+	{"Error: column %s: invalid default %s", protocol.CodeSyntax},
+	{"Error: column %s: unterminated default %s", protocol.CodeSyntax},
+	{"Error: unterminated identifier %s", protocol.CodeSyntax},
+
+	// Failures of the server, its files and its keys
+	{"Error: WAL is unavailable (%v); writes are rejected until the server is restarted with a working WAL", protocol.CodeInternal},
+	{"Error: only %d MB free in the data directory, below the minimum of %d MB; %s", protocol.CodeInternal},
+	{"Failed to write to WAL: %v", protocol.CodeInternal},
+	{"Error: table %s exists but cannot be decrypted: %v", protocol.CodeInternal},
+	{"Error: encryption key %s is not in %s", protocol.CodeInternal},
+	{"Error: key %s in keyring is not a 256-bit key", protocol.CodeInternal},
+	{"Error: no keyring for key %s", protocol.CodeInternal},
+	{"Error: unknown page encryption %q", protocol.CodeInternal},
+	{"Error: ciphertext too short", protocol.CodeInternal},
+	{"Error: encrypted data too short", protocol.CodeInternal},
+	{"Error: header too short", protocol.CodeInternal},
+	{"Error: invalid page magic number", protocol.CodeInternal},
+	{"Error: page %d of %s is ahead of the WAL (LSN %d > %d)", protocol.CodeInternal},
+	{"Error: row %d is truncated", protocol.CodeInternal},
+	{"Error: invalid timestamp %q", protocol.CodeInternal},
+	{"Error: invalid %s operation data", protocol.CodeInternal},
+	{"Error: unsupported operation type: %d", protocol.CodeInternal},
+	{"Error: cannot read %s: %v", protocol.CodeInternal},
+	{"Error: preloading table %s: %v", protocol.CodeInternal},
+	{"Error: rekeyed %d of %d tables with key %s: %v", protocol.CodeInternal},
+	{"Error: repairing table %s: %s", protocol.CodeInternal},
+	{"Error: no verified backup in %s holds the page", protocol.CodeInternal},
+	{"Error: page changed since backup %s", protocol.CodeInternal},
+	{"Error: backup has no integrity manifest", protocol.CodeInternal},
+	{"Error: backup info not found in backup file", protocol.CodeInternal},
+	{"Error: backup is encrypted: passphrase required", protocol.CodeInternal},
+	{"Error: encrypted backup too short", protocol.CodeInternal},
+	{"Error: unsupported backup encryption version %d", protocol.CodeInternal},
+	{"Error: file %s checksum mismatch", protocol.CodeInternal},
+	{"Error: file %s listed in manifest is missing", protocol.CodeInternal},
+	{"Error: file %s is not listed in the manifest", protocol.CodeInternal},
+	{"Error: file %s appears twice in backup", protocol.CodeInternal},
+	{"Error: file %s size mismatch: expected %d, got %d", protocol.CodeInternal},
+	{"Error: page checksum mismatch: %v", protocol.CodeInternal},
+	{"Error: external table %s: %v", protocol.CodeInternal},
+
+	// Last, as the formats above include some ending in "not found"
+	{"Error: %s not found%s", protocol.CodeNotFound},
+}
+
+// failureWrappers start messages that report another failure after them,
+// which decides the code
+var failureWrappers = []string{
+	"Error evaluating WHERE condition: ",
+	"Error sorting rows: ",
+	"Failed to add operation to transaction: ",
+}
+
+// failurePatterns holds the compiled failureFormats, in the same order
+var failurePatterns = compileFailureFormats()
+
+// compileFailureFormats turns each format into a pattern matching the
+// messages it formats, each verb matching any text
+func compileFailureFormats() []*regexp.Regexp {
+	verb := regexp.MustCompile(`%[sdvqw]`)
+	patterns := make([]*regexp.Regexp, len(failureFormats))
+	for i, f := range failureFormats {
+		parts := verb.Split(f.format, -1)
+		for j := range parts {
+			parts[j] = regexp.QuoteMeta(parts[j])
+		}
+		patterns[i] = regexp.MustCompile(`(?s)^` + strings.Join(parts, ".*") + `$`)
+	}
+	return patterns
+}
+
+// FailureCode returns the code of a failure message returned by the
+// storage package, or "" if msg is not one of its failure formats
+func FailureCode(msg string) protocol.ErrorCode {
+	for _, wrapper := range failureWrappers {
+		if rest, ok := strings.CutPrefix(msg, wrapper); ok {
+			return FailureCode("Error: " + rest)
+		}
+	}
+	line, _, _ := strings.Cut(msg, "\n")
+	for i, pattern := range failurePatterns {
+		if pattern.MatchString(line) {
+			return failureFormats[i].code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Hareesh108/haruDB/internal/protocol"
+)
+
+func TestFailureCode(t *testing.T) {
+	for msg, want := range map[string]protocol.ErrorCode{
+		"Error: value 'x' does not match type INT of column id":                                protocol.CodeConstraint,
+		"Error: NULL value in column id violates NOT NULL":                                     protocol.CodeConstraint,
+		"Error: table p is referenced by FOREIGN KEY c.pid":                                    protocol.CodeConstraint,
+		"Error: table p is referenced by FOREIGN KEY c.pid and cannot have a retention policy": protocol.CodeConstraint,
+		"Error: c.pid = 9 violates FOREIGN KEY: no p row has id = 9":                           protocol.CodeConstraint,
+		"Error: version conflict on row ID 1 of t: the row is at version 3, not 2":             protocol.CodeConstraint,
+		"Column count does not match. Expected 2, got 3":                                       protocol.CodeConstraint,
+		"Error: could not serialize access: table t was changed by another transaction":        protocol.CodeSerialization,
+		"Table users not found":                       protocol.CodeNotFound,
+		"Error: savepoint s1 not found":               protocol.CodeNotFound,
+		"Error: backup info not found in backup file": protocol.CodeInternal,
+		"Error: column x: invalid default 'a":         protocol.CodeSyntax,
+		"Error: unknown column type BLOB (use INT, FLOAT, TEXT, BOOL, POINT, DATE or TIMESTAMP)": protocol.CodeSyntax,
+		"Error evaluating WHERE condition: column nope not found":                                protocol.CodeNotFound,
+		"Failed to add operation to transaction: transaction tx1 is not active":                  protocol.CodeConstraint,
+		"Error: page checksum mismatch: page 3":                                                  protocol.CodeInternal,
+		"Error: failed to open WAL file: permission denied":                                      "",
+		"Table users created": "",
+	} {
+		if got := FailureCode(msg); got != want {
+			t.Errorf("%q: got %q, want %q", msg, got, want)
+		}
+	}
+}
+
+// TestEveryFailureHasACode formats each failure message of the package's
+// sources and checks failureFormats codes it
+func TestEveryFailureHasACode(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verb := regexp.MustCompile(`%[sdvqw]`)
+	// Formats that only wrap another failure, which is checked on its own,
+	// and prefixes of formats built from constants
+	generic := regexp.MustCompile(`^Error: ((%[sdvqw])(: %[sdvqw])?)?$|^Error: %w: `)
+	failure := regexp.MustCompile(`^(Error[: ]|Failed |Column count |(Table|Column) .* (not found|already exists)$)`)
+
+	checked := 0
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || file == "errors.go" {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ignored := map[*ast.BasicLit]bool{}
+		var formats []string
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			for i, arg := range call.Args {
+				lit, ok := arg.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				switch {
+				case pkg.Name == "log", pkg.Name == "strings":
+					ignored[lit] = true
+				case pkg.Name == "fmt" && sel.Sel.Name == "Errorf" && i == 0:
+					ignored[lit] = true
+					s, _ := strconv.Unquote(lit.Value)
+					formats = append(formats, "Error: "+s)
+				}
+			}
+			return true
+		})
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if ok && lit.Kind == token.STRING && !ignored[lit] {
+				if s, err := strconv.Unquote(lit.Value); err == nil && failure.MatchString(s) {
+					formats = append(formats, s)
+				}
+			}
+			return true
+		})
+
+		for _, format := range formats {
+			if generic.MatchString(format) || hasFailureWrapper(format) {
+				continue
+			}
+			msg := verb.ReplaceAllString(format, "x")
+			code := FailureCode(msg)
+			checked++
+			if strings.HasPrefix(format, "Error: failed to ") || strings.HasSuffix(format, ": %w") {
+				// I/O failures are internal, and left to the engine
+				if code != "" && code != protocol.CodeInternal {
+					t.Errorf("%s: %q is coded %s, want INTERNAL", file, format, code)
+				}
+				continue
+			}
+			if code == "" {
+				t.Errorf("%s: failure %q has no code in failureFormats", file, format)
+			}
+		}
+	}
+	if checked < 100 {
+		t.Errorf("only %d failure formats found", checked)
+	}
+}
+
+func hasFailureWrapper(format string) bool {
+	for _, wrapper := range failureWrappers {
+		if strings.HasPrefix(format, wrapper) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+// internal/storage/external.go
+package storage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrExternalReadOnly is returned for writes to an external table
+const ErrExternalReadOnly = "Error: table %s is external and read-only"
+
+// ExternalSource is the CSV file an external table reads its rows from
+type ExternalSource struct {
+	// Path is the absolute path of the file
+	Path string
+	// Header skips the file's first line
+	Header bool
+
+	// cache holds the rows of a preloaded source (see preload.go)
+	cache atomic.Pointer[externalRows]
+}
+
+// CreateExternalTable creates a read-only table whose rows are read from a
+// CSV file on every scan. The file must exist; its contents are not copied.
+func (db *Database) CreateExternalTable(name string, columns []string, location string, header bool) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	path, err := filepath.Abs(location)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid location %s: %v", location, err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		return fmt.Sprintf("Error: cannot read %s: %v", location, err)
+	} else if info.IsDir() {
+		return fmt.Sprintf("Error: %s is a directory", location)
+	}
+
+	db.catalog.Lock()
+	defer db.catalog.Unlock()
+
+	name = strings.ToLower(name)
+	if _, exists := db.Tables[name]; exists {
+		return fmt.Sprintf("Table %s already exists", name)
+	}
+	defs, err := parseColumnSpecs(columns)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if len(defs.types) > 0 || len(defs.notNull) > 0 || len(defs.defaults) > 0 {
+		return fmt.Sprintf("Error: columns of external table %s cannot have types, NOT NULL or DEFAULT", name)
+	}
+	if len(defs.foreignKeys) > 0 {
+		return fmt.Sprintf("Error: external table %s cannot have foreign keys", name)
+	}
+
+	// Write to WAL first
+	if db.WAL != nil {
+		data := map[string]interface{}{"columns": columns, "location": path, "header": header}
+		if err := db.WAL.WriteEntry(WAL_CREATE_TABLE, name, data); err != nil {
+			return fmt.Sprintf("Failed to write to WAL: %v", err)
+		}
+	}
+
+	table := &Table{
+		Name:       name,
+		Columns:    defs.names,
+		Indexes:    make(map[string]map[string][]int),
+		Collations: defs.collations,
+		External:   &ExternalSource{Path: path, Header: header},
+	}
+	db.addTable(table)
+	if err := db.saveTable(table); err != nil {
+		return fmt.Sprintf("External table %s created (warning: failed to persist: %v)", name, err)
+	}
+
+	return fmt.Sprintf("External table %s created over %s", name, path)
+}
+
+// readRows returns every row of the file, read again unless the source is
+// preloaded and the file unchanged
+func (s *ExternalSource) readRows(columns int) ([][]string, error) {
+	if rows, ok, err := s.cachedRows(columns); ok {
+		return rows, err
+	}
+	return s.readFile(columns)
+}
+
+// readFile reads every row of the file. Rows are padded or truncated to
+// columns fields, so a ragged export can still be queried.
+func (s *ExternalSource) readFile(columns int) ([][]string, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var rows [][]string
+	skip := s.Header
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Path, err)
+		}
+		if skip {
+			skip = false
+			continue
+		}
+		row := make([]string, columns)
+		copy(row, record)
+		rows = append(rows, row)
+	}
+}
+
+// externalError returns a user-facing error if table is external and its
+// file cannot be read, or ""
+func (t *Table) externalError() string {
+	if t.External == nil {
+		return ""
+	}
+	if _, err := os.Stat(t.External.Path); err != nil {
+		return fmt.Sprintf("Error: external table %s: %v", t.Name, err)
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExternalTable(t *testing.T) {
+	dataDir := t.TempDir()
+	csvPath := filepath.Join(t.TempDir(), "sales.csv")
+	if err := os.WriteFile(csvPath, []byte("region,amount\nnorth,10\n\"south, east\",20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewDatabase(dataDir)
+	if msg := db.CreateExternalTable("sales", []string{"region", "amount"}, csvPath, true); msg != "External table sales created over "+csvPath {
+		t.Fatalf("create: %s", msg)
+	}
+	if got := db.SelectAll("sales"); got != "region | amount\nnorth | 10\nsouth, east | 20\n" {
+		t.Errorf("select:\n%s", got)
+	}
+
+	// Every scan re-reads the file; short rows are padded
+	if err := os.WriteFile(csvPath, []byte("region,amount\nwest\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SelectAll("sales"); got != "region | amount\nwest | \n" {
+		t.Errorf("select after rewrite:\n%s", got)
+	}
+
+	for name, msg := range map[string]string{
+		"insert": db.Insert("sales", []string{"x", "1"}),
+		"update": db.Update("sales", 0, []string{"x", "1"}),
+		"delete": db.Delete("sales", 0),
+		"index":  db.CreateIndex("sales", "region"),
+	} {
+		if msg != "Error: table sales is external and read-only" {
+			t.Errorf("%s: %s", name, msg)
+		}
+	}
+
+	// The definition survives a restart; the rows stay in the file
+	db = NewDatabase(dataDir)
+	if got := db.SelectCount("sales", nil); got != "count\n1\n" {
+		t.Errorf("count after restart: %s", got)
+	}
+
+	os.Remove(csvPath)
+	if got := db.SelectAll("sales"); !strings.HasPrefix(got, "Error:") {
+		t.Errorf("expected an error for a missing file, got %q", got)
+	}
+	if msg := db.CreateExternalTable("other", []string{"a"}, csvPath, false); !strings.HasPrefix(msg, "Error:") {
+		t.Errorf("create over a missing file: %s", msg)
+	}
+}
@@ -0,0 +1,373 @@
+// internal/storage/foreignkey.go
+//
+// FOREIGN KEY constraints. A table declares them among its column
+// definitions:
+//
+//	FOREIGN KEY (customer_id) REFERENCES customers(id) [ON DELETE RESTRICT | CASCADE]
+//
+// Every non-NULL value written to customer_id must then be present in
+// customers.id. Deleting a customers row that orders still reference is
+// refused (RESTRICT, the default) or deletes those orders too (CASCADE);
+// changing a referenced id is always refused. The checks run in InsertTx,
+// UpdateTx and DeleteTx, against the rows the current transaction sees.
+// Insert, Update and Delete apply rows as given, as replicas do with the
+// changes of their primary.
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Foreign key errors returned to clients
+const (
+	ErrForeignKeyMissing  = "Error: %s.%s = %s violates FOREIGN KEY: no %s row has %s = %s"
+	ErrForeignKeyRestrict = "Error: %s row with %s = %s is still referenced by %s.%s and violates FOREIGN KEY"
+	ErrForeignKeyInTx     = "Error: cannot delete %s rows inserted in this transaction by ON DELETE CASCADE; commit them first"
+	ErrReferencedTable    = "Error: table %s is referenced by FOREIGN KEY %s.%s"
+)
+
+// ForeignKey makes a column reference a column of another table, or of the
+// same one
+type ForeignKey struct {
+	Column    string `json:"column"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
+	// Cascade deletes referencing rows along with the row they reference;
+	// otherwise such deletes are refused
+	Cascade bool `json:"cascade,omitempty"`
+}
+
+// String returns the foreign key as it is written in CREATE TABLE
+func (fk ForeignKey) String() string {
+	s := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", QuoteIdentifier(fk.Column), QuoteIdentifier(fk.RefTable), QuoteIdentifier(fk.RefColumn))
+	if fk.Cascade {
+		s += " ON DELETE CASCADE"
+	}
+	return s
+}
+
+// foreignKeyPattern matches a FOREIGN KEY table constraint
+var foreignKeyPattern = regexp.MustCompile(`(?is)^FOREIGN\s+KEY\s*\(\s*("(?:[^"]|"")*"|[^\s()"]+)\s*\)\s*REFERENCES\s+("(?:[^"]|"")*"|[^\s()"]+)\s*\(\s*("(?:[^"]|"")*"|[^\s()"]+)\s*\)(?:\s+ON\s+DELETE\s+(\w+))?$`)
+
+// isForeignKeySpec reports whether a column definition is a FOREIGN KEY
+// constraint rather than a column
+func isForeignKeySpec(spec string) bool {
+	fields := strings.Fields(spec)
+	return len(fields) > 1 && strings.EqualFold(fields[0], "FOREIGN") &&
+		strings.HasPrefix(strings.ToUpper(fields[1]), "KEY")
+}
+
+// parseForeignKey parses a FOREIGN KEY constraint
+func parseForeignKey(spec string) (ForeignKey, error) {
+	m := foreignKeyPattern.FindStringSubmatch(spec)
+	if m == nil {
+		if strings.Contains(spec, ",") {
+			return ForeignKey{}, fmt.Errorf("foreign keys of several columns are not supported")
+		}
+		return ForeignKey{}, fmt.Errorf("invalid foreign key %q (expected: FOREIGN KEY (column) REFERENCES table(column) [ON DELETE RESTRICT | CASCADE])", spec)
+	}
+	var fk ForeignKey
+	var err error
+	if fk.Column, err = UnquoteIdentifier(m[1]); err != nil {
+		return ForeignKey{}, err
+	}
+	if fk.RefTable, err = UnquoteIdentifier(m[2]); err != nil {
+		return ForeignKey{}, err
+	}
+	fk.RefTable = strings.ToLower(fk.RefTable)
+	if fk.RefColumn, err = UnquoteIdentifier(m[3]); err != nil {
+		return ForeignKey{}, err
+	}
+	switch strings.ToUpper(m[4]) {
+	case "", "RESTRICT":
+	case "CASCADE":
+		fk.Cascade = true
+	default:
+		return ForeignKey{}, fmt.Errorf("unsupported ON DELETE %s (use RESTRICT or CASCADE)", m[4])
+	}
+	return fk, nil
+}
+
+// resolveForeignKeys checks that the foreign keys of table name refer to
+// existing columns, and spells the column names as they were declared.
+// lookup finds the other tables.
+func (d *columnDefs) resolveForeignKeys(name string, lookup func(string) (*Table, bool)) error {
+	own := &Table{Name: name, Columns: d.names}
+	for i := range d.foreignKeys {
+		fk := &d.foreignKeys[i]
+		ci := own.columnIndex(fk.Column)
+		if ci < 0 {
+			return fmt.Errorf("foreign key column %s not found", fk.Column)
+		}
+		fk.Column = d.names[ci]
+
+		ref := own
+		if fk.RefTable != name {
+			var ok bool
+			if ref, ok = lookup(fk.RefTable); !ok {
+				return fmt.Errorf("table %s referenced by foreign key %s not found", fk.RefTable, fk.Column)
+			}
+			if ref.External != nil {
+				return fmt.Errorf("foreign keys cannot reference external table %s", fk.RefTable)
+			}
+			ref.lock.RLock()
+			retained := ref.Retention != nil
+			ref.lock.RUnlock()
+			if retained {
+				return fmt.Errorf("foreign keys cannot reference table %s, which has a retention policy", fk.RefTable)
+			}
+		}
+		ri := ref.columnIndex(fk.RefColumn)
+		if ri < 0 {
+			return fmt.Errorf("column %s referenced by foreign key %s not found in %s", fk.RefColumn, fk.Column, fk.RefTable)
+		}
+		fk.RefColumn = ref.Columns[ri]
+	}
+	return nil
+}
+
+// catalogTable looks a table up while the caller holds db.catalog
+func (db *Database) catalogTable(name string) (*Table, bool) {
+	t, ok := db.Tables[name]
+	return t, ok
+}
+
+// tableReference is a foreign key of table that references another table
+type tableReference struct {
+	table *Table
+	fk    ForeignKey
+}
+
+// referencesTo returns the foreign keys referencing table name, its own
+// included. The caller holds db.catalog.
+func (db *Database) referencesTo(name string) []tableReference {
+	var refs []tableReference
+	for _, t := range db.Tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.RefTable == name {
+				refs = append(refs, tableReference{table: t, fk: fk})
+			}
+		}
+	}
+	return refs
+}
+
+// referencedByOther returns the error for dropping or renaming table name
+// while another table references it. The caller holds db.catalog.
+func (db *Database) referencedByOther(name string) string {
+	for _, ref := range db.referencesTo(name) {
+		if ref.table.Name != name {
+			return fmt.Sprintf(ErrReferencedTable, name, ref.table.Name, ref.fk.Column)
+		}
+	}
+	return ""
+}
+
+// txRowSet returns the rows of table the current transaction sees, with its
+// queued inserts, updates and deletes applied. Queued inserts have ID 0.
+func (db *Database) txRowSet(table *Table) ([][]string, []int64) {
+	rows, ids := db.visibleRowSet(table)
+	tx := db.currentTransaction
+	if tx == nil {
+		return rows, ids
+	}
+	tx.mu.RLock()
+	ops := tx.Operations
+	tx.mu.RUnlock()
+
+	copied := false
+	for _, op := range ops {
+		data, ok := op.Data.(map[string]interface{})
+		if op.TableName != table.Name || !ok {
+			continue
+		}
+		if !copied {
+			rows, ids = append([][]string(nil), rows...), append([]int64(nil), ids...)
+			copied = true
+		}
+		id, _ := data["row_id"].(float64)
+		switch op.Type {
+		case WAL_INSERT:
+			if values, ok := operationValues(data); ok {
+				rows, ids = append(rows, values), append(ids, 0)
+			}
+		case WAL_UPDATE:
+			for i := range ids {
+				if ids[i] == int64(id) && id != 0 {
+					if values, ok := operationValues(data); ok {
+						rows[i] = values
+					}
+					break
+				}
+			}
+		case WAL_DELETE:
+			for i := range ids {
+				if ids[i] == int64(id) && id != 0 {
+					rows, ids = append(rows[:i], rows[i+1:]...), append(ids[:i], ids[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return rows, ids
+}
+
+// matchingRows returns the IDs of the rows whose column col holds value,
+// skipping the row with ID skip
+func matchingRows(t *Table, rows [][]string, ids []int64, col string, value string, skip int64) []int64 {
+	ci := t.columnIndex(col)
+	if ci < 0 {
+		return nil
+	}
+	coll := t.Collation(t.Columns[ci])
+	var matches []int64
+	for i, row := range rows {
+		if ci >= len(row) || (skip != 0 && ids[i] == skip) || IsNull(row[ci]) {
+			continue
+		}
+		if row[ci] == value || (coll != nil && coll.Equal(row[ci], value)) {
+			matches = append(matches, ids[i])
+		}
+	}
+	return matches
+}
+
+// checkForeignKeys returns the error for writing values to a row of table
+// when a foreign key value is not present in the referenced table. old is
+// the row's current values for an update, nil for an insert; unchanged
+// values are not checked again.
+func (db *Database) checkForeignKeys(table *Table, values, old []string) string {
+	for _, fk := range table.ForeignKeys {
+		ci := table.columnIndex(fk.Column)
+		if ci < 0 || ci >= len(values) || IsNull(values[ci]) {
+			continue
+		}
+		if old != nil && ci < len(old) && old[ci] == values[ci] {
+			continue
+		}
+		ref := table
+		if fk.RefTable != table.Name {
+			var ok bool
+			if ref, ok = db.lookupTable(fk.RefTable); !ok {
+				return fmt.Sprintf(ErrForeignKeyMissing, table.Name, fk.Column, values[ci], fk.RefTable, fk.RefColumn, values[ci])
+			}
+		}
+		rows, ids := db.txRowSet(ref)
+		if fk.RefTable == table.Name && old == nil {
+			// A row may reference itself
+			rows, ids = append(rows, values), append(ids, 0)
+		}
+		if len(matchingRows(ref, rows, ids, fk.RefColumn, values[ci], 0)) == 0 {
+			return fmt.Sprintf(ErrForeignKeyMissing, table.Name, fk.Column, values[ci], fk.RefTable, fk.RefColumn, values[ci])
+		}
+	}
+	return ""
+}
+
+// referencedKeyChanges returns the error for changing the row of table with
+// ID id from old to values while other tables reference a value it changes
+func (db *Database) referencedKeyChanges(table *Table, id int64, old, values []string) string {
+	db.catalog.RLock()
+	refs := db.referencesTo(table.Name)
+	db.catalog.RUnlock()
+	for _, ref := range refs {
+		if msg := db.restrictReference(table, id, old, ref, func(ci int) bool {
+			return ci < len(values) && values[ci] != old[ci]
+		}); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// restrictReference returns the error for changing the referenced column of
+// the row of table with ID id, whose values are row, when changed reports
+// that the column changes and rows of ref still reference its value
+func (db *Database) restrictReference(table *Table, id int64, row []string, ref tableReference, changed func(int) bool) string {
+	ci := table.columnIndex(ref.fk.RefColumn)
+	if ci < 0 || ci >= len(row) || IsNull(row[ci]) || !changed(ci) {
+		return ""
+	}
+	value := row[ci]
+	// Another row with the same value still satisfies the references
+	rows, ids := db.txRowSet(table)
+	if len(matchingRows(table, rows, ids, ref.fk.RefColumn, value, id)) > 0 {
+		return ""
+	}
+	rows, ids = db.txRowSet(ref.table)
+	skip := int64(0)
+	if ref.table == table {
+		skip = id
+	}
+	if len(matchingRows(ref.table, rows, ids, ref.fk.Column, value, skip)) > 0 {
+		return fmt.Sprintf(ErrForeignKeyRestrict, table.Name, ref.fk.RefColumn, value, ref.table.Name, ref.fk.Column)
+	}
+	return ""
+}
+
+// rowDelete is a row to delete
+type rowDelete struct {
+	table *Table
+	id    int64
+}
+
+// cascadeDeletes applies ON DELETE for the rows referencing the row of
+// table with ID id and values row: it returns the error for a RESTRICT
+// foreign key that still has referencing rows, and adds the referencing
+// rows of CASCADE ones to cascade, those deleted by cascade from them
+// first. deleting holds the IDs of the rows being deleted by table, so
+// reference cycles end. Nothing is deleted, so a refused delete changes
+// nothing.
+func (db *Database) cascadeDeletes(table *Table, id int64, row []string, deleting map[string]map[int64]bool, cascade *[]rowDelete) string {
+	if deleting[table.Name] == nil {
+		deleting[table.Name] = make(map[int64]bool)
+	}
+	deleting[table.Name][id] = true
+	db.catalog.RLock()
+	refs := db.referencesTo(table.Name)
+	db.catalog.RUnlock()
+
+	everyColumn := func(int) bool { return true }
+	for _, ref := range refs {
+		if !ref.fk.Cascade {
+			if msg := db.restrictReference(table, id, row, ref, everyColumn); msg != "" {
+				return msg
+			}
+			continue
+		}
+		ci := table.columnIndex(ref.fk.RefColumn)
+		if ci < 0 || ci >= len(row) || IsNull(row[ci]) {
+			continue
+		}
+		rows, ids := db.txRowSet(table)
+		if len(matchingRows(table, rows, ids, ref.fk.RefColumn, row[ci], id)) > 0 {
+			continue
+		}
+		rows, ids = db.txRowSet(ref.table)
+		positions := make(map[int64]int, len(ids))
+		for i, rid := range ids {
+			positions[rid] = i
+		}
+		for _, childID := range matchingRows(ref.table, rows, ids, ref.fk.Column, row[ci], 0) {
+			if childID == 0 {
+				return fmt.Sprintf(ErrForeignKeyInTx, ref.table.Name)
+			}
+			if deleting[ref.table.Name][childID] {
+				continue
+			}
+			if msg := db.cascadeDeletes(ref.table, childID, rows[positions[childID]], deleting, cascade); msg != "" {
+				return msg
+			}
+			*cascade = append(*cascade, rowDelete{table: ref.table, id: childID})
+		}
+	}
+	return ""
+}
+
+// isDeleteError reports whether the result of a delete is an error rather
+// than a deleted or queued row
+func isDeleteError(msg string) bool {
+	return !strings.HasPrefix(msg, "1 row delete") && !strings.HasPrefix(msg, "Row deleted")
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForeignKeys(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	if msg := db.CreateTable("customers", []string{"id INT", "name"}); strings.HasPrefix(msg, "Error") {
+		t.Fatal(msg)
+	}
+	if msg := db.CreateTable("orders", []string{"id INT", "customer_id INT", "FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE"}); strings.HasPrefix(msg, "Error") {
+		t.Fatal(msg)
+	}
+	if msg := db.CreateTable("notes", []string{"order_id INT", "FOREIGN KEY (order_id) REFERENCES orders(id)"}); strings.HasPrefix(msg, "Error") {
+		t.Fatal(msg)
+	}
+	if msg := db.CreateTable("bad", []string{"a", "FOREIGN KEY (a) REFERENCES missing(id)"}); !strings.HasPrefix(msg, "Error") {
+		t.Errorf("reference to a missing table accepted: %s", msg)
+	}
+
+	const inserted = "1 row inserted with secure page-based storage"
+	for _, row := range [][]string{{"1", "ann"}, {"2", "bob"}} {
+		if msg := db.InsertTx("customers", row); msg != inserted {
+			t.Fatal(msg)
+		}
+	}
+	for _, row := range [][]string{{"10", "1"}, {"11", "1"}, {"20", "2"}, {"30", NullValue}} {
+		if msg := db.InsertTx("orders", row); msg != inserted {
+			t.Fatalf("insert %q: %s", row, msg)
+		}
+	}
+	if msg := db.InsertTx("orders", []string{"40", "3"}); !strings.Contains(msg, "violates FOREIGN KEY") {
+		t.Errorf("insert of a missing key: %s", msg)
+	}
+	if msg := db.InsertTx("notes", []string{"20"}); msg != inserted {
+		t.Fatal(msg)
+	}
+
+	// Referenced keys cannot change, nor can a referenced table go
+	if msg := db.UpdateTx("customers", 0, []string{"5", "ann"}); !strings.Contains(msg, "violates FOREIGN KEY") {
+		t.Errorf("update of a referenced key: %s", msg)
+	}
+	if msg := db.UpdateTx("orders", 0, []string{"10", "3"}); !strings.Contains(msg, "violates FOREIGN KEY") {
+		t.Errorf("update to a missing key: %s", msg)
+	}
+	if msg := db.DropTableTx("customers"); !strings.Contains(msg, "referenced by FOREIGN KEY") {
+		t.Errorf("drop of a referenced table: %s", msg)
+	}
+
+	// Deleting bob cascades to order 20, which notes still references
+	if msg := db.DeleteTx("customers", 1); !strings.Contains(msg, "violates FOREIGN KEY") {
+		t.Errorf("delete restricted by a cascaded row: %s", msg)
+	}
+	// Deleting ann takes orders 10 and 11 with her
+	if msg := db.DeleteTx("customers", 0); isDeleteError(msg) {
+		t.Fatalf("cascading delete: %s", msg)
+	}
+
+	check := func(db *Database) {
+		t.Helper()
+		if got, want := db.SelectAll("orders"), "id | customer_id\n20 | 2\n30 | NULL\n"; got != want {
+			t.Errorf("orders:\n got %q\nwant %q", got, want)
+		}
+		if got := db.ShowCreateTable("orders"); !strings.Contains(got, "FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE") {
+			t.Errorf("SHOW CREATE TABLE: %s", got)
+		}
+		if msg := db.InsertTx("orders", []string{"50", "1"}); !strings.Contains(msg, "violates FOREIGN KEY") {
+			t.Errorf("foreign key lost: %s", msg)
+		}
+	}
+	check(db)
+	db.Close()
+
+	// The constraints survive a restart
+	db = NewDatabase(dir)
+	defer db.Close()
+	check(db)
+}
+
+func TestForeignKeysInTransaction(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("parents", []string{"id INT"})
+	db.CreateTable("children", []string{"parent_id INT", "FOREIGN KEY (parent_id) REFERENCES parents(id) ON DELETE CASCADE"})
+
+	db.InsertTx("parents", []string{"1"})
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	// Rows queued in the transaction can be referenced...
+	db.InsertTx("parents", []string{"2"})
+	if msg := db.InsertTx("children", []string{"2"}); msg != "1 row insert queued in transaction" {
+		t.Fatalf("reference to a queued row: %s", msg)
+	}
+	if msg := db.InsertTx("children", []string{"1"}); msg != "1 row insert queued in transaction" {
+		t.Fatalf("reference to a queued row: %s", msg)
+	}
+	// ...but not deleted by a cascade before they are committed
+	if msg := db.DeleteTx("parents", 0); msg != "Error: cannot delete children rows inserted in this transaction by ON DELETE CASCADE; commit them first" {
+		t.Errorf("cascade to a queued row: %s", msg)
+	}
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := db.SelectAll("children"), "parent_id\n2\n1\n"; got != want {
+		t.Errorf("children:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestParseForeignKey(t *testing.T) {
+	fk, err := parseForeignKey(`foreign key ("Customer Id") references Customers(id) on delete restrict`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ForeignKey{Column: "Customer Id", RefTable: "customers", RefColumn: "id"}); fk != want {
+		t.Errorf("got %+v, want %+v", fk, want)
+	}
+	for spec, want := range map[string]string{
+		"FOREIGN KEY (a, b) REFERENCES t(a, b)":          "foreign keys of several columns are not supported",
+		"FOREIGN KEY (a) REFERENCES t(a) ON DELETE NULL": "ON DELETE",
+		"FOREIGN KEY a REFERENCES t(a)":                  "invalid foreign key",
+	} {
+		if _, err := parseForeignKey(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseForeignKey(%q) = %v, want an error containing %q", spec, err, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// internal/storage/geo.go
+//
+// Points and bounding boxes. A POINT column holds x, y coordinates, written
+// as '1.5 2', '1.5,2', '(1.5, 2)' or 'POINT(1.5 2)' and stored as (1.5, 2).
+// WHERE location WITHIN BOX(x1, y1, x2, y2) matches the points inside a box,
+// edges included. An index on a POINT column also keeps a grid index: the
+// plane is cut into square cells, sized when the index is built so that its
+// points spread over about as many cells as there are points, and a box
+// query reads only the rows in the cells it overlaps.
+package storage
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Box is an axis-aligned rectangle, edges included
+type Box struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// NewBox returns the box with corners (x1, y1) and (x2, y2), in any order
+func NewBox(x1, y1, x2, y2 float64) Box {
+	return Box{MinX: math.Min(x1, x2), MinY: math.Min(y1, y2), MaxX: math.Max(x1, x2), MaxY: math.Max(y1, y2)}
+}
+
+// Contains reports whether the point (x, y) lies in the box
+func (b Box) Contains(x, y float64) bool {
+	return x >= b.MinX && x <= b.MaxX && y >= b.MinY && y <= b.MaxY
+}
+
+// String returns the box as WITHIN BOX takes it
+func (b Box) String() string {
+	return "BOX(" + formatCoord(b.MinX) + ", " + formatCoord(b.MinY) + ", " + formatCoord(b.MaxX) + ", " + formatCoord(b.MaxY) + ")"
+}
+
+// ParsePoint parses a point written as x y, x,y, (x, y) or POINT(x y)
+func ParsePoint(s string) (x, y float64, ok bool) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 5 && strings.EqualFold(s[:5], "POINT") {
+		s = strings.TrimSpace(s[5:])
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	coords := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	if len(coords) != 2 {
+		return 0, 0, false
+	}
+	x, errX := strconv.ParseFloat(coords[0], 64)
+	y, errY := strconv.ParseFloat(coords[1], 64)
+	if errX != nil || errY != nil || !finite(x) || !finite(y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// formatPoint returns the stored form of a point
+func formatPoint(x, y float64) string {
+	return "(" + formatCoord(x) + ", " + formatCoord(y) + ")"
+}
+
+func formatCoord(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func finite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+// boxMatcher is implemented by WHERE expressions that only match rows whose
+// column lies in a box, such as location WITHIN BOX(0, 0, 10, 10) AND ...
+type boxMatcher interface {
+	WithinBox(column string) (Box, bool)
+}
+
+// gridCell is a cell of a grid index, numbered from the origin
+type gridCell struct {
+	x, y int64
+}
+
+// gridIndex maps grid cells to the IDs of the rows whose point lies in them
+type gridIndex struct {
+	size  float64
+	cells map[gridCell][]int
+}
+
+// maxGridCell bounds cell numbers so that far-off points do not overflow
+const maxGridCell = 1 << 52
+
+// cellOf returns the cell holding (x, y)
+func (g *gridIndex) cellOf(x, y float64) gridCell {
+	clamp := func(f float64) int64 {
+		return int64(math.Max(-maxGridCell, math.Min(maxGridCell, math.Floor(f/g.size))))
+	}
+	return gridCell{clamp(x), clamp(y)}
+}
+
+// insert adds a row's point to the grid
+func (g *gridIndex) insert(x, y float64, id int) {
+	c := g.cellOf(x, y)
+	g.cells[c] = append(g.cells[c], id)
+}
+
+// search returns the IDs of the rows in the cells box overlaps. Their
+// points may still lie outside the box.
+func (g *gridIndex) search(box Box) []int {
+	lo, hi := g.cellOf(box.MinX, box.MinY), g.cellOf(box.MaxX, box.MaxY)
+	var ids []int
+	// A box wider than the populated cells is cheaper to answer from them
+	if float64(hi.x-lo.x+1)*float64(hi.y-lo.y+1) > float64(len(g.cells)) {
+		for c, cell := range g.cells {
+			if c.x >= lo.x && c.x <= hi.x && c.y >= lo.y && c.y <= hi.y {
+				ids = append(ids, cell...)
+			}
+		}
+		return ids
+	}
+	for x := lo.x; x <= hi.x; x++ {
+		for y := lo.y; y <= hi.y; y++ {
+			ids = append(ids, g.cells[gridCell{x, y}]...)
+		}
+	}
+	return ids
+}
+
+// buildGridForColumn rebuilds the grid index of an indexed POINT column,
+// or drops it for a column of any other type. The caller holds table.lock.
+func (db *Database) buildGridForColumn(table *Table, columnName string) {
+	colIdx := table.columnIndex(columnName)
+	if colIdx < 0 || table.Type(columnName) != TypePoint {
+		delete(table.grids, columnName)
+		return
+	}
+
+	type point struct {
+		x, y float64
+		id   int
+	}
+	var points []point
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for ri, row := range table.Rows {
+		if colIdx >= len(row) {
+			continue
+		}
+		if x, y, ok := ParsePoint(row[colIdx]); ok {
+			points = append(points, point{x, y, int(table.RowIDs[ri])})
+			minX, minY, maxX, maxY = math.Min(minX, x), math.Min(minY, y), math.Max(maxX, x), math.Max(maxY, y)
+		}
+	}
+
+	// About one point per cell: sqrt(n) cells along the longer side
+	size := 1.0
+	if extent := math.Max(maxX-minX, maxY-minY); len(points) > 1 && extent > 0 {
+		size = extent / math.Ceil(math.Sqrt(float64(len(points))))
+	}
+	g := &gridIndex{size: size, cells: make(map[gridCell][]int)}
+	for _, p := range points {
+		g.insert(p.x, p.y, p.id)
+	}
+	if table.grids == nil {
+		table.grids = make(map[string]*gridIndex)
+	}
+	table.grids[columnName] = g
+}
+
+// gridInsert adds the row at rowIndex to the grid index of column. The
+// caller holds table.lock exclusively.
+func (table *Table) gridInsert(column string, rowIndex int) {
+	g := table.grids[column]
+	colIdx := table.columnIndex(column)
+	if g == nil || colIdx < 0 || colIdx >= len(table.Rows[rowIndex]) {
+		return
+	}
+	if x, y, ok := ParsePoint(table.Rows[rowIndex][colIdx]); ok {
+		g.insert(x, y, int(table.RowIDs[rowIndex]))
+	}
+}
+
+// gridRows returns the rows in the cells of the grid on path.column that
+// path.box overlaps and that match, in table order. The caller holds
+// table.lock shared.
+func gridRows(table *Table, path accessPath, match func([]string) (bool, error)) ([]int, error) {
+	ids := table.grids[path.column].search(path.box)
+	candidates := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if ri, ok := table.rowPosition(int64(id)); ok {
+			candidates = append(candidates, ri)
+		}
+	}
+	return matchCandidates(table, candidates, match)
+}
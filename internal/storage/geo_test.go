@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParsePoint(t *testing.T) {
+	for _, s := range []string{"1.5 2", "1.5,2", " (1.5, 2) ", "POINT(1.5 2)", "point (1.5,2)"} {
+		x, y, ok := ParsePoint(s)
+		if !ok || x != 1.5 || y != 2 {
+			t.Errorf("ParsePoint(%q) = %v, %v, %v", s, x, y, ok)
+		}
+	}
+	for _, s := range []string{"", "1", "1 2 3", "a b", "(1, NaN)", "1 Inf", "POINT()"} {
+		if _, _, ok := ParsePoint(s); ok {
+			t.Errorf("ParsePoint(%q) should fail", s)
+		}
+	}
+	if got := NewBox(4, -1, 0, 2.5).String(); got != "BOX(0, -1, 4, 2.5)" {
+		t.Errorf("NewBox should order its corners: %s", got)
+	}
+}
+
+// withinBox is a WHERE column WITHIN box for SelectQuery
+type withinBox struct {
+	column string
+	box    Box
+}
+
+func (w withinBox) EvaluateExpression(row []string, columnIndexes map[string]int) (bool, error) {
+	x, y, ok := ParsePoint(row[columnIndexes[w.column]])
+	return ok && w.box.Contains(x, y), nil
+}
+
+func (w withinBox) WithinBox(column string) (Box, bool) {
+	return w.box, column == w.column
+}
+
+func TestGridIndex(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	db.CreateTable("places", []string{"id INT", "location POINT"})
+	rng := rand.New(rand.NewSource(1))
+	insert := func(id int) {
+		x, y := rng.Float64()*200-100, rng.Float64()*200-100
+		if msg := db.Insert("places", []string{fmt.Sprint(id), fmt.Sprintf("%g %g", x, y)}); !strings.HasPrefix(msg, "1 row inserted") {
+			t.Fatal(msg)
+		}
+	}
+	for i := 0; i < 300; i++ {
+		insert(i)
+	}
+	db.Insert("places", []string{"300", "NULL"})
+
+	boxes := []Box{NewBox(-10, -10, 10, 10), NewBox(50, -100, 100, 0), NewBox(-1000, -1000, 1000, 1000), NewBox(0, 0, 0.001, 0.001)}
+	// bruteForce filters every row by hand
+	bruteForce := func(box Box) string {
+		lines := strings.Split(db.SelectQuery("places", Query{OrderBy: "id", Limit: -1}), "\n")
+		out := lines[0] + "\n"
+		for _, line := range lines[1 : len(lines)-1] {
+			_, point, _ := strings.Cut(line, " | ")
+			if x, y, ok := ParsePoint(point); ok && box.Contains(x, y) {
+				out += line + "\n"
+			}
+		}
+		if out == lines[0]+"\n" {
+			out += "(no rows)\n"
+		}
+		return out
+	}
+
+	db.CreateIndex("places", "location")
+	// Rows inserted after the grid was built are added to it
+	for i := 301; i < 400; i++ {
+		insert(i)
+	}
+	check := func(when string) {
+		t.Helper()
+		for _, box := range boxes {
+			q := Query{Where: withinBox{"location", box}, OrderBy: "id", Limit: -1}
+			if plan := db.Explain("places", q); !strings.Contains(plan, "Grid Index Lookup on places using location") {
+				t.Errorf("%s: box %s not read through the grid:\n%s", when, box, plan)
+			}
+			if got, want := db.SelectQuery("places", q), bruteForce(box); got != want {
+				t.Errorf("%s: grid result for %s differs from a scan:\n got %q\nwant %q", when, box, got, want)
+			}
+		}
+	}
+	check("after inserts")
+	if got := db.SelectQuery("places", Query{Where: withinBox{"location", boxes[2]}, Limit: -1}); strings.Count(got, "\n") != 400 {
+		t.Errorf("expected 399 points and the header, got %d lines", strings.Count(got, "\n"))
+	}
+	db.Close()
+
+	// The grid is rebuilt with the other indexes on load
+	db = NewDatabase(dir)
+	defer db.Close()
+	check("after restart")
+}
@@ -0,0 +1,194 @@
+// internal/storage/group.go
+//
+// GROUP BY. SELECT city, COUNT(*), SUM(total) FROM orders GROUP BY city
+// returns one row per distinct combination of the GROUP BY columns, with
+// each aggregate computed over the rows of its group. Group values compare
+// like DISTINCT compares them, under the column's collation, and NULLs form
+// one group. A group shows its columns as its first row holds them, and
+// groups come in the order their first rows appear unless ORDER BY sorts
+// them by their result columns.
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GroupQuery is a SELECT ... GROUP BY
+type GroupQuery struct {
+	// GroupBy lists the columns rows are grouped by
+	GroupBy []string
+	// Results lists the result columns: aggregates, and GROUP BY columns
+	// given as an Aggregate with no Func
+	Results []Aggregate
+	// Where is a parsed WHERE expression (see SelectWhereAdvanced), or nil
+	Where interface{}
+	// OrderBy sorts the groups by result columns, named by their headings
+	OrderBy []SortKey
+	// Limit caps the number of groups returned; negative means no limit
+	Limit int
+	// Role masks the columns masked for it in the result (see mask.go)
+	Role string
+}
+
+// group accumulates the aggregates of one group
+type group struct {
+	// first is the group's first row
+	first  []string
+	states []*aggState
+}
+
+// groupColumn is a result column of a grouped query: the table column col,
+// or the aggregate agg when col is -1
+type groupColumn struct {
+	col  int
+	agg  int
+	coll *Collation
+}
+
+// SelectGroupsResult returns one row per group of the rows of a table, or of the
+// rows matching q.Where, holding the result columns of q
+func (db *Database) SelectGroupsResult(tableName string, q GroupQuery) (*ResultSet, string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if msg := table.externalError(); msg != "" {
+		return nil, msg
+	}
+	table.stats.reads.Add(1)
+
+	keys := make([]orderKey, len(q.GroupBy))
+	for i, column := range q.GroupBy {
+		idx := table.columnIndex(column)
+		if idx < 0 {
+			return nil, fmt.Sprintf("Column %s not found", column)
+		}
+		keys[i] = orderKey{col: idx, coll: table.Collation(table.Columns[idx])}
+	}
+
+	var aggs []Aggregate
+	columns := make([]groupColumn, len(q.Results))
+	for i, r := range q.Results {
+		if r.Func != "" {
+			columns[i] = groupColumn{col: -1, agg: len(aggs)}
+			aggs = append(aggs, r)
+			continue
+		}
+		idx := table.columnIndex(r.Column)
+		if idx < 0 {
+			return nil, fmt.Sprintf("Column %s not found", r.Column)
+		}
+		if !slices.ContainsFunc(keys, func(k orderKey) bool { return k.col == idx }) {
+			return nil, fmt.Sprintf("Error: column %s must appear in GROUP BY or be used in an aggregate", table.Columns[idx])
+		}
+		columns[i] = groupColumn{col: idx, agg: -1, coll: table.Collation(table.Columns[idx])}
+	}
+	template, msg := table.aggStates(aggs)
+	if msg != "" {
+		return nil, msg
+	}
+	labels := make([]string, len(columns))
+	types := make([]ColumnType, len(columns))
+	for i, c := range columns {
+		if c.col >= 0 {
+			labels[i] = table.Columns[c.col]
+			types[i] = table.Type(labels[i])
+			continue
+		}
+		s := template[c.agg]
+		labels[i] = s.Label()
+		types[i] = table.aggType(s)
+		if s.Func == AggMin || s.Func == AggMax {
+			columns[i].coll = s.coll
+		}
+	}
+
+	var rows [][]string
+	var matched []int
+	if q.Where != nil {
+		if rows, matched, msg = db.whereRows(table, q.Where); msg != "" {
+			return nil, msg
+		}
+	} else {
+		rows = db.visibleRows(table)
+		matched = make([]int, len(rows))
+		for i := range matched {
+			matched[i] = i
+		}
+	}
+
+	groupKey := rowOrder{rows: rows, keys: keys}
+	groups := make(map[string]*group)
+	var order []*group
+	for _, ri := range matched {
+		key := string(groupKey.rowKey(ri))
+		g := groups[key]
+		if g == nil {
+			g = &group{first: rows[ri], states: make([]*aggState, len(template))}
+			for i, s := range template {
+				fresh := *s
+				g.states[i] = &fresh
+			}
+			groups[key] = g
+			order = append(order, g)
+		}
+		aggregateRow(g.states, rows[ri])
+	}
+
+	masks := make([]func(string) string, len(columns))
+	for i, c := range columns {
+		if c.col >= 0 {
+			masks[i] = table.columnMask(table.Columns[c.col], q.Role)
+		}
+	}
+	results := make([][]string, len(order))
+	for gi, g := range order {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			if c.col < 0 {
+				s := g.states[c.agg]
+				if s.err != nil {
+					return nil, fmt.Sprintf("Error: %v", s.err)
+				}
+				row[i] = table.aggResult(s, q.Role)
+				continue
+			}
+			row[i] = g.first[c.col]
+			if masks[i] != nil && !IsNull(row[i]) {
+				row[i] = masks[i](row[i])
+			}
+		}
+		results[gi] = row
+	}
+
+	indexes := make([]int, len(results))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if len(q.OrderBy) > 0 {
+		sorted := rowOrder{rows: results}
+		for _, key := range q.OrderBy {
+			i := slices.IndexFunc(labels, func(label string) bool { return strings.EqualFold(label, key.Column) })
+			if i < 0 {
+				return nil, fmt.Sprintf("Column %s not found", key.Column)
+			}
+			sorted.keys = append(sorted.keys, orderKey{col: i, coll: columns[i].coll, desc: key.Desc})
+		}
+		sort.Slice(indexes, func(a, b int) bool { return sorted.less(indexes[a], indexes[b]) })
+	}
+	if q.Limit >= 0 && len(indexes) > q.Limit {
+		indexes = indexes[:q.Limit]
+	}
+	rs := newResultSet(labels, results, indexes)
+	rs.Types = types
+	return rs, ""
+}
+
+// SelectGroups is SelectGroupsResult rendered as text
+func (db *Database) SelectGroups(tableName string, q GroupQuery) string {
+	return resultText(db.SelectGroupsResult(tableName, q))
+}
@@ -0,0 +1,50 @@
+package storage
+
+import "testing"
+
+func TestSelectGroups(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("visits", []string{"id", "city COLLATE NOCASE", "email", "ms"})
+	for _, row := range [][]string{
+		{"1", "Paris", "a@x", "120"},
+		{"2", "Lyon", "b@x", "80"},
+		{"3", "paris", "c@x", "100"},
+		{"4", NullValue, "d@x", "90"},
+		{"5", NullValue, "e@x", "x"},
+	} {
+		db.Insert("visits", row)
+	}
+
+	for _, tc := range []struct {
+		name string
+		q    GroupQuery
+		want string
+	}{
+		// NOCASE puts Paris and paris in one group, shown as its first row
+		// has it, and NULLs form a group of their own
+		{"collation", GroupQuery{GroupBy: []string{"city"}, Results: []Aggregate{{Column: "CITY"}, {Func: AggCount, Column: "*"}, {Func: "min", Column: "email"}}, Limit: -1},
+			"city | count | min(email)\nParis | 2 | a@x\nLyon | 1 | b@x\nNULL | 2 | d@x\n"},
+		{"order and limit", GroupQuery{GroupBy: []string{"city"}, Results: []Aggregate{{Column: "city"}, {Func: AggCount, Column: "*"}},
+			OrderBy: []SortKey{{Column: "COUNT", Desc: true}, {Column: "city"}}, Limit: 2},
+			"city | count\nNULL | 2\nParis | 2\n"},
+		{"where", GroupQuery{GroupBy: []string{"city"}, Results: []Aggregate{{Column: "city"}, {Func: AggSum, Column: "ms"}}, Where: &equalWhere{col: "id", value: "2"}, Limit: -1},
+			"city | sum(ms)\nLyon | 80\n"},
+		{"error in one group", GroupQuery{GroupBy: []string{"city"}, Results: []Aggregate{{Func: AggSum, Column: "ms"}}, Limit: -1},
+			"Error: sum(ms): x is not a number"},
+		{"ungrouped column", GroupQuery{GroupBy: []string{"city"}, Results: []Aggregate{{Column: "email"}}, Limit: -1},
+			"Error: column email must appear in GROUP BY or be used in an aggregate"},
+		{"unknown order column", GroupQuery{GroupBy: []string{"city"}, Results: []Aggregate{{Column: "city"}}, OrderBy: []SortKey{{Column: "ms"}}, Limit: -1},
+			"Column ms not found"},
+	} {
+		if got := db.SelectGroups("visits", tc.q); got != tc.want {
+			t.Errorf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+
+	// Masked group columns and MIN or MAX are masked in the result
+	db.SetMask("visits", "email", "user", MaskFull)
+	q := GroupQuery{GroupBy: []string{"email"}, Results: []Aggregate{{Column: "email"}, {Func: AggMax, Column: "email"}}, Role: "user", Limit: 1}
+	if got, want := db.SelectGroups("visits", q), "email | max(email)\n**** | ****\n"; got != want {
+		t.Errorf("masked: %q, want %q", got, want)
+	}
+}
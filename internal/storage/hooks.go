@@ -0,0 +1,123 @@
+// internal/storage/hooks.go
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// InsertHook is called before a row is inserted. Returning an error rejects
+// the insert.
+type InsertHook func(table string, values []string) error
+
+// UpdateHook is called before a row is updated. Returning an error rejects
+// the update.
+type UpdateHook func(table string, rowIndex int, oldValues, newValues []string) error
+
+// DeleteHook is called before a row is deleted. Returning an error rejects
+// the delete.
+type DeleteHook func(table string, rowIndex int, oldValues []string) error
+
+// CommitHook is called after changes are committed: once per transaction
+// with all of its changes, or once per statement outside a transaction with
+// an empty txID
+type CommitHook func(txID string, changes []ChangeEvent)
+
+// ErrRejectedByHook is returned when a hook rejects a write
+const ErrRejectedByHook = "Error: %s on %s rejected: %v"
+
+// hooks holds the callbacks registered by an embedding application
+type hooks struct {
+	mu       sync.RWMutex
+	onInsert []InsertHook
+	onUpdate []UpdateHook
+	onDelete []DeleteHook
+	onCommit []CommitHook
+}
+
+// OnInsert registers a hook run before every insert, inside or outside a
+// transaction. Hooks run while the table is locked and must not write to
+// the database.
+func (db *Database) OnInsert(fn InsertHook) {
+	db.hooks.mu.Lock()
+	defer db.hooks.mu.Unlock()
+	db.hooks.onInsert = append(db.hooks.onInsert, fn)
+}
+
+// OnUpdate registers a hook run before every update
+func (db *Database) OnUpdate(fn UpdateHook) {
+	db.hooks.mu.Lock()
+	defer db.hooks.mu.Unlock()
+	db.hooks.onUpdate = append(db.hooks.onUpdate, fn)
+}
+
+// OnDelete registers a hook run before every delete
+func (db *Database) OnDelete(fn DeleteHook) {
+	db.hooks.mu.Lock()
+	defer db.hooks.mu.Unlock()
+	db.hooks.onDelete = append(db.hooks.onDelete, fn)
+}
+
+// OnCommit registers a hook run after changes are committed. Commit hooks
+// cannot undo the changes; they suit cache invalidation and auditing.
+func (db *Database) OnCommit(fn CommitHook) {
+	db.hooks.mu.Lock()
+	defer db.hooks.mu.Unlock()
+	db.hooks.onCommit = append(db.hooks.onCommit, fn)
+}
+
+// runInsertHooks runs the insert hooks and returns the rejection message of
+// the first that fails, or ""
+func (db *Database) runInsertHooks(tableName string, values []string) string {
+	db.hooks.mu.RLock()
+	defer db.hooks.mu.RUnlock()
+	for _, fn := range db.hooks.onInsert {
+		if err := fn(tableName, values); err != nil {
+			return fmt.Sprintf(ErrRejectedByHook, "insert", tableName, err)
+		}
+	}
+	return ""
+}
+
+// runUpdateHooks runs the update hooks like runInsertHooks
+func (db *Database) runUpdateHooks(tableName string, rowIndex int, oldValues, newValues []string) string {
+	db.hooks.mu.RLock()
+	defer db.hooks.mu.RUnlock()
+	for _, fn := range db.hooks.onUpdate {
+		if err := fn(tableName, rowIndex, oldValues, newValues); err != nil {
+			return fmt.Sprintf(ErrRejectedByHook, "update", tableName, err)
+		}
+	}
+	return ""
+}
+
+// runDeleteHooks runs the delete hooks like runInsertHooks
+func (db *Database) runDeleteHooks(tableName string, rowIndex int, oldValues []string) string {
+	db.hooks.mu.RLock()
+	defer db.hooks.mu.RUnlock()
+	for _, fn := range db.hooks.onDelete {
+		if err := fn(tableName, rowIndex, oldValues); err != nil {
+			return fmt.Sprintf(ErrRejectedByHook, "delete", tableName, err)
+		}
+	}
+	return ""
+}
+
+// runCommitHooks passes committed changes to the commit hooks
+func (db *Database) runCommitHooks(txID string, changes []ChangeEvent) {
+	if len(changes) == 0 {
+		return
+	}
+	db.hooks.mu.RLock()
+	defer db.hooks.mu.RUnlock()
+	for _, fn := range db.hooks.onCommit {
+		fn(txID, changes)
+	}
+}
+
+// hasCommitHooks reports whether any commit hook is registered
+func (db *Database) hasCommitHooks() bool {
+	db.hooks.mu.RLock()
+	defer db.hooks.mu.RUnlock()
+	return len(db.hooks.onCommit) > 0
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteHooks(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	_ = db.CreateTable("users", []string{"id", "name"})
+
+	var log []string
+	db.OnInsert(func(table string, values []string) error {
+		if values[1] == "" {
+			return errors.New("name is required")
+		}
+		log = append(log, "insert "+table+" "+strings.Join(values, ","))
+		return nil
+	})
+	db.OnUpdate(func(table string, rowIndex int, oldValues, newValues []string) error {
+		log = append(log, "update "+oldValues[1]+"->"+newValues[1])
+		return nil
+	})
+	db.OnDelete(func(table string, rowIndex int, oldValues []string) error {
+		if oldValues[1] == "root" {
+			return errors.New("root cannot be deleted")
+		}
+		log = append(log, "delete "+oldValues[1])
+		return nil
+	})
+
+	_ = db.Insert("users", []string{"1", "alice"})
+	_ = db.Insert("users", []string{"2", "root"})
+	if msg := db.Insert("users", []string{"3", ""}); msg != "Error: insert on users rejected: name is required" {
+		t.Fatalf("rejected insert: %s", msg)
+	}
+	_ = db.Update("users", 0, []string{"1", "alicia"})
+	if msg := db.Delete("users", 1); !strings.Contains(msg, "root cannot be deleted") {
+		t.Fatalf("rejected delete: %s", msg)
+	}
+	_ = db.Delete("users", 0)
+
+	want := []string{"insert users 1,alice", "insert users 2,root", "update alice->alicia", "delete alicia"}
+	if strings.Join(log, "|") != strings.Join(want, "|") {
+		t.Errorf("hooks ran %q, want %q", log, want)
+	}
+	rows := db.Tables["users"].Rows
+	if len(rows) != 1 || rows[0][1] != "root" {
+		t.Errorf("rows after rejected writes: %v", rows)
+	}
+}
+
+func TestCommitHooks(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	_ = db.CreateTable("users", []string{"id", "name"})
+
+	type commit struct {
+		txID string
+		ops  []string
+	}
+	var commits []commit
+	db.OnCommit(func(txID string, changes []ChangeEvent) {
+		c := commit{txID: txID}
+		for _, ev := range changes {
+			c.ops = append(c.ops, ev.Op+" "+ev.Table)
+		}
+		commits = append(commits, c)
+	})
+
+	_ = db.Insert("users", []string{"1", "alice"})
+
+	tx, err := db.BeginTransaction(ReadCommitted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.InsertTx("users", []string{"2", "bob"})
+	_ = db.UpdateTx("users", 0, []string{"1", "alicia"})
+	if len(commits) != 1 {
+		t.Fatalf("commit hook ran before COMMIT: %v", commits)
+	}
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rolled back transaction is never reported
+	_, _ = db.BeginTransaction(ReadCommitted)
+	_ = db.InsertTx("users", []string{"3", "carol"})
+	_ = db.RollbackTransaction()
+
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2: %v", len(commits), commits)
+	}
+	if commits[0].txID != "" || strings.Join(commits[0].ops, ",") != "INSERT users" {
+		t.Errorf("autocommit insert: %+v", commits[0])
+	}
+	if commits[1].txID != tx.ID || strings.Join(commits[1].ops, ",") != "INSERT users,UPDATE users" {
+		t.Errorf("transaction: %+v", commits[1])
+	}
+}
+
+func TestInsertHookInTransaction(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	_ = db.CreateTable("users", []string{"id", "name"})
+	db.OnInsert(func(table string, values []string) error {
+		if values[0] == "0" {
+			return errors.New("id must be positive")
+		}
+		return nil
+	})
+
+	_, _ = db.BeginTransaction(ReadCommitted)
+	if msg := db.InsertTx("users", []string{"0", "zero"}); !strings.HasPrefix(msg, "Error: insert on users rejected") {
+		t.Fatalf("rejected insert in transaction: %s", msg)
+	}
+	_ = db.InsertTx("users", []string{"1", "one"})
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if rows := db.Tables["users"].Rows; len(rows) != 1 || rows[0][0] != "1" {
+		t.Errorf("rows: %v", rows)
+	}
+}
@@ -0,0 +1,140 @@
+// internal/storage/ident.go
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// UnquoteIdentifier returns the name an identifier refers to. A double-quoted
+// identifier such as "order" or "first name" has its quotes removed, with two
+// quotes inside standing for one; an unquoted identifier is returned as is.
+func UnquoteIdentifier(ident string) (string, error) {
+	if !strings.HasPrefix(ident, `"`) {
+		if strings.Contains(ident, `"`) {
+			return "", fmt.Errorf("invalid identifier %s", ident)
+		}
+		return ident, nil
+	}
+	name, rest, err := cutQuotedIdentifier(ident)
+	if err != nil {
+		return "", err
+	}
+	if rest != "" {
+		return "", fmt.Errorf("unexpected %q after identifier %s", rest, ident[:len(ident)-len(rest)])
+	}
+	return name, nil
+}
+
+// reservedWords are the keywords that name a table or column only when
+// quoted, as in "order"
+var reservedWords = map[string]bool{
+	"ADD": true, "ALL": true, "ALTER": true, "AND": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASE": true, "CHECK": true, "COLLATE": true,
+	"COLUMN": true, "CREATE": true, "CROSS": true, "DEFAULT": true, "DELETE": true,
+	"DESC": true, "DISTINCT": true, "DROP": true, "ELSE": true, "END": true,
+	"ESCAPE": true, "EXISTS": true, "FALSE": true, "FOREIGN": true, "FROM": true,
+	"FULL": true, "GROUP": true, "HAVING": true, "ILIKE": true, "IN": true,
+	"INDEX": true, "INNER": true, "INSERT": true, "INTO": true, "IS": true,
+	"JOIN": true, "LEFT": true, "LIKE": true, "LIMIT": true, "NOT": true,
+	"NULL": true, "OFFSET": true, "ON": true, "OR": true, "ORDER": true,
+	"OUTER": true, "PRIMARY": true, "REFERENCES": true, "RIGHT": true,
+	"ROW": true, "SELECT": true, "SET": true, "TABLE": true, "THEN": true,
+	"TRUE": true, "UNION": true, "UNIQUE": true, "UNKNOWN": true, "UPDATE": true,
+	"VALUES": true, "WHEN": true, "WHERE": true, "WITH": true, "WITHIN": true,
+}
+
+// IsReservedWord reports whether name is a keyword, which must be quoted to
+// name a table or column
+func IsReservedWord(name string) bool {
+	return reservedWords[strings.ToUpper(name)]
+}
+
+// QuoteIdentifier returns name as it must be written in a statement:
+// unchanged if it is a plain word, otherwise double-quoted. Reserved words
+// are quoted too.
+func QuoteIdentifier(name string) string {
+	plain := name != "" && !IsReservedWord(name)
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// cutQuotedIdentifier parses the double-quoted identifier at the start of s
+// and returns its name and the text after it
+func cutQuotedIdentifier(s string) (name, rest string, err error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			b.WriteByte('"')
+			i++
+			continue
+		}
+		if b.Len() == 0 {
+			return "", "", fmt.Errorf("empty identifier")
+		}
+		return b.String(), s[i+1:], nil
+	}
+	return "", "", fmt.Errorf("unterminated identifier %s", s)
+}
+
+// columnIndex returns the index of a column, or -1. Column names are
+// case-insensitive; an exact match wins, so columns of older tables that
+// differ only in case stay addressable.
+func (t *Table) columnIndex(name string) int {
+	found := -1
+	for i, c := range t.Columns {
+		if c == name {
+			return i
+		}
+		if found < 0 && strings.EqualFold(c, name) {
+			found = i
+		}
+	}
+	return found
+}
+
+// resolveColumn returns a column's name as the table declares it, or name
+// unchanged if the table has no such column
+func (t *Table) resolveColumn(name string) string {
+	if i := t.columnIndex(name); i >= 0 {
+		return t.Columns[i]
+	}
+	return name
+}
+
+// typeSetter is implemented by WHERE expressions that write the values they
+// compare typed columns with in the columns' canonical form, so that
+// '2025-01-15' finds the TIMESTAMP 2025-01-15T00:00:00.000000Z
+type typeSetter interface {
+	SetTypes(map[string]ColumnType)
+}
+
+// bindWhere prepares a WHERE expression for evaluation against table: it
+// resolves column names to their declared spelling and sets each column's
+// collation and type
+func bindWhere(whereExpr interface{}, table *Table) {
+	if expr, ok := whereExpr.(interface{ ResolveColumns(func(string) string) }); ok {
+		expr.ResolveColumns(table.resolveColumn)
+	}
+	if expr, ok := whereExpr.(typeSetter); ok {
+		expr.SetTypes(table.Types)
+	}
+	if expr, ok := whereExpr.(interface {
+		SetCollations(map[string]*Collation)
+	}); ok {
+		expr.SetCollations(table.Collations)
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnquoteIdentifier(t *testing.T) {
+	for in, want := range map[string]string{
+		`users`:        "users",
+		`"order"`:      "order",
+		`"First Name"`: "First Name",
+		`"say ""hi"""`: `say "hi"`,
+		`"a,b (c)"`:    "a,b (c)",
+	} {
+		got, err := UnquoteIdentifier(in)
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{`""`, `"open`, `"a"b`, `a"b`} {
+		if _, err := UnquoteIdentifier(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for in, want := range map[string]string{
+		"users":      "users",
+		"_id2":       "_id2",
+		"First Name": `"First Name"`,
+		"2nd":        `"2nd"`,
+		`say "hi"`:   `"say ""hi"""`,
+		"from":       `"from"`,
+		"Select":     `"Select"`,
+		"selection":  "selection",
+	} {
+		if got := QuoteIdentifier(in); got != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+		if got, err := UnquoteIdentifier(QuoteIdentifier(in)); err != nil || got != in {
+			t.Errorf("%s: round trip gave %q, %v", in, got, err)
+		}
+	}
+}
+
+func TestQuotedColumnSpecs(t *testing.T) {
+	defs, err := parseColumnSpecs([]string{`"First Name" COLLATE NOCASE`, ` id `, `"select"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"First Name", "id", "select"}; !reflect.DeepEqual(defs.names, want) {
+		t.Errorf("got %q, want %q", defs.names, want)
+	}
+	if defs.collations["First Name"] == nil {
+		t.Error("collation of quoted column was lost")
+	}
+
+	for _, bad := range [][]string{{"id", "ID"}, {`"a"b`}, {`"open`}, {"select TEXT"}, {"id", "where"}} {
+		if _, err := parseColumnSpecs(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
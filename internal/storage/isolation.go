@@ -0,0 +1,170 @@
+// internal/storage/isolation.go
+//
+// Transaction isolation. READ UNCOMMITTED and READ COMMITTED read the latest
+// committed rows on every statement (other transactions' writes are queued
+// until they commit, so nothing uncommitted is ever visible). REPEATABLE READ
+// and SERIALIZABLE read a snapshot taken at BEGIN: because rows are
+// copy-on-write, the snapshot is just each table's published row slice.
+//
+// At COMMIT, a REPEATABLE READ transaction fails if another transaction
+// updated or deleted a row it updates or deletes, instead of overwriting that
+// change. While a snapshot transaction is open, every update and delete
+// records the table version that changed the row, and a row whose version is
+// newer than the snapshot was changed concurrently. SERIALIZABLE also fails
+// if any table it read or wrote changed; those conflicts are detected per
+// table, so they can be false positives.
+package storage
+
+import "fmt"
+
+// tableSnapshot is a table's committed rows as of a transaction's start
+type tableSnapshot struct {
+	rows    [][]string
+	ids     []int64
+	version uint64
+}
+
+// takeSnapshot records every table's published rows for tx
+func (db *Database) takeSnapshot(tx *Transaction) {
+	db.catalog.RLock()
+	defer db.catalog.RUnlock()
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.snapshot = make(map[string]tableSnapshot, len(db.Tables))
+	tx.reads = make(map[string]bool)
+	for name, table := range db.Tables {
+		// Read the version first: a write racing with the snapshot then only
+		// causes a spurious conflict, never a missed one. Reading it under the
+		// lock means every later write sees snapshotTxs and records its row.
+		table.lock.RLock()
+		version := table.version.Load()
+		table.lock.RUnlock()
+		rows, ids := table.rowSetView()
+		tx.snapshot[name] = tableSnapshot{rows: rows, ids: ids, version: version}
+	}
+}
+
+// visibleRows returns the committed rows the current statement should see:
+// the transaction's snapshot under REPEATABLE READ and SERIALIZABLE, otherwise
+// the latest published rows. Tables created after the snapshot was taken are
+// read as they are now.
+func (db *Database) visibleRows(table *Table) [][]string {
+	rows, _ := db.visibleRowSet(table)
+	return rows
+}
+
+// visibleRowSet is visibleRows that also returns the rows' IDs
+func (db *Database) visibleRowSet(table *Table) ([][]string, []int64) {
+	if snap, ok := db.snapshotOf(table); ok {
+		return snap.rows, snap.ids
+	}
+	return table.rowSetView()
+}
+
+// snapshotOf returns the current transaction's snapshot of table and records
+// the read
+func (db *Database) snapshotOf(table *Table) (tableSnapshot, bool) {
+	tx := db.currentTransaction
+	if tx == nil {
+		return tableSnapshot{}, false
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	snap, ok := tx.snapshot[table.Name]
+	if ok {
+		tx.reads[table.Name] = true
+	}
+	return snap, ok
+}
+
+// readsCurrentRows reports whether reads of table see its current rows, so
+// its indexes, which always describe the current rows, may be used
+func (db *Database) readsCurrentRows(table *Table) bool {
+	snap, ok := db.snapshotOf(table)
+	return !ok || snap.version == table.version.Load()
+}
+
+// noteRowWrite records that the row at rowIndex is about to be updated or
+// deleted, so that snapshot transactions writing it too fail at commit.
+// Nothing is recorded while no snapshot transaction is open. The caller
+// holds table.lock exclusively.
+func (db *Database) noteRowWrite(table *Table, rowIndex int) {
+	if db.snapshotTxs.Load() == 0 {
+		return
+	}
+	if table.rowVersions == nil {
+		table.rowVersions = make(map[int64]uint64)
+	}
+	// The write publishes the next version
+	table.rowVersions[table.RowIDs[rowIndex]] = table.version.Load() + 1
+}
+
+// forgetRowVersions drops the recorded row versions once the last snapshot
+// transaction has ended
+func (db *Database) forgetRowVersions() {
+	db.catalog.RLock()
+	defer db.catalog.RUnlock()
+	for _, table := range db.Tables {
+		table.lock.Lock()
+		if db.snapshotTxs.Load() == 0 {
+			table.rowVersions = nil
+		}
+		table.lock.Unlock()
+	}
+}
+
+// rowChangedSince reports whether the row with ID id was updated or deleted
+// after version
+func (table *Table) rowChangedSince(id int64, version uint64) bool {
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+	return table.rowVersions[id] > version
+}
+
+// checkConflicts returns an error if rows or tables tx depends on changed
+// after its snapshot. The caller holds tx.mu.
+func (tm *TransactionManager) checkConflicts(tx *Transaction) error {
+	if tx.snapshot == nil {
+		return nil
+	}
+
+	for _, op := range tx.Operations {
+		if op.Type != WAL_UPDATE && op.Type != WAL_DELETE {
+			continue
+		}
+		snap, ok := tx.snapshot[op.TableName]
+		data, _ := op.Data.(map[string]interface{})
+		target, hasTarget := operationTarget(data)
+		if !ok || !hasTarget || !target.byID {
+			continue
+		}
+		table, exists := tm.db.lookupTable(op.TableName)
+		if !exists || table.rowChangedSince(target.id, snap.version) {
+			return fmt.Errorf("could not serialize access: row ID %d of table %s was changed by another transaction", target.id, op.TableName)
+		}
+	}
+	if tx.IsolationLevel != Serializable {
+		return nil
+	}
+
+	depends := make(map[string]bool)
+	for _, op := range tx.Operations {
+		depends[op.TableName] = true
+	}
+	for name := range tx.reads {
+		depends[name] = true
+	}
+
+	for name := range depends {
+		snap, ok := tx.snapshot[name]
+		if !ok {
+			continue
+		}
+		table, exists := tm.db.lookupTable(name)
+		if !exists || table.version.Load() != snap.version {
+			return fmt.Errorf("could not serialize access: table %s was changed by another transaction", name)
+		}
+	}
+	return nil
+}
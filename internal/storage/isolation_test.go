@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsolationLevels(t *testing.T) {
+	setup := func(t *testing.T) *Database {
+		db := NewDatabase(t.TempDir())
+		db.CreateTable("accounts", []string{"id", "balance"})
+		db.CreateTable("audit", []string{"id"})
+		db.CreateIndex("accounts", "balance")
+		db.Insert("accounts", []string{"1", "100"})
+		return db
+	}
+
+	t.Run("ReadCommittedSeesNewCommits", func(t *testing.T) {
+		db := setup(t)
+		db.BeginTransaction(ReadCommitted)
+		db.Insert("accounts", []string{"2", "50"})
+		if got := db.SelectCount("accounts", nil); got != "count\n2\n" {
+			t.Errorf("READ COMMITTED should see the committed insert, got %q", got)
+		}
+		if err := db.CommitTransaction(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("RepeatableReadUsesSnapshot", func(t *testing.T) {
+		db := setup(t)
+		db.BeginTransaction(RepeatableRead)
+		db.Insert("accounts", []string{"2", "50"})
+		db.InsertTx("audit", []string{"a1"})
+
+		if got := db.SelectAll("accounts"); strings.Contains(got, "50") {
+			t.Errorf("snapshot should not show the later insert:\n%s", got)
+		}
+		if got := db.SelectCount("accounts", nil); got != "count\n1\n" {
+			t.Errorf("snapshot count = %q", got)
+		}
+		if got := db.SelectQuery("accounts", Query{OrderBy: "balance", Limit: -1}); strings.Contains(got, "50") {
+			t.Errorf("ordered read should not use the index of changed rows:\n%s", got)
+		}
+		if got := db.SelectAll("audit"); !strings.Contains(got, "a1") {
+			t.Errorf("a transaction sees its own writes:\n%s", got)
+		}
+		// Inserting into a table someone else changed does not conflict
+		if err := db.CommitTransaction(); err != nil {
+			t.Fatalf("commit failed: %v", err)
+		}
+		if got := db.SelectCount("accounts", nil); got != "count\n2\n" {
+			t.Errorf("after commit the new row is visible, got %q", got)
+		}
+	})
+
+	t.Run("RepeatableReadWriteConflict", func(t *testing.T) {
+		db := setup(t)
+		db.BeginTransaction(RepeatableRead)
+		if got := db.UpdateTx("accounts", 0, []string{"1", "90"}); !strings.Contains(got, "queued") {
+			t.Fatalf("update not queued: %s", got)
+		}
+		db.Update("accounts", 0, []string{"1", "80"})
+
+		err := db.CommitTransaction()
+		if err == nil || !strings.Contains(err.Error(), "could not serialize") {
+			t.Fatalf("expected serialization failure, got %v", err)
+		}
+		if db.GetCurrentTransaction() != nil {
+			t.Error("a failed commit should end the transaction")
+		}
+		if got := db.SelectAll("accounts"); !strings.Contains(got, "1 | 80") {
+			t.Errorf("the other transaction's update should survive:\n%s", got)
+		}
+	})
+
+	t.Run("RepeatableReadOtherRowDoesNotConflict", func(t *testing.T) {
+		db := setup(t)
+		db.Insert("accounts", []string{"2", "50"})
+		db.BeginTransaction(RepeatableRead)
+		db.UpdateTx("accounts", 1, []string{"2", "40"})
+		db.Update("accounts", 0, []string{"1", "80"})
+
+		if err := db.CommitTransaction(); err != nil {
+			t.Fatalf("updates of different rows should not conflict: %v", err)
+		}
+		if got := db.SelectAll("accounts"); got != "id | balance\n1 | 80\n2 | 40\n" {
+			t.Errorf("both updates should apply:\n%s", got)
+		}
+		table, _ := db.lookupTable("accounts")
+		if table.rowVersions != nil {
+			t.Error("row versions should be forgotten once no snapshot transaction is open")
+		}
+	})
+
+	t.Run("RepeatableReadDeleteConflict", func(t *testing.T) {
+		db := setup(t)
+		db.Insert("accounts", []string{"2", "50"})
+		db.BeginTransaction(RepeatableRead)
+		db.DeleteTx("accounts", 1)
+		// Shifts row 1 to position 0 and changes it
+		db.Delete("accounts", 0)
+		db.Update("accounts", 0, []string{"2", "60"})
+
+		err := db.CommitTransaction()
+		if err == nil || !strings.Contains(err.Error(), "row ID 2 of table accounts") {
+			t.Fatalf("expected a conflict on row ID 2, got %v", err)
+		}
+		if got := db.SelectAll("accounts"); got != "id | balance\n2 | 60\n" {
+			t.Errorf("the concurrent update should survive:\n%s", got)
+		}
+	})
+
+	t.Run("ReadCommittedLastWriteWins", func(t *testing.T) {
+		db := setup(t)
+		db.BeginTransaction(ReadCommitted)
+		db.UpdateTx("accounts", 0, []string{"1", "90"})
+		db.Update("accounts", 0, []string{"1", "80"})
+		if err := db.CommitTransaction(); err != nil {
+			t.Fatal(err)
+		}
+		if got := db.SelectAll("accounts"); got != "id | balance\n1 | 90\n" {
+			t.Errorf("READ COMMITTED applies its update:\n%s", got)
+		}
+	})
+
+	t.Run("SerializableReadConflict", func(t *testing.T) {
+		db := setup(t)
+		db.BeginTransaction(Serializable)
+		db.SelectAll("accounts")
+		db.InsertTx("audit", []string{"read accounts"})
+		db.Insert("accounts", []string{"2", "50"})
+
+		if err := db.CommitTransaction(); err == nil || !strings.Contains(err.Error(), "accounts") {
+			t.Fatalf("expected a conflict on accounts, got %v", err)
+		}
+		if got := db.SelectCount("audit", nil); got != "count\n0\n" {
+			t.Errorf("the failed transaction should apply nothing, got %q", got)
+		}
+
+		db.BeginTransaction(Serializable)
+		db.SelectAll("accounts")
+		db.InsertTx("audit", []string{"ok"})
+		if err := db.CommitTransaction(); err != nil {
+			t.Errorf("commit without concurrent changes failed: %v", err)
+		}
+	})
+}
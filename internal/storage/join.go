@@ -0,0 +1,413 @@
+// internal/storage/join.go
+//
+// SELECT from several tables. FROM a, b and FROM a CROSS JOIN b pair every
+// row of a with every row of b, and WHERE keeps the pairs that match. A
+// joined row holds the columns of each table in FROM order, named
+// qualifier.column after the table's alias, or its name when it has none;
+// an unqualified name refers to the one table that has such a column. A
+// comparison with a qualified column, as in WHERE o.customer_id = c.id,
+// compares the two columns of the joined row.
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JoinTable is a table of a multi-table FROM
+type JoinTable struct {
+	Name string
+	// Qualifier is the table's alias, or its name when it has none
+	Qualifier string
+}
+
+// valueColumnResolver is implemented by WHERE expressions that can compare
+// a column with another column instead of a value
+type valueColumnResolver interface {
+	ResolveValueColumns(func(string) (string, bool))
+}
+
+// joinPlan is a multi-table query resolved against its tables
+type joinPlan struct {
+	tables []*Table
+	from   []JoinTable
+	// offsets holds the index of each table's first joined column
+	offsets []int
+	// columns names every joined column qualifier.column
+	columns       []string
+	columnIndexes map[string]int
+	collations    map[string]*Collation
+	types         map[string]ColumnType
+}
+
+// resolveJoin looks up the tables of a multi-table FROM
+func (db *Database) resolveJoin(from []JoinTable) (*joinPlan, string) {
+	p := &joinPlan{from: from, columnIndexes: map[string]int{}, collations: map[string]*Collation{}, types: map[string]ColumnType{}}
+	for _, jt := range from {
+		table, exists := db.lookupTable(strings.ToLower(jt.Name))
+		if !exists {
+			return nil, fmt.Sprintf(ErrTableNotFound, strings.ToLower(jt.Name))
+		}
+		if msg := table.externalError(); msg != "" {
+			return nil, msg
+		}
+		p.tables = append(p.tables, table)
+		p.offsets = append(p.offsets, len(p.columns))
+		for _, col := range table.Columns {
+			name := jt.Qualifier + "." + col
+			p.columnIndexes[name] = len(p.columns)
+			p.collations[name] = table.Collation(col)
+			if ct := table.Type(col); ct != "" {
+				p.types[name] = ct
+			}
+			p.columns = append(p.columns, name)
+		}
+	}
+	return p, ""
+}
+
+// column returns the index of the joined column ref names, or an error
+// message
+func (p *joinPlan) column(ref string) (int, string) {
+	if qualifier, name, found := strings.Cut(ref, "."); found {
+		for i, jt := range p.from {
+			if strings.EqualFold(jt.Qualifier, qualifier) {
+				idx := p.tables[i].columnIndex(name)
+				if idx < 0 {
+					return -1, fmt.Sprintf("Column %s not found", ref)
+				}
+				return p.offsets[i] + idx, ""
+			}
+		}
+		return -1, fmt.Sprintf("Error: unknown table %s in %s", qualifier, ref)
+	}
+	found := -1
+	for i, table := range p.tables {
+		if idx := table.columnIndex(ref); idx >= 0 {
+			if found >= 0 {
+				return -1, fmt.Sprintf("Error: column %s is ambiguous; qualify it with its table", ref)
+			}
+			found = p.offsets[i] + idx
+		}
+	}
+	if found < 0 {
+		return -1, fmt.Sprintf("Column %s not found", ref)
+	}
+	return found, ""
+}
+
+// name returns the unqualified name of joined column idx
+func (p *joinPlan) name(idx int) string {
+	own := p.tableOf(idx)
+	return p.tables[own].Columns[idx-p.offsets[own]]
+}
+
+// label returns the heading of joined column idx in the result of an
+// unqualified *: its name, or its qualified name when another table has a
+// column of the same name
+func (p *joinPlan) label(idx int) string {
+	own, name := p.tableOf(idx), p.name(idx)
+	for i, table := range p.tables {
+		if i != own && table.columnIndex(name) >= 0 {
+			return p.columns[idx]
+		}
+	}
+	return name
+}
+
+// tableOf returns the position in FROM of the table joined column idx
+// belongs to
+func (p *joinPlan) tableOf(idx int) int {
+	for i := len(p.offsets) - 1; i > 0; i-- {
+		if idx >= p.offsets[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+// bindWhere resolves the column references of a WHERE expression against
+// the joined columns
+func (p *joinPlan) bindWhere(whereExpr interface{}) string {
+	var msg string
+	if expr, ok := whereExpr.(interface{ ResolveColumns(func(string) string) }); ok {
+		expr.ResolveColumns(func(ref string) string {
+			idx, err := p.column(ref)
+			if err != "" {
+				if msg == "" {
+					msg = err
+				}
+				return ref
+			}
+			return p.columns[idx]
+		})
+	}
+	if expr, ok := whereExpr.(valueColumnResolver); ok {
+		expr.ResolveValueColumns(func(value string) (string, bool) {
+			if !strings.Contains(value, ".") {
+				return "", false
+			}
+			idx, err := p.column(value)
+			if err != "" {
+				return "", false
+			}
+			return p.columns[idx], true
+		})
+	}
+	if expr, ok := whereExpr.(typeSetter); ok {
+		expr.SetTypes(p.types)
+	}
+	if expr, ok := whereExpr.(interface {
+		SetCollations(map[string]*Collation)
+	}); ok {
+		expr.SetCollations(p.collations)
+	}
+	return msg
+}
+
+// output resolves the result columns of a query, returning the joined
+// column index and heading of each, and the computed columns by position,
+// whose index is -1
+func (p *joinPlan) output(columns []ResultColumn) ([]int, []string, map[int]valueEvaluator, string) {
+	var output []int
+	var labels []string
+	exprs := map[int]valueEvaluator{}
+	for _, rc := range columns {
+		if rc.Name == "*" {
+			for idx := range p.columns {
+				switch {
+				case rc.Table == "":
+					output = append(output, idx)
+					labels = append(labels, p.label(idx))
+				case strings.EqualFold(p.from[p.tableOf(idx)].Qualifier, rc.Table):
+					output = append(output, idx)
+					labels = append(labels, p.name(idx))
+				}
+			}
+			continue
+		}
+		if rc.Expr != nil {
+			expr, ok := rc.Expr.(valueEvaluator)
+			if !ok {
+				return nil, nil, nil, "Invalid result column expression type"
+			}
+			var msg string
+			expr.ResolveColumns(func(ref string) string {
+				idx, err := p.column(ref)
+				if err != "" {
+					if msg == "" {
+						msg = err
+					}
+					return ref
+				}
+				return p.columns[idx]
+			})
+			if msg != "" {
+				return nil, nil, nil, msg
+			}
+			label := rc.As
+			if label == "" {
+				label = rc.Name
+			}
+			exprs[len(output)] = expr
+			output = append(output, -1)
+			labels = append(labels, label)
+			continue
+		}
+		ref := rc.Name
+		if rc.Table != "" {
+			ref = rc.Table + "." + rc.Name
+		}
+		idx, msg := p.column(ref)
+		if msg != "" {
+			return nil, nil, nil, msg
+		}
+		label := rc.As
+		if label == "" {
+			label = p.name(idx)
+		}
+		output = append(output, idx)
+		labels = append(labels, label)
+	}
+	return output, labels, exprs, ""
+}
+
+// SelectJoinResult returns the rows of the cartesian product of the from tables
+// that match q. Its result columns may be qualified with ResultColumn.Table,
+// and its WHERE and ORDER BY columns written qualifier.column.
+func (db *Database) SelectJoinResult(from []JoinTable, q Query) (*ResultSet, string) {
+	if q.RowIDs {
+		return nil, "Error: ROWID is not available when selecting from several tables"
+	}
+	p, msg := db.resolveJoin(from)
+	if msg != "" {
+		return nil, msg
+	}
+
+	var where rowEvaluator
+	if q.Where != nil {
+		var ok bool
+		if where, ok = q.Where.(rowEvaluator); !ok {
+			return nil, "Invalid WHERE expression type"
+		}
+		if msg := p.bindWhere(q.Where); msg != "" {
+			return nil, msg
+		}
+	}
+	var order []orderKey
+	if q.OrderBy != "" {
+		for _, key := range append([]SortKey{{Column: q.OrderBy, Desc: q.Desc}}, q.ThenBy...) {
+			idx, msg := p.column(key.Column)
+			if msg != "" {
+				return nil, msg
+			}
+			order = append(order, orderKey{col: idx, coll: p.collations[p.columns[idx]], desc: key.Desc})
+		}
+	}
+	columns := q.Columns
+	if columns == nil {
+		columns = []ResultColumn{{Name: "*"}}
+	}
+	output, labels, exprs, msg := p.output(columns)
+	if msg != "" {
+		return nil, msg
+	}
+
+	sources := make([][][]string, len(p.tables))
+	for i, table := range p.tables {
+		sources[i], _ = db.visibleRowSet(table)
+		table.stats.reads.Add(1)
+	}
+	// Without ORDER BY or DISTINCT the product can stop at LIMIT
+	stopAt := -1
+	if order == nil && !q.Distinct {
+		stopAt = q.Limit
+	}
+	rows, err := joinRows(sources, len(p.columns), stopAt, func(row []string) (bool, error) {
+		if where == nil {
+			return true, nil
+		}
+		return where.EvaluateExpression(row, p.columnIndexes)
+	})
+	if err != nil {
+		return nil, fmt.Sprintf("Error evaluating WHERE condition: %v", err)
+	}
+
+	matched := make([]int, len(rows))
+	for i := range matched {
+		matched[i] = i
+	}
+	if order != nil {
+		if matched, err = externalSort(rows, matched, rowOrder{rows: rows, keys: order}, &memoryBudget{limit: db.QueryMemoryBudget}); err != nil {
+			return nil, fmt.Sprintf("Error sorting rows: %v", err)
+		}
+	}
+
+	masks := make([]func([]string) []string, len(p.tables))
+	for i, table := range p.tables {
+		masks[i] = table.masker(q.Role)
+	}
+	var distinct *distinctFilter
+	if q.Distinct {
+		distinct = &distinctFilter{seen: make(map[string]struct{})}
+		for i, idx := range output {
+			key := orderKey{col: i}
+			if idx >= 0 {
+				key.coll = p.collations[p.columns[idx]]
+			}
+			distinct.keys = append(distinct.keys, key)
+		}
+	}
+	var result [][]string
+	for _, ri := range matched {
+		if q.Limit >= 0 && len(result) >= q.Limit {
+			break
+		}
+		row := p.mask(rows[ri], masks)
+		out := make([]string, len(output))
+		for i, idx := range output {
+			if idx < 0 {
+				out[i] = exprs[i].EvaluateValue(row, p.columnIndexes)
+				continue
+			}
+			out[i] = row[idx]
+		}
+		if distinct == nil || distinct.first(out) {
+			result = append(result, out)
+		}
+	}
+	types := make([]ColumnType, len(output))
+	for i, idx := range output {
+		if idx >= 0 {
+			types[i] = p.types[p.columns[idx]]
+		}
+	}
+	return &ResultSet{Columns: labels, Rows: result, Types: types}, ""
+}
+
+// SelectJoin is SelectJoinResult rendered as text
+func (db *Database) SelectJoin(from []JoinTable, q Query) string {
+	return resultText(db.SelectJoinResult(from, q))
+}
+
+// mask masks each table's part of a joined row with its masker
+func (p *joinPlan) mask(row []string, masks []func([]string) []string) []string {
+	var masked []string
+	for i, mask := range masks {
+		if mask == nil {
+			continue
+		}
+		if masked == nil {
+			masked = append([]string(nil), row...)
+		}
+		end := len(row)
+		if i+1 < len(p.offsets) {
+			end = p.offsets[i+1]
+		}
+		copy(masked[p.offsets[i]:end], mask(row[p.offsets[i]:end]))
+	}
+	if masked == nil {
+		return row
+	}
+	return masked
+}
+
+// joinRows returns the rows of the cartesian product of sources, each width
+// columns wide, that match, in the order of the first table's rows, then the
+// second's and so on, stopping at limit unless it is negative
+func joinRows(sources [][][]string, width, limit int, match func([]string) (bool, error)) ([][]string, error) {
+	for _, rows := range sources {
+		if len(rows) == 0 {
+			return nil, nil
+		}
+	}
+	var result [][]string
+	pos := make([]int, len(sources))
+	for {
+		if limit >= 0 && len(result) >= limit {
+			return result, nil
+		}
+		row := make([]string, 0, width)
+		for i, rows := range sources {
+			row = append(row, rows[pos[i]]...)
+		}
+		ok, err := match(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, row)
+		}
+		// Advance the last table fastest
+		i := len(pos) - 1
+		for ; i >= 0; i-- {
+			if pos[i]++; pos[i] < len(sources[i]) {
+				break
+			}
+			pos[i] = 0
+		}
+		if i < 0 {
+			return result, nil
+		}
+	}
+}
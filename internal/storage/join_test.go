@@ -0,0 +1,41 @@
+package storage
+
+import "testing"
+
+func TestSelectJoin(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("users", []string{"id", "email"})
+	_ = db.CreateTable("tags", []string{"id", "tag"})
+	db.Insert("users", []string{"1", "ann@example.com"})
+	db.Insert("users", []string{"2", "bob@example.com"})
+	db.Insert("tags", []string{"1", "x"})
+	db.Insert("tags", []string{"2", "y"})
+	db.Insert("tags", []string{"3", "z"})
+	db.SetMask("users", "email", "readonly", MaskFull)
+	from := []JoinTable{{Name: "users", Qualifier: "u"}, {Name: "tags", Qualifier: "tags"}}
+
+	got := db.SelectJoin(from, Query{Limit: -1, Where: &equalWhere{col: "tags.tag", value: "y"}})
+	if want := "u.id | email | tags.id | tag\n1 | ann@example.com | 2 | y\n2 | bob@example.com | 2 | y\n"; got != want {
+		t.Errorf("WHERE:\n%q\nwant %q", got, want)
+	}
+
+	got = db.SelectJoin(from, Query{
+		Columns: []ResultColumn{{Name: "email", As: "who"}, {Name: "tag"}},
+		OrderBy: "tag", Desc: true, ThenBy: []SortKey{{Column: "u.id", Desc: true}},
+		Limit: 3, Role: "readonly",
+	})
+	if want := "who | tag\n**** | z\n**** | z\n**** | y\n"; got != want {
+		t.Errorf("masked, ordered:\n%q\nwant %q", got, want)
+	}
+
+	if got := db.SelectJoin(from, Query{Limit: 2}); got != "u.id | email | tags.id | tag\n1 | ann@example.com | 1 | x\n1 | ann@example.com | 2 | y\n" {
+		t.Errorf("LIMIT: %q", got)
+	}
+	if got := db.SelectJoin(from, Query{Limit: -1, OrderBy: "id"}); got != "Error: column id is ambiguous; qualify it with its table" {
+		t.Errorf("ambiguous ORDER BY: %q", got)
+	}
+	if got := db.SelectJoin(from, Query{Limit: -1, Columns: []ResultColumn{{Name: "id", Table: "users"}}}); got != "Error: unknown table users in users.id" {
+		t.Errorf("aliased table by name: %q", got)
+	}
+}
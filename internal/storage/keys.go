@@ -0,0 +1,271 @@
+// internal/storage/keys.go
+//
+// Table encryption keys. Encrypted tables are sealed with AES-256-GCM under
+// a key from the data directory's keyring, keys.json, and record the ID of
+// that key in their table file and page manifest. Tables only pay for
+// encryption when they ask for it: CREATE TABLE ... WITH (encrypted=true),
+// or every new table when the database default is on. REKEY moves tables to
+// a new key and removes the keys no table uses any more.
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyringName is the file in the data directory holding encryption keys
+const KeyringName = "keys.json"
+
+// EncryptionKey is a key in the keyring
+type EncryptionKey struct {
+	ID      string    `json:"id"`
+	Key     []byte    `json:"key"`
+	Created time.Time `json:"created"`
+	// Current is set by Keys on the key new encrypted tables use
+	Current bool `json:"-"`
+}
+
+// Keyring holds the keys encrypted tables are sealed with
+type Keyring struct {
+	path string
+	mu   sync.Mutex
+	keys map[string]*EncryptionKey
+	// current is the ID of the key new encrypted tables use, "" before the
+	// first one is created
+	current string
+}
+
+// onDiskKeyring is the JSON layout of keys.json
+type onDiskKeyring struct {
+	Current string           `json:"current"`
+	Keys    []*EncryptionKey `json:"keys"`
+}
+
+// loadKeyring reads the keyring of a data directory; a missing file is an
+// empty keyring
+func loadKeyring(dataDir string) (*Keyring, error) {
+	k := &Keyring{path: filepath.Join(dataDir, KeyringName), keys: make(map[string]*EncryptionKey)}
+	data, err := os.ReadFile(k.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return k, nil
+	}
+	if err != nil {
+		return k, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	var disk onDiskKeyring
+	if err := json.Unmarshal(data, &disk); err != nil {
+		return k, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+	for _, key := range disk.Keys {
+		if len(key.Key) != 32 {
+			return k, fmt.Errorf("key %s in keyring is not a 256-bit key", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+	k.current = disk.Current
+	return k, nil
+}
+
+// Keys returns every key in the keyring, oldest first, without the secrets
+func (k *Keyring) Keys() []EncryptionKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := make([]EncryptionKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, EncryptionKey{ID: key.ID, Created: key.Created, Current: key.ID == k.current})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyNumber(keys[i].ID) < keyNumber(keys[j].ID) })
+	return keys
+}
+
+// Current returns the ID of the key new encrypted tables use, creating the
+// first key when there is none
+func (k *Keyring) Current() (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.current != "" {
+		return k.current, nil
+	}
+	return k.rotateLocked()
+}
+
+// Rotate creates a key and makes it current
+func (k *Keyring) Rotate() (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.rotateLocked()
+}
+
+// rotateLocked is Rotate with k.mu held
+func (k *Keyring) rotateLocked() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	next := 1
+	for id := range k.keys {
+		if n := keyNumber(id); n >= next {
+			next = n + 1
+		}
+	}
+	key := &EncryptionKey{ID: "k" + strconv.Itoa(next), Key: secret, Created: time.Now().UTC()}
+	previous := k.current
+	k.keys[key.ID] = key
+	k.current = key.ID
+	if err := k.saveLocked(); err != nil {
+		delete(k.keys, key.ID)
+		k.current = previous
+		return "", err
+	}
+	return key.ID, nil
+}
+
+// Retire removes every key except the current one and those in inUse
+func (k *Keyring) Retire(inUse map[string]bool) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var retired []string
+	for id := range k.keys {
+		if id != k.current && !inUse[id] {
+			retired = append(retired, id)
+		}
+	}
+	if len(retired) == 0 {
+		return nil, nil
+	}
+	removed := make(map[string]*EncryptionKey, len(retired))
+	for _, id := range retired {
+		removed[id] = k.keys[id]
+		delete(k.keys, id)
+	}
+	if err := k.saveLocked(); err != nil {
+		for id, key := range removed {
+			k.keys[id] = key
+		}
+		return nil, err
+	}
+	sort.Slice(retired, func(i, j int) bool { return keyNumber(retired[i]) < keyNumber(retired[j]) })
+	return retired, nil
+}
+
+// seal encrypts plaintext with key id, returning the nonce followed by the
+// ciphertext. The key ID is authenticated with it.
+func (k *Keyring) seal(id string, plaintext []byte) ([]byte, error) {
+	gcm, err := k.cipher(id)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, []byte(id)), nil
+}
+
+// open decrypts data sealed with key id
+func (k *Keyring) open(id string, data []byte) ([]byte, error) {
+	gcm, err := k.cipher(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %s: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// cipher returns the AES-GCM cipher of key id
+func (k *Keyring) cipher(id string) (cipher.AEAD, error) {
+	k.mu.Lock()
+	key, ok := k.keys[id]
+	k.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("encryption key %s is not in %s", id, KeyringName)
+	}
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// saveLocked atomically replaces keys.json, readable by the owner only. The
+// caller holds k.mu.
+func (k *Keyring) saveLocked() error {
+	disk := onDiskKeyring{Current: k.current}
+	for _, key := range k.keys {
+		disk.Keys = append(disk.Keys, key)
+	}
+	sort.Slice(disk.Keys, func(i, j int) bool { return keyNumber(disk.Keys[i].ID) < keyNumber(disk.Keys[j].ID) })
+	data, err := json.MarshalIndent(&disk, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal keyring: %w", err)
+	}
+	tmp := k.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	return syncDir(filepath.Dir(k.path))
+}
+
+// keyNumber returns the sequence number in a key ID such as k3
+func keyNumber(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "k"))
+	return n
+}
+
+// has reports whether key id is in the keyring
+func (k *Keyring) has(id string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	_, ok := k.keys[id]
+	return ok
+}
+
+// localKeyID returns the key a replayed table created with key id is sealed
+// with: id itself, or the current key when the WAL came from a server with
+// another keyring
+func (db *Database) localKeyID(id string) string {
+	if id == "" || db.Keys.has(id) {
+		return id
+	}
+	current, err := db.Keys.Current()
+	if err != nil {
+		log.Printf("Warning: failed to create encryption key: %v\n", err)
+		return id
+	}
+	return current
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyringSealAndRetire(t *testing.T) {
+	dir := t.TempDir()
+	k, err := loadKeyring(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := k.Current()
+	if err != nil || first != "k1" {
+		t.Fatalf("first key %q: %v", first, err)
+	}
+	sealed, err := k.seal(first, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := k.Rotate()
+	if err != nil || second != "k2" {
+		t.Fatalf("rotated key %q: %v", second, err)
+	}
+
+	// The key ID is authenticated with the data
+	if _, err := k.open(second, sealed); err == nil {
+		t.Error("opened data with the wrong key")
+	}
+	reloaded, err := loadKeyring(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := reloaded.open(first, sealed); err != nil || string(plain) != "hello" {
+		t.Fatalf("open after reload: %q %v", plain, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, KeyringName)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("keyring mode: %v %v", info.Mode(), err)
+	}
+
+	retired, err := reloaded.Retire(map[string]bool{})
+	if err != nil || len(retired) != 1 || retired[0] != "k1" {
+		t.Fatalf("retired %v: %v", retired, err)
+	}
+	if _, err := reloaded.open(first, sealed); err == nil {
+		t.Error("opened data with a retired key")
+	}
+}
+
+func TestEncryptedTableWithoutKey(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	db.CreateTableWithOptionsTx("secrets", []string{"id"}, TableOptions{Encrypted: true})
+	db.Insert("secrets", []string{"1"})
+	db.Close()
+
+	if err := os.Remove(filepath.Join(dir, KeyringName)); err != nil {
+		t.Fatal(err)
+	}
+	db = NewDatabase(dir)
+	defer db.Close()
+	if _, ok := db.lookupTable("secrets"); ok {
+		t.Fatal("table loaded without its key")
+	}
+	if got := db.CreateTableTx("secrets", []string{"id"}); !strings.HasPrefix(got, "Error: table secrets exists but cannot be decrypted") {
+		t.Errorf("create over unreadable table: %q", got)
+	}
+}
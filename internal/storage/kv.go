@@ -0,0 +1,126 @@
+// internal/storage/kv.go
+//
+// Key-value commands. KV SET, KV GET and KV DEL keep string values under
+// string keys in the system table _kv, created by the first KV SET with a
+// key and a value column and an index on key. It is an ordinary table, so
+// its writes go through the WAL and it is backed up, replicated and
+// queryable with SELECT like any other, but only the KV commands write to
+// it. KV writes take effect at once; they are not part of transactions.
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+// KVTable is the system table holding the KV commands' keys
+const KVTable = "_kv"
+
+// kvColumns are the columns of KVTable
+var kvColumns = []string{"key", "value"}
+
+// KVGet returns the value stored under key
+func (db *Database) KVGet(key string) (string, bool, error) {
+	table, err := db.lookupKVTable()
+	if table == nil {
+		return "", false, err
+	}
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+	_, value, found := table.kvFind(key)
+	return value, found, nil
+}
+
+// KVSet stores value under key, replacing any value stored before
+func (db *Database) KVSet(key, value string) error {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	db.kvMu.Lock()
+	defer db.kvMu.Unlock()
+
+	table, err := db.kvTable()
+	if err != nil {
+		return err
+	}
+	table.lock.RLock()
+	id, _, found := table.kvFind(key)
+	table.lock.RUnlock()
+	if found {
+		return kvResult(db.update(KVTable, withRowID(id), []string{key, value}, 0), "1 row updated")
+	}
+	return kvResult(db.insert(KVTable, []string{key, value}), "1 row inserted with secure page-based storage")
+}
+
+// KVDelete removes key, reporting whether it was stored
+func (db *Database) KVDelete(key string) (bool, error) {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	db.kvMu.Lock()
+	defer db.kvMu.Unlock()
+
+	table, err := db.lookupKVTable()
+	if table == nil {
+		return false, err
+	}
+	table.lock.RLock()
+	id, _, found := table.kvFind(key)
+	table.lock.RUnlock()
+	if !found {
+		return false, nil
+	}
+	return true, kvResult(db.deleteRow(KVTable, withRowID(id)), "1 row deleted")
+}
+
+// lookupKVTable returns KVTable, or nil before the first KV SET. A table
+// of that name without the KV columns is an error.
+func (db *Database) lookupKVTable() (*Table, error) {
+	table, exists := db.lookupTable(KVTable)
+	if !exists {
+		return nil, nil
+	}
+	if !slices.Equal(table.Columns, kvColumns) {
+		return nil, fmt.Errorf("table %s exists but is not a KV table", KVTable)
+	}
+	return table, nil
+}
+
+// kvTable returns KVTable, creating it and its index on first use. The
+// caller holds db.kvMu and the write gate.
+func (db *Database) kvTable() (*Table, error) {
+	if table, err := db.lookupKVTable(); table != nil || err != nil {
+		return table, err
+	}
+	msg := db.createTable(KVTable, kvColumns, db.DefaultTableOptions())
+	table, exists := db.lookupTable(KVTable)
+	if !exists {
+		return nil, fmt.Errorf("failed to create %s: %s", KVTable, msg)
+	}
+	// Without the index kvFind scans, so a failure here only slows lookups
+	db.createIndex(KVTable, "key")
+	return table, nil
+}
+
+// kvFind returns the row ID and value of key. The caller holds t.lock.
+func (t *Table) kvFind(key string) (int64, string, bool) {
+	if ids, indexed := t.Indexes["key"]; indexed && t.indexesUsable() {
+		for _, id := range ids[t.Collation("key").Key(key)] {
+			if ri, ok := t.rowPosition(int64(id)); ok && t.Rows[ri][0] == key {
+				return int64(id), t.Rows[ri][1], true
+			}
+		}
+		return 0, "", false
+	}
+	if ri := slices.IndexFunc(t.Rows, func(row []string) bool { return row[0] == key }); ri >= 0 {
+		return t.RowIDs[ri], t.Rows[ri][1], true
+	}
+	return 0, "", false
+}
+
+// kvResult turns the message of a write to KVTable into an error unless it
+// is the success message ok
+func kvResult(msg, ok string) error {
+	if msg != ok {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
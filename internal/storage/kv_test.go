@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKV(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	if _, found, err := db.KVGet("missing"); found || err != nil {
+		t.Fatalf("KVGet before any KVSet = %v, %v", found, err)
+	}
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}, {"a", "3"}, {"with space", "x y"}} {
+		if err := db.KVSet(kv[0], kv[1]); err != nil {
+			t.Fatalf("KVSet(%q): %v", kv[0], err)
+		}
+	}
+	if value, found, _ := db.KVGet("a"); !found || value != "3" {
+		t.Errorf("KVGet(a) = %q, %v after replacing it", value, found)
+	}
+	if n := db.SelectCount(KVTable, nil); !strings.Contains(n, "3") {
+		t.Errorf("expected one row per key:\n%s", n)
+	}
+	if plan := db.Explain(KVTable, Query{Where: kvKeyEquals("b"), Limit: -1}); !strings.Contains(plan, "Index") {
+		t.Errorf("key lookups should use the index:\n%s", plan)
+	}
+	if deleted, err := db.KVDelete("b"); !deleted || err != nil {
+		t.Errorf("KVDelete(b) = %v, %v", deleted, err)
+	}
+	if deleted, _ := db.KVDelete("b"); deleted {
+		t.Error("KVDelete of a deleted key reported a delete")
+	}
+	db.Close()
+
+	// Keys are an ordinary table, so they survive a restart
+	db = NewDatabase(dir)
+	defer db.Close()
+	for key, want := range map[string]string{"a": "3", "with space": "x y"} {
+		if value, found, _ := db.KVGet(key); !found || value != want {
+			t.Errorf("after restart KVGet(%q) = %q, %v", key, value, found)
+		}
+	}
+	if _, found, _ := db.KVGet("b"); found {
+		t.Error("deleted key came back after restart")
+	}
+}
+
+func TestKVTableMismatch(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable(KVTable, []string{"id"})
+	if err := db.KVSet("a", "1"); err == nil || !strings.Contains(err.Error(), "not a KV table") {
+		t.Errorf("KVSet into a foreign _kv table: %v", err)
+	}
+	if _, _, err := db.KVGet("a"); err == nil {
+		t.Error("KVGet from a foreign _kv table should fail")
+	}
+}
+
+// kvKeyEquals is a WHERE key = value for Explain
+type kvKeyEquals string
+
+func (k kvKeyEquals) EvaluateExpression(row []string, columnIndexes map[string]int) (bool, error) {
+	return row[columnIndexes["key"]] == string(k), nil
+}
+
+func (k kvKeyEquals) EqualityValue(column string) (string, bool) {
+	return string(k), column == "key"
+}
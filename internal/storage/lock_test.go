@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPerTableLocks(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("analytics", []string{"id", "value"})
+	db.CreateTable("orders", []string{"id", "total"})
+	db.Insert("analytics", []string{"1", "10"})
+
+	// A long-running read on one table must not block writes to another
+	analytics := db.Tables["analytics"]
+	analytics.lock.RLock()
+	done := make(chan string, 1)
+	go func() { done <- db.Insert("orders", []string{"1", "99"}) }()
+	select {
+	case msg := <-done:
+		if !strings.Contains(msg, "inserted") {
+			t.Fatalf("insert failed: %s", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("insert into orders blocked on a read of analytics")
+	}
+
+	// ...and other readers of the same table still proceed
+	if out := db.SelectAll("analytics"); !strings.Contains(out, "1 | 10") {
+		t.Errorf("concurrent read failed: %s", out)
+	}
+
+	// A write to the table being read waits for the reader
+	go func() { done <- db.Insert("analytics", []string{"2", "20"}) }()
+	select {
+	case <-done:
+		t.Fatal("insert into analytics should wait for the shared lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	analytics.lock.RUnlock()
+	if msg := <-done; !strings.Contains(msg, "inserted") {
+		t.Fatalf("insert failed: %s", msg)
+	}
+}
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("events", []string{"id", "kind"})
+	db.CreateIndex("events", "kind")
+
+	for w := 0; w < 4; w++ {
+		db.CreateTable(fmt.Sprintf("events_%d", w), []string{"id"})
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				db.Insert("events", []string{fmt.Sprint(w*100 + i), fmt.Sprint(i % 3)})
+				db.Insert(fmt.Sprintf("events_%d", w), []string{fmt.Sprint(i)})
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				db.SelectWhere("events", "kind", "1")
+				db.SelectQuery("events", Query{OrderBy: "kind", Limit: 5})
+				db.SelectCount("events", nil)
+				db.CreateTable(fmt.Sprintf("scratch_%d_%d", w, i), []string{"id"})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if out := db.SelectCount("events", nil); out != "count\n80\n" {
+		t.Errorf("expected 80 rows, got %q", out)
+	}
+}
+
+func TestCopyOnWriteReads(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("users", []string{"id", "name"})
+	db.Insert("users", []string{"1", "Hareesh"})
+	db.Insert("users", []string{"2", "Asha"})
+	users := db.Tables["users"]
+
+	// A view taken before a write keeps its contents
+	before := users.rowView()
+	db.Update("users", 0, []string{"1", "Renamed"})
+	db.Delete("users", 1)
+	if len(before) != 2 || before[0][1] != "Hareesh" || before[1][1] != "Asha" {
+		t.Errorf("earlier view changed under writes: %v", before)
+	}
+
+	// Scans do not wait for a writer holding the table lock
+	users.lock.Lock()
+	done := make(chan string, 1)
+	go func() { done <- db.SelectAll("users") }()
+	select {
+	case out := <-done:
+		if !strings.Contains(out, "Renamed") || strings.Contains(out, "Asha") {
+			t.Errorf("expected the latest published rows, got:\n%s", out)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SELECT blocked on a writer")
+	}
+	if out := db.SelectCount("users", nil); out != "count\n1\n" {
+		t.Errorf("unexpected count while locked: %q", out)
+	}
+	users.lock.Unlock()
+}
@@ -0,0 +1,169 @@
+// internal/storage/locks.go
+//
+// Lock tracking for SHOW LOCKS. Each table's reader/writer lock records the
+// shared and exclusive locks held on it and those still waited for, with
+// the session that asked for them, so that contention can be seen while it
+// happens. Locks cover whole tables; there are no row locks.
+package storage
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockInfo describes a lock held on a table or waited for
+type LockInfo struct {
+	Table string
+	// Exclusive is set for write locks, clear for shared read locks
+	Exclusive bool
+	// Granted is clear while the lock is waited for
+	Granted bool
+	// Session is the session that asked for the lock, "" when unknown
+	Session string
+	// Waited is how long the lock was (or has so far been) waited for
+	Waited time.Duration
+	// Held is how long a granted lock has been held
+	Held time.Duration
+}
+
+// Mode returns "exclusive" or "shared"
+func (l LockInfo) Mode() string {
+	if l.Exclusive {
+		return "exclusive"
+	}
+	return "shared"
+}
+
+// lockRequest is a lock held on a table or waited for
+type lockRequest struct {
+	session   string
+	exclusive bool
+	granted   bool
+	requested time.Time
+	acquired  time.Time
+}
+
+// tableLock is a table's reader/writer lock, recording its holders and
+// waiters
+type tableLock struct {
+	sync.RWMutex
+	// session names the session locks are taken for; nil for tables not
+	// added to a database
+	session *atomic.Pointer[string]
+
+	mu       sync.Mutex
+	requests []*lockRequest
+}
+
+// Lock takes the lock exclusively
+func (l *tableLock) Lock() {
+	r := l.request(true)
+	l.RWMutex.Lock()
+	l.grant(r)
+}
+
+// Unlock releases an exclusive lock
+func (l *tableLock) Unlock() {
+	l.release(true)
+	l.RWMutex.Unlock()
+}
+
+// RLock takes the lock shared
+func (l *tableLock) RLock() {
+	r := l.request(false)
+	l.RWMutex.RLock()
+	l.grant(r)
+}
+
+// RUnlock releases a shared lock
+func (l *tableLock) RUnlock() {
+	l.release(false)
+	l.RWMutex.RUnlock()
+}
+
+// request records a lock as waited for
+func (l *tableLock) request(exclusive bool) *lockRequest {
+	r := &lockRequest{exclusive: exclusive, requested: time.Now()}
+	if l.session != nil {
+		if s := l.session.Load(); s != nil {
+			r.session = *s
+		}
+	}
+	l.mu.Lock()
+	l.requests = append(l.requests, r)
+	l.mu.Unlock()
+	return r
+}
+
+// grant records a requested lock as held
+func (l *tableLock) grant(r *lockRequest) {
+	l.mu.Lock()
+	r.granted = true
+	r.acquired = time.Now()
+	l.mu.Unlock()
+}
+
+// release forgets a held lock of the given mode. Shared locks are not told
+// apart, so the one held longest is forgotten.
+func (l *tableLock) release(exclusive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, r := range l.requests {
+		if r.granted && r.exclusive == exclusive {
+			l.requests = append(l.requests[:i], l.requests[i+1:]...)
+			return
+		}
+	}
+}
+
+// snapshot returns the locks held on table and waited for, holders first
+func (l *tableLock) snapshot(table string, now time.Time) []LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var locks []LockInfo
+	for _, r := range l.requests {
+		info := LockInfo{Table: table, Exclusive: r.exclusive, Granted: r.granted, Session: r.session}
+		if r.granted {
+			info.Waited = r.acquired.Sub(r.requested)
+			info.Held = now.Sub(r.acquired)
+		} else {
+			info.Waited = now.Sub(r.requested)
+		}
+		locks = append(locks, info)
+	}
+	sort.SliceStable(locks, func(i, j int) bool { return locks[i].Granted && !locks[j].Granted })
+	return locks
+}
+
+// SetLockSession names the session the following table locks are taken
+// for, as shown by Locks
+func (db *Database) SetLockSession(session string) {
+	db.lockSession.Store(&session)
+}
+
+// addTable adds t to the catalog under its name. The caller holds
+// db.catalog exclusively or owns the database.
+func (db *Database) addTable(t *Table) {
+	t.lock.session = &db.lockSession
+	db.Tables[t.Name] = t
+}
+
+// Locks returns the table locks held and waited for, by table name
+func (db *Database) Locks() []LockInfo {
+	db.catalog.RLock()
+	tables := make([]*Table, 0, len(db.Tables))
+	for _, t := range db.Tables {
+		tables = append(tables, t)
+	}
+	db.catalog.RUnlock()
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	now := time.Now()
+	var locks []LockInfo
+	for _, t := range tables {
+		locks = append(locks, t.lock.snapshot(t.Name, now)...)
+	}
+	return locks
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocks(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("orders", []string{"id"})
+
+	db.SetLockSession("ann")
+	table := db.Tables["orders"]
+	table.lock.RLock()
+
+	db.SetLockSession("bob")
+	done := make(chan string, 1)
+	go func() { done <- db.Insert("orders", []string{"1"}) }()
+	var locks []LockInfo
+	for deadline := time.Now().Add(2 * time.Second); len(locks) < 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		locks = db.Locks()
+	}
+	if len(locks) != 2 {
+		t.Fatalf("expected a held and a waiting lock, got %+v", locks)
+	}
+	held, waiting := locks[0], locks[1]
+	if held.Table != "orders" || held.Mode() != "shared" || !held.Granted || held.Session != "ann" {
+		t.Errorf("unexpected held lock %+v", held)
+	}
+	if waiting.Mode() != "exclusive" || waiting.Granted || waiting.Session != "bob" || waiting.Waited <= 0 {
+		t.Errorf("unexpected waiting lock %+v", waiting)
+	}
+
+	table.lock.RUnlock()
+	if msg := <-done; !strings.Contains(msg, "inserted") {
+		t.Fatalf("insert failed: %s", msg)
+	}
+	if locks := db.Locks(); len(locks) != 0 {
+		t.Errorf("locks left after release: %+v", locks)
+	}
+}
@@ -0,0 +1,212 @@
+// internal/storage/mask.go
+//
+// Column masks. A mask redacts a column's values in query results for one
+// role, so users with that role see "j***@example.com" where others see the
+// address. Masks change only what SELECT and cursors return: the stored
+// rows, WHERE conditions and indexes use the real values.
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Mask methods
+const (
+	// MaskFull replaces the whole value with ****
+	MaskFull = "full"
+	// MaskPartial keeps the first character and domain of an email address,
+	// or the last four characters of any other value
+	MaskPartial = "partial"
+	// MaskHash replaces the value with the start of its SHA-256 digest, so
+	// equal values still look equal
+	MaskHash = "hash"
+)
+
+// MaskRoles lists the roles a mask can apply to. Admins always see real
+// values.
+var MaskRoles = []string{"user", "readonly"}
+
+// ColumnMask redacts a column for a role
+type ColumnMask struct {
+	Column string `json:"column"`
+	Role   string `json:"role"`
+	Method string `json:"method"`
+}
+
+// SetMask creates or replaces the mask of a column for a role, or drops it
+// when method is empty
+func (db *Database) SetMask(tableName, column, role, method string) string {
+	role = strings.ToLower(role)
+	method = strings.ToLower(method)
+	if !slices.Contains(MaskRoles, role) {
+		return fmt.Sprintf("Error: invalid role %s for a mask; use %s", role, strings.Join(MaskRoles, " or "))
+	}
+	if method != "" && maskFunc(method) == nil {
+		return fmt.Sprintf("Error: unknown mask method '%s'; use '%s', '%s' or '%s'", method, MaskFull, MaskPartial, MaskHash)
+	}
+
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	colIdx := table.columnIndex(column)
+	if colIdx == -1 {
+		return fmt.Sprintf("Column %s not found", column)
+	}
+	column = table.Columns[colIdx]
+	if method == "" && table.maskIndex(column, role) == -1 {
+		return fmt.Sprintf("Error: no mask on %s.%s for role %s", tableName, column, role)
+	}
+
+	// Write to WAL first
+	if db.WAL != nil {
+		data := map[string]interface{}{"column": column, "role": role, "method": method}
+		if err := db.WAL.WriteEntry(WAL_SET_MASK, tableName, data); err != nil {
+			return fmt.Sprintf("Failed to write to WAL: %v", err)
+		}
+	}
+
+	table.setMask(column, role, method)
+	if err := db.saveTable(table); err != nil {
+		return fmt.Sprintf("Mask set with warnings: failed to persist: %v", err)
+	}
+
+	if method == "" {
+		return fmt.Sprintf("Mask dropped from %s.%s for role %s", tableName, column, role)
+	}
+	return fmt.Sprintf("Mask %s created on %s.%s for role %s", method, tableName, column, role)
+}
+
+// HasMasks reports whether any column of a table is masked for role
+func (db *Database) HasMasks(tableName, role string) bool {
+	table, exists := db.lookupTable(strings.ToLower(tableName))
+	if !exists {
+		return false
+	}
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+	for _, m := range table.Masks {
+		if m.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// maskIndex returns the position of the mask of column for role in
+// t.Masks, or -1
+func (t *Table) maskIndex(column, role string) int {
+	return slices.IndexFunc(t.Masks, func(m ColumnMask) bool {
+		return m.Column == column && m.Role == role
+	})
+}
+
+// setMask sets or, for an empty method, removes the mask of a column for a
+// role
+func (t *Table) setMask(column, role, method string) {
+	i := t.maskIndex(column, role)
+	switch {
+	case method == "" && i >= 0:
+		t.Masks = slices.Delete(t.Masks, i, i+1)
+	case method == "":
+	case i >= 0:
+		t.Masks[i].Method = method
+	default:
+		t.Masks = append(t.Masks, ColumnMask{Column: column, Role: role, Method: method})
+	}
+}
+
+// masker returns a function that returns a masked copy of a row for role,
+// or nil when no column is masked for it
+func (t *Table) masker(role string) func([]string) []string {
+	if role == "" {
+		return nil
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	masks := make(map[int]func(string) string)
+	for _, m := range t.Masks {
+		if m.Role != role {
+			continue
+		}
+		if colIdx := t.columnIndex(m.Column); colIdx >= 0 {
+			masks[colIdx] = maskFunc(m.Method)
+		}
+	}
+	if len(masks) == 0 {
+		return nil
+	}
+	return func(row []string) []string {
+		masked := slices.Clone(row)
+		for i, fn := range masks {
+			if i < len(masked) && !IsNull(masked[i]) {
+				masked[i] = fn(masked[i])
+			}
+		}
+		return masked
+	}
+}
+
+// maskRows returns masked copies of the rows of rs at indexes, with their
+// IDs, and their indexes in the copy. rs is returned as it is when mask is
+// nil.
+func maskRows(rs rowSet, indexes []int, mask func([]string) []string) (rowSet, []int) {
+	if mask == nil {
+		return rs, indexes
+	}
+	masked := rowSet{rows: make([][]string, len(indexes))}
+	if rs.ids != nil {
+		masked.ids = make([]int64, len(indexes))
+	}
+	order := make([]int, len(indexes))
+	for i, ri := range indexes {
+		masked.rows[i] = mask(rs.rows[ri])
+		if rs.ids != nil && ri < len(rs.ids) {
+			masked.ids[i] = rs.ids[ri]
+		}
+		order[i] = i
+	}
+	return masked, order
+}
+
+// maskFunc returns the function applying a mask method, or nil for an
+// unknown method
+func maskFunc(method string) func(string) string {
+	switch method {
+	case MaskFull:
+		return func(string) string { return "****" }
+	case MaskPartial:
+		return maskPartial
+	case MaskHash:
+		return func(v string) string {
+			sum := sha256.Sum256([]byte(v))
+			return hex.EncodeToString(sum[:8])
+		}
+	}
+	return nil
+}
+
+// maskPartial keeps the first character and domain of an email address and
+// the last four characters of other values, e.g. j***@example.com and
+// ****1234. Values of four characters or fewer are masked in full.
+func maskPartial(v string) string {
+	if local, domain, ok := strings.Cut(v, "@"); ok && local != "" && domain != "" {
+		return string([]rune(local)[:1]) + "***@" + domain
+	}
+	r := []rune(v)
+	if len(r) <= 4 {
+		return "****"
+	}
+	return strings.Repeat("*", len(r)-4) + string(r[len(r)-4:])
+}
@@ -0,0 +1,21 @@
+package storage
+
+import "testing"
+
+func TestMaskMethods(t *testing.T) {
+	for _, tc := range []struct{ method, in, want string }{
+		{MaskFull, "secret", "****"},
+		{MaskPartial, "jane@example.com", "j***@example.com"},
+		{MaskPartial, "@example.com", "********.com"},
+		{MaskPartial, "555-0100", "****0100"},
+		{MaskPartial, "ünïcödé", "***cödé"},
+		{MaskPartial, "abcd", "****"},
+	} {
+		if got := maskFunc(tc.method)(tc.in); got != tc.want {
+			t.Errorf("%s(%q) = %q, want %q", tc.method, tc.in, got, tc.want)
+		}
+	}
+	if a, b := maskFunc(MaskHash)("a"), maskFunc(MaskHash)("b"); a == b || len(a) != 16 {
+		t.Errorf("hash masks: %q %q", a, b)
+	}
+}
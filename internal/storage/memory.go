@@ -19,7 +19,7 @@
 // Integration Points:
 // - CREATE TABLE: Creates both JSON and page-based storage
 // - INSERT/UPDATE/DELETE: Uses page-based storage when available
-// - SELECT: Reads from page-based storage with fallback to JSON
+// - SELECT: Reads the in-memory tables loaded from JSON; pages mirror inserts
 // - Indexes: B-tree indexes work with both storage types
 // - Transactions: WAL integration works with both storage types
 //
@@ -39,8 +39,13 @@ package storage
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -52,25 +57,144 @@ type Table struct {
 	Name    string
 	Columns []string
 	Rows    [][]string
+	// RowIDs holds the ID of each row in Rows (see rowid.go); NextRowID is
+	// the ID the next inserted row gets
+	RowIDs    []int64
+	NextRowID int64
 	// IndexedColumns lists column names that are indexed
 	IndexedColumns []string
-	// Indexes maps column name -> value -> list of row indexes
+	// Indexes maps column name -> value -> list of row IDs
 	Indexes map[string]map[string][]int
-	// BTreeIndexes holds a B-tree per indexed column for fast equality/range lookups
+	// BTreeIndexes holds a B-tree of row IDs per indexed column for fast
+	// equality/range lookups
 	BTreeIndexes map[string]*BTree
+	// grids holds a grid index per indexed POINT column (see geo.go)
+	grids map[string]*gridIndex
+	// Collations maps column name -> collation; missing columns are BINARY
+	Collations map[string]*Collation
+	// Types maps column name -> declared type; missing columns are untyped
+	// (see types.go)
+	Types map[string]ColumnType
+	// NotNull marks the columns declared NOT NULL; Defaults maps column name
+	// -> default value of columns declared with one (see constraints.go)
+	NotNull  map[string]bool
+	Defaults map[string]string
+	// Comment describes the table; ColumnComments maps column name -> comment
+	Comment        string
+	ColumnComments map[string]string
+	// Masks redact columns in query results for some roles (see mask.go)
+	Masks []ColumnMask
+	// External is the file of an external table, nil for stored tables
+	External *ExternalSource
+	// Unlogged tables skip the WAL and fsync, trading crash safety for
+	// write speed (see unlogged.go)
+	Unlogged bool
+	// KeyID is the keyring key the table's file and pages are encrypted
+	// with, "" for unencrypted tables (see keys.go)
+	KeyID string
+	// Versioned tables keep a row version in their last column, _version,
+	// which every update advances (see version.go)
+	Versioned bool
+	// Timestamped tables keep created_at and updated_at columns, set by
+	// inserts and updates (see timestamps.go)
+	Timestamps bool
+	// ForeignKeys are the table's FOREIGN KEY constraints (see foreignkey.go)
+	ForeignKeys []ForeignKey
+	// Retention bounds how much of the table is kept, nil for no limit
+	// (see retention.go)
+	Retention *Retention
+
+	// lock is held shared by indexed reads and exclusively by writes; it
+	// records its holders and waiters for SHOW LOCKS (see locks.go)
+	lock tableLock
+	// published is the row slice lock-free scans read (see rows.go)
+	published atomic.Pointer[rowSet]
+	// positions maps row ID -> position in Rows
+	positions map[int64]int
+	// version counts published row changes, for transaction conflict checks
+	version atomic.Uint64
+	// rowVersions maps the ID of a row updated or deleted while a snapshot
+	// transaction was open -> the version that changed it (see isolation.go)
+	rowVersions map[int64]uint64
+	// stats counts reads and writes for SHOW TABLE STATS
+	stats tableStats
+	// staleIndexes is set when bulk load mode skipped index maintenance
+	staleIndexes atomic.Bool
+	// analysis holds the statistics of the last ANALYZE, nil before one
+	analysis atomic.Pointer[TableAnalysis]
+	// sizes counts the table's rows and value bytes (see size.go)
+	sizes tableSize
+	// pagesExact is set when the table's pages in PageStorage held exactly
+	// its rows at version pagesVersion (see aggregate.go)
+	pagesExact   bool
+	pagesVersion uint64
 }
 
+// Database is a session's handle on a database. The handles NewDatabase and
+// Session return share the database; each has its own open transaction, so
+// one session's BEGIN, COMMIT and queued writes do not touch another's.
 type Database struct {
+	*database
+	// currentTransaction is the handle's open transaction, or nil
+	currentTransaction *Transaction
+}
+
+// database is the state a Database's handles share
+type database struct {
 	DataDir            string
 	Tables             map[string]*Table
 	WAL                *WALManager
 	TransactionManager *TransactionManager
-	currentTransaction *Transaction
+	// activeTransactions holds the transactions the handles have open
 	activeTransactions map[string]*Transaction
+	txMu               sync.Mutex
 	// PageStorage provides PostgreSQL-like secure page-based storage
 	PageStorage *PageStorage
+	// Keys holds the keys of encrypted tables; EncryptTables makes new
+	// tables encrypted unless they are created WITH (encrypted=false)
+	Keys          *Keyring
+	EncryptTables bool
 	// StorageMode determines which storage system to use
 	StorageMode StorageMode
+	// walErr is why the WAL could not be opened; WALFailurePolicy decides
+	// how writes are treated while it is set (see degraded.go)
+	walErr           error
+	WALFailurePolicy WALFailurePolicy
+	// MinFreeBytes is the free space in the data directory below which new
+	// writes are refused (see diskspace.go); 0 disables the check
+	MinFreeBytes int64
+	disk         diskSpace
+	// Changes records committed changes for CDC sinks; nil when disabled
+	Changes *ChangeLog
+	// MaxScanParallelism caps the workers a full-table scan uses; 0 uses
+	// GOMAXPROCS and 1 scans serially
+	MaxScanParallelism int
+	// QueryMemoryBudget caps the bytes one query may hold for sorting before
+	// it spills to temporary files; 0 or less means unlimited
+	QueryMemoryBudget int64
+	// writeGate is held shared by every write and exclusively by Snapshot,
+	// letting online backups briefly pause writers
+	writeGate sync.RWMutex
+	// catalog guards the Tables map; row data is guarded by each Table's lock
+	catalog sync.RWMutex
+	// lockSession names the session table locks are taken for (see locks.go)
+	lockSession atomic.Pointer[string]
+	// hooks are the callbacks registered with OnInsert, OnCommit and so on
+	hooks hooks
+	// bulkLoad defers index maintenance until it is turned off (see bulkload.go)
+	bulkLoad atomic.Bool
+	// snapshotTxs counts open REPEATABLE READ and SERIALIZABLE transactions;
+	// row versions are only recorded while one is open
+	snapshotTxs atomic.Int32
+	// recovery counts the tables loaded and WAL records replayed while the
+	// database is opened (see recovery.go)
+	recovery *RecoveryProgress
+	// unreadable maps the name of each table whose file could not be
+	// decrypted -> the error, so it is not replaced by a new table
+	unreadable   map[string]error
+	unreadableMu sync.Mutex
+	// kvMu serializes the KV commands' writes (see kv.go)
+	kvMu sync.Mutex
 }
 
 // StorageMode determines which storage system to use
@@ -85,23 +209,53 @@ const (
 	StorageModeHybrid
 )
 
+// String names the storage mode
+func (m StorageMode) String() string {
+	switch m {
+	case StorageModeJSON:
+		return "json"
+	case StorageModePage:
+		return "page"
+	case StorageModeHybrid:
+		return "hybrid (json + page)"
+	}
+	return fmt.Sprintf("StorageMode(%d)", int(m))
+}
+
 func NewDatabase(dataDir string) *Database {
-	db := &Database{
+	return NewDatabaseWithProgress(dataDir, &RecoveryProgress{})
+}
+
+// NewDatabaseWithProgress opens the database in dataDir like NewDatabase,
+// counting its recovery in progress, which another goroutine may read
+func NewDatabaseWithProgress(dataDir string, progress *RecoveryProgress) *Database {
+	db := &Database{database: &database{
 		DataDir:            dataDir,
 		Tables:             make(map[string]*Table),
 		activeTransactions: make(map[string]*Transaction),
 		StorageMode:        StorageModeHybrid, // Use hybrid mode by default
-	}
+		recovery:           progress,
+	}}
 
 	// Initialize PageStorage with security features enabled
 	db.PageStorage = NewPageStorage(dataDir, true, true) // Enable encryption and compression
+	db.removeOrphanTempFiles()
+	var keysErr error
+	if db.Keys, keysErr = loadKeyring(dataDir); keysErr != nil {
+		log.Printf("Warning: %v; encrypted tables cannot be read\n", keysErr)
+	}
+	db.PageStorage.keys = db.Keys
 
 	// Initialize WAL manager
 	var err error
 	db.WAL, err = NewWALManager(dataDir)
 	if err != nil {
 		// If WAL initialization fails, continue without WAL (degraded mode)
-		fmt.Printf("Warning: Failed to initialize WAL: %v\n", err)
+		log.Printf("Warning: Failed to initialize WAL: %v\n", err)
+		db.walErr = err
+	} else {
+		// LSNs continue after the last page checkpoint, whose WAL was truncated
+		db.WAL.advanceLSN(db.PageStorage.CheckpointLSN())
 	}
 
 	// Initialize Transaction Manager
@@ -110,44 +264,187 @@ func NewDatabase(dataDir string) *Database {
 	// Load any existing .harudb files first (legacy JSON storage)
 	_ = db.loadTables()
 
+	// An existing change log is reopened before replay, which logs the
+	// replayed changes it is missing
+	if _, err := os.Stat(filepath.Join(dataDir, ChangeLogName)); err == nil {
+		if err := db.EnableChangeLog(); err != nil {
+			log.Printf("Warning: %v\n", err)
+		}
+	}
+
 	// Replay WAL entries if WAL is available (only replay uncommitted transactions)
+	unlogged := db.unloggedTables()
 	if db.WAL != nil {
 		if err := db.WAL.ReplayWAL(db); err != nil {
-			fmt.Printf("Warning: Failed to replay WAL: %v\n", err)
+			log.Printf("Warning: Failed to replay WAL: %v\n", err)
 		}
-		// Clear WAL after successful replay to prevent duplicates
-		if err := db.WAL.TruncateWAL(); err != nil {
-			fmt.Printf("Warning: Failed to truncate WAL: %v\n", err)
+		// Replayed writes do not maintain indexes
+		db.rebuildIndexesParallel()
+		// Clear WAL after successful replay to prevent duplicates. Pages
+		// redone from it must reach disk first.
+		if err := db.checkpointPages(); err != nil {
+			log.Printf("Warning: Failed to checkpoint pages, keeping the WAL: %v\n", err)
+		} else if err := db.WAL.TruncateWAL(); err != nil {
+			log.Printf("Warning: Failed to truncate WAL: %v\n", err)
 		}
 	}
+	db.restoreUnloggedTables(unlogged)
+	db.loadTableStats()
+	progress.done.Store(true)
 
 	return db
 }
 
+// Close releases the database's open files. The Database must not be used
+// after Close; open a new one with NewDatabase instead.
+func (db *Database) Close() error {
+	db.writeGate.Lock()
+	if err := db.checkpointPages(); err != nil {
+		log.Printf("Warning: failed to checkpoint pages: %v\n", err)
+	}
+	db.writeGate.Unlock()
+	if err := db.SaveTableStats(); err != nil {
+		log.Printf("Warning: %v\n", err)
+	}
+	if db.Changes != nil {
+		db.Changes.Close()
+	}
+	if db.WAL != nil {
+		return db.WAL.Close()
+	}
+	return nil
+}
+
+// lookupTable returns a table by its lower-case name
+func (db *Database) lookupTable(name string) (*Table, bool) {
+	db.catalog.RLock()
+	defer db.catalog.RUnlock()
+	table, exists := db.Tables[name]
+	return table, exists
+}
+
+// ReadRow returns a table's columns and a copy of one row. msg is a
+// user-facing error, empty on success.
+func (db *Database) ReadRow(tableName string, rowIndex int) (columns, row []string, msg string) {
+	return db.readRow(tableName, atIndex(rowIndex))
+}
+
+// ReadRowByID is ReadRow of the row with ID id
+func (db *Database) ReadRowByID(tableName string, id int64) (columns, row []string, msg string) {
+	return db.readRow(tableName, withRowID(id))
+}
+
+// readRow returns a copy of the target row among the rows the current
+// statement sees
+func (db *Database) readRow(tableName string, target rowTarget) (columns, row []string, msg string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if msg := table.externalError(); msg != "" {
+		return nil, nil, msg
+	}
+	rows, ids := db.visibleRowSet(table)
+	rowIndex, msg := target.find(rows, ids)
+	if msg != "" {
+		return nil, nil, msg
+	}
+	return table.Columns, append([]string(nil), rows[rowIndex]...), ""
+}
+
 func (db *Database) CreateTable(name string, columns []string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.createTable(name, columns, db.DefaultTableOptions())
+}
+
+// TableOptions are the options of CREATE TABLE
+type TableOptions struct {
+	// Unlogged tables skip the WAL (see unlogged.go)
+	Unlogged bool
+	// Encrypted tables are sealed with a keyring key (see keys.go)
+	Encrypted bool
+	// Versioned tables get a _version column (see version.go)
+	Versioned bool
+	// Timestamped tables get created_at and updated_at columns (see
+	// timestamps.go)
+	Timestamps bool
+}
+
+// DefaultTableOptions returns the options of a table created without any
+func (db *Database) DefaultTableOptions() TableOptions {
+	return TableOptions{Encrypted: db.EncryptTables}
+}
+
+func (db *Database) createTable(name string, columns []string, opts TableOptions) string {
+	db.catalog.Lock()
+	defer db.catalog.Unlock()
+
 	name = strings.ToLower(name)
 	if _, exists := db.Tables[name]; exists {
 		return fmt.Sprintf("Table %s already exists", name)
 	}
+	columns, err := timestampedColumns(columns, opts.Timestamps)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if columns, err = versionedColumns(columns, opts.Versioned); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	defs, err := parseColumnSpecs(columns)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := defs.resolveForeignKeys(name, db.catalogTable); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if msg := db.unreadableTable(name); msg != "" {
+		return msg
+	}
+	var keyID string
+	if opts.Encrypted {
+		if keyID, err = db.Keys.Current(); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
 
 	// Write to WAL (Write Ahead Logs) first
+	var lsn uint64
 	if db.WAL != nil {
 		data := map[string]interface{}{
 			"columns": columns,
 		}
-		if err := db.WAL.WriteEntry(WAL_CREATE_TABLE, name, data); err != nil {
+		if opts.Unlogged {
+			data["unlogged"] = true
+		}
+		if opts.Versioned {
+			data["versioned"] = true
+		}
+		if opts.Timestamps {
+			data["timestamps"] = true
+		}
+		if keyID != "" {
+			data["key_id"] = keyID
+		}
+		if lsn, err = db.WAL.AppendEntry(WAL_CREATE_TABLE, name, data); err != nil {
 			return fmt.Sprintf("Table %s created (warning: failed to write to WAL: %v)", name, err)
 		}
 	}
 
 	// Apply changes to memory (legacy JSON storage)
-	db.Tables[name] = &Table{Name: name, Columns: columns, Rows: [][]string{}, IndexedColumns: []string{}, Indexes: make(map[string]map[string][]int), BTreeIndexes: make(map[string]*BTree)}
+	db.addTable(&Table{Name: name, Rows: [][]string{}, IndexedColumns: []string{}, Indexes: make(map[string]map[string][]int), BTreeIndexes: make(map[string]*BTree), Unlogged: opts.Unlogged, KeyID: keyID, Versioned: opts.Versioned, Timestamps: opts.Timestamps})
+	defs.apply(db.Tables[name])
+	change := createTableEvent(db.Tables[name])
+	change.WALLSN = lsn
+	db.appendChange(change)
 
 	// Create table in page-based storage (PostgreSQL-like secure storage)
 	if db.PageStorage != nil {
-		if err := db.PageStorage.CreateTable(name, columns); err != nil {
+		if err := db.PageStorage.CreateTable(name, defs.names, keyID); err != nil {
 			return fmt.Sprintf("Table %s created (warning: failed to create page storage: %v)", name, err)
 		}
+		db.Tables[name].pagesExact = true
 	}
 
 	// Persist to disk (legacy JSON storage)
@@ -158,44 +455,88 @@ func (db *Database) CreateTable(name string, columns []string) string {
 	// Write checkpoint to WAL
 	if db.WAL != nil {
 		if err := db.WAL.WriteCheckpoint(); err != nil {
-			fmt.Printf(ErrWALCheckpoint, err)
+			log.Printf(ErrWALCheckpoint, err)
 		}
 	}
 
+	db.runCommitHooks("", []ChangeEvent{change})
+
+	if opts.Unlogged {
+		return fmt.Sprintf("Unlogged table %s created", name)
+	}
+	if keyID != "" {
+		return fmt.Sprintf("Encrypted table %s created with key %s", name, keyID)
+	}
 	return fmt.Sprintf("Table %s created with secure page-based storage", name)
 }
 
 func (db *Database) Insert(tableName string, values []string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.insert(tableName, values)
+}
+
+func (db *Database) insert(tableName string, values []string) string {
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	values, msg := table.versionedRow(table.timestampedRow(values, time.Now()))
+	if msg != "" {
+		return msg
+	}
 	if len(values) != len(table.Columns) {
 		return "Column count does not match"
 	}
+	if values, msg = table.constrainedRow(values); msg != "" {
+		return msg
+	}
+	if values, msg = table.typedRow(values); msg != "" {
+		return msg
+	}
+	if msg := db.runInsertHooks(tableName, values); msg != "" {
+		return msg
+	}
 
 	// Write to WAL first
-	if db.WAL != nil {
+	id := table.allocRowID()
+	var lsn uint64
+	if wal := db.walFor(table); wal != nil {
 		data := map[string]interface{}{
 			"values": values,
+			"row_id": id,
 		}
-		if err := db.WAL.WriteEntry(WAL_INSERT, tableName, data); err != nil {
+		var err error
+		if lsn, err = wal.AppendEntry(WAL_INSERT, tableName, data); err != nil {
 			return fmt.Sprintf("1 row inserted (warning: failed to write to WAL: %v)", err)
 		}
 	}
+	rowIndex := len(table.Rows)
+	change := ChangeEvent{Op: ChangeInsert, Table: tableName, Columns: table.Columns, RowIndex: &rowIndex, RowID: id, Values: values, WALLSN: lsn}
+	db.appendChange(change)
 
-	// Insert into page-based storage (primary storage)
+	// Insert into page-based storage (primary storage); the page is written
+	// at the next checkpoint
 	if db.PageStorage != nil {
-		if err := db.PageStorage.InsertRow(tableName, values); err != nil {
+		if err := db.PageStorage.InsertRow(tableName, values, lsn); err != nil {
 			return fmt.Sprintf("1 row inserted (warning: failed to insert into page storage: %v)", err)
 		}
 	}
 
 	// Apply changes to memory (legacy JSON storage for backward compatibility)
-	table.Rows = append(table.Rows, values)
+	mirrored := db.PageStorage != nil && table.pagesMirrorRows()
+	table.appendRow(values, id)
+	if mirrored {
+		table.pagesVersion = table.version.Load()
+	}
 	// Maintain indexes for this row
-	db.applyIndexesOnInsert(table, len(table.Rows)-1)
+	db.indexInsert(table, len(table.Rows)-1)
 
 	// Persist to disk (legacy JSON storage)
 	if err := db.saveTable(table); err != nil {
@@ -203,45 +544,53 @@ func (db *Database) Insert(tableName string, values []string) string {
 	}
 
 	// Write checkpoint to WAL
-	if db.WAL != nil {
-		if err := db.WAL.WriteCheckpoint(); err != nil {
-			fmt.Printf(ErrWALCheckpoint, err)
+	if wal := db.walFor(table); wal != nil {
+		if err := wal.WriteCheckpoint(); err != nil {
+			log.Printf(ErrWALCheckpoint, err)
 		}
 	}
 
+	table.stats.recordWrite(&table.stats.inserts)
+	db.runCommitHooks("", []ChangeEvent{change})
+
 	return "1 row inserted with secure page-based storage"
 }
 
+// SelectAll returns every row of a table as text
 func (db *Database) SelectAll(tableName string) string {
+	return db.SelectAllAs(tableName, "")
+}
+
+// SelectAllAsResult returns every row of a table, with the columns masked
+// for role masked (see mask.go)
+func (db *Database) SelectAllAsResult(tableName, role string) (*ResultSet, string) {
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
-		return fmt.Sprintf(ErrTableNotFound, tableName)
+		return nil, fmt.Sprintf(ErrTableNotFound, tableName)
 	}
-
-	// Try to read from page-based storage first (primary storage)
-	if db.PageStorage != nil {
-		rows, err := db.PageStorage.ReadRows(tableName, 0, 1000) // Read up to 1000 rows
-		if err == nil && len(rows) > 0 {
-			result := strings.Join(table.Columns, " | ") + "\n"
-			for _, row := range rows {
-				result += strings.Join(row, " | ") + "\n"
-			}
-			return result
-		}
+	if msg := table.externalError(); msg != "" {
+		return nil, msg
+	}
+	table.stats.reads.Add(1)
+	rows := db.visibleRows(table)
+	mask := table.masker(role)
+	if mask == nil {
+		mask = func(row []string) []string { return row }
 	}
 
-	// Fallback to legacy JSON storage
+	// The in-memory table is authoritative: page storage only mirrors inserts,
+	// so reading it would show rows that were since updated or deleted.
 	// If we're in a transaction, show the current state including uncommitted changes
 	if db.currentTransaction != nil {
 		// Apply transaction operations temporarily for display
 		tempTable := &Table{
 			Name:    table.Name,
 			Columns: make([]string, len(table.Columns)),
-			Rows:    make([][]string, len(table.Rows)),
+			Rows:    make([][]string, len(rows)),
 		}
 		copy(tempTable.Columns, table.Columns)
-		for i, row := range table.Rows {
+		for i, row := range rows {
 			tempTable.Rows[i] = make([]string, len(row))
 			copy(tempTable.Rows[i], row)
 		}
@@ -252,22 +601,14 @@ func (db *Database) SelectAll(tableName string) string {
 				switch op.Type {
 				case WAL_INSERT:
 					if data, ok := op.Data.(map[string]interface{}); ok {
-						if values, ok := data["values"].([]interface{}); ok {
-							valStrs := make([]string, len(values))
-							for i, val := range values {
-								valStrs[i] = val.(string)
-							}
+						if valStrs, ok := operationValues(data); ok {
 							tempTable.Rows = append(tempTable.Rows, valStrs)
 						}
 					}
 				case WAL_UPDATE:
 					if data, ok := op.Data.(map[string]interface{}); ok {
 						if rowIndex, ok := data["row_index"].(float64); ok {
-							if values, ok := data["values"].([]interface{}); ok {
-								valStrs := make([]string, len(values))
-								for i, val := range values {
-									valStrs[i] = val.(string)
-								}
+							if valStrs, ok := operationValues(data); ok {
 								if int(rowIndex) < len(tempTable.Rows) {
 									tempTable.Rows[int(rowIndex)] = valStrs
 								}
@@ -286,58 +627,104 @@ func (db *Database) SelectAll(tableName string) string {
 			}
 		}
 
-		result := strings.Join(tempTable.Columns, " | ") + "\n"
-		for _, row := range tempTable.Rows {
-			result += strings.Join(row, " | ") + "\n"
-		}
-		if len(tempTable.Rows) == 0 {
-			result += "(no rows)\n"
-		}
-		return result
+		rows = tempTable.Rows
 	}
 
-	// Normal non-transactional behavior (legacy JSON storage)
-	result := strings.Join(table.Columns, " | ") + "\n"
-	for _, row := range table.Rows {
-		result += strings.Join(row, " | ") + "\n"
+	rs := &ResultSet{Columns: table.Columns, Rows: make([][]string, len(rows)), Types: table.columnTypes()}
+	for i, row := range rows {
+		rs.Rows[i] = mask(row)
 	}
-	if len(table.Rows) == 0 {
-		result += "(no rows)\n"
+	return rs, ""
+}
+
+// SelectAllAs is SelectAllAsResult rendered as text
+func (db *Database) SelectAllAs(tableName, role string) string {
+	return resultText(db.SelectAllAsResult(tableName, role))
+}
+
+// operationValues returns the row values of a queued transaction operation,
+// which hold []string when queued and []interface{} when decoded from JSON
+func operationValues(data map[string]interface{}) ([]string, bool) {
+	switch values := data["values"].(type) {
+	case []string:
+		return values, true
+	case []interface{}:
+		valStrs := make([]string, len(values))
+		for i, val := range values {
+			valStrs[i], _ = val.(string)
+		}
+		return valStrs, true
 	}
-	return result
+	return nil, false
 }
 
 // Update updates a row in the specified table
 func (db *Database) Update(tableName string, rowIndex int, values []string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.update(tableName, atIndex(rowIndex), values, 0)
+}
+
+// update is Update of the target row without taking the write gate. A
+// versioned table's row must be at version expected unless it is 0.
+func (db *Database) update(tableName string, target rowTarget, values []string, expected int64) string {
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
 
-	if rowIndex < 0 || rowIndex >= len(table.Rows) {
-		return "Row index out of bounds"
+	rowIndex, msg := table.locate(target)
+	if msg != "" {
+		return msg
 	}
+	id := table.RowIDs[rowIndex]
 
 	if len(values) != len(table.Columns) {
 		return "Column count does not match"
 	}
+	if values, msg = table.constrainedRow(values); msg != "" {
+		return msg
+	}
+	if values, msg = table.typedRow(values); msg != "" {
+		return msg
+	}
+	values = table.touchedRow(table.Rows[rowIndex], values, time.Now())
+	values, msg = table.nextVersion(id, table.Rows[rowIndex], values, expected)
+	if msg != "" {
+		return msg
+	}
+	if msg := db.runUpdateHooks(tableName, rowIndex, table.Rows[rowIndex], values); msg != "" {
+		return msg
+	}
 
 	// Write to WAL first
-	if db.WAL != nil {
+	var lsn uint64
+	if wal := db.walFor(table); wal != nil {
 		data := map[string]interface{}{
 			"row_index": rowIndex,
+			"row_id":    id,
 			"values":    values,
 		}
-		if err := db.WAL.WriteEntry(WAL_UPDATE, tableName, data); err != nil {
+		var err error
+		if lsn, err = wal.AppendEntry(WAL_UPDATE, tableName, data); err != nil {
 			return fmt.Sprintf("Row updated (warning: failed to write to WAL: %v)", err)
 		}
 	}
+	oldValues := table.Rows[rowIndex]
+	change := ChangeEvent{Op: ChangeUpdate, Table: tableName, Columns: table.Columns, RowIndex: &rowIndex, RowID: id, Values: values, OldValues: oldValues, WALLSN: lsn}
+	db.appendChange(change)
 
 	// Apply changes to memory
-	table.Rows[rowIndex] = values
-	// Rebuild indexes as row positions and values may have changed
-	db.rebuildAllIndexes(table)
+	db.noteRowWrite(table, rowIndex)
+	table.setRow(rowIndex, values)
+	// Rebuild indexes as the row's values may have changed
+	db.reindex(table)
 
 	// Persist to disk
 	if err := db.saveTable(table); err != nil {
@@ -345,41 +732,68 @@ func (db *Database) Update(tableName string, rowIndex int, values []string) stri
 	}
 
 	// Write checkpoint to WAL
-	if db.WAL != nil {
-		if err := db.WAL.WriteCheckpoint(); err != nil {
-			fmt.Printf(ErrWALCheckpoint, err)
+	if wal := db.walFor(table); wal != nil {
+		if err := wal.WriteCheckpoint(); err != nil {
+			log.Printf(ErrWALCheckpoint, err)
 		}
 	}
 
+	table.stats.recordWrite(&table.stats.updates)
+	db.runCommitHooks("", []ChangeEvent{change})
+
 	return "1 row updated"
 }
 
 // Delete deletes a row from the specified table
 func (db *Database) Delete(tableName string, rowIndex int) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.deleteRow(tableName, atIndex(rowIndex))
+}
+
+// deleteRow is Delete of the target row without taking the write gate
+func (db *Database) deleteRow(tableName string, target rowTarget) string {
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
 
-	if rowIndex < 0 || rowIndex >= len(table.Rows) {
-		return "Row index out of bounds"
+	rowIndex, msg := table.locate(target)
+	if msg != "" {
+		return msg
+	}
+	id := table.RowIDs[rowIndex]
+	if msg := db.runDeleteHooks(tableName, rowIndex, table.Rows[rowIndex]); msg != "" {
+		return msg
 	}
 
 	// Write to WAL first
-	if db.WAL != nil {
+	var lsn uint64
+	if wal := db.walFor(table); wal != nil {
 		data := map[string]interface{}{
 			"row_index": rowIndex,
+			"row_id":    id,
 		}
-		if err := db.WAL.WriteEntry(WAL_DELETE, tableName, data); err != nil {
+		var err error
+		if lsn, err = wal.AppendEntry(WAL_DELETE, tableName, data); err != nil {
 			return fmt.Sprintf("Row deleted (warning: failed to write to WAL: %v)", err)
 		}
 	}
+	oldValues := table.Rows[rowIndex]
+	change := ChangeEvent{Op: ChangeDelete, Table: tableName, Columns: table.Columns, RowIndex: &rowIndex, RowID: id, OldValues: oldValues, WALLSN: lsn}
+	db.appendChange(change)
 
 	// Apply changes to memory
-	table.Rows = append(table.Rows[:rowIndex], table.Rows[rowIndex+1:]...)
-	// Rebuild indexes as row positions shifted
-	db.rebuildAllIndexes(table)
+	db.noteRowWrite(table, rowIndex)
+	table.removeRow(rowIndex)
+	// Rebuild indexes without the deleted row's ID
+	db.reindex(table)
 
 	// Persist to disk
 	if err := db.saveTable(table); err != nil {
@@ -387,71 +801,185 @@ func (db *Database) Delete(tableName string, rowIndex int) string {
 	}
 
 	// Write checkpoint to WAL
-	if db.WAL != nil {
-		if err := db.WAL.WriteCheckpoint(); err != nil {
-			fmt.Printf(ErrWALCheckpoint, err)
+	if wal := db.walFor(table); wal != nil {
+		if err := wal.WriteCheckpoint(); err != nil {
+			log.Printf(ErrWALCheckpoint, err)
 		}
 	}
 
+	table.stats.recordWrite(&table.stats.deletes)
+	db.runCommitHooks("", []ChangeEvent{change})
+
 	return "1 row deleted"
 }
 
 // DropTable drops the specified table
 func (db *Database) DropTable(tableName string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.dropTable(tableName)
+}
+
+// dropTable is DropTable without taking the write gate
+func (db *Database) dropTable(tableName string) string {
+	db.catalog.Lock()
+	defer db.catalog.Unlock()
+
 	tableName = strings.ToLower(tableName)
 	_, exists := db.Tables[tableName]
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if msg := db.referencedByOther(tableName); msg != "" {
+		return msg
+	}
 
 	// Write to WAL first
+	var lsn uint64
 	if db.WAL != nil {
-		if err := db.WAL.WriteEntry(WAL_DROP_TABLE, tableName, nil); err != nil {
+		var err error
+		if lsn, err = db.WAL.AppendEntry(WAL_DROP_TABLE, tableName, nil); err != nil {
 			return fmt.Sprintf("Table dropped (warning: failed to write to WAL: %v)", err)
 		}
 	}
+	change := ChangeEvent{Op: ChangeDropTable, Table: tableName, WALLSN: lsn}
+	db.appendChange(change)
 
 	// Apply changes to memory
 	delete(db.Tables, tableName)
 
-	// Remove table file from disk
-	tablePath := db.tablePath(tableName)
-	if err := os.Remove(tablePath); err != nil && !os.IsNotExist(err) {
-		return fmt.Sprintf("Table dropped (warning: failed to remove table file: %v)", err)
+	// Remove table file and pages from disk
+	if err := db.removeTableFiles(tableName); err != nil {
+		return fmt.Sprintf("Table dropped (warning: %v)", err)
 	}
 
 	// Write checkpoint to WAL
 	if db.WAL != nil {
 		if err := db.WAL.WriteCheckpoint(); err != nil {
-			fmt.Printf(ErrWALCheckpoint, err)
+			log.Printf(ErrWALCheckpoint, err)
 		}
 	}
 
+	db.runCommitHooks("", []ChangeEvent{change})
+
 	return fmt.Sprintf("Table %s dropped", tableName)
 }
 
+// RenameTable renames a table along with its files. Indexes and collations
+// belong to the table and move with it.
+func (db *Database) RenameTable(oldName, newName string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.renameTable(oldName, newName)
+}
+
+// renameTable is RenameTable without taking the write gate
+func (db *Database) renameTable(oldName, newName string) string {
+	db.catalog.Lock()
+	defer db.catalog.Unlock()
+
+	oldName, newName = strings.ToLower(oldName), strings.ToLower(newName)
+	table, exists := db.Tables[oldName]
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, oldName)
+	}
+	if _, exists := db.Tables[newName]; exists {
+		return fmt.Sprintf("Table %s already exists", newName)
+	}
+	if msg := db.referencedByOther(oldName); msg != "" {
+		return msg
+	}
+
+	// Write to WAL first
+	var lsn uint64
+	if db.WAL != nil {
+		data := map[string]interface{}{"new_name": newName}
+		var err error
+		if lsn, err = db.WAL.AppendEntry(WAL_RENAME_TABLE, oldName, data); err != nil {
+			return fmt.Sprintf("Failed to write to WAL: %v", err)
+		}
+	}
+	change := ChangeEvent{Op: ChangeRenameTable, Table: oldName, NewName: newName, WALLSN: lsn}
+	db.appendChange(change)
+
+	// Writers that looked the table up before the rename save it under
+	// table.Name, so wait for them before changing it
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	if err := db.moveTable(table, newName); err != nil {
+		return fmt.Sprintf("Table renamed (warning: %v)", err)
+	}
+
+	// Write checkpoint to WAL
+	if db.WAL != nil {
+		if err := db.WAL.WriteCheckpoint(); err != nil {
+			log.Printf(ErrWALCheckpoint, err)
+		}
+	}
+
+	db.runCommitHooks("", []ChangeEvent{change})
+
+	return fmt.Sprintf("Table %s renamed to %s", oldName, newName)
+}
+
+// moveTable moves a table to a new name in the catalog and on disk. The new
+// table file is written before the old one is removed, so a crash in
+// between leaves both and WAL replay finishes the rename.
+func (db *Database) moveTable(table *Table, newName string) error {
+	oldName := table.Name
+	delete(db.Tables, oldName)
+	table.Name = newName
+	db.Tables[newName] = table
+	for i := range table.ForeignKeys {
+		if table.ForeignKeys[i].RefTable == oldName {
+			table.ForeignKeys[i].RefTable = newName
+		}
+	}
+
+	if err := db.saveTable(table); err != nil {
+		return fmt.Errorf("failed to save table file: %w", err)
+	}
+	if err := os.Remove(db.tablePath(oldName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old table file: %w", err)
+	}
+	if db.PageStorage != nil {
+		if err := db.PageStorage.RenameTable(oldName, newName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CreateIndex creates an in-memory hash index on a given column and
 // persists the indexed column metadata so indexes can be rebuilt on load.
 func (db *Database) CreateIndex(tableName string, columnName string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	return db.createIndex(tableName, columnName)
+}
+
+// createIndex is CreateIndex without taking the write gate
+func (db *Database) createIndex(tableName string, columnName string) string {
 	tableName = strings.ToLower(tableName)
 	columnName = strings.TrimSpace(columnName)
 
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
 
 	// Validate column exists
-	colIdx := -1
-	for i, c := range table.Columns {
-		if c == columnName {
-			colIdx = i
-			break
-		}
-	}
+	colIdx := table.columnIndex(columnName)
 	if colIdx == -1 {
 		return fmt.Sprintf("Column %s not found", columnName)
 	}
+	columnName = table.Columns[colIdx]
 
 	// Initialize maps if needed (hash index and B-tree index structures)
 	if table.Indexes == nil {
@@ -482,6 +1010,11 @@ func (db *Database) CreateIndex(tableName string, columnName string) string {
 	// Build hash index and B-tree for this column
 	db.buildIndexForColumn(table, columnName)
 	db.buildBTreeForColumn(table, columnName)
+	db.buildGridForColumn(table, columnName)
+	if !db.bulkLoad.Load() && table.staleIndexes.Load() {
+		db.rebuildAllIndexes(table)
+		table.staleIndexes.Store(false)
+	}
 
 	// Persist table metadata so indexes can be rebuilt on restart
 	if err := db.saveTable(table); err != nil {
@@ -491,119 +1024,45 @@ func (db *Database) CreateIndex(tableName string, columnName string) string {
 	return fmt.Sprintf("Index created on %s(%s)", tableName, columnName)
 }
 
-// SelectWhere returns rows where columnName == value. Uses index if available.
+// SelectWhere returns rows where columnName == value. It is planned like a
+// SQL WHERE clause, so the index, collation and NULL handling are the same.
 func (db *Database) SelectWhere(tableName, columnName, value string) string {
-	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
-	if !exists {
-		return fmt.Sprintf(ErrTableNotFound, tableName)
-	}
-
-	// Header
-	result := strings.Join(table.Columns, " | ") + "\n"
-
-	// If B-tree exists for this column, try it first (fast equality lookup)
-	if table.BTreeIndexes != nil {
-		if bt, ok := table.BTreeIndexes[columnName]; ok && bt != nil {
-			rowIdxs := bt.GetEqual(value)
-			if len(rowIdxs) > 0 {
-				for _, ri := range rowIdxs {
-					if ri >= 0 && ri < len(table.Rows) {
-						result += strings.Join(table.Rows[ri], " | ") + "\n"
-					}
-				}
-				return result
-			}
-			// If B-tree says no match, short-circuit with (no rows)
-			result += "(no rows)\n"
-			return result
-		}
-	}
-	// Fallback to legacy hash index
-	if table.Indexes != nil {
-		if idxMap, ok := table.Indexes[columnName]; ok {
-			if rowIdxs, ok2 := idxMap[value]; ok2 {
-				for _, ri := range rowIdxs {
-					if ri >= 0 && ri < len(table.Rows) {
-						result += strings.Join(table.Rows[ri], " | ") + "\n"
-					}
-				}
-				if len(rowIdxs) == 0 {
-					result += "(no rows)\n"
-				}
-				return result
-			}
-			// no matching value
-			result += "(no rows)\n"
-			return result
-		}
-	}
-
-	// Fallback: full scan
-	colIdx := -1
-	for i, c := range table.Columns {
-		if c == columnName {
-			colIdx = i
-			break
-		}
-	}
-	if colIdx == -1 {
-		return fmt.Sprintf("Column %s not found", columnName)
-	}
-	matched := 0
-	for _, row := range table.Rows {
-		if row[colIdx] == value {
-			result += strings.Join(row, " | ") + "\n"
-			matched++
-		}
-	}
-	if matched == 0 {
-		result += "(no rows)\n"
-	}
-	return result
+	return db.SelectWhereAdvanced(tableName, &columnEquals{column: columnName, value: value})
 }
 
-// SelectWhereAdvanced returns rows matching complex WHERE conditions
+// SelectWhereAdvanced returns rows matching complex WHERE conditions, using
+// an index when the planner finds one worthwhile
 func (db *Database) SelectWhereAdvanced(tableName string, whereExpr interface{}) string {
-	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
-	if !exists {
-		return fmt.Sprintf(ErrTableNotFound, tableName)
-	}
-
-	// Build column index map
-	columnIndexes := make(map[string]int)
-	for i, col := range table.Columns {
-		columnIndexes[col] = i
-	}
+	return db.SelectQuery(tableName, Query{Where: whereExpr, Limit: -1})
+}
 
-	// Header
-	result := strings.Join(table.Columns, " | ") + "\n"
+// SelectCount returns the number of rows in a table, or of rows matching
+// whereExpr when it is not nil. Without a WHERE clause the count comes from
+// the table itself, adjusted for the open transaction's pending inserts and
+// deletes, so no rows are read or formatted.
+func (db *Database) SelectCount(tableName string, whereExpr interface{}) string {
+	return db.SelectAggregates(tableName, []Aggregate{{Func: AggCount, Column: "*"}}, whereExpr, "")
+}
 
-	// Evaluate each row against the WHERE expression
-	matched := 0
-	for _, row := range table.Rows {
-		// Use reflection to call EvaluateExpression method
-		if expr, ok := whereExpr.(interface {
-			EvaluateExpression([]string, map[string]int) (bool, error)
-		}); ok {
-			match, err := expr.EvaluateExpression(row, columnIndexes)
-			if err != nil {
-				return fmt.Sprintf("Error evaluating WHERE condition: %v", err)
-			}
-			if match {
-				result += strings.Join(row, " | ") + "\n"
-				matched++
-			}
-		} else {
-			return "Invalid WHERE expression type"
-		}
+// pendingRowDelta returns how many rows the current transaction's queued
+// inserts and deletes add to a table, matching what SelectAll shows
+func (db *Database) pendingRowDelta(tableName string) int {
+	if db.currentTransaction == nil {
+		return 0
 	}
-
-	if matched == 0 {
-		result += "(no rows)\n"
+	delta := 0
+	for _, op := range db.currentTransaction.Operations {
+		if op.TableName != tableName {
+			continue
+		}
+		switch op.Type {
+		case WAL_INSERT:
+			delta++
+		case WAL_DELETE:
+			delta--
+		}
 	}
-	return result
+	return delta
 }
 
 // buildIndexForColumn builds index for a specific column from scratch
@@ -632,10 +1091,11 @@ func (db *Database) buildIndexForColumn(table *Table, columnName string) {
 	if colIdx == -1 {
 		return
 	}
+	coll := table.Collation(columnName)
 	for ri, row := range table.Rows {
 		if colIdx < len(row) {
-			val := row[colIdx]
-			idx[val] = append(idx[val], ri)
+			val := coll.Key(row[colIdx])
+			idx[val] = append(idx[val], int(table.RowIDs[ri]))
 		}
 	}
 }
@@ -665,11 +1125,12 @@ func (db *Database) buildBTreeForColumn(table *Table, columnName string) {
 	if colIdx == -1 {
 		return
 	}
-	// Insert all rows into the B-tree for this column
+	// Insert all rows into the B-tree for this column, keyed by collation
+	coll := table.Collation(columnName)
 	for ri, row := range table.Rows {
 		if colIdx < len(row) {
-			val := row[colIdx]
-			bt.Insert(val, ri)
+			val := coll.Key(row[colIdx])
+			bt.Insert(val, int(table.RowIDs[ri]))
 		}
 	}
 }
@@ -682,6 +1143,7 @@ func (db *Database) rebuildAllIndexes(table *Table) {
 	for _, col := range table.IndexedColumns {
 		db.buildIndexForColumn(table, col)
 		db.buildBTreeForColumn(table, col)
+		db.buildGridForColumn(table, col)
 	}
 }
 
@@ -703,7 +1165,7 @@ func (db *Database) applyIndexesOnInsert(table *Table, rowIndex int) {
 		if colIdx == -1 || colIdx >= len(row) {
 			continue
 		}
-		val := row[colIdx]
+		val := table.Collation(col).Key(row[colIdx])
 		// Update legacy hash index
 		if table.Indexes == nil {
 			table.Indexes = make(map[string]map[string][]int)
@@ -711,7 +1173,7 @@ func (db *Database) applyIndexesOnInsert(table *Table, rowIndex int) {
 		if _, ok := table.Indexes[col]; !ok {
 			table.Indexes[col] = make(map[string][]int)
 		}
-		table.Indexes[col][val] = append(table.Indexes[col][val], rowIndex)
+		table.Indexes[col][val] = append(table.Indexes[col][val], int(table.RowIDs[rowIndex]))
 		// Update B-tree index
 		if table.BTreeIndexes == nil {
 			table.BTreeIndexes = make(map[string]*BTree)
@@ -719,7 +1181,8 @@ func (db *Database) applyIndexesOnInsert(table *Table, rowIndex int) {
 		if _, ok := table.BTreeIndexes[col]; !ok {
 			table.BTreeIndexes[col] = NewBTree()
 		}
-		table.BTreeIndexes[col].Insert(val, rowIndex)
+		table.BTreeIndexes[col].Insert(val, int(table.RowIDs[rowIndex]))
+		table.gridInsert(col, rowIndex)
 	}
 }
 
@@ -727,17 +1190,29 @@ func (db *Database) applyIndexesOnInsert(table *Table, rowIndex int) {
 
 // BeginTransaction starts a new transaction
 func (db *Database) BeginTransaction(isolationLevel IsolationLevel) (*Transaction, error) {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	tx, err := db.TransactionManager.BeginTransaction(isolationLevel)
 	if err != nil {
 		return nil, err
 	}
+	if isolationLevel >= RepeatableRead {
+		db.snapshotTxs.Add(1)
+		db.takeSnapshot(tx)
+	}
+	db.txMu.Lock()
 	db.activeTransactions[tx.ID] = tx
+	db.txMu.Unlock()
 	db.currentTransaction = tx
 	return tx, nil
 }
 
 // CommitTransaction commits the current transaction
 func (db *Database) CommitTransaction() error {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	if db.currentTransaction == nil {
 		return fmt.Errorf("no active transaction")
 	}
@@ -745,15 +1220,18 @@ func (db *Database) CommitTransaction() error {
 	txID := db.currentTransaction.ID
 	err := db.TransactionManager.CommitTransaction(txID)
 
-	if err == nil {
-		delete(db.activeTransactions, txID)
-		db.currentTransaction = nil
+	// A commit that fails rolls the transaction back
+	if err == nil || !db.currentTransaction.isActive() {
+		db.endTransaction()
 	}
 	return err
 }
 
 // RollbackTransaction rolls back the current transaction
 func (db *Database) RollbackTransaction() error {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	if db.currentTransaction == nil {
 		return fmt.Errorf("no active transaction")
 	}
@@ -761,12 +1239,24 @@ func (db *Database) RollbackTransaction() error {
 	txID := db.currentTransaction.ID
 	err := db.TransactionManager.RollbackTransaction(txID)
 	if err == nil {
-		delete(db.activeTransactions, txID)
-		db.currentTransaction = nil
+		db.endTransaction()
 	}
 	return err
 }
 
+// endTransaction forgets the current transaction once it has committed or
+// rolled back
+func (db *Database) endTransaction() {
+	tx := db.currentTransaction
+	db.txMu.Lock()
+	delete(db.activeTransactions, tx.ID)
+	db.txMu.Unlock()
+	db.currentTransaction = nil
+	if tx.IsolationLevel >= RepeatableRead && db.snapshotTxs.Add(-1) == 0 {
+		db.forgetRowVersions()
+	}
+}
+
 // CreateSavepoint creates a savepoint in the current transaction
 func (db *Database) CreateSavepoint(name string) error {
 	if db.currentTransaction == nil {
@@ -788,19 +1278,71 @@ func (db *Database) GetCurrentTransaction() *Transaction {
 	return db.currentTransaction
 }
 
+// Session returns another handle on the database, with no transaction open
+func (db *Database) Session() *Database {
+	return &Database{database: db.database}
+}
+
+// SameDatabase reports whether db and other are handles on one database
+func (db *Database) SameDatabase(other *Database) bool {
+	return other != nil && db.database == other.database
+}
+
 // Transaction-aware versions of existing methods
 
 // CreateTableTx creates a table within a transaction
 func (db *Database) CreateTableTx(name string, columns []string) string {
+	return db.CreateTableWithOptionsTx(name, columns, db.DefaultTableOptions())
+}
+
+// CreateTableWithOptionsTx creates a table with the given options, within
+// the current transaction if there is one
+func (db *Database) CreateTableWithOptionsTx(name string, columns []string, opts TableOptions) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	name = strings.ToLower(name)
-	if _, exists := db.Tables[name]; exists {
+	if _, exists := db.lookupTable(name); exists {
 		return fmt.Sprintf("Table %s already exists", name)
 	}
+	specs, err := timestampedColumns(columns, opts.Timestamps)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if specs, err = versionedColumns(specs, opts.Versioned); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	defs, err := parseColumnSpecs(specs)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := defs.resolveForeignKeys(name, db.lookupTable); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if msg := db.unreadableTable(name); msg != "" {
+		return msg
+	}
 
 	// If we're in a transaction, add operation to transaction
 	if db.currentTransaction != nil {
 		data := map[string]interface{}{
-			"columns": columns,
+			"columns": specs,
+		}
+		if opts.Unlogged {
+			data["unlogged"] = true
+		}
+		if opts.Versioned {
+			data["versioned"] = true
+		}
+		if opts.Timestamps {
+			data["timestamps"] = true
+		}
+		if opts.Encrypted {
+			keyID, err := db.Keys.Current()
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			data["key_id"] = keyID
 		}
 		if err := db.TransactionManager.AddOperation(db.currentTransaction.ID, WAL_CREATE_TABLE, name, data); err != nil {
 			return fmt.Sprintf("Failed to add operation to transaction: %v", err)
@@ -809,22 +1351,44 @@ func (db *Database) CreateTableTx(name string, columns []string) string {
 	}
 
 	// Original non-transactional behavior
-	return db.CreateTable(name, columns)
+	return db.createTable(name, columns, opts)
 }
 
 // InsertTx inserts a row within a transaction
 func (db *Database) InsertTx(tableName string, values []string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	values, msg := table.versionedRow(table.timestampedRow(values, time.Now()))
+	if msg != "" {
+		return msg
+	}
 	if len(values) != len(table.Columns) {
 		return "Column count does not match"
 	}
+	if values, msg = table.constrainedRow(values); msg != "" {
+		return msg
+	}
+	if values, msg = table.typedRow(values); msg != "" {
+		return msg
+	}
+	if msg := db.checkForeignKeys(table, values, nil); msg != "" {
+		return msg
+	}
 
 	// If we're in a transaction, add operation to transaction
 	if db.currentTransaction != nil {
+		if msg := db.runInsertHooks(tableName, values); msg != "" {
+			return msg
+		}
 		data := map[string]interface{}{
 			"values": values,
 		}
@@ -835,30 +1399,88 @@ func (db *Database) InsertTx(tableName string, values []string) string {
 	}
 
 	// Original non-transactional behavior
-	return db.Insert(tableName, values)
+	return db.insert(tableName, values)
 }
 
 // UpdateTx updates a row within a transaction
 func (db *Database) UpdateTx(tableName string, rowIndex int, values []string) string {
+	return db.updateTx(tableName, atIndex(rowIndex), values, 0)
+}
+
+// UpdateByIDTx updates the row with ID id within a transaction
+func (db *Database) UpdateByIDTx(tableName string, id int64, values []string) string {
+	return db.updateTx(tableName, withRowID(id), values, 0)
+}
+
+// UpdateAtVersionTx is UpdateTx for a row of a versioned table that must
+// still be at version
+func (db *Database) UpdateAtVersionTx(tableName string, rowIndex int, values []string, version int64) string {
+	return db.updateTx(tableName, atIndex(rowIndex), values, version)
+}
+
+// UpdateByIDAtVersionTx is UpdateByIDTx for a row of a versioned table that
+// must still be at version
+func (db *Database) UpdateByIDAtVersionTx(tableName string, id int64, values []string, version int64) string {
+	return db.updateTx(tableName, withRowID(id), values, version)
+}
+
+// updateTx updates the target row, queuing the update by row ID when a
+// transaction is open so that earlier deletes cannot shift it onto another
+// row. A versioned table's row must be at version expected unless it is 0.
+func (db *Database) updateTx(tableName string, target rowTarget, values []string, expected int64) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
 
-	if rowIndex < 0 || rowIndex >= len(table.Rows) {
-		return "Row index out of bounds"
+	rows, ids := db.visibleRowSet(table)
+	rowIndex, msg := target.find(rows, ids)
+	if msg != "" {
+		return msg
 	}
 
 	if len(values) != len(table.Columns) {
 		return "Column count does not match"
 	}
+	if values, msg = table.constrainedRow(values); msg != "" {
+		return msg
+	}
+	if values, msg = table.typedRow(values); msg != "" {
+		return msg
+	}
+	if msg := db.checkForeignKeys(table, values, rows[rowIndex]); msg != "" {
+		return msg
+	}
+	if msg := db.referencedKeyChanges(table, ids[rowIndex], rows[rowIndex], values); msg != "" {
+		return msg
+	}
 
 	// If we're in a transaction, add operation to transaction
 	if db.currentTransaction != nil {
+		touched := table.touchedRow(rows[rowIndex], values, time.Now())
+		next, msg := table.nextVersion(ids[rowIndex], rows[rowIndex], touched, expected)
+		if msg != "" {
+			return msg
+		}
+		if msg := db.runUpdateHooks(tableName, rowIndex, rows[rowIndex], next); msg != "" {
+			return msg
+		}
 		data := map[string]interface{}{
 			"row_index": float64(rowIndex),
-			"values":    values,
+			"row_id":    ids[rowIndex],
+			"values":    next,
+		}
+		// The row must still be at the version the transaction saw when the
+		// update is applied at commit
+		if table.Versioned {
+			data["version"] = float64(table.rowVersion(rows[rowIndex]))
 		}
 		if err := db.TransactionManager.AddOperation(db.currentTransaction.ID, WAL_UPDATE, tableName, data); err != nil {
 			return fmt.Sprintf("Failed to add operation to transaction: %v", err)
@@ -867,43 +1489,97 @@ func (db *Database) UpdateTx(tableName string, rowIndex int, values []string) st
 	}
 
 	// Original non-transactional behavior
-	return db.Update(tableName, rowIndex, values)
+	return db.update(tableName, withRowID(ids[rowIndex]), values, expected)
 }
 
 // DeleteTx deletes a row within a transaction
 func (db *Database) DeleteTx(tableName string, rowIndex int) string {
+	return db.deleteTx(tableName, atIndex(rowIndex))
+}
+
+// DeleteByIDTx deletes the row with ID id within a transaction
+func (db *Database) DeleteByIDTx(tableName string, id int64) string {
+	return db.deleteTx(tableName, withRowID(id))
+}
+
+// deleteTx deletes the target row, queuing the delete by row ID when a
+// transaction is open. Rows referencing it by ON DELETE CASCADE foreign
+// keys are deleted first.
+func (db *Database) deleteTx(tableName string, target rowTarget) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	tableName = strings.ToLower(tableName)
-	table, exists := db.Tables[tableName]
+	table, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
 
-	if rowIndex < 0 || rowIndex >= len(table.Rows) {
-		return "Row index out of bounds"
+	rows, ids := db.visibleRowSet(table)
+	rowIndex, msg := target.find(rows, ids)
+	if msg != "" {
+		return msg
 	}
+	id := ids[rowIndex]
 
+	var cascade []rowDelete
+	if msg := db.cascadeDeletes(table, id, rows[rowIndex], make(map[string]map[int64]bool), &cascade); msg != "" {
+		return msg
+	}
+	for _, d := range cascade {
+		if msg := db.deleteByID(d.table, d.id); isDeleteError(msg) {
+			return msg
+		}
+	}
+	return db.deleteByID(table, id)
+}
+
+// deleteByID deletes the row of table with ID id, or queues the delete in
+// the current transaction. The caller holds the write gate.
+func (db *Database) deleteByID(table *Table, id int64) string {
 	// If we're in a transaction, add operation to transaction
 	if db.currentTransaction != nil {
+		rows, ids := db.visibleRowSet(table)
+		rowIndex, msg := withRowID(id).find(rows, ids)
+		if msg != "" {
+			return msg
+		}
+		if msg := db.runDeleteHooks(table.Name, rowIndex, rows[rowIndex]); msg != "" {
+			return msg
+		}
 		data := map[string]interface{}{
 			"row_index": float64(rowIndex),
+			"row_id":    id,
 		}
-		if err := db.TransactionManager.AddOperation(db.currentTransaction.ID, WAL_DELETE, tableName, data); err != nil {
+		if err := db.TransactionManager.AddOperation(db.currentTransaction.ID, WAL_DELETE, table.Name, data); err != nil {
 			return fmt.Sprintf("Failed to add operation to transaction: %v", err)
 		}
 		return "1 row delete queued in transaction"
 	}
 
 	// Original non-transactional behavior
-	return db.Delete(tableName, rowIndex)
+	return db.deleteRow(table.Name, withRowID(id))
 }
 
 // DropTableTx drops a table within a transaction
 func (db *Database) DropTableTx(tableName string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
 	tableName = strings.ToLower(tableName)
-	_, exists := db.Tables[tableName]
+	_, exists := db.lookupTable(tableName)
 	if !exists {
 		return fmt.Sprintf(ErrTableNotFound, tableName)
 	}
+	db.catalog.RLock()
+	msg := db.referencedByOther(tableName)
+	db.catalog.RUnlock()
+	if msg != "" {
+		return msg
+	}
 
 	// If we're in a transaction, add operation to transaction
 	if db.currentTransaction != nil {
@@ -914,5 +1590,36 @@ func (db *Database) DropTableTx(tableName string) string {
 	}
 
 	// Original non-transactional behavior
-	return db.DropTable(tableName)
+	return db.dropTable(tableName)
+}
+
+// RenameTableTx renames a table with transaction support
+func (db *Database) RenameTableTx(oldName, newName string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	oldName, newName = strings.ToLower(oldName), strings.ToLower(newName)
+	if _, exists := db.lookupTable(oldName); !exists {
+		return fmt.Sprintf(ErrTableNotFound, oldName)
+	}
+	if _, exists := db.lookupTable(newName); exists {
+		return fmt.Sprintf("Table %s already exists", newName)
+	}
+	db.catalog.RLock()
+	msg := db.referencedByOther(oldName)
+	db.catalog.RUnlock()
+	if msg != "" {
+		return msg
+	}
+
+	// If we're in a transaction, add operation to transaction
+	if db.currentTransaction != nil {
+		data := map[string]interface{}{"new_name": newName}
+		if err := db.TransactionManager.AddOperation(db.currentTransaction.ID, WAL_RENAME_TABLE, oldName, data); err != nil {
+			return fmt.Sprintf("Failed to add operation to transaction: %v", err)
+		}
+		return fmt.Sprintf("Table %s rename queued in transaction", oldName)
+	}
+
+	return db.renameTable(oldName, newName)
 }
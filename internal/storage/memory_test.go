@@ -35,6 +35,20 @@ func TestCreateIndexAndSelectWhere(t *testing.T) {
 	}
 }
 
+func TestSelectAllAfterUpdateDelete(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+
+	_ = db.CreateTable("t", []string{"k", "v"})
+	_ = db.Insert("t", []string{"a", "1"})
+	_ = db.Insert("t", []string{"b", "2"})
+	_ = db.Update("t", 0, []string{"a", "10"})
+	_ = db.Delete("t", 1)
+
+	if out := db.SelectAll("t"); out != "k | v\na | 10\n" {
+		t.Fatalf("expected SELECT * to show the updated row only, got:\n%s", out)
+	}
+}
+
 func TestIndexMaintenanceOnUpdateDelete(t *testing.T) {
 	dataDir := t.TempDir()
 	db := NewDatabase(dataDir)
@@ -72,3 +86,47 @@ func TestIndexMaintenanceOnUpdateDelete(t *testing.T) {
 		t.Fatalf("expected persisted table file: %v", err)
 	}
 }
+
+func TestRenameTable(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+
+	_ = db.CreateTable("t", []string{"k", "v COLLATE NOCASE"})
+	_ = db.Insert("t", []string{"a", "1"})
+	_ = db.CreateIndex("t", "k")
+	_ = db.CreateTable("other", []string{"x"})
+
+	if msg := db.RenameTable("t", "other"); msg != "Table other already exists" {
+		t.Fatalf("rename onto existing table: %s", msg)
+	}
+	if msg := db.RenameTable("missing", "u"); msg != "Table missing not found" {
+		t.Fatalf("rename missing table: %s", msg)
+	}
+	if msg := db.RenameTable("T", "U"); msg != "Table t renamed to u" {
+		t.Fatalf("rename failed: %s", msg)
+	}
+	_ = db.Insert("u", []string{"b", "2"})
+
+	for _, name := range []string{"t.harudb", "tables/t"} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be gone, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"u.harudb", "tables/u/manifest.json"} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+	if out := db.SelectWhere("u", "k", "a"); !strings.Contains(out, "a | 1") {
+		t.Fatalf("expected index to move with the table, got:\n%s", out)
+	}
+
+	// Replaying the WAL on restart must end with the renamed table only
+	db = NewDatabase(dataDir)
+	if out := db.SelectAll("t"); out != "Table t not found" {
+		t.Errorf("old name still present after restart: %s", out)
+	}
+	if out := db.SelectAll("u"); out != "k | v\na | 1\nb | 2\n" {
+		t.Errorf("renamed table after restart:\n%s", out)
+	}
+}
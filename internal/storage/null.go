@@ -0,0 +1,29 @@
+// internal/storage/null.go
+package storage
+
+import "strings"
+
+// NullValue is how a SQL NULL is stored. Rows hold strings, so NULL is a
+// value that no SQL literal can produce.
+const NullValue = "\x00NULL"
+
+// IsNull reports whether a stored value is NULL
+func IsNull(value string) bool {
+	return value == NullValue
+}
+
+// joinRow renders a row for results, showing NULL values as NULL
+func joinRow(row []string) string {
+	for _, v := range row {
+		if IsNull(v) {
+			shown := make([]string, len(row))
+			for i, v := range row {
+				if shown[i] = v; IsNull(v) {
+					shown[i] = "NULL"
+				}
+			}
+			return strings.Join(shown, " | ")
+		}
+	}
+	return strings.Join(row, " | ")
+}
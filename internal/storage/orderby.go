@@ -0,0 +1,522 @@
+// internal/storage/orderby.go
+package storage
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+)
+
+// Query describes a SELECT with optional filtering, ordering and a row limit
+type Query struct {
+	// Where is a parsed WHERE expression (see SelectWhereAdvanced), or nil
+	Where interface{}
+	// OrderBy is the column to sort by; empty keeps table order
+	OrderBy string
+	Desc    bool
+	// ThenBy lists the columns that order the rows OrderBy leaves tied
+	ThenBy []SortKey
+	// Limit caps the number of rows returned; negative means no limit
+	Limit int
+	// RowIDs adds each row's ID as a leading rowid column
+	RowIDs bool
+	// Role masks the columns masked for it in the result (see mask.go)
+	Role string
+	// Columns lists the result columns in order; nil returns every column
+	Columns []ResultColumn
+	// Distinct returns only the first of result rows with equal values
+	Distinct bool
+}
+
+// SortKey is a column of ORDER BY and its direction
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// ResultColumn is a column of a query result: the table column Name, or
+// every column when Name is "*", headed As instead of its name when As is set
+type ResultColumn struct {
+	Name string
+	As   string
+	// Table qualifies Name in a query of several tables (see SelectJoin)
+	Table string
+	// Expr computes the column from the row, as a parsed COALESCE(a, b)
+	// does (see valueEvaluator); Name then heads it
+	Expr interface{}
+}
+
+// valueEvaluator is the interface computed result columns implement
+type valueEvaluator interface {
+	ColumnRefs() []string
+	ResolveColumns(func(string) string)
+	EvaluateValue([]string, map[string]int) string
+}
+
+// rowEvaluator is the interface WHERE expressions implement
+type rowEvaluator interface {
+	EvaluateExpression([]string, map[string]int) (bool, error)
+}
+
+// seekBounder is implemented by WHERE expressions that imply a first value
+// of a column for matching rows, such as id > 100. An index walk in ORDER BY
+// order then starts there, so keyset pagination reads only the rows it
+// returns however deep the page is.
+type seekBounder interface {
+	SeekBound(column string, desc bool) (string, bool)
+}
+
+// SelectQueryResult returns the rows matching q. An equality condition on an
+// indexed column fetches its rows through the index when the planner
+// estimates it selective enough (see choosePath). When the ORDER BY column
+// has a B-tree index, rows are read in index order, starting at the bound a
+// WHERE such as id > 100 implies, and the scan stops once LIMIT rows are
+// found; otherwise a LIMIT keeps only the best rows in a bounded heap
+// instead of sorting every match. Full sorts spill to temporary files once
+// their keys exceed QueryMemoryBudget. Scans without an index read the published
+// rows without locking and run in parallel on large tables.
+func (db *Database) SelectQueryResult(tableName string, q Query) (*ResultSet, string) {
+	p, msg := db.prepareQuery(tableName, q)
+	if msg != "" {
+		return nil, msg
+	}
+	limit := q.Limit
+	if q.Distinct {
+		// Duplicates are dropped from the result rows, so LIMIT counts
+		// after them
+		q.Limit = -1
+	}
+	rs, matched, msg := db.runQuery(p, q)
+	if msg != "" {
+		return nil, msg
+	}
+	rs, matched = maskRows(rs, matched, p.transform(q.Role))
+	if q.Distinct {
+		matched = p.distinctRows(rs.rows, matched, limit)
+	}
+	var out *ResultSet
+	if q.RowIDs {
+		out = resultWithIDs(p.resultColumns(), rs, matched)
+		out.Types = append([]ColumnType{TypeInt}, p.resultTypes()...)
+	} else {
+		out = newResultSet(p.resultColumns(), rs.rows, matched)
+		out.Types = p.resultTypes()
+	}
+	return out, ""
+}
+
+// SelectQuery is SelectQueryResult rendered as text
+func (db *Database) SelectQuery(tableName string, q Query) string {
+	return resultText(db.SelectQueryResult(tableName, q))
+}
+
+// queryPlan is a query resolved against its table
+type queryPlan struct {
+	table         *Table
+	columnIndexes map[string]int
+	match         func([]string) (bool, error)
+	// orderIdx is the first ORDER BY column's index, or -1
+	orderIdx int
+	// order lists every ORDER BY column
+	order []orderKey
+	// output lists the indexes of the result columns and labels their
+	// headings; output is nil when every column is returned
+	output []int
+	labels []string
+	// exprs holds the computed result columns by position; their output
+	// index is -1
+	exprs map[int]valueEvaluator
+}
+
+// resultColumns returns the names of the result columns
+func (p *queryPlan) resultColumns() []string {
+	if p.output != nil {
+		return p.labels
+	}
+	return p.table.Columns
+}
+
+// resultTypes returns the types of the result columns; computed columns
+// have none
+func (p *queryPlan) resultTypes() []ColumnType {
+	if p.output == nil {
+		return p.table.columnTypes()
+	}
+	types := make([]ColumnType, len(p.output))
+	for i, col := range p.output {
+		if col >= 0 {
+			types[i] = p.table.Type(p.table.Columns[col])
+		}
+	}
+	return types
+}
+
+// transform returns the function that masks the result rows for role and
+// keeps only the result columns, or nil when rows are returned as stored
+func (p *queryPlan) transform(role string) func([]string) []string {
+	mask := p.table.masker(role)
+	if p.output == nil {
+		return mask
+	}
+	return func(row []string) []string {
+		if mask != nil {
+			row = mask(row)
+		}
+		out := make([]string, len(p.output))
+		for i, col := range p.output {
+			if col < 0 {
+				out[i] = p.exprs[i].EvaluateValue(row, p.columnIndexes)
+				continue
+			}
+			out[i] = row[col]
+		}
+		return out
+	}
+}
+
+// resolveOutput resolves the result columns of a query against its table
+func (p *queryPlan) resolveOutput(columns []ResultColumn) string {
+	for _, rc := range columns {
+		if rc.Name == "*" {
+			for i, col := range p.table.Columns {
+				p.output = append(p.output, i)
+				p.labels = append(p.labels, col)
+			}
+			continue
+		}
+		if rc.Expr != nil {
+			expr, ok := rc.Expr.(valueEvaluator)
+			if !ok {
+				return "Invalid result column expression type"
+			}
+			expr.ResolveColumns(p.table.resolveColumn)
+			for _, ref := range expr.ColumnRefs() {
+				if p.table.columnIndex(ref) < 0 {
+					return fmt.Sprintf("Column %s not found", ref)
+				}
+			}
+			label := rc.As
+			if label == "" {
+				label = rc.Name
+			}
+			if p.exprs == nil {
+				p.exprs = make(map[int]valueEvaluator)
+			}
+			p.exprs[len(p.output)] = expr
+			p.output = append(p.output, -1)
+			p.labels = append(p.labels, label)
+			continue
+		}
+		idx := p.table.columnIndex(rc.Name)
+		if idx < 0 {
+			return fmt.Sprintf("Column %s not found", rc.Name)
+		}
+		label := rc.As
+		if label == "" {
+			label = p.table.Columns[idx]
+		}
+		p.output = append(p.output, idx)
+		p.labels = append(p.labels, label)
+	}
+	return ""
+}
+
+// prepareQuery resolves q like resolveQuery and counts the read
+func (db *Database) prepareQuery(tableName string, q Query) (*queryPlan, string) {
+	p, msg := db.resolveQuery(tableName, q)
+	if msg != "" {
+		return nil, msg
+	}
+	p.table.stats.reads.Add(1)
+	return p, ""
+}
+
+// resolveQuery resolves the table, WHERE expression and ORDER BY column of q
+func (db *Database) resolveQuery(tableName string, q Query) (*queryPlan, string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if msg := table.externalError(); msg != "" {
+		return nil, msg
+	}
+	columnIndexes := make(map[string]int)
+	for i, col := range table.Columns {
+		columnIndexes[col] = i
+	}
+
+	var where rowEvaluator
+	if q.Where != nil {
+		var ok bool
+		if where, ok = q.Where.(rowEvaluator); !ok {
+			return nil, "Invalid WHERE expression type"
+		}
+		bindWhere(q.Where, table)
+	}
+	match := func(row []string) (bool, error) {
+		if where == nil {
+			return true, nil
+		}
+		return where.EvaluateExpression(row, columnIndexes)
+	}
+
+	p := &queryPlan{table: table, columnIndexes: columnIndexes, match: match, orderIdx: -1}
+	if q.OrderBy != "" {
+		for _, key := range append([]SortKey{{Column: q.OrderBy, Desc: q.Desc}}, q.ThenBy...) {
+			idx := table.columnIndex(key.Column)
+			if idx < 0 {
+				return nil, fmt.Sprintf("Column %s not found", key.Column)
+			}
+			p.order = append(p.order, orderKey{col: idx, coll: table.Collation(table.Columns[idx]), desc: key.Desc})
+		}
+		p.orderIdx = p.order[0].col
+	}
+	if msg := p.resolveOutput(q.Columns); msg != "" {
+		return nil, msg
+	}
+	return p, ""
+}
+
+// runQuery returns the rows snapshot and the indexes of the result rows in
+// order, or an error message
+func (db *Database) runQuery(p *queryPlan, q Query) (rowSet, []int, string) {
+	table := p.table
+	if p.orderIdx >= 0 {
+		q.OrderBy = table.Columns[p.orderIdx]
+	}
+
+	// Indexes are updated in place, so index reads hold the table's shared
+	// lock; scans read the published rows without locking. Indexes describe
+	// the current rows, so snapshot reads of a table that has since changed
+	// scan instead.
+	var rows [][]string
+	var ids []int64
+	var matched []int
+	var err error
+	path := accessPath{method: seqScan}
+	if db.readsCurrentRows(table) {
+		table.lock.RLock()
+		switch path = choosePath(p, q); path.method {
+		case indexOrder:
+			table.stats.indexLookups.Add(1)
+			rows, ids = table.Rows, table.RowIDs
+			matched, err = indexOrderRows(table, q, p.match)
+		case indexLookup:
+			table.stats.indexLookups.Add(1)
+			rows, ids = table.Rows, table.RowIDs
+			matched, err = lookupRows(table, path, p.match)
+		case gridLookup:
+			table.stats.indexLookups.Add(1)
+			rows, ids = table.Rows, table.RowIDs
+			matched, err = gridRows(table, path, p.match)
+		}
+		table.lock.RUnlock()
+	}
+	if (path.method == indexLookup || path.method == gridLookup) && err == nil {
+		if p.orderIdx >= 0 {
+			if matched, err = externalSort(rows, matched, p.rowOrder(rows), &memoryBudget{limit: db.QueryMemoryBudget}); err != nil {
+				return rowSet{}, nil, fmt.Sprintf("Error sorting rows: %v", err)
+			}
+		}
+		if q.Limit >= 0 && len(matched) > q.Limit {
+			matched = matched[:q.Limit]
+		}
+	}
+	if path.method == seqScan {
+		rows, ids = db.visibleRowSet(table)
+		order := p.rowOrder(rows)
+		switch {
+		case p.orderIdx < 0 && q.Limit >= 0:
+			matched, err = scanRows(rows, q.Limit, p.match)
+		case p.orderIdx < 0:
+			matched, err = db.matchRows(rows, p.match)
+		case q.Limit >= 0:
+			matched, err = db.topRows(rows, order, q.Limit, p.match)
+		default:
+			if matched, err = db.matchRows(rows, p.match); err == nil {
+				if matched, err = externalSort(rows, matched, order, &memoryBudget{limit: db.QueryMemoryBudget}); err != nil {
+					return rowSet{}, nil, fmt.Sprintf("Error sorting rows: %v", err)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return rowSet{}, nil, fmt.Sprintf("Error evaluating WHERE condition: %v", err)
+	}
+	return rowSet{rows: rows, ids: ids}, matched, ""
+}
+
+// indexOrdered reports whether the column's B-tree visits rows in ORDER BY
+// order. Locale collations index opaque sort keys, which do not keep numbers
+// in numeric order, so they are sorted instead.
+func indexOrdered(table *Table, column string) bool {
+	if bt := table.BTreeIndexes[column]; bt == nil || !table.indexesUsable() {
+		return false
+	}
+	coll := table.Collation(column)
+	return coll.IsBinary() || coll.collator == nil
+}
+
+// scanRows returns matching rows in table order, stopping at limit
+func scanRows(rows [][]string, limit int, match func([]string) (bool, error)) ([]int, error) {
+	var matched []int
+	for i, row := range rows {
+		if limit >= 0 && len(matched) >= limit {
+			break
+		}
+		ok, err := match(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, i)
+		}
+	}
+	return matched, nil
+}
+
+// indexOrderRows walks the ORDER BY column's B-tree, stopping at the limit.
+// The caller must hold table.lock shared.
+func indexOrderRows(table *Table, q Query, match func([]string) (bool, error)) ([]int, error) {
+	var rows []int
+	var err error
+	visit := func(_ string, group []int) bool {
+		for _, id := range group {
+			if q.Limit >= 0 && len(rows) >= q.Limit {
+				return false
+			}
+			ri, found := table.rowPosition(int64(id))
+			if !found {
+				continue
+			}
+			var ok bool
+			if ok, err = match(table.Rows[ri]); err != nil {
+				return false
+			}
+			if ok {
+				rows = append(rows, ri)
+			}
+		}
+		return q.Limit < 0 || len(rows) < q.Limit
+	}
+	bt := table.BTreeIndexes[q.OrderBy]
+	if sb, ok := q.Where.(seekBounder); ok {
+		if bound, ok := sb.SeekBound(q.OrderBy, q.Desc); ok {
+			seekIndex(bt, table.Collation(q.OrderBy).Key(bound), q.Desc, visit)
+			return rows, err
+		}
+	}
+	if q.Desc {
+		bt.Descend(visit)
+	} else {
+		bt.Ascend(visit)
+	}
+	return rows, err
+}
+
+// seekIndex walks bt in ORDER BY order from bound. WHERE compares a number
+// with text as text, while the index orders all numbers before all text, so
+// keys of the other kind than bound that come first are all visited before
+// the walk jumps to bound.
+func seekIndex(bt *BTree, bound string, desc bool, visit func(string, []int) bool) {
+	_, boundNum := parseOrderNumber(bound)
+	if boundNum == desc {
+		stopped := false
+		otherKind := func(key string, group []int) bool {
+			if _, num := parseOrderNumber(key); num == boundNum {
+				return false
+			}
+			if !visit(key, group) {
+				stopped = true
+				return false
+			}
+			return true
+		}
+		if desc {
+			bt.Descend(otherKind)
+		} else {
+			bt.Ascend(otherKind)
+		}
+		if stopped {
+			return
+		}
+	}
+	if desc {
+		bt.DescendFrom(bound, visit)
+	} else {
+		bt.AscendFrom(bound, visit)
+	}
+}
+
+// orderKey is an ORDER BY column resolved against its table
+type orderKey struct {
+	col  int
+	coll *Collation
+	desc bool
+}
+
+// rowOrder compares rows by the ORDER BY columns in turn, keeping table
+// order for ties
+type rowOrder struct {
+	rows [][]string
+	keys []orderKey
+}
+
+// rowOrder returns the order of the query's ORDER BY over rows
+func (p *queryPlan) rowOrder(rows [][]string) rowOrder {
+	return rowOrder{rows: rows, keys: p.order}
+}
+
+// less reports whether row a sorts before row b
+func (o rowOrder) less(a, b int) bool {
+	for _, k := range o.keys {
+		cmp := compareValues(o.rows[a][k.col], o.rows[b][k.col], k.coll)
+		if k.desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return a < b
+}
+
+// compareValues orders two values like compareKeys, comparing text under coll
+func compareValues(a, b string, coll *Collation) int {
+	if coll.IsBinary() {
+		return compareKeys(a, b)
+	}
+	_, aNum := parseOrderNumber(a)
+	_, bNum := parseOrderNumber(b)
+	if aNum || bNum {
+		return compareKeys(a, b)
+	}
+	return coll.Compare(a, b)
+}
+
+// rowHeap is a max-heap of row indexes: the row that sorts last is on top
+type rowHeap struct {
+	rows  []int
+	order rowOrder
+}
+
+func (h *rowHeap) Len() int           { return len(h.rows) }
+func (h *rowHeap) Less(i, j int) bool { return h.order.less(h.rows[j], h.rows[i]) }
+func (h *rowHeap) Swap(i, j int)      { h.rows[i], h.rows[j] = h.rows[j], h.rows[i] }
+func (h *rowHeap) Push(x interface{}) { h.rows = append(h.rows, x.(int)) }
+func (h *rowHeap) Pop() interface{} {
+	last := h.rows[len(h.rows)-1]
+	h.rows = h.rows[:len(h.rows)-1]
+	return last
+}
+
+// offer adds row ri if it is among the first limit rows seen so far
+func (h *rowHeap) offer(ri, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, ri)
+	} else if h.order.less(ri, h.rows[0]) {
+		h.rows[0] = ri
+		heap.Fix(h, 0)
+	}
+}
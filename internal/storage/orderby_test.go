@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBTreeOrderedTraversal(t *testing.T) {
+	bt := NewBTree()
+	keys := []string{"50", "7", "b", "100", "a", "7", "-3", "2.5", "c", "1e3"}
+	for i := 0; i < 20; i++ {
+		keys = append(keys, fmt.Sprintf("k%02d", i))
+	}
+	for i, k := range keys {
+		bt.Insert(k, i)
+	}
+
+	for i, k := range keys {
+		found := false
+		for _, ri := range bt.GetEqual(k) {
+			found = found || ri == i
+		}
+		if !found {
+			t.Errorf("GetEqual(%q) lost row %d", k, i)
+		}
+	}
+
+	var asc []string
+	bt.Ascend(func(key string, rows []int) bool {
+		asc = append(asc, key)
+		return true
+	})
+	if len(asc) != len(keys)-1 {
+		t.Fatalf("expected %d distinct keys, got %d", len(keys)-1, len(asc))
+	}
+	want := []string{"-3", "2.5", "7", "50", "100", "1e3", "a", "b", "c", "k00"}
+	if got := strings.Join(asc[:len(want)], ","); got != strings.Join(want, ",") {
+		t.Errorf("ascending order = %s, want %s", got, strings.Join(want, ","))
+	}
+
+	var desc []string
+	bt.Descend(func(key string, rows []int) bool {
+		desc = append(desc, key)
+		return len(desc) < 3
+	})
+	if strings.Join(desc, ",") != "k19,k18,k17" {
+		t.Errorf("descending traversal should stop early, got %v", desc)
+	}
+}
+
+func TestSelectQueryOrderAndLimit(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("scores", []string{"name COLLATE NOCASE", "score"})
+	for i, name := range []string{"dave", "Alice", "carol", "bob", "Eve", "frank"} {
+		db.Insert("scores", []string{name, fmt.Sprint((i * 37) % 101)})
+	}
+
+	names := func(out string) string {
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+			got = append(got, strings.Split(line, " | ")[0])
+		}
+		return strings.Join(got, ",")
+	}
+
+	// Without an index: full sort, then the bounded heap for LIMIT
+	sorted := names(db.SelectQuery("scores", Query{OrderBy: "score", Limit: -1}))
+	if sorted != "dave,bob,Alice,Eve,carol,frank" {
+		t.Fatalf("unexpected numeric order: %s", sorted)
+	}
+	if got := names(db.SelectQuery("scores", Query{OrderBy: "score", Desc: true, Limit: 2})); got != "frank,carol" {
+		t.Errorf("top-2 by score DESC = %s", got)
+	}
+	if got := names(db.SelectQuery("scores", Query{OrderBy: "name", Limit: 3})); got != "Alice,bob,carol" {
+		t.Errorf("NOCASE ordering = %s", got)
+	}
+
+	// With an index the same queries walk the B-tree
+	db.CreateIndex("scores", "score")
+	db.CreateIndex("scores", "name")
+	if got := names(db.SelectQuery("scores", Query{OrderBy: "score", Limit: -1})); got != sorted {
+		t.Errorf("index order %s differs from sort order %s", got, sorted)
+	}
+	if got := names(db.SelectQuery("scores", Query{OrderBy: "score", Desc: true, Limit: 2})); got != "frank,carol" {
+		t.Errorf("indexed top-2 by score DESC = %s", got)
+	}
+	if got := names(db.SelectQuery("scores", Query{OrderBy: "name", Desc: true, Limit: 2})); got != "frank,Eve" {
+		t.Errorf("indexed NOCASE DESC = %s", got)
+	}
+
+	if out := db.SelectQuery("scores", Query{Limit: 0}); !strings.Contains(out, "(no rows)") {
+		t.Errorf("LIMIT 0 should return no rows, got:\n%s", out)
+	}
+	if out := db.SelectQuery("scores", Query{OrderBy: "missing", Limit: -1}); !strings.Contains(out, "not found") {
+		t.Errorf("expected unknown ORDER BY column to fail, got:\n%s", out)
+	}
+}
+
+func TestSelectQueryThenBy(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("staff", []string{"name", "dept", "salary"})
+	for _, row := range [][]string{
+		{"ann", "ops", "900"}, {"bo", "dev", "1000"}, {"cy", "ops", "95"},
+		{"di", "dev", "1000"}, {"ed", "ops", "900"}, {"fay", "dev", "80.5"},
+	} {
+		db.Insert("staff", row)
+	}
+	db.CreateIndex("staff", "dept")
+
+	names := func(q Query) string {
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(db.SelectQuery("staff", q)), "\n")[1:] {
+			got = append(got, strings.Split(line, " | ")[0])
+		}
+		return strings.Join(got, ",")
+	}
+
+	// Salaries compare as numbers; ties fall to the next column
+	q := Query{OrderBy: "dept", ThenBy: []SortKey{{Column: "salary", Desc: true}, {Column: "name", Desc: true}}, Limit: -1}
+	if got := names(q); got != "di,bo,fay,ed,ann,cy" {
+		t.Errorf("dept, salary DESC, name DESC = %s", got)
+	}
+	q.Limit = 4
+	if got := names(q); got != "di,bo,fay,ed" {
+		t.Errorf("with LIMIT 4 = %s", got)
+	}
+	q.Where = &equalWhere{"dept", "ops"}
+	q.Limit = -1
+	if got := names(q); got != "ed,ann,cy" {
+		t.Errorf("filtered = %s", got)
+	}
+	if got := db.Explain("staff", q); !strings.Contains(got, "Sort by dept ASC, salary DESC, name DESC\n") {
+		t.Errorf("unexpected plan:\n%s", got)
+	}
+
+	// An index orders a single column only
+	q = Query{OrderBy: "dept", ThenBy: []SortKey{{Column: "name"}}, Limit: -1}
+	if got := names(q); got != "bo,di,fay,ann,cy,ed" {
+		t.Errorf("dept, name = %s", got)
+	}
+	q.ThenBy[0].Column = "missing"
+	if out := db.SelectQuery("staff", q); !strings.Contains(out, "not found") {
+		t.Errorf("expected unknown ORDER BY column to fail, got:\n%s", out)
+	}
+}
+
+func TestBTreeSeek(t *testing.T) {
+	bt := NewBTree()
+	keys := []string{"-3", "05", "2.5", "5", "5.0", "7", "50", "a", "b", "m"}
+	for i := 0; i < 40; i++ {
+		keys = append(keys, fmt.Sprint(i*3), fmt.Sprintf("k%02d", i))
+	}
+	for i, k := range keys {
+		bt.Insert(k, i)
+	}
+	var all []string
+	bt.Ascend(func(key string, _ []int) bool {
+		all = append(all, key)
+		return true
+	})
+
+	for _, start := range []string{"5", "5.00", "-10", "k10", "a", "zz", "0"} {
+		var want, got []string
+		for _, k := range all {
+			if compareBound(k, start) >= 0 {
+				want = append(want, k)
+			}
+		}
+		bt.AscendFrom(start, func(key string, _ []int) bool {
+			got = append(got, key)
+			return true
+		})
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("AscendFrom(%q) = %v, want %v", start, got, want)
+		}
+
+		want, got = nil, nil
+		for i := len(all) - 1; i >= 0; i-- {
+			if compareBound(all[i], start) <= 0 {
+				want = append(want, all[i])
+			}
+		}
+		bt.DescendFrom(start, func(key string, _ []int) bool {
+			got = append(got, key)
+			return true
+		})
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("DescendFrom(%q) = %v, want %v", start, got, want)
+		}
+	}
+}
+
+// boundedWhere is a WHERE expression col > bound that counts its evaluations
+type boundedWhere struct {
+	bound string
+	evals int
+}
+
+func (w *boundedWhere) EvaluateExpression(row []string, cols map[string]int) (bool, error) {
+	w.evals++
+	return compareValues(row[cols["id"]], w.bound, nil) > 0, nil
+}
+
+func (w *boundedWhere) SeekBound(column string, desc bool) (string, bool) {
+	return w.bound, column == "id" && !desc
+}
+
+func TestKeysetSeek(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("items", []string{"id", "name"})
+	for i := 0; i < 500; i++ {
+		db.Insert("items", []string{fmt.Sprint(i), fmt.Sprintf("item%d", i)})
+	}
+	db.Insert("items", []string{"x1", "text id"})
+	db.CreateIndex("items", "id")
+
+	where := &boundedWhere{bound: "480"}
+	out := db.SelectQuery("items", Query{Where: where, OrderBy: "id", Limit: 5})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 6 || !strings.HasPrefix(lines[1], "481 |") || !strings.HasPrefix(lines[5], "485 |") {
+		t.Fatalf("unexpected page:\n%s", out)
+	}
+	if where.evals > 10 {
+		t.Errorf("seek evaluated %d rows for a 5 row page", where.evals)
+	}
+
+	// Text sorts after numbers in the index but compares as text with a
+	// number, so it still matches a numeric bound
+	where = &boundedWhere{bound: "498"}
+	out = db.SelectQuery("items", Query{Where: where, OrderBy: "id", Limit: 5})
+	if !strings.Contains(out, "499 |") || !strings.Contains(out, "x1 |") {
+		t.Errorf("last page:\n%s", out)
+	}
+}
+
+func TestSelectQueryColumns(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("users", []string{"id", "name", "email"})
+	db.Insert("users", []string{"1", "Alice", "alice@example.com"})
+	db.Insert("users", []string{"2", "Bob", "bob@example.com"})
+	db.SetMask("users", "email", "user", MaskFull)
+
+	q := Query{Columns: []ResultColumn{{Name: "EMAIL", As: "contact"}, {Name: "id"}}, OrderBy: "id", Desc: true, Limit: -1}
+	if got, want := db.SelectQuery("users", q), "contact | id\nbob@example.com | 2\nalice@example.com | 1\n"; got != want {
+		t.Errorf("projection:\n got %q\nwant %q", got, want)
+	}
+	// Masks apply to the columns they cover whatever they are headed
+	q.Role = "user"
+	if got, want := db.SelectQuery("users", q), "contact | id\n**** | 2\n**** | 1\n"; got != want {
+		t.Errorf("masked projection:\n got %q\nwant %q", got, want)
+	}
+
+	q = Query{Columns: []ResultColumn{{Name: "name"}, {Name: "*"}}, Limit: 1}
+	if got, want := db.SelectQuery("users", q), "name | id | name | email\nAlice | 1 | Alice | alice@example.com\n"; got != want {
+		t.Errorf("* among columns:\n got %q\nwant %q", got, want)
+	}
+	if got := db.SelectQuery("users", Query{Columns: []ResultColumn{{Name: "phone"}}, Limit: -1}); got != "Column phone not found" {
+		t.Errorf("unknown column: %q", got)
+	}
+}
@@ -0,0 +1,272 @@
+// internal/storage/page_checkpoint.go
+//
+// Dirty page tracking and page checkpoints. A page changed in the cache is
+// marked dirty with its recovery LSN, the LSN of the first WAL entry whose
+// change is not yet in the page file, and its header records the LSN of the
+// last entry applied. The WAL entry is synced before the page is changed, so
+// a page never reaches disk ahead of the log describing it.
+//
+// A checkpoint runs with writes blocked. It stages every dirty page, in
+// recovery LSN order, to a synced temporary file, then writes the checkpoint
+// LSN and the list of staged pages to the page control file: that write is
+// the commit point. Only then are the staged files renamed over the page
+// files, the pending list cleared and a checkpoint entry logged. Recovery
+// finishes the renames of a committed checkpoint and ignores pages staged by
+// one that did not commit, so the page files hold exactly the WAL up to the
+// checkpoint LSN, and replay redoes the inserts logged after it.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// PageControlName is the file recording the page checkpoint
+const PageControlName = "pages.control"
+
+// pageControl is the content of the page control file
+type pageControl struct {
+	CheckpointLSN uint64 `json:"checkpoint_lsn"`
+	// Pending lists the page files, relative to the data directory, that a
+	// committed checkpoint staged and may not have renamed into place yet
+	Pending []string `json:"pending,omitempty"`
+}
+
+// markDirty records that page now holds the change logged at lsn. The
+// caller holds page.mu.
+func (ps *PageStorage) markDirty(tableName string, page *Page, lsn uint64) {
+	page.Modified = true
+	if lsn > page.Header.LSN {
+		page.Header.LSN = lsn
+	}
+	ps.cacheMu.Lock()
+	defer ps.cacheMu.Unlock()
+	key := pageKey{tableName, page.Header.PageNumber}
+	if _, dirty := ps.dirty[key]; !dirty {
+		ps.dirty[key] = lsn
+	}
+}
+
+// DirtyPages returns how many cached pages have changes not yet written to
+// their files
+func (ps *PageStorage) DirtyPages() int {
+	ps.cacheMu.RLock()
+	defer ps.cacheMu.RUnlock()
+	return len(ps.dirty)
+}
+
+// DropCache removes every clean page from the cache, along with the cached
+// sealing of each table, and returns the number of pages removed. Dirty
+// pages stay until a checkpoint writes them.
+func (ps *PageStorage) DropCache() int {
+	ps.cacheMu.Lock()
+	dropped := 0
+	for key := range ps.cache {
+		if _, dirty := ps.dirty[key]; !dirty {
+			delete(ps.cache, key)
+			dropped++
+		}
+	}
+	ps.cacheMu.Unlock()
+	ps.sealsMu.Lock()
+	ps.seals = make(map[string]pageSeal)
+	ps.sealsMu.Unlock()
+	return dropped
+}
+
+// CheckpointLSN returns the LSN the page files are up to date with
+func (ps *PageStorage) CheckpointLSN() uint64 {
+	ps.checkpointMu.Lock()
+	defer ps.checkpointMu.Unlock()
+	return ps.checkpointLSN
+}
+
+// Checkpoint writes every dirty page and advances the checkpoint to the last
+// LSN of wal, which may be nil when the WAL is unavailable. The caller must
+// block writes for the duration.
+func (ps *PageStorage) Checkpoint(wal *WALManager) error {
+	ps.checkpointMu.Lock()
+	defer ps.checkpointMu.Unlock()
+
+	redo := ps.checkpointLSN
+	if wal != nil && wal.LastLSN() > redo {
+		redo = wal.LastLSN()
+	}
+
+	type dirtyPage struct {
+		key    pageKey
+		recLSN uint64
+		page   *Page
+	}
+	ps.cacheMu.RLock()
+	pages := make([]dirtyPage, 0, len(ps.dirty))
+	for key, recLSN := range ps.dirty {
+		if page, ok := ps.cache[key]; ok {
+			pages = append(pages, dirtyPage{key, recLSN, page})
+		}
+	}
+	ps.cacheMu.RUnlock()
+	if len(pages) == 0 && redo == ps.checkpointLSN {
+		return nil
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		a, b := pages[i], pages[j]
+		if a.recLSN != b.recLSN {
+			return a.recLSN < b.recLSN
+		}
+		if a.key.table != b.key.table {
+			return a.key.table < b.key.table
+		}
+		return a.key.id < b.key.id
+	})
+
+	pending := make([]string, 0, len(pages))
+	for _, d := range pages {
+		d.page.mu.Lock()
+		if wal != nil && d.page.Header.LSN > redo {
+			d.page.mu.Unlock()
+			return fmt.Errorf("page %d of %s is ahead of the WAL (LSN %d > %d)", d.key.id, d.key.table, d.page.Header.LSN, redo)
+		}
+		_, err := ps.stagePage(d.key.table, d.page)
+		d.page.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to stage page %d of %s: %w", d.key.id, d.key.table, err)
+		}
+		pending = append(pending, pageFile(d.key.table, d.key.id))
+	}
+
+	if err := ps.writeControl(pageControl{CheckpointLSN: redo, Pending: pending}); err != nil {
+		return err
+	}
+	ps.checkpointLSN = redo
+	if err := ps.finishPending(pending); err != nil {
+		return err
+	}
+
+	ps.cacheMu.Lock()
+	for _, d := range pages {
+		d.page.Modified = false
+		delete(ps.dirty, d.key)
+	}
+	ps.cacheMu.Unlock()
+
+	if err := ps.writeControl(pageControl{CheckpointLSN: redo}); err != nil {
+		return err
+	}
+	if wal != nil {
+		if _, err := wal.AppendEntry(WAL_CHECKPOINT, "", map[string]interface{}{"redo_lsn": redo}); err != nil {
+			return fmt.Errorf("failed to log checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// recover loads the page control file and completes the renames of a
+// checkpoint that committed before a crash
+func (ps *PageStorage) recover() error {
+	data, err := os.ReadFile(filepath.Join(ps.dataDir, PageControlName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read page control file: %w", err)
+	}
+	var ctl pageControl
+	if err := json.Unmarshal(data, &ctl); err != nil {
+		return fmt.Errorf("failed to parse page control file: %w", err)
+	}
+	ps.checkpointLSN = ctl.CheckpointLSN
+	if len(ctl.Pending) == 0 {
+		return nil
+	}
+	if err := ps.finishPending(ctl.Pending); err != nil {
+		return err
+	}
+	return ps.writeControl(pageControl{CheckpointLSN: ctl.CheckpointLSN})
+}
+
+// finishPending renames staged page files into place. A page whose staged
+// file is gone was already renamed, or belongs to a table dropped since.
+func (ps *PageStorage) finishPending(pending []string) error {
+	dirs := make(map[string]bool)
+	for _, name := range pending {
+		pagePath := filepath.Join(ps.dataDir, name)
+		err := os.Rename(pagePath+".tmp", pagePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to rename staged page %s: %w", name, err)
+		}
+		dirs[filepath.Dir(pagePath)] = true
+	}
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeControl atomically replaces the page control file
+func (ps *PageStorage) writeControl(ctl pageControl) error {
+	data, err := json.Marshal(ctl)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page control file: %w", err)
+	}
+	path := filepath.Join(ps.dataDir, PageControlName)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return fmt.Errorf("failed to write page control file: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write page control file: %w", err)
+	}
+	return syncDir(ps.dataDir)
+}
+
+// writeFileSync writes data to path and syncs it to disk
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkpointPages writes dirty pages and logs a checkpoint, or only logs one
+// without page storage. The caller blocks writes.
+func (db *Database) checkpointPages() error {
+	if db.PageStorage != nil {
+		return db.PageStorage.Checkpoint(db.WAL)
+	}
+	if db.WAL != nil {
+		return db.WAL.WriteCheckpoint()
+	}
+	return nil
+}
+
+// redoPageInsert applies a replayed insert to page storage unless the page
+// files already hold it. Tables without page storage are skipped.
+func (db *Database) redoPageInsert(entry *WALEntry, values []string) {
+	if db.PageStorage == nil || entry.LSN == 0 || entry.LSN <= db.PageStorage.checkpointLSN {
+		return
+	}
+	err := db.PageStorage.InsertRow(entry.TableName, values, entry.LSN)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Warning: failed to redo insert into page storage: %v\n", err)
+	}
+}
@@ -0,0 +1,155 @@
+// internal/storage/page_layout.go
+//
+// Page file layout. Each page-stored table has its own directory under
+// tables/ in the data directory, holding a manifest with the table's page
+// metadata and one file per page:
+//
+//	data/tables/users/manifest.json
+//	data/tables/users/page.1
+//	data/tables/users/page.2
+//
+// Renaming a table renames its directory, and dropping it removes the
+// directory. Older data directories kept <table>.meta and <table>.page.N
+// files next to the table files; they are moved into this layout when the
+// page storage is opened.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TablesDirName is the data directory subdirectory holding a directory
+	// per page-stored table
+	TablesDirName = "tables"
+	// ManifestName is the file in a table directory describing its pages
+	ManifestName = "manifest.json"
+	// pageFilePrefix starts the name of each page file: page.<id>
+	pageFilePrefix = "page."
+)
+
+// tableDir returns the directory holding a table's manifest and pages
+func (ps *PageStorage) tableDir(tableName string) string {
+	return filepath.Join(ps.dataDir, TablesDirName, tableName)
+}
+
+// manifestPath returns the path of a table's manifest
+func (ps *PageStorage) manifestPath(tableName string) string {
+	return filepath.Join(ps.tableDir(tableName), ManifestName)
+}
+
+// pageFile returns the path of a page file relative to the data directory
+func pageFile(tableName string, pageID uint32) string {
+	return filepath.Join(TablesDirName, tableName, pageFilePrefix+strconv.FormatUint(uint64(pageID), 10))
+}
+
+// isTableFile reports whether name, a slash-separated path relative to the
+// data directory, is a table manifest or page file
+func isTableFile(name string) bool {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != TablesDirName || parts[1] == "" || parts[1] == "." || parts[1] == ".." {
+		return false
+	}
+	if parts[2] == ManifestName {
+		return true
+	}
+	id, ok := strings.CutPrefix(parts[2], pageFilePrefix)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseUint(id, 10, 32)
+	return err == nil
+}
+
+// DropTable removes a table's pages from the cache, dirty ones included,
+// and deletes its directory
+func (ps *PageStorage) DropTable(tableName string) error {
+	ps.cacheMu.Lock()
+	for key := range ps.cache {
+		if key.table == tableName {
+			delete(ps.cache, key)
+			delete(ps.dirty, key)
+		}
+	}
+	ps.cacheMu.Unlock()
+	ps.forgetSeal(tableName)
+	if err := os.RemoveAll(ps.tableDir(tableName)); err != nil {
+		return fmt.Errorf("failed to remove pages of %s: %w", tableName, err)
+	}
+	return nil
+}
+
+// migrateLayout moves every table still stored as <table>.meta and
+// <table>.page.N files into its own directory. The .meta file is removed
+// last, so a migration interrupted by a crash is finished on the next start.
+func (ps *PageStorage) migrateLayout() error {
+	entries, err := os.ReadDir(ps.dataDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".meta" {
+			continue
+		}
+		tableName := strings.TrimSuffix(e.Name(), ".meta")
+		if err := ps.migrateTable(tableName); err != nil {
+			return fmt.Errorf("failed to migrate pages of %s: %w", tableName, err)
+		}
+	}
+	return nil
+}
+
+// migrateTable moves one table's flat page files into its directory
+func (ps *PageStorage) migrateTable(tableName string) error {
+	oldMeta := filepath.Join(ps.dataDir, tableName+".meta")
+	data, err := os.ReadFile(oldMeta)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ps.tableDir(tableName), 0755); err != nil {
+		return err
+	}
+
+	// Pages already moved by an interrupted migration are skipped
+	prefix := tableName + ".page."
+	entries, err := os.ReadDir(ps.dataDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		id, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		oldPath := filepath.Join(ps.dataDir, e.Name())
+		pageID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			// A staged page left by a checkpoint that did not commit
+			os.Remove(oldPath)
+			continue
+		}
+		if err := os.Rename(oldPath, filepath.Join(ps.dataDir, pageFile(tableName, uint32(pageID)))); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileSync(ps.manifestPath(tableName), data); err != nil {
+		return err
+	}
+	if err := syncDir(ps.tableDir(tableName)); err != nil {
+		return err
+	}
+	if err := os.Remove(oldMeta); err != nil {
+		return err
+	}
+	return syncDir(ps.dataDir)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageLayoutMigratesFlatFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("items", []string{"name"})
+	_ = db.Insert("items", []string{"a"})
+	_ = db.Insert("items", []string{"b"})
+	db.Close()
+
+	// Rewrite the table directory in the layout of older releases
+	tableDir := filepath.Join(dir, TablesDirName, "items")
+	entries, err := os.ReadDir(tableDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		flat := "items.meta"
+		if id, ok := strings.CutPrefix(e.Name(), pageFilePrefix); ok {
+			flat = "items.page." + id
+		}
+		if err := os.Rename(filepath.Join(tableDir, e.Name()), filepath.Join(dir, flat)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.RemoveAll(filepath.Join(dir, TablesDirName))
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	if rows := pageRows(t, dir, "items"); len(rows) != 2 || rows[1][0] != "b" {
+		t.Errorf("pages after migration: %v", rows)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "items.*"))
+	if len(leftovers) != 1 || filepath.Base(leftovers[0]) != "items.harudb" {
+		t.Errorf("flat files left after migration: %v", leftovers)
+	}
+
+	// New pages go to the table directory, which moves on rename and goes
+	// away on drop
+	_ = db.Insert("items", []string{"c"})
+	if msg := db.RenameTable("items", "goods"); msg != "Table items renamed to goods" {
+		t.Fatal(msg)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if rows := pageRows(t, dir, "goods"); len(rows) != 3 || rows[2][0] != "c" {
+		t.Errorf("pages after rename: %v", rows)
+	}
+	_ = db.DropTable("goods")
+	if _, err := os.Stat(filepath.Join(dir, TablesDirName, "goods")); !os.IsNotExist(err) {
+		t.Errorf("table directory after drop: %v", err)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Errorf("checkpoint after drop: %v", err)
+	}
+}
@@ -37,7 +37,12 @@
 // - Free space offset (2 bytes): start of free space
 // - Free space size (2 bytes): available space
 // - Row count (2 bytes): number of rows in page
-// - Reserved (37 bytes): for future use
+// - Timestamp (4 bytes): last modification time
+// - LSN (8 bytes): LSN of the last WAL entry applied to the page
+// - Reserved (31 bytes): for future use
+//
+// Modified pages stay in the cache, marked dirty, until a checkpoint writes
+// them (see page_checkpoint.go).
 
 package storage
 
@@ -49,9 +54,11 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -88,7 +95,8 @@ type PageHeader struct {
 	FreeSize   uint16   // Size of free space
 	RowCount   uint16   // Number of rows in page
 	Timestamp  uint32   // Last modification timestamp
-	Reserved   [39]byte // Reserved to make header exactly 64 bytes
+	LSN        uint64   // LSN of the last WAL entry applied to the page
+	Reserved   [31]byte // Reserved to make header exactly 64 bytes
 }
 
 // Page represents a single storage page
@@ -105,28 +113,63 @@ type PageStorage struct {
 	pageSize    int
 	encryption  bool
 	compression bool
-	cache       map[uint32]*Page
+	cache       map[pageKey]*Page
 	cacheMu     sync.RWMutex
 	pageFiles   map[string]*os.File
 	filesMu     sync.RWMutex
+	// dirty maps each modified cached page -> the LSN of the first WAL entry
+	// not yet written to its file; guarded by cacheMu
+	dirty map[pageKey]uint64
+	// checkpointLSN is the LSN every page file is up to date with
+	checkpointLSN uint64
+	// checkpointMu serializes checkpoints
+	checkpointMu sync.Mutex
+	// keys seals the pages of encrypted tables (see keys.go); seals caches
+	// how each table's pages are sealed, as its manifest says
+	keys    *Keyring
+	seals   map[string]pageSeal
+	sealsMu sync.Mutex
+	// repairDir holds the backups damaged pages are repaired from (see
+	// repair.go); empty disables repair on read
+	repairDir        string
+	repairPassphrase string
+}
+
+// pageKey identifies a cached page; page IDs are only unique within a table
+type pageKey struct {
+	table string
+	id    uint32
 }
 
-// NewPageStorage creates a new page-based storage manager
+// NewPageStorage creates a new page-based storage manager, completing a
+// checkpoint interrupted by a crash and moving page files kept in the old
+// flat layout into table directories
 func NewPageStorage(dataDir string, enableEncryption, enableCompression bool) *PageStorage {
-	return &PageStorage{
+	ps := &PageStorage{
 		dataDir:     dataDir,
 		pageSize:    PageSize,
 		encryption:  enableEncryption,
 		compression: enableCompression,
-		cache:       make(map[uint32]*Page),
+		cache:       make(map[pageKey]*Page),
 		pageFiles:   make(map[string]*os.File),
+		dirty:       make(map[pageKey]uint64),
+		seals:       make(map[string]pageSeal),
+	}
+	if err := ps.recover(); err != nil {
+		log.Printf("Warning: %v\n", err)
 	}
+	if err := ps.migrateLayout(); err != nil {
+		log.Printf("Warning: %v\n", err)
+	}
+	return ps
 }
 
-// CreateTable creates a new table with page-based storage
-func (ps *PageStorage) CreateTable(tableName string, columns []string) error {
-	// Create table metadata file
-	metadataPath := filepath.Join(ps.dataDir, tableName+".meta")
+// CreateTable creates a new table with page-based storage, encrypting its
+// pages with the keyring key keyID unless it is empty
+func (ps *PageStorage) CreateTable(tableName string, columns []string, keyID string) error {
+	if err := os.MkdirAll(ps.tableDir(tableName), 0755); err != nil {
+		return fmt.Errorf("failed to create directory of %s: %w", tableName, err)
+	}
 	metadata := TableMetadata{
 		Name:           tableName,
 		Columns:        columns,
@@ -137,12 +180,58 @@ func (ps *PageStorage) CreateTable(tableName string, columns []string) error {
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
+	metadata.Encryption, metadata.KeyID = PageEncryptionNone, ""
+	if keyID != "" {
+		metadata.Encryption, metadata.KeyID = PageEncryptionAES, keyID
+	}
+	ps.forgetSeal(tableName)
 
-	return ps.writeMetadata(metadataPath, &metadata)
+	return ps.writeMetadata(tableName, &metadata)
 }
 
-// InsertRow inserts a row into the table using page-based storage
-func (ps *PageStorage) InsertRow(tableName string, row []string) error {
+// RenameTable moves a table's directory and cached pages to a new name.
+// Dirty pages stay dirty under the new name.
+func (ps *PageStorage) RenameTable(oldName, newName string) error {
+	metadata, err := ps.loadMetadata(oldName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metadata of %s: %w", oldName, err)
+	}
+
+	if err := os.Rename(ps.tableDir(oldName), ps.tableDir(newName)); err != nil {
+		return fmt.Errorf("failed to rename directory of %s: %w", oldName, err)
+	}
+	metadata.Name = newName
+	metadata.UpdatedAt = time.Now()
+	if err := ps.writeMetadata(newName, metadata); err != nil {
+		return fmt.Errorf("failed to write metadata of %s: %w", newName, err)
+	}
+	ps.forgetSeal(oldName)
+	ps.forgetSeal(newName)
+
+	ps.cacheMu.Lock()
+	for key, page := range ps.cache {
+		if key.table == oldName {
+			delete(ps.cache, key)
+			ps.cache[pageKey{newName, key.id}] = page
+		}
+	}
+	for key, recLSN := range ps.dirty {
+		if key.table == oldName {
+			delete(ps.dirty, key)
+			ps.dirty[pageKey{newName, key.id}] = recLSN
+		}
+	}
+	ps.cacheMu.Unlock()
+	return nil
+}
+
+// InsertRow inserts a row into the table using page-based storage. lsn is
+// the LSN of the WAL entry logging the insert, which must already be on
+// disk; the page is written at the next checkpoint.
+func (ps *PageStorage) InsertRow(tableName string, row []string, lsn uint64) error {
 	// Serialize row data
 	rowData, err := ps.serializeRow(row)
 	if err != nil {
@@ -162,13 +251,14 @@ func (ps *PageStorage) InsertRow(tableName string, row []string) error {
 	}
 
 	// Insert row into page
+	page.mu.Lock()
+	defer page.mu.Unlock()
 	err = ps.insertRowIntoPage(page, rowData)
 	if err != nil {
 		return fmt.Errorf("failed to insert row into page: %w", err)
 	}
-
-	// Write page back to disk
-	return ps.writePage(tableName, page)
+	ps.markDirty(tableName, page, lsn)
+	return nil
 }
 
 // ReadRows reads rows from the table using page-based storage
@@ -210,8 +300,8 @@ func (ps *PageStorage) ReadRows(tableName string, offset, limit int) ([][]string
 	return rows, nil
 }
 
-// UpdateRow updates a row in the table
-func (ps *PageStorage) UpdateRow(tableName string, rowIndex int, newRow []string) error {
+// UpdateRow updates a row in the table; lsn is as for InsertRow
+func (ps *PageStorage) UpdateRow(tableName string, rowIndex int, newRow []string, lsn uint64) error {
 	// Find the page containing the row
 	pageID, pageRowIndex, err := ps.findRowLocation(tableName, rowIndex)
 	if err != nil {
@@ -225,17 +315,18 @@ func (ps *PageStorage) UpdateRow(tableName string, rowIndex int, newRow []string
 	}
 
 	// Update row in page
+	page.mu.Lock()
+	defer page.mu.Unlock()
 	err = ps.updateRowInPage(page, pageRowIndex, newRow)
 	if err != nil {
 		return fmt.Errorf("failed to update row in page: %w", err)
 	}
-
-	// Write page back to disk
-	return ps.writePage(tableName, page)
+	ps.markDirty(tableName, page, lsn)
+	return nil
 }
 
-// DeleteRow deletes a row from the table
-func (ps *PageStorage) DeleteRow(tableName string, rowIndex int) error {
+// DeleteRow deletes a row from the table; lsn is as for InsertRow
+func (ps *PageStorage) DeleteRow(tableName string, rowIndex int, lsn uint64) error {
 	// Find the page containing the row
 	pageID, pageRowIndex, err := ps.findRowLocation(tableName, rowIndex)
 	if err != nil {
@@ -249,20 +340,21 @@ func (ps *PageStorage) DeleteRow(tableName string, rowIndex int) error {
 	}
 
 	// Delete row from page
+	page.mu.Lock()
+	defer page.mu.Unlock()
 	err = ps.deleteRowFromPage(page, pageRowIndex)
 	if err != nil {
 		return fmt.Errorf("failed to delete row from page: %w", err)
 	}
-
-	// Write page back to disk
-	return ps.writePage(tableName, page)
+	ps.markDirty(tableName, page, lsn)
+	return nil
 }
 
 // loadPage loads a page from disk or cache
 func (ps *PageStorage) loadPage(tableName string, pageID uint32) (*Page, error) {
 	// Check cache first
 	ps.cacheMu.RLock()
-	if page, exists := ps.cache[pageID]; exists {
+	if page, exists := ps.cache[pageKey{tableName, pageID}]; exists {
 		ps.cacheMu.RUnlock()
 		return page, nil
 	}
@@ -274,13 +366,30 @@ func (ps *PageStorage) loadPage(tableName string, pageID uint32) (*Page, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read page file: %w", err)
 	}
+	page, err := ps.decodePage(tableName, data)
+	if errors.Is(err, errPageChecksum) && ps.repairDir != "" {
+		page, err = ps.repairPage(tableName, pageID, page.Header)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Add to cache
+	ps.cacheMu.Lock()
+	ps.cache[pageKey{tableName, pageID}] = page
+	ps.cacheMu.Unlock()
 
+	return page, nil
+}
+
+// decodePage opens, decompresses and checks the contents of a page file. A
+// page whose data does not match its checksum is returned with
+// errPageChecksum, so its header can still be read.
+func (ps *PageStorage) decodePage(tableName string, data []byte) (*Page, error) {
 	// Decrypt then decompress (encrypt after compress when writing)
-	if ps.encryption {
-		data, err = ps.decrypt(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt page: %w", err)
-		}
+	data, err := ps.openPage(tableName, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt page: %w", err)
 	}
 	if ps.compression {
 		data, err = ps.decompress(data)
@@ -300,29 +409,48 @@ func (ps *PageStorage) loadPage(tableName string, pageID uint32) (*Page, error)
 		return nil, fmt.Errorf("invalid page magic number")
 	}
 
-	// Verify checksum
-	expectedChecksum := crc32.ChecksumIEEE(data[PageHeaderSize:])
-	if header.Checksum != expectedChecksum {
-		return nil, fmt.Errorf("page checksum mismatch")
-	}
-
-	// Create page
 	page := &Page{
 		Header:   header,
 		Data:     data[PageHeaderSize:],
 		Modified: false,
 	}
 
-	// Add to cache
-	ps.cacheMu.Lock()
-	ps.cache[pageID] = page
-	ps.cacheMu.Unlock()
-
+	// Verify checksum
+	if header.Checksum != crc32.ChecksumIEEE(page.Data) {
+		return page, errPageChecksum
+	}
 	return page, nil
 }
 
 // writePage writes a page to disk
 func (ps *PageStorage) writePage(tableName string, page *Page) error {
+	tempPath, err := ps.stagePage(tableName, page)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tempPath, ps.getPagePath(tableName, page.Header.PageNumber))
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename temp page file: %w", err)
+	}
+
+	page.Modified = false
+	return nil
+}
+
+// stagePage writes a page to a temporary file next to its page file and
+// syncs it, returning the temporary file's path
+func (ps *PageStorage) stagePage(tableName string, page *Page) (string, error) {
+	seal, err := ps.pageSealing(tableName)
+	if err != nil {
+		return "", err
+	}
+	return ps.stageSealed(tableName, page, seal)
+}
+
+// stageSealed is stagePage sealing the page with seal
+func (ps *PageStorage) stageSealed(tableName string, page *Page, seal pageSeal) (string, error) {
 	// Update checksum
 	page.Header.Checksum = crc32.ChecksumIEEE(page.Data)
 	page.Header.Timestamp = uint32(time.Now().Unix())
@@ -336,33 +464,20 @@ func (ps *PageStorage) writePage(tableName string, page *Page) error {
 	if ps.compression {
 		data, err = ps.compress(data)
 		if err != nil {
-			return fmt.Errorf("failed to compress page: %w", err)
-		}
-	}
-	if ps.encryption {
-		data, err = ps.encrypt(data)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt page: %w", err)
+			return "", fmt.Errorf("failed to compress page: %w", err)
 		}
 	}
-
-	// Write to disk atomically
-	pagePath := ps.getPagePath(tableName, page.Header.PageNumber)
-	tempPath := pagePath + ".tmp"
-
-	err = os.WriteFile(tempPath, data, 0644)
+	data, err = ps.sealWith(seal, data)
 	if err != nil {
-		return fmt.Errorf("failed to write temp page file: %w", err)
+		return "", fmt.Errorf("failed to encrypt page: %w", err)
 	}
 
-	err = os.Rename(tempPath, pagePath)
-	if err != nil {
+	tempPath := ps.getPagePath(tableName, page.Header.PageNumber) + ".tmp"
+	if err := writeFileSync(tempPath, data); err != nil {
 		os.Remove(tempPath)
-		return fmt.Errorf("failed to rename temp page file: %w", err)
+		return "", fmt.Errorf("failed to write temp page file: %w", err)
 	}
-
-	page.Modified = false
-	return nil
+	return tempPath, nil
 }
 
 // packPageHeader serializes PageHeader into a stable 64-byte slice (little-endian)
@@ -387,8 +502,10 @@ func packPageHeader(h PageHeader) []byte {
 	off += 2
 	binary.LittleEndian.PutUint32(buf[off:], h.Timestamp)
 	off += 4
+	binary.LittleEndian.PutUint64(buf[off:], h.LSN)
+	off += 8
 	// Fill remaining reserved bytes with zeros
-	// off should now be 25; reserved is 39 bytes to reach 64
+	// off should now be 33; reserved is 31 bytes to reach 64
 	// leave zeros (default) for buf[off:]
 	return buf
 }
@@ -417,6 +534,9 @@ func unpackPageHeader(b []byte) (PageHeader, error) {
 	h.RowCount = binary.LittleEndian.Uint16(b[off:])
 	off += 2
 	h.Timestamp = binary.LittleEndian.Uint32(b[off:])
+	off += 4
+	// Pages written before LSNs existed have zeros here
+	h.LSN = binary.LittleEndian.Uint64(b[off:])
 	// Remaining bytes are reserved; ignore
 	return h, nil
 }
@@ -570,9 +690,90 @@ func (ps *PageStorage) decrypt(data []byte) ([]byte, error) {
 	return gcm.Open(nil, nonce, ciphertext, nil)
 }
 
+// Page encryption modes recorded in a table's manifest
+const (
+	// PageEncryptionNone stores pages in the clear
+	PageEncryptionNone = "none"
+	// PageEncryptionAES seals pages with AES-256-GCM under a keyring key
+	PageEncryptionAES = "aes-256-gcm"
+)
+
+// pageSeal is how a table's pages are sealed: mode is a PageEncryption
+// constant, or "" for manifests written before tables chose, whose pages
+// carry their own key when the page storage encrypts
+type pageSeal struct {
+	mode  string
+	keyID string
+}
+
+// pageSealing returns how a table's pages are sealed
+func (ps *PageStorage) pageSealing(tableName string) (pageSeal, error) {
+	ps.sealsMu.Lock()
+	defer ps.sealsMu.Unlock()
+	if seal, ok := ps.seals[tableName]; ok {
+		return seal, nil
+	}
+	metadata, err := ps.loadMetadata(tableName)
+	if err != nil {
+		return pageSeal{}, fmt.Errorf("failed to read metadata of %s: %w", tableName, err)
+	}
+	seal := pageSeal{mode: metadata.Encryption, keyID: metadata.KeyID}
+	ps.seals[tableName] = seal
+	return seal, nil
+}
+
+// forgetSeal drops the cached sealing of a table whose manifest changed
+func (ps *PageStorage) forgetSeal(tableName string) {
+	ps.sealsMu.Lock()
+	delete(ps.seals, tableName)
+	ps.sealsMu.Unlock()
+}
+
+// sealWith encrypts a serialized page with the given sealing
+func (ps *PageStorage) sealWith(seal pageSeal, data []byte) ([]byte, error) {
+	switch seal.mode {
+	case PageEncryptionNone:
+		return data, nil
+	case PageEncryptionAES:
+		if ps.keys == nil {
+			return nil, fmt.Errorf("no keyring for key %s", seal.keyID)
+		}
+		return ps.keys.seal(seal.keyID, data)
+	case "":
+		if ps.encryption {
+			return ps.encrypt(data)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown page encryption %q", seal.mode)
+}
+
+// openPage decrypts a page file of a table as its manifest says
+func (ps *PageStorage) openPage(tableName string, data []byte) ([]byte, error) {
+	seal, err := ps.pageSealing(tableName)
+	if err != nil {
+		return nil, err
+	}
+	switch seal.mode {
+	case PageEncryptionNone:
+		return data, nil
+	case PageEncryptionAES:
+		if ps.keys == nil {
+			return nil, fmt.Errorf("no keyring for key %s", seal.keyID)
+		}
+		return ps.keys.open(seal.keyID, data)
+	case "":
+		if ps.encryption {
+			return ps.decrypt(data)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown page encryption %q", seal.mode)
+}
+
 // Helper methods for page management
 func (ps *PageStorage) getPagePath(tableName string, pageID uint32) string {
-	return filepath.Join(ps.dataDir, fmt.Sprintf("%s.page.%d", tableName, pageID))
+	return filepath.Join(ps.dataDir, pageFile(tableName, pageID))
 }
 
 func (ps *PageStorage) findPageWithSpace(tableName string, requiredSize int) (uint32, error) {
@@ -613,7 +814,7 @@ func (ps *PageStorage) findPageWithSpace(tableName string, requiredSize int) (ui
 		metadata.FirstPageID = pageID
 	}
 	metadata.PageCount++
-	err = ps.writeMetadata(filepath.Join(ps.dataDir, tableName+".meta"), metadata)
+	err = ps.writeMetadata(tableName, metadata)
 	if err != nil {
 		return 0, err
 	}
@@ -713,11 +914,15 @@ type TableMetadata struct {
 	IndexedColumns []string  `json:"indexed_columns"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	// Encryption is how the pages are sealed (PageEncryptionNone or
+	// PageEncryptionAES with the keyring key KeyID); manifests written
+	// before tables chose leave it empty
+	Encryption string `json:"encryption,omitempty"`
+	KeyID      string `json:"key_id,omitempty"`
 }
 
 func (ps *PageStorage) loadMetadata(tableName string) (*TableMetadata, error) {
-	metadataPath := filepath.Join(ps.dataDir, tableName+".meta")
-	data, err := os.ReadFile(metadataPath)
+	data, err := os.ReadFile(ps.manifestPath(tableName))
 	if err != nil {
 		return nil, err
 	}
@@ -731,11 +936,16 @@ func (ps *PageStorage) loadMetadata(tableName string) (*TableMetadata, error) {
 	return &metadata, nil
 }
 
-func (ps *PageStorage) writeMetadata(metadataPath string, metadata *TableMetadata) error {
+// writeMetadata atomically replaces a table's manifest
+func (ps *PageStorage) writeMetadata(tableName string, metadata *TableMetadata) error {
 	data, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(metadataPath, data, 0644)
+	path := ps.manifestPath(tableName)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
 }
@@ -0,0 +1,485 @@
+// internal/storage/parquet.go
+//
+// This file implements a minimal Apache Parquet writer used by
+// EXPORT TABLE ... TO 'file.parquet'. It has no external dependencies and
+// produces files readable by Spark, DuckDB, pandas/pyarrow and friends.
+//
+// File layout written here (single row group, one data page per column):
+//
+//   "PAR1"
+//   column chunk 0: PageHeader | PLAIN encoded values
+//   column chunk 1: PageHeader | PLAIN encoded values
+//   ...
+//   FileMetaData (Thrift compact protocol)
+//   footer length (4 bytes, little-endian)
+//   "PAR1"
+//
+// Columns are written as OPTIONAL, uncompressed, PLAIN encoded, with
+// definition levels marking the NULL values, which have no value in the
+// page. A column's physical type follows its declared type: INT64 for INT,
+// DOUBLE for FLOAT, BOOLEAN for BOOL, INT32 (DATE) for DATE, INT64
+// (TIMESTAMP_MICROS) for TIMESTAMP and BYTE_ARRAY (UTF8) for TEXT and POINT.
+// The type of an untyped column is inferred from its values that are not
+// NULL: INT64 when every value parses as an integer, DOUBLE when every value
+// parses as a float, BOOLEAN for true/false columns and BYTE_ARRAY otherwise.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const parquetMagic = "PAR1"
+
+// Parquet physical types (parquet.thrift Type)
+const (
+	parquetBoolean   int32 = 0
+	parquetInt32     int32 = 1
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+)
+
+// Other parquet.thrift enum values used by the writer
+const (
+	parquetRepetitionOptional int32 = 1
+	parquetConvertedUTF8      int32 = 0
+	parquetConvertedDate      int32 = 6
+	parquetConvertedTimestamp int32 = 10 // TIMESTAMP_MICROS
+	parquetEncodingPlain      int32 = 0
+	parquetEncodingRLE        int32 = 3
+	parquetCodecUncompressed  int32 = 0
+	parquetPageTypeData       int32 = 0
+)
+
+// ExportParquet writes the contents of a table to a Parquet file at path.
+func (db *Database) ExportParquet(tableName, path string) (int, error) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return 0, fmt.Errorf("table %s not found", tableName)
+	}
+	var rows [][]string
+	if table.External != nil {
+		var err error
+		if rows, err = table.External.readRows(len(table.Columns)); err != nil {
+			return 0, err
+		}
+	} else {
+		rows = table.rowView()
+	}
+
+	types := make([]ColumnType, len(table.Columns))
+	for i, col := range table.Columns {
+		types[i] = table.Type(col)
+	}
+	data, err := encodeParquet(table.Columns, types, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, fmt.Errorf("failed to create export directory: %w", err)
+		}
+	}
+
+	// Write atomically so readers never observe a partial file
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return 0, fmt.Errorf("failed to rename export file: %w", err)
+	}
+
+	return len(rows), nil
+}
+
+// encodeParquet serializes columns of the given declared types and rows into
+// a complete Parquet file.
+func encodeParquet(columns []string, types []ColumnType, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(parquetMagic)
+
+	numRows := int64(len(rows))
+	chunks := make([]parquetColumnChunk, len(columns))
+	var totalSize int64
+
+	for ci, name := range columns {
+		// Only the values that are not NULL are written; the definition
+		// levels tell which rows have one
+		defined := make([]bool, len(rows))
+		var values []string
+		for ri, row := range rows {
+			if ci < len(row) && !IsNull(row[ci]) {
+				defined[ri] = true
+				values = append(values, row[ci])
+			}
+		}
+
+		physType, converted := parquetColumnType(types[ci], values)
+		encoded, err := encodeParquetValues(physType, converted, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode column %s: %w", name, err)
+		}
+		pageData := append(encodeDefinitionLevels(defined), encoded...)
+
+		header := parquetPageHeader(len(rows), len(pageData))
+		offset := int64(buf.Len())
+		buf.Write(header)
+		buf.Write(pageData)
+
+		size := int64(len(header) + len(pageData))
+		totalSize += size
+		chunks[ci] = parquetColumnChunk{
+			name:      name,
+			physType:  physType,
+			converted: converted,
+			offset:    offset,
+			size:      size,
+		}
+	}
+
+	footer := parquetFileMetaData(chunks, numRows, totalSize)
+	buf.Write(footer)
+
+	footerLen := make([]byte, 4)
+	binary.LittleEndian.PutUint32(footerLen, uint32(len(footer)))
+	buf.Write(footerLen)
+	buf.WriteString(parquetMagic)
+
+	return buf.Bytes(), nil
+}
+
+// parquetColumnChunk records where a column chunk was written
+type parquetColumnChunk struct {
+	name     string
+	physType int32
+	// converted is the column's converted type, -1 for none
+	converted int32
+	offset    int64
+	size      int64
+}
+
+// parquetColumnType returns the physical and converted type (-1 for none) a
+// column of the declared type is written with. values are the column's
+// values that are not NULL, from which an untyped column's type is inferred.
+func parquetColumnType(ct ColumnType, values []string) (int32, int32) {
+	switch ct {
+	case TypeInt:
+		return parquetInt64, -1
+	case TypeFloat:
+		return parquetDouble, -1
+	case TypeBool:
+		return parquetBoolean, -1
+	case TypeDate:
+		return parquetInt32, parquetConvertedDate
+	case TypeTimestamp:
+		return parquetInt64, parquetConvertedTimestamp
+	case TypeText, TypePoint:
+		return parquetByteArray, parquetConvertedUTF8
+	}
+	if physType := inferParquetType(values); physType != parquetByteArray {
+		return physType, -1
+	}
+	return parquetByteArray, parquetConvertedUTF8
+}
+
+// inferParquetType picks the narrowest physical type that can hold all values
+func inferParquetType(values []string) int32 {
+	if len(values) == 0 {
+		return parquetByteArray
+	}
+
+	allInt, allFloat, allBool := true, true, true
+	for _, v := range values {
+		if allInt {
+			if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+				allInt = false
+			}
+		}
+		if allFloat {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				allFloat = false
+			}
+		}
+		if allBool {
+			lv := strings.ToLower(v)
+			if lv != "true" && lv != "false" {
+				allBool = false
+			}
+		}
+	}
+
+	switch {
+	case allInt:
+		return parquetInt64
+	case allFloat:
+		return parquetDouble
+	case allBool:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// encodeParquetValues PLAIN-encodes the values of a single column
+func encodeParquetValues(physType, converted int32, values []string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch physType {
+	case parquetInt32:
+		// Dates are days since the Unix epoch
+		b := make([]byte, 4)
+		for _, v := range values {
+			t, err := time.Parse(DateFormat, v)
+			if err != nil {
+				return nil, err
+			}
+			binary.LittleEndian.PutUint32(b, uint32(int32(t.Unix()/86400)))
+			buf.Write(b)
+		}
+
+	case parquetInt64:
+		b := make([]byte, 8)
+		for _, v := range values {
+			var n int64
+			if converted == parquetConvertedTimestamp {
+				// Timestamps are microseconds since the Unix epoch
+				t, ok := ParseTime(v)
+				if !ok {
+					return nil, fmt.Errorf("invalid timestamp %q", v)
+				}
+				n = t.UnixMicro()
+			} else {
+				var err error
+				if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+					return nil, err
+				}
+			}
+			binary.LittleEndian.PutUint64(b, uint64(n))
+			buf.Write(b)
+		}
+
+	case parquetDouble:
+		b := make([]byte, 8)
+		for _, v := range values {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, err
+			}
+			binary.LittleEndian.PutUint64(b, math.Float64bits(f))
+			buf.Write(b)
+		}
+
+	case parquetBoolean:
+		// Booleans are bit-packed, least significant bit first
+		packed := make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if strings.EqualFold(v, "true") {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		buf.Write(packed)
+
+	default:
+		b := make([]byte, 4)
+		for _, v := range values {
+			binary.LittleEndian.PutUint32(b, uint32(len(v)))
+			buf.Write(b)
+			buf.WriteString(v)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeDefinitionLevels writes the definition levels of an OPTIONAL column,
+// 1 for a value and 0 for NULL, as runs of the RLE/bit-packing hybrid
+// encoding behind their 4-byte length
+func encodeDefinitionLevels(defined []bool) []byte {
+	var runs []byte
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		runs = binary.AppendUvarint(runs, uint64(j-i)<<1)
+		if defined[i] {
+			runs = append(runs, 1)
+		} else {
+			runs = append(runs, 0)
+		}
+		i = j
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(runs))), runs...)
+}
+
+// parquetPageHeader builds the Thrift PageHeader for an uncompressed data page
+func parquetPageHeader(numValues, pageSize int) []byte {
+	w := &thriftWriter{}
+	w.fieldI32(1, parquetPageTypeData)
+	w.fieldI32(2, int32(pageSize))
+	w.fieldI32(3, int32(pageSize))
+	w.fieldStructBegin(5) // DataPageHeader
+	w.fieldI32(1, int32(numValues))
+	w.fieldI32(2, parquetEncodingPlain)
+	w.fieldI32(3, parquetEncodingRLE)
+	w.fieldI32(4, parquetEncodingRLE)
+	w.structEnd()
+	w.structEnd()
+	return w.buf.Bytes()
+}
+
+// parquetFileMetaData builds the Thrift FileMetaData footer
+func parquetFileMetaData(chunks []parquetColumnChunk, numRows, totalSize int64) []byte {
+	w := &thriftWriter{}
+	w.fieldI32(1, 1) // version
+
+	// Schema: root element followed by one leaf per column
+	w.fieldListBegin(2, thriftStruct, len(chunks)+1)
+	w.structBegin()
+	w.fieldString(4, "schema")
+	w.fieldI32(5, int32(len(chunks)))
+	w.structEnd()
+	for _, c := range chunks {
+		w.structBegin()
+		w.fieldI32(1, c.physType)
+		w.fieldI32(3, parquetRepetitionOptional)
+		w.fieldString(4, c.name)
+		if c.converted >= 0 {
+			w.fieldI32(6, c.converted)
+		}
+		w.structEnd()
+	}
+
+	w.fieldI64(3, numRows)
+
+	// Single row group containing every column chunk
+	w.fieldListBegin(4, thriftStruct, 1)
+	w.structBegin()
+	w.fieldListBegin(1, thriftStruct, len(chunks))
+	for _, c := range chunks {
+		w.structBegin()
+		w.fieldI64(2, c.offset)
+		w.fieldStructBegin(3) // ColumnMetaData
+		w.fieldI32(1, c.physType)
+		w.fieldListBegin(2, thriftI32, 2)
+		w.writeVarint(zigzag32(parquetEncodingPlain))
+		w.writeVarint(zigzag32(parquetEncodingRLE))
+		w.fieldListBegin(3, thriftBinary, 1)
+		w.writeBinary(c.name)
+		w.fieldI32(4, parquetCodecUncompressed)
+		w.fieldI64(5, numRows)
+		w.fieldI64(6, c.size)
+		w.fieldI64(7, c.size)
+		w.fieldI64(9, c.offset)
+		w.structEnd()
+		w.structEnd()
+	}
+	w.fieldI64(2, totalSize)
+	w.fieldI64(3, numRows)
+	w.structEnd()
+
+	w.fieldString(6, "HaruDB")
+	w.structEnd()
+	return w.buf.Bytes()
+}
+
+// Thrift compact protocol type identifiers
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter is a tiny Thrift compact protocol encoder covering the subset
+// of types needed for Parquet metadata.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	idStack []int16
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	delta := id - w.lastID
+	if delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.writeVarint(uint64(uint16((id << 1) ^ (id >> 15))))
+	}
+	w.lastID = id
+}
+
+func (w *thriftWriter) fieldI32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.writeVarint(zigzag32(v))
+}
+
+func (w *thriftWriter) fieldI64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.writeVarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) fieldString(id int16, s string) {
+	w.fieldHeader(id, thriftBinary)
+	w.writeBinary(s)
+}
+
+func (w *thriftWriter) fieldListBegin(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.buf.WriteByte(0xF0 | elemType)
+		w.writeVarint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) fieldStructBegin(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.structBegin()
+}
+
+// structBegin starts a nested struct (either a struct field or a list element)
+func (w *thriftWriter) structBegin() {
+	w.idStack = append(w.idStack, w.lastID)
+	w.lastID = 0
+}
+
+// structEnd writes the stop byte and restores the enclosing field id
+func (w *thriftWriter) structEnd() {
+	w.buf.WriteByte(0)
+	if n := len(w.idStack); n > 0 {
+		w.lastID = w.idStack[n-1]
+		w.idStack = w.idStack[:n-1]
+	}
+}
+
+func (w *thriftWriter) writeBinary(s string) {
+	w.writeVarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) writeVarint(v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, v)
+	w.buf.Write(b[:n])
+}
+
+func zigzag32(v int32) uint64 {
+	return uint64(uint32((v << 1) ^ (v >> 31)))
+}
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportParquet(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+
+	_ = db.CreateTable("items", []string{"id", "name", "price"})
+	_ = db.Insert("items", []string{"1", "Laptop", "999.99"})
+	_ = db.Insert("items", []string{"2", "Mouse, wireless", "29.5"})
+
+	out := filepath.Join(dataDir, "exports", "items.parquet")
+	n, err := db.ExportParquet("items", out)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 exported rows, got %d", n)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("export is missing PAR1 magic")
+	}
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		t.Fatalf("invalid footer length %d for file of %d bytes", footerLen, len(data))
+	}
+	footer := string(data[len(data)-8-footerLen : len(data)-8])
+	for _, col := range []string{"id", "name", "price"} {
+		if !strings.Contains(footer, col) {
+			t.Errorf("footer does not describe column %s", col)
+		}
+	}
+
+	if _, err := db.ExportParquet("missing", out); err == nil {
+		t.Error("expected error exporting a missing table")
+	}
+}
+
+func TestExportParquetRoundTrip(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+
+	_ = db.CreateTable("events", []string{"id INT", "name TEXT", "price FLOAT", "active BOOL", "day DATE", "at TIMESTAMP", "note"})
+	for _, row := range [][]string{
+		{"1", "Laptop", "999.99", "true", "2025-01-15", "2025-01-15 09:30:00", "7"},
+		{NullValue, "a | b", NullValue, "false", NullValue, "2024-12-31T23:59:59.5Z", NullValue},
+		{"3", NullValue, "0.5", NullValue, "1969-12-31", NullValue, "12"},
+	} {
+		if msg := db.Insert("events", row); !strings.Contains(msg, "inserted") {
+			t.Fatalf("insert failed: %s", msg)
+		}
+	}
+
+	out := filepath.Join(dataDir, "events.parquet")
+	if _, err := db.ExportParquet("events", out); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	columns, err := readParquet(data)
+	if err != nil {
+		t.Fatalf("failed to decode export: %v", err)
+	}
+
+	null := (*string)(nil)
+	v := func(s string) *string { return &s }
+	want := []parquetTestColumn{
+		{"id", parquetInt64, -1, []*string{v("1"), null, v("3")}},
+		{"name", parquetByteArray, parquetConvertedUTF8, []*string{v("Laptop"), v("a | b"), null}},
+		{"price", parquetDouble, -1, []*string{v("999.99"), null, v("0.5")}},
+		{"active", parquetBoolean, -1, []*string{v("true"), v("false"), null}},
+		{"day", parquetInt32, parquetConvertedDate, []*string{v("2025-01-15"), null, v("1969-12-31")}},
+		{"at", parquetInt64, parquetConvertedTimestamp, []*string{v("2025-01-15T09:30:00Z"), v("2024-12-31T23:59:59.5Z"), null}},
+		{"note", parquetInt64, -1, []*string{v("7"), null, v("12")}},
+	}
+	if len(columns) != len(want) {
+		t.Fatalf("decoded %d columns, want %d", len(columns), len(want))
+	}
+	for i, got := range columns {
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("column %d:\n got %s\nwant %s", i, got, want[i])
+		}
+	}
+}
+
+// parquetTestColumn is a column decoded by readParquet; nil values are NULL
+type parquetTestColumn struct {
+	name      string
+	physType  int32
+	converted int32
+	values    []*string
+}
+
+func (c parquetTestColumn) String() string {
+	values := make([]string, len(c.values))
+	for i, v := range c.values {
+		values[i] = "NULL"
+		if v != nil {
+			values[i] = strconv.Quote(*v)
+		}
+	}
+	return fmt.Sprintf("%s type=%d converted=%d [%s]", c.name, c.physType, c.converted, strings.Join(values, " "))
+}
+
+// readParquet decodes the files encodeParquet writes: one row group of
+// OPTIONAL columns with one PLAIN data page each
+func readParquet(data []byte) ([]parquetTestColumn, error) {
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return nil, fmt.Errorf("missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-footerLen : len(data)-8]}
+	meta := r.readStruct()
+	if r.err != nil {
+		return nil, r.err
+	}
+	numRows := int(meta[3].(int64))
+	schema := meta[2].([]interface{})[1:]
+	chunks := meta[4].([]interface{})[0].(map[int16]interface{})[1].([]interface{})
+
+	var columns []parquetTestColumn
+	for i, element := range schema {
+		el := element.(map[int16]interface{})
+		if el[3].(int64) != int64(parquetRepetitionOptional) {
+			return nil, fmt.Errorf("column %s is not OPTIONAL", el[4])
+		}
+		col := parquetTestColumn{name: string(el[4].([]byte)), physType: int32(el[1].(int64)), converted: -1}
+		if converted, ok := el[6]; ok {
+			col.converted = int32(converted.(int64))
+		}
+
+		offset := chunks[i].(map[int16]interface{})[3].(map[int16]interface{})[9].(int64)
+		r := &thriftReader{data: data[offset:]}
+		header := r.readStruct()
+		if r.err != nil {
+			return nil, r.err
+		}
+		page := r.data[r.pos : r.pos+int(header[2].(int64))]
+		if n := header[5].(map[int16]interface{})[1].(int64); int(n) != numRows {
+			return nil, fmt.Errorf("column %s has %d values for %d rows", col.name, n, numRows)
+		}
+
+		levelsLen := int(binary.LittleEndian.Uint32(page))
+		defined, err := decodeDefinitionLevels(page[4:4+levelsLen], numRows)
+		if err != nil {
+			return nil, err
+		}
+		page = page[4+levelsLen:]
+		for row, bit := 0, 0; row < numRows; row++ {
+			if !defined[row] {
+				col.values = append(col.values, nil)
+				continue
+			}
+			var value string
+			switch col.physType {
+			case parquetBoolean:
+				value = strconv.FormatBool(page[bit/8]&(1<<uint(bit%8)) != 0)
+				bit++
+			case parquetInt32:
+				days := int32(binary.LittleEndian.Uint32(page))
+				value, page = time.Unix(int64(days)*86400, 0).UTC().Format(DateFormat), page[4:]
+			case parquetInt64:
+				n := int64(binary.LittleEndian.Uint64(page))
+				value, page = strconv.FormatInt(n, 10), page[8:]
+				if col.converted == parquetConvertedTimestamp {
+					value = time.UnixMicro(n).UTC().Format(time.RFC3339Nano)
+				}
+			case parquetDouble:
+				f := math.Float64frombits(binary.LittleEndian.Uint64(page))
+				value, page = strconv.FormatFloat(f, 'g', -1, 64), page[8:]
+			default:
+				n := int(binary.LittleEndian.Uint32(page))
+				value, page = string(page[4:4+n]), page[4+n:]
+			}
+			col.values = append(col.values, &value)
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// decodeDefinitionLevels decodes n definition levels of bit width 1 written
+// in the RLE/bit-packing hybrid encoding
+func decodeDefinitionLevels(data []byte, n int) ([]bool, error) {
+	var levels []bool
+	for len(levels) < n {
+		header, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, fmt.Errorf("truncated definition levels")
+		}
+		data = data[size:]
+		if header&1 == 1 {
+			groups := int(header >> 1)
+			for i := 0; i < groups*8; i++ {
+				levels = append(levels, data[i/8]&(1<<uint(i%8)) != 0)
+			}
+			data = data[groups:]
+			continue
+		}
+		for i := uint64(0); i < header>>1; i++ {
+			levels = append(levels, data[0] == 1)
+		}
+		data = data[1:]
+	}
+	return levels[:n], nil
+}
+
+// thriftReader decodes Thrift compact protocol structs into maps of field
+// ID to value: int64 for integers, []byte for binary, []interface{} for
+// lists and map[int16]interface{} for structs
+type thriftReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *thriftReader) readVarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.err = fmt.Errorf("invalid varint at %d", r.pos)
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for r.err == nil && r.pos < len(r.data) {
+		b := r.data[r.pos]
+		r.pos++
+		if b == 0 {
+			return fields
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v := r.readVarint()
+			id = int16(v>>1) ^ -int16(v&1)
+		}
+		fields[id] = r.readValue(b & 0x0F)
+	}
+	if r.err == nil {
+		r.err = fmt.Errorf("unterminated struct")
+	}
+	return fields
+}
+
+func (r *thriftReader) readValue(typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		r.pos++
+		return int64(int8(r.data[r.pos-1]))
+	case 4, thriftI32, thriftI64:
+		v := r.readVarint()
+		return int64(v>>1) ^ -int64(v&1)
+	case thriftBinary:
+		n := int(r.readVarint())
+		r.pos += n
+		return r.data[r.pos-n : r.pos]
+	case thriftList:
+		b := r.data[r.pos]
+		r.pos++
+		size := int(b >> 4)
+		if size == 15 {
+			size = int(r.readVarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.readValue(b & 0x0F)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	r.err = fmt.Errorf("unsupported thrift type %d", typ)
+	return nil
+}
+
+func TestInferParquetType(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   int32
+	}{
+		{[]string{"1", "-2", "30"}, parquetInt64},
+		{[]string{"1", "2.5"}, parquetDouble},
+		{[]string{"true", "FALSE"}, parquetBoolean},
+		{[]string{"1", "abc"}, parquetByteArray},
+		{nil, parquetByteArray},
+	}
+
+	for _, tt := range tests {
+		if got := inferParquetType(tt.values); got != tt.want {
+			t.Errorf("inferParquetType(%v) = %d, want %d", tt.values, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,10 +12,45 @@ import (
 
 // onDiskTable is the JSON layout stored in .harudb files
 type onDiskTable struct {
-	Name           string     `json:"name"`
-	Columns        []string   `json:"columns"`
-	Rows           [][]string `json:"rows"`
-	IndexedColumns []string   `json:"indexed_columns,omitempty"`
+	Name    string     `json:"name"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	// RowIDs is parallel to Rows; files written before rows had IDs lack
+	// it and their rows are numbered from 1 when loaded
+	RowIDs         []int64  `json:"row_ids,omitempty"`
+	NextRowID      int64    `json:"next_row_id,omitempty"`
+	IndexedColumns []string `json:"indexed_columns,omitempty"`
+	// Collations maps column name -> collation name for non-BINARY columns
+	Collations map[string]string `json:"collations,omitempty"`
+	// Types maps column name -> type name for typed columns
+	Types map[string]string `json:"types,omitempty"`
+	// NotNull lists the NOT NULL columns; Defaults maps column name ->
+	// default value
+	NotNull     []string          `json:"not_null,omitempty"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+	ForeignKeys []ForeignKey      `json:"foreign_keys,omitempty"`
+	Comment     string            `json:"comment,omitempty"`
+	// ColumnComments maps column name -> comment for commented columns
+	ColumnComments map[string]string `json:"column_comments,omitempty"`
+	Masks          []ColumnMask      `json:"masks,omitempty"`
+	Retention      *Retention        `json:"retention,omitempty"`
+	// Location and Header describe the CSV file of an external table
+	Location string `json:"location,omitempty"`
+	Header   bool   `json:"header,omitempty"`
+	Unlogged bool   `json:"unlogged,omitempty"`
+	// Versioned tables keep row versions in their last column
+	Versioned bool `json:"versioned,omitempty"`
+	// Timestamped tables keep created_at and updated_at columns
+	Timestamps bool `json:"timestamps,omitempty"`
+	// Stats and Analysis let a restarted server size the table and plan
+	// queries without scanning it (see size.go)
+	Stats    *TableSize     `json:"stats,omitempty"`
+	Analysis *TableAnalysis `json:"analysis,omitempty"`
+	// KeyID is the keyring key of an encrypted table. Its file holds only
+	// the name, KeyID and Ciphertext, the rest of the fields sealed with the
+	// key (see keys.go).
+	KeyID      string `json:"key_id,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
 }
 
 // tablePath returns the target .harudb file path for a table
@@ -26,18 +62,68 @@ func (db *Database) tablePath(name string) string {
 // saveTable writes a table atomically to disk using a temp file + rename.
 // It writes the temp file in the same directory (required for atomic rename),
 // fsyncs the file, closes it, renames to the final path, and fsyncs the directory.
+// Unlogged tables skip both fsyncs.
 func (db *Database) saveTable(t *Table) error {
+	return db.writeTable(t, !t.Unlogged)
+}
+
+// removeTableFiles deletes a dropped table's file and page directory
+func (db *Database) removeTableFiles(name string) error {
+	if err := os.Remove(db.tablePath(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove table file: %w", err)
+	}
+	if db.PageStorage != nil {
+		return db.PageStorage.DropTable(name)
+	}
+	return nil
+}
+
+// writeTable is saveTable, fsyncing only when durable is set
+func (db *Database) writeTable(t *Table, durable bool) error {
 	// Prepare serialized payload
 	payload := onDiskTable{
 		Name:           t.Name,
 		Columns:        t.Columns,
 		Rows:           t.Rows,
+		RowIDs:         t.RowIDs,
+		NextRowID:      t.NextRowID,
 		IndexedColumns: t.IndexedColumns,
+		Collations:     collationNames(t.Collations),
+		Types:          typeNames(t.Types),
+		NotNull:        t.notNullColumns(),
+		Defaults:       t.Defaults,
+		ForeignKeys:    t.ForeignKeys,
+		Comment:        t.Comment,
+		ColumnComments: t.ColumnComments,
+		Masks:          t.Masks,
+		Retention:      t.Retention,
+		Unlogged:       t.Unlogged,
+		Versioned:      t.Versioned,
+		Timestamps:     t.Timestamps,
+		Analysis:       t.analysis.Load(),
+		KeyID:          t.KeyID,
+	}
+	if t.External != nil {
+		payload.Location = t.External.Path
+		payload.Header = t.External.Header
+	} else {
+		size := t.size()
+		size.Table = ""
+		payload.Stats = &size
 	}
 	data, err := json.MarshalIndent(&payload, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal table %s: %w", t.Name, err)
 	}
+	if t.KeyID != "" {
+		sealed, err := db.Keys.seal(t.KeyID, data)
+		if err != nil {
+			return fmt.Errorf("encrypt table %s: %w", t.Name, err)
+		}
+		if data, err = json.MarshalIndent(&onDiskTable{Name: t.Name, KeyID: t.KeyID, Ciphertext: sealed}, "", "  "); err != nil {
+			return fmt.Errorf("marshal table %s: %w", t.Name, err)
+		}
+	}
 
 	dir := db.DataDir
 	finalPath := db.tablePath(t.Name)
@@ -57,10 +143,12 @@ func (db *Database) saveTable(t *Table) error {
 	}
 
 	// Ensure data is flushed to disk for the temp file
-	if err := tempFile.Sync(); err != nil {
-		tempFile.Close()
-		os.Remove(tempPath)
-		return fmt.Errorf("fsync temp file %s: %w", tempPath, err)
+	if durable {
+		if err := tempFile.Sync(); err != nil {
+			tempFile.Close()
+			os.Remove(tempPath)
+			return fmt.Errorf("fsync temp file %s: %w", tempPath, err)
+		}
 	}
 
 	// Close the temp file
@@ -87,6 +175,9 @@ func (db *Database) saveTable(t *Table) error {
 afterRename:
 	// Best-effort fsync of containing directory so the rename is durable.
 	// If this fails, we still return an error so callers know persistence may be weaker.
+	if !durable {
+		return nil
+	}
 	if err := syncDir(dir); err != nil {
 		return fmt.Errorf("sync dir %s: %w", dir, err)
 	}
@@ -102,39 +193,140 @@ func (db *Database) loadTables() error {
 		return err
 	}
 
+	var names []string
 	for _, e := range entries {
-		if e.IsDir() {
-			continue
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".harudb" {
+			names = append(names, e.Name())
 		}
-		if filepath.Ext(e.Name()) != ".harudb" {
-			continue
+	}
+	db.recovery.TablesTotal.Store(int64(len(names)))
+
+	// Files are read and indexed in parallel but added in directory order,
+	// so the last of two files naming the same table wins as before
+	loaded := make([]*Table, len(names))
+	runParallel(len(names), func(i int) {
+		loaded[i] = db.loadTable(names[i])
+		if loaded[i] != nil {
+			db.rebuildAllIndexes(loaded[i])
+		}
+		db.recovery.TablesLoaded.Add(1)
+	})
+	for _, t := range loaded {
+		if t != nil {
+			db.addTable(t)
+		}
+	}
+	return nil
+}
+
+// loadTable reads the table file named file, returning nil if it is
+// unreadable or invalid
+func (db *Database) loadTable(file string) *Table {
+	raw, err := os.ReadFile(filepath.Join(db.DataDir, file))
+	if err != nil {
+		// skip unreadable files
+		return nil
+	}
+	var disk onDiskTable
+	if err := json.Unmarshal(raw, &disk); err != nil {
+		// skip invalid JSON (do not stop loading other tables)
+		return nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(file), ".harudb")
+	if disk.Name != "" {
+		name = strings.ToLower(disk.Name)
+	}
+	if disk.Ciphertext != nil {
+		keyID := disk.KeyID
+		plain, err := db.Keys.open(keyID, disk.Ciphertext)
+		if err == nil {
+			disk = onDiskTable{}
+			err = json.Unmarshal(plain, &disk)
 		}
-		path := filepath.Join(db.DataDir, e.Name())
-		raw, err := os.ReadFile(path)
 		if err != nil {
-			// skip unreadable files
+			log.Printf("Warning: table %s cannot be decrypted: %v\n", name, err)
+			db.markUnreadable(name, err)
+			return nil
+		}
+		disk.KeyID = keyID
+	}
+	t := &Table{
+		Name:           name,
+		Columns:        disk.Columns,
+		Rows:           disk.Rows,
+		RowIDs:         disk.RowIDs,
+		NextRowID:      disk.NextRowID,
+		IndexedColumns: disk.IndexedColumns,
+		Indexes:        make(map[string]map[string][]int),
+		Defaults:       disk.Defaults,
+		ForeignKeys:    disk.ForeignKeys,
+		Comment:        disk.Comment,
+		ColumnComments: disk.ColumnComments,
+		Masks:          disk.Masks,
+		Retention:      disk.Retention,
+		Unlogged:       disk.Unlogged,
+		KeyID:          disk.KeyID,
+		Versioned:      disk.Versioned,
+		Timestamps:     disk.Timestamps,
+	}
+	if disk.Location != "" {
+		t.External = &ExternalSource{Path: disk.Location, Header: disk.Header}
+	}
+	for col, collName := range disk.Collations {
+		coll, err := ParseCollation(collName)
+		if err != nil {
+			log.Printf("Warning: table %s column %s: %v; using BINARY\n", name, col, err)
 			continue
 		}
-		var disk onDiskTable
-		if err := json.Unmarshal(raw, &disk); err != nil {
-			// skip invalid JSON (do not stop loading other tables)
+		if t.Collations == nil {
+			t.Collations = make(map[string]*Collation)
+		}
+		t.Collations[col] = coll
+	}
+	for col, typeName := range disk.Types {
+		ct, err := ParseColumnType(typeName)
+		if err != nil {
+			log.Printf("Warning: table %s column %s: %v; leaving it untyped\n", name, col, err)
 			continue
 		}
-		name := strings.TrimSuffix(strings.ToLower(e.Name()), ".harudb")
-		if disk.Name != "" {
-			name = strings.ToLower(disk.Name)
+		if t.Types == nil {
+			t.Types = make(map[string]ColumnType)
 		}
-		t := &Table{
-			Name:           name,
-			Columns:        disk.Columns,
-			Rows:           disk.Rows,
-			IndexedColumns: disk.IndexedColumns,
-			Indexes:        make(map[string]map[string][]int),
+		t.Types[col] = ct
+	}
+	for _, col := range disk.NotNull {
+		if t.NotNull == nil {
+			t.NotNull = make(map[string]bool)
 		}
-		db.Tables[name] = t
-		db.rebuildAllIndexes(t)
+		t.NotNull[col] = true
 	}
-	return nil
+	t.initRowIDs()
+	t.restoreSize(disk.Stats)
+	if disk.Analysis != nil {
+		t.analysis.Store(disk.Analysis)
+	}
+	return t
+}
+
+// markUnreadable records a table whose file could not be decrypted
+func (db *Database) markUnreadable(name string, err error) {
+	db.unreadableMu.Lock()
+	defer db.unreadableMu.Unlock()
+	if db.unreadable == nil {
+		db.unreadable = make(map[string]error)
+	}
+	db.unreadable[name] = err
+}
+
+// unreadableTable returns the error for creating a table whose file exists
+// but could not be decrypted, or ""
+func (db *Database) unreadableTable(name string) string {
+	db.unreadableMu.Lock()
+	defer db.unreadableMu.Unlock()
+	if err, ok := db.unreadable[name]; ok {
+		return fmt.Sprintf("Error: table %s exists but cannot be decrypted: %v", name, err)
+	}
+	return ""
 }
 
 // syncDir opens the directory and calls Sync() so the rename is durable on disk.
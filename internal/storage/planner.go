@@ -0,0 +1,338 @@
+// internal/storage/planner.go
+package storage
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexSelectivityThreshold is the largest estimated fraction of a table's
+// rows an equality condition may match for the planner to fetch them through
+// an index. Above it a scan, which reads rows in order and in parallel, is
+// cheaper than visiting that many rows one lookup at a time.
+const IndexSelectivityThreshold = 0.2
+
+// mostCommonValues is how many of a column's most frequent values ANALYZE
+// keeps
+const mostCommonValues = 10
+
+// ValueCount is a value and how many rows hold it
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// ColumnStats describes the values of one column when it was analyzed.
+// Values are index keys, so they compare under the column's collation.
+type ColumnStats struct {
+	Distinct   int          `json:"distinct"`
+	MostCommon []ValueCount `json:"most_common,omitempty"`
+}
+
+// TableAnalysis holds the statistics ANALYZE collected for a table. They are
+// saved in the table file, so they survive a restart.
+type TableAnalysis struct {
+	Rows       int                    `json:"rows"`
+	Columns    map[string]ColumnStats `json:"columns"`
+	AnalyzedAt time.Time              `json:"analyzed_at"`
+}
+
+// equalityMatcher is implemented by WHERE expressions that only match rows
+// whose column equals a value, such as name = 'x' AND age > 3
+type equalityMatcher interface {
+	EqualityValue(column string) (string, bool)
+}
+
+// Analyze collects the statistics the planner estimates selectivity from,
+// for one table or for every table when tableName is empty
+func (db *Database) Analyze(tableName string) string {
+	if tableName != "" {
+		tableName = strings.ToLower(tableName)
+		table, exists := db.lookupTable(tableName)
+		if !exists {
+			return fmt.Sprintf(ErrTableNotFound, tableName)
+		}
+		if msg := table.externalError(); msg != "" {
+			return msg
+		}
+		a := db.analyzeAndSave(table)
+		return fmt.Sprintf("Table %s analyzed (%d rows)", tableName, a.Rows)
+	}
+
+	db.catalog.RLock()
+	tables := make([]*Table, 0, len(db.Tables))
+	for _, table := range db.Tables {
+		tables = append(tables, table)
+	}
+	db.catalog.RUnlock()
+
+	analyzed := 0
+	for _, table := range tables {
+		if table.externalError() != "" {
+			continue
+		}
+		db.analyzeAndSave(table)
+		analyzed++
+	}
+	return fmt.Sprintf("Analyzed %d table(s)", analyzed)
+}
+
+// analyzeAndSave analyzes table and saves the statistics in its table file
+func (db *Database) analyzeAndSave(table *Table) *TableAnalysis {
+	a := analyzeTable(table)
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	if err := db.saveTable(table); err != nil {
+		log.Printf("Warning: failed to save statistics of %s: %v\n", table.Name, err)
+	}
+	return a
+}
+
+// analyzeTable computes and stores the statistics of table's committed rows
+func analyzeTable(table *Table) *TableAnalysis {
+	rows := table.rowView()
+	a := &TableAnalysis{Rows: len(rows), Columns: make(map[string]ColumnStats), AnalyzedAt: time.Now().UTC()}
+	for ci, col := range table.Columns {
+		coll := table.Collation(col)
+		counts := make(map[string]int)
+		for _, row := range rows {
+			if ci < len(row) {
+				counts[coll.Key(row[ci])]++
+			}
+		}
+		common := make([]ValueCount, 0, len(counts))
+		for v, n := range counts {
+			common = append(common, ValueCount{Value: v, Count: n})
+		}
+		sort.Slice(common, func(i, j int) bool {
+			if common[i].Count != common[j].Count {
+				return common[i].Count > common[j].Count
+			}
+			return common[i].Value < common[j].Value
+		})
+		if len(common) > mostCommonValues {
+			common = common[:mostCommonValues]
+		}
+		a.Columns[col] = ColumnStats{Distinct: len(counts), MostCommon: common}
+	}
+	table.analysis.Store(a)
+	return a
+}
+
+// estimateSelectivity estimates the fraction of table's rows whose column
+// equals value. It reports false when the column has not been analyzed.
+// Values outside the most common ones are assumed to share the remaining
+// rows evenly.
+func estimateSelectivity(table *Table, column, value string) (float64, bool) {
+	a := table.analysis.Load()
+	if a == nil {
+		return 0, false
+	}
+	cs, ok := a.Columns[column]
+	if !ok {
+		return 0, false
+	}
+	if a.Rows == 0 {
+		return 0, true
+	}
+	key := table.Collation(column).Key(value)
+	rest, restDistinct := a.Rows, cs.Distinct
+	for _, mc := range cs.MostCommon {
+		if mc.Value == key {
+			return float64(mc.Count) / float64(a.Rows), true
+		}
+		rest -= mc.Count
+		restDistinct--
+	}
+	if rest <= 0 || restDistinct <= 0 {
+		return 0, true
+	}
+	return float64(rest) / float64(restDistinct) / float64(a.Rows), true
+}
+
+// accessMethod is how a query reads its table
+type accessMethod int
+
+const (
+	seqScan accessMethod = iota
+	// indexLookup fetches the rows an equality condition matches
+	indexLookup
+	// indexOrder walks the ORDER BY column's B-tree
+	indexOrder
+	// gridLookup fetches the rows in the grid cells a box overlaps
+	gridLookup
+)
+
+// accessPath is the planner's choice of how to read a query's rows
+type accessPath struct {
+	method accessMethod
+	// column is the indexed column used, or for a scan the column whose
+	// index the estimate ruled out
+	column string
+	value  string
+	// box is the box a grid lookup reads
+	box Box
+	// selectivity is the estimated fraction of rows matching column = value;
+	// estimated is false when the column has not been analyzed
+	selectivity float64
+	estimated   bool
+}
+
+// choosePath picks how to read the rows of p. An equality condition on an
+// indexed column is looked up unless ANALYZE statistics estimate it matches
+// more than IndexSelectivityThreshold of the rows. A lookup without an
+// estimate only wins when the B-tree cannot produce the ORDER BY order. The
+// caller holds table.lock shared and has checked that the indexes describe
+// the rows the query reads.
+func choosePath(p *queryPlan, q Query) accessPath {
+	table := p.table
+	path := accessPath{method: seqScan}
+	var skipped accessPath
+	if eq, ok := q.Where.(equalityMatcher); ok && table.indexesUsable() {
+		for _, col := range table.IndexedColumns {
+			value, ok := eq.EqualityValue(col)
+			if !ok || table.Indexes[col] == nil {
+				continue
+			}
+			c := accessPath{method: indexLookup, column: col, value: value}
+			c.selectivity, c.estimated = estimateSelectivity(table, col, value)
+			if c.estimated && c.selectivity > IndexSelectivityThreshold {
+				if skipped.column == "" || c.selectivity < skipped.selectivity {
+					skipped = c
+				}
+				continue
+			}
+			if path.method != indexLookup || (c.estimated && (!path.estimated || c.selectivity < path.selectivity)) {
+				path = c
+			}
+		}
+	}
+
+	if bm, ok := q.Where.(boxMatcher); ok && path.method != indexLookup && table.indexesUsable() {
+		for _, col := range table.IndexedColumns {
+			if box, ok := bm.WithinBox(col); ok && table.grids[col] != nil {
+				return accessPath{method: gridLookup, column: col, box: box}
+			}
+		}
+	}
+
+	// A B-tree visits ties in row ID order, so it gives the order of a
+	// single ORDER BY column only
+	if len(p.order) == 1 && indexOrdered(table, table.Columns[p.orderIdx]) && (path.method != indexLookup || !path.estimated) {
+		return accessPath{method: indexOrder, column: table.Columns[p.orderIdx]}
+	}
+	if path.method == seqScan && skipped.column != "" {
+		skipped.method = seqScan
+		return skipped
+	}
+	return path
+}
+
+// lookupRows returns the rows the index on path.column holds for path.value
+// that match, in table order. The caller holds table.lock shared.
+func lookupRows(table *Table, path accessPath, match func([]string) (bool, error)) ([]int, error) {
+	ids := table.Indexes[path.column][table.Collation(path.column).Key(path.value)]
+	candidates := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if ri, ok := table.rowPosition(int64(id)); ok {
+			candidates = append(candidates, ri)
+		}
+	}
+	return matchCandidates(table, candidates, match)
+}
+
+// matchCandidates returns the rows at the candidate positions an index
+// found that match, in table order. The caller holds table.lock shared.
+func matchCandidates(table *Table, candidates []int, match func([]string) (bool, error)) ([]int, error) {
+	slices.Sort(candidates)
+	var matched []int
+	for _, ri := range slices.Compact(candidates) {
+		ok, err := match(table.Rows[ri])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, ri)
+		}
+	}
+	return matched, nil
+}
+
+// ExplainResult describes how SelectQuery would read the rows of q, one
+// step per row
+func (db *Database) ExplainResult(tableName string, q Query) (*ResultSet, string) {
+	p, msg := db.resolveQuery(tableName, q)
+	if msg != "" {
+		return nil, msg
+	}
+	table := p.table
+	limit := q.Limit
+	if q.Distinct {
+		q.Limit = -1
+	}
+	path := accessPath{method: seqScan}
+	if db.readsCurrentRows(table) {
+		table.lock.RLock()
+		path = choosePath(p, q)
+		table.lock.RUnlock()
+	}
+
+	var steps []string
+	if limit >= 0 {
+		steps = append(steps, fmt.Sprintf("Limit %d", limit))
+	}
+	if q.Distinct {
+		steps = append(steps, "Distinct")
+	}
+	if p.orderIdx >= 0 && path.method != indexOrder {
+		keys := make([]string, len(p.order))
+		for i, k := range p.order {
+			dir := "ASC"
+			if k.desc {
+				dir = "DESC"
+			}
+			keys[i] = table.Columns[k.col] + " " + dir
+		}
+		steps = append(steps, "Sort by "+strings.Join(keys, ", "))
+	}
+	estimate := ""
+	if path.estimated {
+		estimate = fmt.Sprintf("estimated %.1f%% of %d rows", path.selectivity*100, table.analysis.Load().Rows)
+	}
+	switch path.method {
+	case indexLookup:
+		step := fmt.Sprintf("Index Lookup on %s using %s", table.Name, path.column)
+		if estimate != "" {
+			step += " (" + estimate + ")"
+		} else {
+			step += " (not analyzed)"
+		}
+		steps = append(steps, step)
+	case indexOrder:
+		steps = append(steps, fmt.Sprintf("Index Scan on %s using %s", table.Name, path.column))
+	case gridLookup:
+		steps = append(steps, fmt.Sprintf("Grid Index Lookup on %s using %s within %s", table.Name, path.column, path.box))
+	default:
+		step := fmt.Sprintf("Seq Scan on %s", table.Name)
+		if path.column != "" {
+			step += fmt.Sprintf(" (index on %s skipped: %s match)", path.column, estimate)
+		}
+		steps = append(steps, step)
+	}
+	rs := &ResultSet{Columns: []string{"plan"}}
+	for _, step := range steps {
+		rs.Rows = append(rs.Rows, []string{step})
+	}
+	return rs, ""
+}
+
+// Explain is ExplainResult rendered as text
+func (db *Database) Explain(tableName string, q Query) string {
+	return resultText(db.ExplainResult(tableName, q))
+}
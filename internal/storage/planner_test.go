@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// equalWhere is a WHERE expression col = value
+type equalWhere struct {
+	col, value string
+}
+
+func (w *equalWhere) EvaluateExpression(row []string, cols map[string]int) (bool, error) {
+	return row[cols[w.col]] == w.value, nil
+}
+
+func (w *equalWhere) EqualityValue(column string) (string, bool) {
+	return w.value, column == w.col
+}
+
+func TestPlannerSelectivity(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("orders", []string{"id", "status"})
+	for i := 0; i < 100; i++ {
+		status := "shipped"
+		if i%25 == 0 {
+			status = fmt.Sprintf("held%d", i)
+		}
+		_ = db.Insert("orders", []string{fmt.Sprint(i), status})
+	}
+	_ = db.CreateIndex("orders", "status")
+
+	explain := func(value string) string {
+		return db.Explain("orders", Query{Where: &equalWhere{col: "status", value: value}, Limit: -1})
+	}
+
+	// Without statistics an equality condition on an indexed column uses the index
+	if got := explain("shipped"); !strings.Contains(got, "Index Lookup on orders using status (not analyzed)") {
+		t.Errorf("before ANALYZE: %s", got)
+	}
+
+	if msg := db.Analyze("orders"); msg != "Table orders analyzed (100 rows)" {
+		t.Fatalf("analyze: %s", msg)
+	}
+	if got := explain("shipped"); !strings.Contains(got, "Seq Scan on orders (index on status skipped: estimated 96.0% of 100 rows match)") {
+		t.Errorf("common value: %s", got)
+	}
+	if got := explain("held25"); !strings.Contains(got, "Index Lookup on orders using status (estimated 1.0% of 100 rows)") {
+		t.Errorf("rare value: %s", got)
+	}
+
+	// Both plans return the same rows
+	table, _ := db.lookupTable("orders")
+	lookups := table.stats.indexLookups.Load()
+	if got := db.SelectQuery("orders", Query{Where: &equalWhere{col: "status", value: "held50"}, Limit: -1}); got != "id | status\n50 | held50\n" {
+		t.Errorf("lookup: %q", got)
+	}
+	if table.stats.indexLookups.Load() != lookups+1 {
+		t.Error("rare value did not use the index")
+	}
+	got := db.SelectQuery("orders", Query{Where: &equalWhere{col: "status", value: "shipped"}, Limit: 2})
+	if got != "id | status\n1 | shipped\n2 | shipped\n" {
+		t.Errorf("scan: %q", got)
+	}
+	if table.stats.indexLookups.Load() != lookups+1 {
+		t.Error("common value used the index")
+	}
+}
+
+func TestPlannerOrderBy(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("items", []string{"id", "kind"})
+	for i := 0; i < 40; i++ {
+		_ = db.Insert("items", []string{fmt.Sprint(i), fmt.Sprintf("k%d", i%20)})
+	}
+	_ = db.CreateIndex("items", "id")
+	_ = db.CreateIndex("items", "kind")
+
+	q := Query{Where: &equalWhere{col: "kind", value: "k3"}, OrderBy: "id", Desc: true, Limit: 5}
+	if got := db.Explain("items", q); got != "plan\nLimit 5\nIndex Scan on items using id\n" {
+		t.Errorf("before ANALYZE: %q", got)
+	}
+	_ = db.Analyze("")
+	if got := db.Explain("items", q); got != "plan\nLimit 5\nSort by id DESC\nIndex Lookup on items using kind (estimated 5.0% of 40 rows)\n" {
+		t.Errorf("after ANALYZE: %q", got)
+	}
+	if got := db.SelectQuery("items", q); got != "id | kind\n23 | k3\n3 | k3\n" {
+		t.Errorf("lookup with ORDER BY: %q", got)
+	}
+}
+
+func TestSelectWherePlanned(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("orders", []string{"id", "status"})
+	for i := 0; i < 100; i++ {
+		status := "shipped"
+		switch {
+		case i == 7:
+			status = NullValue
+		case i%25 == 0:
+			status = fmt.Sprintf("held%d", i)
+		}
+		_ = db.Insert("orders", []string{fmt.Sprint(i), status})
+	}
+	_ = db.CreateIndex("orders", "status")
+	table, _ := db.lookupTable("orders")
+
+	// SelectWhere and a WHERE expression return the same rows the same way
+	for _, analyzed := range []bool{false, true} {
+		if analyzed {
+			db.Analyze("orders")
+		}
+		for _, value := range []string{"held50", "shipped", "NULL", "missing"} {
+			lookups := table.stats.indexLookups.Load()
+			got := db.SelectWhere("orders", "STATUS", value)
+			viaWhere := table.stats.indexLookups.Load() - lookups
+			want := db.SelectWhereAdvanced("orders", &equalWhere{col: "status", value: value})
+			if got != want {
+				t.Errorf("analyzed=%v %s: SelectWhere %q, WHERE %q", analyzed, value, got, want)
+			}
+			if viaExpr := table.stats.indexLookups.Load() - lookups - viaWhere; viaWhere != viaExpr {
+				t.Errorf("analyzed=%v %s: index lookups %d and %d", analyzed, value, viaWhere, viaExpr)
+			}
+		}
+	}
+
+	// A NULL status equals no value, not even the text NULL
+	if got := db.SelectWhere("orders", "status", "NULL"); got != "id | status\n(no rows)\n" {
+		t.Errorf("NULL: %q", got)
+	}
+	if got := db.SelectWhere("orders", "nope", "x"); got != "Error evaluating WHERE condition: column nope not found" {
+		t.Errorf("unknown column: %q", got)
+	}
+}
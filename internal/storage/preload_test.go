@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreloadExternalTable(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "codes.csv")
+	if err := os.WriteFile(csvPath, []byte("a,1\nb,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateExternalTable("codes", []string{"code", "n"}, csvPath, false)
+
+	if got := db.Preload("Codes"); got != "Table codes preloaded: 2 rows from "+csvPath {
+		t.Fatalf("Preload: %s", got)
+	}
+
+	// Scans reuse the rows while the file's size and time are unchanged...
+	info, _ := os.Stat(csvPath)
+	if err := os.WriteFile(csvPath, []byte("x,9\ny,8\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(csvPath, info.ModTime(), info.ModTime())
+	if got := db.SelectAll("codes"); got != "code | n\na | 1\nb | 2\n" {
+		t.Errorf("cached select:\n%s", got)
+	}
+
+	// ...and read the file again once it changes
+	if err := os.WriteFile(csvPath, []byte("c,3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SelectAll("codes"); got != "code | n\nc | 3\n" {
+		t.Errorf("select after change:\n%s", got)
+	}
+
+	os.Remove(csvPath)
+	if got := db.Preload("codes"); !strings.HasPrefix(got, "Error: external table codes: ") {
+		t.Errorf("Preload of a missing file: %s", got)
+	}
+	if got := db.Preload("nope"); got != "Table nope not found" {
+		t.Errorf("Preload of a missing table: %s", got)
+	}
+}
+
+func TestPreloadPages(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("items", []string{"id", "name"})
+	db.CreateIndex("items", "id")
+	for i := 0; i < 50; i++ {
+		db.Insert("items", []string{fmt.Sprint(i), strings.Repeat("x", 200)})
+	}
+	if _, err := db.FlushPageCache(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.PageStorage.cache); n != 0 {
+		t.Fatalf("%d pages cached after flush", n)
+	}
+
+	got := db.Preload("items")
+	var rows, pages, indexes int
+	if _, err := fmt.Sscanf(got, "Table items preloaded: %d rows, %d pages, %d indexes", &rows, &pages, &indexes); err != nil {
+		t.Fatalf("Preload: %s", got)
+	}
+	if rows != 50 || pages < 2 || indexes != 1 {
+		t.Errorf("Preload: %s", got)
+	}
+	if n := len(db.PageStorage.cache); n != pages {
+		t.Errorf("%d pages cached, want %d", n, pages)
+	}
+}
@@ -0,0 +1,129 @@
+// internal/storage/procedures.go
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// proceduresFileName holds the stored procedure catalog
+const proceduresFileName = "procedures.json"
+
+// Procedure is a named, parameterized list of statements
+type Procedure struct {
+	Name       string    `json:"name"`
+	Params     []string  `json:"params"`
+	Statements []string  `json:"statements"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateProcedure stores a procedure in the catalog
+func (db *Database) CreateProcedure(proc Procedure) error {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	procs, err := db.loadProcedures()
+	if err != nil {
+		return err
+	}
+
+	proc.Name = strings.ToLower(proc.Name)
+	if _, exists := procs[proc.Name]; exists {
+		return fmt.Errorf("procedure %s already exists", proc.Name)
+	}
+	if proc.CreatedAt.IsZero() {
+		proc.CreatedAt = time.Now()
+	}
+	procs[proc.Name] = proc
+
+	return db.saveProcedures(procs)
+}
+
+// DropProcedure removes a procedure from the catalog
+func (db *Database) DropProcedure(name string) error {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	procs, err := db.loadProcedures()
+	if err != nil {
+		return err
+	}
+
+	name = strings.ToLower(name)
+	if _, exists := procs[name]; !exists {
+		return fmt.Errorf("procedure %s not found", name)
+	}
+	delete(procs, name)
+
+	return db.saveProcedures(procs)
+}
+
+// GetProcedure looks up a procedure by name
+func (db *Database) GetProcedure(name string) (Procedure, error) {
+	procs, err := db.loadProcedures()
+	if err != nil {
+		return Procedure{}, err
+	}
+
+	proc, exists := procs[strings.ToLower(name)]
+	if !exists {
+		return Procedure{}, fmt.Errorf("procedure %s not found", strings.ToLower(name))
+	}
+	return proc, nil
+}
+
+// ListProcedures returns all procedures sorted by name
+func (db *Database) ListProcedures() ([]Procedure, error) {
+	procs, err := db.loadProcedures()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Procedure, 0, len(procs))
+	for _, proc := range procs {
+		list = append(list, proc)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// loadProcedures reads the procedure catalog from disk
+func (db *Database) loadProcedures() (map[string]Procedure, error) {
+	procs := make(map[string]Procedure)
+
+	data, err := os.ReadFile(filepath.Join(db.DataDir, proceduresFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return procs, nil
+		}
+		return nil, fmt.Errorf("failed to read procedures: %w", err)
+	}
+	if err := json.Unmarshal(data, &procs); err != nil {
+		return nil, fmt.Errorf("failed to parse procedures: %w", err)
+	}
+	return procs, nil
+}
+
+// saveProcedures atomically writes the procedure catalog
+func (db *Database) saveProcedures(procs map[string]Procedure) error {
+	data, err := json.MarshalIndent(procs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal procedures: %w", err)
+	}
+
+	path := filepath.Join(db.DataDir, proceduresFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write procedures: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save procedures: %w", err)
+	}
+	return nil
+}
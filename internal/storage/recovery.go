@@ -0,0 +1,126 @@
+// internal/storage/recovery.go
+//
+// Startup recovery. Opening a database loads every table file and builds
+// its indexes on a pool of workers, one table per worker at a time, then
+// replays the WAL. RecoveryProgress counts both as they go so a server can
+// report how far it has got and turn clients away until it is done. Temp
+// files left by atomic writes a crash interrupted are removed first.
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RecoveryProgress counts the work done while a database is opened
+type RecoveryProgress struct {
+	TablesTotal  atomic.Int64
+	TablesLoaded atomic.Int64
+	WALRecords   atomic.Int64
+	// Discarded counts the transactions the WAL shows in flight at a crash
+	Discarded atomic.Int64
+	done      atomic.Bool
+}
+
+// Done reports whether the database has finished opening
+func (p *RecoveryProgress) Done() bool {
+	return p.done.Load()
+}
+
+// String describes the progress, e.g. "loaded 3/10 tables, replayed 250
+// WAL records"
+func (p *RecoveryProgress) String() string {
+	s := fmt.Sprintf("loaded %d/%d tables, replayed %d WAL records",
+		p.TablesLoaded.Load(), p.TablesTotal.Load(), p.WALRecords.Load())
+	if n := p.Discarded.Load(); n > 0 {
+		s += fmt.Sprintf(", discarded %d in-flight transactions", n)
+	}
+	return s
+}
+
+// runParallel calls fn(0) through fn(n-1) on up to GOMAXPROCS workers and
+// returns once all calls have finished
+func runParallel(n int, fn func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// rebuildIndexesParallel rebuilds the indexes of every table. It is only
+// used while the database is opened, before any other goroutine can see it.
+func (db *Database) rebuildIndexesParallel() {
+	tables := make([]*Table, 0, len(db.Tables))
+	for _, table := range db.Tables {
+		tables = append(tables, table)
+	}
+	runParallel(len(tables), func(i int) {
+		db.rebuildAllIndexes(tables[i])
+	})
+}
+
+// isTempFile reports whether name is a temp file of an atomic write: a file
+// written as <name>.tmp or <name>.harudb.tmp-* and renamed into place
+func isTempFile(name string) bool {
+	return strings.HasSuffix(name, ".tmp") || strings.Contains(name, ".tmp-")
+}
+
+// removeOrphanTempFiles deletes the temp files under the data directory.
+// It runs while the database is opened, after the page storage finished any
+// interrupted checkpoint, so no write that could still complete owns them;
+// staged pages a checkpoint that could not be finished still lists in the
+// page control file are kept. It returns the number of files removed.
+func (db *Database) removeOrphanTempFiles() int {
+	pending := make(map[string]bool)
+	if data, err := os.ReadFile(filepath.Join(db.DataDir, PageControlName)); err == nil {
+		var ctl pageControl
+		if json.Unmarshal(data, &ctl) == nil {
+			for _, name := range ctl.Pending {
+				pending[filepath.ToSlash(name)+".tmp"] = true
+			}
+		}
+	}
+
+	removed := 0
+	filepath.WalkDir(db.DataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isTempFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(db.DataDir, path)
+		if err != nil || pending[filepath.ToSlash(rel)] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("Warning: failed to remove orphaned temp file %s: %v\n", rel, err)
+			return nil
+		}
+		log.Printf("Removed orphaned temp file %s\n", rel)
+		removed++
+		return nil
+	})
+	return removed
+}
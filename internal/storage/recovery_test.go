@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoveryLoadsTablesInParallel(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("t%d", i)
+		_ = db.CreateTable(name, []string{"id", "name"})
+		_ = db.CreateIndex(name, "name")
+		_ = db.Insert(name, []string{"1", name})
+	}
+	db.Close()
+
+	progress := &RecoveryProgress{}
+	db = NewDatabaseWithProgress(dir, progress)
+	defer db.Close()
+	if !progress.Done() {
+		t.Error("recovery not marked done")
+	}
+	if got := progress.TablesLoaded.Load(); got != 12 || progress.TablesTotal.Load() != 12 {
+		t.Errorf("progress: %s", progress)
+	}
+	// Close checkpoints without truncating the WAL, so its writes are
+	// replayed again
+	if progress.WALRecords.Load() == 0 {
+		t.Errorf("no WAL records counted: %s", progress)
+	}
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("t%d", i)
+		if got := db.SelectWhere(name, "name", name); got != "id | name\n1 | "+name+"\n" {
+			t.Errorf("%s after recovery: %q", name, got)
+		}
+	}
+}
+
+func TestRecoveryRemovesOrphanTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.Insert("users", []string{"1", "ann"})
+	db.Close()
+
+	write := func(name string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orphans := []string{
+		"users.harudb.tmp-123456",
+		"keys.json.tmp",
+		TableStatsFile + ".tmp",
+		filepath.Join(TablesDirName, "users", "page.9.tmp"),
+		filepath.Join(TablesDirName, "users", ManifestName+".tmp"),
+	}
+	for _, name := range orphans {
+		write(name)
+	}
+	// A staged page the page control file lists but that cannot be renamed
+	// into place, since a directory is in the way, must survive
+	stuck := filepath.Join(TablesDirName, "users", "page.8")
+	write(stuck + ".tmp")
+	write(filepath.Join(stuck, "blocker"))
+	if err := os.WriteFile(filepath.Join(dir, PageControlName), []byte(`{"pending":["`+filepath.ToSlash(stuck)+`"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	for _, name := range orphans {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, stuck+".tmp")); err != nil {
+		t.Errorf("pending staged page was removed: %v", err)
+	}
+	if got := db.SelectWhere("users", "id", "1"); got != "id | name\n1 | ann\n" {
+		t.Errorf("users after recovery: %q", got)
+	}
+}
@@ -0,0 +1,183 @@
+// internal/storage/repair.go
+//
+// Repairing damaged pages from backups. A page file whose data no longer
+// matches its checksum can be replaced by the same page from a backup, as
+// long as the backup holds it exactly as it was written: the backup's copy
+// must pass its own checksum and carry the checksum and LSN the damaged
+// page's header records. A page changed since the newest backup that has it
+// cannot be repaired this way. Backups are tried newest first, each verified
+// against its manifest, and every repair is written to the server log.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errPageChecksum reports a page whose data does not match its checksum
+var errPageChecksum = errors.New("page checksum mismatch")
+
+// SetRepairBackups makes pages that fail their checksum when read repair
+// themselves from the backups in dir, decrypted with passphrase when they are
+// encrypted. An empty dir turns repair off. Call it before the database
+// serves statements.
+func (db *Database) SetRepairBackups(dir, passphrase string) {
+	if db.PageStorage != nil {
+		db.PageStorage.repairDir = dir
+		db.PageStorage.repairPassphrase = passphrase
+	}
+}
+
+// RepairBackups returns the backup directory and passphrase set with
+// SetRepairBackups
+func (db *Database) RepairBackups() (dir, passphrase string) {
+	if db.PageStorage == nil {
+		return "", ""
+	}
+	return db.PageStorage.repairDir, db.PageStorage.repairPassphrase
+}
+
+// repairPage repairs the page of a table whose data failed its checksum
+// from the backups in the repair directory, and returns it
+func (ps *PageStorage) repairPage(tableName string, pageID uint32, damaged PageHeader) (*Page, error) {
+	page, backup, err := ps.restorePage(tableName, pageID, damaged, ps.repairDir, ps.repairPassphrase)
+	if err != nil {
+		log.Printf("Warning: page %d of table %s failed its checksum and could not be repaired: %v\n", pageID, tableName, err)
+		return nil, fmt.Errorf("%w: %v", errPageChecksum, err)
+	}
+	log.Printf("🩹 Repaired page %d of table %s from backup %s\n", pageID, tableName, backup)
+	return page, nil
+}
+
+// restorePage replaces the damaged file of a page with its copy from the
+// newest verified backup in dir holding it as damaged records, and returns
+// the page and the backup it came from
+func (ps *PageStorage) restorePage(tableName string, pageID uint32, damaged PageHeader, dir, passphrase string) (*Page, string, error) {
+	backups, err := backupsNewestFirst(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	name := filepath.ToSlash(pageFile(tableName, pageID))
+	bm := NewBackupManager(ps.dataDir)
+	for _, backup := range backups {
+		contents, info, err := bm.readArchive(backup, passphrase)
+		if err != nil {
+			continue
+		}
+		if err := verifyManifest(contents, info); err != nil {
+			continue
+		}
+		raw, ok := contents[name]
+		if !ok {
+			continue
+		}
+		page, err := ps.decodePage(tableName, raw)
+		if err != nil {
+			continue
+		}
+		if page.Header.Checksum != damaged.Checksum || page.Header.LSN != damaged.LSN {
+			if page.Header.LSN < damaged.LSN {
+				// Older backups hold older copies still
+				return nil, "", fmt.Errorf("page changed since backup %s", backup)
+			}
+			continue
+		}
+
+		tempPath := ps.getPagePath(tableName, pageID) + ".tmp"
+		if err := writeFileSync(tempPath, raw); err != nil {
+			os.Remove(tempPath)
+			return nil, "", fmt.Errorf("failed to write temp page file: %w", err)
+		}
+		if err := os.Rename(tempPath, ps.getPagePath(tableName, pageID)); err != nil {
+			os.Remove(tempPath)
+			return nil, "", fmt.Errorf("failed to rename temp page file: %w", err)
+		}
+		return page, backup, nil
+	}
+	return nil, "", fmt.Errorf("no verified backup in %s holds the page", dir)
+}
+
+// backupsNewestFirst returns the paths of the backups in dir, most recently
+// written first
+func backupsNewestFirst(dir string) ([]string, error) {
+	names, err := NewBackupManager("").ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(names))
+	modTimes := make(map[string]int64, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, path)
+		modTimes[path] = info.ModTime().UnixNano()
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return modTimes[paths[i]] > modTimes[paths[j]]
+	})
+	return paths, nil
+}
+
+// RepairTable checks every page file of a table and repairs those that fail
+// their checksum from the backups in dir
+func (db *Database) RepairTable(tableName, dir, passphrase string) string {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if table.External != nil {
+		return fmt.Sprintf("Error: table %s is external and has no pages", tableName)
+	}
+	ps := db.PageStorage
+	if ps == nil {
+		return fmt.Sprintf("Table %s: 0 pages checked, none damaged", tableName)
+	}
+	metadata, err := ps.loadMetadata(tableName)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("Table %s: 0 pages checked, none damaged", tableName)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: repairing table %s: failed to load metadata: %v", tableName, err)
+	}
+
+	checked, repaired := 0, 0
+	var failed []string
+	for pageID := metadata.FirstPageID; metadata.PageCount > 0 && pageID <= metadata.LastPageID; pageID++ {
+		checked++
+		data, err := os.ReadFile(ps.getPagePath(tableName, pageID))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("page %d: %v", pageID, err))
+			continue
+		}
+		page, err := ps.decodePage(tableName, data)
+		if !errors.Is(err, errPageChecksum) {
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("page %d: %v", pageID, err))
+			}
+			continue
+		}
+		_, backup, err := ps.restorePage(tableName, pageID, page.Header, dir, passphrase)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("page %d: %v", pageID, err))
+			continue
+		}
+		log.Printf("🩹 Repaired page %d of table %s from backup %s\n", pageID, tableName, backup)
+		repaired++
+	}
+	if len(failed) > 0 {
+		return fmt.Sprintf("Error: repairing table %s: %d pages repaired, %d could not be: %s", tableName, repaired, len(failed), strings.Join(failed, "; "))
+	}
+	if repaired == 0 {
+		return fmt.Sprintf("Table %s: %d pages checked, none damaged", tableName, checked)
+	}
+	return fmt.Sprintf("Table %s: %d pages checked, %d repaired from backups", tableName, checked, repaired)
+}
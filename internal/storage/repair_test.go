@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// corruptPage flips a byte of a page's data, keeping the checksum in its
+// header, and writes the page back to its file
+func corruptPage(t *testing.T, ps *PageStorage, tableName string, pageID uint32) {
+	t.Helper()
+	raw, err := os.ReadFile(ps.getPagePath(tableName, pageID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := ps.decodePage(tableName, raw)
+	if err != nil && !errors.Is(err, errPageChecksum) {
+		t.Fatal(err)
+	}
+	page.Data[0] ^= 0xff
+	data := append(packPageHeader(page.Header), page.Data...)
+	if ps.compression {
+		if data, err = ps.compress(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seal, err := ps.pageSealing(tableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = ps.sealWith(seal, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ps.getPagePath(tableName, pageID), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepairPageFromBackup(t *testing.T) {
+	dataDir, backupDir := t.TempDir(), t.TempDir()
+	db := NewDatabase(dataDir)
+	defer db.Close()
+	db.CreateTable("items", []string{"id", "name"})
+	for i := 0; i < 50; i++ {
+		db.Insert("items", []string{fmt.Sprint(i), strings.Repeat("x", 200)})
+	}
+	if _, err := db.FlushPageCache(); err != nil {
+		t.Fatal(err)
+	}
+	bm := NewBackupManager(dataDir)
+	bm.SetSnapshotter(db)
+	if err := bm.CreateBackup(filepath.Join(backupDir, "items.backup"), "test"); err != nil {
+		t.Fatal(err)
+	}
+	ps := db.PageStorage
+	metadata, err := ps.loadMetadata("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := metadata.FirstPageID
+
+	// Without a backup directory the damaged page cannot be read
+	corruptPage(t, ps, "items", first)
+	if _, err := ps.preloadPages("items"); !errors.Is(err, errPageChecksum) {
+		t.Fatalf("preload of a damaged page: %v", err)
+	}
+
+	// REPAIR TABLE replaces it
+	pages := metadata.LastPageID - metadata.FirstPageID + 1
+	want := fmt.Sprintf("Table items: %d pages checked, 1 repaired from backups", pages)
+	if got := db.RepairTable("items", backupDir, ""); got != want {
+		t.Fatalf("RepairTable: %s", got)
+	}
+	if got := db.RepairTable("items", backupDir, ""); got != fmt.Sprintf("Table items: %d pages checked, none damaged", pages) {
+		t.Errorf("RepairTable of a sound table: %s", got)
+	}
+
+	// With a backup directory reads repair the page themselves
+	corruptPage(t, ps, "items", first)
+	db.SetRepairBackups(backupDir, "")
+	if _, err := ps.preloadPages("items"); err != nil {
+		t.Fatalf("preload with repair: %v", err)
+	}
+	raw, err := os.ReadFile(ps.getPagePath("items", first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ps.decodePage("items", raw); err != nil {
+		t.Errorf("page file after repair: %v", err)
+	}
+}
+
+func TestRepairPageChangedSinceBackup(t *testing.T) {
+	dataDir, backupDir := t.TempDir(), t.TempDir()
+	db := NewDatabase(dataDir)
+	defer db.Close()
+	db.CreateTable("items", []string{"id"})
+	db.Insert("items", []string{"1"})
+	if _, err := db.FlushPageCache(); err != nil {
+		t.Fatal(err)
+	}
+	bm := NewBackupManager(dataDir)
+	bm.SetSnapshotter(db)
+	if err := bm.CreateBackup(filepath.Join(backupDir, "items.backup"), "test"); err != nil {
+		t.Fatal(err)
+	}
+	ps := db.PageStorage
+	metadata, err := ps.loadMetadata("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(ps.getPagePath("items", metadata.FirstPageID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := ps.decodePage("items", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A page written after the backup cannot be repaired from it
+	newer := page.Header
+	newer.LSN++
+	if _, _, err := ps.restorePage("items", metadata.FirstPageID, newer, backupDir, ""); err == nil || !strings.Contains(err.Error(), "changed since backup") {
+		t.Errorf("restore of a newer page: %v", err)
+	}
+	if _, _, err := ps.restorePage("items", metadata.FirstPageID, page.Header, t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "no verified backup") {
+		t.Errorf("restore without backups: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// internal/storage/result.go
+package storage
+
+import "strings"
+
+// ResultSet holds the columns and rows of a query result, so callers can
+// render it as text with String or encode it in another format. NULL values
+// are NullValue.
+type ResultSet struct {
+	Columns []string
+	Rows    [][]string
+	// Types holds the type of each column where it is known: the declared
+	// type of a table column, or the type an aggregate returns. It is nil,
+	// or "" for a column, when the type is not known.
+	Types []ColumnType
+}
+
+// newResultSet returns the rows at the given indexes under columns
+func newResultSet(columns []string, rows [][]string, indexes []int) *ResultSet {
+	out := make([][]string, len(indexes))
+	for i, ri := range indexes {
+		out[i] = rows[ri]
+	}
+	return &ResultSet{Columns: columns, Rows: out}
+}
+
+// String renders the result as text: the column names, then one line per
+// row with values separated by " | " and NULL shown as NULL, or "(no rows)"
+func (rs *ResultSet) String() string {
+	var b strings.Builder
+	b.WriteString(strings.Join(rs.Columns, " | "))
+	b.WriteString("\n")
+	for _, row := range rs.Rows {
+		b.WriteString(joinRow(row))
+		b.WriteString("\n")
+	}
+	if len(rs.Rows) == 0 {
+		b.WriteString("(no rows)\n")
+	}
+	return b.String()
+}
+
+// resultText renders the result of a ...Result method as text. Those
+// return a nil ResultSet and a message when the query fails.
+func resultText(rs *ResultSet, msg string) string {
+	if rs == nil {
+		return msg
+	}
+	return rs.String()
+}
@@ -0,0 +1,341 @@
+// internal/storage/retention.go
+//
+// Retention policies. ALTER TABLE logs SET RETENTION 30 DAYS, 1000000 ROWS
+// bounds how much of a table is kept, and EnforceRetention, which the
+// server runs as a background maintenance task, deletes the oldest rows
+// beyond any limit. Rows carry no insert time, so their age is told by
+// marks: each enforcement records the table's next row ID, and every row
+// below a mark older than the limit has expired. A row is therefore kept
+// at least as long as the limit and at most one enforcement interval
+// longer; rows present when the policy is set count as inserted then.
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention limits how much of a table is kept. Zero limits are unset.
+type Retention struct {
+	MaxAge   time.Duration `json:"max_age,omitempty"`
+	MaxRows  int64         `json:"max_rows,omitempty"`
+	MaxBytes int64         `json:"max_bytes,omitempty"`
+	// Marks record the next row ID at past enforcements, oldest first
+	Marks []RetentionMark `json:"marks,omitempty"`
+}
+
+// RetentionMark records that every row with an ID below RowID was inserted
+// by Time
+type RetentionMark struct {
+	Time  time.Time `json:"time"`
+	RowID int64     `json:"row_id"`
+}
+
+// retentionKind is what a retention limit bounds
+type retentionKind int
+
+const (
+	retainAge retentionKind = iota
+	retainRows
+	retainBytes
+)
+
+// retentionUnit is a unit of a retention limit: size nanoseconds of age,
+// size bytes, or one row
+type retentionUnit struct {
+	names []string
+	kind  retentionKind
+	size  int64
+}
+
+// retentionUnits lists the units largest first within each kind, so that
+// String picks the largest that fits
+var retentionUnits = []retentionUnit{
+	{[]string{"WEEKS", "WEEK"}, retainAge, int64(7 * 24 * time.Hour)},
+	{[]string{"DAYS", "DAY"}, retainAge, int64(24 * time.Hour)},
+	{[]string{"HOURS", "HOUR"}, retainAge, int64(time.Hour)},
+	{[]string{"MINUTES", "MINUTE"}, retainAge, int64(time.Minute)},
+	{[]string{"ROWS", "ROW"}, retainRows, 1},
+	{[]string{"GB"}, retainBytes, 1 << 30},
+	{[]string{"MB"}, retainBytes, 1 << 20},
+	{[]string{"KB"}, retainBytes, 1 << 10},
+	{[]string{"BYTES", "BYTE"}, retainBytes, 1},
+}
+
+// limit returns the limit of a kind, 0 when unset
+func (r *Retention) limit(kind retentionKind) *int64 {
+	switch kind {
+	case retainAge:
+		return (*int64)(&r.MaxAge)
+	case retainRows:
+		return &r.MaxRows
+	}
+	return &r.MaxBytes
+}
+
+// ParseRetention parses the limits of SET RETENTION: a comma-separated list
+// of n MINUTES|HOURS|DAYS|WEEKS, n ROWS and n BYTES|KB|MB|GB, at most one
+// of each kind. NONE removes the policy and returns nil.
+func ParseRetention(spec string) (*Retention, error) {
+	if strings.EqualFold(strings.TrimSpace(spec), "NONE") {
+		return nil, nil
+	}
+	r := &Retention{}
+	for _, limit := range strings.Split(spec, ",") {
+		limit = strings.TrimSpace(limit)
+		fields := strings.Fields(limit)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid retention limit %q (expected: n DAYS, n ROWS or n MB)", limit)
+		}
+		n, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid retention limit %q: %s is not a positive integer", limit, fields[0])
+		}
+		unit := strings.ToUpper(fields[1])
+		i := slices.IndexFunc(retentionUnits, func(u retentionUnit) bool { return slices.Contains(u.names, unit) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown retention unit %s (use MINUTES, HOURS, DAYS, WEEKS, ROWS, BYTES, KB, MB or GB)", fields[1])
+		}
+		u := retentionUnits[i]
+		p := r.limit(u.kind)
+		if *p != 0 {
+			return nil, fmt.Errorf("retention limit %q repeats a limit given before", limit)
+		}
+		*p = n * u.size
+	}
+	return r, nil
+}
+
+// String returns the limits as SET RETENTION takes them
+func (r *Retention) String() string {
+	var limits []string
+	for _, kind := range []retentionKind{retainAge, retainRows, retainBytes} {
+		n := *r.limit(kind)
+		if n == 0 {
+			continue
+		}
+		for _, u := range retentionUnits {
+			if u.kind == kind && n%u.size == 0 {
+				limits = append(limits, fmt.Sprintf("%d %s", n/u.size, u.names[0]))
+				break
+			}
+		}
+	}
+	return strings.Join(limits, ", ")
+}
+
+// SetRetention sets the retention policy of a table, or removes it when r
+// is nil
+func (db *Database) SetRetention(tableName string, r *Retention) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	tableName = strings.ToLower(tableName)
+	db.catalog.RLock()
+	defer db.catalog.RUnlock()
+	table, exists := db.Tables[tableName]
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	// Expired rows are deleted without regard to references to them
+	if r != nil {
+		if refs := db.referencesTo(tableName); len(refs) > 0 {
+			return fmt.Sprintf(ErrReferencedTable+" and cannot have a retention policy", tableName, refs[0].table.Name, refs[0].fk.Column)
+		}
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	if r == nil && table.Retention == nil {
+		return fmt.Sprintf("Error: table %s has no retention policy", tableName)
+	}
+
+	// Write to WAL first
+	spec := "NONE"
+	if r != nil {
+		spec = r.String()
+	}
+	if db.WAL != nil {
+		if err := db.WAL.WriteEntry(WAL_SET_RETENTION, tableName, map[string]interface{}{"retention": spec}); err != nil {
+			return fmt.Sprintf("Failed to write to WAL: %v", err)
+		}
+	}
+
+	table.setRetention(r, time.Now())
+	if err := db.saveTable(table); err != nil {
+		return fmt.Sprintf("Retention set with warnings: failed to persist: %v", err)
+	}
+	if r == nil {
+		return fmt.Sprintf("Retention policy removed from table %s", tableName)
+	}
+	return fmt.Sprintf("Retention of table %s set to %s", tableName, spec)
+}
+
+// setRetention sets a table's policy, keeping the marks of the one it
+// replaces. Only policies with a MaxAge keep marks. The caller holds
+// t.lock exclusively.
+func (t *Table) setRetention(r *Retention, now time.Time) {
+	if r != nil && r.MaxAge > 0 {
+		if t.Retention != nil {
+			r.Marks = t.Retention.Marks
+		}
+		if len(r.Marks) == 0 {
+			r.Marks = []RetentionMark{{Time: now, RowID: t.NextRowID}}
+		}
+	}
+	t.Retention = r
+}
+
+// EnforceRetention deletes the rows that the retention policies of the
+// tables no longer keep, and returns the number deleted
+func (db *Database) EnforceRetention() (int, error) {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	db.catalog.RLock()
+	var tables []*Table
+	for _, table := range db.Tables {
+		tables = append(tables, table)
+	}
+	db.catalog.RUnlock()
+
+	deleted := 0
+	var firstErr error
+	for _, table := range tables {
+		n, err := db.enforceRetention(table, time.Now())
+		deleted += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return deleted, firstErr
+}
+
+// enforceRetention deletes the rows of one table its policy no longer keeps
+func (db *Database) enforceRetention(table *Table, now time.Time) (int, error) {
+	if current, ok := db.lookupTable(table.Name); !ok || current != table {
+		return 0, nil
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	r := table.Retention
+	if r == nil {
+		return 0, nil
+	}
+
+	// Record where the rows inserted since the last enforcement end
+	marked := false
+	if r.MaxAge > 0 && (len(r.Marks) == 0 || r.Marks[len(r.Marks)-1].RowID < table.NextRowID) {
+		r.Marks = append(r.Marks, RetentionMark{Time: now, RowID: table.NextRowID})
+		marked = true
+	}
+
+	expired := table.expiredRows(now)
+	if len(expired) == 0 {
+		if marked {
+			if err := db.saveTable(table); err != nil {
+				return 0, fmt.Errorf("failed to persist table %s: %w", table.Name, err)
+			}
+		}
+		return 0, nil
+	}
+
+	// Write to WAL first
+	ids := make([]int64, len(expired))
+	for i, rowIndex := range expired {
+		ids[i] = table.RowIDs[rowIndex]
+	}
+	var lsn uint64
+	if wal := db.walFor(table); wal != nil {
+		var err error
+		if lsn, err = wal.AppendEntry(WAL_PURGE, table.Name, map[string]interface{}{"row_ids": ids}); err != nil {
+			return 0, fmt.Errorf("failed to write purge of %s to WAL: %w", table.Name, err)
+		}
+	}
+
+	changes := make([]ChangeEvent, len(expired))
+	for i, rowIndex := range expired {
+		db.noteRowWrite(table, rowIndex)
+		changes[i] = ChangeEvent{Op: ChangeDelete, Table: table.Name, Columns: table.Columns, RowID: ids[i], OldValues: table.Rows[rowIndex], WALLSN: lsn}
+		db.appendChange(changes[i])
+	}
+	table.purgeRows(ids)
+	db.reindex(table)
+	if err := db.saveTable(table); err != nil {
+		return len(ids), fmt.Errorf("failed to persist table %s: %w", table.Name, err)
+	}
+
+	for range ids {
+		table.stats.recordWrite(&table.stats.deletes)
+	}
+	db.runCommitHooks("", changes)
+	log.Printf("Retention deleted %d rows from table %s\n", len(ids), table.Name)
+	return len(ids), nil
+}
+
+// expiredRows returns the positions of the rows the policy no longer keeps,
+// and drops the marks no longer needed. The caller holds t.lock
+// exclusively.
+func (t *Table) expiredRows(now time.Time) []int {
+	r := t.Retention
+	order := make([]int, len(t.Rows))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return cmp.Compare(t.RowIDs[a], t.RowIDs[b]) })
+
+	// Rows below the newest mark older than MaxAge have expired
+	keepFrom := 0
+	if r.MaxAge > 0 {
+		var below int64
+		for len(r.Marks) > 1 && now.Sub(r.Marks[0].Time) >= r.MaxAge {
+			below = r.Marks[0].RowID
+			r.Marks = r.Marks[1:]
+		}
+		if len(r.Marks) > 0 && now.Sub(r.Marks[0].Time) >= r.MaxAge {
+			below = r.Marks[0].RowID
+		}
+		for keepFrom < len(order) && t.RowIDs[order[keepFrom]] < below {
+			keepFrom++
+		}
+	}
+	if r.MaxRows > 0 && int64(len(order)-keepFrom) > r.MaxRows {
+		keepFrom = len(order) - int(r.MaxRows)
+	}
+	if r.MaxBytes > 0 {
+		var bytes int64
+		for _, i := range order[keepFrom:] {
+			bytes += rowBytes(t.Rows[i])
+		}
+		for bytes > r.MaxBytes && keepFrom < len(order) {
+			bytes -= rowBytes(t.Rows[order[keepFrom]])
+			keepFrom++
+		}
+	}
+	return order[:keepFrom]
+}
+
+// purgeRows deletes the rows with the given IDs. The caller holds t.lock
+// exclusively.
+func (t *Table) purgeRows(ids []int64) {
+	gone := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+	}
+	rows := make([][]string, 0, len(t.Rows))
+	keptIDs := make([]int64, 0, len(t.RowIDs))
+	for i, row := range t.Rows {
+		if !gone[t.RowIDs[i]] {
+			rows = append(rows, row)
+			keptIDs = append(keptIDs, t.RowIDs[i])
+		}
+	}
+	t.replaceRows(rows, keptIDs)
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	for spec, want := range map[string]string{
+		"30 days":               "30 DAYS",
+		"48 HOURS, 1000 rows":   "2 DAYS, 1000 ROWS",
+		"2048 kb, 14 day":       "2 WEEKS, 2 MB",
+		"90 minutes, 10 bytes":  "90 MINUTES, 10 BYTES",
+		" 1 GB ":                "1 GB",
+		"5 ROWS,3 MINUTES,1 KB": "3 MINUTES, 5 ROWS, 1 KB",
+	} {
+		r, err := ParseRetention(spec)
+		if err != nil {
+			t.Errorf("ParseRetention(%q): %v", spec, err)
+			continue
+		}
+		if got := r.String(); got != want {
+			t.Errorf("ParseRetention(%q) = %q, want %q", spec, got, want)
+		}
+	}
+	if r, err := ParseRetention("none"); r != nil || err != nil {
+		t.Errorf("NONE = %v, %v", r, err)
+	}
+	for _, spec := range []string{"", "30", "0 DAYS", "-1 ROWS", "3 FORTNIGHTS", "1 DAYS, 2 HOURS", "x ROWS"} {
+		if _, err := ParseRetention(spec); err == nil {
+			t.Errorf("ParseRetention(%q) should fail", spec)
+		}
+	}
+}
+
+func TestRetentionLimits(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	db.CreateTable("logs", []string{"msg"})
+	for i := 1; i <= 5; i++ {
+		db.Insert("logs", []string{fmt.Sprintf("m%d", i)})
+	}
+	r, _ := ParseRetention("3 ROWS")
+	if got := db.SetRetention("logs", r); got != "Retention of table logs set to 3 ROWS" {
+		t.Fatal(got)
+	}
+	if n, err := db.EnforceRetention(); n != 2 || err != nil {
+		t.Fatalf("EnforceRetention = %d, %v", n, err)
+	}
+	if got, want := db.SelectAll("logs"), "msg\nm3\nm4\nm5\n"; got != want {
+		t.Errorf("after 3 ROWS:\n got %q\nwant %q", got, want)
+	}
+
+	// Each value is two bytes
+	r, _ = ParseRetention("5 BYTES")
+	db.SetRetention("logs", r)
+	if n, _ := db.EnforceRetention(); n != 1 {
+		t.Errorf("5 BYTES deleted %d rows", n)
+	}
+	db.Close()
+
+	// The policy and the deletes survive a restart
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got, want := db.SelectAll("logs"), "msg\nm4\nm5\n"; got != want {
+		t.Errorf("after restart:\n got %q\nwant %q", got, want)
+	}
+	if got := db.ShowCreateTable("logs"); !strings.Contains(got, "ALTER TABLE logs SET RETENTION 5 BYTES\n") {
+		t.Errorf("SHOW CREATE TABLE: %s", got)
+	}
+	if got := db.SetRetention("logs", nil); got != "Retention policy removed from table logs" {
+		t.Error(got)
+	}
+	if got := db.SetRetention("logs", nil); !strings.HasPrefix(got, "Error") {
+		t.Errorf("removing a missing policy: %s", got)
+	}
+}
+
+func TestRetentionAge(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("logs", []string{"msg"})
+	db.Insert("logs", []string{"old1"})
+	db.Insert("logs", []string{"old2"})
+	r, _ := ParseRetention("1 HOURS")
+	db.SetRetention("logs", r)
+	table, _ := db.lookupTable("logs")
+	start := table.Retention.Marks[0].Time
+
+	db.Insert("logs", []string{"new"})
+	enforce := func(after time.Duration, deleted int, want string) {
+		t.Helper()
+		if n, err := db.enforceRetention(table, start.Add(after)); n != deleted || err != nil {
+			t.Errorf("after %s: deleted %d, %v; want %d", after, n, err, deleted)
+		}
+		if got := db.SelectAll("logs"); got != want {
+			t.Errorf("after %s:\n got %q\nwant %q", after, got, want)
+		}
+	}
+	// Rows count as inserted when the policy was set, and later ones from
+	// the enforcement after their insert
+	enforce(30*time.Minute, 0, "msg\nold1\nold2\nnew\n")
+	enforce(61*time.Minute, 2, "msg\nnew\n")
+	enforce(89*time.Minute, 0, "msg\nnew\n")
+	enforce(91*time.Minute, 1, "msg\n(no rows)\n")
+	if n := len(table.Retention.Marks); n != 1 {
+		t.Errorf("expired marks kept: %d left", n)
+	}
+}
+
+func TestRetentionForeignKeys(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	db.CreateTable("users", []string{"id"})
+	db.CreateTable("events", []string{"user_id", "FOREIGN KEY (user_id) REFERENCES users(id)"})
+	r, _ := ParseRetention("30 DAYS")
+	if got := db.SetRetention("users", r); !strings.Contains(got, "referenced by FOREIGN KEY events.user_id") {
+		t.Errorf("retention on a referenced table: %s", got)
+	}
+	db.SetRetention("events", r)
+	if got := db.CreateTable("audit", []string{"e", "FOREIGN KEY (e) REFERENCES events(user_id)"}); !strings.Contains(got, "retention policy") {
+		t.Errorf("reference to a table with retention: %s", got)
+	}
+}
@@ -0,0 +1,133 @@
+// internal/storage/rowid.go
+//
+// Every row has a row ID, assigned when it is inserted and never reused in
+// its table. Positions shift when earlier rows are deleted, but row IDs do
+// not, so indexes, WAL entries and queued transaction operations refer to
+// rows by ID. Table order is insertion order, as before; ROW n still
+// addresses the row at position n.
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+// rowTarget addresses a row by position or, when byID is set, by row ID
+type rowTarget struct {
+	index int
+	id    int64
+	byID  bool
+}
+
+// atIndex addresses the row at position rowIndex
+func atIndex(rowIndex int) rowTarget {
+	return rowTarget{index: rowIndex}
+}
+
+// withRowID addresses the row with ID id
+func withRowID(id int64) rowTarget {
+	return rowTarget{id: id, byID: true}
+}
+
+// find returns the position of the target among rows, whose IDs are ids, or
+// an error message
+func (rt rowTarget) find(rows [][]string, ids []int64) (int, string) {
+	if rt.byID {
+		if i := slices.Index(ids, rt.id); i >= 0 {
+			return i, ""
+		}
+		return -1, fmt.Sprintf("Row ID %d not found", rt.id)
+	}
+	if rt.index < 0 || rt.index >= len(rows) {
+		return -1, "Row index out of bounds"
+	}
+	return rt.index, ""
+}
+
+// locate returns the position of the target in the table's current rows, or
+// an error message. The caller holds t.lock.
+func (t *Table) locate(rt rowTarget) (int, string) {
+	if rt.byID {
+		if i, ok := t.rowPosition(rt.id); ok {
+			return i, ""
+		}
+		return -1, fmt.Sprintf("Row ID %d not found", rt.id)
+	}
+	return rt.find(t.Rows, t.RowIDs)
+}
+
+// rowPosition returns the position of the row with ID id. The caller holds
+// t.lock.
+func (t *Table) rowPosition(id int64) (int, bool) {
+	if t.positions == nil {
+		i := slices.Index(t.RowIDs, id)
+		return i, i >= 0
+	}
+	i, ok := t.positions[id]
+	return i, ok
+}
+
+// allocRowID returns the next unused row ID. The caller must hold t.lock
+// exclusively.
+func (t *Table) allocRowID() int64 {
+	if t.NextRowID < 1 {
+		t.NextRowID = 1
+	}
+	id := t.NextRowID
+	t.NextRowID++
+	return id
+}
+
+// initRowIDs numbers the rows of a table saved without row IDs, such as one
+// written by an older version, and indexes the IDs. The caller must hold
+// t.lock exclusively or own the table.
+func (t *Table) initRowIDs() {
+	if len(t.RowIDs) != len(t.Rows) {
+		t.RowIDs = sequentialRowIDs(max(t.NextRowID, 1), len(t.Rows))
+	}
+	for _, id := range t.RowIDs {
+		if id >= t.NextRowID {
+			t.NextRowID = id + 1
+		}
+	}
+	t.indexRowIDs()
+}
+
+// indexRowIDs rebuilds the map from row ID to position. The caller must hold
+// t.lock exclusively or own the table.
+func (t *Table) indexRowIDs() {
+	t.positions = make(map[int64]int, len(t.RowIDs))
+	for i, id := range t.RowIDs {
+		t.positions[id] = i
+	}
+}
+
+// sequentialRowIDs returns n row IDs counting up from first
+func sequentialRowIDs(first int64, n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = first + int64(i)
+	}
+	return ids
+}
+
+// String describes the target for error messages
+func (rt rowTarget) String() string {
+	if rt.byID {
+		return fmt.Sprintf("row ID %d", rt.id)
+	}
+	return fmt.Sprintf("row index %d", rt.index)
+}
+
+// operationTarget returns the row a queued or logged UPDATE or DELETE
+// addresses: its row ID when one was recorded, otherwise its position, as
+// in entries written before rows had IDs
+func operationTarget(data map[string]interface{}) (rowTarget, bool) {
+	if id, ok := data["row_id"].(float64); ok {
+		return withRowID(int64(id)), true
+	}
+	if ri, ok := data["row_index"].(float64); ok {
+		return atIndex(int(ri)), true
+	}
+	return rowTarget{}, false
+}
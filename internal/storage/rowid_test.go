@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestRowIDsAreStable(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("items", []string{"id", "name"})
+	_ = db.CreateIndex("items", "name")
+	for _, name := range []string{"a", "b", "c"} {
+		_ = db.Insert("items", []string{name, name})
+	}
+
+	// Deleting the first row shifts positions but not IDs
+	_ = db.Delete("items", 0)
+	if _, row, msg := db.ReadRowByID("items", 3); msg != "" || row[1] != "c" {
+		t.Fatalf("row ID 3 after delete: %v %q", row, msg)
+	}
+	if _, _, msg := db.ReadRowByID("items", 1); msg != "Row ID 1 not found" {
+		t.Errorf("deleted row ID: %q", msg)
+	}
+	if got := db.SelectWhere("items", "name", "c"); got != "id | name\nc | c\n" {
+		t.Errorf("index lookup after delete: %q", got)
+	}
+	if got := db.SelectQuery("items", Query{Limit: -1, RowIDs: true}); got != "rowid | id | name\n2 | b | b\n3 | c | c\n" {
+		t.Errorf("select with row IDs: %q", got)
+	}
+	db.Close()
+
+	// IDs and the next ID survive a restart
+	db = NewDatabase(dir)
+	defer db.Close()
+	_ = db.Insert("items", []string{"d", "d"})
+	if got := db.SelectQuery("items", Query{Limit: -1, RowIDs: true}); got != "rowid | id | name\n2 | b | b\n3 | c | c\n4 | d | d\n" {
+		t.Errorf("row IDs after restart: %q", got)
+	}
+	if got := db.SelectWhere("items", "name", "d"); got != "id | name\nd | d\n" {
+		t.Errorf("index lookup after restart: %q", got)
+	}
+}
+
+func TestTransactionTargetsRowIDs(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("items", []string{"name"})
+	for _, name := range []string{"a", "b", "c"} {
+		_ = db.Insert("items", []string{name})
+	}
+
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	// Row 2 is read before the delete of row 0 is applied, so it must still
+	// name c at commit
+	_ = db.DeleteTx("items", 0)
+	_ = db.DeleteTx("items", 2)
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SelectAll("items"); got != "name\nb\n" {
+		t.Errorf("rows after commit: %q", got)
+	}
+	if got := db.UpdateByIDTx("items", 2, []string{"B"}); got == "" || db.SelectAll("items") != "name\nB\n" {
+		t.Errorf("update by ID: %q", got)
+	}
+	if got := db.DeleteByIDTx("items", 9); got != "Row ID 9 not found" {
+		t.Errorf("delete of a missing ID: %q", got)
+	}
+}
+
+func TestChangeEventRowID(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	if err := db.EnableChangeLog(); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.CreateTable("items", []string{"name"})
+	_ = db.Insert("items", []string{"a"})
+	_ = db.Insert("items", []string{"b"})
+	_ = db.Delete("items", 0)
+
+	events, err := db.Changes.ReadAfter(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, ev := range events {
+		if ev.Op != ChangeCreateTable {
+			ids = append(ids, ev.RowID)
+		}
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 1 {
+		t.Errorf("change event row IDs: %v", ids)
+	}
+}
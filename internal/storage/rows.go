@@ -0,0 +1,106 @@
+// internal/storage/rows.go
+//
+// Copy-on-write row storage. Writers, holding the table lock exclusively,
+// never modify a row slice that readers may hold: appends only write past
+// the end of published slices, and updates and deletes build a new slice.
+// After every change the writer publishes the new slice atomically, so scans
+// read a consistent snapshot without taking any lock.
+package storage
+
+import "log"
+
+// rowSet is one published version of a table's rows and their row IDs
+type rowSet struct {
+	rows [][]string
+	ids  []int64
+}
+
+// rowView returns the table's latest published rows. The slice and its rows
+// must be treated as read-only.
+func (t *Table) rowView() [][]string {
+	rows, _ := t.rowSetView()
+	return rows
+}
+
+// rowSetView returns the table's latest published rows and their row IDs.
+// External tables number their rows from 1 in file order.
+func (t *Table) rowSetView() ([][]string, []int64) {
+	if t.External != nil {
+		rows, err := t.External.readRows(len(t.Columns))
+		if err != nil {
+			log.Printf("Warning: external table %s: %v\n", t.Name, err)
+		}
+		return rows, sequentialRowIDs(1, len(rows))
+	}
+	if s := t.published.Load(); s != nil {
+		return s.rows, s.ids
+	}
+	// Tables that have not been written since they were loaded
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.Rows, t.RowIDs
+}
+
+// publishRows makes the current rows visible to lock-free readers.
+// The caller must hold t.lock exclusively.
+func (t *Table) publishRows() {
+	t.published.Store(&rowSet{rows: t.Rows, ids: t.RowIDs})
+	t.version.Add(1)
+}
+
+// appendRow adds a row with row ID id, or with the next unused ID when id is
+// 0, and returns the ID. The caller must hold t.lock exclusively.
+func (t *Table) appendRow(values []string, id int64) int64 {
+	if id == 0 {
+		id = t.allocRowID()
+	} else if id >= t.NextRowID {
+		t.NextRowID = id + 1
+	}
+	if t.positions == nil {
+		t.indexRowIDs()
+	}
+	t.Rows = append(t.Rows, values)
+	t.RowIDs = append(t.RowIDs, id)
+	t.sizes.add(1, rowBytes(values))
+	t.positions[id] = len(t.Rows) - 1
+	t.publishRows()
+	return id
+}
+
+// setRow replaces a row in a copy of the row slice. The row keeps its ID.
+// The caller must hold t.lock exclusively.
+func (t *Table) setRow(rowIndex int, values []string) {
+	rows := make([][]string, len(t.Rows))
+	copy(rows, t.Rows)
+	t.sizes.add(0, rowBytes(values)-rowBytes(rows[rowIndex]))
+	rows[rowIndex] = values
+	t.Rows = rows
+	t.publishRows()
+}
+
+// removeRow deletes a row into a copy of the row slice. The caller must hold
+// t.lock exclusively.
+func (t *Table) removeRow(rowIndex int) {
+	rows := make([][]string, 0, len(t.Rows)-1)
+	rows = append(rows, t.Rows[:rowIndex]...)
+	rows = append(rows, t.Rows[rowIndex+1:]...)
+	ids := make([]int64, 0, len(t.RowIDs)-1)
+	ids = append(ids, t.RowIDs[:rowIndex]...)
+	ids = append(ids, t.RowIDs[rowIndex+1:]...)
+	t.sizes.add(-1, -rowBytes(t.Rows[rowIndex]))
+	t.Rows = rows
+	t.RowIDs = ids
+	t.indexRowIDs()
+	t.publishRows()
+}
+
+// replaceRows replaces every row, keeping ids when they match the rows and
+// numbering the rows afresh otherwise. The caller must hold t.lock
+// exclusively.
+func (t *Table) replaceRows(rows [][]string, ids []int64) {
+	t.Rows = rows
+	t.RowIDs = ids
+	t.initRowIDs()
+	t.sizes.recount(rows)
+	t.publishRows()
+}
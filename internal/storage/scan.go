@@ -0,0 +1,153 @@
+// internal/storage/scan.go
+package storage
+
+import (
+	"container/heap"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// scanChunkRows is the number of rows one scan worker processes at a time.
+// Tables smaller than two chunks are always scanned serially.
+const scanChunkRows = 4096
+
+// scanWorkers returns how many workers may scan a table of n rows
+func (db *Database) scanWorkers(n int) int {
+	workers := db.MaxScanParallelism
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if chunks := (n + scanChunkRows - 1) / scanChunkRows; chunks < workers {
+		workers = chunks
+	}
+	return workers
+}
+
+// scanChunks splits rows [0, n) into chunks and runs scan over them on up to
+// workers goroutines. Results come back in chunk order; the first error stops
+// the remaining chunks.
+func scanChunks[T any](n, workers int, scan func(lo, hi int) (T, error)) ([]T, error) {
+	chunks := (n + scanChunkRows - 1) / scanChunkRows
+	results := make([]T, chunks)
+	if workers <= 1 {
+		for c := range results {
+			res, err := scan(c*scanChunkRows, min((c+1)*scanChunkRows, n))
+			if err != nil {
+				return nil, err
+			}
+			results[c] = res
+		}
+		return results, nil
+	}
+
+	var (
+		next     atomic.Int64
+		failed   atomic.Bool
+		errOnce  sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				c := int(next.Add(1) - 1)
+				if c >= chunks {
+					return
+				}
+				res, err := scan(c*scanChunkRows, min((c+1)*scanChunkRows, n))
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+				results[c] = res
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// matchRows returns the indexes of all rows accepted by match, scanning large
+// tables in parallel
+func (db *Database) matchRows(rows [][]string, match func([]string) (bool, error)) ([]int, error) {
+	chunks, err := scanChunks(len(rows), db.scanWorkers(len(rows)), func(lo, hi int) ([]int, error) {
+		var matched []int
+		for i := lo; i < hi; i++ {
+			ok, err := match(rows[i])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = append(matched, i)
+			}
+		}
+		return matched, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []int
+	for _, chunk := range chunks {
+		matched = append(matched, chunk...)
+	}
+	return matched, nil
+}
+
+// topRows returns the first limit rows accepted by match in order. Each
+// worker keeps a bounded heap for its chunks and the heaps are merged.
+func (db *Database) topRows(rows [][]string, order rowOrder, limit int, match func([]string) (bool, error)) ([]int, error) {
+	if limit == 0 {
+		return nil, nil
+	}
+	chunks, err := scanChunks(len(rows), db.scanWorkers(len(rows)), func(lo, hi int) ([]int, error) {
+		h := &rowHeap{order: order}
+		for i := lo; i < hi; i++ {
+			ok, err := match(rows[i])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				h.offer(i, limit)
+			}
+		}
+		return h.rows, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h := &rowHeap{order: order}
+	for _, chunk := range chunks {
+		for _, ri := range chunk {
+			h.offer(ri, limit)
+		}
+	}
+	top := make([]int, h.Len())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i] = heap.Pop(h).(int)
+	}
+	return top, nil
+}
+
+// resultWithIDs returns the rows of rs at the given indexes with each row's
+// ID in a leading rowid column
+func resultWithIDs(columns []string, rs rowSet, indexes []int) *ResultSet {
+	out := &ResultSet{Columns: append([]string{"rowid"}, columns...), Rows: make([][]string, len(indexes))}
+	for i, ri := range indexes {
+		var id int64
+		if ri < len(rs.ids) {
+			id = rs.ids[ri]
+		}
+		out.Rows[i] = append([]string{strconv.FormatInt(id, 10)}, rs.rows[ri]...)
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// evenRows matches rows whose second column is an even number
+type evenRows struct{}
+
+func (evenRows) EvaluateExpression(row []string, _ map[string]int) (bool, error) {
+	var n int
+	if _, err := fmt.Sscan(row[1], &n); err != nil {
+		return false, err
+	}
+	return n%2 == 0, nil
+}
+
+func TestParallelScan(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("events", []string{"id", "n"})
+	table := db.Tables["events"]
+	for i := 0; i < 5*scanChunkRows+17; i++ {
+		table.Rows = append(table.Rows, []string{fmt.Sprint(i), fmt.Sprint((i * 7919) % 10007)})
+	}
+
+	db.MaxScanParallelism = 1
+	serial := db.SelectWhereAdvanced("events", evenRows{})
+	serialTop := db.SelectQuery("events", Query{Where: evenRows{}, OrderBy: "n", Desc: true, Limit: 5})
+	serialSorted := db.SelectQuery("events", Query{OrderBy: "n", Limit: -1})
+
+	db.MaxScanParallelism = 4
+	if got := db.scanWorkers(len(table.Rows)); got != 4 {
+		t.Fatalf("expected 4 workers, got %d", got)
+	}
+	if got := db.SelectWhereAdvanced("events", evenRows{}); got != serial {
+		t.Error("parallel WHERE scan should match the serial result in table order")
+	}
+	if got := db.SelectQuery("events", Query{Where: evenRows{}, OrderBy: "n", Desc: true, Limit: 5}); got != serialTop {
+		t.Errorf("parallel top-N differs:\n%s\nwant:\n%s", got, serialTop)
+	}
+	if got := db.SelectQuery("events", Query{OrderBy: "n", Limit: -1}); got != serialSorted {
+		t.Error("parallel sort should match the serial result")
+	}
+
+	// An error in any chunk fails the scan
+	table.Rows[3*scanChunkRows+1][1] = "oops"
+	if out := db.SelectWhereAdvanced("events", evenRows{}); !strings.HasPrefix(out, "Error evaluating WHERE condition") {
+		t.Errorf("expected scan error, got %.80s", out)
+	}
+
+	if got := db.scanWorkers(10); got != 1 {
+		t.Errorf("small tables should scan serially, got %d workers", got)
+	}
+}
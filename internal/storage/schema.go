@@ -0,0 +1,218 @@
+// internal/storage/schema.go
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SetComment sets the comment of a table, or of one of its columns when
+// column is not empty. An empty or NULL comment removes it.
+func (db *Database) SetComment(tableName, column, comment string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	if column != "" {
+		colIdx := table.columnIndex(column)
+		if colIdx == -1 {
+			return fmt.Sprintf("Column %s not found", column)
+		}
+		column = table.Columns[colIdx]
+	}
+	if IsNull(comment) {
+		comment = ""
+	}
+
+	// Write to WAL first
+	var lsn uint64
+	if db.WAL != nil {
+		data := map[string]interface{}{"column": column, "comment": comment}
+		var err error
+		if lsn, err = db.WAL.AppendEntry(WAL_COMMENT, tableName, data); err != nil {
+			return fmt.Sprintf("Failed to write to WAL: %v", err)
+		}
+	}
+	change := ChangeEvent{Op: ChangeComment, Table: tableName, Column: column, Comment: comment, WALLSN: lsn}
+	db.appendChange(change)
+
+	table.setComment(column, comment)
+	if err := db.saveTable(table); err != nil {
+		return fmt.Sprintf("Comment set with warnings: failed to persist: %v", err)
+	}
+
+	db.runCommitHooks("", []ChangeEvent{change})
+
+	if column != "" {
+		return fmt.Sprintf("Comment set on column %s.%s", tableName, column)
+	}
+	return fmt.Sprintf("Comment set on table %s", tableName)
+}
+
+// setComment sets or, for an empty comment, removes a table or column comment
+func (t *Table) setComment(column, comment string) {
+	if column == "" {
+		t.Comment = comment
+		return
+	}
+	if comment == "" {
+		delete(t.ColumnComments, column)
+		return
+	}
+	if t.ColumnComments == nil {
+		t.ColumnComments = make(map[string]string)
+	}
+	t.ColumnComments[column] = comment
+}
+
+// DescribeTableResult lists a table and its columns with their types, NOT NULL
+// and default, collations, indexes and comments. The first row describes the table
+// itself.
+func (db *Database) DescribeTableResult(tableName string) (*ResultSet, string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+
+	kind := "table"
+	if table.External != nil {
+		kind = "external table"
+	} else if table.Unlogged {
+		kind = "unlogged table"
+	}
+	rows := [][]string{{table.Name, kind, "", "", "", "", "", table.Comment}}
+	for _, col := range table.Columns {
+		collation := "BINARY"
+		if coll := table.Collation(col); coll != nil {
+			collation = coll.Name
+		}
+		indexed := "no"
+		if slices.Contains(table.IndexedColumns, col) {
+			indexed = "yes"
+		}
+		nullable := "yes"
+		if table.NotNull[col] {
+			nullable = "no"
+		}
+		def := ""
+		if v, ok := table.Defaults[col]; ok {
+			def = defaultLiteral(table.Type(col), v)
+		}
+		rows = append(rows, []string{col, "column", string(table.Type(col)), nullable, def, collation, indexed, table.ColumnComments[col]})
+	}
+	columns := []string{"name", "kind", "type", "nullable", "default", "collation", "indexed", "comment"}
+	return &ResultSet{Columns: columns, Rows: rows}, ""
+}
+
+// DescribeTable is DescribeTableResult rendered as text
+func (db *Database) DescribeTable(tableName string) string {
+	return resultText(db.DescribeTableResult(tableName))
+}
+
+// ShowCreateTableResult returns the statements that recreate a table's schema,
+// one per row
+func (db *Database) ShowCreateTableResult(tableName string) (*ResultSet, string) {
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return nil, fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+
+	name := QuoteIdentifier(table.Name)
+	specs := make([]string, 0, len(table.Columns))
+	for i, col := range table.Columns {
+		// WITH (versioned=true) adds _version back, and WITH
+		// (timestamps=true) created_at and updated_at
+		if table.autoColumn(i) {
+			continue
+		}
+		spec := QuoteIdentifier(col)
+		ct := table.Type(col)
+		if ct != "" {
+			spec += " " + string(ct)
+		}
+		if table.NotNull[col] {
+			spec += " NOT NULL"
+		}
+		if v, ok := table.Defaults[col]; ok {
+			spec += " DEFAULT " + defaultLiteral(ct, v)
+		}
+		if coll := table.Collation(col); coll != nil {
+			spec += " COLLATE " + coll.Name
+		}
+		specs = append(specs, spec)
+	}
+	for _, fk := range table.ForeignKeys {
+		specs = append(specs, fk.String())
+	}
+
+	var b strings.Builder
+	if ext := table.External; ext != nil {
+		fmt.Fprintf(&b, "CREATE EXTERNAL TABLE %s (%s) LOCATION %s", name, strings.Join(specs, ", "), quoteString(ext.Path))
+		if ext.Header {
+			b.WriteString(" HEADER")
+		}
+	} else {
+		kind := "TABLE"
+		if table.Unlogged {
+			kind = "UNLOGGED TABLE"
+		}
+		fmt.Fprintf(&b, "CREATE %s %s (%s)", kind, name, strings.Join(specs, ", "))
+		var options []string
+		// Encryption is only named when it differs from the server's default
+		if encrypted := table.KeyID != ""; encrypted != db.EncryptTables {
+			options = append(options, fmt.Sprintf("encrypted=%t", encrypted))
+		}
+		if table.Versioned {
+			options = append(options, "versioned=true")
+		}
+		if table.Timestamps {
+			options = append(options, "timestamps=true")
+		}
+		if len(options) > 0 {
+			fmt.Fprintf(&b, " WITH (%s)", strings.Join(options, ", "))
+		}
+	}
+	statements := [][]string{{b.String()}}
+	for _, col := range table.IndexedColumns {
+		statements = append(statements, []string{fmt.Sprintf("CREATE INDEX ON %s (%s)", name, QuoteIdentifier(col))})
+	}
+	if table.Comment != "" {
+		statements = append(statements, []string{fmt.Sprintf("COMMENT ON TABLE %s IS %s", name, quoteString(table.Comment))})
+	}
+	for _, col := range table.Columns {
+		if comment, ok := table.ColumnComments[col]; ok {
+			statements = append(statements, []string{fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", name, QuoteIdentifier(col), quoteString(comment))})
+		}
+	}
+	for _, m := range table.Masks {
+		statements = append(statements, []string{fmt.Sprintf("CREATE MASK ON %s (%s) FOR ROLE %s USING %s", name, QuoteIdentifier(m.Column), m.Role, quoteString(m.Method))})
+	}
+	if table.Retention != nil {
+		statements = append(statements, []string{fmt.Sprintf("ALTER TABLE %s SET RETENTION %s", name, table.Retention)})
+	}
+	return &ResultSet{Columns: []string{"statement"}, Rows: statements}, ""
+}
+
+// ShowCreateTable is ShowCreateTableResult rendered as text
+func (db *Database) ShowCreateTable(tableName string) string {
+	return resultText(db.ShowCreateTableResult(tableName))
+}
+
+// quoteString returns s as a single-quoted SQL string literal
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+
+	_ = db.CreateTable("users", []string{"id", `"Full Name" COLLATE NOCASE`})
+	_ = db.CreateIndex("users", "id")
+	if msg := db.SetComment("users", "", "People who can log in"); msg != "Comment set on table users" {
+		t.Fatalf("table comment: %s", msg)
+	}
+	if msg := db.SetComment("users", "full name", "Shown on 'profile'"); msg != "Comment set on column users.Full Name" {
+		t.Fatalf("column comment: %s", msg)
+	}
+	if msg := db.SetComment("users", "missing", "x"); msg != "Column missing not found" {
+		t.Fatalf("missing column: %s", msg)
+	}
+	_ = db.SetComment("users", "id", "temporary")
+	_ = db.SetComment("users", "id", NullValue)
+
+	wantDescribe := "name | kind | type | nullable | default | collation | indexed | comment\n" +
+		"users | table |  |  |  |  |  | People who can log in\n" +
+		"id | column |  | yes |  | BINARY | yes | \n" +
+		"Full Name | column |  | yes |  | NOCASE | no | Shown on 'profile'\n"
+	if got := db.DescribeTable("users"); got != wantDescribe {
+		t.Errorf("DESCRIBE:\n%s", got)
+	}
+	wantCreate := "statement\n" +
+		`CREATE TABLE users (id, "Full Name" COLLATE NOCASE)` + "\n" +
+		"CREATE INDEX ON users (id)\n" +
+		"COMMENT ON TABLE users IS 'People who can log in'\n" +
+		`COMMENT ON COLUMN users."Full Name" IS 'Shown on ''profile'''` + "\n"
+	if got := db.ShowCreateTable("users"); got != wantCreate {
+		t.Errorf("SHOW CREATE TABLE:\n%s", got)
+	}
+}
+
+func TestCommentsSurviveRestart(t *testing.T) {
+	dataDir := t.TempDir()
+	db := NewDatabase(dataDir)
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.SetComment("users", "", "People")
+	_ = db.SetComment("users", "name", "Display name")
+
+	// Restarting replays the WAL, including the CREATE TABLE, so the
+	// comments must be replayed too
+	for i := 0; i < 2; i++ {
+		db = NewDatabase(dataDir)
+		table, ok := db.lookupTable("users")
+		if !ok {
+			t.Fatalf("restart %d: table missing", i)
+		}
+		if table.Comment != "People" || table.ColumnComments["name"] != "Display name" {
+			t.Errorf("restart %d: comments %q, %v", i, table.Comment, table.ColumnComments)
+		}
+	}
+}
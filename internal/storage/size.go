@@ -0,0 +1,149 @@
+// internal/storage/size.go
+//
+// Table sizes. Every table counts its rows and the bytes its values take as
+// rows change, and saves them, with the number of distinct keys in each
+// index and its ANALYZE statistics, in its table file. After a restart SHOW
+// TABLES and the planner read the saved figures instead of scanning.
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// TableSize describes a table's contents
+type TableSize struct {
+	Table string `json:"table,omitempty"`
+	Rows  int64  `json:"rows"`
+	// Bytes is the total length of the table's values
+	Bytes int64 `json:"bytes"`
+	// Indexes maps indexed column -> number of distinct keys
+	Indexes map[string]int `json:"indexes,omitempty"`
+}
+
+// DatabaseSize totals the sizes of every table
+type DatabaseSize struct {
+	Tables  int
+	Rows    int64
+	Bytes   int64
+	Indexes int
+}
+
+// tableSize holds a table's live row and byte counts
+type tableSize struct {
+	rows  atomic.Int64
+	bytes atomic.Int64
+}
+
+// rowBytes returns the total length of a row's values
+func rowBytes(row []string) int64 {
+	var n int64
+	for _, v := range row {
+		n += int64(len(v))
+	}
+	return n
+}
+
+// add counts rows rows holding bytes bytes; both may be negative
+func (s *tableSize) add(rows, bytes int64) {
+	s.rows.Add(rows)
+	s.bytes.Add(bytes)
+}
+
+// recount sets the counts from every row
+func (s *tableSize) recount(rows [][]string) {
+	var bytes int64
+	for _, row := range rows {
+		bytes += rowBytes(row)
+	}
+	s.rows.Store(int64(len(rows)))
+	s.bytes.Store(bytes)
+}
+
+// size returns table's size. Index cardinalities are omitted while bulk load
+// leaves the indexes stale, and external tables are counted from their file.
+// The caller holds table.lock shared.
+func (table *Table) size() TableSize {
+	ts := TableSize{Table: table.Name, Rows: table.sizes.rows.Load(), Bytes: table.sizes.bytes.Load()}
+	if table.External != nil {
+		var s tableSize
+		s.recount(table.rowView())
+		ts.Rows, ts.Bytes = s.rows.Load(), s.bytes.Load()
+	}
+	if len(table.IndexedColumns) > 0 && table.indexesUsable() {
+		ts.Indexes = make(map[string]int, len(table.IndexedColumns))
+		for _, col := range table.IndexedColumns {
+			ts.Indexes[col] = len(table.Indexes[col])
+		}
+	}
+	return ts
+}
+
+// restoreSize sets the counts saved in a table file, or counts the rows when
+// the file has none or they no longer match its rows
+func (table *Table) restoreSize(saved *TableSize) {
+	if saved != nil && saved.Rows == int64(len(table.Rows)) {
+		table.sizes.rows.Store(saved.Rows)
+		table.sizes.bytes.Store(saved.Bytes)
+		return
+	}
+	table.sizes.recount(table.Rows)
+}
+
+// TableSizes returns the size of every table, sorted by name
+func (db *Database) TableSizes() []TableSize {
+	db.catalog.RLock()
+	tables := make([]*Table, 0, len(db.Tables))
+	for _, table := range db.Tables {
+		tables = append(tables, table)
+	}
+	db.catalog.RUnlock()
+
+	sizes := make([]TableSize, 0, len(tables))
+	for _, table := range tables {
+		table.lock.RLock()
+		sizes = append(sizes, table.size())
+		table.lock.RUnlock()
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Table < sizes[j].Table })
+	return sizes
+}
+
+// DatabaseSize totals the sizes of every table
+func (db *Database) DatabaseSize() DatabaseSize {
+	var total DatabaseSize
+	for _, ts := range db.TableSizes() {
+		total.Tables++
+		total.Rows += ts.Rows
+		total.Bytes += ts.Bytes
+		total.Indexes += len(ts.Indexes)
+	}
+	return total
+}
+
+// ShowTablesResult lists every table with its row count, value bytes and the
+// distinct keys of each index
+func (db *Database) ShowTablesResult() (*ResultSet, string) {
+	sizes := db.TableSizes()
+	rs := &ResultSet{Columns: []string{"table", "rows", "bytes", "indexes"}}
+	for _, ts := range sizes {
+		cols := make([]string, 0, len(ts.Indexes))
+		for col := range ts.Indexes {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		indexes := make([]string, len(cols))
+		for i, col := range cols {
+			indexes[i] = fmt.Sprintf("%s (%d keys)", col, ts.Indexes[col])
+		}
+		rs.Rows = append(rs.Rows, []string{ts.Table, fmt.Sprint(ts.Rows), fmt.Sprint(ts.Bytes), strings.Join(indexes, ", ")})
+	}
+	return rs, ""
+}
+
+// ShowTables is ShowTablesResult rendered as text
+func (db *Database) ShowTables() string {
+	return resultText(db.ShowTablesResult())
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTableSizeTracksWrites(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("items", []string{"id", "name"})
+	_ = db.CreateIndex("items", "name")
+	_ = db.Insert("items", []string{"1", "apple"})
+	_ = db.Insert("items", []string{"2", "fig"})
+	_ = db.Insert("items", []string{"3", "fig"})
+	_ = db.Update("items", 0, []string{"1", "pear"})
+	_ = db.Delete("items", 1)
+
+	want := TableSize{Table: "items", Rows: 2, Bytes: 2 + 4 + 3, Indexes: map[string]int{"name": 2}}
+	check := func(when string, db *Database) {
+		t.Helper()
+		sizes := db.TableSizes()
+		if len(sizes) != 1 || fmt.Sprint(sizes[0]) != fmt.Sprint(want) {
+			t.Errorf("%s: sizes = %+v, want %+v", when, sizes, want)
+		}
+	}
+	check("after writes", db)
+	if total := db.DatabaseSize(); total != (DatabaseSize{Tables: 1, Rows: 2, Bytes: 9, Indexes: 1}) {
+		t.Errorf("database size = %+v", total)
+	}
+	db.Close()
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	check("after restart", db)
+}
+
+func TestAnalysisSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("orders", []string{"id", "status"})
+	for i := 0; i < 20; i++ {
+		_ = db.Insert("orders", []string{fmt.Sprint(i), "shipped"})
+	}
+	_ = db.CreateIndex("orders", "status")
+	_ = db.Analyze("orders")
+	db.Close()
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	got := db.Explain("orders", Query{Where: &equalWhere{col: "status", value: "shipped"}, Limit: -1})
+	if !strings.Contains(got, "index on status skipped: estimated 100.0% of 20 rows match") {
+		t.Errorf("plan after restart: %s", got)
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import "fmt"
+
+// Snapshot blocks new writes, waits for in-flight writes to finish, writes
+// dirty pages and a WAL checkpoint and runs fn. The data directory does not
+// change while fn runs, so fn should only copy what it needs and return
+// quickly.
+func (db *Database) Snapshot(fn func() error) error {
+	db.writeGate.Lock()
+	defer db.writeGate.Unlock()
+
+	if err := db.checkpointPages(); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+
+	return fn()
+}
+
+// Checkpoint waits for in-flight writes, writes dirty pages and a WAL
+// checkpoint and saves the table statistics. Tables are saved as each write
+// completes, so everything before the checkpoint is already on disk.
+func (db *Database) Checkpoint() error {
+	if err := db.Snapshot(func() error { return nil }); err != nil {
+		return err
+	}
+	return db.SaveTableStats()
+}
+
+// ForcedCheckpoint describes what CHECKPOINT or FLUSH TABLES wrote
+type ForcedCheckpoint struct {
+	// Tables is the number of table files rewritten
+	Tables int
+	// Pages is the number of dirty pages written
+	Pages int
+	// WALBytes is the size of the WAL before it was truncated
+	WALBytes int64
+}
+
+// ForceCheckpoint is Checkpoint followed by truncating the WAL, whose
+// changes are then all in the table and page files. With flushTables every
+// table file, unlogged ones included, is first rewritten and synced. When it
+// returns the data directory can be copied by a filesystem snapshot.
+func (db *Database) ForceCheckpoint(flushTables bool) (ForcedCheckpoint, error) {
+	result, err := db.forceCheckpoint(flushTables)
+	if err != nil {
+		return result, err
+	}
+	return result, db.SaveTableStats()
+}
+
+// forceCheckpoint does the work of ForceCheckpoint with writes blocked
+func (db *Database) forceCheckpoint(flushTables bool) (ForcedCheckpoint, error) {
+	db.writeGate.Lock()
+	defer db.writeGate.Unlock()
+
+	var result ForcedCheckpoint
+	if flushTables {
+		db.catalog.RLock()
+		tables := make([]*Table, 0, len(db.Tables))
+		for _, table := range db.Tables {
+			tables = append(tables, table)
+		}
+		db.catalog.RUnlock()
+		for _, table := range tables {
+			table.lock.RLock()
+			err := db.writeTable(table, true)
+			table.lock.RUnlock()
+			if err != nil {
+				return result, fmt.Errorf("failed to flush table %s: %w", table.Name, err)
+			}
+			result.Tables++
+		}
+	}
+
+	if db.PageStorage != nil {
+		result.Pages = db.PageStorage.DirtyPages()
+	}
+	if err := db.checkpointPages(); err != nil {
+		return result, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	if db.WAL == nil {
+		return result, nil
+	}
+	size, err := db.WAL.Size()
+	if err != nil {
+		return result, err
+	}
+	if err := db.WAL.TruncateWAL(); err != nil {
+		return result, err
+	}
+	result.WALBytes = size
+	return result, nil
+}
+
+// FlushPageCache checkpoints and then empties the page cache, so that every
+// page is read from its file again. It returns the number of pages dropped.
+func (db *Database) FlushPageCache() (int, error) {
+	if db.PageStorage == nil {
+		return 0, nil
+	}
+	db.writeGate.Lock()
+	defer db.writeGate.Unlock()
+	if err := db.checkpointPages(); err != nil {
+		return 0, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return db.PageStorage.DropCache(), nil
+}
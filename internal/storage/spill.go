@@ -0,0 +1,283 @@
+// internal/storage/spill.go
+//
+// Memory accounting and spill-to-disk for ORDER BY sorts.
+//
+// A sort materializes one binary sort key per matching row so comparisons
+// are plain byte compares. A key joins the keys of the ORDER BY columns,
+// each escaped and terminated so that a shorter value sorts before a longer
+// one it begins, and inverted for a DESC column. Keys are charged against the query's memory
+// budget; when the budget is exhausted the keys collected so far are sorted
+// and written to a temporary run file, and the final order is produced by a
+// k-way merge of all runs. Only the returned row indexes stay in memory.
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// sortEntryOverhead approximates the bytes a sortEntry uses besides its key
+const sortEntryOverhead = 48
+
+// memoryBudget tracks the bytes a query has reserved. A limit of 0 or less
+// means unlimited.
+type memoryBudget struct {
+	limit int64
+	used  int64
+}
+
+// reserve charges n bytes, reporting false if that would exceed the limit
+func (b *memoryBudget) reserve(n int64) bool {
+	if b.limit > 0 && b.used+n > b.limit {
+		return false
+	}
+	b.used += n
+	return true
+}
+
+// release returns n bytes to the budget
+func (b *memoryBudget) release(n int64) {
+	b.used -= n
+}
+
+// sortEntry is one row's sort key
+type sortEntry struct {
+	key []byte
+	row int
+}
+
+// sortKey encodes value so that bytes.Compare orders keys like compareValues:
+// numbers first in numeric order, then text under coll
+func sortKey(value string, coll *Collation) []byte {
+	if f, ok := parseOrderNumber(value); ok {
+		if f == 0 {
+			f = 0 // -0 sorts with 0, then by spelling
+		}
+		bits := math.Float64bits(f)
+		if bits&(1<<63) == 0 {
+			bits ^= 1 << 63
+		} else {
+			bits = ^bits
+		}
+		key := make([]byte, 9, 9+len(value))
+		binary.BigEndian.PutUint64(key[1:], bits)
+		return append(key, value...)
+	}
+	return append([]byte{1}, coll.Key(value)...)
+}
+
+// rowKey returns the sort key of row ri, ordering rows like o.less
+func (o rowOrder) rowKey(ri int) []byte {
+	var key []byte
+	for _, k := range o.keys {
+		start := len(key)
+		// 0 is escaped as 0 0xff and the column ends with 0 0, which sorts
+		// below any byte that could follow
+		for _, b := range sortKey(o.rows[ri][k.col], k.coll) {
+			if b == 0 {
+				key = append(key, 0, 0xff)
+			} else {
+				key = append(key, b)
+			}
+		}
+		key = append(key, 0, 0)
+		if k.desc {
+			for i := start; i < len(key); i++ {
+				key[i] = ^key[i]
+			}
+		}
+	}
+	return key
+}
+
+// entryLess orders sort entries, keeping table order for equal keys
+func entryLess(a, b sortEntry) bool {
+	if cmp := bytes.Compare(a.key, b.key); cmp != 0 {
+		return cmp < 0
+	}
+	return a.row < b.row
+}
+
+// externalSort orders the matched rows by the ORDER BY columns, spilling
+// sorted runs to temporary files whenever the keys exceed the budget
+func externalSort(rows [][]string, matched []int, order rowOrder, budget *memoryBudget) ([]int, error) {
+	var run []sortEntry
+	var runBytes int64
+	var spills []*os.File
+	defer func() {
+		for _, f := range spills {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	for _, ri := range matched {
+		e := sortEntry{key: order.rowKey(ri), row: ri}
+		size := int64(len(e.key)) + sortEntryOverhead
+		if !budget.reserve(size) {
+			if len(run) > 0 {
+				f, err := spillRun(run)
+				if err != nil {
+					return nil, err
+				}
+				spills = append(spills, f)
+				budget.release(runBytes)
+				run, runBytes = nil, 0
+			}
+			if !budget.reserve(size) {
+				// A single key larger than the budget still has to be sorted
+				budget.used += size
+			}
+		}
+		run = append(run, e)
+		runBytes += size
+	}
+	defer budget.release(runBytes)
+
+	sort.Slice(run, func(i, j int) bool { return entryLess(run[i], run[j]) })
+	if len(spills) == 0 {
+		sorted := make([]int, len(run))
+		for i, e := range run {
+			sorted[i] = e.row
+		}
+		return sorted, nil
+	}
+	return mergeRuns(spills, run, len(matched))
+}
+
+// spillRun sorts run and writes it to a temporary file
+func spillRun(run []sortEntry) (*os.File, error) {
+	sort.Slice(run, func(i, j int) bool { return entryLess(run[i], run[j]) })
+
+	f, err := os.CreateTemp("", "harudb-sort-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create sort spill file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	var buf [binary.MaxVarintLen64]byte
+	for _, e := range run {
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(e.key)))])
+		w.Write(e.key)
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(e.row))])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("write sort spill file: %w", err)
+	}
+	return f, nil
+}
+
+// runReader streams the entries of a spilled run
+type runReader struct {
+	r *bufio.Reader
+}
+
+// next reads the following entry, returning io.EOF at the end of the run
+func (rr *runReader) next() (sortEntry, error) {
+	n, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return sortEntry{}, err
+	}
+	key := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, key); err != nil {
+		return sortEntry{}, fmt.Errorf("read sort spill file: %w", err)
+	}
+	row, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return sortEntry{}, fmt.Errorf("read sort spill file: %w", err)
+	}
+	return sortEntry{key: key, row: int(row)}, nil
+}
+
+// mergeCursor is the head of one sorted run during the merge
+type mergeCursor struct {
+	head sortEntry
+	// exactly one of reader and mem is set
+	reader *runReader
+	mem    []sortEntry
+}
+
+// advance moves to the next entry, reporting whether one exists
+func (c *mergeCursor) advance() (bool, error) {
+	if c.reader == nil {
+		if len(c.mem) == 0 {
+			return false, nil
+		}
+		c.head, c.mem = c.mem[0], c.mem[1:]
+		return true, nil
+	}
+	e, err := c.reader.next()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c.head = e
+	return true, nil
+}
+
+// mergeHeap is a min-heap of run cursors ordered by their head entry
+type mergeHeap struct {
+	cursors []*mergeCursor
+}
+
+func (h *mergeHeap) Len() int { return len(h.cursors) }
+func (h *mergeHeap) Less(i, j int) bool {
+	return entryLess(h.cursors[i].head, h.cursors[j].head)
+}
+func (h *mergeHeap) Swap(i, j int)      { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+func (h *mergeHeap) Push(x interface{}) { h.cursors = append(h.cursors, x.(*mergeCursor)) }
+func (h *mergeHeap) Pop() interface{} {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
+
+// mergeRuns merges the spilled runs and the final in-memory run
+func mergeRuns(spills []*os.File, mem []sortEntry, total int) ([]int, error) {
+	h := &mergeHeap{}
+	add := func(c *mergeCursor) error {
+		ok, err := c.advance()
+		if ok {
+			h.cursors = append(h.cursors, c)
+		}
+		return err
+	}
+	for _, f := range spills {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("rewind sort spill file: %w", err)
+		}
+		if err := add(&mergeCursor{reader: &runReader{r: bufio.NewReader(f)}}); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(&mergeCursor{mem: mem}); err != nil {
+		return nil, err
+	}
+	heap.Init(h)
+
+	sorted := make([]int, 0, total)
+	for h.Len() > 0 {
+		c := h.cursors[0]
+		sorted = append(sorted, c.head.row)
+		ok, err := c.advance()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestExternalSortSpills(t *testing.T) {
+	spillDir := t.TempDir()
+	t.Setenv("TMPDIR", spillDir)
+
+	values := []string{"banana", "Apple", "10", "9.5", "-0", "0", "+0", "cherry", "apple", "-3", "éclair", "Zebra", "1e3", "", "NaN"}
+	var rows [][]string
+	for i := 0; i < 500; i++ {
+		rows = append(rows, []string{fmt.Sprint(i), values[(i*7)%len(values)] + fmt.Sprint(i%3)})
+		rows = append(rows, []string{fmt.Sprint(i), values[(i*11)%len(values)]})
+	}
+	matched := make([]int, len(rows))
+	for i := range matched {
+		matched[i] = i
+	}
+
+	for _, name := range []string{"BINARY", "NOCASE", "en"} {
+		coll, err := ParseCollation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, desc := range []bool{false, true} {
+			// The second key orders the rows with equal words by id, the
+			// other way round
+			id, _ := ParseCollation("BINARY")
+			order := rowOrder{rows: rows, keys: []orderKey{{col: 1, coll: coll, desc: desc}, {col: 0, coll: id, desc: !desc}}}
+			want := append([]int(nil), matched...)
+			sort.Slice(want, func(i, j int) bool { return order.less(want[i], want[j]) })
+
+			for _, limit := range []int64{0, 4096, 100} {
+				budget := &memoryBudget{limit: limit}
+				got, err := externalSort(rows, append([]int(nil), matched...), order, budget)
+				if err != nil {
+					t.Fatalf("%s desc=%v budget=%d: %v", name, desc, limit, err)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("%s desc=%v budget=%d: external sort differs from in-memory order", name, desc, limit)
+				}
+				if budget.used != 0 {
+					t.Errorf("%s budget=%d: %d bytes still reserved", name, limit, budget.used)
+				}
+			}
+		}
+	}
+
+	if entries, _ := os.ReadDir(spillDir); len(entries) != 0 {
+		t.Errorf("spill files were not removed: %v", entries)
+	}
+}
+
+func TestSelectQuerySpillsLargeSort(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	db := NewDatabase(t.TempDir())
+	db.CreateTable("words", []string{"id", "word"})
+	for i := 0; i < 300; i++ {
+		db.Insert("words", []string{fmt.Sprint(i), fmt.Sprintf("w%03d", (i*37)%300)})
+	}
+
+	unlimited := db.SelectQuery("words", Query{OrderBy: "word", Desc: true, Limit: -1})
+	db.QueryMemoryBudget = 1024
+	if got := db.SelectQuery("words", Query{OrderBy: "word", Desc: true, Limit: -1}); got != unlimited {
+		t.Errorf("spilled sort differs:\n%.200s\nwant:\n%.200s", got, unlimited)
+	}
+}
@@ -0,0 +1,159 @@
+// internal/storage/stats.go
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TableStatsFile holds the per-table counters saved at each checkpoint
+const TableStatsFile = "table_stats.json"
+
+// TableStats counts how a table has been used. Counters are kept in memory
+// and saved by Checkpoint and Close, so a crash loses the counts since the
+// last checkpoint.
+type TableStats struct {
+	Table   string `json:"table"`
+	Reads   uint64 `json:"reads"`
+	Inserts uint64 `json:"inserts"`
+	Updates uint64 `json:"updates"`
+	Deletes uint64 `json:"deletes"`
+	// IndexLookups counts reads answered from an index instead of a scan
+	IndexLookups uint64 `json:"index_lookups"`
+	// LastWrite is zero when the table has not been written to
+	LastWrite time.Time `json:"last_write,omitempty"`
+}
+
+// tableStats holds a table's live counters
+type tableStats struct {
+	reads        atomic.Uint64
+	inserts      atomic.Uint64
+	updates      atomic.Uint64
+	deletes      atomic.Uint64
+	indexLookups atomic.Uint64
+	// lastWrite is in Unix nanoseconds, 0 before the first write
+	lastWrite atomic.Int64
+}
+
+// recordWrite counts one insert, update or delete
+func (s *tableStats) recordWrite(counter *atomic.Uint64) {
+	counter.Add(1)
+	s.lastWrite.Store(time.Now().UnixNano())
+}
+
+// snapshot returns the counters of table name
+func (s *tableStats) snapshot(name string) TableStats {
+	st := TableStats{
+		Table:        name,
+		Reads:        s.reads.Load(),
+		Inserts:      s.inserts.Load(),
+		Updates:      s.updates.Load(),
+		Deletes:      s.deletes.Load(),
+		IndexLookups: s.indexLookups.Load(),
+	}
+	if ns := s.lastWrite.Load(); ns != 0 {
+		st.LastWrite = time.Unix(0, ns).UTC()
+	}
+	return st
+}
+
+// restore sets the counters from saved statistics
+func (s *tableStats) restore(st TableStats) {
+	s.reads.Store(st.Reads)
+	s.inserts.Store(st.Inserts)
+	s.updates.Store(st.Updates)
+	s.deletes.Store(st.Deletes)
+	s.indexLookups.Store(st.IndexLookups)
+	if !st.LastWrite.IsZero() {
+		s.lastWrite.Store(st.LastWrite.UnixNano())
+	}
+}
+
+// TableStats returns the statistics of every table, sorted by name
+func (db *Database) TableStats() []TableStats {
+	db.catalog.RLock()
+	defer db.catalog.RUnlock()
+
+	stats := make([]TableStats, 0, len(db.Tables))
+	for name, table := range db.Tables {
+		stats = append(stats, table.stats.snapshot(name))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Table < stats[j].Table })
+	return stats
+}
+
+// ShowTableStatsResult lists the statistics of one table, or of every table when
+// tableName is empty
+func (db *Database) ShowTableStatsResult(tableName string) (*ResultSet, string) {
+	var stats []TableStats
+	if tableName != "" {
+		tableName = strings.ToLower(tableName)
+		table, exists := db.lookupTable(tableName)
+		if !exists {
+			return nil, fmt.Sprintf(ErrTableNotFound, tableName)
+		}
+		stats = []TableStats{table.stats.snapshot(tableName)}
+	} else {
+		stats = db.TableStats()
+	}
+
+	rs := &ResultSet{Columns: []string{"table", "reads", "inserts", "updates", "deletes", "index_lookups", "last_write"}}
+	for _, st := range stats {
+		lastWrite := ""
+		if !st.LastWrite.IsZero() {
+			lastWrite = st.LastWrite.Format(time.RFC3339)
+		}
+		rs.Rows = append(rs.Rows, []string{st.Table, fmt.Sprint(st.Reads), fmt.Sprint(st.Inserts), fmt.Sprint(st.Updates),
+			fmt.Sprint(st.Deletes), fmt.Sprint(st.IndexLookups), lastWrite})
+	}
+	return rs, ""
+}
+
+// ShowTableStats is ShowTableStatsResult rendered as text
+func (db *Database) ShowTableStats(tableName string) string {
+	return resultText(db.ShowTableStatsResult(tableName))
+}
+
+// SaveTableStats writes the table statistics to TableStatsFile
+func (db *Database) SaveTableStats() error {
+	data, err := json.MarshalIndent(db.TableStats(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal table stats: %w", err)
+	}
+	path := filepath.Join(db.DataDir, TableStatsFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write table stats: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("save table stats: %w", err)
+	}
+	return nil
+}
+
+// loadTableStats restores the statistics saved by SaveTableStats. Missing
+// or unreadable statistics start from zero.
+func (db *Database) loadTableStats() {
+	data, err := os.ReadFile(filepath.Join(db.DataDir, TableStatsFile))
+	if err != nil {
+		return
+	}
+	var stats []TableStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		log.Printf("Warning: ignoring corrupt %s: %v\n", TableStatsFile, err)
+		return
+	}
+	for _, st := range stats {
+		if table, ok := db.lookupTable(st.Table); ok {
+			table.stats.restore(st)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableStats(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	_ = db.CreateTable("users", []string{"id", "name"})
+	_ = db.CreateTable("empty", []string{"id"})
+	_ = db.CreateIndex("users", "name")
+
+	_ = db.Insert("users", []string{"1", "alice"})
+	_ = db.Insert("users", []string{"2", "bob"})
+	_ = db.Update("users", 0, []string{"1", "alicia"})
+	_ = db.Delete("users", 1)
+	_ = db.SelectAll("users")
+	_ = db.SelectWhere("users", "name", "alicia")
+
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.InsertTx("users", []string{"3", "carol"})
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := db.TableStats()
+	if len(stats) != 2 || stats[0].Table != "empty" || stats[1].Table != "users" {
+		t.Fatalf("stats not sorted by table: %+v", stats)
+	}
+	if !stats[0].LastWrite.IsZero() {
+		t.Errorf("unwritten table has a last write: %v", stats[0].LastWrite)
+	}
+	got := stats[1]
+	if got.Reads != 2 || got.Inserts != 3 || got.Updates != 1 || got.Deletes != 1 || got.IndexLookups != 1 {
+		t.Errorf("users stats = %+v", got)
+	}
+	if got.LastWrite.IsZero() {
+		t.Error("last write not recorded")
+	}
+
+	out := db.ShowTableStats("users")
+	if !strings.HasPrefix(out, "table | reads | inserts") || !strings.Contains(out, "users | 2 | 3 | 1 | 1 | 1 | ") {
+		t.Errorf("SHOW TABLE STATS users:\n%s", out)
+	}
+	if out := db.ShowTableStats("missing"); !strings.Contains(out, "not found") {
+		t.Errorf("unknown table: %s", out)
+	}
+}
+
+func TestTableStatsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("users", []string{"id"})
+	_ = db.Insert("users", []string{"1"})
+	_ = db.SelectAll("users")
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = NewDatabase(dir)
+	defer db.Close()
+	got := db.TableStats()
+	if len(got) != 1 || got[0].Inserts != 1 || got[0].Reads != 1 || got[0].LastWrite.IsZero() {
+		t.Errorf("stats after restart = %+v", got)
+	}
+}
@@ -0,0 +1,131 @@
+// internal/storage/timestamps.go
+//
+// Row timestamps. A table created WITH (timestamps=true) gets two columns,
+// created_at and updated_at, after its own columns (and before _version).
+// An insert sets both to the current time and an update sets updated_at,
+// so every row records when it was written, for sync jobs, retention
+// decisions and change data capture consumers, which see both columns in
+// the row values of every change. Times are UTC with microseconds, written
+// so that they sort as text in time order: 2025-01-15T09:30:00.000000Z.
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Columns a timestamped table keeps its row times in
+const (
+	CreatedAtColumn = "created_at"
+	UpdatedAtColumn = "updated_at"
+)
+
+// TimestampFormat is the layout of created_at and updated_at values
+const TimestampFormat = "2006-01-02T15:04:05.000000Z"
+
+// DateFormat is the layout of DATE values
+const DateFormat = "2006-01-02"
+
+// FormatTimestamp returns the created_at or updated_at value of time t
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampFormat)
+}
+
+// timeLayouts are the layouts ParseTime accepts, tried in order
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	DateFormat,
+}
+
+// ParseTime parses a value written as a point in time: a created_at value,
+// an RFC 3339 time, a date and time with a space instead of the T, or a
+// date. Times without a zone are UTC.
+func ParseTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// timestampedColumns returns the column specs of a new table, with
+// created_at and updated_at added when it is timestamped
+func timestampedColumns(columns []string, timestamps bool) ([]string, error) {
+	if !timestamps {
+		return columns, nil
+	}
+	defs, err := parseColumnSpecs(columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range []string{CreatedAtColumn, UpdatedAtColumn} {
+		if slices.Contains(defs.names, col) {
+			return nil, fmt.Errorf("%s is added by timestamps=true; leave it out of the column list", col)
+		}
+	}
+	return append(slices.Clone(columns), CreatedAtColumn, UpdatedAtColumn), nil
+}
+
+// timestampIndex returns the position of the created_at column, which
+// updated_at follows, or -1 when the table is not timestamped
+func (t *Table) timestampIndex() int {
+	if !t.Timestamps {
+		return -1
+	}
+	i := len(t.Columns) - 2
+	if t.Versioned {
+		i--
+	}
+	return i
+}
+
+// autoColumn reports whether the table maintains a column itself
+func (t *Table) autoColumn(idx int) bool {
+	ti := t.timestampIndex()
+	return idx >= 0 && (idx == t.versionIndex() || ti >= 0 && (idx == ti || idx == ti+1))
+}
+
+// AutoColumn reports whether a table maintains a column itself, so that
+// statements cannot set it
+func (db *Database) AutoColumn(tableName, column string) bool {
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return false
+	}
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+	return table.autoColumn(table.columnIndex(column))
+}
+
+// timestampedRow completes the values of a row inserted into a timestamped
+// table, which gives only its own columns, with created_at and updated_at
+// set to now. Rows given with every column keep their times.
+func (t *Table) timestampedRow(values []string, now time.Time) []string {
+	ti := t.timestampIndex()
+	if ti < 0 || len(values) != ti {
+		return values
+	}
+	ts := FormatTimestamp(now)
+	return append(slices.Clone(values), ts, ts)
+}
+
+// touchedRow returns the values of an update of a row of a timestamped
+// table, currently holding current, with its created_at kept and
+// updated_at set to now
+func (t *Table) touchedRow(current, values []string, now time.Time) []string {
+	ti := t.timestampIndex()
+	if ti < 0 || len(values) != len(t.Columns) || len(current) != len(t.Columns) {
+		return values
+	}
+	touched := slices.Clone(values)
+	touched[ti] = current[ti]
+	touched[ti+1] = FormatTimestamp(now)
+	return touched
+}
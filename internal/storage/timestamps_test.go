@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTimestampedRows(t *testing.T) {
+	now := time.Date(2025, 1, 15, 9, 30, 0, 1500, time.FixedZone("x", 3600))
+	later := now.Add(time.Minute)
+	table := &Table{Name: "t", Columns: []string{"id", CreatedAtColumn, UpdatedAtColumn}, Timestamps: true}
+	if got := table.timestampIndex(); got != 1 {
+		t.Errorf("timestampIndex = %d, want 1", got)
+	}
+	row := table.timestampedRow([]string{"1"}, now)
+	if want := []string{"1", "2025-01-15T08:30:00.000001Z", "2025-01-15T08:30:00.000001Z"}; !slices.Equal(row, want) {
+		t.Errorf("timestampedRow = %v, want %v", row, want)
+	}
+	// A full row, as replayed or restored, keeps its times
+	if got := table.timestampedRow(row, later); !slices.Equal(got, row) {
+		t.Errorf("timestampedRow of a full row = %v", got)
+	}
+	touched := table.touchedRow(row, []string{"2", "x", "y"}, later)
+	if want := []string{"2", "2025-01-15T08:30:00.000001Z", "2025-01-15T08:31:00.000001Z"}; !slices.Equal(touched, want) {
+		t.Errorf("touchedRow = %v, want %v", touched, want)
+	}
+
+	versioned := &Table{Name: "v", Columns: []string{"id", CreatedAtColumn, UpdatedAtColumn, VersionColumn}, Timestamps: true, Versioned: true}
+	if got := versioned.timestampIndex(); got != 1 {
+		t.Errorf("timestampIndex of a versioned table = %d, want 1", got)
+	}
+	for i, want := range []bool{false, true, true, true} {
+		if got := versioned.autoColumn(i); got != want {
+			t.Errorf("autoColumn(%d) = %v", i, got)
+		}
+	}
+	if plain := (&Table{Columns: []string{"id"}}); plain.timestampIndex() != -1 || plain.autoColumn(0) {
+		t.Error("a plain table has timestamp columns")
+	}
+
+	if _, err := timestampedColumns([]string{"id", "created_at"}, true); err == nil || err.Error() != "created_at is added by timestamps=true; leave it out of the column list" {
+		t.Errorf("timestampedColumns with created_at: %v", err)
+	}
+	if cols, err := timestampedColumns([]string{"id"}, false); err != nil || !slices.Equal(cols, []string{"id"}) {
+		t.Errorf("timestampedColumns without timestamps: %v %v", cols, err)
+	}
+}
@@ -3,6 +3,7 @@ package storage
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 )
@@ -36,7 +37,15 @@ type Transaction struct {
 	EndTime        time.Time
 	Operations     []TransactionOperation
 	Savepoints     map[string]int // savepoint name -> operation index
-	mu             sync.RWMutex
+	// savepointOrder lists savepoint names, oldest first
+	savepointOrder []string
+	// snapshot holds each table's rows as of BEGIN under REPEATABLE READ and
+	// SERIALIZABLE (see isolation.go); reads records the tables read
+	snapshot map[string]tableSnapshot
+	reads    map[string]bool
+	// logged is set once the transaction's BEGIN record is in the WAL
+	logged bool
+	mu     sync.RWMutex
 }
 
 // TransactionOperation represents a single operation within a transaction
@@ -83,17 +92,28 @@ func (tm *TransactionManager) BeginTransaction(isolationLevel IsolationLevel) (*
 
 	tm.transactions[txID] = tx
 
-	// Log transaction begin to WAL
-	if tm.db.WAL != nil {
-		data := map[string]interface{}{
-			"isolation_level": int(isolationLevel),
+	// The BEGIN record waits for the transaction's first WAL entry, so idle
+	// transactions write nothing
+	return tx, nil
+}
+
+// logEntry writes a WAL entry of tx, after its BEGIN record when it is the
+// transaction's first. The caller holds tx.mu.
+func (tm *TransactionManager) logEntry(tx *Transaction, entryType WALEntryType, data map[string]interface{}) error {
+	if tm.db.WAL == nil {
+		return nil
+	}
+	if !tx.logged {
+		begin := map[string]interface{}{
+			"transaction_id":  tx.ID,
+			"isolation_level": int(tx.IsolationLevel),
 		}
-		if err := tm.db.WAL.WriteEntry(WAL_BEGIN_TRANSACTION, "", data); err != nil {
-			return nil, fmt.Errorf("failed to write transaction begin to WAL: %w", err)
+		if err := tm.db.WAL.WriteEntry(WAL_BEGIN_TRANSACTION, "", begin); err != nil {
+			return fmt.Errorf("failed to write transaction begin to WAL: %w", err)
 		}
+		tx.logged = true
 	}
-
-	return tx, nil
+	return tm.db.WAL.WriteEntry(entryType, "", data)
 }
 
 // GetTransaction retrieves a transaction by ID
@@ -104,60 +124,59 @@ func (tm *TransactionManager) GetTransaction(txID string) (*Transaction, bool) {
 	return tx, exists
 }
 
-// CommitTransaction commits a transaction
+// CommitTransaction commits a transaction. Its operations are written to
+// the WAL as one commit record before any table changes (see commit.go).
 func (tm *TransactionManager) CommitTransaction(txID string) error {
-	fmt.Printf("[COMMIT] start txID=%s", txID)
-
-	// 1️⃣ Grab the transaction safely
-	fmt.Printf("[COMMIT] locking tm.mu to fetch tx")
 	tm.mu.Lock()
 	tx, exists := tm.transactions[txID]
+	tm.mu.Unlock()
 	if !exists {
-		tm.mu.Unlock()
-		fmt.Printf("[COMMIT] tx %s not found", txID)
 		return fmt.Errorf("transaction %s not found", txID)
 	}
-	fmt.Printf("[COMMIT] tx %s fetched, releasing tm.mu", txID)
-	tm.mu.Unlock()
 
-	// 2️⃣ Lock the transaction itself
-	fmt.Printf("[COMMIT] locking tx.mu")
 	tx.mu.Lock()
-	defer func() {
-		fmt.Printf("[COMMIT] unlocked tx.mu for %s", txID)
-		tx.mu.Unlock()
-	}()
+	defer tx.mu.Unlock()
 
 	if tx.State != TransactionActive {
-		fmt.Printf("[COMMIT] tx %s not active (state=%d)", txID, tx.State)
 		return fmt.Errorf("transaction %s is not active (state: %d)", txID, tx.State)
 	}
-	fmt.Printf("[COMMIT] tx %s is active with %d ops", txID, len(tx.Operations))
 
-	// 3️⃣ Apply operations atomically
+	// Snapshot transactions fail if another transaction changed what they
+	// depend on
+	if err := tm.checkConflicts(tx); err != nil {
+		tm.abortLocked(tx)
+		return err
+	}
+
+	// Log the whole transaction, apply it in memory, record its changes
+	// and only then save the tables it changed
+	lsn, err := tm.logCommit(tx)
+	if err != nil {
+		tm.abortLocked(tx)
+		return err
+	}
+	deferred := make(map[*Table]bool)
 	for i, op := range tx.Operations {
-		fmt.Printf("[COMMIT] applying op %d: %+v", i, op)
-		if err := tm.applyOperation(op); err != nil {
-			fmt.Printf("[COMMIT] FAILED op %d: %v — rolling back", i, err)
-			tm.rollbackTransactionUnsafe(tx)
+		if err := tm.applyOperation(op, deferred); err != nil {
+			tm.abortLocked(tx)
 			return fmt.Errorf("failed to apply operation %d: %w", i, err)
 		}
-		fmt.Printf("[COMMIT] op %d applied successfully", i)
 	}
+	var changes []ChangeEvent
+	for _, op := range tx.Operations {
+		changes = append(changes, tm.db.recordOperation(op, lsn)...)
+	}
+	tm.saveDeferred(deferred)
 
-	// 4️⃣ Mark committed
+	// Mark committed and publish the changes to commit hooks
 	tx.State = TransactionCommitted
 	tx.EndTime = time.Now()
-	fmt.Printf("[COMMIT] tx %s marked committed", txID)
 
-	// 5️⃣ Clean up safely
-	fmt.Printf("[COMMIT] locking tm.mu for cleanup")
 	tm.mu.Lock()
 	delete(tm.transactions, txID)
 	tm.mu.Unlock()
-	fmt.Printf("[COMMIT] tx %s removed from manager", txID)
 
-	fmt.Printf("[COMMIT] completed successfully for tx %s", txID)
+	tm.db.runCommitHooks(txID, changes)
 	return nil
 }
 
@@ -178,7 +197,20 @@ func (tm *TransactionManager) RollbackTransaction(txID string) error {
 func (tm *TransactionManager) rollbackTransactionUnsafe(tx *Transaction) error {
 	tx.mu.Lock()
 	defer tx.mu.Unlock()
+	return tm.rollbackLocked(tx)
+}
+
+// abortLocked rolls back a transaction whose commit failed. The caller holds
+// tx.mu but not the manager lock.
+func (tm *TransactionManager) abortLocked(tx *Transaction) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.rollbackLocked(tx)
+}
 
+// rollbackLocked marks tx rolled back and forgets it. The caller holds tx.mu
+// and the manager lock.
+func (tm *TransactionManager) rollbackLocked(tx *Transaction) error {
 	if tx.State != TransactionActive {
 		return fmt.Errorf("transaction %s is not active (state: %d)", tx.ID, tx.State)
 	}
@@ -187,12 +219,12 @@ func (tm *TransactionManager) rollbackTransactionUnsafe(tx *Transaction) error {
 	tx.State = TransactionRolledBack
 	tx.EndTime = time.Now()
 
-	// Log transaction rollback to WAL
-	if tm.db.WAL != nil {
+	// Log transaction rollback to WAL, unless it never logged its BEGIN
+	if tx.logged {
 		data := map[string]interface{}{
 			"transaction_id": tx.ID,
 		}
-		if err := tm.db.WAL.WriteEntry(WAL_ROLLBACK_TRANSACTION, "", data); err != nil {
+		if err := tm.logEntry(tx, WAL_ROLLBACK_TRANSACTION, data); err != nil {
 			return fmt.Errorf("failed to write transaction rollback to WAL: %w", err)
 		}
 	}
@@ -220,8 +252,13 @@ func (tm *TransactionManager) CreateSavepoint(txID, savepointName string) error
 		return fmt.Errorf("transaction %s is not active", txID)
 	}
 
-	// Record savepoint at current operation count
+	// Record savepoint at current operation count. Reusing a name moves the
+	// savepoint, as if the old one were released.
+	if i := slices.Index(tx.savepointOrder, savepointName); i >= 0 {
+		tx.removeSavepoints(i, 1)
+	}
 	tx.Savepoints[savepointName] = len(tx.Operations)
+	tx.savepointOrder = append(tx.savepointOrder, savepointName)
 
 	// Log savepoint creation to WAL
 	if tm.db.WAL != nil {
@@ -230,7 +267,7 @@ func (tm *TransactionManager) CreateSavepoint(txID, savepointName string) error
 			"savepoint_name":  savepointName,
 			"operation_index": len(tx.Operations),
 		}
-		if err := tm.db.WAL.WriteEntry(WAL_SAVEPOINT, "", data); err != nil {
+		if err := tm.logEntry(tx, WAL_SAVEPOINT, data); err != nil {
 			return fmt.Errorf("failed to write savepoint to WAL: %w", err)
 		}
 	}
@@ -260,8 +297,14 @@ func (tm *TransactionManager) RollbackToSavepoint(txID, savepointName string) er
 		return fmt.Errorf("savepoint %s not found", savepointName)
 	}
 
-	// Truncate operations to the savepoint
+	// Truncate operations to the savepoint. The transaction's view of its
+	// own writes is built from the queued operations, so this also reverts
+	// what later statements in the transaction read. Savepoints created
+	// after this one are destroyed; this one stays usable.
 	tx.Operations = tx.Operations[:operationIndex]
+	if i := slices.Index(tx.savepointOrder, savepointName); i >= 0 {
+		tx.removeSavepoints(i+1, len(tx.savepointOrder)-i-1)
+	}
 
 	// Log rollback to savepoint to WAL
 	if tm.db.WAL != nil {
@@ -270,7 +313,7 @@ func (tm *TransactionManager) RollbackToSavepoint(txID, savepointName string) er
 			"savepoint_name":  savepointName,
 			"operation_index": operationIndex,
 		}
-		if err := tm.db.WAL.WriteEntry(WAL_ROLLBACK_TO_SAVEPOINT, "", data); err != nil {
+		if err := tm.logEntry(tx, WAL_ROLLBACK_TO_SAVEPOINT, data); err != nil {
 			return fmt.Errorf("failed to write rollback to savepoint to WAL: %w", err)
 		}
 	}
@@ -278,6 +321,15 @@ func (tm *TransactionManager) RollbackToSavepoint(txID, savepointName string) er
 	return nil
 }
 
+// removeSavepoints forgets n savepoints starting at position i of
+// savepointOrder. The caller holds tx.mu.
+func (tx *Transaction) removeSavepoints(i, n int) {
+	for _, name := range tx.savepointOrder[i : i+n] {
+		delete(tx.Savepoints, name)
+	}
+	tx.savepointOrder = slices.Delete(tx.savepointOrder, i, i+n)
+}
+
 // AddOperation adds an operation to a transaction
 func (tm *TransactionManager) AddOperation(txID string, opType WALEntryType, tableName string, data interface{}) error {
 	tm.mu.RLock()
@@ -305,10 +357,22 @@ func (tm *TransactionManager) AddOperation(txID string, opType WALEntryType, tab
 			}
 			m["values"] = intfVals
 		}
+		// and for the "columns" of CREATE TABLE
+		if cols, ok := m["columns"].([]string); ok {
+			intfCols := make([]interface{}, len(cols))
+			for i, c := range cols {
+				intfCols[i] = c
+			}
+			m["columns"] = intfCols
+		}
 		// convert int -> float64 for "row_index" if present
 		if ri, ok := m["row_index"].(int); ok {
 			m["row_index"] = float64(ri)
 		}
+		// and int64 -> float64 for "row_id"
+		if id, ok := m["row_id"].(int64); ok {
+			m["row_id"] = float64(id)
+		}
 		data = m
 	}
 
@@ -323,8 +387,10 @@ func (tm *TransactionManager) AddOperation(txID string, opType WALEntryType, tab
 	return nil
 }
 
-// applyOperation applies a single transaction operation to the database
-func (tm *TransactionManager) applyOperation(op TransactionOperation) error {
+// applyOperation applies a single transaction operation to the database.
+// Changed tables are saved right away, or added to deferred when it is not
+// nil.
+func (tm *TransactionManager) applyOperation(op TransactionOperation, deferred map[*Table]bool) error {
 	switch op.Type {
 	case WAL_CREATE_TABLE:
 		if data, ok := op.Data.(map[string]interface{}); ok {
@@ -333,7 +399,12 @@ func (tm *TransactionManager) applyOperation(op TransactionOperation) error {
 				for i, col := range columns {
 					colStrs[i] = col.(string)
 				}
-				return tm.applyCreateTable(op.TableName, colStrs)
+				var opts TableOptions
+				opts.Unlogged, _ = data["unlogged"].(bool)
+				opts.Versioned, _ = data["versioned"].(bool)
+				opts.Timestamps, _ = data["timestamps"].(bool)
+				keyID, _ := data["key_id"].(string)
+				return tm.applyCreateTable(op.TableName, colStrs, opts, keyID, deferred)
 			}
 		}
 		return fmt.Errorf("invalid CREATE TABLE operation data")
@@ -345,20 +416,28 @@ func (tm *TransactionManager) applyOperation(op TransactionOperation) error {
 				for i, val := range values {
 					valStrs[i] = val.(string)
 				}
-				return tm.applyInsert(op.TableName, valStrs)
+				// The commit record reserves row IDs of inserts into existing
+				// tables; record the ID of the others for the change log
+				reserved, _ := data["row_id"].(float64)
+				id, err := tm.applyInsert(op.TableName, valStrs, int64(reserved), deferred)
+				if err == nil {
+					data["row_id"] = float64(id)
+				}
+				return err
 			}
 		}
 		return fmt.Errorf("invalid INSERT operation data")
 
 	case WAL_UPDATE:
 		if data, ok := op.Data.(map[string]interface{}); ok {
-			if rowIndex, ok := data["row_index"].(float64); ok {
+			if target, ok := operationTarget(data); ok {
 				if values, ok := data["values"].([]interface{}); ok {
 					valStrs := make([]string, len(values))
 					for i, val := range values {
 						valStrs[i] = val.(string)
 					}
-					return tm.applyUpdate(op.TableName, int(rowIndex), valStrs)
+					version, _ := data["version"].(float64)
+					return tm.applyUpdate(op.TableName, target, valStrs, int64(version), deferred)
 				}
 			}
 		}
@@ -366,8 +445,8 @@ func (tm *TransactionManager) applyOperation(op TransactionOperation) error {
 
 	case WAL_DELETE:
 		if data, ok := op.Data.(map[string]interface{}); ok {
-			if rowIndex, ok := data["row_index"].(float64); ok {
-				return tm.applyDelete(op.TableName, int(rowIndex))
+			if target, ok := operationTarget(data); ok {
+				return tm.applyDelete(op.TableName, target, deferred)
 			}
 		}
 		return fmt.Errorf("invalid DELETE operation data")
@@ -375,91 +454,158 @@ func (tm *TransactionManager) applyOperation(op TransactionOperation) error {
 	case WAL_DROP_TABLE:
 		return tm.applyDropTable(op.TableName)
 
+	case WAL_RENAME_TABLE:
+		if data, ok := op.Data.(map[string]interface{}); ok {
+			if newName, ok := data["new_name"].(string); ok {
+				return tm.applyRenameTable(op.TableName, newName)
+			}
+		}
+		return fmt.Errorf("invalid RENAME TABLE operation data")
+
 	default:
 		return fmt.Errorf("unsupported operation type: %d", op.Type)
 	}
 }
 
 // applyCreateTable applies CREATE TABLE operation
-func (tm *TransactionManager) applyCreateTable(tableName string, columns []string) error {
+func (tm *TransactionManager) applyCreateTable(tableName string, columns []string, opts TableOptions, keyID string, deferred map[*Table]bool) error {
+	tm.db.catalog.Lock()
+	defer tm.db.catalog.Unlock()
+
 	if _, exists := tm.db.Tables[tableName]; exists {
 		return fmt.Errorf("table %s already exists", tableName)
 	}
+	defs, err := parseColumnSpecs(columns)
+	if err != nil {
+		return err
+	}
+	if err := defs.resolveForeignKeys(tableName, tm.db.catalogTable); err != nil {
+		return err
+	}
 
-	tm.db.Tables[tableName] = &Table{
+	tm.db.addTable(&Table{
 		Name:           tableName,
-		Columns:        columns,
 		Rows:           [][]string{},
 		IndexedColumns: []string{},
 		Indexes:        make(map[string]map[string][]int),
-	}
-
-	return tm.db.saveTable(tm.db.Tables[tableName])
+		Unlogged:       opts.Unlogged,
+		KeyID:          keyID,
+		Versioned:      opts.Versioned,
+		Timestamps:     opts.Timestamps,
+	})
+	defs.apply(tm.db.Tables[tableName])
+
+	return tm.db.persist(tm.db.Tables[tableName], deferred)
 }
 
-// applyInsert applies INSERT operation
-func (tm *TransactionManager) applyInsert(tableName string, values []string) error {
-	table, exists := tm.db.Tables[tableName]
+// applyInsert applies INSERT operation and returns the new row's ID, which
+// is id when that is not 0
+func (tm *TransactionManager) applyInsert(tableName string, values []string, id int64, deferred map[*Table]bool) (int64, error) {
+	table, exists := tm.db.lookupTable(tableName)
 	if !exists {
-		return fmt.Errorf("table %s not found", tableName)
+		return 0, fmt.Errorf("table %s not found", tableName)
 	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
 
 	if len(values) != len(table.Columns) {
-		return fmt.Errorf("column count mismatch")
+		return 0, fmt.Errorf("column count mismatch")
 	}
 
-	table.Rows = append(table.Rows, values)
-	tm.db.applyIndexesOnInsert(table, len(table.Rows)-1)
+	id = table.appendRow(values, id)
+	tm.db.indexInsert(table, len(table.Rows)-1)
 
-	return tm.db.saveTable(table)
+	table.stats.recordWrite(&table.stats.inserts)
+	return id, tm.db.persist(table, deferred)
 }
 
 // applyUpdate applies UPDATE operation
-func (tm *TransactionManager) applyUpdate(tableName string, rowIndex int, values []string) error {
-	table, exists := tm.db.Tables[tableName]
+func (tm *TransactionManager) applyUpdate(tableName string, target rowTarget, values []string, version int64, deferred map[*Table]bool) error {
+	table, exists := tm.db.lookupTable(tableName)
 	if !exists {
 		return fmt.Errorf("table %s not found", tableName)
 	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
 
-	if rowIndex < 0 || rowIndex >= len(table.Rows) {
-		return fmt.Errorf("row index %d out of bounds (table has %d rows)", rowIndex, len(table.Rows))
+	rowIndex, msg := table.locate(target)
+	if msg != "" {
+		return fmt.Errorf("%s not found (table has %d rows)", target, len(table.Rows))
 	}
 
 	if len(values) != len(table.Columns) {
 		return fmt.Errorf("column count mismatch: expected %d, got %d", len(table.Columns), len(values))
 	}
+	if err := table.checkVersion(rowIndex, version); err != nil {
+		return err
+	}
 
-	table.Rows[rowIndex] = values
-	tm.db.rebuildAllIndexes(table)
+	tm.db.noteRowWrite(table, rowIndex)
+	table.setRow(rowIndex, values)
+	tm.db.reindex(table)
 
-	return tm.db.saveTable(table)
+	table.stats.recordWrite(&table.stats.updates)
+	return tm.db.persist(table, deferred)
 }
 
 // applyDelete applies DELETE operation
-func (tm *TransactionManager) applyDelete(tableName string, rowIndex int) error {
-	table, exists := tm.db.Tables[tableName]
+func (tm *TransactionManager) applyDelete(tableName string, target rowTarget, deferred map[*Table]bool) error {
+	table, exists := tm.db.lookupTable(tableName)
 	if !exists {
 		return fmt.Errorf("table %s not found", tableName)
 	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
 
-	if rowIndex < 0 || rowIndex >= len(table.Rows) {
-		return fmt.Errorf("row index out of bounds")
+	rowIndex, msg := table.locate(target)
+	if msg != "" {
+		return fmt.Errorf("%s not found", target)
 	}
 
-	table.Rows = append(table.Rows[:rowIndex], table.Rows[rowIndex+1:]...)
-	tm.db.rebuildAllIndexes(table)
+	tm.db.noteRowWrite(table, rowIndex)
+	table.removeRow(rowIndex)
+	tm.db.reindex(table)
 
-	return tm.db.saveTable(table)
+	table.stats.recordWrite(&table.stats.deletes)
+	return tm.db.persist(table, deferred)
 }
 
 // applyDropTable applies DROP TABLE operation
 func (tm *TransactionManager) applyDropTable(tableName string) error {
+	tm.db.catalog.Lock()
+	defer tm.db.catalog.Unlock()
+
 	if _, exists := tm.db.Tables[tableName]; !exists {
 		return fmt.Errorf("table %s not found", tableName)
 	}
 
 	delete(tm.db.Tables, tableName)
-	return nil
+	return tm.db.removeTableFiles(tableName)
+}
+
+// applyRenameTable applies RENAME TABLE operation
+func (tm *TransactionManager) applyRenameTable(oldName, newName string) error {
+	tm.db.catalog.Lock()
+	defer tm.db.catalog.Unlock()
+
+	table, exists := tm.db.Tables[oldName]
+	if !exists {
+		return fmt.Errorf("table %s not found", oldName)
+	}
+	if _, exists := tm.db.Tables[newName]; exists {
+		return fmt.Errorf("table %s already exists", newName)
+	}
+
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	return tm.db.moveTable(table, newName)
+}
+
+// isActive reports whether the transaction can still be committed
+func (tx *Transaction) isActive() bool {
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+	return tx.State == TransactionActive
 }
 
 // GetActiveTransactions returns all active transactions
@@ -562,3 +562,59 @@ func TestTransactionIsolationLevels(t *testing.T) {
 		db.RollbackTransaction()
 	})
 }
+
+func TestRollbackToSavepointRestoresView(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	_ = db.CreateTable("items", []string{"id", "name"})
+	_ = db.Insert("items", []string{"1", "apple"})
+
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.InsertTx("items", []string{"2", "pear"})
+	if err := db.CreateSavepoint("a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.UpdateTx("items", 0, []string{"1", "apricot"})
+	if err := db.CreateSavepoint("b"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.DeleteTx("items", 0)
+	if got := db.SelectAll("items"); got != "id | name\n2 | pear\n" {
+		t.Fatalf("before rollback: %q", got)
+	}
+
+	// Rolling back to a reverts the transaction's view and destroys b
+	if err := db.RollbackToSavepoint("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SelectAll("items"); got != "id | name\n1 | apple\n2 | pear\n" {
+		t.Errorf("after rollback to a: %q", got)
+	}
+	if got := db.SelectCount("items", nil); got != "count\n2\n" {
+		t.Errorf("count after rollback to a: %q", got)
+	}
+	if err := db.RollbackToSavepoint("b"); err == nil {
+		t.Error("savepoint b survived rollback to an earlier savepoint")
+	}
+
+	// a stays usable, and reusing a name moves the savepoint
+	_ = db.InsertTx("items", []string{"3", "plum"})
+	if err := db.RollbackToSavepoint("a"); err != nil {
+		t.Fatalf("second rollback to a: %v", err)
+	}
+	_ = db.InsertTx("items", []string{"4", "fig"})
+	if err := db.CreateSavepoint("a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.InsertTx("items", []string{"5", "kiwi"})
+	if err := db.RollbackToSavepoint("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SelectAll("items"); got != "id | name\n1 | apple\n2 | pear\n4 | fig\n" {
+		t.Errorf("after commit: %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+// internal/storage/types.go
+//
+// Column types. A column may be declared with a type, as in
+// CREATE TABLE users (id INT, name TEXT, active BOOL); inserts and updates
+// then reject values that are not of that type and store the others in a
+// canonical form, so 007 is stored as 7 and TRUE as true. Columns declared
+// without a type accept any value, as every column did before types.
+package storage
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the declared type of a column; "" for an untyped column
+type ColumnType string
+
+// Column types
+const (
+	TypeInt   ColumnType = "INT"
+	TypeFloat ColumnType = "FLOAT"
+	TypeText  ColumnType = "TEXT"
+	TypeBool  ColumnType = "BOOL"
+	// TypePoint holds x, y coordinates (see geo.go)
+	TypePoint ColumnType = "POINT"
+	// TypeDate and TypeTimestamp hold points in time, stored as 2025-01-15
+	// and as created_at values are, so that they sort as text in time order
+	TypeDate      ColumnType = "DATE"
+	TypeTimestamp ColumnType = "TIMESTAMP"
+)
+
+// typeAliases maps every accepted type name to its column type
+var typeAliases = map[string]ColumnType{
+	"INT": TypeInt, "INTEGER": TypeInt, "BIGINT": TypeInt,
+	"FLOAT": TypeFloat, "REAL": TypeFloat, "DOUBLE": TypeFloat,
+	"TEXT": TypeText, "VARCHAR": TypeText, "STRING": TypeText,
+	"BOOL": TypeBool, "BOOLEAN": TypeBool,
+	"POINT": TypePoint,
+	"DATE":  TypeDate, "TIMESTAMP": TypeTimestamp, "DATETIME": TypeTimestamp, "TIMESTAMPTZ": TypeTimestamp,
+}
+
+// ParseColumnType parses a type name such as INT or BOOLEAN
+func ParseColumnType(name string) (ColumnType, error) {
+	if t, ok := typeAliases[strings.ToUpper(name)]; ok {
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown column type %s (use INT, FLOAT, TEXT, BOOL, POINT, DATE or TIMESTAMP)", name)
+}
+
+// Normalize returns value in the canonical form of the type, or false when
+// it is not a value of the type. NULL is a value of every type.
+func (ct ColumnType) Normalize(value string) (string, bool) {
+	if IsNull(value) {
+		return value, true
+	}
+	switch ct {
+	case TypeInt:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatInt(n, 10), true
+	case TypeFloat:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	case TypeBool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "t", "1":
+			return "true", true
+		case "false", "f", "0":
+			return "false", true
+		}
+		return "", false
+	case TypePoint:
+		x, y, ok := ParsePoint(value)
+		if !ok {
+			return "", false
+		}
+		return formatPoint(x, y), true
+	case TypeDate, TypeTimestamp:
+		t, ok := ParseTime(value)
+		if !ok {
+			return "", false
+		}
+		if ct == TypeDate {
+			return t.Format(DateFormat), true
+		}
+		return FormatTimestamp(t), true
+	}
+	return value, true
+}
+
+// Temporal reports whether values of the type are points in time
+func (ct ColumnType) Temporal() bool {
+	return ct == TypeDate || ct == TypeTimestamp
+}
+
+// ComparisonValue returns a value compared with a column of the type in the
+// type's canonical form when the type is DATE or TIMESTAMP, which sorts as
+// text in time order. It returns false for a DATE compared with a time of
+// day, which must be compared as a time instead; values that are not times
+// are left to compare as text.
+func (ct ColumnType) ComparisonValue(value string) (string, bool) {
+	if !ct.Temporal() || IsNull(value) {
+		return value, true
+	}
+	if t, ok := ParseTime(value); ok && ct == TypeDate && !t.Equal(t.Truncate(24*time.Hour)) {
+		return value, false
+	}
+	if v, ok := ct.Normalize(value); ok {
+		return v, true
+	}
+	return value, true
+}
+
+// typeNames returns the persisted form of a table's column types
+func typeNames(types map[string]ColumnType) map[string]string {
+	if len(types) == 0 {
+		return nil
+	}
+	names := make(map[string]string, len(types))
+	for col, t := range types {
+		names[col] = string(t)
+	}
+	return names
+}
+
+// Type returns the declared type of a column, "" when it has none
+func (t *Table) Type(column string) ColumnType {
+	return t.Types[column]
+}
+
+// columnTypes returns the types of the columns in order, or nil when the
+// table has none
+func (t *Table) columnTypes() []ColumnType {
+	if len(t.Types) == 0 {
+		return nil
+	}
+	types := make([]ColumnType, len(t.Columns))
+	for i, col := range t.Columns {
+		types[i] = t.Types[col]
+	}
+	return types
+}
+
+// typedRow checks values against the column types, returning them in
+// canonical form, or the error to fail the write with. Replay checks the
+// rows it reads back from the WAL the same way, so a record damaged on disk
+// cannot put a value of the wrong type into a typed column.
+func (t *Table) typedRow(values []string) ([]string, string) {
+	if len(t.Types) == 0 {
+		return values, ""
+	}
+	var typed []string
+	for i, col := range t.Columns {
+		ct := t.Types[col]
+		if ct == "" || i >= len(values) {
+			continue
+		}
+		v, ok := ct.Normalize(values[i])
+		if !ok {
+			return nil, fmt.Sprintf("Error: value %s does not match type %s of column %s", quoteString(values[i]), ct, col)
+		}
+		if v != values[i] {
+			if typed == nil {
+				typed = append([]string(nil), values...)
+			}
+			typed[i] = v
+		}
+	}
+	if typed != nil {
+		return typed, ""
+	}
+	return values, ""
+}
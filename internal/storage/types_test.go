@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypedColumns(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	if msg := db.CreateTable("users", []string{"id INT", "name TEXT COLLATE NOCASE", "score FLOAT", "active BOOL", "note"}); strings.HasPrefix(msg, "Error") {
+		t.Fatal(msg)
+	}
+
+	// Valid values are stored in canonical form
+	if msg := db.Insert("users", []string{"007", "Ann", "1.50", "TRUE", "anything"}); msg != "1 row inserted with secure page-based storage" {
+		t.Fatalf("insert: %s", msg)
+	}
+	if msg := db.Insert("users", []string{"8", "Bob", NullValue, "f", NullValue}); strings.HasPrefix(msg, "Error") {
+		t.Fatalf("insert with NULLs: %s", msg)
+	}
+	for _, tt := range []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"x", "a", "1", "true", ""}, "Error: value 'x' does not match type INT of column id"},
+		{[]string{"1.5", "a", "1", "true", ""}, "Error: value '1.5' does not match type INT of column id"},
+		{[]string{"1", "a", "abc", "true", ""}, "Error: value 'abc' does not match type FLOAT of column score"},
+		{[]string{"1", "a", "1", "maybe", ""}, "Error: value 'maybe' does not match type BOOL of column active"},
+	} {
+		if got := db.Insert("users", tt.values); got != tt.want {
+			t.Errorf("Insert(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+	if got := db.Update("users", 0, []string{"7", "Ann", "2", "yes", ""}); got != "Error: value 'yes' does not match type BOOL of column active" {
+		t.Errorf("update with a bad value: %q", got)
+	}
+	if got := db.Update("users", 0, []string{"7", "Ann", "2e1", "0", ""}); got != "1 row updated" {
+		t.Errorf("update: %q", got)
+	}
+
+	check := func(db *Database) {
+		t.Helper()
+		if got := db.SelectWhere("users", "name", "ann"); got != "id | name | score | active | note\n7 | Ann | 20 | false | \n" {
+			t.Errorf("typed row: %q", got)
+		}
+		if got := db.DescribeTable("users"); !strings.Contains(got, "id | column | INT | yes |  | BINARY") || !strings.Contains(got, "note | column |  | yes |  | BINARY") {
+			t.Errorf("DESCRIBE: %s", got)
+		}
+	}
+	check(db)
+	db.Close()
+
+	// Types survive a restart, from the table file or the WAL
+	db = NewDatabase(dir)
+	defer db.Close()
+	check(db)
+	if got := db.Insert("users", []string{"x", "a", "1", "true", ""}); !strings.HasPrefix(got, "Error: value 'x'") {
+		t.Errorf("types lost after restart: %q", got)
+	}
+}
+
+func TestParseColumnSpecsTypes(t *testing.T) {
+	for _, tt := range []struct {
+		spec, err string
+	}{
+		{"id INTEGER", ""},
+		{"ok BOOLEAN", ""},
+		{`"Full Name" VARCHAR COLLATE NOCASE`, ""},
+		{"id BLOB", "unknown column type BLOB (use INT, FLOAT, TEXT, BOOL, POINT, DATE or TIMESTAMP)"},
+		{"id INT COLLATE NOCASE", "COLLATE applies only to TEXT columns, not id INT"},
+		{"id INT TEXT", `invalid column definition "id INT TEXT" (expected: name [type] [NOT NULL] [DEFAULT value] [COLLATE collation])`},
+	} {
+		_, err := parseColumnSpecs([]string{tt.spec})
+		if got := ""; err != nil {
+			got = err.Error()
+			if got != tt.err {
+				t.Errorf("%s: got error %q, want %q", tt.spec, got, tt.err)
+			}
+		} else if tt.err != "" {
+			t.Errorf("%s: expected error %q", tt.spec, tt.err)
+		}
+	}
+}
+
+func TestTemporalTypes(t *testing.T) {
+	for _, tt := range []struct {
+		ct          ColumnType
+		value, want string
+		ok          bool
+	}{
+		{TypeDate, "2025-01-15", "2025-01-15", true},
+		{TypeDate, "2025-01-15T23:30:00Z", "2025-01-15", true},
+		{TypeDate, "15/01/2025", "", false},
+		{TypeTimestamp, "2025-01-15", "2025-01-15T00:00:00.000000Z", true},
+		{TypeTimestamp, "2025-01-15 09:30:00", "2025-01-15T09:30:00.000000Z", true},
+		{TypeTimestamp, "2025-01-15T10:30:00.5+01:00", "2025-01-15T09:30:00.500000Z", true},
+		{TypeTimestamp, "yesterday", "", false},
+		{TypeTimestamp, NullValue, NullValue, true},
+	} {
+		if got, ok := tt.ct.Normalize(tt.value); got != tt.want || ok != tt.ok {
+			t.Errorf("%s %q: %q, %v", tt.ct, tt.value, got, ok)
+		}
+	}
+	for name, want := range map[string]ColumnType{"date": TypeDate, "DATETIME": TypeTimestamp, "timestamptz": TypeTimestamp} {
+		if got, err := ParseColumnType(name); err != nil || got != want {
+			t.Errorf("ParseColumnType(%s) = %s, %v", name, got, err)
+		}
+	}
+}
+
+func TestSelectWhereComparesTypes(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	if msg := db.CreateTable("events", []string{"id", "day DATE", "at TIMESTAMP"}); strings.HasPrefix(msg, "Error") {
+		t.Fatal(msg)
+	}
+	_ = db.Insert("events", []string{"1", "2025-01-15", "2025-01-15 09:30:00"})
+	_ = db.Insert("events", []string{"2", "2025-01-16", "2025-01-16 09:30:00"})
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			db.CreateIndex("events", "day")
+			db.CreateIndex("events", "at")
+		}
+		for _, tt := range []struct{ column, value, want string }{
+			{"at", "2025-01-15T10:30:00+01:00", "1"},
+			{"at", "2025-01-15", ""},
+			{"day", "2025-01-16T00:00:00Z", "2"},
+			{"day", "2025-01-15T09:30:00Z", ""},
+		} {
+			want := "id | day | at\n(no rows)\n"
+			if tt.want != "" {
+				want = "id | day | at\n" + tt.want
+			}
+			if got := db.SelectWhere("events", tt.column, tt.value); !strings.HasPrefix(got, want) {
+				t.Errorf("indexed=%v %s = %s: %q", indexed, tt.column, tt.value, got)
+			}
+		}
+	}
+}
+
+func TestReplayChecksTypes(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("flags", []string{"id INT", `"on" BOOL`})
+	_ = db.Insert("flags", []string{"1", "true"})
+	id := db.Tables["flags"].RowIDs[0]
+
+	// Records that bypassed the checks of a write, as a damaged WAL could
+	// hold: values that are not of the column type are skipped, the others
+	// are stored in canonical form
+	for _, rec := range []struct {
+		typ  WALEntryType
+		data map[string]interface{}
+	}{
+		{WAL_INSERT, map[string]interface{}{"values": []string{"x", "true"}, "row_id": 50}},
+		{WAL_INSERT, map[string]interface{}{"values": []string{"007", "T"}, "row_id": 51}},
+		{WAL_UPDATE, map[string]interface{}{"values": []string{"1", "maybe"}, "row_id": id}},
+	} {
+		if err := db.WAL.WriteEntry(rec.typ, "flags", rec.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Crash and recover from the WAL
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.SelectAll("flags"); got != "id | on\n1 | true\n7 | true\n" {
+		t.Errorf("rows after replay: %q", got)
+	}
+}
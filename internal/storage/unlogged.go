@@ -0,0 +1,112 @@
+// internal/storage/unlogged.go
+//
+// Unlogged tables are for high-churn data that can be rebuilt, such as
+// caches and telemetry. Their writes skip the WAL and are saved without
+// fsync, so they are much cheaper, but a crash may lose recent writes or,
+// if the table file was only partly written, the whole table. Creating,
+// dropping and renaming unlogged tables is still logged, and other tables
+// stay fully durable.
+package storage
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// CreateUnloggedTable creates a table whose writes skip the WAL
+func (db *Database) CreateUnloggedTable(name string, columns []string) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+	opts := db.DefaultTableOptions()
+	opts.Unlogged = true
+	return db.createTable(name, columns, opts)
+}
+
+// CreateUnloggedTableTx creates an unlogged table within a transaction
+func (db *Database) CreateUnloggedTableTx(name string, columns []string) string {
+	opts := db.DefaultTableOptions()
+	opts.Unlogged = true
+	return db.CreateTableWithOptionsTx(name, columns, opts)
+}
+
+// SetLogged switches a table between logged and unlogged. A table becoming
+// logged has its rows written to the WAL, so they are durable from then on.
+func (db *Database) SetLogged(tableName string, logged bool) string {
+	db.writeGate.RLock()
+	defer db.writeGate.RUnlock()
+
+	tableName = strings.ToLower(tableName)
+	table, exists := db.lookupTable(tableName)
+	if !exists {
+		return fmt.Sprintf(ErrTableNotFound, tableName)
+	}
+	if table.External != nil {
+		return fmt.Sprintf(ErrExternalReadOnly, tableName)
+	}
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	kind := "logged"
+	if !logged {
+		kind = "unlogged"
+	}
+	if table.Unlogged != logged {
+		return fmt.Sprintf("Table %s is already %s", tableName, kind)
+	}
+
+	// Write to WAL first. A table becoming logged logs all of its rows,
+	// since none of its earlier writes are in the WAL.
+	if db.WAL != nil {
+		data := map[string]interface{}{"logged": logged}
+		if logged {
+			data["rows"] = table.Rows
+			data["row_ids"] = table.RowIDs
+		}
+		if err := db.WAL.WriteEntry(WAL_SET_LOGGED, tableName, data); err != nil {
+			return fmt.Sprintf("Failed to write to WAL: %v", err)
+		}
+	}
+
+	table.Unlogged = !logged
+	if err := db.writeTable(table, true); err != nil {
+		table.Unlogged = logged
+		return fmt.Sprintf("Error: failed to persist table %s: %v", tableName, err)
+	}
+	return fmt.Sprintf("Table %s set %s", tableName, kind)
+}
+
+// walFor returns the WAL that writes to table go through, nil for unlogged
+// tables or when the WAL is unavailable
+func (db *Database) walFor(table *Table) *WALManager {
+	if table.Unlogged {
+		return nil
+	}
+	return db.WAL
+}
+
+// unloggedTables returns the unlogged tables loaded from disk
+func (db *Database) unloggedTables() map[string]*Table {
+	tables := make(map[string]*Table)
+	for name, table := range db.Tables {
+		if table.Unlogged {
+			tables[name] = table
+		}
+	}
+	return tables
+}
+
+// restoreUnloggedTables puts back the unlogged tables loaded before WAL
+// replay. Their writes are not in the WAL, so replay leaves them empty or
+// stale; the table file is their only copy.
+func (db *Database) restoreUnloggedTables(loaded map[string]*Table) {
+	for name, table := range loaded {
+		if current, ok := db.Tables[name]; !ok || !current.Unlogged {
+			continue
+		}
+		db.addTable(table)
+		if err := db.saveTable(table); err != nil {
+			log.Printf("Warning: failed to restore unlogged table %s: %v\n", name, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnloggedTableSkipsWAL(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("orders", []string{"id"})
+	if msg := db.CreateUnloggedTable("cache", []string{"key", "value"}); msg != "Unlogged table cache created" {
+		t.Fatalf("create: %s", msg)
+	}
+
+	walSize := func() int64 {
+		info, err := os.Stat(filepath.Join(dir, "wal.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	before := walSize()
+	_ = db.Insert("cache", []string{"a", "1"})
+	_ = db.Update("cache", 0, []string{"a", "2"})
+	_ = db.Insert("cache", []string{"b", "3"})
+	_ = db.Delete("cache", 1)
+	if after := walSize(); after != before {
+		t.Errorf("unlogged writes grew the WAL from %d to %d bytes", before, after)
+	}
+	_ = db.Insert("orders", []string{"1"})
+	if walSize() == before {
+		t.Error("logged table write did not reach the WAL")
+	}
+
+	// Rows and the unlogged flag survive a clean restart
+	_ = db.Close()
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.SelectAll("cache"); got != "key | value\na | 2\n" {
+		t.Errorf("after restart: %q", got)
+	}
+	if got := db.ShowCreateTable("cache"); !strings.Contains(got, "CREATE UNLOGGED TABLE cache (key, value)") {
+		t.Errorf("SHOW CREATE TABLE: %s", got)
+	}
+	if got := db.DescribeTable("cache"); !strings.Contains(got, "cache | unlogged table") {
+		t.Errorf("DESCRIBE: %s", got)
+	}
+}
+
+func TestSetLogged(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateTable("metrics", []string{"name"})
+
+	if msg := db.SetLogged("metrics", true); msg != "Table metrics is already logged" {
+		t.Errorf("already logged: %s", msg)
+	}
+	if msg := db.SetLogged("metrics", false); msg != "Table metrics set unlogged" {
+		t.Fatalf("set unlogged: %s", msg)
+	}
+	info, _ := os.Stat(filepath.Join(dir, "wal.log"))
+	_ = db.Insert("metrics", []string{"cpu"})
+	if after, _ := os.Stat(filepath.Join(dir, "wal.log")); after.Size() != info.Size() {
+		t.Error("write to unlogged table reached the WAL")
+	}
+	if msg := db.SetLogged("metrics", true); msg != "Table metrics set logged" {
+		t.Fatalf("set logged: %s", msg)
+	}
+	if msg := db.SetLogged("missing", true); msg != "Table missing not found" {
+		t.Errorf("missing table: %s", msg)
+	}
+
+	_ = db.Close()
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.ShowCreateTable("metrics"); !strings.Contains(got, "CREATE TABLE metrics (name)") {
+		t.Errorf("flag not persisted: %s", got)
+	}
+}
+
+func TestUnloggedTableInTransaction(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	if _, err := db.BeginTransaction(ReadCommitted); err != nil {
+		t.Fatal(err)
+	}
+	if msg := db.CreateUnloggedTableTx("sessions", []string{"id"}); !strings.Contains(msg, "queued") {
+		t.Fatalf("create in transaction: %s", msg)
+	}
+	if err := db.CommitTransaction(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.DescribeTable("sessions"); !strings.Contains(got, "sessions | unlogged table") {
+		t.Errorf("DESCRIBE after commit: %s", got)
+	}
+}
+
+func TestSetLoggedSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	db := NewDatabase(dir)
+	_ = db.CreateUnloggedTable("events", []string{"id"})
+	_ = db.Insert("events", []string{"1"})
+	_ = db.SetLogged("events", true)
+	_ = db.Insert("events", []string{"2"})
+	_ = db.CreateTable("audit", []string{"id"})
+	_ = db.SetLogged("audit", false)
+	_ = db.Insert("audit", []string{"a"})
+	_ = db.Close()
+
+	// Replay sees only some of each table's writes in the WAL
+	db = NewDatabase(dir)
+	defer db.Close()
+	if got := db.SelectAll("events"); got != "id\n1\n2\n" {
+		t.Errorf("events after restart: %q", got)
+	}
+	if got := db.SelectAll("audit"); got != "id\na\n" {
+		t.Errorf("audit after restart: %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+// internal/storage/version.go
+//
+// Versioned tables, for optimistic concurrency without long transactions. A
+// table created WITH (versioned=true) gets a last column, _version, that
+// starts at 1 and goes up by one with every update of the row. A client reads
+// a row with its version and updates it only if the version is unchanged:
+//
+//	UPDATE accounts SET balance = '90' ROWID 7 WHERE _version = 5
+//
+// If someone else updated the row in between, the update fails with a
+// version conflict instead of silently overwriting their change.
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// VersionColumn is the column a versioned table keeps row versions in
+const VersionColumn = "_version"
+
+// ErrVersionConflict is returned for an update of a row that has changed
+const ErrVersionConflict = "Error: version conflict on row ID %d of %s: the row is at version %d, not %d"
+
+// ErrVersionInvalid rejects a _version that is not a positive integer
+const ErrVersionInvalid = "Error: " + VersionColumn + " must be a positive integer"
+
+// versionedColumns returns the column specs of a new table, with _version
+// added when it is versioned
+func versionedColumns(columns []string, versioned bool) ([]string, error) {
+	if !versioned {
+		return columns, nil
+	}
+	defs, err := parseColumnSpecs(columns)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(defs.names, VersionColumn) {
+		return nil, fmt.Errorf("%s is added by versioned=true; leave it out of the column list", VersionColumn)
+	}
+	return append(slices.Clone(columns), VersionColumn), nil
+}
+
+// versionIndex returns the position of the _version column, or -1 when the
+// table is not versioned
+func (t *Table) versionIndex() int {
+	if !t.Versioned {
+		return -1
+	}
+	return len(t.Columns) - 1
+}
+
+// IsVersioned reports whether a table keeps row versions
+func (db *Database) IsVersioned(tableName string) bool {
+	table, exists := db.lookupTable(tableName)
+	return exists && table.Versioned
+}
+
+// versionedRow completes the values of a row inserted into a versioned
+// table, which may leave out _version to start it at 1
+func (t *Table) versionedRow(values []string) ([]string, string) {
+	vi := t.versionIndex()
+	switch {
+	case vi < 0:
+		return values, ""
+	case len(values) == vi:
+		return append(slices.Clone(values), "1"), ""
+	case len(values) == len(t.Columns):
+		if v, err := strconv.ParseInt(values[vi], 10, 64); err != nil || v < 1 {
+			return nil, ErrVersionInvalid
+		}
+	}
+	return values, ""
+}
+
+// nextVersion returns the values of an update of row id, currently holding
+// current, with _version advanced. The update was built from a read of the
+// row at the version in values, and must also be at expected unless it is
+// 0; when the row has moved on since, it fails with a version conflict.
+func (t *Table) nextVersion(id int64, current, values []string, expected int64) ([]string, string) {
+	vi := t.versionIndex()
+	if vi < 0 {
+		if expected != 0 {
+			return nil, fmt.Sprintf("Error: table %s is not versioned", t.Name)
+		}
+		return values, ""
+	}
+	if len(values) != len(t.Columns) {
+		return values, ""
+	}
+	version := t.rowVersion(current)
+	read, err := strconv.ParseInt(values[vi], 10, 64)
+	if err != nil {
+		return nil, ErrVersionInvalid
+	}
+	if expected == 0 {
+		expected = read
+	}
+	if version != expected || version != read {
+		return nil, fmt.Sprintf(ErrVersionConflict, id, t.Name, version, expected)
+	}
+	next := slices.Clone(values)
+	next[vi] = strconv.FormatInt(version+1, 10)
+	return next, ""
+}
+
+// rowVersion returns the version of a row of a versioned table
+func (t *Table) rowVersion(row []string) int64 {
+	version, _ := strconv.ParseInt(row[t.versionIndex()], 10, 64)
+	return version
+}
+
+// checkVersion fails a queued update of a versioned table, applied at
+// commit, when the row is no longer at the version the transaction saw
+func (t *Table) checkVersion(rowIndex int, expected int64) error {
+	if !t.Versioned || expected == 0 {
+		return nil
+	}
+	if t.rowVersion(t.Rows[rowIndex]) != expected {
+		return fmt.Errorf("could not serialize access: row ID %d of table %s was changed by another transaction", t.RowIDs[rowIndex], t.Name)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package storage
+
+import "testing"
+
+func TestNextVersion(t *testing.T) {
+	table := &Table{Name: "t", Columns: []string{"id", VersionColumn}, Versioned: true}
+	current := []string{"1", "4"}
+	for _, tc := range []struct {
+		values   []string
+		expected int64
+		want     string
+		msg      string
+	}{
+		{[]string{"2", "4"}, 0, "5", ""},
+		{[]string{"2", "4"}, 4, "5", ""},
+		{[]string{"2", "4"}, 3, "", "Error: version conflict on row ID 7 of t: the row is at version 4, not 3"},
+		// Built from a read at version 3
+		{[]string{"2", "3"}, 0, "", "Error: version conflict on row ID 7 of t: the row is at version 4, not 3"},
+	} {
+		got, msg := table.nextVersion(7, current, tc.values, tc.expected)
+		if msg != tc.msg || (msg == "" && got[1] != tc.want) {
+			t.Errorf("nextVersion(%v, %d) = %v %q", tc.values, tc.expected, got, msg)
+		}
+	}
+	if row, msg := table.versionedRow([]string{"1"}); msg != "" || len(row) != 2 || row[1] != "1" {
+		t.Errorf("versionedRow: %v %q", row, msg)
+	}
+	if _, msg := table.versionedRow([]string{"1", "x"}); msg == "" {
+		t.Error("versionedRow accepted a non-numeric version")
+	}
+}
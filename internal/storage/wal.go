@@ -6,6 +6,8 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -27,6 +29,13 @@ const (
 	WAL_ROLLBACK_TRANSACTION
 	WAL_SAVEPOINT
 	WAL_ROLLBACK_TO_SAVEPOINT
+	WAL_RENAME_TABLE
+	WAL_COMMENT
+	WAL_SET_LOGGED
+	WAL_BATCH // same layout as a commit record; replayed like one
+	WAL_SET_MASK
+	WAL_SET_RETENTION
+	WAL_PURGE
 )
 
 // WALEntry represents a single entry in the WAL
@@ -35,6 +44,9 @@ type WALEntry struct {
 	Type      WALEntryType `json:"type"`
 	TableName string       `json:"table_name"`
 	Data      interface{}  `json:"data"`
+	// LSN numbers entries in the order they were logged; it keeps increasing
+	// across WAL truncations. Entries written before LSNs existed have none.
+	LSN uint64 `json:"lsn,omitempty"`
 }
 
 // WALManager handles Write-Ahead Logging
@@ -44,6 +56,8 @@ type WALManager struct {
 	walPath    string
 	mu         sync.Mutex
 	checkpoint time.Time
+	// lsn is the LSN of the last entry logged
+	lsn uint64
 }
 
 // NewWALManager creates a new WAL manager
@@ -79,41 +93,16 @@ func (wm *WALManager) Close() error {
 
 // WriteEntry writes an entry to the WAL
 func (wm *WALManager) WriteEntry(entryType WALEntryType, tableName string, data interface{}) error {
+	_, err := wm.AppendEntry(entryType, tableName, data)
+	return err
+}
+
+// AppendEntry writes an entry to the WAL and returns its LSN. The entry is
+// synced to disk before AppendEntry returns.
+func (wm *WALManager) AppendEntry(entryType WALEntryType, tableName string, data interface{}) (uint64, error) {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
-
-	entry := WALEntry{
-		Timestamp: time.Now(),
-		Type:      entryType,
-		TableName: tableName,
-		Data:      data,
-	}
-
-	// Serialize entry to JSON
-	jsonData, err := json.Marshal(entry)
-	if err != nil {
-		return fmt.Errorf("failed to marshal WAL entry: %w", err)
-	}
-
-	// Write entry length (4 bytes) + entry data
-	length := uint32(len(jsonData))
-
-	// Write length
-	if err := binary.Write(wm.walFile, binary.LittleEndian, length); err != nil {
-		return fmt.Errorf("failed to write WAL entry length: %w", err)
-	}
-
-	// Write data
-	if _, err := wm.walFile.Write(jsonData); err != nil {
-		return fmt.Errorf("failed to write WAL entry data: %w", err)
-	}
-
-	// Flush to ensure data is written to disk
-	if err := wm.walFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync WAL file: %w", err)
-	}
-
-	return nil
+	return wm.appendLocked(entryType, tableName, data)
 }
 
 // WriteCheckpoint writes a checkpoint entry
@@ -122,18 +111,42 @@ func (wm *WALManager) WriteCheckpoint() error {
 	defer wm.mu.Unlock()
 
 	wm.checkpoint = time.Now()
+	_, err := wm.appendLocked(WAL_CHECKPOINT, "", nil)
+	return err
+}
+
+// LastLSN returns the LSN of the last entry logged. Every entry up to it is
+// on disk.
+func (wm *WALManager) LastLSN() uint64 {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	return wm.lsn
+}
+
+// advanceLSN makes later entries number after lsn
+func (wm *WALManager) advanceLSN(lsn uint64) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	if lsn > wm.lsn {
+		wm.lsn = lsn
+	}
+}
 
+// appendLocked assigns the next LSN to an entry, writes it and syncs the
+// file. The caller holds wm.mu.
+func (wm *WALManager) appendLocked(entryType WALEntryType, tableName string, data interface{}) (uint64, error) {
 	entry := WALEntry{
 		Timestamp: time.Now(),
-		Type:      WAL_CHECKPOINT,
-		TableName: "",
-		Data:      nil,
+		Type:      entryType,
+		TableName: tableName,
+		Data:      data,
+		LSN:       wm.lsn + 1,
 	}
 
 	// Serialize entry to JSON
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal WAL entry: %w", err)
+		return 0, fmt.Errorf("failed to marshal WAL entry: %w", err)
 	}
 
 	// Write entry length (4 bytes) + entry data
@@ -141,23 +154,27 @@ func (wm *WALManager) WriteCheckpoint() error {
 
 	// Write length
 	if err := binary.Write(wm.walFile, binary.LittleEndian, length); err != nil {
-		return fmt.Errorf("failed to write WAL entry length: %w", err)
+		return 0, fmt.Errorf("failed to write WAL entry length: %w", err)
 	}
 
 	// Write data
 	if _, err := wm.walFile.Write(jsonData); err != nil {
-		return fmt.Errorf("failed to write WAL entry data: %w", err)
+		return 0, fmt.Errorf("failed to write WAL entry data: %w", err)
 	}
 
 	// Flush to ensure data is written to disk
 	if err := wm.walFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync WAL file: %w", err)
+		return 0, fmt.Errorf("failed to sync WAL file: %w", err)
 	}
 
-	return nil
+	wm.lsn = entry.LSN
+	return entry.LSN, nil
 }
 
-// ReplayWAL replays WAL entries since last checkpoint
+// ReplayWAL replays WAL entries since last checkpoint. Transactional writes
+// are only logged in commit records, so a transaction still in flight at a
+// crash has nothing to replay; those are counted as discarded. A record cut
+// short by the crash is dropped along with the rest of the file.
 func (wm *WALManager) ReplayWAL(db *Database) error {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
@@ -179,28 +196,51 @@ func (wm *WALManager) ReplayWAL(db *Database) error {
 	defer walFile.Close()
 
 	reader := bufio.NewReader(walFile)
-
+	// inFlight holds the transactions begun but not yet committed or
+	// rolled back
+	inFlight := make(map[string]bool)
+	var offset int64
 	for {
 		// Read entry length
 		var length uint32
 		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
-			if err.Error() == "EOF" {
+			if err == io.EOF {
 				break // End of file
 			}
+			if err == io.ErrUnexpectedEOF {
+				wm.dropTornTail(offset)
+				break
+			}
 			return fmt.Errorf("failed to read WAL entry length: %w", err)
 		}
 
 		// Read entry data
 		jsonData := make([]byte, length)
-		if _, err := reader.Read(jsonData); err != nil {
+		if _, err := io.ReadFull(reader, jsonData); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				wm.dropTornTail(offset)
+				break
+			}
 			return fmt.Errorf("failed to read WAL entry data: %w", err)
 		}
+		offset += 4 + int64(length)
 
 		// Deserialize entry
 		var entry WALEntry
 		if err := json.Unmarshal(jsonData, &entry); err != nil {
 			return fmt.Errorf("failed to unmarshal WAL entry: %w", err)
 		}
+		if entry.LSN > wm.lsn {
+			wm.lsn = entry.LSN
+		}
+		if id := entryTransactionID(&entry); id != "" {
+			switch entry.Type {
+			case WAL_BEGIN_TRANSACTION:
+				inFlight[id] = true
+			case WAL_COMMIT_TRANSACTION, WAL_ROLLBACK_TRANSACTION:
+				delete(inFlight, id)
+			}
+		}
 
 		// For now, replay all entries (we'll optimize this later)
 		// Skip entries before checkpoint (but always process CHECKPOINT entries)
@@ -212,6 +252,12 @@ func (wm *WALManager) ReplayWAL(db *Database) error {
 		if err := wm.replayEntry(db, &entry); err != nil {
 			return fmt.Errorf("failed to replay WAL entry: %w", err)
 		}
+		db.logReplayedChanges(&entry)
+		db.recovery.WALRecords.Add(1)
+	}
+	if len(inFlight) > 0 {
+		log.Printf("Discarded %d transactions in flight at shutdown\n", len(inFlight))
+		db.recovery.Discarded.Add(int64(len(inFlight)))
 	}
 
 	// Reopen WAL file for writing
@@ -223,20 +269,58 @@ func (wm *WALManager) ReplayWAL(db *Database) error {
 	return nil
 }
 
+// entryTransactionID returns the transaction a WAL entry belongs to, or ""
+func entryTransactionID(entry *WALEntry) string {
+	data, ok := entry.Data.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := data["transaction_id"].(string)
+	return id
+}
+
+// dropTornTail cuts the WAL file at offset, the end of its last complete
+// entry, so that entries appended later are not written after the partial
+// one a crash left behind. The caller holds wm.mu.
+func (wm *WALManager) dropTornTail(offset int64) {
+	log.Printf("Warning: discarding incomplete WAL entry at offset %d\n", offset)
+	if err := os.Truncate(wm.walPath, offset); err != nil {
+		log.Printf("Warning: failed to truncate WAL file: %v\n", err)
+	}
+}
+
 // replayEntry replays a single WAL entry
 func (wm *WALManager) replayEntry(db *Database, entry *WALEntry) error {
 	switch entry.Type {
 	case WAL_CREATE_TABLE:
+		// A table loaded from its file keeps its rows, indexes and statistics,
+		// and one whose file could not be decrypted is left alone
+		if _, exists := db.Tables[entry.TableName]; exists || db.unreadableTable(entry.TableName) != "" {
+			break
+		}
 		if data, ok := entry.Data.(map[string]interface{}); ok {
 			if columns, ok := data["columns"].([]interface{}); ok {
 				colStrs := make([]string, len(columns))
 				for i, col := range columns {
 					colStrs[i] = col.(string)
 				}
-				db.Tables[entry.TableName] = &Table{
-					Name:    entry.TableName,
-					Columns: colStrs,
-					Rows:    [][]string{},
+				defs, err := parseColumnSpecs(colStrs)
+				if err != nil {
+					return err
+				}
+				// A referenced table dropped later leaves the foreign key
+				// as it was written
+				_ = defs.resolveForeignKeys(entry.TableName, db.catalogTable)
+				db.addTable(&Table{Name: entry.TableName, Rows: [][]string{}})
+				defs.apply(db.Tables[entry.TableName])
+				db.Tables[entry.TableName].Unlogged, _ = data["unlogged"].(bool)
+				db.Tables[entry.TableName].Versioned, _ = data["versioned"].(bool)
+				db.Tables[entry.TableName].Timestamps, _ = data["timestamps"].(bool)
+				keyID, _ := data["key_id"].(string)
+				db.Tables[entry.TableName].KeyID = db.localKeyID(keyID)
+				if location, ok := data["location"].(string); ok {
+					header, _ := data["header"].(bool)
+					db.Tables[entry.TableName].External = &ExternalSource{Path: location, Header: header}
 				}
 				_ = db.saveTable(db.Tables[entry.TableName])
 			}
@@ -249,24 +333,42 @@ func (wm *WALManager) replayEntry(db *Database, entry *WALEntry) error {
 				for i, val := range values {
 					valStrs[i] = val.(string)
 				}
-				if table, exists := db.Tables[entry.TableName]; exists {
-					table.Rows = append(table.Rows, valStrs)
-					_ = db.saveTable(table)
+				table, exists := db.Tables[entry.TableName]
+				if exists {
+					var msg string
+					if valStrs, msg = table.typedRow(valStrs); msg != "" {
+						log.Printf("Warning: skipping replayed %s on table %s: %s\n", entry.Type, entry.TableName, msg)
+						break
+					}
+				}
+				db.redoPageInsert(entry, valStrs)
+				if exists {
+					// A row already saved to the table file is not inserted again
+					id, _ := data["row_id"].(float64)
+					if _, saved := table.rowPosition(int64(id)); id == 0 || !saved {
+						table.appendRow(valStrs, int64(id))
+						_ = db.saveTable(table)
+					}
 				}
 			}
 		}
 
 	case WAL_UPDATE:
 		if data, ok := entry.Data.(map[string]interface{}); ok {
-			if rowIndex, ok := data["row_index"].(float64); ok {
+			if target, ok := operationTarget(data); ok {
 				if values, ok := data["values"].([]interface{}); ok {
 					valStrs := make([]string, len(values))
 					for i, val := range values {
 						valStrs[i] = val.(string)
 					}
 					if table, exists := db.Tables[entry.TableName]; exists {
-						if int(rowIndex) < len(table.Rows) {
-							table.Rows[int(rowIndex)] = valStrs
+						valStrs, msg := table.typedRow(valStrs)
+						if msg != "" {
+							log.Printf("Warning: skipping replayed %s on table %s: %s\n", entry.Type, entry.TableName, msg)
+							break
+						}
+						if rowIndex, msg := table.locate(target); msg == "" {
+							table.setRow(rowIndex, valStrs)
 							_ = db.saveTable(table)
 						}
 					}
@@ -276,11 +378,10 @@ func (wm *WALManager) replayEntry(db *Database, entry *WALEntry) error {
 
 	case WAL_DELETE:
 		if data, ok := entry.Data.(map[string]interface{}); ok {
-			if rowIndex, ok := data["row_index"].(float64); ok {
+			if target, ok := operationTarget(data); ok {
 				if table, exists := db.Tables[entry.TableName]; exists {
-					if int(rowIndex) < len(table.Rows) {
-						// Remove row at index
-						table.Rows = append(table.Rows[:int(rowIndex)], table.Rows[int(rowIndex)+1:]...)
+					if rowIndex, msg := table.locate(target); msg == "" {
+						table.removeRow(rowIndex)
 						_ = db.saveTable(table)
 					}
 				}
@@ -289,23 +390,97 @@ func (wm *WALManager) replayEntry(db *Database, entry *WALEntry) error {
 
 	case WAL_DROP_TABLE:
 		delete(db.Tables, entry.TableName)
-		os.Remove(db.tablePath(entry.TableName))
+		_ = db.removeTableFiles(entry.TableName)
+
+	case WAL_RENAME_TABLE:
+		if data, ok := entry.Data.(map[string]interface{}); ok {
+			if newName, ok := data["new_name"].(string); ok {
+				if table, exists := db.Tables[entry.TableName]; exists {
+					_ = db.moveTable(table, newName)
+				}
+			}
+		}
+
+	case WAL_COMMENT:
+		if data, ok := entry.Data.(map[string]interface{}); ok {
+			column, _ := data["column"].(string)
+			comment, _ := data["comment"].(string)
+			if table, exists := db.Tables[entry.TableName]; exists {
+				table.setComment(column, comment)
+				_ = db.saveTable(table)
+			}
+		}
+
+	case WAL_SET_LOGGED:
+		if data, ok := entry.Data.(map[string]interface{}); ok {
+			logged, _ := data["logged"].(bool)
+			if table, exists := db.Tables[entry.TableName]; exists {
+				table.Unlogged = !logged
+				if rows, ok := data["rows"].([]interface{}); ok {
+					replaced := make([][]string, len(rows))
+					for i, row := range rows {
+						replaced[i] = interfaceStrings(row)
+					}
+					var ids []int64
+					if raw, ok := data["row_ids"].([]interface{}); ok {
+						for _, id := range raw {
+							n, _ := id.(float64)
+							ids = append(ids, int64(n))
+						}
+					}
+					table.replaceRows(replaced, ids)
+					db.rebuildAllIndexes(table)
+				}
+				_ = db.saveTable(table)
+			}
+		}
+
+	case WAL_SET_MASK:
+		if data, ok := entry.Data.(map[string]interface{}); ok {
+			column, _ := data["column"].(string)
+			role, _ := data["role"].(string)
+			method, _ := data["method"].(string)
+			if table, exists := db.Tables[entry.TableName]; exists {
+				table.setMask(column, role, method)
+				_ = db.saveTable(table)
+			}
+		}
+
+	case WAL_SET_RETENTION:
+		if data, ok := entry.Data.(map[string]interface{}); ok {
+			spec, _ := data["retention"].(string)
+			r, err := ParseRetention(spec)
+			if table, exists := db.Tables[entry.TableName]; exists && err == nil {
+				table.setRetention(r, entry.Timestamp)
+				_ = db.saveTable(table)
+			}
+		}
+
+	case WAL_PURGE:
+		if data, ok := entry.Data.(map[string]interface{}); ok {
+			raw, _ := data["row_ids"].([]interface{})
+			ids := make([]int64, 0, len(raw))
+			for _, id := range raw {
+				if f, ok := id.(float64); ok {
+					ids = append(ids, int64(f))
+				}
+			}
+			if table, exists := db.Tables[entry.TableName]; exists {
+				table.purgeRows(ids)
+				_ = db.saveTable(table)
+			}
+		}
+
+	case WAL_COMMIT_TRANSACTION, WAL_BATCH:
+		return wm.replayCommit(db, entry)
 
 	case WAL_CHECKPOINT:
 		// Update checkpoint time
 		wm.checkpoint = entry.Timestamp
 
-	case WAL_BEGIN_TRANSACTION:
-		// Transaction begin - just log, no action needed during replay
-		// Transactions are handled by the TransactionManager
-
-	case WAL_COMMIT_TRANSACTION:
-		// Transaction commit - just log, no action needed during replay
-		// Transactions are handled by the TransactionManager
-
-	case WAL_ROLLBACK_TRANSACTION:
-		// Transaction rollback - just log, no action needed during replay
-		// Transactions are handled by the TransactionManager
+	case WAL_BEGIN_TRANSACTION, WAL_ROLLBACK_TRANSACTION:
+		// Transaction boundaries - ReplayWAL tracks them; the writes of a
+		// transaction are all in its commit record
 
 	case WAL_SAVEPOINT:
 		// Savepoint creation - just log, no action needed during replay
@@ -319,6 +494,17 @@ func (wm *WALManager) replayEntry(db *Database, entry *WALEntry) error {
 	return nil
 }
 
+// Size returns the size of the WAL file in bytes
+func (wm *WALManager) Size() (int64, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	info, err := os.Stat(wm.walPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat WAL file: %w", err)
+	}
+	return info.Size(), nil
+}
+
 // TruncateWAL truncates the WAL file after successful checkpoint
 func (wm *WALManager) TruncateWAL() error {
 	wm.mu.Lock()
@@ -0,0 +1,169 @@
+// internal/storage/wal_inspect.go
+//
+// WAL introspection for SHOW WAL. The WAL is a single file, wal.log, of
+// length-prefixed JSON entries; these functions read it without blocking
+// writers, stopping at the size it had when they started.
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// walEntryTypeNames names the WAL entry types for display
+var walEntryTypeNames = map[WALEntryType]string{
+	WAL_CREATE_TABLE:          "CREATE_TABLE",
+	WAL_INSERT:                "INSERT",
+	WAL_UPDATE:                "UPDATE",
+	WAL_DELETE:                "DELETE",
+	WAL_DROP_TABLE:            "DROP_TABLE",
+	WAL_CHECKPOINT:            "CHECKPOINT",
+	WAL_BEGIN_TRANSACTION:     "BEGIN",
+	WAL_COMMIT_TRANSACTION:    "COMMIT",
+	WAL_ROLLBACK_TRANSACTION:  "ROLLBACK",
+	WAL_SAVEPOINT:             "SAVEPOINT",
+	WAL_ROLLBACK_TO_SAVEPOINT: "ROLLBACK_TO_SAVEPOINT",
+	WAL_RENAME_TABLE:          "RENAME_TABLE",
+	WAL_COMMENT:               "COMMENT",
+	WAL_SET_LOGGED:            "SET_LOGGED",
+	WAL_BATCH:                 "BATCH",
+	WAL_SET_MASK:              "SET_MASK",
+	WAL_SET_RETENTION:         "SET_RETENTION",
+	WAL_PURGE:                 "PURGE",
+}
+
+func (t WALEntryType) String() string {
+	if name, ok := walEntryTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", uint8(t))
+}
+
+// WALStatus describes the WAL
+type WALStatus struct {
+	Path string
+	// Bytes is the size of the WAL file
+	Bytes int64
+	// Segments is the number of WAL files
+	Segments int
+	// Records is the number of entries in the WAL file
+	Records int
+	// LastLSN is the LSN of the last entry logged
+	LastLSN uint64
+	// CheckpointLSN is the LSN of the last checkpoint: the LSN of the last
+	// checkpoint entry in the file, or the LSN the page files are up to date
+	// with when that is later
+	CheckpointLSN uint64
+	// LagRecords and LagBytes measure the entries after the last checkpoint,
+	// which a restart has to replay
+	LagRecords int
+	LagBytes   int64
+}
+
+// WALStatus reads the WAL and reports its size, LSNs and replay lag
+func (db *Database) WALStatus() (WALStatus, error) {
+	if db.WAL == nil {
+		return WALStatus{}, errors.New("WAL is not available")
+	}
+	st := WALStatus{Path: db.WAL.walPath, Segments: 1, LastLSN: db.WAL.LastLSN()}
+	if db.PageStorage != nil {
+		st.CheckpointLSN = db.PageStorage.CheckpointLSN()
+	}
+
+	// The end of each entry, to measure the lag once the last checkpoint
+	// entry is known
+	type entryEnd struct {
+		lsn uint64
+		end int64
+	}
+	var ends []entryEnd
+	size, err := db.WAL.scan(func(entry *WALEntry, end int64) {
+		st.Records++
+		if entry.Type == WAL_CHECKPOINT && entry.LSN > st.CheckpointLSN {
+			st.CheckpointLSN = entry.LSN
+		}
+		ends = append(ends, entryEnd{entry.LSN, end})
+	})
+	if err != nil {
+		return st, err
+	}
+	st.Bytes = size
+
+	var start int64
+	for _, e := range ends {
+		if e.lsn != 0 && e.lsn <= st.CheckpointLSN {
+			start = e.end
+			continue
+		}
+		st.LagRecords++
+	}
+	st.LagBytes = size - start
+	if st.LagRecords == 0 {
+		st.LagBytes = 0
+	}
+	return st, nil
+}
+
+// RecentWALEntries returns the last n entries of the WAL, oldest first
+func (db *Database) RecentWALEntries(n int) ([]WALEntry, error) {
+	if db.WAL == nil {
+		return nil, errors.New("WAL is not available")
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	ring := make([]WALEntry, 0, n)
+	next := 0
+	_, err := db.WAL.scan(func(entry *WALEntry, end int64) {
+		if len(ring) < n {
+			ring = append(ring, *entry)
+			return
+		}
+		ring[next] = *entry
+		next = (next + 1) % n
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(ring[next:], ring[:next]...), nil
+}
+
+// scan calls fn with each entry of the WAL file and the offset just after
+// it, and returns the size of the file it read. An entry cut short by a
+// concurrent write or truncation ends the scan.
+func (wm *WALManager) scan(fn func(entry *WALEntry, end int64)) (int64, error) {
+	size, err := wm.Size()
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(wm.walPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open WAL file: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(io.LimitReader(f, size))
+	var offset int64
+	for {
+		var length uint32
+		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+			break
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			break
+		}
+		var entry WALEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return offset, fmt.Errorf("failed to decode WAL entry at offset %d: %w", offset, err)
+		}
+		offset += 4 + int64(length)
+		fn(&entry, offset)
+	}
+	return size, nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import "testing"
+
+func TestWALStatusReplayLag(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	defer db.Close()
+	_ = db.CreateTable("users", []string{"id"})
+	if err := db.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := db.WALStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.LagRecords != 0 || before.LagBytes != 0 || before.CheckpointLSN != before.LastLSN {
+		t.Errorf("status after checkpoint: %+v", before)
+	}
+
+	// Entries logged after the checkpoint are what a restart replays
+	for _, comment := range []string{"a", "b"} {
+		if err := db.WAL.WriteEntry(WAL_COMMENT, "users", map[string]interface{}{"comment": comment}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err := db.WALStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.LagRecords != 2 || st.LagBytes != st.Bytes-before.Bytes || st.LastLSN != before.LastLSN+2 || st.Records != before.Records+2 {
+		t.Errorf("status after two entries: %+v (before %+v)", st, before)
+	}
+
+	entries, err := db.RecentWALEntries(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Type != WAL_CHECKPOINT || entries[2].LSN != st.LastLSN || entries[2].Type.String() != "COMMENT" {
+		t.Errorf("recent entries: %+v", entries)
+	}
+}
@@ -0,0 +1,59 @@
+// internal/storage/where.go
+package storage
+
+import "fmt"
+
+// columnEquals is the WHERE expression column = value. It implements the
+// same interfaces as a parsed WHERE clause, so SelectWhere runs through the
+// planner like any other query.
+type columnEquals struct {
+	column, value string
+	coll          *Collation
+	// temporal is set when value must be compared with the column as a
+	// time, such as a time of day with a DATE column
+	temporal bool
+}
+
+// EvaluateExpression reports whether row's column equals the value under
+// the column's collation and type
+func (w *columnEquals) EvaluateExpression(row []string, columnIndexes map[string]int) (bool, error) {
+	i, ok := columnIndexes[w.column]
+	if !ok {
+		return false, fmt.Errorf("column %s not found", w.column)
+	}
+	if i >= len(row) {
+		return false, nil
+	}
+	if w.temporal {
+		a, ok := ParseTime(row[i])
+		b, ok2 := ParseTime(w.value)
+		if ok && ok2 {
+			return a.Equal(b), nil
+		}
+	}
+	return w.coll.Equal(row[i], w.value), nil
+}
+
+// EqualityValue returns the value column must equal. A value compared as a
+// time has no entry in the column's index.
+func (w *columnEquals) EqualityValue(column string) (string, bool) {
+	return w.value, column == w.column && !w.temporal
+}
+
+// ResolveColumns rewrites the column name to its declared spelling
+func (w *columnEquals) ResolveColumns(resolve func(string) string) {
+	w.column = resolve(w.column)
+}
+
+// SetTypes writes the value in the column's canonical form, as a WHERE
+// clause compares it
+func (w *columnEquals) SetTypes(types map[string]ColumnType) {
+	var ok bool
+	w.value, ok = types[w.column].ComparisonValue(w.value)
+	w.temporal = !ok
+}
+
+// SetCollations sets the collation the column is compared under
+func (w *columnEquals) SetCollations(collations map[string]*Collation) {
+	w.coll = collations[w.column]
+}